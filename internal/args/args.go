@@ -1,6 +1,7 @@
 package args
 
 import (
+	"os"
 	"strings"
 
 	"github.com/pborman/getopt/v2"
@@ -14,21 +15,44 @@ type Flags struct {
 	MonochromeMode bool
 	ShowUpdates    bool
 	ShowInstalled  bool
+	OutputFormat   string
+	Watch          bool
+	ExportFile     string
+	ImportFile     string
+	Offline        bool
+	ReadOnly       bool
+	Package        string
+	Completion     string
 	Help           bool
 }
 
 // Parse is parsing our arguments and creates a Flags struct from it
 func Parse() Flags {
-	repos := getopt.String('r', "", "Limit searching to a comma separated list of repositories")
-	term := getopt.String('s', "", "Search-term")
-	ascii := getopt.Bool('a', "ASCII mode")
-	mono := getopt.Bool('m', "Monochrome mode")
-	upd := getopt.Bool('u', "Show updates after startup")
-	inst := getopt.Bool('i', "Show installed packages after startup")
-	help := getopt.BoolLong("help", 'h', "Show usage / help")
-	qhelp := getopt.BoolLong("?", '?', "Show usage / help")
-
-	err := getopt.Getopt(nil)
+	return parse(getopt.CommandLine, os.Args)
+}
+
+// parses the arguments (program name first) with the options of our set
+// -i is "installed" (like it always was), the details of a package are shown with --info / "pacseek open <pkg>"
+func parse(set *getopt.Set, arguments []string) Flags {
+	repos := set.String('r', "", "Limit searching to a comma separated list of repositories")
+	term := set.StringLong("search", 's', "", "Search-term")
+	ascii := set.Bool('a', "ASCII mode")
+	mono := set.Bool('m', "Monochrome mode")
+	upd := set.BoolLong("upgrades", 'u', "Show updates after startup")
+	inst := set.BoolLong("installed", 'i', "Show installed packages after startup")
+	pkg := set.StringLong("info", 0, "", "Show the details of a package after startup")
+	output := set.StringLong("output", 'o', "", "Print search results (json, csv, plain) instead of starting the UI")
+	jsonOutput := set.BoolLong("json", 'j', "Print search results as JSON instead of starting the UI")
+	watch := set.BoolLong("watch", 'w', "Periodically check for updates and send desktop notifications instead of starting the UI")
+	export := set.StringLong("export", 0, "", "Export the explicitly installed packages to a file instead of starting the UI")
+	imp := set.StringLong("import", 0, "", "Compare a package list (see --export) with the installed packages and queue the missing ones")
+	offline := set.BoolLong("offline", 0, "Offline mode: search the local / sync databases only and use cached AUR data")
+	readOnly := set.BoolLong("read-only", 0, "Read-only mode: packages can't be installed, removed or upgraded")
+	completion := set.StringLong("completion", 0, "", "Print a completion script for a shell (bash, zsh, fish)")
+	help := set.BoolLong("help", 'h', "Show usage / help")
+	qhelp := set.BoolLong("?", '?', "Show usage / help")
+
+	err := set.Getopt(arguments, nil)
 	if err != nil {
 		return Flags{
 			Help: true,
@@ -41,6 +65,17 @@ func Parse() Flags {
 		MonochromeMode: *mono,
 		ShowUpdates:    *upd,
 		ShowInstalled:  *inst,
+		OutputFormat:   *output,
+		Watch:          *watch,
+		ExportFile:     *export,
+		ImportFile:     *imp,
+		Offline:        *offline,
+		ReadOnly:       *readOnly,
+		Package:        *pkg,
+		Completion:     *completion,
+	}
+	if *jsonOutput {
+		flags.OutputFormat = "json"
 	}
 
 	if len(*repos) > 0 {
@@ -49,8 +84,14 @@ func Parse() Flags {
 
 	flags.Help = *help || *qhelp
 
-	if flags.SearchTerm == "" && len(getopt.Args()) > 0 {
-		flags.SearchTerm = getopt.Args()[0]
+	// "pacseek open <pkg>" is the same as "pacseek --info <pkg>"
+	rest := set.Args()
+	if len(rest) > 1 && rest[0] == "open" && flags.Package == "" {
+		flags.Package = rest[1]
+		rest = rest[2:]
+	}
+	if flags.SearchTerm == "" && len(rest) > 0 {
+		flags.SearchTerm = rest[0]
 	}
 
 	return flags
@@ -0,0 +1,89 @@
+package args
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pborman/getopt/v2"
+	"github.com/stretchr/testify/suite"
+)
+
+type argsTestSuite struct {
+	suite.Suite
+}
+
+func TestRunArgsTestSuite(t *testing.T) {
+	suite.Run(t, new(argsTestSuite))
+}
+
+func (suite *argsTestSuite) TestParse() {
+	f := parse(getopt.New(), []string{"pacseek", "-i", "-u", "-r", "core,extra", "vim"})
+	suite.True(f.ShowInstalled)
+	suite.True(f.ShowUpdates)
+	suite.Equal([]string{"core", "extra"}, f.Repositories)
+	suite.Equal("vim", f.SearchTerm)
+
+	f = parse(getopt.New(), []string{"pacseek", "open", "vim", "neovim"})
+	suite.Equal("vim", f.Package)
+	suite.Equal("neovim", f.SearchTerm)
+
+	f = parse(getopt.New(), []string{"pacseek", "--info", "extra/vim", "-j"})
+	suite.Equal("extra/vim", f.Package)
+	suite.Equal("json", f.OutputFormat)
+
+	// nok
+	suite.True(parse(getopt.New(), []string{"pacseek", "--nonsense"}).Help)
+}
+
+func (suite *argsTestSuite) TestCompletionOptions() {
+	set := getopt.New()
+	parse(set, []string{"pacseek"})
+
+	// our completion options have to match the ones of our parser ("-?" is an alias of "-h")
+	parsed := map[string]bool{}
+	set.VisitAll(func(o getopt.Option) {
+		if o.ShortName() == "?" {
+			return
+		}
+		parsed[o.ShortName()+"/"+o.LongName()] = o.IsFlag()
+	})
+	completed := map[string]bool{}
+	for _, o := range options {
+		completed[o.short+"/"+o.long] = o.arg == ""
+	}
+	suite.Equal(parsed, completed)
+}
+
+func (suite *argsTestSuite) TestCompletion() {
+	dir := suite.T().TempDir()
+	checks := map[string][]string{
+		"bash": {"bash", "-n"},
+		"zsh":  {"zsh", "-n"},
+		"fish": {"fish", "--no-execute"},
+	}
+	for shell, check := range checks {
+		script, err := Completion(shell)
+		suite.Nil(err, err)
+		for _, o := range options {
+			if o.long != "" {
+				suite.Contains(script, o.long, shell)
+			}
+		}
+
+		// syntax of our scripts (if the shell is installed)
+		if _, err := exec.LookPath(check[0]); err != nil {
+			continue
+		}
+		file := filepath.Join(dir, "pacseek."+shell)
+		suite.Nil(os.WriteFile(file, []byte(script), 0644))
+		out, err := exec.Command(check[0], append(check[1:], file)...).CombinedOutput()
+		suite.Nil(err, shell+": "+strings.TrimSpace(string(out)))
+	}
+
+	// nok
+	_, err := Completion("powershell")
+	suite.NotNil(err)
+}
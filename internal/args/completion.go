@@ -0,0 +1,195 @@
+package args
+
+import (
+	"fmt"
+	"strings"
+)
+
+// option describes one of our command line options (see Parse) for our completion scripts
+// "arg" is the kind of argument it takes, empty for flags
+type option struct {
+	short string
+	long  string
+	desc  string
+	arg   string
+}
+
+// argument kinds and their values (if there's a fixed set of them)
+const (
+	argRepos   = "repos"
+	argPackage = "package"
+	argFile    = "file"
+	argTerm    = "term"
+	argFormat  = "format"
+	argShell   = "shell"
+)
+
+var argValues = map[string][]string{
+	argFormat: {"json", "csv", "plain"},
+	argShell:  {"bash", "zsh", "fish"},
+}
+
+// the options of Parse, keep them in sync
+var options = []option{
+	{"r", "", "Limit searching to a comma separated list of repositories", argRepos},
+	{"s", "search", "Search-term", argTerm},
+	{"a", "", "ASCII mode", ""},
+	{"m", "", "Monochrome mode", ""},
+	{"u", "upgrades", "Show updates after startup", ""},
+	{"i", "installed", "Show installed packages after startup", ""},
+	{"", "info", "Show the details of a package after startup", argPackage},
+	{"o", "output", "Print search results instead of starting the UI", argFormat},
+	{"j", "json", "Print search results as JSON instead of starting the UI", ""},
+	{"w", "watch", "Periodically check for updates and send desktop notifications", ""},
+	{"", "export", "Export the explicitly installed packages to a file", argFile},
+	{"", "import", "Compare a package list with the installed packages", argFile},
+	{"", "offline", "Search the local / sync databases only and use cached AUR data", ""},
+	{"", "read-only", "Disable installing, removing and upgrading packages", ""},
+	{"", "completion", "Print a completion script for a shell", argShell},
+	{"h", "help", "Show usage / help", ""},
+}
+
+// commands to list repositories / package names
+const (
+	listRepos    = "pacman-conf --repo-list 2>/dev/null"
+	listPackages = "pacman -Slq 2>/dev/null"
+)
+
+// Completion returns the completion script for a shell (bash, zsh or fish)
+func Completion(shell string) (string, error) {
+	switch shell {
+	case "bash":
+		return bashCompletion(), nil
+	case "zsh":
+		return zshCompletion(), nil
+	case "fish":
+		return fishCompletion(), nil
+	}
+	return "", fmt.Errorf("unsupported shell '%s', use bash, zsh or fish", shell)
+}
+
+// returns "-x" / "--long" for our options
+func (o option) names() []string {
+	names := []string{}
+	if o.short != "" {
+		names = append(names, "-"+o.short)
+	}
+	if o.long != "" {
+		names = append(names, "--"+o.long)
+	}
+	return names
+}
+
+// returns our completion script for bash (a function filling COMPREPLY, registered with "complete -F")
+func bashCompletion() string {
+	all := []string{}
+	cases := map[string][]string{}
+	for _, o := range options {
+		all = append(all, o.names()...)
+		if o.arg != "" {
+			cases[o.arg] = append(cases[o.arg], o.names()...)
+		}
+	}
+	// "open" is followed by a package name as well
+	cases[argPackage] = append(cases[argPackage], "open")
+
+	var b strings.Builder
+	b.WriteString("# bash completion for pacseek\n\n_pacseek() {\n")
+	b.WriteString("\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	b.WriteString("\tcase \"$prev\" in\n")
+	for _, kind := range []string{argRepos, argPackage, argFile, argTerm, argFormat, argShell} {
+		b.WriteString("\t" + strings.Join(cases[kind], "|") + ")\n")
+		switch kind {
+		case argRepos:
+			b.WriteString("\t\tCOMPREPLY=($(compgen -W \"$(" + listRepos + ")\" -- \"$cur\"))\n")
+		case argPackage:
+			b.WriteString("\t\tCOMPREPLY=($(compgen -W \"$(" + listPackages + ")\" -- \"$cur\"))\n")
+		case argFile:
+			b.WriteString("\t\tCOMPREPLY=($(compgen -f -- \"$cur\"))\n")
+		case argTerm:
+			b.WriteString("\t\tCOMPREPLY=()\n")
+		default:
+			b.WriteString("\t\tCOMPREPLY=($(compgen -W \"" + strings.Join(argValues[kind], " ") + "\" -- \"$cur\"))\n")
+		}
+		b.WriteString("\t\treturn\n\t\t;;\n")
+	}
+	b.WriteString("\tesac\n")
+	b.WriteString("\tif [[ \"$cur\" == -* ]]; then\n")
+	b.WriteString("\t\tCOMPREPLY=($(compgen -W \"" + strings.Join(all, " ") + "\" -- \"$cur\"))\n")
+	b.WriteString("\telif [[ $COMP_CWORD -eq 1 ]]; then\n")
+	b.WriteString("\t\tCOMPREPLY=($(compgen -W \"open\" -- \"$cur\"))\n")
+	b.WriteString("\tfi\n}\n\ncomplete -F _pacseek pacseek\n")
+	return b.String()
+}
+
+// returns our completion script for zsh (an _arguments spec, loaded via #compdef)
+func zshCompletion() string {
+	var b strings.Builder
+	b.WriteString("#compdef pacseek\n\n")
+	b.WriteString("_pacseek_repos() {\n\t_values -s , repository $(" + listRepos + ")\n}\n\n")
+	b.WriteString("_pacseek_packages() {\n\tcompadd -- $(" + listPackages + ")\n}\n\n")
+	b.WriteString("_pacseek() {\n\t_arguments -s \\\n")
+	for _, o := range options {
+		names := o.names()
+		spec := "'" + names[0]
+		if len(names) > 1 {
+			spec = "'(" + strings.Join(names, " ") + ")'{" + strings.Join(names, ",") + "}'"
+		}
+		spec += "[" + zshEscape(o.desc) + "]"
+		switch o.arg {
+		case argRepos:
+			spec += ":repositories:_pacseek_repos"
+		case argPackage:
+			spec += ":package:_pacseek_packages"
+		case argFile:
+			spec += ":file:_files"
+		case argTerm:
+			spec += ":search-term: "
+		case argFormat, argShell:
+			spec += ":" + o.arg + ":(" + strings.Join(argValues[o.arg], " ") + ")"
+		}
+		b.WriteString("\t\t" + spec + "' \\\n")
+	}
+	b.WriteString("\t\t'1:: :->first' \\\n\t\t'2:: :->second'\n\n")
+	b.WriteString("\tcase $state in\n")
+	b.WriteString("\tfirst)\n\t\t_values command 'open[show the details of a package]'\n\t\t;;\n")
+	b.WriteString("\tsecond)\n\t\t[[ $words[2] == open ]] && _pacseek_packages\n\t\t;;\n")
+	b.WriteString("\tesac\n}\n\n_pacseek \"$@\"\n")
+	return b.String()
+}
+
+// escapes characters with a special meaning in the descriptions of _arguments
+func zshEscape(s string) string {
+	return strings.NewReplacer("'", "'\\''", "[", "\\[", "]", "\\]").Replace(s)
+}
+
+// returns our completion script for fish (one "complete" command per option)
+func fishCompletion() string {
+	var b strings.Builder
+	b.WriteString("# fish completion for pacseek\n\ncomplete -c pacseek -f\n")
+	for _, o := range options {
+		line := "complete -c pacseek"
+		if o.short != "" {
+			line += " -s " + o.short
+		}
+		if o.long != "" {
+			line += " -l " + o.long
+		}
+		switch o.arg {
+		case argRepos:
+			line += " -x -a \"(" + listRepos + ")\""
+		case argPackage:
+			line += " -x -a \"(" + listPackages + ")\""
+		case argFile:
+			line += " -r -F"
+		case argTerm:
+			line += " -x"
+		case argFormat, argShell:
+			line += " -x -a \"" + strings.Join(argValues[o.arg], " ") + "\""
+		}
+		b.WriteString(line + " -d '" + strings.ReplaceAll(o.desc, "'", "\\'") + "'\n")
+	}
+	b.WriteString("complete -c pacseek -n __fish_use_subcommand -a open -d 'Show the details of a package'\n")
+	b.WriteString("complete -c pacseek -n '__fish_seen_subcommand_from open' -a \"(" + listPackages + ")\"\n")
+	return b.String()
+}
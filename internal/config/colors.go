@@ -7,7 +7,10 @@ import (
 	"io/fs"
 	"os"
 	"path"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/gdamore/tcell/v2"
 )
@@ -25,15 +28,31 @@ type Colors struct {
 	SettingsFieldLabel          tcell.Color
 	SettingsDropdownNotSelected tcell.Color
 	DefaultBackground           tcell.Color
+	Text                        tcell.Color
+	Background                  tcell.Color
+	Border                      tcell.Color
+	Installed                   tcell.Color
+	Warning                     tcell.Color
+	Error                       tcell.Color
 	StylePKGBUILD               string
-	colorParsingError           error
 }
 
 // default scheme
 const (
 	defaultColorScheme = "Arch Linux"
+	lightColorScheme   = "Light"
 )
 
+// colors that are used when a scheme does not define them
+var baseColors = Colors{
+	Text:       tcell.ColorWhite,
+	Background: tcell.ColorBlack,
+	Border:     tcell.ColorWhite,
+	Installed:  tcell.NewHexColor(0x00ff00),
+	Warning:    tcell.ColorYellow,
+	Error:      tcell.ColorRed,
+}
+
 // color scheme definitions
 var (
 	colorSchemes = map[string]Colors{
@@ -126,25 +145,88 @@ var (
 			SettingsFieldText:           tcell.ColorWhite,
 			SettingsFieldLabel:          tcell.ColorWhite,
 			SettingsDropdownNotSelected: tcell.ColorBlack,
+			Installed:                   tcell.ColorWhite,
 			StylePKGBUILD:               "bw",
 		},
+		"Light": {
+			Accent:                      tcell.NewHexColor(0x005faf),
+			Title:                       tcell.NewHexColor(0x0087d7),
+			SearchBar:                   tcell.NewHexColor(0xc6c6c6),
+			PackagelistSourceRepository: tcell.NewHexColor(0x008700),
+			PackagelistSourceAUR:        tcell.NewHexColor(0x005faf),
+			PackagelistHeader:           tcell.NewHexColor(0xaf5f00),
+			SettingsFieldBackground:     tcell.NewHexColor(0xc6c6c6),
+			SettingsFieldText:           tcell.ColorBlack,
+			SettingsFieldLabel:          tcell.NewHexColor(0xaf5f00),
+			SettingsDropdownNotSelected: tcell.NewHexColor(0xe4e4e4),
+			Text:                        tcell.ColorBlack,
+			Background:                  tcell.ColorWhite,
+			Border:                      tcell.NewHexColor(0x585858),
+			Installed:                   tcell.NewHexColor(0x008700),
+			Warning:                     tcell.NewHexColor(0xaf8700),
+			Error:                       tcell.NewHexColor(0xd70000),
+			StylePKGBUILD:               "github",
+		},
+		"Nord": {
+			Accent:                      tcell.NewHexColor(0x88c0d0),
+			Title:                       tcell.NewHexColor(0x8fbcbb),
+			SearchBar:                   tcell.NewHexColor(0x434c5e),
+			PackagelistSourceRepository: tcell.NewHexColor(0xa3be8c),
+			PackagelistSourceAUR:        tcell.NewHexColor(0x81a1c1),
+			PackagelistHeader:           tcell.NewHexColor(0xebcb8b),
+			SettingsFieldBackground:     tcell.NewHexColor(0x434c5e),
+			SettingsFieldText:           tcell.NewHexColor(0xeceff4),
+			SettingsFieldLabel:          tcell.NewHexColor(0xebcb8b),
+			SettingsDropdownNotSelected: tcell.NewHexColor(0x4c566a),
+			Text:                        tcell.NewHexColor(0xd8dee9),
+			Background:                  tcell.NewHexColor(0x2e3440),
+			Border:                      tcell.NewHexColor(0x4c566a),
+			Installed:                   tcell.NewHexColor(0xa3be8c),
+			Warning:                     tcell.NewHexColor(0xebcb8b),
+			Error:                       tcell.NewHexColor(0xbf616a),
+			StylePKGBUILD:               "nord",
+		},
+		"Dracula": {
+			Accent:                      tcell.NewHexColor(0xbd93f9),
+			Title:                       tcell.NewHexColor(0xff79c6),
+			SearchBar:                   tcell.NewHexColor(0x44475a),
+			PackagelistSourceRepository: tcell.NewHexColor(0x50fa7b),
+			PackagelistSourceAUR:        tcell.NewHexColor(0xbd93f9),
+			PackagelistHeader:           tcell.NewHexColor(0xf1fa8c),
+			SettingsFieldBackground:     tcell.NewHexColor(0x44475a),
+			SettingsFieldText:           tcell.NewHexColor(0xf8f8f2),
+			SettingsFieldLabel:          tcell.NewHexColor(0xf1fa8c),
+			SettingsDropdownNotSelected: tcell.NewHexColor(0x6272a4),
+			Text:                        tcell.NewHexColor(0xf8f8f2),
+			Background:                  tcell.NewHexColor(0x282a36),
+			Border:                      tcell.NewHexColor(0x6272a4),
+			Installed:                   tcell.NewHexColor(0x50fa7b),
+			Warning:                     tcell.NewHexColor(0xffb86c),
+			Error:                       tcell.NewHexColor(0xff5555),
+			StylePKGBUILD:               "dracula",
+		},
 	}
 )
 
 // SetColorScheme applies a color scheme
+// besides our built-in ones, this can be "Auto" (depending on the terminal background), "Custom" (colors.json)
+// or the name of a user theme (themes/<name>.json in our config dir)
 func (s *Settings) SetColorScheme(scheme string) error {
-	if scheme == "Custom" {
-		var err error
+	var err error
+	switch scheme {
+	case "Custom":
 		s.colors, err = loadCustomColors()
-		if err != nil {
-			return err
+	case "Auto":
+		s.colors = colorSchemes[autoColorScheme(os.Getenv("COLORFGBG"))]
+	default:
+		if c, ok := colorSchemes[scheme]; ok {
+			s.colors = c
+		} else {
+			s.colors, err = loadTheme(scheme)
 		}
-		return nil
 	}
-
-	s.colors = colorSchemes[scheme]
-
-	return nil
+	s.colors = s.colors.withBaseColors()
+	return err
 }
 
 // SetTransparency switched transparency on or off
@@ -152,8 +234,90 @@ func (s *Settings) SetTransparency(enable bool) {
 	if enable {
 		s.colors.DefaultBackground = tcell.ColorDefault
 	} else {
-		s.colors.DefaultBackground = tcell.ColorBlack
+		s.colors.DefaultBackground = s.colors.Background
+	}
+}
+
+// returns our colors with the ones that are not defined taken from our base colors
+func (c Colors) withBaseColors() Colors {
+	for _, color := range []struct {
+		value *tcell.Color
+		base  tcell.Color
+	}{
+		{&c.Text, baseColors.Text},
+		{&c.Background, baseColors.Background},
+		{&c.Border, baseColors.Border},
+		{&c.Installed, baseColors.Installed},
+		{&c.Warning, baseColors.Warning},
+		{&c.Error, baseColors.Error},
+	} {
+		if *color.value == tcell.ColorDefault {
+			*color.value = color.base
+		}
+	}
+	return c
+}
+
+// picks our color scheme for the terminal background, which is detected with the COLORFGBG variable (e.g. "15;0")
+// set by many terminals. when it's not available, we assume a dark background
+func autoColorScheme(colorFgBg string) string {
+	fields := strings.Split(colorFgBg, ";")
+	bg, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil {
+		return defaultColorScheme
+	}
+	// 7 (light gray) and 9-15 (bright colors) are light backgrounds, 8 is dark gray
+	if bg == 7 || (bg >= 9 && bg <= 15) {
+		return lightColorScheme
 	}
+	return defaultColorScheme
+}
+
+// returns the directory holding user themes (~/.config/pacseek/themes)
+func themeDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(dir, "pacseek", "themes"), nil
+}
+
+// returns the names of our user themes, themes named like a built-in scheme are skipped
+func userThemes() []string {
+	dir, err := themeDir()
+	if err != nil {
+		return []string{}
+	}
+	files, err := filepath.Glob(path.Join(dir, "*.json"))
+	if err != nil {
+		return []string{}
+	}
+	themes := []string{}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		if _, builtIn := colorSchemes[name]; !builtIn && name != "Auto" && name != "Custom" {
+			themes = append(themes, name)
+		}
+	}
+	sort.Strings(themes)
+	return themes
+}
+
+// loads a user theme, colors that are not defined by the theme are taken from our default scheme
+func loadTheme(name string) (Colors, error) {
+	dir, err := themeDir()
+	if err != nil {
+		return colorSchemes[defaultColorScheme], err
+	}
+	b, err := os.ReadFile(path.Join(dir, name+".json"))
+	if err != nil {
+		return colorSchemes[defaultColorScheme], err
+	}
+	c := colorSchemes[defaultColorScheme]
+	if err := c.unmarshalJSON(b); err != nil {
+		return colorSchemes[defaultColorScheme], fmt.Errorf("theme %s: %w", name, err)
+	}
+	return c, nil
 }
 
 // loads custom colors from file
@@ -177,21 +341,18 @@ func loadCustomColors() (Colors, error) {
 		return colorSchemes[defaultColorScheme], err
 	}
 
-	c := Colors{}
+	c := colorSchemes[defaultColorScheme]
 	err = c.unmarshalJSON(b)
 	if err != nil {
 		return colorSchemes[defaultColorScheme], err
 	}
-	if c.colorParsingError != nil {
-		return colorSchemes[defaultColorScheme], c.colorParsingError
-	}
 
 	return c, nil
 }
 
 // write our color scheme to a json file
 func createCustomColorsFile(colorFile string) error {
-	c := colorSchemes[defaultColorScheme]
+	c := colorSchemes[defaultColorScheme].withBaseColors()
 	b, err := c.marshalJSON()
 	if err != nil {
 		return err
@@ -218,6 +379,12 @@ func (c *Colors) marshalJSON() ([]byte, error) {
 		SettingsFieldText           string
 		SettingsFieldLabel          string
 		SettingsDropdownNotSelected string
+		Text                        string
+		Background                  string
+		Border                      string
+		Installed                   string
+		Warning                     string
+		Error                       string
 		StylePKGBUILD               string
 		Comments                    string
 	}{
@@ -231,54 +398,81 @@ func (c *Colors) marshalJSON() ([]byte, error) {
 		SettingsFieldText:           fmt.Sprintf("%06x", c.SettingsFieldText.Hex()),
 		SettingsFieldLabel:          fmt.Sprintf("%06x", c.SettingsFieldLabel.Hex()),
 		SettingsDropdownNotSelected: fmt.Sprintf("%06x", c.SettingsDropdownNotSelected.Hex()),
+		Text:                        fmt.Sprintf("%06x", c.Text.Hex()),
+		Background:                  fmt.Sprintf("%06x", c.Background.Hex()),
+		Border:                      fmt.Sprintf("%06x", c.Border.Hex()),
+		Installed:                   fmt.Sprintf("%06x", c.Installed.Hex()),
+		Warning:                     fmt.Sprintf("%06x", c.Warning.Hex()),
+		Error:                       fmt.Sprintf("%06x", c.Error.Hex()),
 		StylePKGBUILD:               c.StylePKGBUILD,
-		Comments:                    "Examples for StylePKGBUILD can be found here: https://xyproto.github.io/splash/docs/all.html",
+		Comments:                    "Colors can be hex values (1793d1), names (yellow) or 256-color palette indexes (208). Examples for StylePKGBUILD can be found here: https://xyproto.github.io/splash/docs/all.html",
 	}, "", "\t")
 }
 
-// custom JSON unmarshalling for our colors
+// custom JSON unmarshalling for our colors, colors that are not set (or empty) are left unchanged
 func (c *Colors) unmarshalJSON(data []byte) error {
-	d := &struct {
-		Accent                      string
-		Title                       string
-		SearchBar                   string
-		PackagelistSourceRepository string
-		PackagelistSourceAUR        string
-		PackagelistHeader           string
-		SettingsFieldBackground     string
-		SettingsFieldText           string
-		SettingsFieldLabel          string
-		SettingsDropdownNotSelected string
-		StylePKGBUILD               string
-	}{}
-	err := json.Unmarshal(data, d)
+	d := map[string]interface{}{}
+	err := json.Unmarshal(data, &d)
 	if err != nil {
 		return err
 	}
 
-	c.Accent = c.colorFromHexString(d.Accent)
-	c.Title = c.colorFromHexString(d.Title)
-	c.SearchBar = c.colorFromHexString(d.SearchBar)
-	c.PackagelistSourceRepository = c.colorFromHexString(d.PackagelistSourceRepository)
-	c.PackagelistSourceAUR = c.colorFromHexString(d.PackagelistSourceAUR)
-	c.PackagelistHeader = c.colorFromHexString(d.PackagelistHeader)
-	c.SettingsFieldBackground = c.colorFromHexString(d.SettingsFieldBackground)
-	c.SettingsFieldText = c.colorFromHexString(d.SettingsFieldText)
-	c.SettingsFieldLabel = c.colorFromHexString(d.SettingsFieldLabel)
-	c.SettingsDropdownNotSelected = c.colorFromHexString(d.SettingsDropdownNotSelected)
-	c.StylePKGBUILD = d.StylePKGBUILD
+	for _, color := range []struct {
+		name  string
+		value *tcell.Color
+	}{
+		{"Accent", &c.Accent},
+		{"Title", &c.Title},
+		{"SearchBar", &c.SearchBar},
+		{"PackagelistSourceRepository", &c.PackagelistSourceRepository},
+		{"PackagelistSourceAUR", &c.PackagelistSourceAUR},
+		{"PackagelistHeader", &c.PackagelistHeader},
+		{"SettingsFieldBackground", &c.SettingsFieldBackground},
+		{"SettingsFieldText", &c.SettingsFieldText},
+		{"SettingsFieldLabel", &c.SettingsFieldLabel},
+		{"SettingsDropdownNotSelected", &c.SettingsDropdownNotSelected},
+		{"Text", &c.Text},
+		{"Background", &c.Background},
+		{"Border", &c.Border},
+		{"Installed", &c.Installed},
+		{"Warning", &c.Warning},
+		{"Error", &c.Error},
+	} {
+		val, _ := d[color.name].(string)
+		if val == "" {
+			continue
+		}
+		parsed, err := parseColor(val)
+		if err != nil {
+			return fmt.Errorf("%s: %w", color.name, err)
+		}
+		*color.value = parsed
+	}
+	if style, ok := d["StylePKGBUILD"].(string); ok && style != "" {
+		c.StylePKGBUILD = style
+	}
 
 	return nil
 }
 
-// convert (color) hex string to tcell.Color
-func (c *Colors) colorFromHexString(val string) tcell.Color {
-	v, err := strconv.ParseInt(val, 16, 32)
-	if err != nil {
-		c.colorParsingError = err
-		return tcell.ColorRed
+// converts a color value to tcell.Color
+// it can be a hex value (true color, e.g. "1793d1" or "#1793d1"), a color name (e.g. "yellow")
+// or an index of the 256-color palette (e.g. "208")
+func parseColor(val string) (tcell.Color, error) {
+	val = strings.ToLower(strings.TrimSpace(val))
+	if c, ok := tcell.ColorNames[val]; ok {
+		return c, nil
+	}
+	hex := strings.TrimPrefix(val, "#")
+	if len(hex) == 6 {
+		if v, err := strconv.ParseInt(hex, 16, 32); err == nil {
+			return tcell.NewHexColor(int32(v)), nil
+		}
+	}
+	if i, err := strconv.Atoi(val); err == nil && i >= 0 && i <= 255 {
+		return tcell.PaletteColor(i), nil
 	}
-	return tcell.NewHexColor(int32(v))
+	return tcell.ColorDefault, fmt.Errorf("invalid color: %s", val)
 }
 
 // Colors exposes our current set of colors
@@ -286,7 +480,7 @@ func (s *Settings) Colors() Colors {
 	return s.colors
 }
 
-// Returns all available color schemes
+// Returns all available color schemes, user themes come last
 func ColorSchemes() []string {
-	return []string{"Arch Linux", "Endeavour OS", "Red", "Green", "Blue", "Orange", "Monochrome", "Custom"}
+	return append([]string{"Arch Linux", "Endeavour OS", "Red", "Green", "Blue", "Orange", "Monochrome", "Light", "Nord", "Dracula", "Auto", "Custom"}, userThemes()...)
 }
@@ -14,17 +14,56 @@ type Settings struct {
 	AurUseDifferentCommands bool
 	AurInstallCommand       string
 	AurUpgradeCommand       string
+	AurChrootBuild          bool
+	AurChrootPackages       []string
+	AurChrootCommand        string
+	AurVoting               bool
+	AurSshCommand           string
 	DisableAur              bool
+	AurIgnore               []string
+	HideOutOfDate           bool
+	IgnoredPackages         []string
+	EnableFlatpak           bool
+	FlatpakInstallCommand   string
+	FlatpakUninstallCommand string
+	FlatpakUpgradeCommand   string
+	Plugins                 string
+	ExcludeSources          []string
+	RepoPriority            []string
+	DisableRepoMerge        bool
 	MaxResults              int
+	DisableLazyLoading      bool
+	DisablePkgstats         bool
+	DisableAppStream        bool
+	ScreenshotGraphics      string
+	MaxDependencies         int
+	BroadSearchWarning      int
+	PacmanRootPath          string
 	PacmanDbPath            string
 	PacmanConfigPath        string
 	InstallCommand          string
 	UninstallCommand        string
+	DisableInstallPreview   bool
+	DowngradeCommand        string
+	DownloadCommand         string
+	AurDownloadCommand      string
+	AurDownloadDir          string
+	DisablePacnewCheck      bool
+	ReadOnly                bool
+	MergeCommand            string
+	PackageCacheDirs        string
+	ShowUpdateStatus        bool
+	UpdateCheckInterval     int
+	QuietHours              string
 	SysUpgradeCommand       string
 	SearchMode              string
 	SearchBy                string
+	LocalFilter             string
+	SortResults             string
 	CacheExpiry             int
 	DisableCache            bool
+	DisableDiskCache        bool
+	DiskCacheExpiry         int
 	ColorScheme             string
 	BorderStyle             string
 	ShowPkgbuildCommand     string
@@ -32,14 +71,27 @@ type Settings struct {
 	ComputeRequiredBy       bool
 	GlyphStyle              string
 	DisableNewsFeed         bool
+	DisableNewsWarning      bool
 	FeedURLs                string
 	FeedMaxItems            int
+	DisableAdvisories       bool
+	SecurityTrackerUrl      string
 	SaveWindowLayout        bool
 	LeftProportion          int
 	Transparent             bool
 	PackageColumnWidth      int
 	EnableAutoSuggest       bool
+	LiveSearch              bool
+	LiveSearchDelay         int
+	LiveSearchMinLength     int
 	SepDepsWithNewLine      bool
+	SkipFailingRepos        bool
+	PreferNameMatches       bool
+	PreserveRepoOrder       bool
+	SegmentPrefixMatch      bool
+	CaseSensitive           bool
+	LogLevel                string
+	KeyBindings             map[string]string
 	colors                  Colors
 	glyphs                  Glyphs
 }
@@ -50,34 +102,88 @@ func Defaults() *Settings {
 		AurRpcUrl:              "https://aurapi.moson.org/rpc",
 		AurTimeout:             5000,
 		AurSearchDelay:         500,
+		AurChrootBuild:         false,
+		AurChrootPackages:      []string{},
+		AurChrootCommand:       "",
+		AurVoting:              false,
+		AurSshCommand:          "ssh aur@aur.archlinux.org",
 		DisableAur:             false,
+		AurIgnore:              []string{},
+		HideOutOfDate:          false,
+		IgnoredPackages:        []string{},
+		EnableFlatpak:          false,
+		Plugins:                "",
+		ExcludeSources:         []string{},
+		RepoPriority:           []string{},
+		DisableRepoMerge:       false,
 		MaxResults:             500,
+		DisableLazyLoading:     false,
+		DisablePkgstats:        false,
+		DisableAppStream:       false,
+		ScreenshotGraphics:     "auto",
+		MaxDependencies:        0,
+		BroadSearchWarning:     0,
+		PacmanRootPath:         "/",
 		PacmanDbPath:           "/var/lib/pacman/",
 		PacmanConfigPath:       "/etc/pacman.conf",
 		InstallCommand:         "yay -S",
 		UninstallCommand:       "yay -Rs",
+		DisableInstallPreview:  false,
+		DowngradeCommand:       "sudo pacman -U",
+		DownloadCommand:        "sudo pacman -Sw",
+		AurDownloadCommand:     "(git -C {pkgbase} pull -q 2>/dev/null || git clone -q {giturl}) && cd {pkgbase} && makepkg -od",
+		AurDownloadDir:         "",
+		DisablePacnewCheck:     false,
+		ReadOnly:               false,
+		MergeCommand:           "sudo vimdiff {original} {file}",
+		PackageCacheDirs:       "",
+		ShowUpdateStatus:       true,
+		UpdateCheckInterval:    60,
+		QuietHours:             "",
 		SearchMode:             "Contains",
 		SysUpgradeCommand:      "yay",
 		SearchBy:               "Name",
+		LocalFilter:            "All",
+		SortResults:            "name",
 		CacheExpiry:            10,
 		DisableCache:           false,
+		DisableDiskCache:       false,
+		DiskCacheExpiry:        60,
 		ColorScheme:            defaultColorScheme,
 		BorderStyle:            "Double",
-		colors:                 colorSchemes[defaultColorScheme],
+		colors:                 colorSchemes[defaultColorScheme].withBaseColors(),
 		ShowPkgbuildCommand:    "curl -s \"{url}\"|less",
 		ShowPkgbuildInternally: true,
 		ComputeRequiredBy:      false,
 		GlyphStyle:             defaultGlyphStyle,
 		glyphs:                 glyphStyles[defaultGlyphStyle],
 		DisableNewsFeed:        false,
+		DisableNewsWarning:     false,
 		FeedURLs:               "https://archlinux.org/feeds/news/",
 		FeedMaxItems:           5,
+		DisableAdvisories:      false,
+		SecurityTrackerUrl:     "https://security.archlinux.org/issues/all.json",
 		SaveWindowLayout:       false,
 		LeftProportion:         4,
 		Transparent:            false,
 		PackageColumnWidth:     0,
 		EnableAutoSuggest:      false,
+		LiveSearch:             false,
+		LiveSearchDelay:        300,
+		LiveSearchMinLength:    2,
 		SepDepsWithNewLine:     true,
+		SkipFailingRepos:       false,
+		PreferNameMatches:      false,
+		PreserveRepoOrder:      false,
+		SegmentPrefixMatch:     false,
+		CaseSensitive:          false,
+		LogLevel:               "info",
+		KeyBindings:            map[string]string{},
+
+		// commands of our Flatpak source
+		FlatpakInstallCommand:   "flatpak install {pkgbase} {pkg}",
+		FlatpakUninstallCommand: "flatpak uninstall {pkg}",
+		FlatpakUpgradeCommand:   "flatpak update {pkg}",
 	}
 
 	return &s
@@ -200,6 +306,106 @@ func (s *Settings) applyUpgradeFixes() {
 		fixApplied = true
 	}
 
+	// Pacman root path added with 1.8.3
+	if s.PacmanRootPath == "" {
+		s.PacmanRootPath = def.PacmanRootPath
+		fixApplied = true
+	}
+
+	// Local filter added with 1.8.3
+	if s.LocalFilter == "" {
+		s.LocalFilter = def.LocalFilter
+		fixApplied = true
+	}
+
+	// Sorting added with 1.8.3
+	if s.SortResults == "" {
+		s.SortResults = def.SortResults
+		fixApplied = true
+	}
+
+	// Security tracker added with 1.8.3
+	if s.SecurityTrackerUrl == "" {
+		s.SecurityTrackerUrl = def.SecurityTrackerUrl
+		fixApplied = true
+	}
+
+	// Downgrades added with 1.8.3
+	if s.DowngradeCommand == "" {
+		s.DowngradeCommand = def.DowngradeCommand
+		fixApplied = true
+	}
+
+	// Update checks added with 1.8.3
+	if s.UpdateCheckInterval < 1 {
+		s.UpdateCheckInterval = def.UpdateCheckInterval
+		fixApplied = true
+	}
+
+	// Disk cache added with 1.8.3
+	if s.DiskCacheExpiry == 0 {
+		s.DiskCacheExpiry = def.DiskCacheExpiry
+		fixApplied = true
+	}
+
+	// Flatpak commands added with 1.8.3
+	if s.FlatpakInstallCommand == "" {
+		s.FlatpakInstallCommand = def.FlatpakInstallCommand
+		fixApplied = true
+	}
+	if s.FlatpakUninstallCommand == "" {
+		s.FlatpakUninstallCommand = def.FlatpakUninstallCommand
+		fixApplied = true
+	}
+	if s.FlatpakUpgradeCommand == "" {
+		s.FlatpakUpgradeCommand = def.FlatpakUpgradeCommand
+		fixApplied = true
+	}
+
+	// Live search added with 1.8.3
+	if s.LiveSearchDelay == 0 {
+		s.LiveSearchDelay = def.LiveSearchDelay
+		fixApplied = true
+	}
+	if s.LiveSearchMinLength == 0 {
+		s.LiveSearchMinLength = def.LiveSearchMinLength
+		fixApplied = true
+	}
+
+	// AUR voting added with 1.8.3
+	if s.AurSshCommand == "" {
+		s.AurSshCommand = def.AurSshCommand
+		fixApplied = true
+	}
+
+	// Screenshot graphics added with 1.8.3
+	if s.ScreenshotGraphics == "" {
+		s.ScreenshotGraphics = def.ScreenshotGraphics
+		fixApplied = true
+	}
+
+	// Download commands added with 1.8.3
+	if s.DownloadCommand == "" {
+		s.DownloadCommand = def.DownloadCommand
+		fixApplied = true
+	}
+	if s.AurDownloadCommand == "" {
+		s.AurDownloadCommand = def.AurDownloadCommand
+		fixApplied = true
+	}
+
+	// .pacnew merging added with 1.8.3
+	if s.MergeCommand == "" {
+		s.MergeCommand = def.MergeCommand
+		fixApplied = true
+	}
+
+	// Logging added with 1.8.3
+	if s.LogLevel == "" {
+		s.LogLevel = def.LogLevel
+		fixApplied = true
+	}
+
 	// save config file when we applied changes
 	if fixApplied {
 		s.Save()
@@ -0,0 +1,146 @@
+package pacseek
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Jguer/go-alpm/v2"
+)
+
+// advisoryTimeout is the timeout for requests to the security tracker
+const advisoryTimeout = 10 * time.Second
+
+// UrlAdvisory is the security tracker page of an issue group
+const UrlAdvisory = "https://security.archlinux.org/%s"
+
+// severities of the security tracker, from most to least severe
+var advisorySeverities = []string{"Critical", "High", "Medium", "Low", "Unknown"}
+
+// advisory is an issue group (AVG) of the Arch Security Tracker
+type advisory struct {
+	Name     string   `json:"name"`
+	Packages []string `json:"packages"`
+	Status   string   `json:"status"`
+	Severity string   `json:"severity"`
+	Type     string   `json:"type"`
+	Affected string   `json:"affected"`
+	Fixed    string   `json:"fixed"`
+	Issues   []string `json:"issues"`
+}
+
+// vulnerability is an advisory that affects an installed package
+type vulnerability struct {
+	advisory
+	Package          string
+	InstalledVersion string
+}
+
+// retrieves all issue groups from the security tracker
+func getAdvisories(url string) ([]advisory, error) {
+	client := http.Client{
+		Timeout: advisoryTimeout,
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "pacseek/"+version)
+
+	r, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("security tracker returned status %d", r.StatusCode)
+	}
+
+	advisories := []advisory{}
+	err = json.NewDecoder(r.Body).Decode(&advisories)
+	if err != nil {
+		return nil, err
+	}
+	return advisories, nil
+}
+
+// returns the advisories affecting the installed packages ("installed" maps names to versions), by package name
+// a package is affected when its version is older than the fixed one (or when there is no fix yet)
+// the affected version of the tracker is the one the issue was found in and not the first affected one, so it isn't checked
+func vulnerablePackages(advisories []advisory, installed map[string]string) map[string][]vulnerability {
+	vulnerable := map[string][]vulnerability{}
+	for _, a := range advisories {
+		if a.Status == "Not affected" {
+			continue
+		}
+		for _, name := range a.Packages {
+			version, ok := installed[name]
+			if !ok || (a.Fixed != "" && alpm.VerCmp(version, a.Fixed) >= 0) {
+				continue
+			}
+			vulnerable[name] = append(vulnerable[name], vulnerability{advisory: a, Package: name, InstalledVersion: version})
+		}
+	}
+	return vulnerable
+}
+
+// returns the security advisories affecting our installed packages
+// the advisories of the security tracker are cached like our package information
+func (ps *UI) getVulnerabilities() (map[string][]vulnerability, error) {
+	if ps.conf.DisableAdvisories {
+		return map[string][]vulnerability{}, nil
+	}
+
+	var advisories []advisory
+	if cached, found := ps.cacheInfo.Get("#advisories#"); found {
+		advisories = cached.([]advisory)
+	} else {
+		var err error
+		advisories, err = getAdvisories(ps.conf.SecurityTrackerUrl)
+		if err != nil {
+			return nil, err
+		}
+		if !ps.conf.DisableCache {
+			ps.cacheInfo.Set("#advisories#", advisories, time.Duration(ps.conf.CacheExpiry)*time.Minute)
+		}
+	}
+
+	installed, err := installedSet(ps.handle())
+	if err != nil {
+		return nil, err
+	}
+	return vulnerablePackages(advisories, installed), nil
+}
+
+// returns all vulnerabilities ordered by severity and package name
+func sortedVulnerabilities(vulnerable map[string][]vulnerability) []vulnerability {
+	all := []vulnerability{}
+	for _, v := range vulnerable {
+		all = append(all, v...)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		si, sj := severityRank(all[i].Severity), severityRank(all[j].Severity)
+		if si != sj {
+			return si < sj
+		}
+		if all[i].Package != all[j].Package {
+			return all[i].Package < all[j].Package
+		}
+		return all[i].Name < all[j].Name
+	})
+	return all
+}
+
+// returns the position of a severity in advisorySeverities (unknown ones come last)
+func severityRank(severity string) int {
+	for i, s := range advisorySeverities {
+		if s == severity {
+			return i
+		}
+	}
+	return len(advisorySeverities)
+}
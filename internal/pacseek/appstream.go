@@ -0,0 +1,192 @@
+package pacseek
+
+import (
+	"compress/gzip"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// the directories of the appstream catalogs (archlinux-appstream-data), older versions used app-info
+var appstreamDirs = []string{"/usr/share/swcatalog/xml", "/usr/share/app-info/xmls"}
+
+// appstreamComponent holds the AppStream metadata of an application
+type appstreamComponent struct {
+	ID          string
+	Name        string
+	Summary     string
+	Categories  []string
+	Screenshots []string // image URLs, the default screenshot comes first
+}
+
+// a component of an appstream catalog, names and summaries exist in multiple languages (xml:lang)
+type appstreamXMLComponent struct {
+	Type    string `xml:"type,attr"`
+	ID      string `xml:"id"`
+	Package string `xml:"pkgname"`
+	Names   []struct {
+		Lang  string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"name"`
+	Summaries []struct {
+		Lang  string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"summary"`
+	Categories  []string `xml:"categories>category"`
+	Screenshots []struct {
+		Type   string `xml:"type,attr"`
+		Images []struct {
+			Type  string `xml:"type,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"image"`
+	} `xml:"screenshots>screenshot"`
+}
+
+// parses an appstream catalog and returns the GUI applications by package name
+func parseAppstream(r io.Reader) (map[string]appstreamComponent, error) {
+	components := map[string]appstreamComponent{}
+	dec := xml.NewDecoder(r)
+	for {
+		t, err := dec.Token()
+		if err == io.EOF {
+			return components, nil
+		}
+		if err != nil {
+			return components, err
+		}
+		start, ok := t.(xml.StartElement)
+		if !ok || start.Name.Local != "component" {
+			continue
+		}
+		var c appstreamXMLComponent
+		if err := dec.DecodeElement(&c, &start); err != nil {
+			return components, err
+		}
+		if (c.Type != "desktop-application" && c.Type != "desktop") || c.Package == "" {
+			continue
+		}
+		comp := appstreamComponent{
+			ID:         c.ID,
+			Categories: c.Categories,
+		}
+		for _, n := range c.Names {
+			if n.Lang == "" {
+				comp.Name = strings.TrimSpace(n.Value)
+			}
+		}
+		for _, s := range c.Summaries {
+			if s.Lang == "" {
+				comp.Summary = strings.TrimSpace(s.Value)
+			}
+		}
+		for _, s := range c.Screenshots {
+			for _, img := range s.Images {
+				// the source image is the original one, the others are thumbnails
+				if img.Type != "source" {
+					continue
+				}
+				if s.Type == "default" {
+					comp.Screenshots = append([]string{strings.TrimSpace(img.Value)}, comp.Screenshots...)
+				} else {
+					comp.Screenshots = append(comp.Screenshots, strings.TrimSpace(img.Value))
+				}
+			}
+		}
+		// some packages ship multiple applications, we keep the first one
+		if _, found := components[c.Package]; !found {
+			components[c.Package] = comp
+		}
+	}
+}
+
+// reads an appstream catalog file (gzip compressed or plain xml)
+func readAppstreamFile(file string) (map[string]appstreamComponent, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(file, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return parseAppstream(r)
+}
+
+// appstream holds the AppStream metadata of the applications in our repositories
+// a nil appstream is valid and doesn't return anything (e.g. when disabled)
+type appstream struct {
+	mu         sync.RWMutex
+	components map[string]appstreamComponent
+}
+
+// reads all catalogs of the first directory that has some, returns the number of applications we found
+func (a *appstream) load(dirs []string) int {
+	components := map[string]appstreamComponent{}
+	for _, dir := range dirs {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.xml*"))
+		for _, file := range files {
+			// catalogs we can't read are skipped
+			c, err := readAppstreamFile(file)
+			if err != nil {
+				continue
+			}
+			for pkg, comp := range c {
+				components[pkg] = comp
+			}
+		}
+		if len(components) > 0 {
+			break
+		}
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.components = components
+	return len(components)
+}
+
+// returns the AppStream metadata of a package
+func (a *appstream) get(pkg string) (appstreamComponent, bool) {
+	if a == nil {
+		return appstreamComponent{}, false
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	c, ok := a.components[pkg]
+	return c, ok
+}
+
+// loads the appstream catalogs in the background (they are rather large)
+func (ps *UI) loadAppstream() {
+	if ps.conf.DisableAppStream {
+		return
+	}
+	go ps.appstream.load(appstreamDirs)
+}
+
+// adds the AppStream metadata of repository packages to our details fields
+func (ps *UI) appstreamFields(i InfoRecord, fields map[string]string) {
+	if ps.conf.DisableAppStream || i.Source == "AUR" || findSource(ps.sources, i.Source) != nil {
+		return
+	}
+	c, ok := ps.appstream.get(i.Name)
+	if !ok {
+		return
+	}
+	if c.Summary != "" && !strings.EqualFold(c.Summary, i.Description) {
+		fields["Summary"] = c.Summary
+	}
+	fields["Categories"] = strings.Join(c.Categories, ", ")
+	if len(c.Screenshots) > 0 {
+		fields["Screenshot URL"] = c.Screenshots[0]
+	}
+}
@@ -1,28 +1,81 @@
 package pacseek
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/url"
+	"path"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/Jguer/go-alpm/v2"
 )
 
 // calls the AUR rpc API (suggest type) and returns found packages (beginning with "term")
 func searchAur(aurUrl, term string, timeout int, mode string, by string, maxResults int) ([]Package, error) {
+	return searchAurCtx(context.Background(), aurUrl, term, timeout, mode, by, maxResults)
+}
+
+// same as searchAur, but the request is aborted when our context is cancelled
+func searchAurCtx(ctx context.Context, aurUrl, term string, timeout int, mode string, by string, maxResults int) ([]Package, error) {
 	packages := []Package{}
+
+	// exact lookups can be done with the info endpoint directly (faster than searching & filtering)
+	if mode == "Exact" && by != "Provides" {
+		info := infoAur(aurUrl, timeout, term)
+		if info.Error != "" {
+			return packages, errors.New(info.Error)
+		}
+		for _, pkg := range info.Results {
+			packages = append(packages, Package{
+				Name:         pkg.Name,
+				Source:       "AUR",
+				LastModified: pkg.LastModified,
+				Popularity:   pkg.Popularity,
+				NumVotes:     pkg.NumVotes,
+				Orphaned:     pkg.Maintainer == "",
+				OutOfDate:    pkg.OutOfDate,
+				Description:  pkg.Description,
+			})
+		}
+		return packages, nil
+	}
 	client := http.Client{
-		Timeout: time.Millisecond * time.Duration(timeout),
+		Timeout:   time.Millisecond * time.Duration(timeout),
+		Transport: aurTransport,
+	}
+
+	// the AUR can't search with regular expressions, we search for a literal part of it and filter the results
+	query := term
+	var re *regexp.Regexp
+	if mode == "Regex" {
+		var err error
+		if re, err = compileSearchRegex(term, true); err != nil {
+			return packages, err
+		}
+		if query = requiredLiteral(term); len(query) < 2 {
+			return packages, errors.New("regular expression needs to contain at least 2 literal characters for AUR searches")
+		}
 	}
 
 	t := "search"
 	if by == "Name" {
 		t = "search&by=name"
+	} else if by == "Keywords" {
+		t = "search&by=keywords"
+	} else if by == "Maintainer" {
+		// the AUR only supports exact matches (user name) for maintainer searches
+		t = "search&by=maintainer"
+	} else if by == "Provides" {
+		// same for provides, the AUR returns the packages providing exactly our search term
+		t = "search&by=provides"
 	}
 
-	req, err := http.NewRequest("GET", aurUrl+"?v=5&type="+t+"&arg="+url.QueryEscape(term), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", aurUrl+"?v=5&type="+t+"&arg="+url.QueryEscape(query), nil)
 	if err != nil {
 		return packages, err
 	}
@@ -45,6 +98,7 @@ func searchAur(aurUrl, term string, timeout int, mode string, by string, maxResu
 	if s.Error != "" {
 		return packages, errors.New(s.Error)
 	}
+	s.removeInvalid()
 
 	// we need to sort our results here. The official aurweb /rpc endpoint is not ordering by name...
 	sort.Slice(s.Results, func(i, j int) bool {
@@ -55,13 +109,19 @@ func searchAur(aurUrl, term string, timeout int, mode string, by string, maxResu
 	for _, pkg := range s.Results {
 		// filter records
 		if (mode == "StartsWith" && by == "Name" && strings.HasPrefix(pkg.Name, term)) ||
-			(mode == "StartsWith" && by != "Name" && (strings.HasPrefix(pkg.Name, term) || strings.HasPrefix(strings.ToLower(pkg.Description), term))) ||
-			mode == "Contains" {
+			(mode == "StartsWith" && by == "Keywords" && keywordHasPrefix(pkg.Keywords, term)) ||
+			(mode == "StartsWith" && by != "Name" && by != "Keywords" && by != "Maintainer" && by != "Provides" && (strings.HasPrefix(pkg.Name, term) || strings.HasPrefix(strings.ToLower(pkg.Description), term))) ||
+			(mode == "Regex" && aurRegexMatches(pkg, re, by)) ||
+			mode == "Contains" || mode == "Fuzzy" || ((by == "Maintainer" || by == "Provides") && mode != "Regex") {
 			packages = append(packages, Package{
 				Name:         pkg.Name,
 				Source:       "AUR",
 				LastModified: pkg.LastModified,
 				Popularity:   pkg.Popularity,
+				NumVotes:     pkg.NumVotes,
+				Orphaned:     pkg.Maintainer == "",
+				OutOfDate:    pkg.OutOfDate,
+				Description:  pkg.Description,
 			})
 			if len(packages) >= maxResults {
 				break
@@ -70,13 +130,79 @@ func searchAur(aurUrl, term string, timeout int, mode string, by string, maxResu
 		}
 	}
 
+	// rank fuzzy matches, so that the closest ones come first
+	if mode == "Fuzzy" {
+		packages = rankFuzzy(packages, term, maxResults)
+	}
+
 	return packages, nil
 }
 
+// checks if a regular expression matches the searched field(s) of an AUR package
+func aurRegexMatches(pkg InfoRecord, re *regexp.Regexp, by string) bool {
+	switch by {
+	case "Name":
+		return re.MatchString(pkg.Name)
+	case "Keywords":
+		for _, k := range pkg.Keywords {
+			if re.MatchString(k) {
+				return true
+			}
+		}
+		return false
+	case "Maintainer":
+		return re.MatchString(pkg.Maintainer)
+	}
+	return re.MatchString(pkg.Name) || re.MatchString(pkg.Description)
+}
+
+// removes packages matching any of the (glob) patterns, e.g. "*-git"
+func filterIgnoredAur(pkgs []Package, patterns []string) []Package {
+	if len(patterns) == 0 {
+		return pkgs
+	}
+	filtered := []Package{}
+	for _, pkg := range pkgs {
+		ignored := false
+		for _, pattern := range patterns {
+			if match, _ := path.Match(pattern, pkg.Name); match {
+				ignored = true
+				break
+			}
+		}
+		if !ignored {
+			filtered = append(filtered, pkg)
+		}
+	}
+	return filtered
+}
+
+// removes packages that have been flagged out of date
+func filterOutOfDate(pkgs []Package) []Package {
+	filtered := []Package{}
+	for _, pkg := range pkgs {
+		if pkg.OutOfDate == 0 {
+			filtered = append(filtered, pkg)
+		}
+	}
+	return filtered
+}
+
+// checks if any of the keywords starts with "term"
+func keywordHasPrefix(keywords []string, term string) bool {
+	for _, k := range keywords {
+		if strings.HasPrefix(strings.ToLower(k), term) {
+			return true
+		}
+	}
+	return false
+}
+
 // calls the AUR rpc API (info type) and returns package information
 func infoAur(aurUrl string, timeout int, pkg ...string) SearchResults {
 	client := http.Client{
-		Timeout: time.Millisecond * time.Duration(timeout),
+		Timeout:   time.Millisecond * time.Duration(timeout),
+		Transport: aurTransport,
 	}
 
 	data := url.Values{}
@@ -106,6 +232,7 @@ func infoAur(aurUrl string, timeout int, pkg ...string) SearchResults {
 	if err != nil {
 		return SearchResults{Error: err.Error()}
 	}
+	p.removeInvalid()
 	for i := 0; i < len(p.Results); i++ {
 		p.Results[i].Source = "AUR"
 	}
@@ -117,7 +244,8 @@ func infoAur(aurUrl string, timeout int, pkg ...string) SearchResults {
 func suggestAur(aurUrl, term string, timeout int) []string {
 	packages := []string{}
 	client := http.Client{
-		Timeout: time.Millisecond * time.Duration(timeout),
+		Timeout:   time.Millisecond * time.Duration(timeout),
+		Transport: aurTransport,
 	}
 
 	req, err := http.NewRequest("GET", aurUrl+"?v=5&type=suggest&arg="+url.PathEscape(term), nil)
@@ -140,3 +268,47 @@ func suggestAur(aurUrl, term string, timeout int) []string {
 
 	return packages
 }
+
+// returns installed packages that don't exist in the repositories and have a newer version in the AUR
+// this is used to upgrade AUR packages only (without upgrading repository packages)
+func aurOnlyUpgrades(h dbHandle, aurUrl string, timeout int) ([]Upgrade, error) {
+	upgrades := []Upgrade{}
+
+	if h == nil {
+		return upgrades, errors.New("alpm handle is nil")
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return upgrades, err
+	}
+
+	_, nf := getUpgradable(h, UpgradeOptions{}, IgnoreRules{})
+	if len(nf) == 0 {
+		return upgrades, nil
+	}
+
+	aurPkgs := infoAur(aurUrl, timeout, packageNames(nf)...)
+	if aurPkgs.Error != "" {
+		return upgrades, errors.New(aurPkgs.Error)
+	}
+	infos := map[string]InfoRecord{}
+	for _, aurPkg := range aurPkgs.Results {
+		infos[aurPkg.Name] = aurPkg
+	}
+
+	for _, pkg := range nf {
+		aurPkg, ok := infos[pkg.Name]
+		lpkg := local.Pkg(pkg.Name)
+		if !ok || lpkg == nil || alpm.VerCmp(aurPkg.Version, lpkg.Version()) <= 0 {
+			continue
+		}
+		aurPkg.LocalVersion = lpkg.Version()
+		upgrades = append(upgrades, Upgrade{
+			InfoRecord: aurPkg,
+			Status:     "upgrade",
+			EpochBump:  versionEpoch(aurPkg.Version) > versionEpoch(lpkg.Version()),
+		})
+	}
+
+	return upgrades, nil
+}
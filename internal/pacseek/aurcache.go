@@ -0,0 +1,202 @@
+package pacseek
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moson-mo/pacseek/internal/config"
+)
+
+// diskCache stores responses of the AUR RPC as JSON files (one per key), so that they survive restarts
+// expired entries are still used when the AUR can't be reached
+// a nil cache is valid and does not cache anything (e.g. when disabled)
+type diskCache struct {
+	dir string
+	ttl time.Duration
+}
+
+// creates our disk cache for AUR responses (~/.cache/pacseek/aur), nil when it is disabled or there's no cache directory
+func newAurDiskCache(conf *config.Settings) *diskCache {
+	if conf.DisableDiskCache {
+		return nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil
+	}
+	return &diskCache{
+		dir: filepath.Join(dir, "pacseek", "aur"),
+		ttl: time.Duration(conf.DiskCacheExpiry) * time.Minute,
+	}
+}
+
+// returns the key (file name) for an entry
+func (c *diskCache) key(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// reads an entry into "v", expired ones only if "stale" is set
+func (c *diskCache) get(key string, v interface{}, stale bool) bool {
+	if c == nil {
+		return false
+	}
+	file := filepath.Join(c.dir, key+".json")
+	fi, err := os.Stat(file)
+	if err != nil || (!stale && time.Since(fi.ModTime()) > c.ttl) {
+		return false
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(b, v) == nil
+}
+
+// stores an entry, the file is replaced atomically so that concurrent reads never see partial data
+func (c *diskCache) set(key string, v interface{}) error {
+	if c == nil {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.dir, key+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(c.dir, key+".json"))
+}
+
+// removes all entries
+func (c *diskCache) clear() error {
+	if c == nil {
+		return nil
+	}
+	return os.RemoveAll(c.dir)
+}
+
+// same as searchAur, but the results are cached on disk
+// if the AUR can't be reached, we fall back to expired results
+func cachedSearchAur(c *diskCache, aurUrl, term string, timeout int, mode string, by string, maxResults int) ([]Package, error) {
+	return cachedSearchAurCtx(context.Background(), c, aurUrl, term, timeout, mode, by, maxResults)
+}
+
+// same as cachedSearchAur, but the request is aborted when our context is cancelled
+func cachedSearchAurCtx(ctx context.Context, c *diskCache, aurUrl, term string, timeout int, mode string, by string, maxResults int) ([]Package, error) {
+	packages, cached, err := cachedSearchAurStale(ctx, c, aurUrl, term, timeout, mode, by, maxResults)
+	if cached {
+		return packages, nil
+	}
+	return packages, err
+}
+
+// same as cachedSearchAurCtx, but the error of the AUR is returned together with expired results
+// "cached" tells if the results are coming from our cache rather than the AUR
+func cachedSearchAurStale(ctx context.Context, c *diskCache, aurUrl, term string, timeout int, mode string, by string, maxResults int) ([]Package, bool, error) {
+	key := aurSearchKey(c, aurUrl, term, mode, by, maxResults)
+	packages := []Package{}
+	if c.get(key, &packages, false) {
+		return packages, true, nil
+	}
+
+	packages, err := searchAurCtx(ctx, aurUrl, term, timeout, mode, by, maxResults)
+	if err != nil {
+		stale := []Package{}
+		if ctx.Err() == nil && c.get(key, &stale, true) {
+			return stale, true, err
+		}
+		return packages, false, err
+	}
+	c.set(key, packages)
+	return packages, false, nil
+}
+
+// returns the cached results of an AUR search (expired ones as well) without contacting the AUR
+func offlineSearchAur(c *diskCache, aurUrl, term string, mode string, by string, maxResults int) ([]Package, bool) {
+	packages := []Package{}
+	if !c.get(aurSearchKey(c, aurUrl, term, mode, by, maxResults), &packages, true) {
+		return []Package{}, false
+	}
+	return packages, true
+}
+
+// returns the key of an AUR search in our disk cache
+func aurSearchKey(c *diskCache, aurUrl, term string, mode string, by string, maxResults int) string {
+	return c.key("search", aurUrl, mode, by, strconv.Itoa(maxResults), term)
+}
+
+// returns the cached package information of AUR packages (expired ones as well) without contacting the AUR
+// packages that are not in our cache are missing in the results
+func offlineInfoAur(c *diskCache, aurUrl string, pkgs ...string) SearchResults {
+	sr := SearchResults{Results: []InfoRecord{}}
+	for _, name := range pkgs {
+		var r InfoRecord
+		if c.get(c.key("info", aurUrl, name), &r, true) {
+			sr.Results = append(sr.Results, r)
+		}
+	}
+	sr.Resultcount = len(sr.Results)
+	return sr
+}
+
+// same as infoAur, but the package information is cached on disk (per package)
+// only the packages that are not cached (or expired) are requested, expired ones are used when the AUR can't be reached
+func cachedInfoAur(c *diskCache, aurUrl string, timeout int, pkgs ...string) SearchResults {
+	if c == nil {
+		return infoAur(aurUrl, timeout, pkgs...)
+	}
+
+	sr := SearchResults{Results: []InfoRecord{}}
+	missing := []string{}
+	for _, name := range pkgs {
+		var r InfoRecord
+		if c.get(c.key("info", aurUrl, name), &r, false) {
+			sr.Results = append(sr.Results, r)
+		} else {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		sr.Resultcount = len(sr.Results)
+		return sr
+	}
+
+	fetched := infoAur(aurUrl, timeout, missing...)
+	if fetched.Error != "" {
+		for _, name := range missing {
+			var r InfoRecord
+			if !c.get(c.key("info", aurUrl, name), &r, true) {
+				return fetched
+			}
+			sr.Results = append(sr.Results, r)
+		}
+		sr.Resultcount = len(sr.Results)
+		return sr
+	}
+	for _, r := range fetched.Results {
+		c.set(c.key("info", aurUrl, r.Name), r)
+		sr.Results = append(sr.Results, r)
+	}
+	sr.Resultcount = len(sr.Results)
+	return sr
+}
@@ -0,0 +1,273 @@
+package pacseek
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/moson-mo/pacseek/internal/config"
+	"github.com/rivo/tview"
+)
+
+// name of the file in our config directory containing the session cookie (AURSID) of the AUR web interface
+const aurSessionFileName = "aur-session"
+
+// aurAccount votes for AUR packages through the SSH interface of aurweb (authenticated with our SSH key)
+// and retrieves the packages we voted for from the web interface (authenticated with our session cookie)
+type aurAccount struct {
+	sshCommand string                                            // e.g. "ssh aur@aur.archlinux.org"
+	run        func(name string, args ...string) ([]byte, error) // runs a command and returns its (combined) output
+	votedUrl   string
+	session    string // AURSID cookie, empty if we don't have one
+}
+
+// creates an AUR account with the SSH command of our configuration
+// the session cookie is read from our session file (if it exists)
+func newAurAccount(conf *config.Settings) (*aurAccount, error) {
+	a := &aurAccount{
+		sshCommand: conf.AurSshCommand,
+		run: func(name string, args ...string) ([]byte, error) {
+			return exec.Command(name, args...).CombinedOutput()
+		},
+		votedUrl: UrlAurVoted,
+	}
+	file, err := aurSessionFile()
+	if err != nil {
+		return a, err
+	}
+	a.session, err = readAurSession(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return a, nil
+	}
+	return a, err
+}
+
+// returns the path of our session file
+func aurSessionFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "pacseek", aurSessionFileName), nil
+}
+
+// reads the session cookie from a file, either as "AURSID=<value>" or just the value
+// the file must not be accessible by other users, since the cookie grants access to our AUR account
+func readAurSession(file string) (string, error) {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return "", err
+	}
+	if fi.Mode().Perm()&0077 != 0 {
+		return "", fmt.Errorf("%s must only be accessible by you (chmod 600 %s)", file, file)
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(strings.TrimSpace(string(b)), "AURSID="), nil
+}
+
+// votes for a package base, or removes our vote with "unvote"
+func (a *aurAccount) vote(base string, unvote bool) error {
+	action := "vote"
+	if unvote {
+		action = "unvote"
+	}
+	command := strings.Fields(a.sshCommand)
+	if len(command) == 0 {
+		return errors.New("AUR SSH command is empty")
+	}
+	out, err := a.run(command[0], append(command[1:], action, base)...)
+	if err != nil {
+		msg := strings.TrimSpace(string(out))
+		if msg == "" {
+			msg = err.Error()
+		}
+		return fmt.Errorf("%s failed: %s", action, msg)
+	}
+	return nil
+}
+
+// returns the names of the packages we voted for (the first 250)
+func (a *aurAccount) votedPackages() ([]string, error) {
+	if a.session == "" {
+		return nil, errors.New("no AUR session, put the AURSID cookie of aur.archlinux.org into " + aurSessionFileName + " in pacseek's config directory")
+	}
+	client := http.Client{
+		Timeout: commentsTimeout,
+	}
+
+	req, err := http.NewRequest("GET", a.votedUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "pacseek/"+version)
+	req.AddCookie(&http.Cookie{Name: "AURSID", Value: a.session})
+
+	r, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("AUR returned status %d", r.StatusCode)
+	}
+	return parseVotedPackages(r.Body)
+}
+
+// parses the package list of the AUR web interface and returns the packages with a "Yes" in the "Voted" column
+// without that column, we are not logged in (e.g. our session expired)
+func parseVotedPackages(r io.Reader) ([]string, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+	column := -1
+	doc.Find("table.results thead th").Each(func(i int, th *goquery.Selection) {
+		if strings.TrimSpace(th.Text()) == "Voted" {
+			column = i
+		}
+	})
+	if column == -1 {
+		return nil, errors.New("not logged in to the AUR, your session might have expired")
+	}
+
+	voted := []string{}
+	doc.Find("table.results tbody tr").Each(func(_ int, tr *goquery.Selection) {
+		cells := tr.Find("td")
+		if cells.Length() <= column || strings.TrimSpace(cells.Eq(column).Text()) == "" {
+			return
+		}
+		if name := strings.TrimSpace(cells.First().Find("a").Text()); name != "" {
+			voted = append(voted, name)
+		}
+	})
+	return voted, nil
+}
+
+// asks if we want to vote for the selected AUR package or remove our vote
+func (ps *UI) voteSelectedPackage() {
+	if !ps.conf.AurVoting {
+		ps.displayMessage("AUR voting is disabled, you can enable it in the settings", true)
+		return
+	}
+	if ps.selectedPackage == nil || ps.selectedPackage.Source != "AUR" {
+		ps.displayMessage("Only AUR packages can be voted for", true)
+		return
+	}
+	pkg := *ps.selectedPackage
+	base := pkg.PackageBase
+	if base == "" {
+		base = pkg.Name
+	}
+
+	ps.promptVisible = true
+	prompt := tview.NewModal().
+		AddButtons([]string{"Vote", "Unvote", "Cancel"}).
+		SetText(fmt.Sprintf("Vote for '%s' in the AUR?", base)).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			ps.promptVisible = false
+			ps.app.SetRoot(ps.flexRoot, true)
+			ps.app.SetFocus(ps.tablePackages)
+			if buttonIndex == 0 || buttonIndex == 1 {
+				ps.vote(pkg.Name, base, buttonIndex == 1)
+			}
+		})
+
+	ps.app.SetRoot(prompt, true)
+}
+
+// votes for a package base (or removes our vote) in the background
+func (ps *UI) vote(name, base string, unvote bool) {
+	go func() {
+		ps.locker.Lock()
+		ps.startSpinner()
+		defer func() {
+			ps.locker.Unlock()
+			ps.stopSpinner()
+		}()
+
+		account, _ := newAurAccount(ps.conf)
+		err := account.vote(base, unvote)
+		ps.app.QueueUpdateDraw(func() {
+			if err != nil {
+				ps.displayMessage(err.Error(), true)
+				return
+			}
+			if ps.aurVotes != nil {
+				ps.aurVotes[name] = !unvote
+			}
+			if unvote {
+				ps.displayMessage("Removed vote for "+base, false)
+			} else {
+				ps.displayMessage("Voted for "+base, false)
+			}
+		})
+	}()
+}
+
+// displays the AUR packages we voted for in our package list
+func (ps *UI) displayVotedPackages() {
+	if !ps.conf.AurVoting {
+		ps.displayMessage("AUR voting is disabled, you can enable it in the settings", true)
+		return
+	}
+	ps.tablePackages.Clear().
+		SetCellSimple(0, 0, "Retrieving voted packages, please wait...")
+
+	go func() {
+		ps.locker.Lock()
+		ps.startSpinner()
+		defer func() {
+			ps.locker.Unlock()
+			ps.stopSpinner()
+		}()
+
+		account, err := newAurAccount(ps.conf)
+		names := []string{}
+		if err == nil {
+			names, err = account.votedPackages()
+		}
+		packages := []Package{}
+		if err == nil && len(names) > 0 {
+			installed := areInstalled(ps.handle(), names)
+			for _, info := range ps.getInfo("AUR", names...).Results {
+				packages = append(packages, Package{
+					Name:         info.Name,
+					Source:       "AUR",
+					IsInstalled:  installed[info.Name],
+					LastModified: info.LastModified,
+					Popularity:   info.Popularity,
+					NumVotes:     info.NumVotes,
+					Orphaned:     info.Maintainer == "",
+					OutOfDate:    info.OutOfDate,
+				})
+			}
+		}
+
+		ps.app.QueueUpdateDraw(func() {
+			if err != nil {
+				ps.tablePackages.Clear()
+				ps.displayMessage(err.Error(), true)
+				return
+			}
+			ps.aurVotes = map[string]bool{}
+			for _, name := range names {
+				ps.aurVotes[name] = true
+			}
+			ps.shownPackages = packages
+			ps.searchPaging = searchPaging{}
+			ps.drawPackageListContent(packages, ps.conf.PackageColumnWidth)
+			ps.app.SetFocus(ps.tablePackages)
+			ps.tablePackages.Select(1, 0)
+		})
+	}()
+}
@@ -0,0 +1,143 @@
+package pacseek
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/moson-mo/pacseek/internal/util"
+)
+
+const (
+	UrlRepoCommits = "https://gitlab.archlinux.org/api/v4/projects/archlinux%%2Fpackaging%%2Fpackages%%2F%s/repository/commits?per_page=%d"
+	UrlAurCommits  = "https://api.github.com/repos/archlinux/aur/commits?sha=%s&per_page=%d"
+)
+
+// number of commits we show in the changelog
+const changelogMaxCommits = 25
+
+// changelogEntry is a commit in the git repository of a package
+type changelogEntry struct {
+	Id      string
+	Author  string
+	Date    time.Time
+	Message string
+}
+
+// gitlabCommit is a commit returned by the GitLab API (repo packages)
+type gitlabCommit struct {
+	ShortId    string    `json:"short_id"`
+	Message    string    `json:"message"`
+	AuthorName string    `json:"author_name"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// githubCommit is a commit returned by the GitHub API (AUR mirror)
+type githubCommit struct {
+	Sha    string `json:"sha"`
+	Commit struct {
+		Message string `json:"message"`
+		Author  struct {
+			Name string    `json:"name"`
+			Date time.Time `json:"date"`
+		} `json:"author"`
+	} `json:"commit"`
+}
+
+// composes the URL to the commit history of a package
+// repo packages are maintained on the Arch GitLab, for AUR packages we use the GitHub mirror
+func getChangelogUrl(source, base string) string {
+	if util.SliceContains(getArchRepos(), source) {
+		return fmt.Sprintf(UrlRepoCommits, encodePackageGitlabUrl(base), changelogMaxCommits)
+	}
+	return fmt.Sprintf(UrlAurCommits, base, changelogMaxCommits)
+}
+
+// retrieves the commit history of a package from the GitLab (repo) or GitHub (AUR) API
+func getChangelog(url string, aur bool) ([]changelogEntry, error) {
+	client := http.Client{
+		Timeout: pkgbuildTimeout,
+	}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "pacseek/"+version)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download changelog: %s", resp.Status)
+	}
+
+	entries := []changelogEntry{}
+	if aur {
+		commits := []githubCommit{}
+		if err := json.NewDecoder(resp.Body).Decode(&commits); err != nil {
+			return nil, err
+		}
+		for _, c := range commits {
+			entries = append(entries, changelogEntry{
+				Id:      shortCommitId(c.Sha),
+				Author:  c.Commit.Author.Name,
+				Date:    c.Commit.Author.Date,
+				Message: c.Commit.Message,
+			})
+		}
+		return entries, nil
+	}
+
+	commits := []gitlabCommit{}
+	if err := json.NewDecoder(resp.Body).Decode(&commits); err != nil {
+		return nil, err
+	}
+	for _, c := range commits {
+		entries = append(entries, changelogEntry{
+			Id:      c.ShortId,
+			Author:  c.AuthorName,
+			Date:    c.CreatedAt,
+			Message: c.Message,
+		})
+	}
+	return entries, nil
+}
+
+// reads the changelog file shipped with an installed package (if it has one), like "pacman -Qc"
+func localChangelog(dbPath, name, version string) (string, bool) {
+	b, err := os.ReadFile(filepath.Join(dbPath, "local", name+"-"+version, "changelog"))
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+// formats the changelog of a package: the changelog file of the installed package (if available) followed by the commit history
+func formatChangelog(local string, entries []changelogEntry) string {
+	var sb strings.Builder
+	if local != "" {
+		sb.WriteString(strings.TrimSpace(local) + "\n\n")
+	}
+	for _, e := range entries {
+		sb.WriteString(e.Date.UTC().Format("2006-01-02") + " " + e.Id + " " + e.Author + "\n")
+		for _, line := range strings.Split(strings.TrimSpace(e.Message), "\n") {
+			sb.WriteString(strings.TrimRight("    "+line, " ") + "\n")
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// returns the first 8 characters of a commit hash (like the GitLab short id)
+func shortCommitId(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
@@ -0,0 +1,67 @@
+package pacseek
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+
+	"github.com/moson-mo/pacseek/internal/config"
+	"github.com/moson-mo/pacseek/internal/util"
+)
+
+// tools (from devtools) that build a package in a clean chroot, the first one found in our PATH is used
+var chrootTools = []struct {
+	program string
+	command string
+}{
+	{"pkgctl", "pkgctl build"},
+	{"extra-x86_64-build", "extra-x86_64-build"},
+}
+
+// checks if an AUR package should be built in a clean chroot (globally enabled or enabled for that package)
+func useChroot(conf *config.Settings, pkg InfoRecord, installed bool) bool {
+	if installed || pkg.Source != "AUR" {
+		return false
+	}
+	return conf.AurChrootBuild ||
+		util.SliceContains(conf.AurChrootPackages, pkg.Name) ||
+		(pkg.PackageBase != "" && util.SliceContains(conf.AurChrootPackages, pkg.PackageBase))
+}
+
+// returns the command building a package in a clean chroot
+// a configured command is used as is, otherwise we look for the devtools in our PATH
+func chrootBuildCommand(conf *config.Settings, lookPath func(string) (string, error)) (string, error) {
+	if strings.TrimSpace(conf.AurChrootCommand) != "" {
+		return conf.AurChrootCommand, nil
+	}
+	for _, tool := range chrootTools {
+		if _, err := lookPath(tool.program); err == nil {
+			return tool.command, nil
+		}
+	}
+	return "", errors.New("no tool for building packages in a clean chroot found. Install \"devtools\" or set a chroot build command")
+}
+
+// returns the command for installing an AUR package that is built in a clean chroot:
+// the AUR repository is cloned to a temporary directory, built with "build" and the resulting packages are installed with our downgrade command (pacman -U)
+// it runs in a subshell, so that batches of commands are not affected by changing the directory
+func chrootInstallCommand(conf *config.Settings, build string, pkg InfoRecord) string {
+	base := pkg.PackageBase
+	if base == "" {
+		base = pkg.Name
+	}
+	return "(dir=$(mktemp -d) && git clone https://aur.archlinux.org/" + base + ".git \"$dir\" && cd \"$dir\" && " +
+		build + " && " +
+		withPackages(conf.DowngradeCommand, "$(find . -maxdepth 1 -name '*.pkg.tar.*' ! -name '*.sig')") + ")"
+}
+
+// checks if we can build the AUR packages (that should be built in a chroot) of our queue
+func checkChrootBuild(conf *config.Settings, queue []queuedPackage) error {
+	for _, q := range queue {
+		if useChroot(conf, q.InfoRecord, q.Installed) {
+			_, err := chrootBuildCommand(conf, exec.LookPath)
+			return err
+		}
+	}
+	return nil
+}
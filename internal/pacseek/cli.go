@@ -0,0 +1,180 @@
+package pacseek
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/moson-mo/pacseek/internal/args"
+	"github.com/moson-mo/pacseek/internal/config"
+	"github.com/moson-mo/pacseek/internal/util"
+)
+
+// output formats of our non-interactive mode
+var outputFormats = []string{"json", "csv", "plain"}
+
+// cliResult is a search result printed in non-interactive mode
+type cliResult struct {
+	Name       string  `json:"name"`
+	Version    string  `json:"version"`
+	Source     string  `json:"source"`
+	Installed  bool    `json:"installed"`
+	Popularity float64 `json:"popularity"`
+}
+
+// Search searches the repositories and the AUR (according to our settings) and writes the results to "w"
+// in the output format given with our flags, without starting the UI
+func Search(conf *config.Settings, flags args.Flags, w io.Writer, errw io.Writer) error {
+	if !util.SliceContains(outputFormats, flags.OutputFormat) {
+		return fmt.Errorf("unknown output format '%s', supported: %s", flags.OutputFormat, strings.Join(outputFormats, ", "))
+	}
+	term := flags.SearchTerm
+	if conf.SearchMode != "Regex" && conf.SearchBy != "File" && !conf.CaseSensitive {
+		term = strings.ToLower(term)
+	}
+	term = normalizeSearchTerm(term)
+	if len(term) < 2 {
+		return errors.New("minimum number of characters for the search-term is 2")
+	}
+
+	h, warnings, err := initPacmanDbs(conf.PacmanRootPath, conf.PacmanDbPath, conf.PacmanConfigPath, flags.Repositories, conf.SkipFailingRepos)
+	if err != nil {
+		return err
+	}
+	defer h.Release()
+	for _, warning := range warnings {
+		fmt.Fprintln(errw, warning)
+	}
+	arch, _ := pacmanArchitecture(conf.PacmanConfigPath)
+
+	results, err := searchAll(h, conf, arch, term)
+	if err != nil {
+		return err
+	}
+	b, err := formatResults(results, flags.OutputFormat)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// searches the repositories and the AUR, merges the results (like the UI does) and adds version information
+func searchAll(h dbHandle, conf *config.Settings, arch, term string) ([]cliResult, error) {
+	var packages, localPackages []Package
+	query, err := parseSearchQuery(term, conf.SearchMode)
+	if err != nil {
+		return nil, err
+	}
+	opts := SearchOptions{
+		PreferNameMatches: conf.PreferNameMatches,
+		Arches:            []string{arch, "any"},
+		ExcludeSources:    conf.ExcludeSources,
+		MergeRepos:        !conf.DisableRepoMerge,
+		RepoPriority:      conf.RepoPriority,
+		SegmentPrefix:     conf.SegmentPrefixMatch,
+		CaseInsensitive:   !conf.CaseSensitive,
+		Predicate:         query.predicate(),
+	}
+	if conf.SearchBy == "File" {
+		packages, localPackages, err = searchFiles(h, conf.PacmanRootPath, conf.PacmanDbPath, term, conf.MaxResults, opts)
+	} else {
+		term = query.Term()
+		packages, localPackages, err = searchRepos(h, term, conf.SearchMode, conf.SearchBy, conf.MaxResults, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	aurVersions := map[string]string{}
+	if !conf.DisableAur && !util.SliceContains(conf.ExcludeSources, "aur") && conf.SearchBy != "File" && query.includesAur() {
+		dc := newAurDiskCache(conf)
+		aurPackages, err := cachedSearchAur(dc, conf.AurRpcUrl, query.aurTerm(), conf.AurTimeout, conf.SearchMode, conf.SearchBy, conf.MaxResults)
+		if err != nil {
+			return nil, err
+		}
+		aurPackages = filterIgnoredAur(aurPackages, conf.AurIgnore)
+		installed := areInstalled(h, packageNames(aurPackages))
+		for i := range aurPackages {
+			aurPackages[i].IsInstalled = installed[aurPackages[i].Name]
+		}
+		aurPackages = filterByPredicate(aurPackages, query.aurPredicate(conf.SearchBy))
+		if len(aurPackages) > 0 {
+			info := cachedInfoAur(dc, conf.AurRpcUrl, conf.AurTimeout, packageNames(aurPackages)...)
+			if info.Error != "" {
+				return nil, errors.New(info.Error)
+			}
+			for _, r := range info.Results {
+				aurVersions[r.Name] = r.Version
+			}
+		}
+		packages = append(packages, aurPackages...)
+	}
+	packages = addLocalOnly(packages, localPackages)
+
+	sortSearchResults(packages, conf, term)
+	if len(packages) > conf.MaxResults {
+		packages = packages[:conf.MaxResults]
+	}
+
+	repoVersions := map[string]string{}
+	for _, r := range infoPacman(h, false, packageNames(packages)...).Results {
+		repoVersions[r.Name+"/"+r.Source] = r.Version
+	}
+
+	results := []cliResult{}
+	for _, pkg := range packages {
+		version := repoVersions[pkg.Name+"/"+pkg.Source]
+		popularity := 0.0
+		if pkg.Source == "AUR" {
+			version = aurVersions[pkg.Name]
+			popularity = pkg.Popularity
+		}
+		results = append(results, cliResult{
+			Name:       pkg.Name,
+			Version:    version,
+			Source:     pkg.Source,
+			Installed:  pkg.IsInstalled,
+			Popularity: popularity,
+		})
+	}
+	return results, nil
+}
+
+// formats search results as "json", "csv" (with header row) or "plain" text ("source/name version [installed]", like pacman -Ss)
+func formatResults(results []cliResult, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		b, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(b, '\n'), nil
+	case "csv":
+		rows := [][]string{{"Name", "Version", "Source", "Installed", "Popularity"}}
+		for _, r := range results {
+			rows = append(rows, []string{
+				r.Name,
+				r.Version,
+				r.Source,
+				strconv.FormatBool(r.Installed),
+				strconv.FormatFloat(r.Popularity, 'f', -1, 64),
+			})
+		}
+		return writeCSV(rows)
+	case "plain":
+		var sb strings.Builder
+		for _, r := range results {
+			sb.WriteString(r.Source + "/" + r.Name + " " + r.Version)
+			if r.Installed {
+				sb.WriteString(" [installed]")
+			}
+			sb.WriteString("\n")
+		}
+		return []byte(sb.String()), nil
+	}
+	return nil, fmt.Errorf("unknown output format '%s'", format)
+}
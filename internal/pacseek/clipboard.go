@@ -0,0 +1,133 @@
+package pacseek
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// maximum time a clipboard tool may take to read our text
+const clipboardTimeout = 2 * time.Second
+
+// the clipboard tools we can use, Wayland ones first
+var clipboardTools = []struct {
+	env     string // the tool needs this variable to be set (the display)
+	program string
+	args    []string
+}{
+	{"WAYLAND_DISPLAY", "wl-copy", nil},
+	{"DISPLAY", "xclip", []string{"-selection", "clipboard"}},
+	{"DISPLAY", "xsel", []string{"--clipboard", "--input"}},
+}
+
+// returns the command for copying text to the clipboard (reading it from stdin), nil if there's none
+// without a display (e.g. ssh sessions), we need to fall back to OSC 52
+func clipboardCommand(getenv func(string) string, lookPath func(string) (string, error)) []string {
+	for _, tool := range clipboardTools {
+		if getenv(tool.env) == "" {
+			continue
+		}
+		if _, err := lookPath(tool.program); err == nil {
+			return append([]string{tool.program}, tool.args...)
+		}
+	}
+	return nil
+}
+
+// returns the OSC 52 escape sequence which makes the terminal copy text to the clipboard
+// tmux and screen only pass it through to the terminal when it's wrapped
+func osc52(text string, getenv func(string) string) string {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	if getenv("TMUX") != "" {
+		return "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	if strings.HasPrefix(getenv("TERM"), "screen") {
+		return "\x1bP" + seq + "\x1b\\"
+	}
+	return seq
+}
+
+// copies text to the clipboard with one of our clipboard tools or OSC 52
+// this has to run in our event loop, the escape sequence must not interfere with drawing the screen
+// xclip / wl-copy keep running in the background (serving the clipboard), so their output must not be a pipe we wait for
+func copyToClipboard(text string) error {
+	if cmd := clipboardCommand(os.Getenv, exec.LookPath); cmd != nil {
+		stderr, err := os.CreateTemp("", "pacseek-clipboard-")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(stderr.Name())
+		defer stderr.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), clipboardTimeout)
+		defer cancel()
+		c := exec.CommandContext(ctx, cmd[0], cmd[1:]...)
+		c.Stdin = strings.NewReader(text)
+		c.Stderr = stderr
+		if err := c.Run(); err != nil {
+			out, _ := os.ReadFile(stderr.Name())
+			return fmt.Errorf("%s failed: %s", cmd[0], strings.TrimSpace(string(out)+" "+err.Error()))
+		}
+		return nil
+	}
+	_, err := os.Stdout.WriteString(osc52(text, os.Getenv))
+	return err
+}
+
+// the things we can copy for the selected package (actions of our key map)
+var clipboardActions = map[string]string{
+	"CopyName":       "package name",
+	"CopyURL":        "URL",
+	"CopyPackageURL": "package URL",
+	"CopyCommand":    "command",
+	"CopyInfo":       "package info",
+}
+
+// returns the text of a clipboard action for a package
+func (ps *UI) clipboardText(action string, pkg InfoRecord) (string, error) {
+	switch action {
+	case "CopyName":
+		return pkg.Name, nil
+	case "CopyURL":
+		if pkg.URL == "" {
+			return "", errors.New(pkg.Name + " doesn't have an upstream URL")
+		}
+		return pkg.URL, nil
+	case "CopyPackageURL":
+		fields, _ := ps.getDetailFields(pkg)
+		if fields["Package URL"] == "" {
+			return "", errors.New(pkg.Name + " doesn't have a package page")
+		}
+		return fields["Package URL"], nil
+	case "CopyCommand":
+		return ps.commandFor(pkg, pkg.LocalVersion != ""), nil
+	case "CopyInfo":
+		return FormatInfoText(pkg), nil
+	}
+	return "", fmt.Errorf("unknown clipboard action %s", action)
+}
+
+// copies the name, URL, package URL, install command or info (as text) of the selected package to the clipboard
+func (ps *UI) copySelected(action string) {
+	if ps.selectedPackage == nil {
+		return
+	}
+	text, err := ps.clipboardText(action, *ps.selectedPackage)
+	if err == nil {
+		err = copyToClipboard(text)
+	}
+	if err != nil {
+		ps.displayMessage(err.Error(), true)
+		return
+	}
+	msg := "Copied " + clipboardActions[action] + " to the clipboard"
+	if !strings.Contains(text, "\n") {
+		msg += ": " + text
+	}
+	ps.displayMessage(msg, false)
+}
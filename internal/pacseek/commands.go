@@ -2,46 +2,317 @@ package pacseek
 
 import (
 	"bufio"
+	"fmt"
 	"os"
 	"os/exec"
 	"os/signal"
+	"regexp"
 	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/moson-mo/pacseek/internal/config"
+	"github.com/moson-mo/pacseek/internal/util"
+	"github.com/rivo/tview"
 )
 
+// queuedPackage is a package that has been marked for a batch install / removal
+// Installed packages are removed, all others are installed
+type queuedPackage struct {
+	InfoRecord
+	Installed bool
+}
+
 // installs or removes a package
 func (ps *UI) installPackage(pkg InfoRecord, installed bool) {
-	// set command based on source and install status
-	command := ps.conf.InstallCommand
-	if installed {
-		command = ps.conf.UninstallCommand
-	} else if pkg.Source == "AUR" && ps.conf.AurUseDifferentCommands && ps.conf.AurInstallCommand != "" {
-		command = ps.conf.AurInstallCommand
+	// Here I'm assuming -c is the argument for passing a command to the shell
+	// This might not be valid for all of em though.
+	command := ps.commandFor(pkg, installed)
+	if err := ps.preflight(command); err != nil {
+		ps.displayMessage(err.Error(), true)
+		return
+	}
+	if err := checkChrootBuild(ps.conf, []queuedPackage{{InfoRecord: pkg, Installed: installed}}); err != nil {
+		ps.displayMessage(err.Error(), true)
+		return
 	}
 
-	// if our command contains {pkg}, replace it with the package name, otherwise concat it
-	if strings.Contains(command, "{pkg}") {
-		command = strings.Replace(command, "{pkg}", pkg.Name, -1)
-	} else {
-		command += " " + pkg.Name
+	// removals are previewed with the removal strategy of our choice
+	if installed && findSource(ps.sources, pkg.Source) == nil {
+		ps.previewRemoval([]string{pkg.Name}, func(flags string) {
+			ps.runTransaction(withRemovalFlags(command, flags))
+
+			// update package install status
+			ps.updateInstalledState()
+		})
+		return
+	}
+	ps.previewInstall(repoInstallTargets(ps.sources, []queuedPackage{{InfoRecord: pkg, Installed: installed}}), func() {
+		ps.runTransaction(command)
+
+		// update package install status
+		ps.updateInstalledState()
+	})
+}
+
+// returns the command for installing / removing a package, additional sources (e.g. Flatpak) have their own ones
+func (ps *UI) commandFor(pkg InfoRecord, installed bool) string {
+	if s := findSource(ps.sources, pkg.Source); s != nil {
+		if installed {
+			return s.UninstallCommand(pkg)
+		}
+		return s.InstallCommand(pkg)
 	}
+	return packageCommand(ps.conf, pkg, installed)
+}
 
-	// replace optdepends
-	command = strings.Replace(command, "{optdepends}", strings.Join(pkg.OptDepends, " "), -1)
+// returns the command for installing / removing a package
+func packageCommand(conf *config.Settings, pkg InfoRecord, installed bool) string {
+	if useChroot(conf, pkg, installed) {
+		build, _ := chrootBuildCommand(conf, exec.LookPath)
+		return chrootInstallCommand(conf, build, pkg)
+	}
+
+	// set command based on source and install status
+	command := conf.InstallCommand
+	if installed {
+		command = conf.UninstallCommand
+	} else if pkg.Source == "AUR" && conf.AurUseDifferentCommands && conf.AurInstallCommand != "" {
+		command = conf.AurInstallCommand
+	}
 
 	// replace {giturl} with AUR url if defined
 	if pkg.Source == "AUR" {
 		command = strings.Replace(command, "{giturl}", "https://aur.archlinux.org/"+pkg.PackageBase+".git", -1)
-		command = strings.Replace(command, "{pkgbase}", pkg.PackageBase, -1)
 	}
+	if !installed {
+		pkg.Name = installTarget(conf, pkg)
+	}
+	return expandCommand(command, pkg)
+}
 
-	// Here I'm assuming -c is the argument for passing a command to the shell
-	// This might not be valid for all of em though.
-	args := []string{"-c", command}
+// returns the name a package is installed with
+// with a repo priority of our own, it's "repo/name", so that our repository is used rather than the first one of pacman.conf
+func installTarget(conf *config.Settings, pkg InfoRecord) string {
+	if len(conf.RepoPriority) == 0 || pkg.Source == "" || pkg.Source == "AUR" || pkg.Source == "local" {
+		return pkg.Name
+	}
+	return pkg.Source + "/" + pkg.Name
+}
 
-	ps.runCommand(ps.shell, args...)
+// placeholders that can be used in our command templates
+var commandPlaceholders = []string{"{pkg}", "{source}", "{optdepends}", "{giturl}", "{pkgbase}"}
 
-	// update package install status
-	ps.updateInstalledState()
+// matches placeholders like {pkg} in a command template
+var placeholderRegex = regexp.MustCompile(`\{[a-z]+\}`)
+
+// if our command contains {pkg}, replace it with the package name(s), otherwise concat it
+func withPackages(command, names string) string {
+	if strings.Contains(command, "{pkg}") {
+		return strings.Replace(command, "{pkg}", names, -1)
+	}
+	return command + " " + names
+}
+
+// replaces the placeholders of a command template with the values of a package
+func expandCommand(command string, pkg InfoRecord) string {
+	command = strings.Replace(withPackages(command, pkg.Name), "{optdepends}", strings.Join(pkg.OptDepends, " "), -1)
+	command = strings.Replace(command, "{source}", pkg.Source, -1)
+	return strings.Replace(command, "{pkgbase}", pkg.PackageBase, -1)
+}
+
+// checks if a command template is set and only contains known placeholders
+func validateCommand(name, command string) error {
+	return validatePlaceholders(name, command, commandPlaceholders)
+}
+
+// checks if a command is set and only contains the given placeholders
+func validatePlaceholders(name, command string, placeholders []string) error {
+	if strings.TrimSpace(command) == "" {
+		return fmt.Errorf("%s is empty", name)
+	}
+	for _, p := range placeholderRegex.FindAllString(command, -1) {
+		if !util.SliceContains(placeholders, p) {
+			return fmt.Errorf("%s contains an unknown placeholder: %s (valid ones: %s)", name, p, strings.Join(placeholders, " "))
+		}
+	}
+	return nil
+}
+
+// validates the command templates of our configuration (the ones that are in use)
+// empty AUR commands are fine, we fall back to the regular ones
+func validateCommands(conf *config.Settings) error {
+	commands := []struct {
+		name    string
+		command string
+	}{
+		{"Install command", conf.InstallCommand},
+		{"Uninstall command", conf.UninstallCommand},
+		{"Upgrade command", conf.SysUpgradeCommand},
+		{"Downgrade command", conf.DowngradeCommand},
+		{"Download command", conf.DownloadCommand},
+		{"AUR download command", conf.AurDownloadCommand},
+	}
+	if conf.AurUseDifferentCommands {
+		commands = append(commands, []struct {
+			name    string
+			command string
+		}{
+			{"AUR Install command", conf.AurInstallCommand},
+			{"AUR Upgrade command", conf.AurUpgradeCommand},
+		}...)
+	}
+	if conf.EnableFlatpak {
+		commands = append(commands, []struct {
+			name    string
+			command string
+		}{
+			{"Flatpak install command", conf.FlatpakInstallCommand},
+			{"Flatpak uninstall command", conf.FlatpakUninstallCommand},
+		}...)
+	}
+	for _, c := range commands {
+		if strings.HasPrefix(c.name, "AUR") && c.command == "" {
+			continue
+		}
+		if err := validateCommand(c.name, c.command); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// returns the commands for removing / installing our queued packages, one command for all packages of the same kind
+// removals come first, AUR packages are installed separately when a different AUR install command is configured
+// commands with package specific placeholders ({giturl} / {pkgbase} / {source}) are issued for each package,
+// as well as the commands for AUR packages that are built in a clean chroot and packages of additional sources (which are run last)
+// packages of additional sources with a local version (our selective upgrades) are upgraded with the upgrade command of their source
+func batchCommands(conf *config.Settings, sources []packageSource, queue []queuedPackage) []string {
+	removals, installs, aurInstalls := []queuedPackage{}, []queuedPackage{}, []queuedPackage{}
+	chrootCommands := []string{}
+	sourceCommands := []string{}
+	for _, q := range queue {
+		s := findSource(sources, q.Source)
+		switch {
+		case s != nil && q.Installed:
+			sourceCommands = append(sourceCommands, s.UninstallCommand(q.InfoRecord))
+		case s != nil && q.LocalVersion != "":
+			sourceCommands = append(sourceCommands, s.UpgradeCommand(q.InfoRecord))
+		case s != nil:
+			sourceCommands = append(sourceCommands, s.InstallCommand(q.InfoRecord))
+		case q.Installed:
+			removals = append(removals, q)
+		case useChroot(conf, q.InfoRecord, q.Installed):
+			chrootCommands = append(chrootCommands, packageCommand(conf, q.InfoRecord, q.Installed))
+		case q.Source == "AUR" && conf.AurUseDifferentCommands && conf.AurInstallCommand != "":
+			aurInstalls = append(aurInstalls, q)
+		default:
+			installs = append(installs, q)
+		}
+	}
+
+	commands := []string{}
+	for _, batch := range []struct {
+		command string
+		pkgs    []queuedPackage
+	}{
+		{conf.UninstallCommand, removals},
+		{conf.InstallCommand, installs},
+		{conf.AurInstallCommand, aurInstalls},
+	} {
+		if len(batch.pkgs) == 0 {
+			continue
+		}
+		if strings.Contains(batch.command, "{giturl}") || strings.Contains(batch.command, "{pkgbase}") || strings.Contains(batch.command, "{source}") {
+			for _, q := range batch.pkgs {
+				commands = append(commands, packageCommand(conf, q.InfoRecord, q.Installed))
+			}
+			continue
+		}
+		names := []string{}
+		for _, q := range batch.pkgs {
+			if q.Installed {
+				names = append(names, q.Name)
+			} else {
+				names = append(names, installTarget(conf, q.InfoRecord))
+			}
+		}
+		commands = append(commands, strings.Replace(withPackages(batch.command, strings.Join(names, " ")), "{optdepends}", "", -1))
+	}
+	return append(append(commands, chrootCommands...), sourceCommands...)
+}
+
+// removes / installs all queued packages
+func (ps *UI) installQueue() {
+	if len(ps.queue) == 0 {
+		return
+	}
+	if err := ps.preflight(strings.Join(batchCommands(ps.conf, ps.sources, ps.queue), " && ")); err != nil {
+		ps.displayMessage(err.Error(), true)
+		return
+	}
+	if err := checkChrootBuild(ps.conf, ps.queue); err != nil {
+		ps.displayMessage(err.Error(), true)
+		return
+	}
+	queue := ps.queue
+	removals := []string{}
+	for _, q := range queue {
+		if q.Installed && findSource(ps.sources, q.Source) == nil {
+			removals = append(removals, q.Name)
+		}
+	}
+
+	// removals are previewed first (with the removal strategy of our choice), then the installs
+	ps.previewRemoval(removals, func(flags string) {
+		conf := *ps.conf
+		conf.UninstallCommand = withRemovalFlags(conf.UninstallCommand, flags)
+		command := strings.Join(batchCommands(&conf, ps.sources, queue), " && ")
+		ps.previewInstall(repoInstallTargets(ps.sources, queue), func() {
+			ps.runTransaction(command)
+
+			// update package install status
+			ps.queue = []queuedPackage{}
+			ps.updateInstalledState()
+			ps.drawQueueMarks()
+		})
+	})
+}
+
+// shows a preview of the transaction for installing repository packages, the install command is run with ENTER
+// without a preview (disabled or nothing to preview), the command is run right away
+func (ps *UI) previewInstall(names []string, run func()) {
+	if ps.conf.DisableInstallPreview || len(names) == 0 {
+		run()
+		return
+	}
+	ps.previewRun = run
+	ps.textPreview.Clear().
+		SetTitle(" [::b]Computing transaction... ")
+	ps.flexRight.Clear().
+		AddItem(ps.textPreview, 0, 1, true)
+	ps.app.SetFocus(ps.textPreview)
+
+	go func() {
+		ps.locker.Lock()
+		ps.startSpinner()
+		defer func() {
+			ps.locker.Unlock()
+			ps.stopSpinner()
+		}()
+
+		var preview transactionPreview
+		out, err := printTargets(ps.conf, names)
+		if err == nil {
+			preview, err = buildTransactionPreview(ps.handle(), parsePrintTargets(out))
+		}
+		ps.app.QueueUpdateDraw(func() {
+			if ps.flexRight.GetItem(0) != ps.textPreview {
+				return
+			}
+			ps.drawTransactionPreview(preview, err)
+		})
+	}()
 }
 
 // installs or removes a package
@@ -52,19 +323,175 @@ func (ps *UI) installSelectedPackage() {
 	row, _ := ps.tablePackages.GetSelection()
 	installed := ps.tablePackages.GetCell(row, 2).Reference == true
 
+	// prebuilt packages that are available in the AUR as well, can be installed from either source
+	if !installed && ps.tablePackages.GetCell(row, 1).Reference == true {
+		ps.selectInstallSource(*ps.selectedPackage)
+		return
+	}
+
 	ps.installPackage(*ps.selectedPackage, installed)
 }
 
+// asks if a package should be installed from its third-party repository or the AUR
+func (ps *UI) selectInstallSource(pkg InfoRecord) {
+	ps.promptVisible = true
+	prompt := tview.NewModal().
+		AddButtons([]string{pkg.Source, "AUR", "Cancel"}).
+		SetText(fmt.Sprintf("'%s' is available prebuilt in '%s' and in the AUR.\nWhich source do you want to install it from?", pkg.Name, pkg.Source)).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			ps.promptVisible = false
+			ps.app.SetRoot(ps.flexRoot, true)
+			ps.app.SetFocus(ps.tablePackages)
+
+			switch buttonIndex {
+			case 0:
+				ps.installPackage(pkg, false)
+			case 1:
+				go func() {
+					ps.locker.Lock()
+					info := ps.getInfo("AUR", pkg.Name)
+					ps.locker.Unlock()
+					ps.app.QueueUpdateDraw(func() {
+						if len(info.Results) != 1 {
+							ps.displayMessage("Could not find '"+pkg.Name+"' in the AUR", true)
+							return
+						}
+						ps.installPackage(info.Results[0], false)
+					})
+				}()
+			}
+		})
+
+	ps.app.SetRoot(prompt, true)
+}
+
+// installs all members of a package group (the install command is run with the group name)
+func (ps *UI) installGroup(group packageGroup) {
+	if group.Installed == group.Members {
+		ps.displayMessage("All packages of "+group.Name+" are installed already", false)
+		return
+	}
+	members, err := searchGroups(ps.handle(), group.Name)
+	if err != nil {
+		ps.displayMessage(err.Error(), true)
+		return
+	}
+	names := []string{}
+	for _, m := range members {
+		if !m.IsInstalled {
+			names = append(names, m.Name)
+		}
+	}
+	command := expandCommand(ps.conf.InstallCommand, InfoRecord{Name: group.Name})
+	if err := ps.preflight(command); err != nil {
+		ps.displayMessage(err.Error(), true)
+		return
+	}
+
+	ps.previewInstall(names, func() {
+		ps.runTransaction(command)
+
+		// update package install status
+		ps.updateInstalledState()
+		ps.displayGroups()
+	})
+}
+
+// installs a package file from our cache (e.g. for a downgrade)
+func (ps *UI) installCachedVersion(pkg InfoRecord, cached cachedPackage) {
+	command := withPackages(ps.conf.DowngradeCommand, shellQuote(cached.Path))
+	if err := ps.preflight(command); err != nil {
+		ps.displayMessage(err.Error(), true)
+		return
+	}
+	ps.runTransaction(command)
+
+	// the installed version changed, so we need to refresh our package info
+	ps.cacheInfo.Delete(pkg.Name + "-" + pkg.Source)
+	ps.updateInstalledState()
+	ps.flexRight.Clear()
+	ps.flexRight.AddItem(ps.tableDetails, 0, 1, false)
+	ps.app.SetFocus(ps.tablePackages)
+	ps.displayPackageInfo(ps.tablePackages.GetSelection())
+}
+
 // issues "Update command"
 func (ps *UI) performUpgrade(aur bool) {
 	command := ps.conf.SysUpgradeCommand
 	if aur && ps.conf.AurUseDifferentCommands && ps.conf.AurUpgradeCommand != "" {
 		command = ps.conf.AurUpgradeCommand
 	}
+	ps.runUpgradeCommand(command)
+}
+
+// runs an upgrade command, after warning about unread news items
+func (ps *UI) runUpgradeCommand(command string) {
+	if err := ps.preflight(command); err != nil {
+		ps.displayMessage(err.Error(), true)
+		return
+	}
+	run := func() {
+		ps.runTransaction(command)
+	}
+	if ps.conf.DisableNewsFeed || ps.conf.DisableNewsWarning {
+		run()
+		return
+	}
 
-	args := []string{"-c", command}
+	// check for news we should read before upgrading
+	go func() {
+		ps.locker.Lock()
+		ps.startSpinner()
+		defer func() {
+			ps.locker.Unlock()
+			ps.stopSpinner()
+		}()
 
-	ps.runCommand(ps.shell, args...)
+		unread, err := pendingNews(ps.conf)
+		ps.app.QueueUpdateDraw(func() {
+			if err != nil {
+				ps.displayMessage("Failed checking news feed(s): "+err.Error(), true)
+			}
+			if len(unread) == 0 {
+				run()
+				return
+			}
+			ps.warnUnreadNews(unread, run)
+		})
+	}()
+}
+
+// asks for confirmation before upgrading when there are unread news items
+func (ps *UI) warnUnreadNews(unread []*gofeed.Item, run func()) {
+	text := fmt.Sprintf("There are %d unread news items since your last system upgrade:\n\n", len(unread))
+	for _, item := range unread {
+		text += item.Title + " (" + item.PublishedParsed.Format("2006-01-02") + ")\n"
+	}
+	text += "\nPlease read them before upgrading."
+
+	ps.promptVisible = true
+	prompt := tview.NewModal().
+		AddButtons([]string{"Mark as read & upgrade", "Open news", "Cancel"}).
+		SetText(text).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			ps.promptVisible = false
+			ps.app.SetRoot(ps.flexRoot, true)
+			ps.app.SetFocus(ps.tablePackages)
+
+			switch buttonIndex {
+			case 0:
+				if err := markNewsRead(unread); err != nil {
+					ps.displayMessage(err.Error(), true)
+				}
+				run()
+			case 1:
+				for _, item := range unread {
+					exec.Command("xdg-open", item.Link).Start()
+				}
+			}
+		})
+
+	ps.app.SetRoot(prompt, true)
 }
 
 // suspends UI and runs a command in the terminal
@@ -78,9 +505,18 @@ func (ps *UI) runCommand(command string, args ...string) {
 		cmd.Stderr = os.Stderr
 
 		// handle SIGINT and forward to the child process
-		cmd.Start()
+		logger.info("running command", "command", strings.Join(append([]string{command}, args...), " "))
+		start := time.Now()
+		if err := cmd.Start(); err != nil {
+			logger.log(levelError, "command could not be started", "command", command, "error", err)
+		}
 		quit := handleSigint(cmd)
 		err := cmd.Wait()
+		level := levelInfo
+		if cmd.ProcessState == nil || !cmd.ProcessState.Success() {
+			level = levelError
+		}
+		logger.log(level, "command finished", "command", command, "exit_code", cmd.ProcessState.ExitCode(), "duration", time.Since(start))
 		if err != nil {
 			if err.Error() != "signal: interrupt" {
 				cmd.Stdout.Write([]byte("\n" + err.Error() + "\nPress ENTER to return to pacseek\n"))
@@ -91,25 +527,55 @@ func (ps *UI) runCommand(command string, args ...string) {
 		quit <- true
 	})
 	// we need to reinitialize the alpm handler to get the proper install state
-	err := ps.reinitPacmanDbs()
-	if err != nil {
-		ps.displayMessage(err.Error(), true)
-	}
+	// our background work might be using it, so it is swapped once that is done
+	ps.updateLocked(func() {
+		if err := ps.reinitPacmanDbs(); err != nil {
+			ps.displayMessage(err.Error(), true)
+			return
+		}
+		ps.updateInstalledState()
+	})
 }
 
 // re-initializes the alpm handler
 func (ps *UI) reinitPacmanDbs() error {
-	err := ps.alpmHandle.Release()
+	var warnings []string
+	var err error
+	ps.alpmHandle, warnings, err = ReloadHandle(ps.alpmHandle, ps.conf.PacmanRootPath, ps.conf.PacmanDbPath, ps.conf.PacmanConfigPath, ps.filterRepos, ps.conf.SkipFailingRepos)
 	if err != nil {
 		return err
 	}
-	ps.alpmHandle, err = initPacmanDbs(ps.conf.PacmanDbPath, ps.conf.PacmanConfigPath, ps.filterRepos)
-	if err != nil {
-		return err
+	ps.arch, _ = pacmanArchitecture(ps.conf.PacmanConfigPath)
+	if len(warnings) > 0 {
+		ps.displayMessage(strings.Join(warnings, "\n"), true)
 	}
 	return nil
 }
 
+// runs "f" in our event loop while holding our lock, so that it can swap what our background work is using
+// our event loop must not wait for the lock itself, background work holding it might be waiting for queued updates
+func (ps *UI) updateLocked(f func()) {
+	go func() {
+		ps.locker.Lock()
+		defer ps.locker.Unlock()
+		done := make(chan struct{})
+		ps.app.QueueUpdate(func() {
+			defer close(done)
+			f()
+		})
+		<-done
+	}()
+}
+
+// returns our alpm handle for the functions querying the pacman databases
+// a nil handle is returned as untyped nil, so that their "h == nil" checks work
+func (ps *UI) handle() dbHandle {
+	if ps.alpmHandle == nil {
+		return nil
+	}
+	return ps.alpmHandle
+}
+
 // handles SIGINT call and passes it to a cmd process
 func handleSigint(cmd *exec.Cmd) chan bool {
 	quit := make(chan bool, 1)
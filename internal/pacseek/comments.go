@@ -0,0 +1,169 @@
+package pacseek
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/moson-mo/pacseek/internal/util"
+	"golang.org/x/net/html"
+)
+
+// number of (latest) comments on a page of the AUR web interface
+const aurCommentsPerPage = 10
+
+// commentsTimeout is the timeout for requests to the AUR web interface
+const commentsTimeout = 10 * time.Second
+
+// aurComment is a comment on the AUR package page
+type aurComment struct {
+	Author string
+	Date   string
+	Pinned bool
+	Text   string
+}
+
+// aurCommentPage is a page of comments, pinned comments are only part of the first page
+type aurCommentPage struct {
+	Comments []aurComment
+	Offset   int
+	HasMore  bool
+}
+
+// block elements that start a new line in our text representation
+var htmlBlockElements = []string{"p", "div", "pre", "ul", "ol", "h1", "h2", "h3", "h4", "h5", "h6", "blockquote", "table", "tr"}
+
+// matches three or more line breaks (with optional spaces between them)
+var multipleNewlines = regexp.MustCompile(`\n[ \t]*\n(?:[ \t]*\n)+`)
+
+// retrieves a page of comments from the package page of the AUR web interface (e.g. https://aur.archlinux.org/packages/yay)
+// "offset" is the number of latest comments to skip (see aurCommentsPerPage)
+func getAurComments(pageUrl string, offset int) (aurCommentPage, error) {
+	page := aurCommentPage{Offset: offset}
+	client := http.Client{
+		Timeout: commentsTimeout,
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s?O=%d", pageUrl, offset), nil)
+	if err != nil {
+		return page, err
+	}
+	req.Header.Set("User-Agent", "pacseek/"+version)
+
+	r, err := client.Do(req)
+	if err != nil {
+		return page, err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		return page, fmt.Errorf("AUR returned status %d", r.StatusCode)
+	}
+
+	page.Comments, page.HasMore, err = parseAurComments(r.Body, offset)
+	return page, err
+}
+
+// parses the comments of an AUR package page, pinned ones first
+// with "offset", we check if there is a link to the next page of comments
+func parseAurComments(r io.Reader, offset int) ([]aurComment, bool, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, false, err
+	}
+	comments := []aurComment{}
+	doc.Find("div.comments").Each(func(_ int, section *goquery.Selection) {
+		pinned := strings.Contains(section.Find(".comments-header h3").Text(), "Pinned")
+		section.Find("h4.comment-header").Each(func(_ int, header *goquery.Selection) {
+			id, _ := header.Attr("id")
+			content := section.Find("#" + id + "-content")
+			text := ""
+			if content.Length() > 0 {
+				text = htmlToText(content.Nodes[0])
+			}
+			comments = append(comments, aurComment{
+				Author: strings.TrimSpace(header.Find("a").First().Text()),
+				Date:   strings.TrimSpace(header.Find("a.date").Text()),
+				Pinned: pinned,
+				Text:   text,
+			})
+		})
+	})
+
+	next := fmt.Sprintf("O=%d", offset+aurCommentsPerPage)
+	hasMore := false
+	doc.Find(".comments-header a").Each(func(_ int, a *goquery.Selection) {
+		if href, _ := a.Attr("href"); strings.HasSuffix(href, next) {
+			hasMore = true
+		}
+	})
+
+	return comments, hasMore, nil
+}
+
+// converts an html node to plain text
+// whitespace is collapsed (except for preformatted text), block elements and <br> start a new line,
+// list items are prefixed with "- " and links are followed by their target (if it differs from the link text)
+func htmlToText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(n *html.Node, pre bool)
+	walk = func(n *html.Node, pre bool) {
+		switch n.Type {
+		case html.TextNode:
+			if pre {
+				sb.WriteString(n.Data)
+			} else {
+				text := strings.Join(strings.Fields(n.Data), " ")
+				if text != "" && strings.TrimLeft(n.Data, " \t\n") != n.Data && !strings.HasSuffix(sb.String(), " ") && !strings.HasSuffix(sb.String(), "\n") {
+					sb.WriteString(" ")
+				}
+				sb.WriteString(text)
+				if text != "" && strings.TrimRight(n.Data, " \t\n") != n.Data {
+					sb.WriteString(" ")
+				}
+			}
+			return
+		case html.ElementNode:
+			switch {
+			case n.Data == "br":
+				sb.WriteString("\n")
+				return
+			case n.Data == "li":
+				sb.WriteString("\n- ")
+			case n.Data == "pre":
+				pre = true
+				sb.WriteString("\n")
+			case util.SliceContains(htmlBlockElements, n.Data):
+				sb.WriteString("\n")
+			}
+		}
+		start := sb.Len()
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, pre)
+		}
+		if n.Type != html.ElementNode {
+			return
+		}
+		if n.Data == "a" {
+			for _, attr := range n.Attr {
+				if attr.Key == "href" && attr.Val != "" && strings.TrimSpace(sb.String()[start:]) != attr.Val {
+					sb.WriteString(" (" + attr.Val + ")")
+				}
+			}
+		}
+		if util.SliceContains(htmlBlockElements, n.Data) {
+			sb.WriteString("\n")
+		}
+	}
+	walk(n, false)
+
+	lines := strings.Split(sb.String(), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " ")
+	}
+	return strings.TrimSpace(multipleNewlines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
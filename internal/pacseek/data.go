@@ -7,6 +7,20 @@ type SearchResults struct {
 	Results     []InfoRecord `json:"results"`
 	Type        string       `json:"type"`
 	Version     int          `json:"version"`
+	Skipped     int          `json:"-"`
+}
+
+// removes malformed records (without name) and counts them in "Skipped"
+func (s *SearchResults) removeInvalid() {
+	valid := []InfoRecord{}
+	for _, r := range s.Results {
+		if r.Name == "" {
+			s.Skipped++
+			continue
+		}
+		valid = append(valid, r)
+	}
+	s.Results = valid
 }
 
 // InfoRecord is a data structure for "search" API calls (results)
@@ -36,11 +50,45 @@ type InfoRecord struct {
 	URL               string   `json:"URL"`
 	URLPath           string   `json:"URLPath"`
 	Version           string   `json:"Version"`
-	LocalVersion      string
-	Source            string `json:"Source"`
-	Architecture      string `json:"Architecture"`
+	LocalVersion      string   // installed version (if installed)
+	Source            string   `json:"Source"`
+	Architecture      string   `json:"Architecture"`
 	IsIgnored         bool
+	HasBuildDate      bool
 	DepsAndSatisfiers []DependencySatisfier
+	OmittedDepends    int
+	Size              int64 // download size (0 for local packages)
+	InstalledSize     int64
+	DiskSize          int64  // space used on disk by the installed files (installed packages, only determined for our details)
+	InstallReason     string // "Explicit" or "Dependency" ("" if not installed)
+	Validation        string // "pgp", "sha256", "md5" or "none" (repo packages only)
+}
+
+// Upgrade is a data structure for packages that can be upgraded
+// Status is either "upgrade" or "replaced" (package will be replaced by package "ReplacedBy")
+// EpochBump is set when the epoch of the new version is higher than the one of the installed version
+// WasForeign is set when the installed package has been installed from a package file (e.g. AUR) and now exists in a repo
+// Kind is the version component that changed: "epoch", "version" (pkgver) or "pkgrel" (rebuild)
+// HeldBack is set for ignored packages that are ignored by IgnorePkg / IgnoreGroup of pacman.conf
+type Upgrade struct {
+	InfoRecord
+	DownloadSize       int64
+	InstalledSizeDelta int64
+	Status             string
+	ReplacedBy         string
+	EpochBump          bool
+	WasForeign         bool
+	Kind               string
+	HeldBack           bool
+}
+
+// UpgradeSummary holds the totals of a list of upgrades
+// InstalledSize is the total installed size of the upgraded packages (after upgrading)
+type UpgradeSummary struct {
+	Count              int
+	DownloadSize       int64
+	InstalledSize      int64
+	InstalledSizeDelta int64
 }
 
 type DependencySatisfier struct {
@@ -52,25 +100,113 @@ type DependencySatisfier struct {
 
 // Package is a data structure for the package tview table
 type Package struct {
-	Name         string
-	Source       string
-	IsInstalled  bool
-	LastModified int
-	Popularity   float64
+	Name          string
+	Source        string
+	IsInstalled   bool
+	LastModified  int
+	HasBuildDate  bool
+	Popularity    float64
+	NumVotes      int
+	InstalledSize int64
+	DownloadSize  int64
+	MatchedField  string
+	SignedRepo    bool
+	Score         int      // fuzzy search score (lower is better)
+	MatchRanges   [][2]int // byte offsets (start, end) of the matching parts of the MatchedField (name or description)
+	AurAvailable  bool     // prebuilt package of a third-party repository that is available in the AUR as well
+	Orphaned      bool     // AUR package without a maintainer
+	OutOfDate     int      // time (unix) when an AUR package has been flagged out of date, 0 if it isn't
+	Description   string   // AUR packages only, needed for our query predicates (see searchQuery)
+	OtherRepos    []string // repositories with the same package but a lower priority (see repoMerger)
+}
+
+// SearchOptions are additional options / filters for searching the repositories
+// PreferNameMatches: collect name matches before other matches (so that they survive truncation)
+// Group: only packages of a group
+// SignedRepos: annotate packages with the signature requirement of their repository (see signedRepos)
+// ExcludeSources: repositories that are not searched ("local" skips local-only packages)
+// SegmentPrefix: StartsWith matches the beginning of name segments as well (see segmentHasPrefix)
+// HasOptDepends: only packages with optional dependencies
+// Arches: only packages built for one of the architectures ("any" has to be included explicitly, see hostArchitectures)
+// CaseInsensitive: ignore the case of the term and package names / descriptions
+// IncludeProvides: match the names of provided (virtual) packages as well, regardless of the search by setting
+// FairQuota: distribute the max. number of results among the databases (see fairShares) instead of filling them up in database order
+// MatchRanges: compute the parts of the name / description that matched our term (see matchRanges)
+// Licenses: only packages with a matching license (see licenseMatches)
+// Predicate: only packages for which the predicate is true (see searchQuery), nil matches all
+// MergeRepos: merge packages that exist in multiple repositories into a single entry (see repoMerger), RepoPriority comes first
+type SearchOptions struct {
+	Installed         InstalledFilter
+	PreferNameMatches bool
+	Group             string
+	SignedRepos       map[string]bool
+	ExcludeSources    []string
+	SegmentPrefix     bool
+	HasOptDepends     bool
+	Arches            []string
+	CaseInsensitive   bool
+	IncludeProvides   bool
+	FairQuota         bool
+	MatchRanges       bool
+	Licenses          []string
+	Predicate         queryPredicate
+	MergeRepos        bool
+	RepoPriority      []string
+}
+
+// IgnoreRules are packages (glob patterns) and groups that are excluded from upgrades (IgnorePkg / IgnoreGroup)
+type IgnoreRules struct {
+	Packages []string
+	Groups   []string
+}
+
+// InstalledFilter restricts search results by their install state
+type InstalledFilter int
+
+const (
+	InstalledAny InstalledFilter = iota
+	InstalledOnly
+	NotInstalledOnly
+)
+
+// checks if a package with the given install state passes the filter
+func (f InstalledFilter) matches(isInstalled bool) bool {
+	switch f {
+	case InstalledOnly:
+		return isInstalled
+	case NotInstalledOnly:
+		return !isInstalled
+	}
+	return true
 }
 
 // get package information
 func (ps *UI) getInfo(source string, pkgs ...string) SearchResults {
 	sr := SearchResults{}
-	if source == "AUR" || source == "all" {
-		sr = infoAur(ps.conf.AurRpcUrl, ps.conf.AurTimeout, pkgs...)
+	if s := findSource(ps.sources, source); s != nil {
+		for _, name := range pkgs {
+			r, err := s.Info(name)
+			if err != nil {
+				sr.Error = err.Error()
+				continue
+			}
+			sr.Results = append(sr.Results, r)
+		}
+		return sr
+	} else if source == "AUR" || source == "all" {
+		if ps.connectivity.offline() {
+			sr = offlineInfoAur(ps.diskCache, ps.conf.AurRpcUrl, pkgs...)
+		} else {
+			sr = cachedInfoAur(ps.diskCache, ps.conf.AurRpcUrl, ps.conf.AurTimeout, pkgs...)
+		}
 		if source == "all" {
-			sr.Results = append(sr.Results, infoPacman(ps.alpmHandle, ps.conf.ComputeRequiredBy, pkgs...).Results...)
+			sr.Results = append(sr.Results, infoPacman(ps.handle(), ps.conf.ComputeRequiredBy, pkgs...).Results...)
 		}
 	} else {
-		sr = infoPacman(ps.alpmHandle, ps.conf.ComputeRequiredBy, pkgs...)
+		sr = infoPacman(ps.handle(), ps.conf.ComputeRequiredBy, pkgs...)
 	}
 
-	addLocalSatisfiers(ps.alpmHandle, sr.Results...)
+	limitDependencies(ps.conf.MaxDependencies, sr.Results...)
+	addLocalSatisfiers(ps.handle(), sr.Results...)
 	return sr
 }
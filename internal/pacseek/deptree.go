@@ -0,0 +1,234 @@
+package pacseek
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Jguer/go-alpm/v2"
+)
+
+// dependency kinds of a node in a dependency tree
+const (
+	depInstalled = "installed"
+	depRepo      = "repo"
+	depAur       = "AUR"
+	depMissing   = "missing"
+)
+
+// depNode is a package in a dependency tree
+// installed dependencies are not expanded any further, "cycle" is set when the package is one of its own ancestors
+type depNode struct {
+	name     string
+	kind     string
+	source   string // repo name or "AUR"
+	cycle    bool
+	children []*depNode
+}
+
+// depResolver resolves dependencies with the help of the alpm handle and the AUR rpc API
+type depResolver struct {
+	local   alpm.IDB
+	dbs     alpm.IDBList
+	aurUrl  string
+	timeout int
+	aur     map[string]*InfoRecord // AUR lookups, nil if a package does not exist
+	nodes   map[string]*depNode    // resolved packages
+	err     error
+}
+
+// resolves the dependency tree of a (repo or AUR) package
+// AUR packages need to be built, so their make dependencies are part of the tree as well
+func depTree(h dbHandle, aurUrl string, timeout int, name string) (*depNode, error) {
+	if h == nil {
+		return nil, errors.New("alpm handle is nil")
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return nil, err
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &depResolver{
+		local:   local,
+		dbs:     dbs,
+		aurUrl:  aurUrl,
+		timeout: timeout,
+		aur:     map[string]*InfoRecord{},
+		nodes:   map[string]*depNode{},
+	}
+
+	var root *depNode
+	if pkg := findSyncPackage(dbs, name); pkg != nil {
+		root = r.repoNode(pkg, map[string]bool{})
+	} else if r.prefetchAur([]string{name}); r.aur[name] != nil {
+		root = r.aurNode(r.aur[name], map[string]bool{})
+	} else if r.err != nil {
+		return nil, r.err
+	} else {
+		return nil, fmt.Errorf("package '%s' not found", name)
+	}
+	if local.Pkg(name) != nil {
+		root.kind = depInstalled
+	}
+
+	return root, r.err
+}
+
+// resolves a dependency string, "ancestors" are the packages on the path from the root
+func (r *depResolver) resolve(dep string, ancestors map[string]bool) *depNode {
+	if lpkg, err := r.local.PkgCache().FindSatisfier(dep); err == nil && lpkg != nil {
+		return &depNode{name: lpkg.Name(), kind: depInstalled, source: "local"}
+	}
+	if spkg, err := r.dbs.FindSatisfier(dep); err == nil && spkg != nil {
+		return r.repoNode(spkg, ancestors)
+	}
+	name := parseDependency(dep).Name
+	if info := r.aur[name]; info != nil {
+		return r.aurNode(info, ancestors)
+	}
+	return &depNode{name: name, kind: depMissing}
+}
+
+// creates the node for a repo package and resolves its dependencies
+func (r *depResolver) repoNode(pkg alpm.IPackage, ancestors map[string]bool) *depNode {
+	deps := []string{}
+	for _, dep := range pkg.Depends().Slice() {
+		deps = append(deps, dep.String())
+	}
+	return r.node(pkg.Name(), depRepo, pkg.DB().Name(), deps, ancestors)
+}
+
+// creates the node for an AUR package and resolves its (make) dependencies
+func (r *depResolver) aurNode(info *InfoRecord, ancestors map[string]bool) *depNode {
+	deps := append(append([]string{}, info.Depends...), info.MakeDepends...)
+	return r.node(info.Name, depAur, "AUR", deps, ancestors)
+}
+
+// creates a node, shared dependencies are resolved only once
+func (r *depResolver) node(name, kind, source string, deps []string, ancestors map[string]bool) *depNode {
+	if ancestors[name] {
+		return &depNode{name: name, kind: kind, source: source, cycle: true}
+	}
+	if n, ok := r.nodes[name]; ok {
+		return n
+	}
+
+	n := &depNode{name: name, kind: kind, source: source, children: []*depNode{}}
+	ancestors[name] = true
+	r.prefetchAur(deps)
+	for _, dep := range deps {
+		n.children = append(n.children, r.resolve(dep, ancestors))
+	}
+	delete(ancestors, name)
+
+	// nodes with a truncated (cyclic) subtree depend on their path, so we don't reuse them
+	if !n.hasCycle() {
+		r.nodes[name] = n
+	}
+	return n
+}
+
+// looks up all dependencies that can't be satisfied by the local or sync db's with a single AUR request
+func (r *depResolver) prefetchAur(deps []string) {
+	names := []string{}
+	for _, dep := range deps {
+		if lpkg, err := r.local.PkgCache().FindSatisfier(dep); err == nil && lpkg != nil {
+			continue
+		}
+		if spkg, err := r.dbs.FindSatisfier(dep); err == nil && spkg != nil {
+			continue
+		}
+		name := parseDependency(dep).Name
+		if _, ok := r.aur[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 || r.aurUrl == "" {
+		return
+	}
+
+	res := infoAur(r.aurUrl, r.timeout, names...)
+	if res.Error != "" {
+		r.err = errors.New(res.Error)
+		return
+	}
+	for _, name := range names {
+		r.aur[name] = nil
+	}
+	for i := range res.Results {
+		r.aur[res.Results[i].Name] = &res.Results[i]
+	}
+}
+
+// checks if the node or any of its descendants has been truncated due to a cycle
+func (n *depNode) hasCycle() bool {
+	if n.cycle {
+		return true
+	}
+	for _, c := range n.children {
+		if c.hasCycle() {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the lines of a textual representation of the tree (using plain ASCII characters for the branches in ASCII mode)
+func (n *depNode) lines(ascii bool) []string {
+	lines := []string{n.label()}
+	for i, c := range n.children {
+		branch, indent := "├─ ", "│  "
+		if ascii {
+			branch, indent = "|- ", "|  "
+		}
+		if i == len(n.children)-1 {
+			branch, indent = "└─ ", "   "
+			if ascii {
+				branch = "`- "
+			}
+		}
+		for j, l := range c.lines(ascii) {
+			if j == 0 {
+				lines = append(lines, branch+l)
+			} else {
+				lines = append(lines, indent+l)
+			}
+		}
+	}
+	return lines
+}
+
+// returns the kinds of the nodes in the same order as "lines"
+func (n *depNode) kinds() []string {
+	kinds := []string{n.kind}
+	for _, c := range n.children {
+		kinds = append(kinds, c.kinds()...)
+	}
+	return kinds
+}
+
+// returns the name of the package plus its kind / source
+func (n *depNode) label() string {
+	var b strings.Builder
+	b.WriteString(n.name)
+	switch n.kind {
+	case depRepo:
+		b.WriteString(" (" + n.source + ")")
+	case depAur:
+		b.WriteString(" (AUR, build)")
+	case depInstalled:
+		b.WriteString(" (installed)")
+	case depMissing:
+		b.WriteString(" (not found)")
+	case revOptional:
+		b.WriteString(" (optional)")
+	}
+	if n.cycle {
+		b.WriteString(" (cycle)")
+	}
+	return b.String()
+}
@@ -1,40 +1,111 @@
 package pacseek
 
 import (
+	"context"
+	"fmt"
+	"os"
 	"os/exec"
 	"sort"
 	"strings"
 	"time"
 
-	"github.com/Jguer/go-alpm/v2"
-	"github.com/gdamore/tcell/v2"
 	"github.com/lithammer/fuzzysearch/fuzzy"
+	"github.com/moson-mo/pacseek/internal/config"
 	"github.com/moson-mo/pacseek/internal/util"
 	"github.com/rivo/tview"
 )
 
+// returns the normalized search term for our input
+// regular expressions (e.g. "\S") and file paths are kept as they are, other terms are lowercased unless our search is case sensitive
+func (ps *UI) searchTerm(text string) string {
+	if ps.conf.SearchMode != "Regex" && ps.conf.SearchBy != "File" && !ps.conf.CaseSensitive {
+		text = strings.ToLower(text)
+	}
+	return normalizeSearchTerm(text)
+}
+
+// searches while typing, once there was no input for our configured delay
+// a running search is cancelled right away, terms shorter than our minimum length are not searched
+func (ps *UI) searchLive(text string) {
+	if ps.searchCancel != nil {
+		ps.searchCancel()
+	}
+	term := ps.searchTerm(text)
+	if len(term) < ps.conf.LiveSearchMinLength {
+		ps.liveSearch.stop()
+		return
+	}
+	ps.liveSearch.call(time.Duration(ps.conf.LiveSearchDelay)*time.Millisecond, func() {
+		ps.app.QueueUpdateDraw(func() {
+			// the term has been changed or searched with ENTER in the meantime
+			if ps.searchTerm(ps.inputSearch.GetText()) != term {
+				return
+			}
+			ps.lastSearchTerm = term
+			ps.displayPackages(term)
+		})
+	})
+}
+
 // gets packages from repos/AUR and displays them
 func (ps *UI) displayPackages(text string) {
+	ps.searchPackages(text, 1)
+}
+
+// gets the first pages of our search results and displays them
+// when more than one page is requested, the selection stays where it is (we're loading more results while scrolling)
+func (ps *UI) searchPackages(text string, pages int) {
 	var packages []Package
+	more := false
+	if ps.conf.DisableLazyLoading {
+		pages = 1
+	}
+	limit := resultLimit(ps.conf.MaxResults, pages)
+	filter := ps.resultFilter
+
+	// "python http -test repo:extra" searches for "python http", exclusions and qualifiers are checked by our predicate
+	query, err := parseSearchQuery(text, ps.conf.SearchMode)
+	if err != nil {
+		ps.displayMessage(err.Error(), true)
+		return
+	}
+	term, predicate := query.Term(), query.predicate()
 
 	showFunc := func() {
+		row, _ := ps.tablePackages.GetSelection()
 		ps.shownPackages = packages
-		best := bestMatch(text, packages) + 1
+		best := bestMatch(term, packages) + 1
 		ps.drawPackageListContent(packages, ps.conf.PackageColumnWidth)
+		ps.searchPaging = searchPaging{term: text, pages: pages, more: more && !ps.conf.DisableLazyLoading}
 		if ps.flexRight.GetItem(0) == ps.formSettings {
 			ps.flexRight.Clear()
 			ps.flexRight.AddItem(ps.tableDetails, 0, 1, false)
 		}
+		if pages > 1 {
+			ps.tablePackages.Select(row, 0)
+			ps.tablePackages.SetTitle(ps.packageListTitle(row))
+			return
+		}
 		ps.tablePackages.Select(best, 0) // select the best match
 	}
 
-	// check cache first
-	if packagesCache, found := ps.cacheSearch.Get(text); found {
+	// a search that is still running is cancelled, our new term replaces it
+	if ps.searchCancel != nil {
+		ps.searchCancel()
+	}
+
+	// check cache first, it might contain more than one page already
+	if packagesCache, found := ps.cacheSearch.Get(text); found && (pages == 1 || len(packagesCache.([]Package)) >= limit) {
 		packages = packagesCache.([]Package)
+		pages = resultPages(len(packages), ps.conf.MaxResults)
+		more = len(packages) >= resultLimit(ps.conf.MaxResults, pages)
 		showFunc()
 		return
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	ps.searchCancel = cancel
+
 	go func() {
 		ps.locker.Lock()
 		ps.startSpinner()
@@ -43,83 +114,211 @@ func (ps *UI) displayPackages(text string) {
 			ps.stopSpinner()
 		}()
 
-		var err error
+		// we've been waiting for another search and got replaced in the meantime
+		if ctx.Err() != nil {
+			return
+		}
+
 		var localPackages []Package
+		sources := []searchSource{}
+		repoCapped, aurCapped, sourcesCapped := false, false, false
+		// our alpm handle must not be used concurrently, the AUR source waits for our repositories to be searched
+		reposDone := make(chan struct{})
 
 		// search repositories
-		packages, localPackages, err = searchRepos(ps.alpmHandle, text, ps.conf.SearchMode, ps.conf.SearchBy, ps.conf.MaxResults)
-		if err != nil {
-			ps.app.QueueUpdateDraw(func() {
-				ps.displayMessage(err.Error(), true)
-			})
-		}
-		// search AUR
-		if !ps.conf.DisableAur {
-			aurPackages, err := searchAur(ps.conf.AurRpcUrl, text, ps.conf.AurTimeout, ps.conf.SearchMode, ps.conf.SearchBy, ps.conf.MaxResults)
+		sources = append(sources, searchSource{name: "repositories", search: func(ctx context.Context) ([]Package, error) {
+			defer close(reposDone)
+			opts := SearchOptions{
+				PreferNameMatches: ps.conf.PreferNameMatches,
+				ExcludeSources:    ps.conf.ExcludeSources,
+				MergeRepos:        !ps.conf.DisableRepoMerge,
+				RepoPriority:      ps.conf.RepoPriority,
+				SegmentPrefix:     ps.conf.SegmentPrefixMatch,
+				CaseInsensitive:   !ps.conf.CaseSensitive,
+				Arches:            filter.searchArches(ps.arch),
+				Licenses:          filter.Licenses,
+				Predicate:         predicate,
+			}
+			if ps.conf.SearchBy == "File" {
+				packages, local, err := searchFiles(ps.handle(), ps.conf.PacmanRootPath, ps.conf.PacmanDbPath, text, limit, opts)
+				localPackages = local
+				repoCapped = len(packages)+len(local) >= limit
+				return packages, err
+			}
+			packages, local, err := searchReposCtx(ctx, ps.handle(), term, ps.conf.SearchMode, ps.conf.SearchBy, limit, opts)
+			localPackages = local
 			if err != nil {
-				ps.app.QueueUpdateDraw(func() {
-					ps.displayMessage(err.Error(), true)
-				})
+				return packages, err
 			}
-
-			for i := 0; i < len(aurPackages); i++ {
-				aurPackages[i].IsInstalled = isPackageInstalled(ps.alpmHandle, aurPackages[i].Name)
+			repoCapped = len(packages)+len(localPackages) >= limit
+			// warn if our search term is too broad (unless more results are loaded while scrolling)
+			if ps.conf.BroadSearchWarning > 0 && ps.conf.DisableLazyLoading && repoCapped {
+				if count := repoMatchCount(ps.handle(), term, ps.conf.SearchMode, ps.conf.SearchBy, opts); count > ps.conf.BroadSearchWarning {
+					ps.app.QueueUpdateDraw(func() {
+						ps.displayMessage(fmt.Sprintf("Your search is too broad: %d matches, showing %d", count, ps.conf.MaxResults), false)
+					})
+				}
 			}
+			return packages, nil
+		}})
 
-			// add AUR results to our list
-			packages = append(packages, aurPackages...)
+		// search AUR (it doesn't have any file lists)
+		if !ps.conf.DisableAur && !util.SliceContains(ps.conf.ExcludeSources, "aur") && ps.conf.SearchBy != "File" && query.includesAur() {
+			sources = append(sources, searchSource{name: "AUR", search: func(ctx context.Context) ([]Package, error) {
+				// the AUR returns all results at once, we keep all of them (disk cache) and page through them
+				aurLimit := ps.conf.MaxResults
+				if !ps.conf.DisableLazyLoading {
+					aurLimit = aurMaxResults
+				}
+				aurPackages, err := ps.searchAurOffline(ctx, query.aurTerm(), aurLimit)
+				aurPackages = filterIgnoredAur(aurPackages, ps.conf.AurIgnore)
+				if ps.conf.HideOutOfDate {
+					aurPackages = filterOutOfDate(aurPackages)
+				}
+
+				select {
+				case <-reposDone:
+				case <-ctx.Done():
+					return aurPackages, ctx.Err()
+				}
+				installed := areInstalled(ps.handle(), packageNames(aurPackages))
+				for i := 0; i < len(aurPackages); i++ {
+					aurPackages[i].IsInstalled = installed[aurPackages[i].Name]
+				}
+				aurPackages = filterByPredicate(aurPackages, query.aurPredicate(ps.conf.SearchBy))
+
+				// licenses are part of the package information only, we look them up for the packages of our shown pages
+				if len(filter.Licenses) > 0 && len(aurPackages) > 0 {
+					aurPackages, aurCapped = pageResults(aurPackages, limit, false)
+					aurPackages = filterAurLicenses(aurPackages, ps.getInfo("AUR", packageNames(aurPackages)...).Results, filter.Licenses)
+				}
+				return aurPackages, err
+			}})
 		}
 
-		// add local-only (not found in repo not AUR)
-		for _, lpkg := range localPackages {
-			found := false
-			for _, pkg := range packages {
-				if pkg.Name == lpkg.Name {
-					found = true
-					break
+		// search additional sources (e.g. Flatpak)
+		if ps.conf.SearchBy != "File" && len(ps.sources) > 0 {
+			sources = append(sources, searchSource{name: "sources", search: func(ctx context.Context) ([]Package, error) {
+				sourcePackages, errs := searchSources(ps.sources, term, limit)
+				sourcesCapped = len(sourcePackages) >= limit
+				sourcePackages = filterByPredicate(sourcePackages, predicate)
+				for _, err := range errs {
+					err := err
+					ps.app.QueueUpdateDraw(func() {
+						ps.displayMessage(err.Error(), true)
+					})
 				}
+				return sourcePackages, nil
+			}})
+		}
+
+		// all sources are searched concurrently, results are shown as soon as they arrive
+		// the final list keeps the order of our sources (repositories, AUR, additional sources)
+		results := map[string][]Package{}
+		start := time.Now()
+		_, _, err := streamSearch(ctx, sources, func(source string, pkgs []Package, err error) {
+			if ctx.Err() == nil {
+				logger.info("search", "term", text, "source", source, "results", len(pkgs), "duration", time.Since(start), "error", err)
+			}
+			if err != nil && ctx.Err() == nil {
+				ps.app.QueueUpdateDraw(func() {
+					ps.displayMessage(err.Error(), true)
+				})
 			}
-			if !found {
-				packages = append(packages, lpkg)
+			results[source] = pkgs
+			// when loading more results, our current list stays until we're done
+			if len(results) < len(sources) && len(pkgs) > 0 && pages == 1 {
+				partial := partialResults(results, sources, ps.conf, term)
+				ps.app.QueueUpdateDraw(func() {
+					if ctx.Err() != nil {
+						return
+					}
+					ps.shownPackages = partial
+					ps.drawPackageListContent(partial, ps.conf.PackageColumnWidth)
+				})
 			}
+		})
+		if err != nil {
+			return
+		}
+		for _, s := range sources {
+			packages = append(packages, results[s.name]...)
+		}
+
+		// merge AUR packages with their prebuilt variants (third-party repositories like chaotic-aur)
+		official := getArchRepos()
+		if ps.isArm {
+			official = getArchArmRepos()
 		}
+		packages = mergePrebuilt(ps.handle(), packages, official)
+
+		// add local-only (not found in repo not AUR)
+		packages = addLocalOnly(packages, localPackages)
+
+		// apply orphan / explicit / foreign filter
+		packages = filterLocal(ps.handle(), packages, ps.conf.LocalFilter)
+
+		// popularity of repository packages (pkgstats), so that they can be compared with AUR packages
+		ps.applyPopularity(packages)
+
+		// sort list by our configured criterion (name, unless the original order is preserved / fuzzy matches are ranked already)
+		// ranked repo and AUR matches are merged by their score, so that the closest ones float to the top
+		sortSearchResults(packages, ps.conf, term)
+
+		// run registered post processors
+		packages = ps.postProcessors.apply(packages)
 
 		// show message if we couldn't find anything
 		if len(packages) == 0 {
 			ps.app.QueueUpdateDraw(func() {
+				if ctx.Err() != nil {
+					return
+				}
 				ps.displayMessage("No packages found for search-term: "+text, false)
 			})
 			return
 		}
 
-		// sort list by name
-		sort.Slice(packages, func(i, j int) bool {
-			return packages[i].Name < packages[j].Name
-		})
-
-		// strip down list to our configured maximum
-		if len(packages) > ps.conf.MaxResults {
-			packages = packages[:ps.conf.MaxResults]
-		}
+		// strip down list to our configured maximum (per page)
+		packages, more = pageResults(packages, limit, repoCapped || aurCapped || sourcesCapped)
 
 		// get info records and store in cache
 		ps.cacheSearchAndPackageInfo(packages, text)
 
 		// draw packages
 		ps.app.QueueUpdateDraw(func() {
+			if ctx.Err() != nil {
+				return
+			}
 			showFunc()
 		})
 	}()
 }
 
+// returns the results of the sources that finished so far, sorted and stripped down to our configured maximum
+func partialResults(results map[string][]Package, sources []searchSource, conf *config.Settings, term string) []Package {
+	packages := []Package{}
+	for _, s := range sources {
+		packages = append(packages, results[s.name]...)
+	}
+	sortSearchResults(packages, conf, term)
+	if len(packages) > conf.MaxResults {
+		packages = packages[:conf.MaxResults]
+	}
+	return packages
+}
+
 // retrieves package info records and stores search results and infos in cache
 func (ps *UI) cacheSearchAndPackageInfo(packages []Package, searchTerm string) {
-	// get string slices for AUR and repo packages
+	// get string slices for AUR, repo and additional source packages
 	aurPkgs := []string{}
 	repoPkgs := []string{}
+	sourcePkgs := map[string][]string{}
 	for _, pkg := range packages {
 		if pkg.Source == "AUR" {
 			aurPkgs = append(aurPkgs, pkg.Name)
+		} else if findSource(ps.sources, pkg.Source) != nil {
+			sourcePkgs[pkg.Source] = append(sourcePkgs[pkg.Source], pkg.Name)
 		} else {
 			repoPkgs = append(repoPkgs, pkg.Name)
 		}
@@ -127,10 +326,12 @@ func (ps *UI) cacheSearchAndPackageInfo(packages []Package, searchTerm string) {
 
 	// get detailed package information for all packages and add to cache
 	if !ps.conf.DisableCache {
-		aurInfos := ps.getInfo("AUR", aurPkgs...)
-		repoInfos := ps.getInfo("repo", repoPkgs...)
+		infos := append(ps.getInfo("AUR", aurPkgs...).Results, ps.getInfo("repo", repoPkgs...).Results...)
+		for source, names := range sourcePkgs {
+			infos = append(infos, ps.getInfo(source, names...).Results...)
+		}
 
-		for _, pkg := range append(aurInfos.Results, repoInfos.Results...) {
+		for _, pkg := range infos {
 			ps.cacheInfo.Set(pkg.Name+"-"+pkg.Source, pkg, time.Duration(ps.conf.CacheExpiry)*time.Minute)
 		}
 
@@ -159,12 +360,15 @@ func (ps *UI) displayPackageInfo(row, column int) {
 			if info.Error != "" {
 				errorMsg = info.Error
 			}
-			ps.tableDetails.SetTitle(" [red]Error ")
-			ps.tableDetails.SetCellSimple(0, 0, "[red]"+errorMsg)
+			ps.tableDetails.SetTitle(" " + colorTag(ps.conf.Colors().Error) + "Error ")
+			ps.tableDetails.SetCellSimple(0, 0, colorTag(ps.conf.Colors().Error)+errorMsg)
 			return
 		}
 		ps.selectedPackage = &info.Results[0]
 		ps.drawPackageInfo(info.Results[0], ps.width)
+		if source == "AUR" && ps.connectivity.offline() {
+			ps.tableDetails.SetTitle(ps.tableDetails.GetTitle() + "- offline, might be outdated ")
+		}
 	}
 
 	if infoCached, found := ps.cacheInfo.Get(pkg + "-" + source); found {
@@ -194,6 +398,9 @@ func (ps *UI) displayPackageInfo(row, column int) {
 		}()
 
 		info = ps.getInfo(source, pkg)
+		if len(info.Results) == 1 && info.Results[0].LocalVersion != "" {
+			info.Results[0].DiskSize, _ = packageDiskSize(ps.handle(), ps.conf.PacmanRootPath, pkg)
+		}
 		if !ps.conf.DisableCache && len(info.Results) == 1 {
 			ps.cacheInfo.Set(pkg+"-"+info.Results[0].Source, info.Results[0], time.Duration(ps.conf.CacheExpiry)*time.Minute)
 		}
@@ -209,7 +416,7 @@ func (ps *UI) displayPackageInfo(row, column int) {
 func (ps *UI) displayMessage(message string, isError bool) {
 	txt := message
 	if isError {
-		txt = "[red]Error: " + message
+		txt = colorTag(ps.conf.Colors().Error) + "Error: " + message
 	}
 
 	ps.textMessage.SetText(txt)
@@ -229,29 +436,174 @@ func (ps *UI) displayMessage(message string, isError bool) {
 func (ps *UI) displayHelp() {
 	ps.tableDetails.Clear().
 		SetTitle(" [::b]" + ps.conf.Glyphs().Help + "Usage ")
-	ps.tableDetails.SetCellSimple(0, 0, "ENTER: Search; Install or remove a selected package").
-		SetCellSimple(1, 0, "TAB / CTRL+Up/Down/Right/Left: Navigate between boxes").
-		SetCellSimple(2, 0, "Up/Down: Navigate within package list").
-		SetCellSimple(3, 0, "Shift+Left/Right: Change size of package list").
-		SetCellSimple(4, 0, "CTRL+S: Open/Close settings").
-		SetCellSimple(5, 0, "CTRL+N: Show these instructions").
-		SetCellSimple(6, 0, "CTRL+U: Perform sysupgrade").
-		SetCellSimple(7, 0, "CTRL+A: Perform AUR upgrade (if configured)").
-		SetCellSimple(8, 0, "CTRL+W: Wipe cache").
-		SetCellSimple(9, 0, "CTRL+P: Show PKGBUILD for selected package").
-		SetCellSimple(10, 0, "CTRL+O: Open URL for selected package").
-		SetCellSimple(11, 0, "CTRL+G: Show list of upgradeable packages").
-		SetCellSimple(12, 0, "CTRL+L: Show list of all installed packages").
-		SetCellSimple(14, 0, "CTRL+Q / ESC: Quit").
-		SetCell(16, 0, &tview.TableCell{
-			Text:            "For detailed instructions, please check the man page or visit the [::b]Wiki",
-			Color:           tcell.ColorWhite,
-			BackgroundColor: ps.conf.Colors().DefaultBackground,
-			Clicked: func() bool {
-				exec.Command("xdg-open", "https://github.com/moson-mo/pacseek/wiki/Usage").Start()
-				return true
-			},
+	// our rows reflect the configured key bindings
+	rows := []string{ps.keys.label("Search") + ": Search; " + ps.keys.label("Install") + " (package list): Install or remove a selected package"}
+	if ps.keys.label("Search") == ps.keys.label("Install") {
+		rows = []string{ps.keys.label("Search") + ": Search; Install or remove a selected package"}
+	}
+	if ps.readOnly() {
+		rows = []string{ps.keys.label("Search") + ": Search (read-only mode, packages can't be installed, removed or upgraded)"}
+	}
+	rows = append(rows,
+		ps.keys.label("NextBox")+" / CTRL+Up/Down/Right/Left: Navigate between boxes",
+		"Up/Down: Navigate within package list",
+		"Shift+Left/Right: Change size of package list")
+	sorts := []string{}
+	for _, a := range keyActions {
+		desc := a.description
+		switch {
+		case a.name == "Search" || a.name == "Install" || a.name == "NextBox" || a.name == "ShowQueue" || a.name == "About" || a.name == "Quit":
+			continue
+		case ps.readOnly() && util.SliceContains(transactionActions, a.name):
+			continue
+		case sortActions[a.name] != 0:
+			sorts = append(sorts, ps.keys.label(a.name))
+			continue
+		case a.name == "Queue":
+			desc = fmt.Sprintf("%s (%s runs queue, %s shows it)", desc, ps.keys.label("Install"), ps.keys.label("ShowQueue"))
+		}
+		rows = append(rows, ps.keys.label(a.name)+": "+desc)
+	}
+	rows = append(rows,
+		strings.Join(sorts, " / ")+": Sort by name / source / installed state / last modified / popularity / votes / installed size / download size",
+		"",
+		ps.keys.label("Quit")+" / ESC: Quit",
+		"")
+	for r, row := range rows {
+		ps.tableDetails.SetCellSimple(r, 0, row)
+	}
+	ps.tableDetails.SetCell(len(rows), 0, &tview.TableCell{
+		Text:            "For detailed instructions, please check the man page or visit the [::b]Wiki",
+		Color:           ps.conf.Colors().Text,
+		BackgroundColor: ps.conf.Colors().DefaultBackground,
+		Clicked: func() bool {
+			exec.Command("xdg-open", "https://github.com/moson-mo/pacseek/wiki/Usage").Start()
+			return true
+		},
+	})
+}
+
+// displays statistics about our installed packages
+func (ps *UI) displayStats() {
+	ps.tableDetails.Clear().
+		SetTitle(" [::b]Computing statistics... ")
+
+	go func() {
+		ps.locker.Lock()
+		ps.startSpinner()
+		defer func() {
+			ps.locker.Unlock()
+			ps.stopSpinner()
+		}()
+
+		stats, err := computeStats(ps.handle())
+		ps.app.QueueUpdateDraw(func() {
+			if err != nil {
+				ps.tableDetails.SetTitle(" [::b]Error computing statistics ")
+				ps.tableDetails.SetCellSimple(0, 0, colorTag(ps.conf.Colors().Error)+tview.Escape(err.Error()))
+				return
+			}
+			ps.drawStats(stats)
+		})
+	}()
+}
+
+// tests the mirrors of our mirrorlist and displays their latency / throughput and last sync
+func (ps *UI) displayMirrors() {
+	ps.tableDetails.Clear().
+		SetTitle(" [::b]Testing mirrors... ")
+
+	go func() {
+		ps.locker.Lock()
+		ps.startSpinner()
+		defer func() {
+			ps.locker.Unlock()
+			ps.stopSpinner()
+		}()
+
+		servers, err := readMirrorlist(mirrorlistFile)
+		if err != nil {
+			ps.app.QueueUpdateDraw(func() {
+				ps.tableDetails.SetTitle(" [::b]Error reading mirrorlist ")
+				ps.tableDetails.SetCellSimple(0, 0, colorTag(ps.conf.Colors().Error)+tview.Escape(err.Error()))
+			})
+			return
+		}
+		synced, statusErr := getMirrorStatus(UrlMirrorStatus)
+		mirrors := checkMirrors(servers, ps.arch, synced)
+		ps.app.QueueUpdateDraw(func() {
+			now := time.Now()
+			ps.drawMirrors(mirrors, len(servers), now)
+			if statusErr != nil {
+				ps.displayMessage("Could not get the mirror status: "+statusErr.Error(), true)
+			} else if outdated := outdatedMirrors(mirrors, now); len(outdated) > 0 {
+				ps.displayMessage(fmt.Sprintf("%d of your mirrors are out of date (%s: last sync %s ago), updates might be missing", len(outdated), outdated[0].Server, formatLag(now.Sub(outdated[0].LastSync))), true)
+			}
+		})
+	}()
+}
+
+// compares a snapshot (package list) with our installed packages
+// the missing packages are shown in our package list and queued, so that they can be installed with ENTER
+func (ps *UI) displaySnapshotDiff(file string) {
+	ps.tableDetails.Clear().
+		SetTitle(" [::b]Comparing package list... ")
+
+	go func() {
+		ps.locker.Lock()
+		ps.startSpinner()
+		defer func() {
+			ps.locker.Unlock()
+			ps.stopSpinner()
+		}()
+
+		bases := map[string]string{}
+		aurAvailable := func(names ...string) map[string]bool {
+			available := map[string]bool{}
+			if ps.conf.DisableAur {
+				return available
+			}
+			for _, r := range cachedInfoAur(ps.diskCache, ps.conf.AurRpcUrl, ps.conf.AurTimeout, names...).Results {
+				available[r.Name] = true
+				bases[r.Name] = r.PackageBase
+			}
+			return available
+		}
+
+		var diff snapshotDiff
+		f, err := os.Open(file)
+		if err == nil {
+			var snap packageSnapshot
+			snap, err = parseSnapshot(f)
+			f.Close()
+			if err == nil {
+				diff, err = diffSnapshot(ps.handle(), snap, aurAvailable)
+			}
+		}
+		ps.app.QueueUpdateDraw(func() {
+			if err != nil {
+				ps.tableDetails.SetTitle(" [::b]Error comparing package list ")
+				ps.tableDetails.SetCellSimple(0, 0, colorTag(ps.conf.Colors().Error)+tview.Escape(err.Error()))
+				return
+			}
+			ps.queue = []queuedPackage{}
+			for _, pkg := range diff.Missing {
+				base := pkg.Name
+				if b, ok := bases[pkg.Name]; ok {
+					base = b
+				}
+				ps.queue = append(ps.queue, queuedPackage{InfoRecord: InfoRecord{Name: pkg.Name, Source: pkg.Source, PackageBase: base}})
+			}
+			ps.shownPackages = diff.Missing
+			ps.searchPaging = searchPaging{}
+			ps.drawPackageListContent(diff.Missing, ps.conf.PackageColumnWidth)
+			ps.drawSnapshotDiff(file, diff)
+			if len(diff.Missing) > 0 {
+				ps.app.SetFocus(ps.tablePackages)
+				ps.tablePackages.Select(1, 0)
+			}
 		})
+	}()
 }
 
 // displays about text
@@ -291,21 +643,31 @@ func (ps *UI) displayAbout() {
 
 // displays PKGBUILD file
 func (ps *UI) displayPkgbuild() {
-	if ps.selectedPackage == nil {
+	ps.displayPackageFile(filePkgbuild)
+}
+
+// displays a file (PKGBUILD or .SRCINFO) of the git repository of the selected package
+func (ps *UI) displayPackageFile(file string) {
+	if ps.selectedPackage == nil || findSource(ps.sources, ps.selectedPackage.Source) != nil {
 		return
 	}
 	pkg := *ps.selectedPackage
+	ps.pkgbuildFile = file
 
 	ps.textPkgbuild.Clear().
-		SetTitle(" [::b]Loading PKGBUILD... ")
+		SetTitle(" [::b]Loading " + file + "... ")
 	ps.flexRight.Clear().
 		AddItem(ps.textPkgbuild, 0, 1, true)
 	ps.app.SetFocus(ps.textPkgbuild)
 
 	// check cache first
-	if contentCached, found := ps.cachePkgbuild.Get(pkg.PackageBase); found {
+	key := pkg.PackageBase
+	if file != filePkgbuild {
+		key += "/" + file
+	}
+	if contentCached, found := ps.cachePkgbuild.Get(key); found {
 		content := contentCached.(string)
-		ps.drawPkgbuild(content, pkg.Name)
+		ps.drawPkgbuild(content, pkg.Name, file)
 		return
 	}
 
@@ -317,23 +679,196 @@ func (ps *UI) displayPkgbuild() {
 			ps.stopSpinner()
 		}()
 
-		content, err := getPkgbuildContent(getPkgbuildUrl(pkg.Source, pkg.PackageBase))
+		content, err := getPkgbuildContent(getPackageFileUrl(pkg.Source, pkg.PackageBase, file))
 		if err != nil {
 			ps.app.QueueUpdateDraw(func() {
-				ps.textPkgbuild.SetTitle(" [::b]Error loading PKGBUILD ")
+				ps.textPkgbuild.SetTitle(" [::b]Error loading " + file + " ")
 				ps.textPkgbuild.SetText(err.Error())
 			})
 			return
 		}
 		if !ps.conf.DisableCache {
-			ps.cachePkgbuild.Set(pkg.PackageBase, content, time.Duration(ps.conf.CacheExpiry)*time.Minute)
+			ps.cachePkgbuild.Set(key, content, time.Duration(ps.conf.CacheExpiry)*time.Minute)
+		}
+		ps.app.QueueUpdateDraw(func() {
+			if ps.pkgbuildFile == file {
+				ps.drawPkgbuild(content, pkg.Name, file)
+			}
+		})
+	}()
+}
+
+// displays the dependency tree of the selected package
+func (ps *UI) displayDepTree() {
+	if ps.selectedPackage == nil {
+		return
+	}
+	pkg := *ps.selectedPackage
+
+	// check cache first
+	if treeCached, found := ps.cacheDeps.Get(pkg.Name); found {
+		ps.drawDepTree(treeCached.(*depNode))
+		return
+	}
+
+	ps.tableDetails.Clear().
+		SetTitle(" [::b]" + pkg.Name + " - Resolving dependencies... ")
+	ps.depTreeVisible = true
+
+	go func() {
+		ps.locker.Lock()
+		ps.startSpinner()
+		defer func() {
+			ps.locker.Unlock()
+			ps.stopSpinner()
+		}()
+
+		aurUrl := ps.conf.AurRpcUrl
+		if ps.conf.DisableAur {
+			aurUrl = ""
+		}
+		tree, err := depTree(ps.handle(), aurUrl, ps.conf.AurTimeout, pkg.Name)
+		if err == nil && !ps.conf.DisableCache {
+			ps.cacheDeps.Set(pkg.Name, tree, time.Duration(ps.conf.CacheExpiry)*time.Minute)
 		}
 		ps.app.QueueUpdateDraw(func() {
-			ps.drawPkgbuild(content, pkg.Name)
+			if ps.selectedPackage == nil || ps.selectedPackage.Name != pkg.Name || !ps.depTreeVisible {
+				return
+			}
+			if tree == nil {
+				ps.tableDetails.SetTitle(" " + colorTag(ps.conf.Colors().Error) + "Error ")
+				ps.tableDetails.SetCellSimple(0, 0, colorTag(ps.conf.Colors().Error)+err.Error())
+				return
+			}
+			ps.drawDepTree(tree)
+			if err != nil {
+				ps.displayMessage(err.Error(), true)
+			}
 		})
 	}()
 }
 
+// marks / unmarks a package of the package list for a batch install / removal
+func (ps *UI) toggleQueued(row int) {
+	if row < 1 || row >= ps.tablePackages.GetRowCount() {
+		return
+	}
+	name := ps.tablePackages.GetCell(row, 0).Text
+	source := ps.tablePackages.GetCell(row, 1).Text
+	if i := ps.queueIndex(name, source); i != -1 {
+		ps.queue = append(ps.queue[:i], ps.queue[i+1:]...)
+	} else {
+		installed := ps.tablePackages.GetCell(row, 2).Reference == true
+		// repo packages that conflict with / replace a queued one can't be installed together
+		if !installed && source != "AUR" && findSource(ps.sources, source) == nil {
+			if conflict, err := queueConflict(ps.handle(), name, repoInstallTargets(ps.sources, ps.queue)); err == nil && conflict != "" {
+				ps.displayMessage(name+" conflicts with queued package "+conflict, true)
+				return
+			}
+		}
+
+		// we need the package base for AUR commands using {giturl} / {pkgbase}
+		info := InfoRecord{Name: name, Source: source, PackageBase: name}
+		if infoCached, found := ps.cacheInfo.Get(name + "-" + source); found {
+			info = infoCached.(InfoRecord)
+		} else if ps.selectedPackage != nil && ps.selectedPackage.Name == name && ps.selectedPackage.Source == source {
+			info = *ps.selectedPackage
+		}
+		ps.queue = append(ps.queue, queuedPackage{
+			InfoRecord: info,
+			Installed:  installed,
+		})
+	}
+	ps.drawQueueMarks()
+	ps.tablePackages.SetTitle(ps.packageListTitle(row))
+}
+
+// adds / removes the selected package to / from our ignore list (packages on this list are excluded from upgrades)
+func (ps *UI) toggleIgnored() {
+	if ps.selectedPackage == nil {
+		return
+	}
+	name := ps.selectedPackage.Name
+	if ps.selectedPackage.IsIgnored {
+		ps.displayMessage(name+" is ignored in pacman.conf (IgnorePkg / IgnoreGroup)", true)
+		return
+	}
+
+	msg := name + " has been added to the ignore list"
+	if i := util.IndexOf(ps.conf.IgnoredPackages, name); i != -1 {
+		ps.conf.IgnoredPackages = append(ps.conf.IgnoredPackages[:i], ps.conf.IgnoredPackages[i+1:]...)
+		msg = name + " has been removed from the ignore list"
+	} else if (IgnoreRules{Packages: ps.conf.IgnoredPackages}).matches(nil, name) {
+		ps.displayMessage(name+" is ignored by a pattern of the ignore list (see settings)", true)
+		return
+	} else {
+		ps.conf.IgnoredPackages = append(ps.conf.IgnoredPackages, name)
+	}
+	if err := ps.conf.Save(); err != nil {
+		ps.displayMessage(err.Error(), true)
+		return
+	}
+	ps.cacheInfo.Delete("#upgrades#")
+	ps.displayMessage(msg, false)
+	ps.drawPackageInfo(*ps.selectedPackage, ps.width)
+}
+
+// returns the position of a package in our queue (-1 if it is not queued)
+func (ps *UI) queueIndex(name, source string) int {
+	for i, q := range ps.queue {
+		if q.Name == name && q.Source == source {
+			return i
+		}
+	}
+	return -1
+}
+
+// returns the title of the package list: selected row, number of packages and queued packages
+func (ps *UI) packageListTitle(row int) string {
+	title := fmt.Sprintf(" (%d/%d) ", row, ps.tablePackages.GetRowCount()-1)
+	if len(ps.queue) > 0 {
+		title += fmt.Sprintf("- %d queued ", len(ps.queue))
+	}
+	if ps.conf.LocalFilter != "All" && ps.conf.LocalFilter != "" {
+		title += "- " + ps.conf.LocalFilter + " "
+	}
+	if ps.resultFilter.active() {
+		title += "- " + ps.resultFilter.String() + " "
+	}
+	if ps.connectivity.offline() {
+		title += "- offline, AUR results might be outdated "
+	}
+	if ps.searchPaging.loading {
+		title += "- loading more results "
+	} else if ps.searchPaging.more {
+		title += "- more results below "
+	}
+	return title
+}
+
+// switches to the next local filter (see localFilters) and repeats our last search / list of installed packages
+func (ps *UI) cycleLocalFilter() {
+	i := util.IndexOf(localFilters, ps.conf.LocalFilter)
+	ps.conf.LocalFilter = localFilters[(i+1)%len(localFilters)]
+	ps.cacheSearch.Flush()
+	ps.displayMessage("Showing packages: "+ps.conf.LocalFilter, false)
+
+	if len(ps.lastSearchTerm) < 2 {
+		ps.displayInstalled(false)
+		return
+	}
+	ps.displayPackages(ps.lastSearchTerm)
+}
+
+// displays the packages that are queued for a batch install / removal
+func (ps *UI) displayQueue() {
+	if ps.flexRight.GetItem(0) != ps.tableDetails {
+		ps.flexRight.Clear()
+		ps.flexRight.AddItem(ps.tableDetails, 0, 1, false)
+	}
+	ps.drawQueue()
+}
+
 // checks if a given package is currently selected in the package list
 func (ps *UI) isPackageSelected(pkg string, queue bool) bool {
 	var sel string
@@ -351,6 +886,367 @@ func (ps *UI) isPackageSelected(pkg string, queue bool) bool {
 	return sel == pkg
 }
 
+// refreshes the vulnerable packages in the background and marks them in the package list
+// failures are silently ignored here, they are shown in the advisories view
+func (ps *UI) updateVulnerabilities() {
+	if ps.conf.DisableAdvisories {
+		return
+	}
+	go func() {
+		vulnerable, err := ps.getVulnerabilities()
+		if err != nil {
+			return
+		}
+		ps.app.QueueUpdateDraw(func() {
+			ps.vulnerable = vulnerable
+			ps.drawAdvisoryMarks()
+		})
+	}()
+}
+
+// displays the security advisories affecting our installed packages
+func (ps *UI) displayAdvisories() {
+	ps.tableDetails.Clear().
+		SetTitle(" [::b]Checking for advisories... ")
+	ps.depTreeVisible = false
+	if ps.conf.DisableAdvisories {
+		ps.tableDetails.SetTitle(" [::b]Advisories ")
+		ps.tableDetails.SetCellSimple(0, 0, "Advisories are disabled in the settings")
+		return
+	}
+
+	go func() {
+		ps.locker.Lock()
+		ps.startSpinner()
+		defer ps.stopSpinner()
+		defer ps.locker.Unlock()
+
+		vulnerable, err := ps.getVulnerabilities()
+		ps.app.QueueUpdateDraw(func() {
+			if err != nil {
+				ps.tableDetails.SetTitle(" [::b]Error ")
+				ps.tableDetails.SetCell(0, 0, &tview.TableCell{
+					Text:            "Failed fetching advisories: " + err.Error(),
+					Color:           ps.conf.Colors().Error,
+					BackgroundColor: ps.conf.Colors().DefaultBackground,
+				})
+				return
+			}
+			ps.vulnerable = vulnerable
+			ps.drawAdvisoryMarks()
+			ps.drawAdvisories()
+		})
+	}()
+}
+
+// displays the packages requiring (or optionally using) the selected package
+// the tree is expanded node by node, the number of packages breaking on removal is computed in the background
+func (ps *UI) displayReverseDeps() {
+	if ps.selectedPackage == nil {
+		return
+	}
+	name := ps.selectedPackage.Name
+	r, err := newRevResolver(ps.handle(), name)
+	if err != nil {
+		ps.displayMessage(err.Error(), true)
+		return
+	}
+	ps.drawReverseDeps(r, name)
+
+	go func() {
+		ps.locker.Lock()
+		ps.startSpinner()
+		defer func() {
+			ps.locker.Unlock()
+			ps.stopSpinner()
+		}()
+
+		broken := r.breaking(name)
+		ps.app.QueueUpdateDraw(func() {
+			if ps.flexRight.GetItem(0) != ps.treeRevDeps || ps.treeRevDeps.GetRoot().GetText() != name {
+				return
+			}
+			ps.treeRevDeps.SetTitle(fmt.Sprintf(" [::b]%sRequired by - %s - %d package(s) break on removal ", ps.conf.Glyphs().Package, name, len(broken)))
+		})
+	}()
+}
+
+// displays a page of comments of an AUR package
+func (ps *UI) displayComments(pkg string, offset int) {
+	ps.commentsPkg = pkg
+	ps.commentsOffset = offset
+	ps.textComments.Clear().
+		SetTitle(" [::b]Loading comments... ")
+	ps.flexRight.Clear().
+		AddItem(ps.textComments, 0, 1, true)
+	ps.app.SetFocus(ps.textComments)
+
+	// check cache first
+	key := fmt.Sprintf("#comments#%s-%d", pkg, offset)
+	if pageCached, found := ps.cacheInfo.Get(key); found {
+		ps.drawComments(pageCached.(aurCommentPage))
+		return
+	}
+
+	go func() {
+		ps.locker.Lock()
+		ps.startSpinner()
+		defer func() {
+			ps.locker.Unlock()
+			ps.stopSpinner()
+		}()
+
+		page, err := getAurComments(fmt.Sprintf(UrlAurPackage, pkg), offset)
+		if err == nil && !ps.conf.DisableCache {
+			ps.cacheInfo.Set(key, page, time.Duration(ps.conf.CacheExpiry)*time.Minute)
+		}
+		ps.app.QueueUpdateDraw(func() {
+			if ps.flexRight.GetItem(0) != ps.textComments || ps.commentsPkg != pkg || ps.commentsOffset != offset {
+				return
+			}
+			if err != nil {
+				ps.textComments.SetTitle(" [::b]Error loading comments ")
+				ps.textComments.SetText(colorTag(ps.conf.Colors().Error) + tview.Escape(err.Error()))
+				return
+			}
+			ps.drawComments(page)
+		})
+	}()
+}
+
+// displays the changelog of a package
+// for installed packages we show the changelog file (if it ships one), followed by the recent history of the package's git repository
+func (ps *UI) displayChangelog(pkg InfoRecord) {
+	ps.changelogPkg = pkg.Name
+	ps.textChangelog.Clear().
+		SetTitle(" [::b]Loading changelog... ")
+	ps.flexRight.Clear().
+		AddItem(ps.textChangelog, 0, 1, true)
+	ps.app.SetFocus(ps.textChangelog)
+
+	local := ""
+	if pkg.LocalVersion != "" {
+		local, _ = localChangelog(ps.conf.PacmanDbPath, pkg.Name, pkg.LocalVersion)
+	}
+
+	// check cache first
+	key := "#changelog#" + pkg.Source + "-" + pkg.PackageBase
+	if cached, found := ps.cacheInfo.Get(key); found {
+		ps.drawChangelog(pkg.Name, formatChangelog(local, cached.([]changelogEntry)))
+		return
+	}
+
+	go func() {
+		ps.locker.Lock()
+		ps.startSpinner()
+		defer func() {
+			ps.locker.Unlock()
+			ps.stopSpinner()
+		}()
+
+		entries, err := getChangelog(getChangelogUrl(pkg.Source, pkg.PackageBase), pkg.Source == "AUR")
+		if err == nil && !ps.conf.DisableCache {
+			ps.cacheInfo.Set(key, entries, time.Duration(ps.conf.CacheExpiry)*time.Minute)
+		}
+		ps.app.QueueUpdateDraw(func() {
+			if ps.flexRight.GetItem(0) != ps.textChangelog || ps.changelogPkg != pkg.Name {
+				return
+			}
+			if err != nil && local == "" {
+				ps.textChangelog.SetTitle(" [::b]Error loading changelog ")
+				ps.textChangelog.SetText(colorTag(ps.conf.Colors().Error) + tview.Escape(err.Error()))
+				return
+			}
+			ps.drawChangelog(pkg.Name, formatChangelog(local, entries))
+		})
+	}()
+}
+
+// displays our debug console
+func (ps *UI) displayDebugConsole() {
+	ps.flexRight.Clear().
+		AddItem(ps.textDebug, 0, 1, true)
+	ps.app.SetFocus(ps.textDebug)
+	ps.drawDebugConsole()
+}
+
+// displays the file list of a package
+func (ps *UI) displayFiles(pkg InfoRecord) {
+	ps.filesPkg = pkg.Name
+	ps.files = []string{}
+	ps.inputFiles.SetText("")
+	ps.tableFiles.Clear()
+	ps.flexFiles.SetTitle(" [::b]Loading files... ")
+	ps.flexRight.Clear().
+		AddItem(ps.flexFiles, 0, 1, true)
+	ps.app.SetFocus(ps.inputFiles)
+
+	// check cache first
+	key := "#files#" + pkg.Name + "-" + pkg.Version + "-" + pkg.LocalVersion
+	if cached, found := ps.cacheInfo.Get(key); found {
+		ps.files = cached.([]string)
+		ps.drawFiles("")
+		return
+	}
+
+	go func() {
+		ps.locker.Lock()
+		ps.startSpinner()
+		defer func() {
+			ps.locker.Unlock()
+			ps.stopSpinner()
+		}()
+
+		files, err := packageFiles(ps.handle(), ps.conf.PacmanRootPath, ps.conf.PacmanDbPath, pkg.Name)
+		if err == nil && !ps.conf.DisableCache {
+			ps.cacheInfo.Set(key, files, time.Duration(ps.conf.CacheExpiry)*time.Minute)
+		}
+		ps.app.QueueUpdateDraw(func() {
+			if ps.flexRight.GetItem(0) != ps.flexFiles || ps.filesPkg != pkg.Name {
+				return
+			}
+			if err != nil {
+				ps.flexFiles.SetTitle(" [::b]Error loading files ")
+				ps.tableFiles.SetCell(0, 0, tview.NewTableCell(colorTag(ps.conf.Colors().Error)+tview.Escape(err.Error())).SetSelectable(false))
+				return
+			}
+			ps.files = files
+			ps.drawFiles(ps.inputFiles.GetText())
+		})
+	}()
+}
+
+// displays the versions of a package that can be found in our cache directories
+func (ps *UI) displayCachedVersions(pkg InfoRecord) {
+	ps.cachedPkg = pkg
+	ps.cachedVersions = cachedVersions(packageCacheDirs(ps.conf.PacmanConfigPath, ps.conf.PackageCacheDirs), pkg.Name)
+	ps.flexRight.Clear().
+		AddItem(ps.tableCache, 0, 1, true)
+	ps.app.SetFocus(ps.tableCache)
+	ps.drawCachedVersions()
+}
+
+// displays the install history from pacman's log file
+// the log file is read incrementally, only new entries are parsed when we show it again
+func (ps *UI) displayHistory() {
+	ps.inputHistory.SetText("")
+	ps.tableHistory.Clear()
+	ps.flexHistory.SetTitle(" [::b]Loading install history... ")
+	ps.flexRight.Clear().
+		AddItem(ps.flexHistory, 0, 1, true)
+	ps.app.SetFocus(ps.inputHistory)
+
+	go func() {
+		ps.locker.Lock()
+		ps.startSpinner()
+		defer func() {
+			ps.locker.Unlock()
+			ps.stopSpinner()
+		}()
+
+		if ps.history == nil {
+			ps.history = newPacmanLog(pacmanLogFile(ps.conf.PacmanConfigPath))
+		}
+		err := ps.history.update()
+		entries := append([]historyEntry{}, ps.history.entries...)
+		ps.app.QueueUpdateDraw(func() {
+			if ps.flexRight.GetItem(0) != ps.flexHistory {
+				return
+			}
+			if err != nil {
+				ps.flexHistory.SetTitle(" [::b]Error loading install history ")
+				ps.tableHistory.SetCell(0, 0, tview.NewTableCell(colorTag(ps.conf.Colors().Error)+tview.Escape(err.Error())).SetSelectable(false))
+				return
+			}
+			ps.historyEntries = entries
+			ps.drawHistory(ps.inputHistory.GetText())
+		})
+	}()
+}
+
+// returns the package information we need for installing a cached version of a package from our history
+func (ps *UI) historyPackage(name string) InfoRecord {
+	pkg := InfoRecord{Name: name, Source: "AUR"}
+	if ps.alpmHandle == nil {
+		return pkg
+	}
+	if local, err := ps.alpmHandle.LocalDB(); err == nil {
+		if lpkg := local.Pkg(name); lpkg != nil {
+			pkg.LocalVersion = lpkg.Version()
+		}
+	}
+	if dbs, err := ps.alpmHandle.SyncDBs(); err == nil {
+		if spkg := findSyncPackage(dbs, name); spkg != nil && spkg.DB() != nil {
+			pkg.Source = spkg.DB().Name()
+		}
+	}
+	return pkg
+}
+
+// displays the package groups of our sync db's
+func (ps *UI) displayGroups() {
+	ps.tableGroups.Clear().
+		SetTitle(" [::b]Loading package groups... ")
+	ps.flexRight.Clear().
+		AddItem(ps.tableGroups, 0, 1, true)
+	ps.app.SetFocus(ps.tableGroups)
+
+	go func() {
+		ps.locker.Lock()
+		ps.startSpinner()
+		defer func() {
+			ps.locker.Unlock()
+			ps.stopSpinner()
+		}()
+
+		groups, err := listGroups(ps.handle())
+		summaries := []packageGroup{}
+		if err == nil {
+			summaries, err = summarizeGroups(ps.handle(), groups)
+		}
+		ps.app.QueueUpdateDraw(func() {
+			if ps.flexRight.GetItem(0) != ps.tableGroups {
+				return
+			}
+			if err != nil {
+				ps.tableGroups.SetTitle(" [::b]Error loading package groups ")
+				ps.tableGroups.SetCell(0, 0, tview.NewTableCell(colorTag(ps.conf.Colors().Error)+tview.Escape(err.Error())).SetSelectable(false))
+				return
+			}
+			ps.groups = summaries
+			ps.drawGroups()
+		})
+	}()
+}
+
+// displays the members of a package group in our package list
+func (ps *UI) displayGroupMembers(group string) {
+	members, err := searchGroups(ps.handle(), group)
+	if err != nil {
+		ps.displayMessage(err.Error(), true)
+		return
+	}
+	ps.shownPackages = members
+	ps.searchPaging = searchPaging{}
+	ps.drawPackageListContent(members, ps.conf.PackageColumnWidth)
+	ps.app.SetFocus(ps.tablePackages)
+	ps.tablePackages.Select(1, 0)
+}
+
+// shows a single package and its details, e.g. "pacseek open yay" ("repo/name" to pick a repository)
+func (ps *UI) openPackage(name string) {
+	pkg, err := lookupPackage(ps.handle(), name)
+	if err != nil {
+		ps.displayMessage(err.Error(), true)
+		return
+	}
+	ps.shownPackages = []Package{pkg}
+	ps.searchPaging = searchPaging{}
+	ps.drawPackageListContent(ps.shownPackages, ps.conf.PackageColumnWidth)
+	ps.app.SetFocus(ps.tablePackages)
+	ps.tablePackages.Select(1, 0)
+}
+
 // displays a list of updatable packages
 func (ps *UI) displayUpgradable() {
 	ps.tableDetails.Clear().
@@ -358,7 +1254,7 @@ func (ps *UI) displayUpgradable() {
 
 	// check cache first
 	if cached, found := ps.cacheInfo.Get("#upgrades#"); found {
-		foundUp := cached.([]InfoRecord)
+		foundUp := cached.([]Upgrade)
 		ps.drawUpgradable(foundUp, true)
 		return
 	}
@@ -369,7 +1265,7 @@ func (ps *UI) displayUpgradable() {
 		defer ps.stopSpinner()
 		defer ps.locker.Unlock()
 
-		h, err := syncToTempDB(ps.conf.PacmanConfigPath, ps.filterRepos)
+		foundUp, err := ps.findUpgrades()
 		if err != nil {
 			ps.app.QueueUpdateDraw(func() {
 				ps.tableDetails.SetTitle(" [::b]Error ")
@@ -378,7 +1274,7 @@ func (ps *UI) displayUpgradable() {
 				for i, line := range lines {
 					ps.tableDetails.SetCell(i+1, 0, &tview.TableCell{
 						Text:            line,
-						Color:           tcell.ColorRed,
+						Color:           ps.conf.Colors().Error,
 						BackgroundColor: ps.conf.Colors().DefaultBackground,
 					})
 				}
@@ -387,29 +1283,14 @@ func (ps *UI) displayUpgradable() {
 			return
 		}
 
-		up, nf := getUpgradable(h, ps.conf.ComputeRequiredBy)
-		aurPkgs := infoAur(ps.conf.AurRpcUrl, ps.conf.AurTimeout, nf...)
-		for _, aurPkg := range aurPkgs.Results {
-			for i := 0; i < len(up); i++ {
-				if up[i].Source == "local" && up[i].Name == aurPkg.Name {
-					if alpm.VerCmp(aurPkg.Version, up[i].LocalVersion) > 0 {
-						up[i].Description = aurPkg.Description
-						up[i].Version = aurPkg.Version
-						up[i].Source = "AUR"
-					}
-				}
-			}
-		}
-		foundUp := []InfoRecord{}
-		for _, pkg := range up {
-			if pkg.Version != pkg.LocalVersion {
-				foundUp = append(foundUp, pkg)
-			}
-		}
 		if !ps.conf.DisableCache {
 			ps.cacheInfo.Set("#upgrades#", foundUp, time.Duration(ps.conf.CacheExpiry)*time.Minute)
 		}
+		state := ps.recordUpgrades(foundUp)
 		ps.app.QueueUpdateDraw(func() {
+			ps.upgradeState = state
+			ps.upgradeCount = countUpgrades(foundUp)
+			ps.drawTitle()
 			ps.drawUpgradable(foundUp, false)
 		})
 	}()
@@ -417,12 +1298,13 @@ func (ps *UI) displayUpgradable() {
 
 // displays list of installed packages
 func (ps *UI) displayInstalled(displayUpdatesAfter bool) {
+	ps.searchPaging = searchPaging{}
 	ps.tablePackages.Clear().
 		SetCellSimple(0, 0, "Generating list, please wait...")
 
 	// search cache
 	if installedCached, found := ps.cacheSearch.Get("#installed#"); found {
-		packages := installedCached.([]Package)
+		packages := filterLocal(ps.handle(), installedCached.([]Package), ps.conf.LocalFilter)
 		ps.shownPackages = packages
 		ps.drawPackageListContent(packages, ps.conf.PackageColumnWidth)
 		ps.tablePackages.Select(1, 0)
@@ -437,7 +1319,7 @@ func (ps *UI) displayInstalled(displayUpdatesAfter bool) {
 			ps.stopSpinner()
 		}()
 
-		in, nf := getInstalled(ps.alpmHandle, ps.conf.ComputeRequiredBy)
+		in, nf := getInstalled(ps.handle(), ps.conf.ComputeRequiredBy)
 		aurPkgs := ps.getInfo("AUR", nf...).Results
 		for _, aurPkg := range aurPkgs {
 			for i := 0; i < len(in); i++ {
@@ -455,11 +1337,14 @@ func (ps *UI) displayInstalled(displayUpdatesAfter bool) {
 		packages := []Package{}
 		for _, pkg := range in {
 			packages = append(packages, Package{
-				Name:         pkg.Name,
-				Source:       pkg.Source,
-				IsInstalled:  true,
-				LastModified: pkg.LastModified,
-				Popularity:   pkg.Popularity,
+				Name:          pkg.Name,
+				Source:        pkg.Source,
+				IsInstalled:   true,
+				LastModified:  pkg.LastModified,
+				HasBuildDate:  pkg.HasBuildDate,
+				Popularity:    pkg.Popularity,
+				NumVotes:      pkg.NumVotes,
+				InstalledSize: pkg.InstalledSize,
 			})
 			if !ps.conf.DisableCache {
 				ps.cacheInfo.Set(pkg.Name+"-"+pkg.Source, pkg, time.Duration(ps.conf.CacheExpiry)*time.Minute)
@@ -468,6 +1353,8 @@ func (ps *UI) displayInstalled(displayUpdatesAfter bool) {
 		if !ps.conf.DisableCache {
 			ps.cacheSearch.Set("#installed#", packages, time.Duration(ps.conf.CacheExpiry)*time.Minute)
 		}
+		ps.applyPopularity(packages)
+		packages = filterLocal(ps.handle(), packages, ps.conf.LocalFilter)
 		ps.shownPackages = packages
 		ps.app.QueueUpdateDraw(func() {
 			ps.drawPackageListContent(packages, ps.conf.PackageColumnWidth)
@@ -491,7 +1378,7 @@ func (ps *UI) autoComplete(text string) []string {
 		go func() {
 			ps.locker.Lock()
 			defer ps.locker.Unlock()
-			repo := suggestRepos(ps.alpmHandle, text)
+			repo := suggestRepos(ps.handle(), text)
 			aur := suggestAur(ps.conf.AurRpcUrl, text, ps.conf.AurTimeout)
 
 			max := 20
@@ -0,0 +1,136 @@
+package pacseek
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/moson-mo/pacseek/internal/config"
+	"github.com/moson-mo/pacseek/internal/util"
+)
+
+// returns the directory our AUR packages are downloaded to (their git repositories with the sources)
+func aurDownloadDir(conf *config.Settings) string {
+	if strings.TrimSpace(conf.AurDownloadDir) != "" {
+		return conf.AurDownloadDir
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "pacseek")
+	}
+	return filepath.Join(dir, "pacseek")
+}
+
+// replaces the placeholders of our AUR download command ({giturl} is the git repository of the package base)
+// unlike our install commands, the package name is not appended
+func aurCommand(command string, pkg InfoRecord) string {
+	base := pkg.PackageBase
+	if base == "" {
+		base = pkg.Name
+	}
+	return strings.NewReplacer(
+		"{pkg}", pkg.Name,
+		"{pkgbase}", base,
+		"{source}", pkg.Source,
+		"{giturl}", "https://aur.archlinux.org/"+base+".git",
+		"{optdepends}", "",
+	).Replace(command)
+}
+
+// returns the repository packages that are needed to build AUR packages (and not installed yet)
+// dependencies that are not found in our sync db's are most likely AUR packages, they have to be downloaded separately
+func aurRepoDependencies(h dbHandle, pkgs []InfoRecord) []string {
+	deps := []string{}
+	if h == nil {
+		return deps
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return deps
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return deps
+	}
+	installed := local.PkgCache()
+	for _, pkg := range pkgs {
+		for _, dep := range append(append([]string{}, pkg.Depends...), pkg.MakeDepends...) {
+			if s, err := installed.FindSatisfier(dep); err == nil && s != nil {
+				continue
+			}
+			if s, err := dbs.FindSatisfier(dep); err == nil && s != nil && !util.SliceContains(deps, s.Name()) {
+				deps = append(deps, s.Name())
+			}
+		}
+	}
+	return deps
+}
+
+// returns the commands for downloading packages without installing them
+// repository packages (and the repository dependencies of AUR packages) are downloaded to the pacman cache with our download command (pacman -Sw),
+// which resolves their dependencies as well. AUR packages are cloned to our download directory and their sources are downloaded (makepkg -o)
+// packages of additional sources (e.g. Flatpak) can't be downloaded
+func downloadCommands(conf *config.Settings, sources []packageSource, pkgs []InfoRecord, aurDeps []string) []string {
+	repo, aur := []string{}, []string{}
+	for _, pkg := range pkgs {
+		switch {
+		case findSource(sources, pkg.Source) != nil:
+			continue
+		case pkg.Source == "AUR":
+			aur = append(aur, "("+
+				"mkdir -p \""+aurDownloadDir(conf)+"\" && cd \""+aurDownloadDir(conf)+"\" && "+
+				aurCommand(conf.AurDownloadCommand, pkg)+")")
+		case !util.SliceContains(repo, installTarget(conf, pkg)):
+			repo = append(repo, installTarget(conf, pkg))
+		}
+	}
+	for _, dep := range aurDeps {
+		if !util.SliceContains(repo, dep) {
+			repo = append(repo, dep)
+		}
+	}
+
+	commands := []string{}
+	if len(repo) > 0 {
+		commands = append(commands, withPackages(conf.DownloadCommand, strings.Join(repo, " ")))
+	}
+	return append(commands, aur...)
+}
+
+// downloads packages without installing them (e.g. to install them later on when we're offline)
+func (ps *UI) downloadPackages(pkgs []InfoRecord) {
+	aur := []InfoRecord{}
+	for _, pkg := range pkgs {
+		if pkg.Source == "AUR" {
+			aur = append(aur, pkg)
+		}
+	}
+	commands := downloadCommands(ps.conf, ps.sources, pkgs, aurRepoDependencies(ps.handle(), aur))
+	if len(commands) == 0 {
+		ps.displayMessage("Nothing to download", true)
+		return
+	}
+	command := strings.Join(commands, " && ")
+	if err := ps.preflight(command); err != nil {
+		ps.displayMessage(err.Error(), true)
+		return
+	}
+	ps.runCommand(ps.shell, "-c", command)
+}
+
+// downloads our queued packages (the ones that are not installed) or the selected one
+func (ps *UI) downloadSelected() {
+	pkgs := []InfoRecord{}
+	for _, q := range ps.queue {
+		if !q.Installed {
+			pkgs = append(pkgs, q.InfoRecord)
+		}
+	}
+	if len(pkgs) == 0 && ps.selectedPackage != nil {
+		pkgs = append(pkgs, *ps.selectedPackage)
+	}
+	if len(pkgs) == 0 {
+		return
+	}
+	ps.downloadPackages(pkgs)
+}
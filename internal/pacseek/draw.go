@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Jguer/go-alpm/v2"
 	"github.com/alecthomas/chroma/quick"
 	"github.com/gdamore/tcell/v2"
 	"github.com/moson-mo/pacseek/internal/config"
@@ -18,17 +19,32 @@ import (
 // draws input fields on settings form
 func (ps *UI) drawSettingsFields(disableAur, disableCache, separateAurCommands, pkgbuildInternal, disableFeed bool) {
 	ps.formSettings.Clear(false)
-	mode := 0
-	if ps.conf.SearchMode != "StartsWith" {
+	searchModes := []string{"StartsWith", "Contains", "Fuzzy", "Regex"}
+	mode := util.IndexOf(searchModes, ps.conf.SearchMode)
+	if mode == -1 {
 		mode = 1
 	}
-	by := 0
-	if ps.conf.SearchBy != "Name" {
+	searchBy := []string{"Name", "Name & Description", "Keywords", "Broad", "Maintainer", "Provides", "File"}
+	by := util.IndexOf(searchBy, ps.conf.SearchBy)
+	if by == -1 {
 		by = 1
 	}
+	lf := util.IndexOf(localFilters, ps.conf.LocalFilter)
+	if lf == -1 {
+		lf = 0
+	}
+	so := util.IndexOf(sortCriteria, ps.conf.SortResults)
+	if so == -1 {
+		so = 0
+	}
 	cIndex := util.IndexOf(config.ColorSchemes(), ps.conf.ColorScheme)
 	bIndex := util.IndexOf(config.BorderStyles(), ps.conf.BorderStyle)
 	gIndex := util.IndexOf(config.GlyphStyles(), ps.conf.GlyphStyle)
+	sg := util.IndexOf(graphicsProtocols, ps.conf.ScreenshotGraphics)
+	if sg == -1 {
+		sg = 0
+	}
+	ll := parseLogLevel(ps.conf.LogLevel)
 
 	// handle text/drop-down field changes
 	sc := func(txt string) {
@@ -39,7 +55,9 @@ func (ps *UI) drawSettingsFields(disableAur, disableCache, separateAurCommands,
 	ps.formSettings.AddDropDown("Color scheme: ", config.ColorSchemes(), cIndex, nil)
 	if dd, ok := ps.formSettings.GetFormItemByLabel("Color scheme: ").(*tview.DropDown); ok {
 		dd.SetSelectedFunc(func(text string, index int) {
-			ps.conf.SetColorScheme(text)
+			if err := ps.conf.SetColorScheme(text); err != nil {
+				ps.displayMessage(err.Error(), true)
+			}
 			if cb, ok := ps.formSettings.GetFormItemByLabel("Transparent: ").(*tview.Checkbox); ok {
 				ps.conf.SetTransparency(cb.IsChecked())
 			}
@@ -84,8 +102,29 @@ func (ps *UI) drawSettingsFields(disableAur, disableCache, separateAurCommands,
 	if !disableAur {
 		ps.formSettings.AddInputField("AUR RPC URL: ", ps.conf.AurRpcUrl, 40, nil, sc).
 			AddInputField("AUR timeout (ms): ", strconv.Itoa(ps.conf.AurTimeout), 6, nil, sc).
-			AddInputField("AUR search delay (ms): ", strconv.Itoa(ps.conf.AurSearchDelay), 6, nil, sc)
+			AddInputField("AUR search delay (ms): ", strconv.Itoa(ps.conf.AurSearchDelay), 6, nil, sc).
+			AddInputField("AUR ignore patterns: ", strings.Join(ps.conf.AurIgnore, " "), 40, nil, sc).
+			AddCheckbox("Hide out-of-date: ", ps.conf.HideOutOfDate, func(checked bool) {
+				ps.settingsChanged = true
+			}).
+			AddCheckbox("Build AUR in chroot: ", ps.conf.AurChrootBuild, func(checked bool) {
+				ps.settingsChanged = true
+			}).
+			AddInputField("Chroot build packages: ", strings.Join(ps.conf.AurChrootPackages, " "), 40, nil, sc).
+			AddInputField("Chroot build command: ", ps.conf.AurChrootCommand, 40, nil, sc).
+			AddCheckbox("AUR voting (SSH): ", ps.conf.AurVoting, func(checked bool) {
+				ps.settingsChanged = true
+			}).
+			AddInputField("AUR SSH command: ", ps.conf.AurSshCommand, 40, nil, sc)
 	}
+	ps.formSettings.AddCheckbox("Enable Flatpak: ", ps.conf.EnableFlatpak, func(checked bool) {
+		ps.settingsChanged = true
+		ps.drawSettingsValidation()
+	}).
+		AddInputField("Flatpak install command: ", ps.conf.FlatpakInstallCommand, 40, nil, sc).
+		AddInputField("Flatpak uninstall command: ", ps.conf.FlatpakUninstallCommand, 40, nil, sc).
+		AddInputField("Flatpak upgrade command: ", ps.conf.FlatpakUpgradeCommand, 40, nil, sc).
+		AddInputField("Plugins: ", ps.conf.Plugins, 40, nil, sc)
 	ps.formSettings.AddCheckbox("Disable Cache: ", disableCache, func(checked bool) {
 		ps.settingsChanged = true
 		i, _ := ps.formSettings.GetFocusedItemIndex()
@@ -96,25 +135,90 @@ func (ps *UI) drawSettingsFields(disableAur, disableCache, separateAurCommands,
 	if !disableCache {
 		ps.formSettings.AddInputField("Cache expiry (m): ", strconv.Itoa(ps.conf.CacheExpiry), 6, nil, sc)
 	}
+	ps.formSettings.AddCheckbox("Disable disk cache: ", ps.conf.DisableDiskCache, func(checked bool) {
+		ps.settingsChanged = true
+	})
+	ps.formSettings.AddInputField("Disk cache expiry (m): ", strconv.Itoa(ps.conf.DiskCacheExpiry), 6, nil, sc)
 	ps.formSettings.AddInputField("Max search results: ", strconv.Itoa(ps.conf.MaxResults), 6, nil, sc).
-		AddDropDown("Search mode: ", []string{"StartsWith", "Contains"}, mode, func(text string, index int) {
+		AddCheckbox("Disable lazy loading: ", ps.conf.DisableLazyLoading, func(checked bool) {
+			ps.settingsChanged = true
+		}).
+		AddCheckbox("Disable pkgstats: ", ps.conf.DisablePkgstats, func(checked bool) {
+			ps.settingsChanged = true
+		}).
+		AddCheckbox("Disable AppStream: ", ps.conf.DisableAppStream, func(checked bool) {
+			ps.settingsChanged = true
+		}).
+		AddDropDown("Screenshot graphics: ", graphicsProtocols, sg, func(text string, index int) {
+			if text != ps.conf.ScreenshotGraphics {
+				ps.settingsChanged = true
+			}
+		}).
+		AddInputField("Max dependencies: ", strconv.Itoa(ps.conf.MaxDependencies), 6, nil, sc).
+		AddInputField("Broad search warning: ", strconv.Itoa(ps.conf.BroadSearchWarning), 6, nil, sc).
+		AddInputField("Exclude sources: ", strings.Join(ps.conf.ExcludeSources, " "), 40, nil, sc).
+		AddInputField("Repo priority: ", strings.Join(ps.conf.RepoPriority, " "), 40, nil, sc).
+		AddCheckbox("Disable repo merging: ", ps.conf.DisableRepoMerge, func(checked bool) {
+			ps.settingsChanged = true
+		}).
+		AddInputField("Ignored packages: ", strings.Join(ps.conf.IgnoredPackages, " "), 40, nil, sc).
+		AddDropDown("Search mode: ", searchModes, mode, func(text string, index int) {
 			if text != ps.conf.SearchMode {
 				ps.settingsChanged = true
 			}
 		}).
-		AddDropDown("Search by: ", []string{"Name", "Name & Description"}, by, func(text string, index int) {
+		AddDropDown("Search by: ", searchBy, by, func(text string, index int) {
 			if text != ps.conf.SearchBy {
 				ps.settingsChanged = true
 			}
 		}).
+		AddDropDown("Local filter: ", localFilters, lf, func(text string, index int) {
+			if text != ps.conf.LocalFilter {
+				ps.settingsChanged = true
+			}
+		}).
+		AddDropDown("Sort results by: ", sortCriteria, so, func(text string, index int) {
+			if text != ps.conf.SortResults {
+				ps.settingsChanged = true
+			}
+		}).
+		AddCheckbox("Prefer name matches: ", ps.conf.PreferNameMatches, func(checked bool) {
+			ps.settingsChanged = true
+		}).
+		AddCheckbox("Preserve repo order: ", ps.conf.PreserveRepoOrder, func(checked bool) {
+			ps.settingsChanged = true
+		}).
+		AddCheckbox("Match name segments: ", ps.conf.SegmentPrefixMatch, func(checked bool) {
+			ps.settingsChanged = true
+		}).
+		AddCheckbox("Case sensitive search: ", ps.conf.CaseSensitive, func(checked bool) {
+			ps.settingsChanged = true
+		}).
 		AddCheckbox("Enable Auto-suggest: ", ps.conf.EnableAutoSuggest, func(checked bool) {
 			ps.settingsChanged = true
 		}).
+		AddCheckbox("Search as you type: ", ps.conf.LiveSearch, func(checked bool) {
+			ps.settingsChanged = true
+		}).
+		AddInputField("Live search delay (ms): ", strconv.Itoa(ps.conf.LiveSearchDelay), 6, nil, sc).
+		AddInputField("Live search min. length: ", strconv.Itoa(ps.conf.LiveSearchMinLength), 6, nil, sc).
 		AddCheckbox("Compute \"Required by\": ", ps.conf.ComputeRequiredBy, func(checked bool) {
 			ps.settingsChanged = true
 		}).
+		AddInputField("Pacman root path: ", ps.conf.PacmanRootPath, 40, nil, sc).
 		AddInputField("Pacman DB path: ", ps.conf.PacmanDbPath, 40, nil, sc).
 		AddInputField("Pacman config path: ", ps.conf.PacmanConfigPath, 40, nil, sc).
+		AddCheckbox("Skip failing repos: ", ps.conf.SkipFailingRepos, func(checked bool) {
+			ps.settingsChanged = true
+		}).
+		AddDropDown("Log level: ", logLevels, ll, func(text string, index int) {
+			if text != ps.conf.LogLevel {
+				ps.settingsChanged = true
+			}
+		}).
+		AddCheckbox("Read-only mode: ", ps.conf.ReadOnly, func(checked bool) {
+			ps.settingsChanged = true
+		}).
 		AddCheckbox("Separate AUR commands: ", separateAurCommands, func(checked bool) {
 			ps.settingsChanged = true
 			i, _ := ps.formSettings.GetFocusedItemIndex()
@@ -137,6 +241,18 @@ func (ps *UI) drawSettingsFields(disableAur, disableCache, separateAurCommands,
 	ps.formSettings.AddInputField("Install command: ", ps.conf.InstallCommand, 40, nil, sc).
 		AddInputField("Upgrade command: ", ps.conf.SysUpgradeCommand, 40, nil, sc).
 		AddInputField("Uninstall command: ", ps.conf.UninstallCommand, 40, nil, sc).
+		AddCheckbox("Disable install preview: ", ps.conf.DisableInstallPreview, func(checked bool) {
+			ps.settingsChanged = true
+		}).
+		AddInputField("Downgrade command: ", ps.conf.DowngradeCommand, 40, nil, sc).
+		AddInputField("Download command: ", ps.conf.DownloadCommand, 40, nil, sc).
+		AddInputField("AUR download command: ", ps.conf.AurDownloadCommand, 40, nil, sc).
+		AddInputField("AUR download dir: ", ps.conf.AurDownloadDir, 40, nil, sc).
+		AddCheckbox("Disable .pacnew check: ", ps.conf.DisablePacnewCheck, func(checked bool) {
+			ps.settingsChanged = true
+		}).
+		AddInputField("Merge command: ", ps.conf.MergeCommand, 40, nil, sc).
+		AddInputField("Package cache dirs: ", ps.conf.PackageCacheDirs, 40, nil, sc).
 		AddCheckbox("Show PKGBUILD internally: ", pkgbuildInternal, func(checked bool) {
 			ps.settingsChanged = true
 			i, _ := ps.formSettings.GetFocusedItemIndex()
@@ -158,8 +274,22 @@ func (ps *UI) drawSettingsFields(disableAur, disableCache, separateAurCommands,
 	if !disableFeed {
 		ps.formSettings.AddInputField("News-feed URL(s): ", ps.conf.FeedURLs, 40, nil, sc)
 		ps.formSettings.AddInputField("News-feed max items: ", strconv.Itoa(ps.conf.FeedMaxItems), 6, nil, sc)
+		ps.formSettings.AddCheckbox("Disable news warning: ", ps.conf.DisableNewsWarning, func(checked bool) {
+			ps.settingsChanged = true
+		})
 	}
 
+	ps.formSettings.AddCheckbox("Disable advisories: ", ps.conf.DisableAdvisories, func(checked bool) {
+		ps.settingsChanged = true
+	})
+	ps.formSettings.AddInputField("Security tracker URL: ", ps.conf.SecurityTrackerUrl, 40, nil, sc)
+
+	ps.formSettings.AddCheckbox("Show update status: ", ps.conf.ShowUpdateStatus, func(checked bool) {
+		ps.settingsChanged = true
+	})
+	ps.formSettings.AddInputField("Update check interval (m): ", strconv.Itoa(ps.conf.UpdateCheckInterval), 6, nil, sc)
+	ps.formSettings.AddInputField("Quiet hours: ", ps.conf.QuietHours, 12, nil, sc)
+
 	ps.formSettings.AddInputField("Package column width: ", strconv.Itoa(ps.conf.PackageColumnWidth), 6, nil, func(text string) {
 		ps.settingsChanged = true
 		width, _ := strconv.Atoi(text)
@@ -170,6 +300,7 @@ func (ps *UI) drawSettingsFields(disableAur, disableCache, separateAurCommands,
 	})
 
 	ps.applyDropDownColors()
+	ps.addSettingsValidation()
 
 	// key bindings
 	ps.formSettings.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
@@ -231,6 +362,7 @@ func (ps *UI) drawPackageInfo(i InfoRecord, width int) {
 	// clear content and set name
 	ps.tableDetails.Clear().
 		SetTitle(" [::b]" + ps.conf.Glyphs().Package + i.Name + " ")
+	ps.depTreeVisible = false
 	r := 0
 	ln := 0
 
@@ -281,7 +413,7 @@ func (ps *UI) drawPackageInfo(i InfoRecord, width int) {
 				}
 				cell := &tview.TableCell{
 					Text:            l,
-					Color:           tcell.ColorWhite,
+					Color:           ps.conf.Colors().Text,
 					BackgroundColor: ps.conf.Colors().DefaultBackground,
 				}
 				if k == "Description" {
@@ -297,7 +429,7 @@ func (ps *UI) drawPackageInfo(i InfoRecord, width int) {
 						return true
 					})
 				}
-				if k == "Maintainer" && i.Source == "AUR" {
+				if k == "Maintainer" && i.Source == "AUR" && i.Maintainer != "" {
 					cell.SetClickedFunc(func() bool {
 						exec.Command("xdg-open", fmt.Sprintf(UrlAurMaintainer, v)).Start()
 						return true
@@ -321,8 +453,679 @@ func (ps *UI) drawPackageInfo(i InfoRecord, width int) {
 	ps.tableDetails.ScrollToBeginning()
 }
 
+// draw dependency tree of a package
+func (ps *UI) drawDepTree(tree *depNode) {
+	ps.tableDetails.Clear().
+		SetTitle(" [::b]" + ps.conf.Glyphs().Package + "Dependencies - " + tree.name + " ")
+	ps.depTreeVisible = true
+
+	colors := map[string]tcell.Color{
+		depInstalled: ps.conf.Colors().PackagelistSourceRepository,
+		depRepo:      ps.conf.Colors().Text,
+		depAur:       ps.conf.Colors().PackagelistSourceAUR,
+		depMissing:   ps.conf.Colors().Error,
+	}
+	kinds := tree.kinds()
+	for r, l := range tree.lines(ps.asciiMode) {
+		ps.tableDetails.SetCell(r, 0, &tview.TableCell{
+			Text:            tview.Escape(l),
+			Color:           colors[kinds[r]],
+			BackgroundColor: ps.conf.Colors().DefaultBackground,
+		})
+	}
+
+	// check if we got more lines than current screen height
+	_, _, _, height := ps.tableDetails.GetInnerRect()
+	ps.tableDetailsMore = ps.tableDetails.GetRowCount() > height-1
+	ps.tableDetails.ScrollToBeginning()
+}
+
+// draw a page of AUR comments
+func (ps *UI) drawComments(page aurCommentPage) {
+	ps.commentsMore = page.HasMore
+	nav := []string{}
+	if page.Offset > 0 {
+		nav = append(nav, "p: previous")
+	}
+	if page.HasMore {
+		nav = append(nav, "n: next")
+	}
+	title := fmt.Sprintf(" [::b]%sComments - %s (page %d) ", ps.conf.Glyphs().Pkgbuild, ps.commentsPkg, page.Offset/aurCommentsPerPage+1)
+	if len(nav) > 0 {
+		title += "[::-](" + strings.Join(nav, ", ") + ") "
+	}
+	ps.textComments.SetTitle(title)
+
+	if len(page.Comments) == 0 {
+		ps.textComments.SetText("No comments")
+		return
+	}
+	var sb strings.Builder
+	for _, c := range page.Comments {
+		if c.Pinned {
+			sb.WriteString("[yellow::b]Pinned[-::-] ")
+		}
+		sb.WriteString(fmt.Sprintf("[#%06x::b]", ps.conf.Colors().Accent.Hex()) + tview.Escape(c.Author) + "[-::-] commented on " + tview.Escape(c.Date) + "\n\n")
+		sb.WriteString(tview.Escape(c.Text) + "\n\n\n")
+	}
+	ps.textComments.SetText(sb.String())
+	ps.textComments.ScrollToBeginning()
+}
+
+// draw our title, with the number of available upgrades if we've checked for them
+func (ps *UI) drawTitle() {
+	title := " [::b]" + ps.conf.Glyphs().Package + "pacseek - v" + version + " "
+	if ps.upgradeCount > 0 {
+		title += fmt.Sprintf("[::-]- %d updates available ", ps.upgradeCount)
+	}
+	if ps.readOnly() {
+		title += "[::-]- read-only "
+	}
+	ps.flexRoot.SetTitle(title)
+}
+
+// draw the cached versions of a package, the installed one is highlighted
+func (ps *UI) drawCachedVersions() {
+	ps.tableCache.Clear().
+		SetTitle(" [::b]" + ps.conf.Glyphs().Package + "Cached versions - " + ps.cachedPkg.Name + " [::-](ENTER: install selected version) ")
+
+	if len(ps.cachedVersions) == 0 {
+		ps.tableCache.SetCell(0, 0, &tview.TableCell{
+			Text:            "No cached versions found",
+			Color:           ps.conf.Colors().PackagelistHeader,
+			BackgroundColor: ps.conf.Colors().DefaultBackground,
+		})
+		return
+	}
+
+	// header
+	for i, col := range []string{"Version  ", "Architecture  ", "Size  ", "File"} {
+		ps.tableCache.SetCell(0, i, &tview.TableCell{
+			Text:            col,
+			Color:           ps.conf.Colors().PackagelistHeader,
+			BackgroundColor: ps.conf.Colors().DefaultBackground,
+			NotSelectable:   true,
+		})
+	}
+
+	for r, c := range ps.cachedVersions {
+		color := ps.conf.Colors().Text
+		version := c.Version
+		if c.Version == ps.cachedPkg.LocalVersion {
+			color = ps.conf.Colors().PackagelistSourceRepository
+			version += " (installed)"
+		} else if ps.cachedPkg.LocalVersion != "" && alpm.VerCmp(c.Version, ps.cachedPkg.LocalVersion) < 0 {
+			version += " (older)"
+		}
+		for i, text := range []string{version, c.Arch, util.FormatSize(c.Size), c.Path} {
+			ps.tableCache.SetCell(r+1, i, &tview.TableCell{
+				Text:            tview.Escape(text) + "  ",
+				Color:           color,
+				BackgroundColor: ps.conf.Colors().DefaultBackground,
+			})
+		}
+	}
+	ps.tableCache.ScrollToBeginning()
+	ps.tableCache.Select(1, 0)
+}
+
+// draw the list of .pacnew / .pacsave files, the ones that don't exist anymore have been merged
+func (ps *UI) drawPacnew() {
+	title := ".pacnew / .pacsave files"
+	if ps.pacnewTransaction {
+		title = "New .pacnew / .pacsave files"
+	}
+	ps.tablePacnew.Clear().
+		SetTitle(" [::b]" + ps.conf.Glyphs().Package + title + " [::-](ENTER: merge, ESC: close) ")
+
+	if len(ps.pacnewFiles) == 0 {
+		ps.tablePacnew.SetCell(0, 0, &tview.TableCell{
+			Text:            "No .pacnew / .pacsave files found",
+			Color:           ps.conf.Colors().PackagelistHeader,
+			BackgroundColor: ps.conf.Colors().DefaultBackground,
+		})
+		return
+	}
+
+	// header
+	for i, col := range []string{"Type  ", "Config file  ", "Modified  ", ""} {
+		ps.tablePacnew.SetCell(0, i, &tview.TableCell{
+			Text:            col,
+			Color:           ps.conf.Colors().PackagelistHeader,
+			BackgroundColor: ps.conf.Colors().DefaultBackground,
+			NotSelectable:   true,
+		})
+	}
+
+	for r, f := range ps.pacnewFiles {
+		color, status := ps.conf.Colors().Text, ""
+		if f.merged() {
+			color, status = ps.conf.Colors().Installed, "merged"
+		}
+		for i, text := range []string{f.Kind, f.Original, f.ModTime.Format("2006-01-02 15:04"), status} {
+			ps.tablePacnew.SetCell(r+1, i, &tview.TableCell{
+				Text:            tview.Escape(text) + "  ",
+				Color:           color,
+				BackgroundColor: ps.conf.Colors().DefaultBackground,
+			})
+		}
+	}
+	row, _ := ps.tablePacnew.GetSelection()
+	if row < 1 || row > len(ps.pacnewFiles) {
+		ps.tablePacnew.ScrollToBeginning()
+		ps.tablePacnew.Select(1, 0)
+	}
+}
+
+// draw the list of package groups
+func (ps *UI) drawGroups() {
+	ps.tableGroups.Clear().
+		SetTitle(" [::b]" + ps.conf.Glyphs().Package + "Package groups [::-](ENTER: show members, i: install group) ")
+
+	if len(ps.groups) == 0 {
+		ps.tableGroups.SetCell(0, 0, &tview.TableCell{
+			Text:            "No package groups found",
+			Color:           ps.conf.Colors().PackagelistHeader,
+			BackgroundColor: ps.conf.Colors().DefaultBackground,
+		})
+		return
+	}
+
+	// header
+	for i, col := range []string{"Group  ", "Packages  ", "Installed"} {
+		ps.tableGroups.SetCell(0, i, &tview.TableCell{
+			Text:            col,
+			Color:           ps.conf.Colors().PackagelistHeader,
+			BackgroundColor: ps.conf.Colors().DefaultBackground,
+			NotSelectable:   true,
+		})
+	}
+
+	for r, g := range ps.groups {
+		color := ps.conf.Colors().Text
+		if g.Installed == g.Members {
+			color = ps.conf.Colors().PackagelistSourceRepository
+		}
+		for i, text := range []string{g.Name, strconv.Itoa(g.Members), strconv.Itoa(g.Installed)} {
+			ps.tableGroups.SetCell(r+1, i, &tview.TableCell{
+				Text:            tview.Escape(text) + "  ",
+				Color:           color,
+				BackgroundColor: ps.conf.Colors().DefaultBackground,
+			})
+		}
+	}
+	ps.tableGroups.ScrollToBeginning()
+	ps.tableGroups.Select(1, 0)
+}
+
+// draw the (filtered) file list of a package
+func (ps *UI) drawFiles(filter string) {
+	files := filterFiles(ps.files, filter)
+	ps.flexFiles.SetTitle(fmt.Sprintf(" [::b]%sFiles - %s (%d/%d) ", ps.conf.Glyphs().Package, ps.filesPkg, len(files), len(filterFiles(ps.files, ""))))
+	ps.tableFiles.Clear()
+	for i, f := range files {
+		ps.tableFiles.SetCell(i, 0, tview.NewTableCell("/"+tview.Escape(f)).
+			SetBackgroundColor(ps.conf.Colors().DefaultBackground))
+	}
+	ps.tableFiles.ScrollToBeginning()
+	ps.tableFiles.Select(0, 0)
+}
+
+// draw the (filtered) install history, newest entries first
+func (ps *UI) drawHistory(filter string) {
+	ps.shownHistory = filterHistory(ps.historyEntries, filter)
+	ps.flexHistory.SetTitle(fmt.Sprintf(" [::b]%sInstall history (%d/%d) [::-](ENTER: cached versions, t: timeline of package) ", ps.conf.Glyphs().Package, len(ps.shownHistory), len(ps.historyEntries)))
+	ps.tableHistory.Clear()
+
+	// header
+	for i, col := range []string{"Date  ", "Action  ", "Package  ", "Version"} {
+		ps.tableHistory.SetCell(0, i, &tview.TableCell{
+			Text:            col,
+			Color:           ps.conf.Colors().PackagelistHeader,
+			BackgroundColor: ps.conf.Colors().DefaultBackground,
+			NotSelectable:   true,
+		})
+	}
+
+	for r, e := range ps.shownHistory {
+		color := ps.conf.Colors().Text
+		switch e.Action {
+		case "installed":
+			color = ps.conf.Colors().PackagelistSourceRepository
+		case "removed":
+			color = ps.conf.Colors().Error
+		case "downgraded":
+			color = ps.conf.Colors().Warning
+		}
+		version := e.Version
+		if e.OldVersion != "" {
+			version = e.OldVersion + " -> " + e.Version
+		}
+		for i, text := range []string{e.Date.Local().Format("2006-01-02 15:04"), e.Action, e.Name, version} {
+			ps.tableHistory.SetCell(r+1, i, &tview.TableCell{
+				Text:            tview.Escape(text) + "  ",
+				Color:           color,
+				BackgroundColor: ps.conf.Colors().DefaultBackground,
+			})
+		}
+	}
+	ps.tableHistory.ScrollToBeginning()
+	ps.tableHistory.Select(1, 0)
+}
+
+// draw the preview of an install transaction
+func (ps *UI) drawTransactionPreview(preview transactionPreview, err error) {
+	ps.textPreview.SetTitle(" [::b]Transaction preview [::-](ENTER: proceed, ESC: cancel) ")
+	if err != nil {
+		ps.textPreview.SetText(colorTag(ps.conf.Colors().Error) + tview.Escape(err.Error()) + "[-]\n\nPress ENTER to run the install command anyway")
+		return
+	}
+
+	var sb strings.Builder
+	for _, section := range []struct{ action, label string }{
+		{"install", "Install"},
+		{"upgrade", "Upgrade"},
+		{"downgrade", "Downgrade"},
+		{"reinstall", "Reinstall"},
+		{"remove", "Remove"},
+	} {
+		action := section.action
+		lines := []string{}
+		for _, e := range preview.Entries {
+			if e.Action != action {
+				continue
+			}
+			line := fmt.Sprintf("  %-40s ", tview.Escape(e.Repo+"/"+e.Name))
+			switch action {
+			case "install", "reinstall":
+				line += e.Version
+			case "remove":
+				line += e.LocalVersion + " " + colorTag(ps.conf.Colors().Error) + "(conflicts with " + tview.Escape(e.ConflictsWith) + ")[-]"
+			default:
+				line += e.LocalVersion + " -> " + e.Version
+			}
+			lines = append(lines, line)
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		color := ps.conf.Colors().Accent
+		if action == "remove" || action == "downgrade" {
+			color = ps.conf.Colors().Error
+		}
+		sb.WriteString(fmt.Sprintf("[#%06x::b]%s (%d)[-::-]\n", color.Hex(), section.label, len(lines)))
+		sb.WriteString(strings.Join(lines, "\n") + "\n\n")
+	}
+	sb.WriteString(fmt.Sprintf("[::b]Total download size:[::-] %s\n", util.FormatSize(preview.DownloadSize)))
+	sb.WriteString(fmt.Sprintf("[::b]Net upgrade size:[::-]    %s", formatSizeDelta(preview.InstalledSizeDelta)))
+	ps.textPreview.SetText(sb.String())
+	ps.textPreview.ScrollToBeginning()
+}
+
+// draw the changelog of a package
+func (ps *UI) drawChangelog(name, text string) {
+	ps.textChangelog.SetTitle(" [::b]" + ps.conf.Glyphs().Pkgbuild + "Changelog - " + name + " ")
+	if text == "" {
+		text = "No changelog available"
+	}
+	ps.textChangelog.SetText(tview.Escape(text))
+	ps.textChangelog.ScrollToBeginning()
+}
+
+// draws the last lines of our log, warnings and errors are highlighted
+func (ps *UI) drawDebugConsole() {
+	ps.textDebug.SetTitle(" [::b]Debug console - " + logger.filePath() + " ")
+	lines := logger.last(0)
+	if len(lines) == 0 {
+		ps.textDebug.SetText("Nothing has been logged (see \"Log level\" in the settings)")
+		return
+	}
+	var sb strings.Builder
+	for _, line := range lines {
+		switch {
+		case strings.Contains(line, " level=error "):
+			sb.WriteString(colorTag(ps.conf.Colors().Error) + tview.Escape(line) + "[-]\n")
+		case strings.Contains(line, " level=warn "):
+			sb.WriteString(colorTag(ps.conf.Colors().Warning) + tview.Escape(line) + "[-]\n")
+		default:
+			sb.WriteString(tview.Escape(line) + "\n")
+		}
+	}
+	ps.textDebug.SetText(sb.String())
+	ps.textDebug.ScrollToEnd()
+}
+
+// draw reverse dependency tree, ENTER expands / collapses a node
+func (ps *UI) drawReverseDeps(r *revResolver, name string) {
+	root := tview.NewTreeNode(name).
+		SetColor(ps.conf.Colors().Accent).
+		SetReference([]string{name})
+	ps.expandReverseDeps(r, root)
+
+	ps.treeRevDeps.SetRoot(root).
+		SetCurrentNode(root).
+		SetGraphics(!ps.asciiMode).
+		SetSelectedFunc(func(node *tview.TreeNode) {
+			if len(node.GetChildren()) == 0 {
+				ps.expandReverseDeps(r, node)
+			} else {
+				node.SetExpanded(!node.IsExpanded())
+			}
+		}).
+		SetTitle(" [::b]" + ps.conf.Glyphs().Package + "Required by - " + name + " ")
+	ps.depTreeVisible = false
+
+	ps.flexRight.Clear().
+		AddItem(ps.treeRevDeps, 0, 1, true)
+	ps.app.SetFocus(ps.treeRevDeps)
+}
+
+// adds the reverse dependencies of a package as child nodes, packages forming a cycle can't be expanded
+func (ps *UI) expandReverseDeps(r *revResolver, node *tview.TreeNode) {
+	path, ok := node.GetReference().([]string)
+	if !ok {
+		return
+	}
+	for _, c := range r.children(path[len(path)-1], path) {
+		child := tview.NewTreeNode(tview.Escape(c.label())).
+			SetColor(ps.conf.Colors().Text)
+		if c.kind == revOptional {
+			child.SetColor(ps.conf.Colors().PackagelistSourceAUR)
+		}
+		if c.cycle {
+			child.SetColor(ps.conf.Colors().Error)
+		} else {
+			child.SetReference(append(append([]string{}, path...), c.name))
+		}
+		node.AddChild(child)
+	}
+	node.SetExpanded(true)
+}
+
+// highlights the packages of the package list that are queued
+func (ps *UI) drawQueueMarks() {
+	for i := 1; i < ps.tablePackages.GetRowCount(); i++ {
+		c := ps.tablePackages.GetCell(i, 0)
+		if ps.queueIndex(c.Text, ps.tablePackages.GetCell(i, 1).Text) != -1 {
+			c.SetAttributes(tcell.AttrBold | tcell.AttrUnderline)
+		} else {
+			c.SetAttributes(tcell.AttrNone)
+		}
+	}
+}
+
+// marks vulnerable (installed) packages in the package list
+func (ps *UI) drawAdvisoryMarks() {
+	for i := 1; i < ps.tablePackages.GetRowCount(); i++ {
+		c := ps.tablePackages.GetCell(i, 0)
+		source := ps.tablePackages.GetCell(i, 1).Text
+		installed, _ := ps.tablePackages.GetCell(i, 2).Reference.(bool)
+		if installed && len(ps.vulnerable[c.Text]) > 0 && findSource(ps.sources, source) == nil {
+			c.SetTextColor(ps.conf.Colors().Error)
+		} else {
+			c.SetTextColor(ps.conf.Colors().Text)
+		}
+	}
+}
+
+// draw list of advisories affecting our installed packages
+func (ps *UI) drawAdvisories() {
+	vulnerabilities := sortedVulnerabilities(ps.vulnerable)
+	ps.tableDetails.Clear().
+		SetTitle(" [::b]" + ps.conf.Glyphs().Package + "Advisories - " + strconv.Itoa(len(vulnerabilities)) + " ")
+	if len(vulnerabilities) == 0 {
+		ps.tableDetails.SetCellSimple(0, 0, "No known vulnerabilities affecting your installed packages")
+		return
+	}
+
+	columns := []string{"Package", "Installed", "Fixed", "Severity", "Type", "Advisory", "Issues"}
+	for i, col := range columns {
+		ps.tableDetails.SetCell(0, i, &tview.TableCell{
+			Text:            "[::b]" + col,
+			Color:           ps.conf.Colors().Accent,
+			BackgroundColor: ps.conf.Colors().DefaultBackground,
+		})
+	}
+	for i, v := range vulnerabilities {
+		fixed := v.Fixed
+		if fixed == "" {
+			fixed = "-"
+		}
+		color := ps.conf.Colors().Warning
+		if severityRank(v.Severity) < 2 {
+			color = ps.conf.Colors().Error
+		}
+		avg := v.Name
+		ps.tableDetails.SetCell(i+1, 0, &tview.TableCell{
+			Text:            v.Package,
+			Color:           ps.conf.Colors().Text,
+			BackgroundColor: ps.conf.Colors().DefaultBackground,
+		}).
+			SetCellSimple(i+1, 1, v.InstalledVersion).
+			SetCellSimple(i+1, 2, fixed).
+			SetCell(i+1, 3, &tview.TableCell{
+				Text:            v.Severity,
+				Color:           color,
+				BackgroundColor: ps.conf.Colors().DefaultBackground,
+			}).
+			SetCellSimple(i+1, 4, v.Type).
+			SetCell(i+1, 5, &tview.TableCell{
+				Text:            "[::u]" + avg,
+				Color:           ps.conf.Colors().Text,
+				BackgroundColor: ps.conf.Colors().DefaultBackground,
+				Clicked: func() bool {
+					exec.Command("xdg-open", fmt.Sprintf(UrlAdvisory, avg)).Start()
+					return true
+				},
+			}).
+			SetCellSimple(i+1, 6, strings.Join(v.Issues, ", "))
+	}
+
+	// check if we got more lines than current screen height
+	_, _, _, height := ps.tableDetails.GetInnerRect()
+	ps.tableDetailsMore = ps.tableDetails.GetRowCount() > height-1
+	ps.tableDetails.ScrollToBeginning()
+}
+
+// draw list of queued packages
+func (ps *UI) drawQueue() {
+	ps.tableDetails.Clear().
+		SetTitle(" [::b]" + ps.conf.Glyphs().Package + "Queue ")
+	ps.depTreeVisible = false
+	if len(ps.queue) == 0 {
+		ps.tableDetails.SetCellSimple(0, 0, "No packages queued. Press SPACE to mark / unmark a package")
+		return
+	}
+
+	for i, q := range ps.queue {
+		action := "Install"
+		if q.Installed {
+			action = "Remove"
+		}
+		color := ps.conf.Colors().PackagelistSourceRepository
+		if q.Source == "AUR" {
+			color = ps.conf.Colors().PackagelistSourceAUR
+		}
+		ps.tableDetails.SetCell(i, 0, &tview.TableCell{
+			Text:            "[::b]" + action,
+			Color:           ps.conf.Colors().Title,
+			BackgroundColor: ps.conf.Colors().DefaultBackground,
+		}).
+			SetCell(i, 1, &tview.TableCell{
+				Text:            q.Name,
+				Color:           ps.conf.Colors().Text,
+				BackgroundColor: ps.conf.Colors().DefaultBackground,
+			}).
+			SetCell(i, 2, &tview.TableCell{
+				Text:            q.Source,
+				Color:           color,
+				BackgroundColor: ps.conf.Colors().DefaultBackground,
+			})
+	}
+	ps.tableDetails.SetCellSimple(len(ps.queue)+1, 0, "").
+		SetCellSimple(len(ps.queue)+2, 0, "ENTER: Run queue")
+
+	// check if we got more lines than current screen height
+	_, _, _, height := ps.tableDetails.GetInnerRect()
+	ps.tableDetailsMore = ps.tableDetails.GetRowCount() > height-1
+	ps.tableDetails.ScrollToBeginning()
+}
+
+// draw results of our mirror tests
+func (ps *UI) drawMirrors(mirrors []mirrorHealth, total int, now time.Time) {
+	ps.tableDetails.Clear().
+		SetTitle(fmt.Sprintf(" [::b]%sMirrors - %d of %d tested ", ps.conf.Glyphs().Package, len(mirrors), total))
+	if len(mirrors) == 0 {
+		ps.tableDetails.SetCellSimple(0, 0, "No active servers found in "+mirrorlistFile)
+		return
+	}
+
+	// header
+	for i, col := range []string{"Mirror  ", "Latency  ", "Throughput  ", "Last sync  ", "Status"} {
+		ps.tableDetails.SetCell(0, i, &tview.TableCell{
+			Text:            col,
+			Color:           ps.conf.Colors().PackagelistHeader,
+			BackgroundColor: ps.conf.Colors().DefaultBackground,
+		})
+	}
+
+	for r, m := range mirrors {
+		color := ps.conf.Colors().Text
+		latency, throughput, lastSync, status := "-", "-", "unknown", "ok"
+		if !m.LastSync.IsZero() {
+			lastSync = formatLag(now.Sub(m.LastSync)) + " ago"
+			if now.Sub(m.LastSync) > mirrorLagWarning {
+				color, status = ps.conf.Colors().Warning, "out of date"
+			}
+		}
+		if m.Error != "" {
+			color, status = ps.conf.Colors().Error, m.Error
+		} else {
+			latency = m.Latency.Round(time.Millisecond).String()
+			throughput = util.FormatSize(int64(m.Throughput)) + "/s"
+		}
+		for i, text := range []string{mirrorBaseURL(m.Server), latency, throughput, lastSync, status} {
+			ps.tableDetails.SetCell(r+2, i, &tview.TableCell{
+				Text:            tview.Escape(text) + "  ",
+				Color:           color,
+				BackgroundColor: ps.conf.Colors().DefaultBackground,
+			})
+		}
+	}
+
+	// check if we got more lines than current screen height
+	_, _, _, height := ps.tableDetails.GetInnerRect()
+	ps.tableDetailsMore = ps.tableDetails.GetRowCount() > height-1
+	ps.tableDetails.ScrollToBeginning()
+}
+
+// draw the result of comparing a snapshot with our installed packages
+func (ps *UI) drawSnapshotDiff(file string, diff snapshotDiff) {
+	ps.tableDetails.Clear().
+		SetTitle(" [::b]" + ps.conf.Glyphs().Package + "Package list - " + tview.Escape(file) + " ")
+
+	r := 0
+	for _, section := range []struct {
+		label string
+		pkgs  []string
+	}{
+		{"Missing (queued, ENTER installs them)", packageNames(diff.Missing)},
+		{"Not available", diff.Unavailable},
+		{"Not in package list", diff.Extra},
+	} {
+		ps.tableDetails.SetCell(r, 0, &tview.TableCell{
+			Text:            "[::b]" + section.label + "  ",
+			Color:           ps.conf.Colors().Accent,
+			BackgroundColor: ps.conf.Colors().DefaultBackground,
+		}).
+			SetCellSimple(r, 1, strconv.Itoa(len(section.pkgs)))
+		r++
+		for _, line := range tview.WordWrap(strings.Join(section.pkgs, " "), 80) {
+			ps.tableDetails.SetCellSimple(r, 1, line)
+			r++
+		}
+		r++
+	}
+
+	// check if we got more lines than current screen height
+	_, _, _, height := ps.tableDetails.GetInnerRect()
+	ps.tableDetailsMore = ps.tableDetails.GetRowCount() > height-1
+	ps.tableDetails.ScrollToBeginning()
+}
+
+// draw statistics about our installed packages
+func (ps *UI) drawStats(stats packageStats) {
+	ps.tableDetails.Clear().
+		SetTitle(" [::b]" + ps.conf.Glyphs().Package + "Package statistics ")
+
+	r := 0
+	label := func(text string) *tview.TableCell {
+		return &tview.TableCell{
+			Text:            "[::b]" + text + "  ",
+			Color:           ps.conf.Colors().Accent,
+			BackgroundColor: ps.conf.Colors().DefaultBackground,
+		}
+	}
+	header := func(columns ...string) {
+		r++
+		for i, col := range columns {
+			ps.tableDetails.SetCell(r, i, &tview.TableCell{
+				Text:            col + "  ",
+				Color:           ps.conf.Colors().PackagelistHeader,
+				BackgroundColor: ps.conf.Colors().DefaultBackground,
+			})
+		}
+		r++
+	}
+
+	// totals
+	for _, line := range []struct {
+		label string
+		value string
+	}{
+		{"Installed packages", strconv.Itoa(stats.Installed)},
+		{"Explicitly installed", strconv.Itoa(stats.Explicit)},
+		{"Installed as dependency", strconv.Itoa(stats.Dependencies)},
+		{"Orphans", strconv.Itoa(stats.Orphans)},
+		{"Foreign", strconv.Itoa(stats.Foreign)},
+		{"Total installed size", util.FormatSize(stats.InstalledSize)},
+	} {
+		ps.tableDetails.SetCell(r, 0, label(line.label)).
+			SetCellSimple(r, 1, line.value)
+		r++
+	}
+
+	// per repository
+	header("Repository", "Packages", "Installed size")
+	for _, repo := range stats.Repos {
+		ps.tableDetails.SetCell(r, 0, label(repo.Name)).
+			SetCellSimple(r, 1, strconv.Itoa(repo.Count)).
+			SetCellSimple(r, 2, util.FormatSize(repo.Size))
+		r++
+	}
+
+	// largest and most recently installed packages
+	header("Largest packages", "Installed size")
+	for _, pkg := range stats.Largest {
+		ps.tableDetails.SetCell(r, 0, label(pkg.Name)).
+			SetCellSimple(r, 1, util.FormatSize(pkg.Size))
+		r++
+	}
+	header("Recently installed / upgraded", "Date")
+	for _, pkg := range stats.Recent {
+		ps.tableDetails.SetCell(r, 0, label(pkg.Name)).
+			SetCellSimple(r, 1, pkg.Date.Format("2006-01-02 15:04"))
+		r++
+	}
+
+	// check if we got more lines than current screen height
+	_, _, _, height := ps.tableDetails.GetInnerRect()
+	ps.tableDetailsMore = ps.tableDetails.GetRowCount() > height-1
+	ps.tableDetails.ScrollToBeginning()
+}
+
 // draw list of upgradable packages
-func (ps *UI) drawUpgradable(up []InfoRecord, cached bool) {
+func (ps *UI) drawUpgradable(up []Upgrade, cached bool) {
 	ps.tableDetails.Clear().
 		SetTitle(" [::b]" + ps.conf.Glyphs().Upgrades + "Upgradable packages ")
 
@@ -333,7 +1136,7 @@ func (ps *UI) drawUpgradable(up []InfoRecord, cached bool) {
 	}
 
 	// header
-	columns := []string{"Package  ", "Source  ", "New version  ", "Installed version", ""}
+	columns := []string{"", "Package  ", "Source  ", "New version  ", "Installed version  ", "Download  ", "Installed  ", "Net  ", ""}
 	for i, col := range columns {
 		hcell := &tview.TableCell{
 			Text:            col,
@@ -343,32 +1146,76 @@ func (ps *UI) drawUpgradable(up []InfoRecord, cached bool) {
 		ps.tableDetails.SetCell(0, i, hcell)
 	}
 
-	// lines (not ignored)
-	r := 1
-	for i := 0; i < len(up); i++ {
-		if !up[i].IsIgnored {
+	// lines, grouped by repository
+	r := 0
+	for _, group := range groupUpgrades(up, ps.syncRepoNames()) {
+		names := []string{}
+		for _, u := range group.Upgrades {
+			if selectableUpgrade(u) {
+				names = append(names, u.Name)
+			}
+		}
+		r += 2
+		ps.tableDetails.SetCell(r, 1, &tview.TableCell{
+			Text:            fmt.Sprintf("[::b]%s (%d)", group.Source, len(group.Upgrades)),
+			Color:           ps.conf.Colors().PackagelistHeader,
+			BackgroundColor: ps.conf.Colors().DefaultBackground,
+			Clicked: func() bool {
+				ps.toggleUpgrade(up, names...)
+				return true
+			},
+		})
+		for _, u := range group.Upgrades {
 			r++
-			ps.drawUpgradeableLine(up[i], r, false)
+			ps.drawUpgradeableLine(up, u, r)
 		}
 	}
-	// lines (ignored)
-	for i := 0; i < len(up); i++ {
-		if up[i].IsIgnored {
+
+	// totals of the selected packages (sizes are unknown for AUR packages)
+	selected := selectedUpgrades(up, ps.upgradeDeselected)
+	if s := summarizeUpgrades(selected); s.Count > 0 {
+		r += 2
+		for i, text := range []string{"", fmt.Sprintf("Total (%d)", s.Count), "", "", "", util.FormatSize(s.DownloadSize), util.FormatSize(s.InstalledSize), formatSizeDelta(s.InstalledSizeDelta)} {
+			ps.tableDetails.SetCell(r, i, &tview.TableCell{
+				Text:            "[::b]" + text,
+				Color:           ps.conf.Colors().Accent,
+				BackgroundColor: ps.conf.Colors().DefaultBackground,
+			})
+		}
+	}
+
+	// changes since our previous check
+	if changes := ps.upgradeState.Changes; len(changes) > 0 {
+		r += 2
+		ps.tableDetails.SetCell(r, 1, &tview.TableCell{
+			Text:            fmt.Sprintf("[::b]Changes since %s (%d)", ps.upgradeState.Since.Format("2006-01-02 15:04"), len(changes)),
+			Color:           ps.conf.Colors().PackagelistHeader,
+			BackgroundColor: ps.conf.Colors().DefaultBackground,
+		})
+		for i, c := range changes {
 			r++
-			ps.drawUpgradeableLine(up[i], r, true)
+			if i == upgradeChangesMax {
+				ps.tableDetails.SetCell(r, 1, &tview.TableCell{
+					Text:            fmt.Sprintf("and %d more", len(changes)-upgradeChangesMax),
+					Color:           ps.conf.Colors().Text,
+					BackgroundColor: ps.conf.Colors().DefaultBackground,
+				})
+				break
+			}
+			ps.drawUpgradeChangeLine(c, r)
 		}
 	}
 
-	// no updates found message else sysupgrade button
+	// no updates found message else sysupgrade button (not in read-only mode)
 	r += 2
 	if len(up) == 0 {
-		ps.tableDetails.SetCell(r, 0, &tview.TableCell{
+		ps.tableDetails.SetCell(r, 1, &tview.TableCell{
 			Text:            "No upgrades found",
 			Color:           ps.conf.Colors().PackagelistHeader,
 			BackgroundColor: ps.conf.Colors().DefaultBackground,
 		})
-	} else {
-		ps.tableDetails.SetCell(r, 0, &tview.TableCell{
+	} else if !ps.readOnly() {
+		ps.tableDetails.SetCell(r, 1, &tview.TableCell{
 			Text:            " [::b]Sysupgrade",
 			Align:           tview.AlignCenter,
 			Color:           ps.conf.Colors().SettingsFieldText,
@@ -382,10 +1229,52 @@ func (ps *UI) drawUpgradable(up []InfoRecord, cached bool) {
 		})
 	}
 
+	// upgrade button for the selected packages, if some have been deselected
+	if !ps.readOnly() && len(selected) > 0 && len(selected) < len(selectedUpgrades(up, nil)) {
+		r += 2
+		ps.tableDetails.SetCell(r, 1, &tview.TableCell{
+			Text:            fmt.Sprintf(" [::b]Upgrade selected (%d)", len(selected)),
+			Align:           tview.AlignCenter,
+			Color:           ps.conf.Colors().SettingsFieldText,
+			BackgroundColor: ps.conf.Colors().SearchBar,
+			Clicked: func() bool {
+				ps.performSelectiveUpgrade(up)
+				ps.cacheInfo.Delete("#upgrades#")
+				ps.displayUpgradable()
+				return true
+			},
+		})
+		r++
+		ps.tableDetails.SetCell(r, 1, &tview.TableCell{
+			Text:            "Partial upgrades are not supported by Arch Linux and might break your system",
+			Color:           ps.conf.Colors().Warning,
+			BackgroundColor: ps.conf.Colors().DefaultBackground,
+		})
+	}
+
+	// download button, the selected packages are downloaded without installing them
+	if !ps.readOnly() && len(selected) > 0 {
+		r += 2
+		ps.tableDetails.SetCell(r, 1, &tview.TableCell{
+			Text:            fmt.Sprintf(" [::b]Download selected (%d)", len(selected)),
+			Align:           tview.AlignCenter,
+			Color:           ps.conf.Colors().SettingsFieldText,
+			BackgroundColor: ps.conf.Colors().SearchBar,
+			Clicked: func() bool {
+				pkgs := []InfoRecord{}
+				for _, u := range selected {
+					pkgs = append(pkgs, u.InfoRecord)
+				}
+				ps.downloadPackages(pkgs)
+				return true
+			},
+		})
+	}
+
 	// refresh button
 	if cached {
 		r += 2
-		ps.tableDetails.SetCell(r, 0, &tview.TableCell{
+		ps.tableDetails.SetCell(r, 1, &tview.TableCell{
 			Text:            " [::b]Refresh",
 			Color:           ps.conf.Colors().SettingsFieldText,
 			BackgroundColor: ps.conf.Colors().SearchBar,
@@ -413,27 +1302,57 @@ func (ps *UI) drawNews() {
 			})
 			return
 		}
+		since, read := newsReadState(ps.conf)
+		unread := map[string]bool{}
+		for _, item := range unreadNews(news, since, read) {
+			unread[newsId(item)] = true
+		}
 
 		ps.app.QueueUpdateDraw(func() {
 			for r, item := range news {
 				item := item
+				color, date := ps.conf.Colors().Text, "("+item.PublishedParsed.Format("2006-01-02")+")"
+				if unread[newsId(item)] {
+					color, date = ps.conf.Colors().Accent, date+" [::b]unread"
+				}
 				ps.tableNews.SetCell(r, 0, &tview.TableCell{
 					Text: "* [::u]" + item.Title,
 					Clicked: func() bool {
 						exec.Command("xdg-open", item.Link).Start()
 						return true
 					},
-					Color:           tcell.ColorWhite,
+					Color:           color,
 					BackgroundColor: ps.conf.Colors().DefaultBackground,
 				}).
-					SetCellSimple(r, 1, "("+item.PublishedParsed.Format("2006-01-02")+")")
+					SetCellSimple(r, 1, date)
 			}
 		})
 	}()
 }
 
+// draws a line for a change since our previous check for upgrades
+func (ps *UI) drawUpgradeChangeLine(c upgradeChange, lNum int) {
+	color := ps.conf.Colors().Accent
+	switch c.Kind {
+	case "installed":
+		color = ps.conf.Colors().Installed
+	case "gone":
+		color = ps.conf.Colors().Warning
+	}
+	for i, text := range []string{c.Name, c.Source, c.Version, describeUpgradeChange(c)} {
+		ps.tableDetails.SetCell(lNum, i+1, &tview.TableCell{
+			Text:            tview.Escape(text),
+			Color:           color,
+			BackgroundColor: ps.conf.Colors().DefaultBackground,
+		})
+	}
+}
+
 // draws a line for an upgradable package
-func (ps *UI) drawUpgradeableLine(up InfoRecord, lNum int, ignored bool) {
+// the check box in front of it (de)selects it for a selective upgrade
+func (ps *UI) drawUpgradeableLine(all []Upgrade, upgrade Upgrade, lNum int) {
+	up := upgrade.InfoRecord
+	ignored := upgrade.IsIgnored
 	cellDesc := &tview.TableCell{
 		Text:            "[::b]" + up.Name,
 		Color:           ps.conf.Colors().Accent,
@@ -454,6 +1373,9 @@ func (ps *UI) drawUpgradeableLine(up InfoRecord, lNum int, ignored bool) {
 		Color:           ps.conf.Colors().PackagelistSourceRepository,
 		BackgroundColor: ps.conf.Colors().DefaultBackground,
 		Clicked: func() bool {
+			if findSource(ps.sources, up.Source) != nil {
+				return false
+			}
 			if ps.conf.ShowPkgbuildInternally {
 				ps.selectedPackage = &up
 				ps.displayPkgbuild()
@@ -469,13 +1391,41 @@ func (ps *UI) drawUpgradeableLine(up InfoRecord, lNum int, ignored bool) {
 		BackgroundColor: ps.conf.Colors().DefaultBackground,
 	}
 
-	ps.tableDetails.SetCell(lNum, 0, cellDesc).
-		SetCell(lNum, 1, cellSource).
-		SetCell(lNum, 2, cellVnew).
-		SetCell(lNum, 3, cellVold)
+	ps.tableDetails.SetCell(lNum, 1, cellDesc).
+		SetCell(lNum, 2, cellSource).
+		SetCell(lNum, 3, cellVnew).
+		SetCell(lNum, 4, cellVold)
+
+	// check box
+	if selectableUpgrade(upgrade) {
+		check := "[x] "
+		if ps.upgradeDeselected[up.Name] {
+			check = "[ ] "
+		}
+		ps.tableDetails.SetCell(lNum, 0, &tview.TableCell{
+			Text:            tview.Escape(check),
+			Color:           ps.conf.Colors().Accent,
+			BackgroundColor: ps.conf.Colors().DefaultBackground,
+			Clicked: func() bool {
+				ps.toggleUpgrade(all, up.Name)
+				return true
+			},
+		})
+	}
+
+	// sizes
+	if up.Source != "AUR" && up.Source != "local" && findSource(ps.sources, up.Source) == nil {
+		for i, text := range []string{util.FormatSize(upgrade.DownloadSize), util.FormatSize(up.InstalledSize), formatSizeDelta(upgrade.InstalledSizeDelta)} {
+			ps.tableDetails.SetCell(lNum, 5+i, &tview.TableCell{
+				Text:            text,
+				Color:           ps.conf.Colors().Text,
+				BackgroundColor: ps.conf.Colors().DefaultBackground,
+			})
+		}
+	}
 
 	// rebuild button for AUR packages
-	if up.Source == "AUR" && !ignored {
+	if up.Source == "AUR" && !ignored && !ps.readOnly() {
 		cellRebuild := &tview.TableCell{
 			Text:            " [::b]Rebuild / Update",
 			Color:           ps.conf.Colors().SettingsFieldText,
@@ -487,18 +1437,22 @@ func (ps *UI) drawUpgradeableLine(up InfoRecord, lNum int, ignored bool) {
 				return true
 			},
 		}
-		ps.tableDetails.SetCell(lNum, 4, cellRebuild)
+		ps.tableDetails.SetCell(lNum, 8, cellRebuild)
 	}
 
 	if ignored {
 		cellDesc.SetTextColor(ps.conf.Colors().PackagelistHeader)
 		cellVnew.SetTextColor(ps.conf.Colors().PackagelistHeader)
+		text := "ignored"
+		if upgrade.HeldBack {
+			text = "held back (IgnorePkg)"
+		}
 		cellIgnored := &tview.TableCell{
-			Text:            "ignored",
+			Text:            text,
 			Color:           ps.conf.Colors().PackagelistHeader,
 			BackgroundColor: ps.conf.Colors().DefaultBackground,
 		}
-		ps.tableDetails.SetCell(lNum, 4, cellIgnored)
+		ps.tableDetails.SetCell(lNum, 8, cellIgnored)
 	}
 }
 
@@ -516,10 +1470,20 @@ func (ps *UI) drawPackageListContent(packages []Package, pkgwidth int) {
 		if pkg.Source == "AUR" {
 			color = ps.conf.Colors().PackagelistSourceAUR
 		}
+		if pkg.OutOfDate != 0 {
+			color = ps.conf.Colors().Warning
+		}
+		if pkg.Orphaned {
+			color = ps.conf.Colors().Error
+		}
+		attributes := tcell.AttrNone
+		if pkg.AurAvailable {
+			attributes = tcell.AttrItalic
+		}
 
 		ps.tablePackages.SetCell(i+1, 0, &tview.TableCell{
 			Text:            pkg.Name,
-			Color:           tcell.ColorWhite,
+			Color:           ps.conf.Colors().Text,
 			BackgroundColor: ps.conf.Colors().DefaultBackground,
 			MaxWidth:        pkgwidth,
 		}).
@@ -527,6 +1491,8 @@ func (ps *UI) drawPackageListContent(packages []Package, pkgwidth int) {
 				Text:            pkg.Source,
 				Color:           color,
 				BackgroundColor: ps.conf.Colors().DefaultBackground,
+				Attributes:      attributes,
+				Reference:       pkg.AurAvailable,
 			}).
 			SetCell(i+1, 2, &tview.TableCell{
 				Color:       ps.conf.Colors().DefaultBackground,
@@ -536,13 +1502,15 @@ func (ps *UI) drawPackageListContent(packages []Package, pkgwidth int) {
 				Transparent: true,
 			})
 	}
+	ps.drawQueueMarks()
+	ps.drawAdvisoryMarks()
 	ps.tablePackages.ScrollToBeginning()
 }
 
 // draw pkgbuild on screen
-func (ps *UI) drawPkgbuild(content, pkg string) {
-	ps.textPkgbuild.SetTitle(" [::b]" + ps.conf.Glyphs().Pkgbuild + "PKGBUILD - " + pkg + " ")
-	err := quick.Highlight(ps.pkgbuildWriter, tview.Escape(content), "bash", "terminal16m", ps.conf.Colors().StylePKGBUILD)
+func (ps *UI) drawPkgbuild(content, pkg, file string) {
+	ps.textPkgbuild.SetTitle(" [::b]" + ps.conf.Glyphs().Pkgbuild + file + " - " + pkg + " [::-](s: switch to " + otherPackageFile(file) + ") ")
+	err := quick.Highlight(ps.pkgbuildWriter, tview.Escape(content), packageFileLexer(file), "terminal16m", ps.conf.Colors().StylePKGBUILD)
 	if err != nil {
 		ps.textPkgbuild.SetText(err.Error())
 		return
@@ -640,17 +1608,49 @@ func (ps *UI) sortAndRedrawPackageList(runeKey rune) {
 	case 'P': // sort by popularity
 		if ps.sortAscending {
 			sort.Slice(ps.shownPackages, func(i, j int) bool {
-				if ps.shownPackages[i].Popularity == ps.shownPackages[j].Popularity {
+				c := comparePopularity(ps.shownPackages[i].Popularity, ps.shownPackages[j].Popularity)
+				if c == 0 {
 					return ps.shownPackages[j].Name > ps.shownPackages[i].Name
 				}
-				return ps.shownPackages[i].Popularity > ps.shownPackages[j].Popularity
+				return c < 0
 			})
 		} else {
 			sort.Slice(ps.shownPackages, func(i, j int) bool {
-				if ps.shownPackages[i].Popularity == ps.shownPackages[j].Popularity {
+				c := comparePopularity(ps.shownPackages[i].Popularity, ps.shownPackages[j].Popularity)
+				if c == 0 {
 					return ps.shownPackages[j].Name > ps.shownPackages[i].Name
 				}
-				return ps.shownPackages[j].Popularity > ps.shownPackages[i].Popularity
+				return c > 0
+			})
+		}
+	case 'V': // sort by votes
+		if ps.sortAscending {
+			sort.SliceStable(ps.shownPackages, func(i, j int) bool {
+				return ps.shownPackages[i].NumVotes > ps.shownPackages[j].NumVotes
+			})
+		} else {
+			sort.SliceStable(ps.shownPackages, func(i, j int) bool {
+				return ps.shownPackages[j].NumVotes > ps.shownPackages[i].NumVotes
+			})
+		}
+	case 'Z': // sort by installed size
+		if ps.sortAscending {
+			sort.SliceStable(ps.shownPackages, func(i, j int) bool {
+				return ps.shownPackages[i].InstalledSize > ps.shownPackages[j].InstalledSize
+			})
+		} else {
+			sort.SliceStable(ps.shownPackages, func(i, j int) bool {
+				return ps.shownPackages[j].InstalledSize > ps.shownPackages[i].InstalledSize
+			})
+		}
+	case 'D': // sort by download size
+		if ps.sortAscending {
+			sort.SliceStable(ps.shownPackages, func(i, j int) bool {
+				return ps.shownPackages[i].DownloadSize > ps.shownPackages[j].DownloadSize
+			})
+		} else {
+			sort.SliceStable(ps.shownPackages, func(i, j int) bool {
+				return ps.shownPackages[j].DownloadSize > ps.shownPackages[i].DownloadSize
 			})
 		}
 	}
@@ -663,17 +1663,30 @@ func (ps *UI) sortAndRedrawPackageList(runeKey rune) {
 func (ps *UI) getDetailFields(i InfoRecord) (map[string]string, []string) {
 	order := []string{
 		"Description",
+		"Summary",
 		"Version",
+		"Repositories",
+		"Flagged out of date",
 		"Maintainer",
 		"Licenses",
+		"Keywords",
+		"Categories",
 		"Votes",
 		"Popularity",
 		"Last modified",
-		"Flagged out of date",
+		"Download size",
+		"Installed size",
+		"Size on disk",
+		"Install reason",
+		"Ignored",
+		"Validated by",
+		"Vulnerable",
 		"URL",
 		"Package URL",
+		"Screenshot URL",
 		"Provides",
 		"Conflicts",
+		"Replaces",
 		"Required by",
 		"Dependencies",
 		" Show PKGBUILD", //the space in front is an ugly alignment hack ;)
@@ -682,15 +1695,50 @@ func (ps *UI) getDetailFields(i InfoRecord) (map[string]string, []string) {
 	fields := map[string]string{}
 	fields["Description"] = i.Description
 	fields["Version"] = i.Version
+	if i.LocalVersion != "" && i.LocalVersion != i.Version {
+		fields["Version"] = fmt.Sprintf("%s (installed: %s)", i.Version, i.LocalVersion)
+	}
 	fields["Provides"] = strings.Join(i.Provides, ", ")
 	fields["Conflicts"] = strings.Join(i.Conflicts, ", ")
+	fields["Replaces"] = strings.Join(i.Replaces, ", ")
 	fields["Licenses"] = strings.Join(i.License, ", ")
+	fields["Keywords"] = strings.Join(i.Keywords, ", ")
 	fields["Maintainer"] = i.Maintainer
+	if i.Source == "AUR" && i.Maintainer == "" {
+		fields["Maintainer"] = colorTag(ps.conf.Colors().Error) + "None (orphaned)"
+	}
 	fields["Dependencies"] = getDependenciesJoined(i, ps.getInstalledStateText(true), ps.getInstalledStateText(false), ps.conf.SepDepsWithNewLine)
 	fields["Required by"] = strings.Join(i.RequiredBy, ", ")
 	fields["URL"] = i.URL
+	if i.Size > 0 {
+		fields["Download size"] = util.FormatSize(i.Size)
+	}
+	if i.InstalledSize > 0 {
+		fields["Installed size"] = util.FormatSize(i.InstalledSize)
+	}
+	if i.DiskSize > 0 {
+		fields["Size on disk"] = util.FormatSize(i.DiskSize)
+	}
+	if i.InstallReason != "" {
+		fields["Install reason"] = i.InstallReason
+	}
+	if i.IsIgnored {
+		fields["Ignored"] = "IgnorePkg / IgnoreGroup (pacman.conf)"
+	} else if (IgnoreRules{Packages: ps.conf.IgnoredPackages}).matches(nil, i.Name) {
+		fields["Ignored"] = "pacseek ignore list"
+	}
+	if i.Validation != "" {
+		fields["Validated by"] = i.Validation
+	}
 	if i.Source == "AUR" {
 		fields["Votes"] = fmt.Sprintf("%d", i.NumVotes)
+		if ps.aurVotes != nil {
+			voted := "no"
+			if ps.aurVotes[i.Name] {
+				voted = "yes"
+			}
+			fields["Votes"] = fmt.Sprintf("%d (voted: %s)", i.NumVotes, voted)
+		}
 		fields["Popularity"] = fmt.Sprintf("%f", i.Popularity)
 		fields["Package URL"] = fmt.Sprintf(UrlAurPackage, i.Name)
 	} else if (!ps.isArm && util.SliceContains(getArchRepos(), i.Source)) ||
@@ -701,15 +1749,37 @@ func (ps *UI) getDetailFields(i InfoRecord) (map[string]string, []string) {
 			fields["Package URL"] = fmt.Sprintf(UrlPackage, i.Source, i.Architecture, i.Name)
 		}
 	}
+	if p, ok := ps.popularity.get(i.Name); ok && i.Source != "AUR" && findSource(ps.sources, i.Source) == nil && !ps.conf.DisablePkgstats {
+		fields["Popularity"] = fmt.Sprintf("%.2f%% (pkgstats)", p)
+	}
 	if i.LastModified != 0 {
 		fields["Last modified"] = time.Unix(int64(i.LastModified), 0).UTC().Format("2006-01-02 - 15:04:05 (UTC)")
+	} else if i.Source != "AUR" && !i.HasBuildDate {
+		fields["Last modified"] = "unknown"
+	}
+	if vulns := ps.vulnerable[i.Name]; findSource(ps.sources, i.Source) == nil && i.LocalVersion != "" && len(vulns) > 0 {
+		avgs := []string{}
+		for _, v := range vulns {
+			avgs = append(avgs, v.Name+" ("+v.Severity+")")
+		}
+		fields["Vulnerable"] = colorTag(ps.conf.Colors().Error) + strings.Join(avgs, ", ")
 	}
 	if i.OutOfDate != 0 {
-		fields["Flagged out of date"] = time.Unix(int64(i.OutOfDate), 0).UTC().Format("[red]2006-01-02 - 15:04:05 (UTC)")
+		fields["Flagged out of date"] = colorTag(ps.conf.Colors().Warning) + time.Unix(int64(i.OutOfDate), 0).UTC().Format("2006-01-02 - 15:04:05 (UTC)")
 	}
-	if !ps.isArm || (ps.isArm && i.Source == "AUR") {
+	if (!ps.isArm || (ps.isArm && i.Source == "AUR")) && findSource(ps.sources, i.Source) == nil {
 		fields[" Show PKGBUILD"] = ps.getPkgbuildCommand(i.Source, i.PackageBase)
 	}
+	ps.appstreamFields(i, fields)
+	if i.Source != "AUR" && findSource(ps.sources, i.Source) == nil {
+		if versions := repoVersions(ps.handle(), i.Name, ps.conf.RepoPriority); len(versions) > 1 {
+			repos := []string{}
+			for _, v := range versions {
+				repos = append(repos, v.Repo+" ("+v.Version+")")
+			}
+			fields["Repositories"] = strings.Join(repos, ", ")
+		}
+	}
 
 	return fields, order
 }
@@ -730,6 +1800,9 @@ func getDependenciesJoined(i InfoRecord, installedIcon, notInstalledicon string,
 		}
 		deps = append(deps, add)
 	}
+	if i.OmittedDepends > 0 {
+		deps = append(deps, fmt.Sprintf("+%d more", i.OmittedDepends))
+	}
 	separator := ", "
 	if newline {
 		separator = "\n"
@@ -744,15 +1817,22 @@ func (ps *UI) updateInstalledState() {
 	cpkg, exp, found := ps.cacheSearch.GetWithExpiration(sterm)
 	if found {
 		scpkg := cpkg.([]Package)
+		installed := ps.installedPackages(scpkg)
 		for i := 0; i < len(scpkg); i++ {
-			scpkg[i].IsInstalled = isPackageInstalled(ps.alpmHandle, scpkg[i].Name)
+			scpkg[i].IsInstalled = installed[i]
 		}
 		ps.cacheSearch.Set(sterm, scpkg, time.Until(exp))
 	}
 
 	// update currently shown packages
+	shown := []Package{}
+	for i := 1; i < ps.tablePackages.GetRowCount(); i++ {
+		shown = append(shown, Package{Name: ps.tablePackages.GetCell(i, 0).Text, Source: ps.tablePackages.GetCell(i, 1).Text})
+	}
+	installed := ps.installedPackages(shown)
+	defer ps.updateVulnerabilities()
 	for i := 1; i < ps.tablePackages.GetRowCount(); i++ {
-		isInstalled := isPackageInstalled(ps.alpmHandle, ps.tablePackages.GetCell(i, 0).Text)
+		isInstalled := installed[i-1]
 		newCell := &tview.TableCell{
 			Text:        ps.getInstalledStateText(isInstalled),
 			Expansion:   1000,
@@ -763,26 +1843,59 @@ func (ps *UI) updateInstalledState() {
 	}
 }
 
+// returns the install state of the given packages (in the same order)
+// packages of additional sources are checked with their source, all others with the local db
+func (ps *UI) installedPackages(pkgs []Package) []bool {
+	names := []string{}
+	for _, pkg := range pkgs {
+		names = append(names, pkg.Name)
+	}
+	local := areInstalled(ps.handle(), names)
+	sourceInstalled := map[string]map[string]bool{}
+	for _, s := range ps.sources {
+		if installed, err := s.Installed(); err == nil {
+			sourceInstalled[s.Name()] = installed
+		}
+	}
+
+	installed := make([]bool, len(pkgs))
+	for i, pkg := range pkgs {
+		if si, ok := sourceInstalled[pkg.Source]; ok {
+			installed[i] = si[pkg.Name]
+		} else {
+			installed[i] = local[pkg.Name]
+		}
+	}
+	return installed
+}
+
 // compose text for "Installed" column in package list
 func (ps *UI) getInstalledStateText(isInstalled bool) string {
 	glyphs := ps.conf.Glyphs()
-	colStrInstalled := "[#ff0000::b]"
+	colors := ps.conf.Colors()
+	colStrInstalled := fmt.Sprintf("[#%06x::b]", colors.Error.Hex())
 	installed := glyphs.NotInstalled
 
 	if isInstalled {
 		installed = glyphs.Installed
-		colStrInstalled = "[#00ff00::b]"
+		colStrInstalled = fmt.Sprintf("[#%06x::b]", colors.Installed.Hex())
 	}
 
+	background := fmt.Sprintf("#%06x", colors.DefaultBackground.Hex())
+	if colors.DefaultBackground == tcell.ColorDefault {
+		background = "-"
+	}
 	if ps.conf.ColorScheme == "Monochrome" || ps.flags.MonochromeMode {
-		colStrInstalled = "[white:black:b]"
+		colStrInstalled = "[white:" + background + ":b]"
 	}
 
-	whiteBlack := "[white:black:-]"
-	if ps.conf.Colors().DefaultBackground == tcell.ColorDefault {
-		whiteBlack = "[white:-:-]"
-	}
-	ret := whiteBlack + glyphs.PrefixState + colStrInstalled + installed + whiteBlack + glyphs.SuffixState
+	textColor := fmt.Sprintf("[#%06x:%s:-]", colors.Text.Hex(), background)
+	ret := textColor + glyphs.PrefixState + colStrInstalled + installed + textColor + glyphs.SuffixState
 
 	return ret
 }
+
+// returns the style tag for a color (e.g. "[#ff0000]")
+func colorTag(c tcell.Color) string {
+	return fmt.Sprintf("[#%06x]", c.Hex())
+}
@@ -0,0 +1,151 @@
+package pacseek
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// creates CSV data (with header row) for a list of packages
+func exportCSV(pkgs []Package) ([]byte, error) {
+	rows := [][]string{{"Name", "Source", "Installed", "LastModified", "Popularity", "NumVotes"}}
+	for _, pkg := range pkgs {
+		popularity := ""
+		if pkg.Source == "AUR" {
+			popularity = strconv.FormatFloat(pkg.Popularity, 'f', -1, 64)
+		}
+		rows = append(rows, []string{
+			pkg.Name,
+			pkg.Source,
+			strconv.FormatBool(pkg.IsInstalled),
+			strconv.Itoa(pkg.LastModified),
+			popularity,
+			strconv.Itoa(pkg.NumVotes),
+		})
+	}
+	return writeCSV(rows)
+}
+
+// creates CSV data (with header row) for a list of upgrades
+func exportUpgradesCSV(upgrades []Upgrade) ([]byte, error) {
+	rows := [][]string{{"Name", "Source", "LocalVersion", "Version", "Status", "DownloadSize", "InstalledSizeDelta", "Description"}}
+	for _, up := range upgrades {
+		rows = append(rows, []string{
+			up.Name,
+			up.Source,
+			up.LocalVersion,
+			up.Version,
+			up.Status,
+			strconv.FormatInt(up.DownloadSize, 10),
+			strconv.FormatInt(up.InstalledSizeDelta, 10),
+			up.Description,
+		})
+	}
+	return writeCSV(rows)
+}
+
+// writes rows as CSV, fields are quoted where necessary
+func writeCSV(rows [][]string) ([]byte, error) {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// maximum line width and label width for our text output (same as pacman -Si)
+const (
+	infoTextWidth      = 80
+	infoTextLabelWidth = 15
+)
+
+// FormatInfoText formats package information like "pacman -Si" does ("Field : value", one field per line)
+// multi-value fields are separated by two spaces and wrapped, optional dependencies are shown one per line
+// empty fields are shown as "None"
+func FormatInfoText(r InfoRecord) string {
+	type field struct {
+		label   string
+		values  []string
+		perLine bool
+	}
+
+	maintainerLabel := "Packager"
+	if r.Source == "AUR" {
+		maintainerLabel = "Maintainer"
+	}
+	lastModified := ""
+	if r.LastModified != 0 {
+		lastModified = time.Unix(int64(r.LastModified), 0).UTC().Format("2006-01-02 - 15:04:05 (UTC)")
+	}
+
+	fields := []field{
+		{label: "Repository", values: []string{r.Source}},
+		{label: "Name", values: []string{r.Name}},
+		{label: "Version", values: []string{r.Version}},
+		{label: "Description", values: []string{r.Description}},
+		{label: "Architecture", values: []string{r.Architecture}},
+		{label: "URL", values: []string{r.URL}},
+		{label: "Licenses", values: r.License},
+		{label: "Groups", values: r.Groups},
+		{label: "Provides", values: r.Provides},
+		{label: "Depends On", values: r.Depends},
+		{label: "Optional Deps", values: r.OptDepends, perLine: true},
+		{label: "Make Deps", values: r.MakeDepends},
+		{label: "Check Deps", values: r.CheckDepends},
+		{label: "Required By", values: r.RequiredBy},
+		{label: "Conflicts With", values: r.Conflicts},
+		{label: "Replaces", values: r.Replaces},
+		{label: maintainerLabel, values: []string{r.Maintainer}},
+		{label: "Last Modified", values: []string{lastModified}},
+	}
+	if r.Source == "AUR" {
+		fields = append(fields,
+			field{label: "Votes", values: []string{strconv.Itoa(r.NumVotes)}},
+			field{label: "Popularity", values: []string{fmt.Sprintf("%f", r.Popularity)}},
+			field{label: "Keywords", values: r.Keywords},
+		)
+	}
+
+	var sb strings.Builder
+	indent := strings.Repeat(" ", infoTextLabelWidth+3)
+	for _, f := range fields {
+		sb.WriteString(fmt.Sprintf("%-*s : ", infoTextLabelWidth, f.label))
+
+		values := []string{}
+		for _, v := range f.values {
+			if v != "" {
+				values = append(values, v)
+			}
+		}
+		if len(values) == 0 {
+			sb.WriteString("None\n")
+			continue
+		}
+		if f.perLine {
+			sb.WriteString(strings.Join(values, "\n"+indent) + "\n")
+			continue
+		}
+
+		// wrap values that exceed our line width
+		lineLen := len(indent)
+		for i, v := range values {
+			if i > 0 {
+				if lineLen+2+len(v) > infoTextWidth {
+					sb.WriteString("\n" + indent)
+					lineLen = len(indent)
+				} else {
+					sb.WriteString("  ")
+					lineLen += 2
+				}
+			}
+			sb.WriteString(v)
+			lineLen += len(v)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
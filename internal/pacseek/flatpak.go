@@ -0,0 +1,219 @@
+package pacseek
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// UrlFlathubApp is the flathub page of an application
+const UrlFlathubApp = "https://flathub.org/apps/%s"
+
+// flatpakSource searches / installs Flatpak applications with the flatpak CLI
+type flatpakSource struct {
+	run       func(args ...string) (string, error) // runs flatpak with the given arguments and returns its output
+	mut       sync.Mutex
+	records   map[string]InfoRecord // search results by application ID (flatpak has no command for remote app info in a parsable format)
+	installed map[string]bool       // installed applications (nil until "flatpak list" has been run), dropped after transactions
+
+	installCommand   string // command templates, the defaults are used when empty
+	uninstallCommand string
+	upgradeCommand   string
+}
+
+// creates a Flatpak source using the flatpak binary
+func newFlatpakSource() *flatpakSource {
+	return &flatpakSource{
+		run: func(args ...string) (string, error) {
+			out, err := exec.Command("flatpak", args...).Output()
+			return string(out), err
+		},
+		records: map[string]InfoRecord{},
+	}
+}
+
+// Name returns the name of our source
+func (f *flatpakSource) Name() string {
+	return "Flatpak"
+}
+
+// Search searches the remotes (e.g. flathub) for applications
+func (f *flatpakSource) Search(term string, maxResults int) ([]Package, error) {
+	packages := []Package{}
+	out, err := f.run("search", "--columns=application,name,description,version,remotes", term)
+	if err != nil {
+		return packages, fmt.Errorf("flatpak search failed: %w", err)
+	}
+	installed, err := f.Installed()
+	if err != nil {
+		return packages, err
+	}
+
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	for _, r := range parseFlatpakSearch(out) {
+		if len(packages) >= maxResults {
+			break
+		}
+		f.records[r.Name] = r
+		packages = append(packages, Package{
+			Name:        r.Name,
+			Source:      f.Name(),
+			IsInstalled: installed[r.Name],
+		})
+	}
+	return packages, nil
+}
+
+// Info returns the information of an application found with Search
+func (f *flatpakSource) Info(name string) (InfoRecord, error) {
+	f.mut.Lock()
+	r, ok := f.records[name]
+	f.mut.Unlock()
+	if !ok {
+		return r, fmt.Errorf("application '%s' not found", name)
+	}
+	installed, err := f.Installed()
+	if err != nil {
+		return r, err
+	}
+	if installed[name] {
+		r.LocalVersion = r.Version
+	}
+	return r, nil
+}
+
+// Installed returns the IDs of the installed applications
+// "flatpak list" is only run again after our cached result has been dropped with refresh
+func (f *flatpakSource) Installed() (map[string]bool, error) {
+	installed := map[string]bool{}
+	f.mut.Lock()
+	cached := f.installed
+	f.mut.Unlock()
+	if cached == nil {
+		out, err := f.run("list", "--app", "--columns=application")
+		if err != nil {
+			return installed, fmt.Errorf("flatpak list failed: %w", err)
+		}
+		cached = map[string]bool{}
+		for _, line := range strings.Split(out, "\n") {
+			if id := strings.TrimSpace(line); id != "" {
+				cached[id] = true
+			}
+		}
+		f.mut.Lock()
+		f.installed = cached
+		f.mut.Unlock()
+	}
+	for id := range cached {
+		installed[id] = true
+	}
+	return installed, nil
+}
+
+// drops our cached installed applications, so that they are determined again
+func (f *flatpakSource) refresh() {
+	f.mut.Lock()
+	f.installed = nil
+	f.mut.Unlock()
+}
+
+// Upgradable returns the installed applications that can be updated
+func (f *flatpakSource) Upgradable() ([]Upgrade, error) {
+	updates, err := f.run("remote-ls", "--updates", "--app", "--columns=application,version,origin")
+	if err != nil {
+		return []Upgrade{}, fmt.Errorf("flatpak remote-ls failed: %w", err)
+	}
+	installed, err := f.run("list", "--app", "--columns=application,version")
+	if err != nil {
+		return []Upgrade{}, fmt.Errorf("flatpak list failed: %w", err)
+	}
+	return parseFlatpakUpdates(updates, installed), nil
+}
+
+// InstallCommand returns the command for installing an application from its remote (stored as PackageBase)
+func (f *flatpakSource) InstallCommand(pkg InfoRecord) string {
+	command := f.installCommand
+	if command == "" {
+		command = "flatpak install {pkgbase} {pkg}"
+	}
+	return expandCommand(command, pkg)
+}
+
+// UninstallCommand returns the command for removing an application
+func (f *flatpakSource) UninstallCommand(pkg InfoRecord) string {
+	command := f.uninstallCommand
+	if command == "" {
+		command = "flatpak uninstall {pkg}"
+	}
+	return expandCommand(command, pkg)
+}
+
+// UpgradeCommand returns the command for updating an application
+func (f *flatpakSource) UpgradeCommand(pkg InfoRecord) string {
+	command := f.upgradeCommand
+	if command == "" {
+		command = "flatpak update {pkg}"
+	}
+	return expandCommand(command, pkg)
+}
+
+// parses the (tab separated) output of "flatpak remote-ls --updates --columns=application,version,origin"
+// together with the one of "flatpak list --columns=application,version" (our installed versions)
+// updates without a version (e.g. for a new commit of the same version) are shown with the installed version
+func parseFlatpakUpdates(updates, installed string) []Upgrade {
+	local := map[string]string{}
+	for _, line := range strings.Split(installed, "\n") {
+		if fields := strings.Split(line, "\t"); len(fields) >= 2 && fields[0] != "" {
+			local[fields[0]] = fields[1]
+		}
+	}
+	up := []Upgrade{}
+	for _, line := range strings.Split(updates, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 3 || fields[0] == "" {
+			continue
+		}
+		version := fields[1]
+		if version == "" {
+			version = local[fields[0]]
+		}
+		up = append(up, Upgrade{
+			InfoRecord: InfoRecord{
+				Name:         fields[0],
+				Version:      version,
+				LocalVersion: local[fields[0]],
+				PackageBase:  fields[2],
+				Source:       "Flatpak",
+			},
+			Status: "upgrade",
+		})
+	}
+	return up
+}
+
+// parses the (tab separated) output of "flatpak search --columns=application,name,description,version,remotes"
+// the remote is stored as PackageBase, applications available in multiple remotes are taken from the first one
+func parseFlatpakSearch(out string) []InfoRecord {
+	records := []InfoRecord{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 5 || fields[0] == "" {
+			continue
+		}
+		remote := strings.Split(fields[4], ",")[0]
+		r := InfoRecord{
+			Name:        fields[0],
+			Description: fields[1] + " - " + fields[2],
+			Version:     fields[3],
+			PackageBase: remote,
+			Source:      "Flatpak",
+		}
+		if remote == "flathub" {
+			r.URL = fmt.Sprintf(UrlFlathubApp, r.Name)
+		}
+		records = append(records, r)
+	}
+	return records
+}
@@ -0,0 +1,233 @@
+package pacseek
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	screenshotTimeout  = 15 * time.Second
+	screenshotMaxSize  = 20 << 20 // we don't download images larger than this
+	screenshotMaxWidth = 1024     // images are scaled down to this width (pixels) for sixel terminals
+	kittyChunkSize     = 4096
+)
+
+// the graphics protocols we can render screenshots with
+var graphicsProtocols = []string{"auto", "kitty", "sixel", "none"}
+
+// returns the graphics protocol supported by our terminal ("kitty", "sixel"), empty if there's none
+// terminals can't be asked while our UI is running, so we're looking at the environment
+func detectGraphicsProtocol(getenv func(string) string) string {
+	term, program := getenv("TERM"), strings.ToLower(getenv("TERM_PROGRAM"))
+	switch {
+	case term == "xterm-kitty" || getenv("KITTY_WINDOW_ID") != "" || program == "ghostty" || term == "xterm-ghostty":
+		return "kitty"
+	case program == "wezterm" || strings.HasPrefix(term, "foot") || strings.HasPrefix(term, "mlterm") ||
+		strings.HasPrefix(term, "contour") || strings.Contains(term, "sixel"):
+		return "sixel"
+	}
+	return ""
+}
+
+// returns the graphics protocol we use, our setting or the detected one ("auto")
+func graphicsProtocol(setting string) string {
+	switch setting {
+	case "kitty", "sixel":
+		return setting
+	case "none":
+		return ""
+	}
+	return detectGraphicsProtocol(os.Getenv)
+}
+
+// writes an image with the kitty graphics protocol (transmitted as PNG in chunks)
+// images are scaled to a width of "cols" cells, unless they are smaller
+func writeKittyImage(w io.Writer, img image.Image, cols int) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	data := base64.StdEncoding.EncodeToString(buf.Bytes())
+	for first := true; len(data) > 0; first = false {
+		chunk := data
+		if len(chunk) > kittyChunkSize {
+			chunk = data[:kittyChunkSize]
+		}
+		data = data[len(chunk):]
+		more := 0
+		if len(data) > 0 {
+			more = 1
+		}
+		ctrl := fmt.Sprintf("m=%d", more)
+		if first {
+			ctrl = "a=T,f=100," + ctrl
+			if cols > 0 && img.Bounds().Dx() > cols*8 {
+				ctrl += fmt.Sprintf(",c=%d", cols)
+			}
+		}
+		if _, err := fmt.Fprintf(w, "\x1b_G%s;%s\x1b\\", ctrl, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scales an image down to a maximum width (nearest neighbour, good enough for a preview)
+func scaleImage(img image.Image, maxWidth int) image.Image {
+	b := img.Bounds()
+	if b.Dx() <= maxWidth {
+		return img
+	}
+	h := b.Dy() * maxWidth / b.Dx()
+	scaled := image.NewRGBA(image.Rect(0, 0, maxWidth, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < maxWidth; x++ {
+			scaled.Set(x, y, img.At(b.Min.X+x*b.Dx()/maxWidth, b.Min.Y+y*b.Dy()/h))
+		}
+	}
+	return scaled
+}
+
+// maps a color to our sixel palette (6 levels per channel, 216 colors)
+func sixelColor(c color.Color) int {
+	r, g, b, _ := c.RGBA()
+	return int(r*5/0xffff)*36 + int(g*5/0xffff)*6 + int(b*5/0xffff)
+}
+
+// writes an image as sixel graphics
+func writeSixelImage(w io.Writer, img image.Image) error {
+	img = scaleImage(img, screenshotMaxWidth)
+	b := img.Bounds()
+	bw := bufio.NewWriter(w)
+
+	// start sixel mode with a 1:1 aspect ratio, define our palette (percentages)
+	fmt.Fprintf(bw, "\x1bP0;1;0q\"1;1;%d;%d", b.Dx(), b.Dy())
+	for i := 0; i < 216; i++ {
+		fmt.Fprintf(bw, "#%d;2;%d;%d;%d", i, i/36*20, i/6%6*20, i%6*20)
+	}
+
+	// sixels are bands of 6 rows, each color of a band is drawn separately
+	colors := make([]int, b.Dx()*6)
+	for band := b.Min.Y; band < b.Max.Y; band += 6 {
+		used := [216]bool{}
+		for x := 0; x < b.Dx(); x++ {
+			for dy := 0; dy < 6; dy++ {
+				c := -1
+				if band+dy < b.Max.Y {
+					c = sixelColor(img.At(b.Min.X+x, band+dy))
+					used[c] = true
+				}
+				colors[x*6+dy] = c
+			}
+		}
+		for c := range used {
+			if !used[c] {
+				continue
+			}
+			fmt.Fprintf(bw, "#%d", c)
+			for x := 0; x < b.Dx(); x++ {
+				bits := 0
+				for dy := 0; dy < 6; dy++ {
+					if colors[x*6+dy] == c {
+						bits |= 1 << dy
+					}
+				}
+				bw.WriteByte(byte(63 + bits))
+			}
+			// carriage return, the next color is drawn over the same band
+			bw.WriteByte('$')
+		}
+		// next band
+		bw.WriteByte('-')
+	}
+	bw.WriteString("\x1b\\")
+	return bw.Flush()
+}
+
+// downloads and decodes an image (PNG / JPEG)
+func fetchImage(url string) (image.Image, error) {
+	client := http.Client{
+		Timeout: screenshotTimeout,
+	}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "pacseek/"+version)
+
+	r, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("screenshot could not be downloaded (status %d)", r.StatusCode)
+	}
+	img, _, err := image.Decode(io.LimitReader(r.Body, screenshotMaxSize))
+	return img, err
+}
+
+// writes an image with a graphics protocol
+func writeImage(w io.Writer, protocol string, img image.Image, cols int) error {
+	switch protocol {
+	case "kitty":
+		return writeKittyImage(w, img, cols)
+	case "sixel":
+		return writeSixelImage(w, img)
+	}
+	return errors.New("no graphics protocol available")
+}
+
+// shows the screenshot of the selected application
+// it's rendered in our terminal if it supports graphics, otherwise it's opened with xdg-open
+func (ps *UI) showScreenshot() {
+	if ps.selectedPackage == nil {
+		return
+	}
+	c, ok := ps.appstream.get(ps.selectedPackage.Name)
+	if !ok || len(c.Screenshots) == 0 || ps.selectedPackage.Source == "AUR" {
+		ps.displayMessage("No screenshot available for "+ps.selectedPackage.Name, true)
+		return
+	}
+	url := c.Screenshots[0]
+	protocol := graphicsProtocol(ps.conf.ScreenshotGraphics)
+	if protocol == "" {
+		exec.Command("xdg-open", url).Start()
+		return
+	}
+
+	go func() {
+		ps.locker.Lock()
+		ps.startSpinner()
+		img, err := fetchImage(url)
+		ps.locker.Unlock()
+		ps.stopSpinner()
+		ps.app.QueueUpdateDraw(func() {
+			if err != nil {
+				ps.displayMessage(err.Error(), true)
+				return
+			}
+			ps.app.Suspend(func() {
+				fmt.Print("\x1b[2J\x1b[H")
+				if err := writeImage(os.Stdout, protocol, img, ps.width); err != nil {
+					fmt.Println(err.Error())
+				}
+				fmt.Printf("\n%s - %s\nPress ENTER to return to pacseek\n", c.Name, url)
+				bufio.NewReader(os.Stdin).ReadLine()
+			})
+		})
+	}()
+}
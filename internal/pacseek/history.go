@@ -0,0 +1,135 @@
+package pacseek
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// historyEntry is a package transaction logged by pacman (e.g. "[ALPM] upgraded vim (9.0-1 -> 9.1-1)")
+// "Action" is one of "installed", "upgraded", "downgraded", "reinstalled" or "removed"
+type historyEntry struct {
+	Date       time.Time
+	Action     string
+	Name       string
+	OldVersion string
+	Version    string
+}
+
+// pacmanLog keeps the history of pacman's log file, which is read incrementally (only lines added since our last read)
+type pacmanLog struct {
+	file    string
+	offset  int64
+	entries []historyEntry
+}
+
+// creates a history for pacman's log file, nothing is read until we update it
+func newPacmanLog(file string) *pacmanLog {
+	return &pacmanLog{file: file, entries: []historyEntry{}}
+}
+
+// reads the lines that have been added to the log file since our last update
+// if the file got smaller (e.g. rotated by logrotate), it is read from the beginning
+func (l *pacmanLog) update() error {
+	f, err := os.Open(l.file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.Size() < l.offset {
+		l.offset = 0
+		l.entries = []historyEntry{}
+	}
+	if _, err := f.Seek(l.offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	// an incomplete last line (pacman is still writing) is picked up with our next update
+	end := bytes.LastIndexByte(b, '\n') + 1
+	scanner := bufio.NewScanner(bytes.NewReader(b[:end]))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if e, ok := parseHistoryLine(scanner.Text()); ok {
+			l.entries = append(l.entries, e)
+		}
+	}
+	l.offset += int64(end)
+	return scanner.Err()
+}
+
+// parses the time stamp of a log line and returns the remainder of the line
+// entries look like "[2024-03-10T12:34:56+0100] [ALPM] ..." (older pacman versions: "[2019-01-01 12:34] [ALPM] ...")
+func parseLogTime(line string) (time.Time, string, bool) {
+	if !strings.HasPrefix(line, "[") {
+		return time.Time{}, "", false
+	}
+	stamp, rest, found := strings.Cut(line[1:], "] ")
+	if !found {
+		return time.Time{}, "", false
+	}
+	if t, err := time.Parse("2006-01-02T15:04:05-0700", stamp); err == nil {
+		return t, rest, true
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04", stamp, time.Local); err == nil {
+		return t, rest, true
+	}
+	return time.Time{}, "", false
+}
+
+// parses a log line of a package transaction, other lines (hooks, scriptlet output, ...) are skipped
+func parseHistoryLine(line string) (historyEntry, bool) {
+	t, rest, ok := parseLogTime(line)
+	if !ok {
+		return historyEntry{}, false
+	}
+	if !strings.HasPrefix(rest, "[ALPM] ") {
+		return historyEntry{}, false
+	}
+	fields := strings.SplitN(strings.TrimPrefix(rest, "[ALPM] "), " ", 3)
+	if len(fields) != 3 || !strings.HasPrefix(fields[2], "(") || !strings.HasSuffix(fields[2], ")") {
+		return historyEntry{}, false
+	}
+	e := historyEntry{Date: t, Action: fields[0], Name: fields[1]}
+	versions := strings.TrimSuffix(strings.TrimPrefix(fields[2], "("), ")")
+	switch e.Action {
+	case "upgraded", "downgraded":
+		old, version, found := strings.Cut(versions, " -> ")
+		if !found {
+			return historyEntry{}, false
+		}
+		e.OldVersion, e.Version = old, version
+	case "installed", "reinstalled", "removed":
+		e.Version = versions
+	default:
+		return historyEntry{}, false
+	}
+	return e, true
+}
+
+// returns the entries (newest first) for packages containing "filter" in their name
+// a filter like "=vim" shows the timeline of exactly that package
+func filterHistory(entries []historyEntry, filter string) []historyEntry {
+	filtered := []historyEntry{}
+	filter = strings.ToLower(strings.TrimSpace(filter))
+	exact := strings.HasPrefix(filter, "=")
+	filter = strings.TrimPrefix(filter, "=")
+	for i := len(entries) - 1; i >= 0; i-- {
+		name := strings.ToLower(entries[i].Name)
+		if (exact && name == filter) || (!exact && strings.Contains(name, filter)) {
+			filtered = append(filtered, entries[i])
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,273 @@
+package pacseek
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// keyBinding is a key (combination) like "Ctrl+Q", "Shift+M", "Alt+x" or "Space"
+// letters with Shift are bound as upper case runes; "ch" is set for runes, "key" for all other keys
+type keyBinding struct {
+	key  tcell.Key
+	ch   rune
+	mods tcell.ModMask
+}
+
+// keyAction is an action that can be bound to a key
+// the context is where the key is handled: "global" keys work everywhere, "search" ones in our search field, "list" ones in the package list
+type keyAction struct {
+	name        string
+	key         string
+	context     string
+	description string
+}
+
+// our actions with their default keys, in the order they are shown in our help
+var keyActions = []keyAction{
+	{"Search", "Enter", "search", "Search"},
+	{"Install", "Enter", "list", "Install or remove the selected / queued packages"},
+	{"NextBox", "Tab", "list", "Navigate to the next box"},
+	{"Settings", "Ctrl+S", "global", "Open/Close settings"},
+	{"Help", "Ctrl+N", "global", "Show these instructions"},
+	{"Upgrade", "Ctrl+U", "global", "Perform sysupgrade"},
+	{"AurUpgrade", "Ctrl+A", "global", "Perform AUR upgrade (if configured)"},
+	{"WipeCache", "Ctrl+W", "global", "Wipe cache / refresh"},
+	{"Pkgbuild", "Ctrl+P", "global", "Show PKGBUILD for selected package"},
+	{"OpenURL", "Ctrl+O", "global", "Open URL for selected package"},
+	{"Upgrades", "Ctrl+G", "global", "Show list of upgradeable packages"},
+	{"Installed", "Ctrl+L", "global", "Show list of all installed packages"},
+	{"Dependencies", "Ctrl+D", "global", "Show/Hide dependency tree of selected package (package list / details)"},
+	{"ReverseDependencies", "Ctrl+R", "global", "Show/Hide reverse dependencies of selected package"},
+	{"Advisories", "Ctrl+V", "global", "Show security advisories for installed packages"},
+	{"Comments", "Ctrl+T", "global", "Show/Hide comments of selected AUR package"},
+	{"Changelog", "Ctrl+E", "global", "Show/Hide changelog of selected package"},
+	{"Files", "Ctrl+F", "global", "Show/Hide file list of selected package"},
+	{"CachedVersions", "Ctrl+K", "global", "Show cached versions of selected package (install/downgrade)"},
+	{"Groups", "Ctrl+X", "global", "Show/Hide package groups (ENTER shows members, i installs group)"},
+	{"Statistics", "Ctrl+Y", "global", "Show statistics of installed packages"},
+	{"History", "Ctrl+J", "global", "Show/Hide install history (ENTER shows cached versions, t the timeline of a package)"},
+	{"About", "Ctrl+B", "global", "Show about"},
+	{"DebugConsole", "F12", "global", "Show/Hide debug console (last log lines, r reloads)"},
+	{"Queue", "Space", "list", "Mark package for batch install/removal"},
+	{"ShowQueue", "Shift+Q", "list", "Show the queued packages"},
+	{"LocalFilter", "Shift+F", "list", "Show all / orphaned / explicitly installed / foreign packages"},
+	{"FilterBar", "Shift+L", "list", "Filter results by license / architecture (e.g. license:GPL,MIT arch:any)"},
+	{"Download", "Shift+G", "list", "Download the queued / selected packages without installing them (pacman -Sw)"},
+	{"CopyName", "Shift+C", "list", "Copy the name of the selected package to the clipboard"},
+	{"CopyURL", "Shift+H", "list", "Copy the upstream URL of the selected package to the clipboard"},
+	{"CopyPackageURL", "Shift+A", "list", "Copy the AUR / archlinux.org page of the selected package to the clipboard"},
+	{"CopyCommand", "Shift+E", "list", "Copy the install / remove command of the selected package to the clipboard"},
+	{"CopyInfo", "Shift+Y", "list", "Copy the info of the selected package to the clipboard (like pacman -Si)"},
+	{"Screenshot", "Shift+O", "list", "Show the screenshot of the selected application (AppStream)"},
+	{"Pacnew", "Shift+R", "list", "Show .pacnew / .pacsave files (ENTER merges the selected one)"},
+	{"Ignore", "Shift+X", "list", "Add/Remove selected package to/from the ignore list (upgrades)"},
+	{"Mirrors", "Shift+T", "list", "Test mirrors (latency, throughput, last sync)"},
+	{"Vote", "Shift+U", "list", "Vote / unvote for selected AUR package (if AUR voting is enabled)"},
+	{"VotedPackages", "Shift+W", "list", "Show the AUR packages you voted for (if AUR voting is enabled)"},
+	{"SortByName", "Shift+N", "list", "Sort by name"},
+	{"SortBySource", "Shift+S", "list", "Sort by source"},
+	{"SortByInstalled", "Shift+I", "list", "Sort by installed state"},
+	{"SortByModified", "Shift+M", "list", "Sort by last modified date"},
+	{"SortByPopularity", "Shift+P", "list", "Sort by popularity"},
+	{"SortByVotes", "Shift+V", "list", "Sort by votes"},
+	{"SortBySize", "Shift+Z", "list", "Sort by installed size"},
+	{"SortByDownloadSize", "Shift+D", "list", "Sort by download size"},
+	{"Quit", "Ctrl+Q", "global", "Quit"},
+}
+
+// sorting actions and the criterion they sort by
+var sortActions = map[string]rune{
+	"SortByName":         'N',
+	"SortBySource":       'S',
+	"SortByInstalled":    'I',
+	"SortByModified":     'M',
+	"SortByPopularity":   'P',
+	"SortByVotes":        'V',
+	"SortBySize":         'Z',
+	"SortByDownloadSize": 'D',
+}
+
+// keymap holds the key binding of each action
+type keymap map[string]keyBinding
+
+// names for keys that are not in tcell's list of key names
+var keyAliases = map[string]tcell.Key{
+	"escape":   tcell.KeyEsc,
+	"return":   tcell.KeyEnter,
+	"pageup":   tcell.KeyPgUp,
+	"pagedown": tcell.KeyPgDn,
+	"del":      tcell.KeyDelete,
+}
+
+// parses a key binding like "Ctrl+Q", "Shift+M", "Alt+x", "Space", "F5" or "Shift+Left"
+func parseKeyBinding(s string) (keyBinding, error) {
+	b := keyBinding{}
+	parts := strings.Split(strings.TrimSpace(s), "+")
+	// "+" itself, e.g. "Shift++"
+	if len(parts) > 1 && parts[len(parts)-1] == "" {
+		parts = append(parts[:len(parts)-2], "+")
+	}
+	name := parts[len(parts)-1]
+	for _, mod := range parts[:len(parts)-1] {
+		switch strings.ToLower(strings.TrimSpace(mod)) {
+		case "ctrl":
+			b.mods |= tcell.ModCtrl
+		case "alt":
+			b.mods |= tcell.ModAlt
+		case "shift":
+			b.mods |= tcell.ModShift
+		default:
+			return b, fmt.Errorf("invalid key binding '%s': unknown modifier %s", s, mod)
+		}
+	}
+
+	runes := []rune(name)
+	switch {
+	case strings.EqualFold(name, "space"):
+		b.ch = ' '
+	case len(runes) == 1 && b.mods&tcell.ModCtrl != 0:
+		r := unicode.ToLower(runes[0])
+		if r < 'a' || r > 'z' || b.mods&tcell.ModAlt != 0 {
+			return b, fmt.Errorf("invalid key binding '%s': only Ctrl+<letter> is supported", s)
+		}
+		b.key = tcell.KeyCtrlA + tcell.Key(r-'a')
+		b.mods = tcell.ModCtrl
+		if !isCtrlLetter(b.key) {
+			return b, fmt.Errorf("invalid key binding '%s': terminals send the same key for Backspace / Tab / Enter", s)
+		}
+		return b, nil
+	case len(runes) == 1:
+		b.ch = runes[0]
+	default:
+		k, ok := keyAliases[strings.ToLower(name)]
+		if !ok {
+			for key, keyName := range tcell.KeyNames {
+				if strings.EqualFold(keyName, name) && !strings.HasPrefix(keyName, "Ctrl-") {
+					k, ok = key, true
+					break
+				}
+			}
+		}
+		if !ok {
+			return b, fmt.Errorf("invalid key binding '%s': unknown key %s", s, name)
+		}
+		b.key = k
+		return b, nil
+	}
+
+	// runes: Shift is part of the rune
+	if b.mods&tcell.ModShift != 0 {
+		b.ch = unicode.ToUpper(b.ch)
+		b.mods &^= tcell.ModShift
+	}
+	b.key = tcell.KeyRune
+	return b, nil
+}
+
+// checks if a key is Ctrl+<letter>, Ctrl+H / Ctrl+I / Ctrl+M are the same as Backspace / Tab / Enter
+func isCtrlLetter(k tcell.Key) bool {
+	return k >= tcell.KeyCtrlA && k <= tcell.KeyCtrlZ && k != tcell.KeyBackspace && k != tcell.KeyTab && k != tcell.KeyEnter
+}
+
+// checks if a key event matches our binding
+func (b keyBinding) matches(event *tcell.EventKey) bool {
+	switch {
+	case b.key == tcell.KeyRune:
+		return event.Key() == tcell.KeyRune && event.Rune() == b.ch && event.Modifiers()&tcell.ModAlt == b.mods&tcell.ModAlt
+	case isCtrlLetter(b.key):
+		return event.Key() == b.key
+	default:
+		return event.Key() == b.key && event.Modifiers()&^tcell.ModMeta == b.mods
+	}
+}
+
+// returns our binding in the style of our help text (e.g. "CTRL+Q", "Shift+M", "SPACE")
+func (b keyBinding) String() string {
+	mods := ""
+	if b.mods&tcell.ModCtrl != 0 && !isCtrlLetter(b.key) {
+		mods += "CTRL+"
+	}
+	if b.mods&tcell.ModAlt != 0 {
+		mods += "ALT+"
+	}
+	if b.mods&tcell.ModShift != 0 {
+		mods += "Shift+"
+	}
+	switch {
+	case b.key == tcell.KeyRune && b.ch == ' ':
+		return mods + "SPACE"
+	case b.key == tcell.KeyRune && unicode.IsUpper(b.ch):
+		return mods + "Shift+" + string(b.ch)
+	case b.key == tcell.KeyRune:
+		return mods + string(b.ch)
+	case isCtrlLetter(b.key):
+		return "CTRL+" + string(rune('A'+b.key-tcell.KeyCtrlA))
+	case b.key == tcell.KeyEnter || b.key == tcell.KeyTab || b.key == tcell.KeyEsc:
+		return mods + strings.ToUpper(tcell.KeyNames[b.key])
+	}
+	return mods + tcell.KeyNames[b.key]
+}
+
+// creates our keymap from the default keys, overridden by the ones of our configuration (action name -> key)
+// invalid bindings are skipped (the default key is used). the returned error lists all problems found,
+// including conflicting bindings (global keys conflict with all others, others with the ones of the same context)
+func newKeymap(bindings map[string]string) (keymap, error) {
+	km := keymap{}
+	problems := []string{}
+	known := map[string]bool{}
+	for _, a := range keyActions {
+		known[a.name] = true
+		b, _ := parseKeyBinding(a.key)
+		km[a.name] = b
+	}
+
+	names := []string{}
+	for name := range bindings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !known[name] {
+			problems = append(problems, fmt.Sprintf("unknown action '%s'", name))
+			continue
+		}
+		b, err := parseKeyBinding(bindings[name])
+		if err != nil {
+			problems = append(problems, name+": "+err.Error())
+			continue
+		}
+		km[name] = b
+	}
+
+	for i, a := range keyActions {
+		b := km[a.name]
+		if a.context == "global" && b.key == tcell.KeyRune && b.mods&tcell.ModAlt == 0 {
+			problems = append(problems, fmt.Sprintf("%s: global keys need a modifier (Ctrl / Alt) or a special key, '%s' would be typed into our search field", a.name, b))
+		}
+		for _, o := range keyActions[i+1:] {
+			if km[o.name] == b && (a.context == o.context || a.context == "global" || o.context == "global") {
+				problems = append(problems, fmt.Sprintf("%s and %s are both bound to %s", a.name, o.name, b))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return km, fmt.Errorf("key bindings: %s", strings.Join(problems, "; "))
+	}
+	return km, nil
+}
+
+// checks if a key event triggers an action
+func (km keymap) matches(action string, event *tcell.EventKey) bool {
+	b, ok := km[action]
+	return ok && b.matches(event)
+}
+
+// returns the key of an action for our help text
+func (km keymap) label(action string) string {
+	return km[action].String()
+}
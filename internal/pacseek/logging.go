@@ -0,0 +1,273 @@
+package pacseek
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	logMaxSize  = 1 << 20 // our log file is rotated when it exceeds this size
+	logKeep     = 3       // number of rotated log files we keep (pacseek.log.1 ...)
+	logMaxLines = 500     // number of lines we keep for our debug console
+)
+
+// the levels of our log, "off" disables logging
+var logLevels = []string{"debug", "info", "warn", "error", "off"}
+
+const (
+	levelDebug = iota
+	levelInfo
+	levelWarn
+	levelError
+	levelOff
+)
+
+// returns the level for a name, unknown ones fall back to "info"
+func parseLogLevel(name string) int {
+	for i, l := range logLevels {
+		if strings.EqualFold(l, strings.TrimSpace(name)) {
+			return i
+		}
+	}
+	return levelInfo
+}
+
+// returns our state directory ($XDG_STATE_HOME/pacseek or ~/.local/state/pacseek), our log files are kept here
+func stateDir(getenv func(string) string) string {
+	if dir := getenv("XDG_STATE_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "pacseek")
+	}
+	home := getenv("HOME")
+	if home == "" {
+		home = os.TempDir()
+	}
+	return filepath.Join(home, ".local", "state", "pacseek")
+}
+
+// formats a value for our log, values with spaces, quotes or "=" are quoted
+func logValue(v interface{}) string {
+	var s string
+	switch v := v.(type) {
+	case time.Duration:
+		s = v.Round(time.Millisecond).String()
+	case error:
+		s = v.Error()
+	default:
+		s = fmt.Sprint(v)
+	}
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// formats a line of our log (logfmt): a timestamp, the level, the message and key / value pairs
+// pairs with a nil value (e.g. no error) are left out
+func formatLogLine(t time.Time, level int, msg string, kv ...interface{}) string {
+	var b strings.Builder
+	b.WriteString("time=" + t.Format("2006-01-02T15:04:05.000Z07:00"))
+	b.WriteString(" level=" + logLevels[level])
+	b.WriteString(" msg=" + logValue(msg))
+	for i := 0; i < len(kv); i += 2 {
+		var v interface{}
+		if i+1 < len(kv) {
+			v = kv[i+1]
+		}
+		if v == nil {
+			continue
+		}
+		b.WriteString(" " + fmt.Sprint(kv[i]) + "=" + logValue(v))
+	}
+	return b.String()
+}
+
+// renames our log file to pacseek.log.1 (and .1 to .2 etc.), the oldest one is removed
+func rotateLogs(path string, keep int) error {
+	os.Remove(path + "." + strconv.Itoa(keep))
+	for i := keep - 1; i > 0; i-- {
+		os.Rename(path+"."+strconv.Itoa(i), path+"."+strconv.Itoa(i+1))
+	}
+	if keep == 0 {
+		return os.Remove(path)
+	}
+	return os.Rename(path, path+".1")
+}
+
+// eventLog writes our log file and keeps the last lines for our debug console
+// a nil eventLog is valid and doesn't log anything (e.g. when disabled)
+type eventLog struct {
+	mu      sync.Mutex
+	level   int
+	path    string
+	file    *os.File
+	size    int64
+	maxSize int64
+	keep    int
+	lines   []string
+	now     func() time.Time
+}
+
+// the log of our UI, nil until it has been created
+var logger *eventLog
+
+// creates a log writing to a file, which is rotated when it exceeds "maxSize"
+// if the file can't be opened, an error is returned and our lines are only kept for the debug console
+func newEventLog(path string, level int, maxSize int64, keep int) (*eventLog, error) {
+	l := &eventLog{
+		level:   levelOff,
+		path:    path,
+		maxSize: maxSize,
+		keep:    keep,
+		now:     time.Now,
+	}
+	return l, l.setLevel(level)
+}
+
+// changes the level of our log, the file is opened / closed when logging gets enabled / disabled
+func (l *eventLog) setLevel(level int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.level = level
+	if level >= levelOff && l.file != nil {
+		err := l.file.Close()
+		l.file = nil
+		return err
+	}
+	if level < levelOff && l.file == nil {
+		return l.open()
+	}
+	return nil
+}
+
+// opens our log file, it's rotated first if it is too large already
+func (l *eventLog) open() error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+	if fi, err := os.Stat(l.path); err == nil && l.maxSize > 0 && fi.Size() >= l.maxSize {
+		if err := rotateLogs(l.path, l.keep); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.file, l.size = f, fi.Size()
+	return nil
+}
+
+// adds a line to our log if its level is high enough
+func (l *eventLog) log(level int, msg string, kv ...interface{}) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if level < l.level {
+		return
+	}
+
+	line := formatLogLine(l.now(), level, msg, kv...)
+	l.lines = append(l.lines, line)
+	if len(l.lines) > logMaxLines {
+		l.lines = l.lines[len(l.lines)-logMaxLines:]
+	}
+	if l.file == nil {
+		return
+	}
+	n, _ := l.file.WriteString(line + "\n")
+	l.size += int64(n)
+	if l.maxSize > 0 && l.size >= l.maxSize {
+		// if the new file can't be opened, we only keep our lines for the debug console
+		l.file.Close()
+		l.file = nil
+		l.open()
+	}
+}
+
+func (l *eventLog) debug(msg string, kv ...interface{}) { l.log(levelDebug, msg, kv...) }
+func (l *eventLog) info(msg string, kv ...interface{})  { l.log(levelInfo, msg, kv...) }
+func (l *eventLog) warn(msg string, kv ...interface{})  { l.log(levelWarn, msg, kv...) }
+
+// returns the last "n" lines of our log (all of them if n is 0)
+func (l *eventLog) last(n int) []string {
+	if l == nil {
+		return []string{}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if n <= 0 || n > len(l.lines) {
+		n = len(l.lines)
+	}
+	return append([]string{}, l.lines[len(l.lines)-n:]...)
+}
+
+// returns the path of our log file
+func (l *eventLog) filePath() string {
+	if l == nil {
+		return ""
+	}
+	return l.path
+}
+
+// closes our log file
+func (l *eventLog) close() error {
+	if l == nil {
+		return nil
+	}
+	return l.setLevel(levelOff)
+}
+
+// loggedTransport logs our HTTP requests with their status and latency
+type loggedTransport struct {
+	base http.RoundTripper
+}
+
+// the transport for our AUR requests
+var aurTransport http.RoundTripper = loggedTransport{}
+
+func (t loggedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		logger.warn("http request failed", "method", req.Method, "url", req.URL.Redacted(), "duration", time.Since(start), "error", err)
+		return resp, err
+	}
+	level := levelInfo
+	if resp.StatusCode >= 400 {
+		level = levelWarn
+	}
+	logger.log(level, "http request", "method", req.Method, "url", req.URL.Redacted(), "status", resp.StatusCode, "duration", time.Since(start))
+	return resp, err
+}
+
+// creates our log (or applies a changed log level)
+func (ps *UI) setupLog() error {
+	var err error
+	if logger == nil {
+		logger, err = newEventLog(filepath.Join(stateDir(os.Getenv), "pacseek.log"), parseLogLevel(ps.conf.LogLevel), logMaxSize, logKeep)
+	} else {
+		err = logger.setLevel(parseLogLevel(ps.conf.LogLevel))
+	}
+	if err != nil {
+		return errors.New("log file could not be opened: " + err.Error())
+	}
+	return nil
+}
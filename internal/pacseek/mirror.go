@@ -0,0 +1,220 @@
+package pacseek
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pconf "github.com/Morganamilo/go-pacmanconf"
+	"github.com/moson-mo/pacseek/internal/util"
+)
+
+const (
+	UrlMirrorStatus = "https://archlinux.org/mirrors/status/json/"
+	mirrorlistFile  = "/etc/pacman.d/mirrorlist"
+)
+
+const (
+	mirrorTimeout    = 10 * time.Second
+	mirrorCheckCount = 10             // number of mirrors (from the top of our mirrorlist) we test
+	mirrorLagWarning = 24 * time.Hour // mirrors that haven't synced for longer than this are considered out of date
+)
+
+// mirrorHealth is the result of testing a mirror
+// Throughput is measured by downloading the core db (bytes per second), LastSync is taken from the mirror status (zero if unknown)
+type mirrorHealth struct {
+	Server     string
+	Latency    time.Duration
+	Throughput float64
+	LastSync   time.Time
+	Error      string
+}
+
+// mirrorStatus is the mirror status JSON of archlinux.org
+type mirrorStatus struct {
+	Urls []struct {
+		Url      string     `json:"url"`
+		LastSync *time.Time `json:"last_sync"`
+	} `json:"urls"`
+}
+
+// returns the download URL's of a package file for all servers of a repository
+func packageDownloadURLs(conf *pconf.Config, repo, filename string) []string {
+	urls := []string{}
+
+	r := conf.Repository(repo)
+	if r == nil {
+		return urls
+	}
+
+	arch := configuredArchitecture(conf.Architecture)
+	for _, server := range r.Servers {
+		urls = append(urls, strings.TrimSuffix(expandServerURL(server, repo, arch), "/")+"/"+filename)
+	}
+
+	return urls
+}
+
+// replaces the $repo and $arch variables of a mirror/server URL
+func expandServerURL(server, repo, arch string) string {
+	return strings.NewReplacer("$repo", repo, "$arch", arch).Replace(server)
+}
+
+// returns all architectures set in pacman.conf (resolved like configuredArchitecture) plus "any"
+func hostArchitectures(archs []string) []string {
+	ret := []string{}
+	if len(archs) == 0 {
+		archs = []string{"auto"}
+	}
+	for _, arch := range append(archs, "any") {
+		if arch = util.ResolveArchitecture(arch); !util.SliceContains(ret, arch) {
+			ret = append(ret, arch)
+		}
+	}
+	return ret
+}
+
+// returns the (first) architecture set in pacman.conf
+// "auto" or a missing setting resolve to the architecture of the running system
+func configuredArchitecture(archs []string) string {
+	if len(archs) == 0 {
+		return util.ResolveArchitecture("auto")
+	}
+	return util.ResolveArchitecture(archs[0])
+}
+
+// returns the active (not commented out) servers of a mirrorlist, in the order they are used by pacman
+func parseMirrorlist(r io.Reader) []string {
+	servers := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if found && strings.TrimSpace(key) == "Server" && strings.TrimSpace(value) != "" {
+			servers = append(servers, strings.TrimSpace(value))
+		}
+	}
+	return servers
+}
+
+// reads the active servers from a mirrorlist file
+func readMirrorlist(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseMirrorlist(f), nil
+}
+
+// returns the base URL of a mirror (the part in front of $repo), like it is listed in the mirror status
+func mirrorBaseURL(server string) string {
+	base, _, _ := strings.Cut(server, "$repo")
+	return strings.TrimSuffix(base, "/") + "/"
+}
+
+// retrieves the time of the last sync for each mirror (base URL) from the mirror status JSON
+func getMirrorStatus(url string) (map[string]time.Time, error) {
+	client := http.Client{
+		Timeout: mirrorTimeout,
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download mirror status: %s", resp.Status)
+	}
+
+	var status mirrorStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, err
+	}
+	synced := map[string]time.Time{}
+	for _, u := range status.Urls {
+		if u.LastSync != nil {
+			synced[mirrorBaseURL(u.Url)] = *u.LastSync
+		}
+	}
+	return synced, nil
+}
+
+// measures the latency (time until we get a response) and throughput of a mirror by downloading the core db
+func checkMirror(server, arch string) mirrorHealth {
+	m := mirrorHealth{Server: server}
+	client := http.Client{
+		Timeout: mirrorTimeout,
+	}
+	start := time.Now()
+	resp, err := client.Get(strings.TrimSuffix(expandServerURL(server, "core", arch), "/") + "/core.db")
+	if err != nil {
+		m.Error = err.Error()
+		return m
+	}
+	defer resp.Body.Close()
+	m.Latency = time.Since(start)
+	if resp.StatusCode != http.StatusOK {
+		m.Error = resp.Status
+		return m
+	}
+	n, err := io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		m.Error = err.Error()
+		return m
+	}
+	if elapsed := time.Since(start).Seconds(); elapsed > 0 {
+		m.Throughput = float64(n) / elapsed
+	}
+	return m
+}
+
+// tests the first mirrors of our list concurrently and adds their last sync from the mirror status (if we got it)
+// results are kept in the order of our mirrorlist
+func checkMirrors(servers []string, arch string, synced map[string]time.Time) []mirrorHealth {
+	if len(servers) > mirrorCheckCount {
+		servers = servers[:mirrorCheckCount]
+	}
+	mirrors := make([]mirrorHealth, len(servers))
+	var wg sync.WaitGroup
+	for i, server := range servers {
+		wg.Add(1)
+		go func(i int, server string) {
+			defer wg.Done()
+			mirrors[i] = checkMirror(server, arch)
+			mirrors[i].LastSync = synced[mirrorBaseURL(server)]
+		}(i, server)
+	}
+	wg.Wait()
+	return mirrors
+}
+
+// returns the mirrors that haven't synced for longer than mirrorLagWarning (sorted by their lag, worst first)
+func outdatedMirrors(mirrors []mirrorHealth, now time.Time) []mirrorHealth {
+	outdated := []mirrorHealth{}
+	for _, m := range mirrors {
+		if !m.LastSync.IsZero() && now.Sub(m.LastSync) > mirrorLagWarning {
+			outdated = append(outdated, m)
+		}
+	}
+	sort.SliceStable(outdated, func(i, j int) bool {
+		return outdated[i].LastSync.Before(outdated[j].LastSync)
+	})
+	return outdated
+}
+
+// formats the time since the last sync of a mirror, like "3h15m"
+func formatLag(lag time.Duration) string {
+	lag = lag.Round(time.Minute)
+	h := int(lag.Hours())
+	if h >= 48 {
+		return fmt.Sprintf("%dd%dh", h/24, h%24)
+	}
+	return fmt.Sprintf("%dh%02dm", h, int(lag.Minutes())%60)
+}
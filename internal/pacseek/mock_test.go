@@ -0,0 +1,310 @@
+package pacseek
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Jguer/go-alpm/v2"
+)
+
+// mockHandle is a fixture implementation of our database handle
+type mockHandle struct {
+	sync       []*mockDB
+	local      *mockDB
+	syncCalls  int
+	localCalls int
+}
+
+func (h *mockHandle) SyncDBs() (alpm.IDBList, error) {
+	h.syncCalls++
+	dbs := mockDBList{}
+	for _, db := range h.sync {
+		dbs = append(dbs, db)
+	}
+	return &dbs, nil
+}
+
+func (h *mockHandle) LocalDB() (alpm.IDB, error) {
+	h.localCalls++
+	if h.local == nil {
+		return nil, errors.New("no local db")
+	}
+	return h.local, nil
+}
+
+// mockTransHandle is a fixture implementation of our transaction handle
+// alpm package lists can't be created outside of alpm, so transactions never contain packages
+type mockTransHandle struct {
+	*mockHandle
+	initErr     error
+	upgradeErr  error
+	initFlags   alpm.TransFlag
+	initialized bool
+	released    bool
+}
+
+func (h *mockTransHandle) TransInit(flags alpm.TransFlag) error {
+	if h.initErr != nil {
+		return h.initErr
+	}
+	h.initFlags = flags
+	h.initialized = true
+	return nil
+}
+
+func (h *mockTransHandle) TransRelease() error {
+	h.released = true
+	return nil
+}
+
+func (h *mockTransHandle) SyncSysupgrade(enableDowngrade bool) error { return h.upgradeErr }
+func (h *mockTransHandle) TransGetAdd() alpm.PackageList             { return alpm.PackageList{} }
+func (h *mockTransHandle) TransGetRemove() alpm.PackageList          { return alpm.PackageList{} }
+
+// mockDB is a fixture implementation of alpm.IDB
+type mockDB struct {
+	name    string
+	servers []string
+	pkgs    []*mockPackage
+}
+
+// creates a mock database and links the packages to it
+func newMockDB(name string, pkgs ...*mockPackage) *mockDB {
+	db := &mockDB{name: name, pkgs: pkgs}
+	for _, pkg := range pkgs {
+		pkg.db = db
+	}
+	return db
+}
+
+func (db *mockDB) Unregister() error           { return nil }
+func (db *mockDB) Name() string                { return db.name }
+func (db *mockDB) Servers() []string           { return db.servers }
+func (db *mockDB) SetServers(servers []string) { db.servers = servers }
+func (db *mockDB) AddServer(server string)     { db.servers = append(db.servers, server) }
+func (db *mockDB) SetUsage(usage alpm.Usage)   {}
+
+func (db *mockDB) Pkg(name string) alpm.IPackage {
+	for _, pkg := range db.pkgs {
+		if pkg.name == name {
+			return pkg
+		}
+	}
+	return nil
+}
+
+func (db *mockDB) PkgCache() alpm.IPackageList {
+	list := mockPackageList{}
+	for _, pkg := range db.pkgs {
+		list = append(list, pkg)
+	}
+	return list
+}
+
+func (db *mockDB) Search(targets []string) alpm.IPackageList {
+	list := mockPackageList{}
+	for _, pkg := range db.pkgs {
+		for _, t := range targets {
+			if strings.Contains(pkg.name, t) || strings.Contains(pkg.desc, t) {
+				list = append(list, pkg)
+				break
+			}
+		}
+	}
+	return list
+}
+
+// mockDBList is a fixture implementation of alpm.IDBList
+type mockDBList []alpm.IDB
+
+func (l *mockDBList) ForEach(f func(alpm.IDB) error) error {
+	for _, db := range *l {
+		if err := f(db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *mockDBList) Slice() []alpm.IDB {
+	return append([]alpm.IDB{}, *l...)
+}
+
+func (l *mockDBList) Append(db alpm.IDB) {
+	*l = append(*l, db)
+}
+
+func (l *mockDBList) FindGroupPkgs(name string) alpm.IPackageList {
+	list := mockPackageList{}
+	for _, db := range *l {
+		for _, pkg := range db.PkgCache().Slice() {
+			if strings.Contains(" "+strings.Join(pkg.(*mockPackage).groups, " ")+" ", " "+name+" ") {
+				list = append(list, pkg)
+			}
+		}
+	}
+	return list
+}
+
+func (l *mockDBList) FindSatisfier(depstring string) (alpm.IPackage, error) {
+	for _, db := range *l {
+		if pkg, err := db.PkgCache().FindSatisfier(depstring); err == nil {
+			return pkg, nil
+		}
+	}
+	return nil, errors.New("unable to satisfy dependency " + depstring)
+}
+
+// mockPackageList is a fixture implementation of alpm.IPackageList
+type mockPackageList []alpm.IPackage
+
+func (l mockPackageList) ForEach(f func(alpm.IPackage) error) error {
+	for _, pkg := range l {
+		if err := f(pkg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l mockPackageList) Slice() []alpm.IPackage {
+	return append([]alpm.IPackage{}, l...)
+}
+
+func (l mockPackageList) SortBySize() alpm.IPackageList {
+	return l
+}
+
+func (l mockPackageList) FindSatisfier(depstring string) (alpm.IPackage, error) {
+	dep := parseDependency(depstring)
+	for _, pkg := range l {
+		if pkg.Name() == dep.Name && versionSatisfies(pkg.Version(), dep) {
+			return pkg, nil
+		}
+	}
+	for _, pkg := range l {
+		if packageSatisfies(pkg, dep) {
+			return pkg, nil
+		}
+	}
+	return nil, errors.New("unable to find dependency " + depstring)
+}
+
+// mockDependList is a fixture implementation of alpm.IDependList
+type mockDependList []alpm.Depend
+
+func (l mockDependList) ForEach(f func(*alpm.Depend) error) error {
+	for i := range l {
+		if err := f(&l[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l mockDependList) Slice() []alpm.Depend {
+	return append([]alpm.Depend{}, l...)
+}
+
+// creates a dependency list from strings like "glibc>=2.38"
+func mockDeps(deps ...string) mockDependList {
+	list := mockDependList{}
+	for _, d := range deps {
+		list = append(list, parseDependency(d))
+	}
+	return list
+}
+
+// mockPackage is a fixture implementation of alpm.IPackage
+type mockPackage struct {
+	name         string
+	version      string
+	desc         string
+	arch         string
+	base         string
+	packager     string
+	url          string
+	filename     string
+	size         int64
+	isize        int64
+	buildDate    time.Time
+	installDate  time.Time
+	reason       alpm.PkgReason
+	validation   alpm.Validation
+	ignore       bool
+	groups       []string
+	files        []alpm.File
+	depends      mockDependList
+	optDepends   mockDependList
+	makeDepends  mockDependList
+	checkDepends mockDependList
+	provides     mockDependList
+	conflicts    mockDependList
+	replaces     mockDependList
+	requiredBy   []string
+	optionalFor  []string
+	db           *mockDB
+}
+
+func (p *mockPackage) FileName() string                  { return p.filename }
+func (p *mockPackage) Base() string                      { return p.base }
+func (p *mockPackage) Base64Signature() string           { return "" }
+func (p *mockPackage) Validation() alpm.Validation       { return p.validation }
+func (p *mockPackage) Architecture() string              { return p.arch }
+func (p *mockPackage) Backup() alpm.BackupList           { return alpm.BackupList{} }
+func (p *mockPackage) BuildDate() time.Time              { return p.buildDate }
+func (p *mockPackage) Conflicts() alpm.IDependList       { return p.conflicts }
+func (p *mockPackage) Depends() alpm.IDependList         { return p.depends }
+func (p *mockPackage) OptionalDepends() alpm.IDependList { return p.optDepends }
+func (p *mockPackage) CheckDepends() alpm.IDependList    { return p.checkDepends }
+func (p *mockPackage) MakeDepends() alpm.IDependList     { return p.makeDepends }
+func (p *mockPackage) Description() string               { return p.desc }
+func (p *mockPackage) Files() []alpm.File                { return p.files }
+func (p *mockPackage) Groups() alpm.StringList           { return alpm.StringList{} }
+func (p *mockPackage) ISize() int64                      { return p.isize }
+func (p *mockPackage) InstallDate() time.Time            { return p.installDate }
+func (p *mockPackage) Licenses() alpm.StringList         { return alpm.StringList{} }
+func (p *mockPackage) SHA256Sum() string                 { return "" }
+func (p *mockPackage) MD5Sum() string                    { return "" }
+func (p *mockPackage) Name() string                      { return p.name }
+func (p *mockPackage) Packager() string                  { return p.packager }
+func (p *mockPackage) Provides() alpm.IDependList        { return p.provides }
+func (p *mockPackage) Reason() alpm.PkgReason            { return p.reason }
+func (p *mockPackage) Origin() alpm.PkgFrom              { return alpm.FromSyncDB }
+func (p *mockPackage) Replaces() alpm.IDependList        { return p.replaces }
+func (p *mockPackage) Size() int64                       { return p.size }
+func (p *mockPackage) URL() string                       { return p.url }
+func (p *mockPackage) Version() string                   { return p.version }
+func (p *mockPackage) ComputeRequiredBy() []string       { return append([]string{}, p.requiredBy...) }
+func (p *mockPackage) ComputeOptionalFor() []string      { return append([]string{}, p.optionalFor...) }
+func (p *mockPackage) ShouldIgnore() bool                { return p.ignore }
+func (p *mockPackage) Type() string                      { return "" }
+
+func (p *mockPackage) DB() alpm.IDB {
+	if p.db == nil {
+		return nil
+	}
+	return p.db
+}
+
+func (p *mockPackage) ContainsFile(path string) (alpm.File, error) {
+	for _, f := range p.files {
+		if f.Name == path {
+			return f, nil
+		}
+	}
+	return alpm.File{}, errors.New("file not found")
+}
+
+func (p *mockPackage) SyncNewVersion(l alpm.IDBList) alpm.IPackage {
+	for _, db := range l.Slice() {
+		if pkg := db.Pkg(p.name); pkg != nil {
+			if alpm.VerCmp(pkg.Version(), p.version) > 0 {
+				return pkg
+			}
+			return nil
+		}
+	}
+	return nil
+}
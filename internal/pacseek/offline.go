@@ -0,0 +1,100 @@
+package pacseek
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// after this time we try to reach the AUR again (when we went offline automatically)
+const offlineRetryInterval = 2 * time.Minute
+
+// connectivity tells if we are offline, either because of our --offline flag or because the AUR couldn't be reached
+// while offline, only our local / sync db's are searched and AUR data comes from our disk cache
+type connectivity struct {
+	mu     sync.Mutex
+	forced bool
+	since  time.Time // when the AUR couldn't be reached, zero if it could
+	retry  time.Duration
+	now    func() time.Time
+}
+
+// creates our connectivity state, "forced" is our --offline flag
+func newConnectivity(forced bool) *connectivity {
+	return &connectivity{
+		forced: forced,
+		retry:  offlineRetryInterval,
+		now:    time.Now,
+	}
+}
+
+// checks if we are offline, a nil connectivity is always online
+func (c *connectivity) offline() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.forced || (!c.since.IsZero() && c.now().Sub(c.since) < c.retry)
+}
+
+// records a failed AUR request, returns true if we just went offline (network errors only)
+func (c *connectivity) failed(err error) bool {
+	if !isNetworkError(err) {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	wasOffline := c.forced || (!c.since.IsZero() && c.now().Sub(c.since) < c.retry)
+	c.since = c.now()
+	return !wasOffline
+}
+
+// records a successful AUR request, returns true if we have been offline before
+func (c *connectivity) succeeded() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	wasOffline := !c.since.IsZero()
+	c.since = time.Time{}
+	return wasOffline && !c.forced
+}
+
+// checks if an error means that the AUR can't be reached (rather than e.g. an error returned by the AUR)
+func isNetworkError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var ne net.Error
+	return errors.As(err, &ne)
+}
+
+// searches the AUR (with our disk cache), unless we are offline
+// while offline, or when the AUR can't be reached, cached results are returned (expired ones as well) without an error
+func (ps *UI) searchAurOffline(ctx context.Context, term string, maxResults int) ([]Package, error) {
+	if ps.connectivity.offline() {
+		packages, _ := offlineSearchAur(ps.diskCache, ps.conf.AurRpcUrl, term, ps.conf.SearchMode, ps.conf.SearchBy, maxResults)
+		return packages, nil
+	}
+
+	packages, cached, err := cachedSearchAurStale(ctx, ps.diskCache, ps.conf.AurRpcUrl, term, ps.conf.AurTimeout, ps.conf.SearchMode, ps.conf.SearchBy, maxResults)
+	if err == nil {
+		if !cached && ps.connectivity.succeeded() {
+			ps.app.QueueUpdateDraw(func() {
+				ps.displayMessage("The AUR can be reached again, leaving offline mode", false)
+			})
+		}
+		return packages, nil
+	}
+	switched := ps.connectivity.failed(err)
+	if switched {
+		ps.app.QueueUpdateDraw(func() {
+			ps.displayMessage("The AUR can't be reached, switching to offline mode (cached AUR results only)", false)
+		})
+	}
+	if cached || ps.connectivity.offline() {
+		return packages, nil
+	}
+	return packages, err
+}
@@ -1,246 +1,2867 @@
 package pacseek
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io/fs"
 	"math"
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/Jguer/go-alpm/v2"
 	pconf "github.com/Morganamilo/go-pacmanconf"
+	"github.com/lithammer/fuzzysearch/fuzzy"
 	"github.com/moson-mo/pacseek/internal/util"
 )
 
+// dbHandle is the part of the alpm handle that is needed to query the pacman databases
+type dbHandle interface {
+	SyncDBs() (alpm.IDBList, error)
+	LocalDB() (alpm.IDB, error)
+}
+
+// transactionHandle is the part of the alpm handle that is needed for (non-committing) transactions
+type transactionHandle interface {
+	dbHandle
+	TransInit(flags alpm.TransFlag) error
+	TransRelease() error
+	SyncSysupgrade(enableDowngrade bool) error
+	TransGetAdd() alpm.PackageList
+	TransGetRemove() alpm.PackageList
+}
+
 // creates the alpm handler used to search packages
-func initPacmanDbs(dbPath, confPath string, repos []string) (*alpm.Handle, error) {
-	h, err := alpm.Initialize("/", dbPath)
+// "rootPath" is the installation root (where files are installed), "dbPath" the location of the databases
+// if "skipFailing" is set, repositories that can not be registered are skipped and returned as warnings
+func initPacmanDbs(rootPath, dbPath, confPath string, repos []string, skipFailing bool) (*alpm.Handle, []string, error) {
+	start := time.Now()
+	h, err := alpm.Initialize(rootPath, dbPath)
 	if err != nil {
-		return nil, err
+		logger.log(levelError, "alpm initialization failed", "root", rootPath, "dbpath", dbPath, "error", err)
+		return nil, nil, err
+	}
+
+	conf, _, err := pconf.ParseFile(confPath)
+	if err != nil {
+		logger.log(levelError, "pacman.conf could not be parsed", "file", confPath, "error", err)
+		return nil, nil, err
+	}
+
+	names := []string{}
+	for _, repo := range conf.Repos {
+		if (len(repos) > 0 && util.SliceContains(repos, repo.Name)) || len(repos) == 0 {
+			names = append(names, repo.Name)
+		}
+	}
+	warnings, err := registerSyncDBs(h.RegisterSyncDB, names, skipFailing)
+	if err != nil {
+		logger.log(levelError, "sync db's could not be registered", "repos", strings.Join(names, ","), "error", err)
+		return nil, nil, err
+	}
+	for _, w := range warnings {
+		logger.warn(w)
+	}
+	h.SetIgnorePkgs(conf.IgnorePkg)
+	h.SetIgnoreGroups(conf.IgnoreGroup)
+
+	logger.info("alpm initialized", "root", rootPath, "dbpath", dbPath, "repos", strings.Join(names, ","), "duration", time.Since(start))
+	return h, warnings, nil
+}
+
+// ReloadHandle creates a new alpm handle and releases the old one afterwards
+// if the new handle can't be created, the old one is returned unchanged
+func ReloadHandle(old *alpm.Handle, rootPath, dbPath, confPath string, repos []string, skipFailing bool) (*alpm.Handle, []string, error) {
+	return swapHandle(old, func() (*alpm.Handle, []string, error) {
+		return initPacmanDbs(rootPath, dbPath, confPath, repos, skipFailing)
+	})
+}
+
+// releasable is anything that holds resources that need to be released (like an alpm handle)
+type releasable interface {
+	comparable
+	Release() error
+}
+
+// replaces a handle with a newly created one, the old handle is only released when the new one could be created
+func swapHandle[H releasable](old H, create func() (H, []string, error)) (H, []string, error) {
+	h, warnings, err := create()
+	if err != nil {
+		return old, nil, err
+	}
+	var none H
+	if old != none {
+		if err := old.Release(); err != nil {
+			warnings = append(warnings, "failed to release alpm handle: "+err.Error())
+		}
+	}
+	return h, warnings, nil
+}
+
+// handleCache keeps alpm handles for reuse, so that we don't need to parse pacman.conf and register our repos each time we need one
+// a handle is re-created when the sync db's or our local db have been modified (e.g. after a "pacman -Sy" or an upgrade)
+type handleCache[H releasable] struct {
+	locker  sync.Mutex
+	entries map[handleKey]cachedHandle[H]
+	create  func(rootPath, dbPath, confPath string, repos []string, skipFailing bool) (H, []string, error)
+}
+
+// handleKey identifies the configuration a handle was created with
+type handleKey struct {
+	rootPath    string
+	dbPath      string
+	confPath    string
+	repos       string
+	skipFailing bool
+}
+
+// cachedHandle is a handle and the modification time of the db's at the time it was created
+type cachedHandle[H releasable] struct {
+	handle H
+	mtime  time.Time
+}
+
+// creates a new handle cache, "create" is used to create new handles (e.g. initPacmanDbs)
+func newHandleCache[H releasable](create func(rootPath, dbPath, confPath string, repos []string, skipFailing bool) (H, []string, error)) *handleCache[H] {
+	return &handleCache[H]{
+		entries: map[handleKey]cachedHandle[H]{},
+		create:  create,
+	}
+}
+
+// returns a cached handle or creates a new one if there is none or the db's changed since it was created
+// an outdated handle is released once its replacement could be created (if that fails, it is returned with the error)
+func (c *handleCache[H]) get(rootPath, dbPath, confPath string, repos []string, skipFailing bool) (H, []string, error) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+
+	key := handleKey{rootPath, dbPath, confPath, strings.Join(repos, "\n"), skipFailing}
+	mtime := dbModTime(dbPath)
+	entry, ok := c.entries[key]
+	if ok && entry.mtime.Equal(mtime) {
+		return entry.handle, nil, nil
+	}
+
+	h, warnings, err := swapHandle(entry.handle, func() (H, []string, error) {
+		return c.create(rootPath, dbPath, confPath, repos, skipFailing)
+	})
+	if err != nil {
+		return h, nil, err
+	}
+	c.entries[key] = cachedHandle[H]{handle: h, mtime: mtime}
+	return h, warnings, nil
+}
+
+// releases all cached handles
+func (c *handleCache[H]) release() {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+
+	for key, entry := range c.entries {
+		entry.handle.Release()
+		delete(c.entries, key)
+	}
+}
+
+// returns the latest modification time of the sync db directory ("dbPath"/sync) and the files in it
+// as well as the one of our local db directory (changes when packages are installed, upgraded or removed)
+func dbModTime(dbPath string) time.Time {
+	dir := filepath.Join(dbPath, "sync")
+	latest := time.Time{}
+	for _, path := range []string{dir, filepath.Join(dbPath, "local")} {
+		if fi, err := os.Stat(path); err == nil && fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return latest
+	}
+	for _, e := range entries {
+		if fi, err := e.Info(); err == nil && fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	return latest
+}
+
+// RepoInitError is returned when a repository can't be registered
+type RepoInitError struct {
+	Repo string
+	Err  error
+}
+
+func (e *RepoInitError) Error() string {
+	return fmt.Sprintf("failed to register repo '%s': %v", e.Repo, e.Err)
+}
+
+func (e *RepoInitError) Unwrap() error {
+	return e.Err
+}
+
+// RepoInitErrors are the failures of all repositories that could not be registered
+type RepoInitErrors []*RepoInitError
+
+func (e RepoInitErrors) Error() string {
+	msgs := []string{}
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// returns the names of the failing repositories
+func (e RepoInitErrors) Repos() []string {
+	repos := []string{}
+	for _, err := range e {
+		repos = append(repos, err.Repo)
+	}
+	return repos
+}
+
+// registers a sync db for each repository
+// failing repositories are either skipped and returned as warnings or abort the registration (with a *RepoInitError)
+func registerSyncDBs(register func(string, alpm.SigLevel) (alpm.IDB, error), repos []string, skipFailing bool) ([]string, error) {
+	warnings := []string{}
+	if !skipFailing {
+		for _, repo := range repos {
+			if _, err := register(repo, 0); err != nil {
+				return warnings, &RepoInitError{Repo: repo, Err: err}
+			}
+		}
+		return warnings, nil
+	}
+
+	var errs RepoInitErrors
+	if errors.As(registerAllSyncDBs(register, repos), &errs) {
+		for _, err := range errs {
+			warnings = append(warnings, err.Error())
+		}
+	}
+	return warnings, nil
+}
+
+// registers a sync db for each repository, failing repositories don't stop the registration of the remaining ones
+// returns RepoInitErrors with all failures (nil if all repositories could be registered)
+func registerAllSyncDBs(register func(string, alpm.SigLevel) (alpm.IDB, error), repos []string) error {
+	errs := RepoInitErrors{}
+	for _, repo := range repos {
+		if _, err := register(repo, 0); err != nil {
+			errs = append(errs, &RepoInitError{Repo: repo, Err: err})
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// returns the architecture configured in a pacman config file ("auto" is resolved to the system architecture)
+func pacmanArchitecture(confPath string) (string, error) {
+	conf, _, err := pconf.ParseFile(confPath)
+	if err != nil {
+		return util.ResolveArchitecture("auto"), err
+	}
+	return configuredArchitecture(conf.Architecture), nil
+}
+
+// returns for each repository if package signatures are required (derived from the SigLevel of the repo / global SigLevel)
+func signedRepos(conf *pconf.Config) map[string]bool {
+	signed := map[string]bool{}
+	global := sigLevelRequired(conf.SigLevel, true)
+	for _, repo := range conf.Repos {
+		signed[repo.Name] = sigLevelRequired(repo.SigLevel, global)
+	}
+	return signed
+}
+
+// checks if a SigLevel requires package signatures, later values override earlier ones
+func sigLevelRequired(levels []string, def bool) bool {
+	required := def
+	for _, level := range levels {
+		for _, l := range strings.Fields(level) {
+			switch l {
+			case "Required", "PackageRequired":
+				required = true
+			case "Optional", "PackageOptional", "Never", "PackageNever":
+				required = false
+			}
+		}
+	}
+	return required
+}
+
+// searches the pacman databases and returns packages that could be found (starting with "term")
+func searchRepos(h dbHandle, term string, mode string, by string, maxResults int, opts SearchOptions) ([]Package, []Package, error) {
+	return searchReposCtx(context.Background(), h, term, mode, by, maxResults, opts)
+}
+
+// same as searchRepos, but stops searching when our context is cancelled
+// in that case, the packages found so far are returned together with the context error (e.g. context.Canceled)
+func searchReposCtx(ctx context.Context, h dbHandle, term string, mode string, by string, maxResults int, opts SearchOptions) ([]Package, []Package, error) {
+	packages := []Package{}
+	installed := []Package{}
+
+	if h == nil {
+		return packages, installed, errors.New("alpm handle is nil")
+	}
+	// keywords only exist for AUR packages, empty terms don't match anything
+	term = normalizeSearchTerm(term)
+	if by == "Keywords" || term == "" {
+		return packages, installed, nil
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return packages, installed, err
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return packages, installed, err
+	}
+
+	searchDbs := excludeDBs(append(dbs.Slice(), local), opts.ExcludeSources)
+	groupMembers := groupMemberNames(dbs, opts.Group)
+	var merger *repoMerger
+	if opts.MergeRepos {
+		merger = newRepoMerger(dbs.Slice(), opts.RepoPriority)
+	}
+
+	// snapshot of installed packages, so that we don't need to look up each result and our databases can be matched concurrently
+	installedVersions, err := installedSet(h)
+	if err != nil {
+		return packages, installed, err
+	}
+
+	// when name matches are preferred, we collect them first and fill up the remaining slots with other matches
+	passes := []string{by}
+	if opts.PreferNameMatches && by != "Name" && by != "Maintainer" {
+		passes = []string{"Name", by}
+	}
+
+	// dependency strings like "python>=3.11" or "libfoo.so=1-64" are resolved to the packages satisfying them
+	// they are matched exactly (in a single pass), so our search mode and fuzzy ranking don't apply to them
+	var match func(c *searchCandidate, pass string) string
+	var ranges func(pkg alpm.IPackage, field string) [][2]int
+	dep, isConstraint := constraintTerm(term)
+	if isConstraint = isConstraint && mode != "Regex"; isConstraint {
+		passes = []string{"Name"}
+		match = func(c *searchCandidate, _ string) string {
+			switch {
+			case !packageSatisfies(c.pkg, dep):
+				return ""
+			case c.pkg.Name() == dep.Name:
+				return "Name"
+			}
+			return "Provides"
+		}
+		ranges = func(pkg alpm.IPackage, field string) [][2]int {
+			if field == "Name" {
+				return [][2]int{{0, len(pkg.Name())}}
+			}
+			return nil
+		}
+	} else {
+		var compFunc func(string, string) bool
+		term, compFunc, err = termCompFunc(term, mode, opts)
+		if err != nil {
+			return packages, installed, err
+		}
+		match = func(c *searchCandidate, pass string) string {
+			return matchedField(c.text, term, pass, opts.IncludeProvides, compFunc)
+		}
+		ranges = func(pkg alpm.IPackage, field string) [][2]int {
+			switch field {
+			case "Name":
+				return matchRanges(pkg.Name(), term, mode, opts.SegmentPrefix)
+			case "Description":
+				return matchRanges(pkg.Description(), term, mode, opts.SegmentPrefix)
+			}
+			return nil
+		}
+	}
+
+	// fuzzy matches are ranked, so we need all of them before we can apply our limit
+	fuzzy := mode == "Fuzzy" && !isConstraint
+	limit := maxResults
+	if fuzzy {
+		limit = math.MaxInt
+	}
+
+	counter := 0
+	added := map[string]bool{}
+	for _, pass := range passes {
+		// candidates are collected on our handle (one database after another)
+		// constraints need libalpm to be checked, all other terms are matched against copies of the fields we search
+		candidates := make([][]searchCandidate, len(searchDbs))
+		for i, db := range searchDbs {
+			for j, pkg := range db.PkgCache().Slice() {
+				if j%ctxCheckInterval == 0 && ctx.Err() != nil {
+					break
+				}
+				if added[db.Name()+"/"+pkg.Name()] || !passesFilters(pkg, opts, groupMembers) || !opts.matchesPredicate(pkg, db, installedVersions) ||
+					!opts.Installed.matches(installedVersions[pkg.Name()] != "") {
+					continue
+				}
+				c := searchCandidate{pkg: pkg}
+				if isConstraint {
+					c.field = match(&c, pass)
+				} else {
+					c.text = newPkgText(pkg, pass, opts.IncludeProvides)
+				}
+				candidates[i] = append(candidates[i], c)
+			}
+		}
+
+		found := matchDatabases(ctx, candidates, true, limit, func(c *searchCandidate) bool {
+			if !isConstraint {
+				c.field = match(c, pass)
+			}
+			return c.field != ""
+		})
+		matches := make([][]Package, len(found))
+		for i, db := range searchDbs {
+			for _, c := range found[i] {
+				lastModified, hasBuildDate := buildDate(c.pkg)
+				var matched [][2]int
+				if opts.MatchRanges {
+					matched = ranges(c.pkg, c.field)
+				}
+				matches[i] = append(matches[i], Package{
+					Name:          c.pkg.Name(),
+					Source:        db.Name(),
+					IsInstalled:   installedVersions[c.pkg.Name()] != "",
+					LastModified:  lastModified,
+					HasBuildDate:  hasBuildDate,
+					InstalledSize: c.pkg.ISize(),
+					DownloadSize:  c.pkg.Size(),
+					Popularity:    repoPopularity,
+					MatchedField:  c.field,
+					SignedRepo:    opts.SignedRepos[db.Name()],
+					MatchRanges:   matched,
+				})
+			}
+		}
+
+		// with a fair quota, each database gets its share of the remaining slots first
+		if opts.FairQuota {
+			sizes := make([]int, len(matches))
+			for i := range matches {
+				sizes[i] = len(matches[i])
+			}
+			for i, share := range fairShares(sizes, limit-counter) {
+				matches[i] = matches[i][:share]
+			}
+		}
+
+		// merge results in the order of our databases
+		for i, db := range searchDbs {
+			for _, pkg := range matches[i] {
+				if counter >= limit {
+					break
+				}
+				added[db.Name()+"/"+pkg.Name] = true
+				if db == local {
+					installed = append(installed, pkg)
+				} else {
+					// merged packages don't take up a slot
+					var appended bool
+					if packages, appended = merger.add(packages, pkg); !appended {
+						continue
+					}
+				}
+
+				counter++
+			}
+		}
+		if ctx.Err() != nil {
+			return packages, installed, ctx.Err()
+		}
+	}
+
+	if fuzzy {
+		packages = rankFuzzy(packages, term, maxResults)
+		installed = rankFuzzy(installed, term, maxResults-len(packages))
+	}
+	return packages, installed, nil
+}
+
+// number of packages that are checked before we look for a cancellation of our context again
+const ctxCheckInterval = 100
+
+// distributes "limit" slots among databases with the given number of matches
+// each database gets up to limit/len(sizes) slots, remaining slots are filled up in database order
+func fairShares(sizes []int, limit int) []int {
+	shares := make([]int, len(sizes))
+	if len(sizes) == 0 || limit <= 0 {
+		return shares
+	}
+	quota := limit / len(sizes)
+	remaining := limit
+	for i, size := range sizes {
+		shares[i] = size
+		if size > quota {
+			shares[i] = quota
+		}
+		remaining -= shares[i]
+	}
+	for i, size := range sizes {
+		extra := size - shares[i]
+		if extra > remaining {
+			extra = remaining
+		}
+		shares[i] += extra
+		remaining -= extra
+	}
+	return shares
+}
+
+// a package of a search that is matched against our search term
+type searchCandidate struct {
+	pkg   alpm.IPackage
+	text  pkgText
+	field string
+}
+
+// runs a match function for the candidates of each database and returns the matching ones (up to "limit" per database, in the same order as our databases)
+// with "parallel", databases are matched concurrently (one goroutine per database), our match function must not use libalpm then
+func matchDatabases(ctx context.Context, candidates [][]searchCandidate, parallel bool, limit int, match func(c *searchCandidate) bool) [][]searchCandidate {
+	results := make([][]searchCandidate, len(candidates))
+	matchDB := func(i int) {
+		for j := range candidates[i] {
+			if len(results[i]) >= limit || (j%ctxCheckInterval == 0 && ctx.Err() != nil) {
+				break
+			}
+			if match(&candidates[i][j]) {
+				results[i] = append(results[i], candidates[i][j])
+			}
+		}
+	}
+	if !parallel {
+		for i := range candidates {
+			matchDB(i)
+		}
+		return results
+	}
+
+	var wg sync.WaitGroup
+	for i := range candidates {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			matchDB(i)
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// counts all packages matching our search term (like searchRepos but without a limit)
+// this is used to tell the user that a search is too broad
+func repoMatchCount(h dbHandle, term string, mode string, by string, opts SearchOptions) int {
+	term = normalizeSearchTerm(term)
+	if h == nil || by == "Keywords" || term == "" {
+		return 0
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return 0
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return 0
+	}
+
+	term, compFunc, err := termCompFunc(term, mode, opts)
+	if err != nil {
+		return 0
+	}
+	groupMembers := groupMemberNames(dbs, opts.Group)
+	installedVersions, err := installedSet(h)
+	if err != nil {
+		return 0
+	}
+
+	count := 0
+	for _, db := range excludeDBs(append(dbs.Slice(), local), opts.ExcludeSources) {
+		for _, pkg := range db.PkgCache().Slice() {
+			if passesFilters(pkg, opts, groupMembers) && opts.matchesPredicate(pkg, db, installedVersions) &&
+				matchedField(newPkgText(pkg, by, opts.IncludeProvides), term, by, opts.IncludeProvides, compFunc) != "" &&
+				opts.Installed.matches(installedVersions[pkg.Name()] != "") {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// removes databases that are excluded from our list
+func excludeDBs(dbs []alpm.IDB, exclude []string) []alpm.IDB {
+	ret := []alpm.IDB{}
+	for _, db := range dbs {
+		if !util.SliceContains(exclude, db.Name()) {
+			ret = append(ret, db)
+		}
+	}
+	return ret
+}
+
+// checks if a search term is a dependency string with a version constraint (e.g. "python>=3.11") and parses it
+func constraintTerm(term string) (alpm.Depend, bool) {
+	if strings.ContainsAny(term, " ") {
+		return alpm.Depend{}, false
+	}
+	dep := parseDependency(term)
+	return dep, dep.Mod != alpm.DepModAny && dep.Name != "" && dep.Version != ""
+}
+
+// removes leading / trailing whitespace from a search term and collapses whitespace between words
+func normalizeSearchTerm(term string) string {
+	return strings.Join(strings.Fields(term), " ")
+}
+
+// returns the compare function for a search mode
+// with "segments", StartsWith matches the beginning of each segment as well (e.g. "lfs" matches "git-lfs")
+func searchCompFunc(mode string, segments bool) func(string, string) bool {
+	if mode == "Contains" {
+		return strings.Contains
+	}
+	if mode == "Fuzzy" {
+		return func(s, term string) bool {
+			return fuzzyScore(term, s) != -1
+		}
+	}
+	if segments {
+		return segmentHasPrefix
+	}
+	return strings.HasPrefix
+}
+
+// returns the compare function for a search and the term it needs to be called with (lowercased when ignoring case)
+// regular expressions are compiled once, their compare function ignores the term
+func termCompFunc(term, mode string, opts SearchOptions) (string, func(string, string) bool, error) {
+	if mode == "Regex" {
+		re, err := compileSearchRegex(term, opts.CaseInsensitive)
+		if err != nil {
+			return term, nil, err
+		}
+		return term, func(s, _ string) bool {
+			return re.MatchString(s)
+		}, nil
+	}
+	compFunc := searchCompFunc(mode, opts.SegmentPrefix)
+	if opts.CaseInsensitive {
+		return strings.ToLower(term), ignoreCase(compFunc), nil
+	}
+	return term, compFunc, nil
+}
+
+// wraps a compare function so that the compared string is lowercased (the term needs to be lowercased already)
+// strings.ToLower doesn't allocate for strings that are lowercase already (e.g. most package names)
+func ignoreCase(compFunc func(string, string) bool) func(string, string) bool {
+	return func(s, term string) bool {
+		return compFunc(strings.ToLower(s), term)
+	}
+}
+
+// rates how well a search term matches a string (lower is better), -1 means no match
+// a term matches if it is contained in the string, is a subsequence (with a few characters missing)
+// or if it has only got a few typos (levenshtein distance)
+func fuzzyScore(term, s string) int {
+	if rank := fuzzy.RankMatch(term, s); rank != -1 && (rank <= len(term) || strings.Contains(s, term)) {
+		return rank
+	}
+	maxTypos := len(term) / 3
+	if maxTypos < 1 {
+		maxTypos = 1
+	}
+	if d := fuzzy.LevenshteinDistance(term, s); d <= maxTypos {
+		return d
+	}
+	return -1
+}
+
+// scores fuzzy matches and returns the best "max" ones (best match first)
+// packages that only matched by their description are put last
+// for multiple words, the scores of each word are summed up
+func rankFuzzy(pkgs []Package, term string, max int) []Package {
+	tokens := strings.Fields(term)
+	for i := range pkgs {
+		pkgs[i].Score = 0
+		for _, token := range tokens {
+			score := fuzzyScore(token, pkgs[i].Name)
+			if score == -1 {
+				pkgs[i].Score = math.MaxInt16
+				break
+			}
+			pkgs[i].Score += score
+		}
+	}
+	sortPackages(pkgs, SortSpec{Keys: []SortKey{SortByScore}})
+	if max < 0 {
+		max = 0
+	}
+	if len(pkgs) > max {
+		pkgs = pkgs[:max]
+	}
+	return pkgs
+}
+
+// checks if a string or one of its segments (separated by "-" or ".") begins with prefix
+func segmentHasPrefix(s, prefix string) bool {
+	if strings.HasPrefix(s, prefix) {
+		return true
+	}
+	for _, segment := range strings.FieldsFunc(s, func(r rune) bool { return r == '-' || r == '.' }) {
+		if strings.HasPrefix(segment, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the names of the packages that belong to a group (empty if no group is given)
+func groupMemberNames(dbs alpm.IDBList, group string) map[string]bool {
+	members := map[string]bool{}
+	if group != "" {
+		for _, pkg := range dbs.FindGroupPkgs(group).Slice() {
+			members[pkg.Name()] = true
+		}
+	}
+	return members
+}
+
+// returns the members of a package group from all sync db's (like "pacman -Sg group")
+func searchGroups(h dbHandle, group string) ([]Package, error) {
+	packages := []Package{}
+
+	if h == nil {
+		return packages, errors.New("alpm handle is nil")
+	}
+	if group == "" {
+		return packages, nil
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return packages, err
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return packages, err
+	}
+
+	for _, pkg := range dbs.FindGroupPkgs(group).Slice() {
+		source := ""
+		if db := pkg.DB(); db != nil {
+			source = db.Name()
+		}
+		lastModified, hasBuildDate := buildDate(pkg)
+		packages = append(packages, Package{
+			Name:          pkg.Name(),
+			Source:        source,
+			IsInstalled:   local.Pkg(pkg.Name()) != nil,
+			LastModified:  lastModified,
+			HasBuildDate:  hasBuildDate,
+			InstalledSize: pkg.ISize(),
+			DownloadSize:  pkg.Size(),
+			Popularity:    repoPopularity,
+		})
+	}
+	return packages, nil
+}
+
+// looks up a package by its exact name in our sync db's, "repo/name" restricts the lookup to a repository
+// packages that are not part of any repository are AUR packages
+func lookupPackage(h dbHandle, name string) (Package, error) {
+	repo, pkgName, found := strings.Cut(name, "/")
+	if !found {
+		repo, pkgName = "", name
+	}
+	pkg := Package{Name: pkgName, Source: "AUR"}
+	if h == nil {
+		return pkg, errors.New("alpm handle is nil")
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return pkg, err
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return pkg, err
+	}
+
+	pkg.IsInstalled = local.Pkg(pkgName) != nil
+	for _, db := range dbs.Slice() {
+		if repo != "" && db.Name() != repo {
+			continue
+		}
+		if p := db.Pkg(pkgName); p != nil {
+			lastModified, hasBuildDate := buildDate(p)
+			pkg.Source = db.Name()
+			pkg.LastModified, pkg.HasBuildDate = lastModified, hasBuildDate
+			pkg.InstalledSize, pkg.DownloadSize = p.ISize(), p.Size()
+			pkg.Popularity = repoPopularity
+			return pkg, nil
+		}
+	}
+	if repo != "" && !strings.EqualFold(repo, "aur") {
+		return pkg, fmt.Errorf("package '%s' not found in repository '%s'", pkgName, repo)
+	}
+	return pkg, nil
+}
+
+// returns the (sorted) names of all package groups in our sync db's (like "pacman -Sg")
+func listGroups(h dbHandle) ([]string, error) {
+	groups := []string{}
+
+	if h == nil {
+		return groups, errors.New("alpm handle is nil")
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return groups, err
+	}
+
+	seen := map[string]bool{}
+	for _, db := range dbs.Slice() {
+		for _, pkg := range db.PkgCache().Slice() {
+			for _, group := range pkg.Groups().Slice() {
+				if !seen[group] {
+					seen[group] = true
+					groups = append(groups, group)
+				}
+			}
+		}
+	}
+	sort.Strings(groups)
+	return groups, nil
+}
+
+// packageGroup is a package group with the number of its members and how many of them are installed
+type packageGroup struct {
+	Name      string
+	Members   int
+	Installed int
+}
+
+// returns the number of members / installed members for a list of groups
+func summarizeGroups(h dbHandle, groups []string) ([]packageGroup, error) {
+	summaries := []packageGroup{}
+	for _, group := range groups {
+		members, err := searchGroups(h, group)
+		if err != nil {
+			return summaries, err
+		}
+		g := packageGroup{Name: group, Members: len(members)}
+		for _, m := range members {
+			if m.IsInstalled {
+				g.Installed++
+			}
+		}
+		summaries = append(summaries, g)
+	}
+	return summaries, nil
+}
+
+// checks the group, architecture and optional dependency restrictions of our search options
+func passesFilters(pkg alpm.IPackage, opts SearchOptions, groupMembers map[string]bool) bool {
+	if opts.Group != "" && !groupMembers[pkg.Name()] {
+		return false
+	}
+	if len(opts.Arches) > 0 && !util.SliceContains(opts.Arches, pkg.Architecture()) {
+		return false
+	}
+	if opts.HasOptDepends && len(pkg.OptionalDepends().Slice()) == 0 {
+		return false
+	}
+	if len(opts.Licenses) > 0 && !licenseMatches(pkg.Licenses().Slice(), opts.Licenses) {
+		return false
+	}
+	return true
+}
+
+// checks if a package of a database matches our predicate (see searchQuery)
+func (opts SearchOptions) matchesPredicate(pkg alpm.IPackage, db alpm.IDB, installed map[string]string) bool {
+	if opts.Predicate == nil {
+		return true
+	}
+	return opts.Predicate(packageCandidate{Name: pkg.Name(), Description: pkg.Description(), Source: db.Name(), Installed: installed[pkg.Name()] != ""})
+}
+
+// searches the sync db's for packages with the given name and a version matching the constraint
+// "op" is one of <, <=, =, >=, >
+func searchByVersion(h dbHandle, name, op, version string) ([]Package, error) {
+	packages := []Package{}
+
+	ops := map[string]alpm.DepMod{
+		"<":  alpm.DepModLT,
+		"<=": alpm.DepModLE,
+		"=":  alpm.DepModEq,
+		">=": alpm.DepModGE,
+		">":  alpm.DepModGT,
+	}
+	mod, ok := ops[op]
+	if !ok {
+		return packages, fmt.Errorf("invalid version operator '%s'", op)
+	}
+	if h == nil {
+		return packages, errors.New("alpm handle is nil")
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return packages, err
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return packages, err
+	}
+
+	dep := alpm.Depend{Name: name, Version: version, Mod: mod}
+	for _, db := range dbs.Slice() {
+		pkg := db.Pkg(name)
+		if pkg == nil || !versionSatisfies(pkg.Version(), dep) {
+			continue
+		}
+		lastModified, hasBuildDate := buildDate(pkg)
+		packages = append(packages, Package{
+			Name:          pkg.Name(),
+			Source:        db.Name(),
+			IsInstalled:   local.Pkg(pkg.Name()) != nil,
+			LastModified:  lastModified,
+			HasBuildDate:  hasBuildDate,
+			InstalledSize: pkg.ISize(),
+			DownloadSize:  pkg.Size(),
+			Popularity:    repoPopularity,
+		})
+	}
+
+	return packages, nil
+}
+
+// returns the field of a package that matches our search term or an empty string if there is no match
+// terms consisting of multiple words only match if each of the words matches (the weakest matching field is returned)
+// maintainer names are matched as a whole
+func matchedField(pkg pkgText, term, by string, provides bool, compFunc func(string, string) bool) string {
+	tokens := strings.Fields(term)
+	if len(tokens) <= 1 || by == "Maintainer" {
+		return matchedTokenField(pkg, term, by, provides, compFunc)
+	}
+	weakest := ""
+	for _, token := range tokens {
+		field := matchedTokenField(pkg, token, by, provides, compFunc)
+		if field == "" {
+			return ""
+		}
+		if fieldRank[field] > fieldRank[weakest] {
+			weakest = field
+		}
+	}
+	return weakest
+}
+
+// ranks the fields a search term can match (higher means a weaker match)
+var fieldRank = map[string]int{
+	"Name":        1,
+	"Maintainer":  1,
+	"Provides":    2,
+	"Description": 3,
+}
+
+// returns the field of a package that matches a single word of our search term
+// "Broad" checks the name, provides and description (in that order), "Provides" the name and provides, "Maintainer" only the packager
+// with "provides", the names of provided packages are checked for any other mode as well
+func matchedTokenField(pkg pkgText, term, by string, provides bool, compFunc func(string, string) bool) string {
+	if by == "Maintainer" {
+		if maintainerMatches(pkg.packager, term, compFunc) {
+			return "Maintainer"
+		}
+		return ""
+	}
+	if compFunc(pkg.name, term) {
+		return "Name"
+	}
+	if by == "Broad" || by == "Provides" || provides {
+		for _, prov := range pkg.provides {
+			if compFunc(prov, term) {
+				return "Provides"
+			}
+		}
+	}
+	if (by == "Name & Description" || by == "Broad") && compFunc(strings.ToLower(pkg.description), term) {
+		return "Description"
+	}
+	return ""
+}
+
+// the fields of a package we match search terms against (copied out of libalpm)
+type pkgText struct {
+	name        string
+	description string
+	packager    string
+	provides    []string
+}
+
+// copies the fields of a package that matchedField checks for "by" / "provides"
+func newPkgText(pkg alpm.IPackage, by string, provides bool) pkgText {
+	t := pkgText{name: pkg.Name()}
+	switch by {
+	case "Maintainer":
+		t.packager = pkg.Packager()
+	case "Name & Description", "Broad":
+		t.description = pkg.Description()
+	}
+	if by == "Broad" || by == "Provides" || provides {
+		for _, prov := range pkg.Provides().Slice() {
+			t.provides = append(t.provides, prov.Name)
+		}
+	}
+	return t
+}
+
+// checks if the name or e-mail address of a packager ("Name <email>") matches our search term
+func maintainerMatches(packager, term string, compFunc func(string, string) bool) bool {
+	packager = strings.ToLower(packager)
+	name, email, _ := strings.Cut(packager, "<")
+	return compFunc(strings.TrimSpace(name), term) || compFunc(strings.TrimSuffix(email, ">"), term) || compFunc(packager, term)
+}
+
+func suggestRepos(h dbHandle, term string) []string {
+	pkgs, _, _ := searchRepos(h, term, "", "", 20, SearchOptions{})
+
+	names := []string{}
+	for _, pkg := range pkgs {
+		names = append(names, pkg.Name)
+	}
+
+	return names
+}
+
+// returns the command (and arguments) for syncing to our temporary db
+// fakeroot is preferred, without it we try to let pacman sync directly (our temp db is owned by the user anyway)
+func tempDBSyncCommand(rootPath, confPath, tmpdb string, hasFakeroot bool) []string {
+	args := []string{"pacman", "-Sy", "--root=" + rootPath, "--config=" + confPath, "--dbpath=" + tmpdb}
+	if hasFakeroot {
+		return append([]string{"fakeroot", "--"}, args...)
+	}
+	return args
+}
+
+// create/update temporary sync DB, the local db of "dbPath" is linked into it
+func syncToTempDB(rootPath, dbPath, confPath string, repos []string, skipFailing bool) (*alpm.Handle, error) {
+	/*
+		We use the same naming as "checkupdates" to have less data to transfer
+		in case the user already makes use of checkupdates...
+	*/
+	tmpdb := os.TempDir() + "/checkup-db-" + strconv.Itoa(os.Getuid())
+	local := tmpdb + "/local"
+
+	// create directory and symlink if needed
+	if _, err := os.Stat(tmpdb); errors.Is(err, fs.ErrNotExist) {
+		err := os.MkdirAll(tmpdb, 0755)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, err := os.Stat(local); errors.Is(err, fs.ErrNotExist) {
+		err := os.Symlink(path.Join(dbPath, "local"), local)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// execute pacman and sync to temporary db
+	_, err := os.Stat("/usr/bin/fakeroot")
+	hasFakeroot := !errors.Is(err, fs.ErrNotExist)
+	args := tempDBSyncCommand(rootPath, confPath, tmpdb, hasFakeroot)
+	cmd := exec.Command(args[0], args[1:]...)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if !hasFakeroot {
+			return nil, errors.New("fakeroot not installed and syncing without it failed: " + string(out))
+		}
+		return nil, errors.New(string(out))
+	}
+
+	h, _, err := initPacmanDbs(rootPath, tmpdb, confPath, repos, skipFailing)
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// UpgradeOptions are the things getUpgradable determines in addition to the upgrades
+// ComputeRequiredBy: the packages requiring an upgraded package
+// ComputeSizes: the download size and installed size delta of each upgrade
+// IncludeReplaced: installed packages that will be replaced by a repo package
+type UpgradeOptions struct {
+	ComputeRequiredBy bool
+	ComputeSizes      bool
+	IncludeReplaced   bool
+}
+
+// returns packages that can be upgraded & packages that only exist locally
+// upgrades of packages matching our ignore rules are marked as ignored
+func getUpgradable(h dbHandle, opts UpgradeOptions, ignore IgnoreRules) ([]Upgrade, []Package) {
+	upgradable := []string{}
+	notFound := []Package{}
+	details := map[string]Upgrade{}
+
+	if h == nil {
+		return []Upgrade{}, notFound
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return []Upgrade{}, notFound
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return []Upgrade{}, notFound
+	}
+
+	replaced := map[string]string{}
+	if opts.IncludeReplaced {
+		replaced = replacedPackages(dbs, local)
+	}
+
+	for _, lpkg := range local.PkgCache().Slice() {
+		if _, ok := replaced[lpkg.Name()]; ok {
+			continue
+		}
+		found := false
+		for _, db := range dbs.Slice() {
+			pkg := db.Pkg(lpkg.Name())
+			if pkg != nil {
+				found = true
+				if alpm.VerCmp(pkg.Version(), lpkg.Version()) > 0 {
+					upgradable = append(upgradable, pkg.Name())
+					up := Upgrade{
+						EpochBump:  versionEpoch(pkg.Version()) > versionEpoch(lpkg.Version()),
+						WasForeign: installedFromFile(lpkg),
+						Kind:       upgradeKind(lpkg.Version(), pkg.Version()),
+					}
+					if opts.ComputeSizes {
+						up.DownloadSize = pkg.Size()
+						up.InstalledSizeDelta = pkg.ISize() - lpkg.ISize()
+					}
+					details[pkg.Name()] = up
+				}
+				break
+			}
+		}
+		if !found {
+			upgradable = append(upgradable, lpkg.Name())
+			lastModified, hasBuildDate := buildDate(lpkg)
+			notFound = append(notFound, Package{
+				Name:          lpkg.Name(),
+				Source:        "local",
+				IsInstalled:   true,
+				LastModified:  lastModified,
+				HasBuildDate:  hasBuildDate,
+				InstalledSize: lpkg.ISize(),
+				Popularity:    repoPopularity,
+			})
+		}
+	}
+	sort.Slice(notFound, func(i, j int) bool {
+		return notFound[i].Name < notFound[j].Name
+	})
+
+	upgrades := []Upgrade{}
+	for _, info := range infoPacman(h, opts.ComputeRequiredBy, upgradable...).Results {
+		up := details[info.Name]
+		up.InfoRecord = info
+		up.IsIgnored = up.IsIgnored || ignore.matches(dbs, info.Name)
+		up.Status = "upgrade"
+		upgrades = append(upgrades, up)
+	}
+
+	for _, lpkg := range local.PkgCache().Slice() {
+		if by, ok := replaced[lpkg.Name()]; ok {
+			upgrades = append(upgrades, Upgrade{
+				InfoRecord: packageInfo(lpkg, "local", local, opts.ComputeRequiredBy),
+				Status:     "replaced",
+				ReplacedBy: by,
+			})
+		}
+	}
+
+	return upgrades, notFound
+}
+
+// returns installed packages that have been installed from a package file (e.g. AUR) but now exist in a repository
+// (installed package -> repository), the user might want to switch to the repository version
+func adoptedPackages(h dbHandle) (map[string]string, error) {
+	adopted := map[string]string{}
+
+	if h == nil {
+		return adopted, errors.New("alpm handle is nil")
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return adopted, err
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return adopted, err
+	}
+
+	for _, lpkg := range local.PkgCache().Slice() {
+		if !installedFromFile(lpkg) {
+			continue
+		}
+		for _, db := range dbs.Slice() {
+			if db.Pkg(lpkg.Name()) != nil {
+				adopted[lpkg.Name()] = db.Name()
+				break
+			}
+		}
+	}
+
+	return adopted, nil
+}
+
+// checks if an installed package has been installed from a package file rather than a repository
+// packages from repositories are validated with checksums / signatures from the sync db
+func installedFromFile(lpkg alpm.IPackage) bool {
+	return lpkg.Validation() == alpm.ValidationNone
+}
+
+// returns the space used on disk by the files of an installed package (0 if it isn't installed)
+// hard links are counted once, files that don't exist (anymore) are skipped
+func packageDiskSize(h dbHandle, root, name string) (int64, error) {
+	if h == nil {
+		return 0, errors.New("alpm handle is nil")
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return 0, err
+	}
+	lpkg := local.Pkg(name)
+	if lpkg == nil {
+		return 0, nil
+	}
+
+	total := int64(0)
+	seen := map[[2]uint64]bool{}
+	for _, f := range lpkg.Files() {
+		fi, err := os.Lstat(filepath.Join(root, f.Name))
+		if err != nil || fi.IsDir() {
+			continue
+		}
+		st, ok := fi.Sys().(*syscall.Stat_t)
+		if !ok {
+			total += fi.Size()
+			continue
+		}
+		id := [2]uint64{uint64(st.Dev), st.Ino}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		total += st.Blocks * 512
+	}
+	return total, nil
+}
+
+// returns the epoch of a version string like "1:2.0-1" (0 if there is none)
+func versionEpoch(version string) int {
+	epoch, _, _ := splitVersion(version)
+	return epoch
+}
+
+// splits a version string ([epoch:]pkgver[-pkgrel]) into its components
+// a missing or invalid epoch is 0, a missing pkgrel is empty
+func splitVersion(version string) (int, string, string) {
+	epoch := 0
+	if e, rest, found := strings.Cut(version, ":"); found {
+		if n, err := strconv.Atoi(e); err == nil {
+			epoch = n
+		}
+		version = rest
+	}
+	pkgver, pkgrel := version, ""
+	if i := strings.LastIndex(version, "-"); i != -1 {
+		pkgver, pkgrel = version[:i], version[i+1:]
+	}
+	return epoch, pkgver, pkgrel
+}
+
+// classifies an upgrade by the version component that changed: "epoch", "version" or "pkgrel"
+func upgradeKind(oldVersion, newVersion string) string {
+	oldEpoch, oldVer, _ := splitVersion(oldVersion)
+	newEpoch, newVer, _ := splitVersion(newVersion)
+	switch {
+	case oldEpoch != newEpoch:
+		return "epoch"
+	case alpm.VerCmp(oldVer, newVer) != 0:
+		return "version"
+	}
+	return "pkgrel"
+}
+
+// returns the names of a list of packages
+func packageNames(pkgs []Package) []string {
+	names := []string{}
+	for _, pkg := range pkgs {
+		names = append(names, pkg.Name)
+	}
+	return names
+}
+
+// computes the upgrades of a system upgrade with a transaction which is released without being committed
+// unlike getUpgradable, this honors alpm's upgrade logic (IgnorePkg, replacements and so on)
+// our alpm bindings do not expose the prepare step, so dependencies of the upgraded packages are not resolved
+func sysUpgradePreview(h transactionHandle) ([]Upgrade, error) {
+	upgrades := []Upgrade{}
+
+	if h == nil {
+		return upgrades, errors.New("alpm handle is nil")
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return upgrades, err
+	}
+
+	// we don't modify anything, so there's no need to lock the database
+	if err := h.TransInit(alpm.TransFlagNoLock); err != nil {
+		return upgrades, fmt.Errorf("failed to initialize transaction: %w", err)
+	}
+	defer h.TransRelease()
+
+	if err := h.SyncSysupgrade(false); err != nil {
+		return upgrades, fmt.Errorf("failed to compute system upgrade: %w", err)
+	}
+
+	add := h.TransGetAdd().Slice()
+	for _, pkg := range add {
+		source := "local"
+		if pkg.DB() != nil {
+			source = pkg.DB().Name()
+		}
+		up := Upgrade{
+			InfoRecord:   packageInfo(pkg, source, local, false),
+			Status:       "upgrade",
+			DownloadSize: pkg.Size(),
+		}
+		up.InstalledSizeDelta = pkg.ISize()
+		if lpkg := local.Pkg(pkg.Name()); lpkg != nil {
+			up.InstalledSizeDelta -= lpkg.ISize()
+			up.EpochBump = versionEpoch(pkg.Version()) > versionEpoch(lpkg.Version())
+		}
+		upgrades = append(upgrades, up)
+	}
+
+	// packages that are removed are being replaced by one of the added ones
+	for _, lpkg := range h.TransGetRemove().Slice() {
+		up := Upgrade{
+			InfoRecord: packageInfo(lpkg, "local", local, false),
+			Status:     "replaced",
+		}
+		for _, pkg := range add {
+			for _, r := range pkg.Replaces().Slice() {
+				if r.Name == lpkg.Name() {
+					up.ReplacedBy = pkg.Name()
+				}
+			}
+		}
+		upgrades = append(upgrades, up)
+	}
+
+	return upgrades, nil
+}
+
+// computes the total download size and installed size change of a list of upgrades (computed with sizes)
+// packages that only exist locally, ignored packages and packages that are being replaced are not taken into account
+func summarizeUpgrades(upgrades []Upgrade) UpgradeSummary {
+	s := UpgradeSummary{}
+	for _, up := range upgrades {
+		if up.Source == "local" || up.Status != "upgrade" || up.IsIgnored {
+			continue
+		}
+		s.Count++
+		s.DownloadSize += up.DownloadSize
+		s.InstalledSize += up.InstalledSize
+		s.InstalledSizeDelta += up.InstalledSizeDelta
+	}
+	return s
+}
+
+// returns the totals of an upgrade summary in a human readable format, e.g. "Download: 412.00 MiB, Net: +58.00 MiB"
+func (s UpgradeSummary) String() string {
+	return fmt.Sprintf("Download: %s, Net: %s", util.FormatSize(s.DownloadSize), formatSizeDelta(s.InstalledSizeDelta))
+}
+
+// formats a size change with its sign, e.g. "+58.00 MiB"
+func formatSizeDelta(delta int64) string {
+	if delta >= 0 {
+		return "+" + util.FormatSize(delta)
+	}
+	return util.FormatSize(delta)
+}
+
+// same as getUpgradable (with sizes), but upgrades are sorted by their download size (smallest first unless "descending")
+func getUpgradableBySize(h dbHandle, descending bool, ignore IgnoreRules) ([]Upgrade, []Package) {
+	up, nf := getUpgradable(h, UpgradeOptions{ComputeSizes: true}, ignore)
+	sort.SliceStable(up, func(i, j int) bool {
+		if descending {
+			return up[i].DownloadSize > up[j].DownloadSize
+		}
+		return up[i].DownloadSize < up[j].DownloadSize
+	})
+	return up, nf
+}
+
+// returns the IgnorePkg and IgnoreGroup rules of a pacman config file
+func pacmanIgnoreRules(confPath string) (IgnoreRules, error) {
+	conf, _, err := pconf.ParseFile(confPath)
+	if err != nil {
+		return IgnoreRules{}, err
+	}
+	return IgnoreRules{Packages: conf.IgnorePkg, Groups: conf.IgnoreGroup}, nil
+}
+
+// returns the cache directories (CacheDir) configured in pacman.conf
+func pacmanCacheDirs(confPath string) ([]string, error) {
+	conf, _, err := pconf.ParseFile(confPath)
+	if err != nil {
+		return []string{}, err
+	}
+	return conf.CacheDir, nil
+}
+
+// returns the cache directories of pacman.conf (or pacman's default one) and our additional ones ("extra", separated by semicolons)
+func packageCacheDirs(confPath, extra string) []string {
+	dirs, err := pacmanCacheDirs(confPath)
+	if err != nil || len(dirs) == 0 {
+		dirs = []string{"/var/cache/pacman/pkg/"}
+	}
+	for _, dir := range strings.Split(extra, ";") {
+		if dir = strings.TrimSpace(dir); dir != "" && !util.SliceContains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// checks if a package is ignored by name (glob patterns like "linux*" are supported) or by one of its groups
+func (r IgnoreRules) matches(dbs alpm.IDBList, name string) bool {
+	for _, pattern := range r.Packages {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	for _, group := range r.Groups {
+		for _, pkg := range dbs.FindGroupPkgs(group).Slice() {
+			if pkg.Name() == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// returns installed packages that are replaced by a repo package (installed package -> replacing package)
+// only the package name of a "replaces" entry is being compared
+func replacedPackages(dbs alpm.IDBList, local alpm.IDB) map[string]string {
+	replaced := map[string]string{}
+	for _, db := range dbs.Slice() {
+		for _, pkg := range db.PkgCache().Slice() {
+			for _, r := range pkg.Replaces().Slice() {
+				if r.Name == pkg.Name() || local.Pkg(r.Name) == nil {
+					continue
+				}
+				if _, ok := replaced[r.Name]; !ok {
+					replaced[r.Name] = pkg.Name()
+				}
+			}
+		}
+	}
+	return replaced
+}
+
+// returns the successor of an installed package that does not exist in the repositories anymore
+// a repo package that replaces the installed one is its successor (renamed / merged),
+// ones that provide it as well are preferred. If there is no successor, the package has been dropped
+func packageSuccessor(dbs alpm.IDBList, name string) string {
+	for _, db := range dbs.Slice() {
+		if db.Pkg(name) != nil {
+			return ""
+		}
+	}
+
+	successor := ""
+	for _, db := range dbs.Slice() {
+		for _, pkg := range db.PkgCache().Slice() {
+			if !dependListContains(pkg.Replaces(), name) {
+				continue
+			}
+			if dependListContains(pkg.Provides(), name) {
+				return pkg.Name()
+			}
+			if successor == "" {
+				successor = pkg.Name()
+			}
+		}
+	}
+	return successor
+}
+
+// checks if a list of dependencies contains an entry for a package name (version constraints are not compared)
+func dependListContains(l alpm.IDependList, name string) bool {
+	for _, d := range l.Slice() {
+		if d.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// returns packages that can be upgraded & packages that only exist locally
+func getInstalled(h dbHandle, computeRequiredBy bool) ([]InfoRecord, []string) {
+	installed := []string{}
+	notFound := []string{}
+
+	if h == nil {
+		return []InfoRecord{}, notFound
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return []InfoRecord{}, notFound
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return []InfoRecord{}, notFound
+	}
+
+	for _, lpkg := range local.PkgCache().Slice() {
+		found := false
+		for _, db := range dbs.Slice() {
+			if pkg := db.Pkg(lpkg.Name()); pkg != nil {
+				found = true
+				installed = append(installed, pkg.Name())
+				break
+			}
+		}
+		if !found {
+			installed = append(installed, lpkg.Name())
+			notFound = append(notFound, lpkg.Name())
+		}
+	}
+
+	results := infoPacman(h, computeRequiredBy, installed...).Results
+	addLocalSatisfiers(h, results...)
+	return results, notFound
+}
+
+// returns the (sorted) names of all explicitly installed packages, e.g. for "pacman -S --needed -"
+func exportInstalledList(h dbHandle) ([]string, error) {
+	explicit := []string{}
+
+	if h == nil {
+		return explicit, errors.New("alpm handle is nil")
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return explicit, err
+	}
+
+	for _, pkg := range local.PkgCache().Slice() {
+		if pkg.Reason() == alpm.PkgReasonExplicit {
+			explicit = append(explicit, pkg.Name())
+		}
+	}
+	sort.Strings(explicit)
+
+	return explicit, nil
+}
+
+// compares two package lists (see exportInstalledList), e.g. from different systems
+// returns the (sorted) packages that are only in "a", only in "b" and in both lists
+func diffInstalledSets(a, b []string) ([]string, []string, []string) {
+	onlyA := []string{}
+	onlyB := []string{}
+	common := []string{}
+
+	inA := map[string]bool{}
+	inB := map[string]bool{}
+	for _, pkg := range a {
+		inA[pkg] = true
+	}
+	for _, pkg := range b {
+		inB[pkg] = true
+	}
+
+	for pkg := range inA {
+		if inB[pkg] {
+			common = append(common, pkg)
+		} else {
+			onlyA = append(onlyA, pkg)
+		}
+	}
+	for pkg := range inB {
+		if !inA[pkg] {
+			onlyB = append(onlyB, pkg)
+		}
+	}
+	sort.Strings(onlyA)
+	sort.Strings(onlyB)
+	sort.Strings(common)
+
+	return onlyA, onlyB, common
+}
+
+// creates the commands to restore a list of packages (see exportInstalledList)
+// packages from the sync db's are installed with "repoCommand", foreign ones with "foreignCommand"
+func restoreCommands(h dbHandle, pkgs []string, repoCommand, foreignCommand string) ([]string, error) {
+	commands := []string{}
+
+	if h == nil {
+		return commands, errors.New("alpm handle is nil")
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return commands, err
+	}
+
+	repo := []string{}
+	foreign := []string{}
+	for _, pkg := range pkgs {
+		found := false
+		for _, db := range dbs.Slice() {
+			if db.Pkg(pkg) != nil {
+				found = true
+				break
+			}
+		}
+		if found {
+			repo = append(repo, pkg)
+		} else {
+			foreign = append(foreign, pkg)
+		}
+	}
+
+	for _, c := range []struct {
+		command string
+		pkgs    []string
+	}{{repoCommand, repo}, {foreignCommand, foreign}} {
+		if len(c.pkgs) == 0 {
+			continue
+		}
+		// if our command contains {pkg}, replace it with the package names, otherwise concat them
+		if strings.Contains(c.command, "{pkg}") {
+			commands = append(commands, strings.Replace(c.command, "{pkg}", strings.Join(c.pkgs, " "), -1))
+		} else {
+			commands = append(commands, c.command+" "+strings.Join(c.pkgs, " "))
+		}
+	}
+
+	return commands, nil
+}
+
+// checks the local db if a package is installed
+func isPackageInstalled(h dbHandle, pkg string) bool {
+	installed, err := installedSet(h)
+	if err != nil {
+		return false
+	}
+
+	_, found := installed[pkg]
+	return found
+}
+
+// returns the names and versions of all installed packages (a single pass over the local db)
+func installedSet(h dbHandle) (map[string]string, error) {
+	installed := map[string]string{}
+
+	if h == nil {
+		return installed, errors.New("alpm handle is nil")
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return installed, err
+	}
+	for _, pkg := range local.PkgCache().Slice() {
+		installed[pkg.Name()] = pkg.Version()
+	}
+
+	return installed, nil
+}
+
+// returns files that are owned by more than one installed package (file -> owning packages)
+// this walks through the file lists of all installed packages and is therefore rather expensive.
+// "prefix" can be used to restrict the check to a certain path, e.g. "/usr/bin/"
+func duplicateOwnedFiles(h dbHandle, prefix string) (map[string][]string, error) {
+	duplicates := map[string][]string{}
+
+	if h == nil {
+		return duplicates, errors.New("alpm handle is nil")
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return duplicates, err
+	}
+
+	// paths in the file lists are relative to the root directory
+	prefix = strings.TrimPrefix(prefix, "/")
+
+	owners := map[string][]string{}
+	for _, pkg := range local.PkgCache().Slice() {
+		for _, file := range pkg.Files() {
+			// directories are usually shared between packages
+			if strings.HasSuffix(file.Name, "/") || !strings.HasPrefix(file.Name, prefix) {
+				continue
+			}
+			owners[file.Name] = append(owners[file.Name], pkg.Name())
+		}
+	}
+
+	for file, pkgs := range owners {
+		if len(pkgs) > 1 {
+			duplicates["/"+file] = pkgs
+		}
+	}
+
+	return duplicates, nil
+}
+
+// errFilesDBNotSynced is returned when the file list of a package can't be retrieved because "pacman -Fy" was never run
+var errFilesDBNotSynced = errors.New("files database not synced, run pacman -Fy")
+
+// returns the files of a package (relative to the installation root), like "pacman -Ql" / "pacman -Fl"
+// installed packages are looked up in the local db, others in the files db ("dbPath"/sync/"repo".files) of their repository
+func packageFiles(h dbHandle, rootPath, dbPath, name string) ([]string, error) {
+	files := []string{}
+
+	if h == nil {
+		return files, errors.New("alpm handle is nil")
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return files, err
+	}
+	if pkg := local.Pkg(name); pkg != nil {
+		for _, f := range pkg.Files() {
+			files = append(files, f.Name)
+		}
+		return files, nil
+	}
+
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return files, err
+	}
+	pkg := findSyncPackage(dbs, name)
+	if pkg == nil || pkg.DB() == nil {
+		return files, fmt.Errorf("package '%s' not found", name)
+	}
+	repo := pkg.DB().Name()
+	fh, fdbs, err := filesHandle(rootPath, dbPath, []string{repo})
+	if err != nil {
+		return files, fmt.Errorf("no file list for '%s': %w", name, err)
+	}
+	defer fh.Release()
+	fpkg := fdbs[0].Pkg(name)
+	if fpkg == nil {
+		return files, fmt.Errorf("package '%s' not found in files db '%s'", name, repo)
+	}
+	for _, f := range fpkg.Files() {
+		files = append(files, f.Name)
+	}
+	return files, nil
+}
+
+// returns the files containing "filter" (case insensitive), directories are skipped
+func filterFiles(files []string, filter string) []string {
+	filtered := []string{}
+	filter = strings.ToLower(filter)
+	for _, f := range files {
+		if strings.HasSuffix(f, "/") || !strings.Contains(strings.ToLower(f), filter) {
+			continue
+		}
+		filtered = append(filtered, f)
+	}
+	return filtered
+}
+
+// returns a handle for the files db's ("dbPath"/sync/"repo".files) of our repositories
+// our regular handle uses the package db's, so we need a separate one for the files db's
+// repositories without a files db are skipped, errFilesDBNotSynced is returned if there is none at all
+func filesHandle(rootPath, dbPath string, repos []string) (*alpm.Handle, []alpm.IDB, error) {
+	synced := []string{}
+	for _, repo := range repos {
+		if _, err := os.Stat(filepath.Join(dbPath, "sync", repo+".files")); err == nil {
+			synced = append(synced, repo)
+		}
+	}
+	if len(synced) == 0 {
+		return nil, nil, errFilesDBNotSynced
+	}
+
+	fh, err := alpm.Initialize(rootPath, dbPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := fh.SetDBExt(".files"); err != nil {
+		fh.Release()
+		return nil, nil, err
+	}
+	dbs := []alpm.IDB{}
+	for _, repo := range synced {
+		db, err := fh.RegisterSyncDB(repo, 0)
+		if err != nil {
+			fh.Release()
+			return nil, nil, fmt.Errorf("failed to register files db '%s': %w", repo, err)
+		}
+		dbs = append(dbs, db)
+	}
+	return fh, dbs, nil
+}
+
+// searches the repositories for packages containing a file (like "pacman -F"), installed packages are searched as well
+// packages found in the local db only (e.g. AUR packages) are returned separately, like with searchRepos
+// the excluded sources and the install state filter of our search options are applied
+func searchFiles(h dbHandle, rootPath, dbPath, term string, maxResults int, opts SearchOptions) ([]Package, []Package, error) {
+	packages := []Package{}
+	installed := []Package{}
+
+	if h == nil {
+		return packages, installed, errors.New("alpm handle is nil")
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return packages, installed, err
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return packages, installed, err
+	}
+	installedVersions, err := installedSet(h)
+	if err != nil {
+		return packages, installed, err
+	}
+
+	repos := []string{}
+	for _, db := range excludeDBs(dbs.Slice(), opts.ExcludeSources) {
+		repos = append(repos, db.Name())
+	}
+	fh, fdbs, err := filesHandle(rootPath, dbPath, repos)
+	if err != nil && !errors.Is(err, errFilesDBNotSynced) {
+		return packages, installed, err
+	}
+	if fh != nil {
+		defer fh.Release()
+	}
+
+	packages = searchFileDBs(fdbs, installedVersions, term, maxResults, opts.Installed)
+	found := map[string]bool{}
+	for _, pkg := range packages {
+		found[pkg.Name] = true
+	}
+	for _, pkg := range searchFileDBs(excludeDBs([]alpm.IDB{local}, opts.ExcludeSources), installedVersions, term, maxResults-len(packages), opts.Installed) {
+		if !found[pkg.Name] {
+			installed = append(installed, pkg)
+		}
+	}
+
+	// without files db's, we can only give results for installed packages
+	if len(packages)+len(installed) == 0 && err != nil {
+		return packages, installed, err
+	}
+	return packages, installed, nil
+}
+
+// returns the packages of our db's with a file matching "term"
+// a term matches a file if it is the full path or its ending (complete path components), e.g. "bin/ffmpeg" matches "usr/bin/ffmpeg"
+func searchFileDBs(dbs []alpm.IDB, installedVersions map[string]string, term string, maxResults int, filter InstalledFilter) []Package {
+	packages := []Package{}
+	term = strings.TrimPrefix(term, "/")
+	if term == "" {
+		return packages
+	}
+	for _, db := range dbs {
+		for _, pkg := range db.PkgCache().Slice() {
+			if len(packages) >= maxResults {
+				return packages
+			}
+			if !filter.matches(installedVersions[pkg.Name()] != "") {
+				continue
+			}
+			for _, file := range pkg.Files() {
+				if file.Name == term || strings.HasSuffix(file.Name, "/"+term) {
+					lastModified, hasBuildDate := buildDate(pkg)
+					packages = append(packages, Package{
+						Name:          pkg.Name(),
+						Source:        db.Name(),
+						IsInstalled:   installedVersions[pkg.Name()] != "",
+						LastModified:  lastModified,
+						HasBuildDate:  hasBuildDate,
+						InstalledSize: pkg.ISize(),
+						DownloadSize:  pkg.Size(),
+						Popularity:    repoPopularity,
+						MatchedField:  "File",
+					})
+					break
+				}
+			}
+		}
+	}
+	return packages
+}
+
+// returns the installed package that owns a file (like "pacman -Qo")
+// "file" is an absolute path within the installation root "rootPath"
+func fileOwner(h dbHandle, rootPath, file string) (string, error) {
+	if h == nil {
+		return "", errors.New("alpm handle is nil")
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(rootPath, file)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("'%s' is not within root '%s'", file, rootPath)
+	}
+	for _, pkg := range local.PkgCache().Slice() {
+		if _, err := pkg.ContainsFile(rel); err == nil {
+			return pkg.Name(), nil
+		}
+	}
+	return "", fmt.Errorf("no package owns '%s'", file)
+}
+
+// returns the files of an installed package that are missing in the installation root "rootPath" (like "pacman -Qk")
+func missingPackageFiles(h dbHandle, rootPath, name string) ([]string, error) {
+	missing := []string{}
+
+	if h == nil {
+		return missing, errors.New("alpm handle is nil")
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return missing, err
+	}
+	pkg := local.Pkg(name)
+	if pkg == nil {
+		return missing, fmt.Errorf("package '%s' is not installed", name)
+	}
+
+	for _, file := range pkg.Files() {
+		if _, err := os.Lstat(filepath.Join(rootPath, file.Name)); errors.Is(err, fs.ErrNotExist) {
+			missing = append(missing, "/"+file.Name)
+		}
+	}
+	return missing, nil
+}
+
+// returns dependencies of installed packages that are not satisfied by any installed package or provider
+// this usually happens after partial upgrades. The entries are formatted like "pkg requires dep>=1.0"
+func brokenDependencies(h dbHandle) ([]string, error) {
+	broken := []string{}
+
+	if h == nil {
+		return broken, errors.New("alpm handle is nil")
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return broken, err
+	}
+
+	installed := local.PkgCache()
+	for _, pkg := range installed.Slice() {
+		for _, dep := range pkg.Depends().Slice() {
+			if found, _ := installed.FindSatisfier(dep.String()); found == nil {
+				broken = append(broken, pkg.Name()+" requires "+dep.String())
+			}
+		}
+	}
+
+	return broken, nil
+}
+
+// returns the names of all sync db packages that have the given package (or one of its provides) as make dependency
+func computeMakeRequiredBy(h dbHandle, pkg string) ([]string, error) {
+	requiredBy := []string{}
+
+	if h == nil {
+		return requiredBy, errors.New("alpm handle is nil")
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return requiredBy, err
+	}
+
+	names := []string{pkg}
+	for _, db := range dbs.Slice() {
+		if p := db.Pkg(pkg); p != nil {
+			for _, prov := range p.Provides().Slice() {
+				names = append(names, prov.Name)
+			}
+			break
+		}
+	}
+
+	for _, db := range dbs.Slice() {
+		for _, p := range db.PkgCache().Slice() {
+			for _, dep := range p.MakeDepends().Slice() {
+				if util.SliceContains(names, dep.Name) {
+					requiredBy = append(requiredBy, p.Name())
+					break
+				}
+			}
+		}
+	}
+	requiredBy = util.UniqueStrings(requiredBy)
+	sort.Strings(requiredBy)
+
+	return requiredBy, nil
+}
+
+// returns all sync db packages (sorted by name) that depend (or make depend) on "target" or any of its provides
+// optional dependencies are only taken into account when "includeOptional" is set
+func findDependents(h dbHandle, target string, includeOptional bool) ([]Package, error) {
+	packages := []Package{}
+
+	if h == nil {
+		return packages, errors.New("alpm handle is nil")
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return packages, err
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return packages, err
+	}
+
+	names := []string{target}
+	if p := findSyncPackage(dbs, target); p != nil {
+		for _, prov := range p.Provides().Slice() {
+			names = append(names, prov.Name)
+		}
+	}
+
+	for _, db := range dbs.Slice() {
+		for _, p := range db.PkgCache().Slice() {
+			if p.Name() == target {
+				continue
+			}
+			lists := []alpm.IDependList{p.Depends(), p.MakeDepends()}
+			if includeOptional {
+				lists = append(lists, p.OptionalDepends())
+			}
+			if !dependsOnAny(lists, names) {
+				continue
+			}
+			lastModified, hasBuildDate := buildDate(p)
+			packages = append(packages, Package{
+				Name:          p.Name(),
+				Source:        db.Name(),
+				IsInstalled:   local.Pkg(p.Name()) != nil,
+				LastModified:  lastModified,
+				HasBuildDate:  hasBuildDate,
+				InstalledSize: p.ISize(),
+				DownloadSize:  p.Size(),
+				Popularity:    repoPopularity,
+			})
+		}
+	}
+	sort.SliceStable(packages, func(i, j int) bool {
+		return packages[i].Name < packages[j].Name
+	})
+
+	return packages, nil
+}
+
+// checks if any of the dependency lists contains one of the given names
+func dependsOnAny(lists []alpm.IDependList, names []string) bool {
+	for _, l := range lists {
+		for _, dep := range l.Slice() {
+			if util.SliceContains(names, dep.Name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// returns the (sorted) installed packages that would be orphaned when removing all of the given packages together
+// dependencies within the batch are taken into account: a package is orphaned once only removed (or orphaned) packages depend on it
+func removalOrphans(h dbHandle, pkgs []string) ([]string, error) {
+	orphans := []string{}
+
+	if h == nil {
+		return orphans, errors.New("alpm handle is nil")
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return orphans, err
+	}
+
+	removed := map[string]bool{}
+	for _, pkg := range pkgs {
+		if local.Pkg(pkg) == nil {
+			return orphans, fmt.Errorf("package '%s' is not installed", pkg)
+		}
+		removed[pkg] = true
+	}
+
+	installed := local.PkgCache().Slice()
+	for changed := true; changed; {
+		changed = false
+		for _, pkg := range installed {
+			if removed[pkg.Name()] || pkg.Reason() != alpm.PkgReasonDepend {
+				continue
+			}
+			neededByRemoved := false
+			neededByRemaining := false
+			for _, other := range installed {
+				if other.Name() == pkg.Name() || !dependsOn(other, pkg) {
+					continue
+				}
+				if removed[other.Name()] {
+					neededByRemoved = true
+				} else {
+					neededByRemaining = true
+					break
+				}
+			}
+			if neededByRemoved && !neededByRemaining {
+				removed[pkg.Name()] = true
+				orphans = append(orphans, pkg.Name())
+				changed = true
+			}
+		}
+	}
+	sort.Strings(orphans)
+
+	return orphans, nil
+}
+
+// checks if any of the dependencies of package "p" is satisfied by package "dep" (by name or provides)
+func dependsOn(p, dep alpm.IPackage) bool {
+	names := []string{dep.Name()}
+	for _, prov := range dep.Provides().Slice() {
+		names = append(names, prov.Name)
+	}
+	for _, d := range p.Depends().Slice() {
+		if util.SliceContains(names, d.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the package that pacman would choose to satisfy a dependency
+// an installed provider is preferred, then a package with the exact name, then the only available provider
+// in case there are multiple providers, the user has to choose and we return the list of candidates instead
+func chooseProvider(h dbHandle, dep string) (string, []string, error) {
+	candidates := []string{}
+
+	if h == nil {
+		return "", candidates, errors.New("alpm handle is nil")
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return "", candidates, err
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return "", candidates, err
+	}
+
+	if pkg, _ := local.PkgCache().FindSatisfier(dep); pkg != nil {
+		return pkg.Name(), candidates, nil
+	}
+
+	d := parseDependency(dep)
+	for _, db := range dbs.Slice() {
+		for _, pkg := range db.PkgCache().Slice() {
+			if !packageSatisfies(pkg, d) {
+				continue
+			}
+			if pkg.Name() == d.Name {
+				return pkg.Name(), []string{}, nil
+			}
+			if !util.SliceContains(candidates, pkg.Name()) {
+				candidates = append(candidates, pkg.Name())
+			}
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return "", candidates, fmt.Errorf("no provider found for '%s'", dep)
+	case 1:
+		return candidates[0], []string{}, nil
+	}
+	return "", candidates, nil
+}
+
+// returns the packages that would be installed together with a repo package (like the "Packages" list of "pacman -S")
+// and the installed packages satisfying its dependencies. dependencies are resolved recursively (optional ones are excluded),
+// provides are resolved to the packages providing them. each package is only listed once (which also breaks cycles)
+func resolveDeps(h dbHandle, name string) ([]string, []string, error) {
+	toInstall := []string{}
+	alreadyInstalled := []string{}
+
+	if h == nil {
+		return toInstall, alreadyInstalled, errors.New("alpm handle is nil")
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return toInstall, alreadyInstalled, err
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return toInstall, alreadyInstalled, err
+	}
+
+	pkg := findSyncPackage(dbs, name)
+	if pkg == nil {
+		return toInstall, alreadyInstalled, fmt.Errorf("package '%s' not found", name)
+	}
+
+	visited := map[string]bool{}
+	queue := []alpm.IPackage{pkg}
+	if local.Pkg(name) == nil {
+		toInstall = append(toInstall, name)
+	}
+	visited[name] = true
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, dep := range p.Depends().Slice() {
+			if lpkg, err := local.PkgCache().FindSatisfier(dep.String()); err == nil && lpkg != nil {
+				if !visited[lpkg.Name()] {
+					visited[lpkg.Name()] = true
+					alreadyInstalled = append(alreadyInstalled, lpkg.Name())
+				}
+				continue
+			}
+			spkg, err := dbs.FindSatisfier(dep.String())
+			if err != nil || spkg == nil {
+				return toInstall, alreadyInstalled, fmt.Errorf("unable to satisfy dependency '%s' required by '%s'", dep.String(), p.Name())
+			}
+			if !visited[spkg.Name()] {
+				visited[spkg.Name()] = true
+				toInstall = append(toInstall, spkg.Name())
+				queue = append(queue, spkg)
+			}
+		}
+	}
+
+	return toInstall, alreadyInstalled, nil
+}
+
+// returns all sync db packages (sorted by name) providing the virtual package "virtualName"
+func providersOf(h dbHandle, virtualName string) ([]Package, error) {
+	packages := []Package{}
+
+	if h == nil {
+		return packages, errors.New("alpm handle is nil")
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return packages, err
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return packages, err
+	}
+
+	for _, db := range dbs.Slice() {
+		for _, pkg := range db.PkgCache().Slice() {
+			if !dependListContains(pkg.Provides(), virtualName) {
+				continue
+			}
+			lastModified, hasBuildDate := buildDate(pkg)
+			packages = append(packages, Package{
+				Name:          pkg.Name(),
+				Source:        db.Name(),
+				IsInstalled:   local.Pkg(pkg.Name()) != nil,
+				LastModified:  lastModified,
+				HasBuildDate:  hasBuildDate,
+				InstalledSize: pkg.ISize(),
+				DownloadSize:  pkg.Size(),
+				Popularity:    repoPopularity,
+			})
+		}
+	}
+	sort.SliceStable(packages, func(i, j int) bool {
+		return packages[i].Name < packages[j].Name
+	})
+
+	return packages, nil
+}
+
+// returns the installed packages that would be upgraded as a side effect of installing a package
+// this is the case when the package (or one of its new dependencies) requires a newer version of an installed package
+func sideEffectUpgrades(h dbHandle, target string) ([]string, error) {
+	upgrades := []string{}
+
+	if h == nil {
+		return upgrades, errors.New("alpm handle is nil")
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return upgrades, err
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return upgrades, err
+	}
+
+	var pkg alpm.IPackage
+	for _, db := range dbs.Slice() {
+		if pkg = db.Pkg(target); pkg != nil {
+			break
+		}
+	}
+	if pkg == nil {
+		return upgrades, fmt.Errorf("package '%s' not found", target)
+	}
+
+	installed := local.PkgCache()
+	visited := map[string]bool{}
+	queue := []alpm.IPackage{pkg}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if visited[p.Name()] {
+			continue
+		}
+		visited[p.Name()] = true
+
+		for _, dep := range p.Depends().Slice() {
+			// already satisfied by an installed package
+			if found, _ := installed.FindSatisfier(dep.String()); found != nil {
+				continue
+			}
+			satisfier, err := dbs.FindSatisfier(dep.String())
+			if err != nil || satisfier == nil {
+				continue
+			}
+			// installed, but the version does not satisfy our dependency -> upgrade
+			if local.Pkg(satisfier.Name()) != nil && !util.SliceContains(upgrades, satisfier.Name()) {
+				upgrades = append(upgrades, satisfier.Name())
+			}
+			queue = append(queue, satisfier)
+		}
+	}
+	sort.Strings(upgrades)
+
+	return upgrades, nil
+}
+
+// checks if a package can be added to a selection of packages (e.g. install queue)
+// returns the name of the first queued package that conflicts with / replaces (or is replaced by) the candidate
+// queued packages that can't be found in the repositories (e.g. AUR) are ignored
+func queueConflict(h dbHandle, candidate string, queue []string) (string, error) {
+	if h == nil {
+		return "", errors.New("alpm handle is nil")
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return "", err
+	}
+
+	cpkg := findSyncPackage(dbs, candidate)
+	if cpkg == nil {
+		return "", fmt.Errorf("package '%s' not found", candidate)
+	}
+	for _, name := range queue {
+		if name == candidate {
+			continue
+		}
+		if qpkg := findSyncPackage(dbs, name); qpkg != nil && (packagesConflict(cpkg, qpkg) || packagesConflict(qpkg, cpkg)) {
+			return name, nil
+		}
 	}
+	return "", nil
+}
 
-	conf, _, err := pconf.ParseFile(confPath)
-	if err != nil {
-		return nil, err
+// returns a package from the first sync db that contains it
+func findSyncPackage(dbs alpm.IDBList, name string) alpm.IPackage {
+	for _, db := range dbs.Slice() {
+		if pkg := db.Pkg(name); pkg != nil {
+			return pkg
+		}
 	}
+	return nil
+}
 
-	for _, repo := range conf.Repos {
-		if (len(repos) > 0 && util.SliceContains(repos, repo.Name)) || len(repos) == 0 {
-			_, err := h.RegisterSyncDB(repo.Name, 0)
-			if err != nil {
-				return nil, err
-			}
+// checks if package "a" conflicts with or replaces package "b"
+func packagesConflict(a, b alpm.IPackage) bool {
+	for _, d := range append(a.Conflicts().Slice(), a.Replaces().Slice()...) {
+		if packageSatisfies(b, d) {
+			return true
 		}
 	}
-	h.SetIgnorePkgs(conf.IgnorePkg)
-	h.SetIgnoreGroups(conf.IgnoreGroup)
+	return false
+}
 
-	return h, nil
+// parses a dependency string like "glibc>=2.38: description"
+func parseDependency(dep string) alpm.Depend {
+	d := alpm.Depend{Mod: alpm.DepModAny}
+	if i := strings.Index(dep, ": "); i != -1 {
+		d.Description = dep[i+2:]
+		dep = dep[:i]
+	}
+	for _, mod := range []alpm.DepMod{alpm.DepModGE, alpm.DepModLE, alpm.DepModEq, alpm.DepModGT, alpm.DepModLT} {
+		if i := strings.Index(dep, mod.String()); i != -1 {
+			d.Name = dep[:i]
+			d.Version = dep[i+len(mod.String()):]
+			d.Mod = mod
+			return d
+		}
+	}
+	d.Name = dep
+	return d
 }
 
-// searches the pacman databases and returns packages that could be found (starting with "term")
-func searchRepos(h *alpm.Handle, term string, mode string, by string, maxResults int) ([]Package, []Package, error) {
-	packages := []Package{}
-	installed := []Package{}
+// checks if a package satisfies a dependency, either by its name or one of its provides
+// versioned dependencies can only be satisfied by provides that have a version as well
+func packageSatisfies(pkg alpm.IPackage, dep alpm.Depend) bool {
+	if pkg.Name() == dep.Name && versionSatisfies(pkg.Version(), dep) {
+		return true
+	}
+	for _, prov := range pkg.Provides().Slice() {
+		if prov.Name != dep.Name {
+			continue
+		}
+		if dep.Mod == alpm.DepModAny || (prov.Mod == alpm.DepModEq && versionSatisfies(prov.Version, dep)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checks if a version satisfies the constraint of a dependency
+func versionSatisfies(version string, dep alpm.Depend) bool {
+	cmp := alpm.VerCmp(version, dep.Version)
+	switch dep.Mod {
+	case alpm.DepModEq:
+		return cmp == 0
+	case alpm.DepModGE:
+		return cmp >= 0
+	case alpm.DepModLE:
+		return cmp <= 0
+	case alpm.DepModGT:
+		return cmp > 0
+	case alpm.DepModLT:
+		return cmp < 0
+	}
+	return true
+}
+
+// returns the optional dependencies of an installed package that are not satisfied by any installed package
+func missingOptDepends(h dbHandle, name string) ([]string, error) {
+	missing := []string{}
 
 	if h == nil {
-		return packages, installed, errors.New("alpm handle is nil")
+		return missing, errors.New("alpm handle is nil")
 	}
-	dbs, err := h.SyncDBs()
+	local, err := h.LocalDB()
 	if err != nil {
-		return packages, installed, err
+		return missing, err
+	}
+	pkg := local.Pkg(name)
+	if pkg == nil {
+		return missing, fmt.Errorf("package '%s' is not installed", name)
+	}
+
+	installed := local.PkgCache()
+	for _, dep := range pkg.OptionalDepends().Slice() {
+		if found, _ := installed.FindSatisfier(dep.String()); found == nil {
+			missing = append(missing, dep.String())
+		}
+	}
+
+	return missing, nil
+}
+
+// returns explicitly installed packages that are not required by any other package (like "pacman -Qet")
+// unlike orphans (dependencies that are not required anymore), these are top-level packages chosen by the user
+// which can be removed without breaking other packages
+func leafExplicitPackages(h dbHandle) []Package {
+	packages := []Package{}
+
+	if h == nil {
+		return packages
 	}
 	local, err := h.LocalDB()
 	if err != nil {
-		return packages, installed, err
+		return packages
 	}
 
-	searchDbs := append(dbs.Slice(), local)
+	for _, pkg := range local.PkgCache().Slice() {
+		if pkg.Reason() != alpm.PkgReasonExplicit || len(pkg.ComputeRequiredBy()) > 0 {
+			continue
+		}
+		lastModified, hasBuildDate := buildDate(pkg)
+		packages = append(packages, Package{
+			Name:          pkg.Name(),
+			Source:        "local",
+			IsInstalled:   true,
+			LastModified:  lastModified,
+			HasBuildDate:  hasBuildDate,
+			InstalledSize: pkg.ISize(),
+			DownloadSize:  pkg.Size(),
+			Popularity:    repoPopularity,
+		})
+	}
 
-	counter := 0
-	for _, db := range searchDbs {
-		for _, pkg := range db.PkgCache().Slice() {
-			if counter >= maxResults {
-				break
-			}
-			compFunc := strings.HasPrefix
-			if mode == "Contains" {
-				compFunc = strings.Contains
-			}
+	return packages
+}
 
-			if compFunc(pkg.Name(), term) ||
-				(by == "Name & Description" && compFunc(strings.ToLower(pkg.Description()), term)) {
-				pkg := Package{
-					Name:         pkg.Name(),
-					Source:       db.Name(),
-					IsInstalled:  local.Pkg(pkg.Name()) != nil,
-					LastModified: int(pkg.BuildDate().Unix()),
-					Popularity:   math.MaxFloat64,
-				}
-				if db != local {
-					packages = append(packages, pkg)
-				} else {
-					installed = append(installed, pkg)
-				}
+// filters for installed packages: orphans (see listOrphans), explicitly installed or foreign (see listForeign) ones
+var localFilters = []string{"All", "Orphans", "Explicit", "Foreign"}
 
-				counter++
+// removes all packages not matching one of our local filters, all but installed packages are removed (unless the filter is "All")
+func filterLocal(h dbHandle, packages []Package, filter string) []Package {
+	if filter == "All" || filter == "" {
+		return packages
+	}
+	if h == nil {
+		return []Package{}
+	}
+
+	matching := map[string]bool{}
+	switch filter {
+	case "Orphans":
+		for _, name := range listOrphans(h, false) {
+			matching[name] = true
+		}
+	case "Foreign":
+		for _, name := range listForeign(h) {
+			matching[name] = true
+		}
+	case "Explicit":
+		if local, err := h.LocalDB(); err == nil {
+			for _, pkg := range local.PkgCache().Slice() {
+				if pkg.Reason() == alpm.PkgReasonExplicit {
+					matching[pkg.Name()] = true
+				}
 			}
 		}
 	}
-	return packages, installed, nil
+
+	filtered := []Package{}
+	for _, pkg := range packages {
+		if pkg.IsInstalled && matching[pkg.Name] {
+			filtered = append(filtered, pkg)
+		}
+	}
+	return filtered
 }
 
-func suggestRepos(h *alpm.Handle, term string) []string {
-	pkgs, _, _ := searchRepos(h, term, "", "", 20)
+// returns the (sorted) names of installed packages that don't exist in any sync db (like "pacman -Qm")
+func listForeign(h dbHandle) []string {
+	foreign := []string{}
 
-	names := []string{}
-	for _, pkg := range pkgs {
-		names = append(names, pkg.Name)
+	if h == nil {
+		return foreign
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return foreign
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return foreign
 	}
 
-	return names
+	for _, pkg := range local.PkgCache().Slice() {
+		if findSyncPackage(dbs, pkg.Name()) == nil {
+			foreign = append(foreign, pkg.Name())
+		}
+	}
+	sort.Strings(foreign)
+
+	return foreign
 }
 
-// create/update temporary sync DB
-func syncToTempDB(confPath string, repos []string) (*alpm.Handle, error) {
-	// check if fakeroot is installed
-	if _, err := os.Stat("/usr/bin/fakeroot"); errors.Is(err, fs.ErrNotExist) {
-		return nil, errors.New("fakeroot not installed")
+// returns the (sorted) names of packages installed as dependency that are not required by any other package (like "pacman -Qdt")
+// with "includeOptional", packages that are only optionally required are orphans as well (like "pacman -Qdtt")
+func listOrphans(h dbHandle, includeOptional bool) []string {
+	orphans := []string{}
+
+	if h == nil {
+		return orphans
 	}
-	conf, _, err := pconf.ParseFile(confPath)
+	local, err := h.LocalDB()
 	if err != nil {
-		return nil, err
+		return orphans
 	}
-	/*
-		We use the same naming as "checkupdates" to have less data to transfer
-		in case the user already makes use of checkupdates...
-	*/
-	tmpdb := os.TempDir() + "/checkup-db-" + strconv.Itoa(os.Getuid())
-	local := tmpdb + "/local"
 
-	// create directory and symlink if needed
-	if _, err := os.Stat(tmpdb); errors.Is(err, fs.ErrNotExist) {
-		err := os.MkdirAll(tmpdb, 0755)
-		if err != nil {
-			return nil, err
+	for _, pkg := range local.PkgCache().Slice() {
+		if pkg.Reason() != alpm.PkgReasonDepend || len(pkg.ComputeRequiredBy()) > 0 {
+			continue
 		}
+		if !includeOptional && len(pkg.ComputeOptionalFor()) > 0 {
+			continue
+		}
+		orphans = append(orphans, pkg.Name())
 	}
-	if _, err := os.Stat(local); errors.Is(err, fs.ErrNotExist) {
-		err := os.Symlink(path.Join(conf.DBPath, "local"), local)
-		if err != nil {
-			return nil, err
+	sort.Strings(orphans)
+
+	return orphans
+}
+
+// returns installed packages that can't be reached from any explicitly installed package via its dependencies
+// unlike orphans, this also catches "islands" of packages that only depend on each other
+// with "includeOptional", optional dependencies are followed as well
+func unreachablePackages(h dbHandle, includeOptional bool) []Package {
+	packages := []Package{}
+
+	if h == nil {
+		return packages
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return packages
+	}
+
+	// installed packages by name and provides
+	installed := local.PkgCache().Slice()
+	satisfiers := map[string][]alpm.IPackage{}
+	for _, pkg := range installed {
+		satisfiers[pkg.Name()] = append(satisfiers[pkg.Name()], pkg)
+		for _, prov := range pkg.Provides().Slice() {
+			satisfiers[prov.Name] = append(satisfiers[prov.Name], pkg)
 		}
 	}
 
-	// execute pacman and sync to temporary db
-	cmd := exec.Command("fakeroot", "--", "pacman", "-Sy", "--dbpath="+tmpdb)
+	// walk the dependency graph starting with our explicitly installed packages (visited packages break cycles)
+	reached := map[string]bool{}
+	queue := []alpm.IPackage{}
+	for _, pkg := range installed {
+		if pkg.Reason() == alpm.PkgReasonExplicit {
+			reached[pkg.Name()] = true
+			queue = append(queue, pkg)
+		}
+	}
+	for len(queue) > 0 {
+		pkg := queue[0]
+		queue = queue[1:]
+		deps := pkg.Depends().Slice()
+		if includeOptional {
+			deps = append(deps, pkg.OptionalDepends().Slice()...)
+		}
+		for _, dep := range deps {
+			for _, sat := range satisfiers[dep.Name] {
+				if !reached[sat.Name()] {
+					reached[sat.Name()] = true
+					queue = append(queue, sat)
+				}
+			}
+		}
+	}
 
-	out, err := cmd.CombinedOutput()
+	for _, pkg := range installed {
+		if reached[pkg.Name()] {
+			continue
+		}
+		lastModified, hasBuildDate := buildDate(pkg)
+		packages = append(packages, Package{
+			Name:          pkg.Name(),
+			Source:        "local",
+			IsInstalled:   true,
+			LastModified:  lastModified,
+			HasBuildDate:  hasBuildDate,
+			InstalledSize: pkg.ISize(),
+			DownloadSize:  pkg.Size(),
+			Popularity:    repoPopularity,
+		})
+	}
+
+	return packages
+}
+
+// returns the changelog of an installed package (like "pacman -Qc")
+// our alpm bindings don't expose changelogs, so we read it from the local db directory ("dbPath"/local/"name"-"version"/changelog)
+func packageChangelog(h dbHandle, dbPath, name string) (string, error) {
+	if h == nil {
+		return "", errors.New("alpm handle is nil")
+	}
+	local, err := h.LocalDB()
 	if err != nil {
-		return nil, errors.New(string(out))
+		return "", err
+	}
+	pkg := local.Pkg(name)
+	if pkg == nil {
+		return "", fmt.Errorf("package '%s' is not installed", name)
 	}
 
-	h, err := initPacmanDbs(tmpdb, confPath, repos)
+	b, err := os.ReadFile(path.Join(dbPath, "local", pkg.Name()+"-"+pkg.Version(), "changelog"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("package '%s' does not have a changelog", name)
+	}
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	return h, nil
+	return string(b), nil
 }
 
-// returns packages that can be upgraded & packages that only exist locally
-func getUpgradable(h *alpm.Handle, computeRequiredBy bool) ([]InfoRecord, []string) {
-	upgradable := []string{}
-	notFound := []string{}
+// returns the number of packages per repository (sync db's only)
+func packagesByRepo(h dbHandle) map[string]int {
+	counts := map[string]int{}
 
 	if h == nil {
-		return []InfoRecord{}, notFound
+		return counts
 	}
 	dbs, err := h.SyncDBs()
 	if err != nil {
-		return []InfoRecord{}, notFound
+		return counts
+	}
+	for _, db := range dbs.Slice() {
+		counts[db.Name()] = len(db.PkgCache().Slice())
+	}
+	return counts
+}
+
+// returns a page ("offset", "limit") of the packages in a repository ("local" for installed packages)
+func listRepoPackages(h dbHandle, repo string, offset, limit int) ([]Package, error) {
+	packages := []Package{}
+
+	if h == nil {
+		return packages, errors.New("alpm handle is nil")
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return packages, err
 	}
 	local, err := h.LocalDB()
 	if err != nil {
-		return []InfoRecord{}, notFound
+		return packages, err
 	}
 
-	for _, lpkg := range local.PkgCache().Slice() {
-		found := false
-		for _, db := range dbs.Slice() {
-			pkg := db.Pkg(lpkg.Name())
-			if pkg != nil {
-				found = true
-				if alpm.VerCmp(pkg.Version(), lpkg.Version()) > 0 {
-					upgradable = append(upgradable, pkg.Name())
-				}
-				break
-			}
-		}
-		if !found {
-			upgradable = append(upgradable, lpkg.Name())
-			notFound = append(notFound, lpkg.Name())
+	var db alpm.IDB
+	for _, d := range append(dbs.Slice(), local) {
+		if d.Name() == repo {
+			db = d
+			break
 		}
 	}
+	if db == nil {
+		return packages, fmt.Errorf("repository '%s' not found", repo)
+	}
 
-	return infoPacman(h, computeRequiredBy, upgradable...).Results, notFound
+	pkgs := db.PkgCache().Slice()
+	if offset < 0 || offset >= len(pkgs) || limit <= 0 {
+		return packages, nil
+	}
+	end := offset + limit
+	if end > len(pkgs) {
+		end = len(pkgs)
+	}
+	for _, pkg := range pkgs[offset:end] {
+		lastModified, hasBuildDate := buildDate(pkg)
+		packages = append(packages, Package{
+			Name:          pkg.Name(),
+			Source:        db.Name(),
+			IsInstalled:   local.Pkg(pkg.Name()) != nil,
+			LastModified:  lastModified,
+			HasBuildDate:  hasBuildDate,
+			InstalledSize: pkg.ISize(),
+			DownloadSize:  pkg.Size(),
+			Popularity:    repoPopularity,
+		})
+	}
+	return packages, nil
 }
 
-// returns packages that can be upgraded & packages that only exist locally
-func getInstalled(h *alpm.Handle, computeRequiredBy bool) ([]InfoRecord, []string) {
-	installed := []string{}
-	notFound := []string{}
+// returns the "n" most recently installed packages (packages without install date go last)
+func recentlyInstalled(h dbHandle, n int) []Package {
+	packages := []Package{}
 
 	if h == nil {
-		return []InfoRecord{}, notFound
-	}
-	dbs, err := h.SyncDBs()
-	if err != nil {
-		return []InfoRecord{}, notFound
+		return packages
 	}
 	local, err := h.LocalDB()
 	if err != nil {
-		return []InfoRecord{}, notFound
+		return packages
 	}
 
-	for _, lpkg := range local.PkgCache().Slice() {
-		found := false
-		for _, db := range dbs.Slice() {
-			if pkg := db.Pkg(lpkg.Name()); pkg != nil {
-				found = true
-				installed = append(installed, pkg.Name())
-				break
-			}
-		}
-		if !found {
-			installed = append(installed, lpkg.Name())
-			notFound = append(notFound, lpkg.Name())
+	installed := local.PkgCache().Slice()
+	sort.SliceStable(installed, func(i, j int) bool {
+		return installed[i].InstallDate().Unix() > installed[j].InstallDate().Unix()
+	})
+
+	for _, pkg := range installed {
+		if len(packages) >= n {
+			break
 		}
+		lastModified, hasBuildDate := buildDate(pkg)
+		packages = append(packages, Package{
+			Name:          pkg.Name(),
+			Source:        "local",
+			IsInstalled:   true,
+			LastModified:  lastModified,
+			HasBuildDate:  hasBuildDate,
+			InstalledSize: pkg.ISize(),
+			DownloadSize:  pkg.Size(),
+			Popularity:    repoPopularity,
+		})
 	}
 
-	results := infoPacman(h, computeRequiredBy, installed...).Results
-	addLocalSatisfiers(h, results...)
-	return results, notFound
+	return packages
 }
 
-// checks the local db if a package is installed
-func isPackageInstalled(h *alpm.Handle, pkg string) bool {
+// returns the sum of the installed size of all installed packages
+func totalInstalledSize(h dbHandle) (int64, error) {
+	if h == nil {
+		return 0, errors.New("alpm handle is nil")
+	}
 	local, err := h.LocalDB()
 	if err != nil {
-		return false
+		return 0, err
+	}
+
+	var total int64
+	for _, pkg := range local.PkgCache().Slice() {
+		total += pkg.ISize()
+	}
+
+	return total, nil
+}
+
+// checks the local db for a list of packages and returns their install state
+func areInstalled(h dbHandle, pkgs []string) map[string]bool {
+	installed := map[string]bool{}
+
+	versions, err := installedSet(h)
+	if err != nil {
+		return installed
+	}
+
+	for _, pkg := range pkgs {
+		_, installed[pkg] = versions[pkg]
 	}
 
-	return local.Pkg(pkg) != nil
+	return installed
 }
 
 // retrieves package information from the pacman DB's and returns it in the same format as the AUR call
-func infoPacman(h *alpm.Handle, computeRequiredBy bool, pkgs ...string) SearchResults {
+func infoPacman(h dbHandle, computeRequiredBy bool, pkgs ...string) SearchResults {
 	r := SearchResults{
 		Results: []InfoRecord{},
 	}
 
+	if h == nil {
+		r.Error = "alpm handle is nil"
+		return r
+	}
 	dbs, err := h.SyncDBs()
 	if err != nil {
 		r.Error = err.Error()
@@ -261,64 +2882,15 @@ func infoPacman(h *alpm.Handle, computeRequiredBy bool, pkgs ...string) SearchRe
 				continue
 			}
 
-			deps := []string{}
-			makedeps := []string{}
-			odeps := []string{}
-			cdeps := []string{}
-			prov := []string{}
-			conf := []string{}
-
-			for _, d := range p.Depends().Slice() {
-				deps = append(deps, d.String())
-			}
-			for _, d := range p.MakeDepends().Slice() {
-				makedeps = append(makedeps, d.String())
-			}
-			for _, d := range p.OptionalDepends().Slice() {
-				odeps = append(odeps, d.String())
-			}
-			for _, d := range p.CheckDepends().Slice() {
-				cdeps = append(cdeps, d.String())
-			}
-			for _, pr := range p.Provides().Slice() {
-				prov = append(prov, pr.String())
-			}
-			for _, c := range p.Conflicts().Slice() {
-				conf = append(conf, c.String())
-			}
-
-			i := InfoRecord{
-				Name:         p.Name(),
-				Description:  p.Description(),
-				Provides:     prov,
-				Conflicts:    conf,
-				Version:      p.Version(),
-				License:      p.Licenses().Slice(),
-				Maintainer:   p.Packager(),
-				Depends:      deps,
-				MakeDepends:  makedeps,
-				OptDepends:   odeps,
-				CheckDepends: cdeps,
-				URL:          p.URL(),
-				LastModified: int(p.BuildDate().UTC().Unix()),
-				Source:       db.Name(),
-				Architecture: p.Architecture(),
-				PackageBase:  p.Base(),
-				IsIgnored:    p.ShouldIgnore(),
-			}
-
-			if computeRequiredBy {
-				optFor := p.ComputeOptionalFor()
-				for i, pkg := range optFor {
-					optFor[i] = pkg + " (opt)"
-				}
-				i.RequiredBy = append(p.ComputeRequiredBy(), optFor...)
-			}
-			if lpkg := local.Pkg(p.Name()); lpkg != nil {
-				i.LocalVersion = lpkg.Version()
-			}
+			i := packageInfo(p, db.Name(), local, computeRequiredBy)
 			if db.Name() == "local" {
-				i.Description = p.Description() + "\n[red]* Package not found in repositories/AUR *"
+				if successor := packageSuccessor(dbs, p.Name()); successor != "" {
+					i.Description = p.Description() + "\n[red]* Package has been replaced by '" + successor + "' *"
+				} else {
+					i.Description = p.Description() + "\n[red]* Package not found in repositories/AUR *"
+				}
+			} else {
+				i.Validation = validationMethod(p.Validation())
 			}
 
 			r.Results = append(r.Results, i)
@@ -330,9 +2902,133 @@ func infoPacman(h *alpm.Handle, computeRequiredBy bool, pkgs ...string) SearchRe
 	return r
 }
 
+// converts an alpm package to an info record
+func packageInfo(p alpm.IPackage, source string, local alpm.IDB, computeRequiredBy bool) InfoRecord {
+	deps := []string{}
+	makedeps := []string{}
+	odeps := []string{}
+	cdeps := []string{}
+	prov := []string{}
+	conf := []string{}
+	repl := []string{}
+
+	for _, d := range p.Depends().Slice() {
+		deps = append(deps, d.String())
+	}
+	for _, d := range p.MakeDepends().Slice() {
+		makedeps = append(makedeps, d.String())
+	}
+	for _, d := range p.OptionalDepends().Slice() {
+		odeps = append(odeps, d.String())
+	}
+	for _, d := range p.CheckDepends().Slice() {
+		cdeps = append(cdeps, d.String())
+	}
+	for _, pr := range p.Provides().Slice() {
+		prov = append(prov, pr.String())
+	}
+	for _, c := range p.Conflicts().Slice() {
+		conf = append(conf, c.String())
+	}
+	for _, r := range p.Replaces().Slice() {
+		repl = append(repl, r.String())
+	}
+
+	i := InfoRecord{
+		Name:         p.Name(),
+		Description:  p.Description(),
+		Provides:     prov,
+		Conflicts:    conf,
+		Replaces:     repl,
+		Version:      p.Version(),
+		License:      p.Licenses().Slice(),
+		Maintainer:   p.Packager(),
+		Depends:      deps,
+		MakeDepends:  makedeps,
+		OptDepends:   odeps,
+		CheckDepends: cdeps,
+		URL:          p.URL(),
+		Source:       source,
+		Architecture: p.Architecture(),
+		PackageBase:  p.Base(),
+		IsIgnored:    p.ShouldIgnore(),
+	}
+	i.LastModified, i.HasBuildDate = buildDate(p)
+	i.Size, i.InstalledSize = p.Size(), p.ISize()
+
+	if computeRequiredBy {
+		optFor := p.ComputeOptionalFor()
+		for i, pkg := range optFor {
+			optFor[i] = pkg + " (opt)"
+		}
+		i.RequiredBy = append(p.ComputeRequiredBy(), optFor...)
+	}
+	if lpkg := local.Pkg(p.Name()); lpkg != nil {
+		i.LocalVersion = lpkg.Version()
+		i.InstallReason = installReason(lpkg.Reason())
+	}
+
+	return i
+}
+
+// returns the strongest validation method of a package ("" if unknown)
+func validationMethod(v alpm.Validation) string {
+	switch {
+	case v&alpm.ValidationSignature != 0:
+		return "pgp"
+	case v&alpm.ValidationSHA256Sum != 0:
+		return "sha256"
+	case v&alpm.ValidationMD5Sum != 0:
+		return "md5"
+	case v&alpm.ValidationNone != 0:
+		return "none"
+	}
+	return ""
+}
+
+// returns the install reason of a package like shown by "pacman -Qi"
+func installReason(reason alpm.PkgReason) string {
+	if reason == alpm.PkgReasonDepend {
+		return "Dependency"
+	}
+	return "Explicit"
+}
+
+// returns the build date of a package as unix timestamp
+// packages without (valid) build date return 0 and false
+func buildDate(p alpm.IPackage) (int, bool) {
+	unix := p.BuildDate().Unix()
+	if unix <= 0 {
+		return 0, false
+	}
+	return int(unix), true
+}
+
+// limits the number of dependencies (of all types) for package info records
+// the number of omitted dependencies is stored in "OmittedDepends". A value of 0 means unlimited
+func limitDependencies(max int, pkgs ...InfoRecord) {
+	if max <= 0 {
+		return
+	}
+	for i := 0; i < len(pkgs); i++ {
+		remaining := max
+		for _, deps := range []*[]string{&pkgs[i].Depends, &pkgs[i].OptDepends, &pkgs[i].MakeDepends, &pkgs[i].CheckDepends} {
+			if len(*deps) > remaining {
+				pkgs[i].OmittedDepends += len(*deps) - remaining
+				*deps = (*deps)[:remaining]
+			}
+			remaining -= len(*deps)
+		}
+	}
+}
+
 // add locally installed satisfiers to pacakge info records
-func addLocalSatisfiers(h *alpm.Handle, pkgs ...InfoRecord) {
-	local, err := h.LocalDB()
+func addLocalSatisfiers(h dbHandle, pkgs ...InfoRecord) {
+	var local alpm.IDB
+	err := errors.New("alpm handle is nil")
+	if h != nil {
+		local, err = h.LocalDB()
+	}
 
 	for i := 0; i < len(pkgs); i++ {
 		depList := []struct {
@@ -0,0 +1,200 @@
+package pacseek
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Jguer/go-alpm/v2"
+)
+
+// placeholders that can be used in our merge command
+var mergePlaceholders = []string{"{original}", "{file}"}
+
+// the suffixes of config files that pacman didn't overwrite (.pacnew) or kept after removing a package (.pacsave)
+var pacnewSuffixes = []string{".pacnew", ".pacsave"}
+
+// pacnewFile is a .pacnew / .pacsave file
+type pacnewFile struct {
+	Path     string // the .pacnew / .pacsave file
+	Original string // the config file it belongs to
+	Kind     string // "pacnew" or "pacsave"
+	ModTime  time.Time
+}
+
+// returns the .pacnew / .pacsave file for a path, false if it is none
+func newPacnewFile(path string, fi fs.FileInfo) (pacnewFile, bool) {
+	for _, suffix := range pacnewSuffixes {
+		if strings.HasSuffix(path, suffix) && len(filepath.Base(path)) > len(suffix) {
+			return pacnewFile{
+				Path:     path,
+				Original: strings.TrimSuffix(path, suffix),
+				Kind:     suffix[1:],
+				ModTime:  fi.ModTime(),
+			}, true
+		}
+	}
+	return pacnewFile{}, false
+}
+
+// a file that doesn't exist anymore has been merged (and removed afterwards)
+func (f pacnewFile) merged() bool {
+	_, err := os.Stat(f.Path)
+	return err != nil
+}
+
+// returns the backup files (config files) of our installed packages, like "pacman -Qii" shows them
+// their paths are relative to the installation root
+func backupFiles(h dbHandle) []string {
+	files := []string{}
+	if h == nil {
+		return files
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return files
+	}
+	local.PkgCache().ForEach(func(pkg alpm.IPackage) error {
+		return pkg.Backup().ForEach(func(b alpm.BackupFile) error {
+			files = append(files, b.Name)
+			return nil
+		})
+	})
+	return files
+}
+
+// returns the .pacnew / .pacsave files of our backup files and the ones in our config directories (e.g. etc)
+// the directories cover packages that have been removed, they don't have any backup files anymore
+// "backups" and "dirs" are relative to "root", directories we can't read are skipped
+func findPacnewFiles(root string, backups []string, dirs []string) []pacnewFile {
+	found := map[string]pacnewFile{}
+	for _, b := range backups {
+		for _, suffix := range pacnewSuffixes {
+			path := filepath.Join(root, b+suffix)
+			if fi, err := os.Stat(path); err == nil && fi.Mode().IsRegular() {
+				found[path], _ = newPacnewFile(path, fi)
+			}
+		}
+	}
+	for _, dir := range dirs {
+		filepath.WalkDir(filepath.Join(root, dir), func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return nil
+			}
+			fi, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			if f, ok := newPacnewFile(path, fi); ok {
+				found[path] = f
+			}
+			return nil
+		})
+	}
+
+	files := []pacnewFile{}
+	for _, f := range found {
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files
+}
+
+// returns the files that have been created (or replaced) since our snapshot "before"
+func changedPacnewFiles(before, after []pacnewFile) []pacnewFile {
+	known := map[string]time.Time{}
+	for _, f := range before {
+		known[f.Path] = f.ModTime
+	}
+	changed := []pacnewFile{}
+	for _, f := range after {
+		if t, ok := known[f.Path]; !ok || !t.Equal(f.ModTime) {
+			changed = append(changed, f)
+		}
+	}
+	return changed
+}
+
+// quotes a string for our shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// returns our merge command for a .pacnew / .pacsave file
+// "{original}" is replaced with the config file, "{file}" with the .pacnew / .pacsave file (both are appended otherwise)
+func mergeCommand(command string, f pacnewFile) string {
+	if !strings.Contains(command, "{original}") && !strings.Contains(command, "{file}") {
+		command += " {original} {file}"
+	}
+	return strings.NewReplacer(
+		"{original}", shellQuote(f.Original),
+		"{file}", shellQuote(f.Path),
+	).Replace(command)
+}
+
+// returns the .pacnew / .pacsave files of our installation root
+// our searches might be using our alpm handle, we can't wait for our lock in our event loop though
+// (background work holding it queues updates), so we read our backup files with a handle of our own
+func (ps *UI) findPacnewFiles() []pacnewFile {
+	backups := []string{}
+	if h, err := alpm.Initialize(ps.conf.PacmanRootPath, ps.conf.PacmanDbPath); err == nil {
+		backups = backupFiles(h)
+		h.Release()
+	}
+	return findPacnewFiles(ps.conf.PacmanRootPath, backups, []string{"etc"})
+}
+
+// runs a command that installs / removes / upgrades packages, unless our pre-flight check fails (see preflight)
+// afterwards, the .pacnew / .pacsave files that have been created are shown, so that they can be merged
+func (ps *UI) runTransaction(command string) {
+	if err := ps.preflight(command); err != nil {
+		ps.displayMessage(err.Error(), true)
+		return
+	}
+	// the install state of our additional sources might have changed
+	defer refreshSources(ps.sources)
+	if ps.conf.DisablePacnewCheck {
+		ps.runCommand(ps.shell, "-c", command)
+		return
+	}
+	before := ps.findPacnewFiles()
+	ps.runCommand(ps.shell, "-c", command)
+	if changed := changedPacnewFiles(before, ps.findPacnewFiles()); len(changed) > 0 {
+		logger.info("config files need to be merged", "files", len(changed))
+		// our caller might still update our views, so we're showing them afterwards
+		go ps.app.QueueUpdateDraw(func() {
+			ps.displayPacnew(changed, true)
+		})
+	}
+}
+
+// displays .pacnew / .pacsave files, "transaction" is set when they have been created by our last transaction
+func (ps *UI) displayPacnew(files []pacnewFile, transaction bool) {
+	ps.pacnewFiles = files
+	ps.pacnewTransaction = transaction
+	ps.flexRight.Clear().
+		AddItem(ps.tablePacnew, 0, 1, true)
+	ps.app.SetFocus(ps.tablePacnew)
+	ps.drawPacnew()
+}
+
+// displays all .pacnew / .pacsave files of our installation root
+func (ps *UI) displayAllPacnew() {
+	ps.displayPacnew(ps.findPacnewFiles(), false)
+}
+
+// opens our merge command for a .pacnew / .pacsave file
+func (ps *UI) mergePacnew(f pacnewFile) {
+	command := mergeCommand(ps.conf.MergeCommand, f)
+	if err := ps.preflight(command); err != nil {
+		ps.displayMessage(err.Error(), true)
+		return
+	}
+	ps.runCommand(ps.shell, "-c", command)
+	ps.drawPacnew()
+}
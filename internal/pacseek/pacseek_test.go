@@ -1,9 +1,35 @@
 package pacseek
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"io/fs"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/Jguer/go-alpm/v2"
+	pconf "github.com/Morganamilo/go-pacmanconf"
+	"github.com/gdamore/tcell/v2"
+	"github.com/mmcdole/gofeed"
+	"github.com/moson-mo/pacseek/internal/args"
+	"github.com/moson-mo/pacseek/internal/config"
+	"github.com/moson-mo/pacseek/internal/util"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -25,45 +51,45 @@ func TestRunTestSuite(t *testing.T) {
 
 func (suite *pacseekTestSuite) TestInitPacmanDbs() {
 	// ok
-	h, err := initPacmanDbs("/var/lib/pacman", "/etc/pacman.conf", []string{})
+	h, _, err := initPacmanDbs("/", "/var/lib/pacman", "/etc/pacman.conf", []string{}, false)
 	suite.NotNil(h, err)
 	suite.Nil(err, err)
 
 	// nok
-	h, err = initPacmanDbs("/var/lib/pacman", "nonsense", []string{})
+	h, _, err = initPacmanDbs("/", "/var/lib/pacman", "nonsense", []string{}, false)
 	suite.Nil(h)
 	suite.NotNil(err)
 
-	h, err = initPacmanDbs("nonsense", "/etc/pacman.conf", []string{})
+	h, _, err = initPacmanDbs("/", "nonsense", "/etc/pacman.conf", []string{}, false)
 	suite.Nil(h)
 	suite.NotNil(err)
 }
 
 func (suite *pacseekTestSuite) TestSearchPacmanDbs() {
-	h, err := initPacmanDbs("/var/lib/pacman", "/etc/pacman.conf", []string{})
+	h, _, err := initPacmanDbs("/", "/var/lib/pacman", "/etc/pacman.conf", []string{}, false)
 	suite.NotNil(h, err)
 	suite.Nil(err, err)
 
 	// ok
-	p, _, err := searchRepos(h, "glibc", "StartsWith", "Name", 1)
+	p, _, err := searchRepos(h, "glibc", "StartsWith", "Name", 1, SearchOptions{})
 	suite.Nil(err, err)
 	suite.Len(p, 1, "Number of packages != 1")
-	p, _, err = searchRepos(h, "glibc", "StartsWith", "Name & Description", 1)
+	p, _, err = searchRepos(h, "glibc", "StartsWith", "Name & Description", 1, SearchOptions{})
 	suite.Nil(err, err)
 	suite.Len(p, 1, "Number of packages != 1")
 
 	// nok
-	p, _, err = searchRepos(h, "nonsense_nonsense", "StartsWith", "Name", 1)
+	p, _, err = searchRepos(h, "nonsense_nonsense", "StartsWith", "Name", 1, SearchOptions{})
 	suite.Nil(err, err)
 	suite.Equal([]Package{}, p, "[]Packages not empty")
 
-	p, _, err = searchRepos(nil, "nonsense_nonsense", "StartsWith", "Name", 1)
+	p, _, err = searchRepos(nil, "nonsense_nonsense", "StartsWith", "Name", 1, SearchOptions{})
 	suite.NotNil(err, err)
 	suite.Equal([]Package{}, p, "[]Packages not empty")
 }
 
 func (suite *pacseekTestSuite) TestInfoPacmanDbs() {
-	h, err := initPacmanDbs("/var/lib/pacman", "/etc/pacman.conf", []string{})
+	h, _, err := initPacmanDbs("/", "/var/lib/pacman", "/etc/pacman.conf", []string{}, false)
 	suite.NotNil(h, err)
 	suite.Nil(err, err)
 
@@ -80,7 +106,7 @@ func (suite *pacseekTestSuite) TestInfoPacmanDbs() {
 }
 
 func (suite *pacseekTestSuite) TestIsInstalled() {
-	h, err := initPacmanDbs("/var/lib/pacman", "/etc/pacman.conf", []string{})
+	h, _, err := initPacmanDbs("/", "/var/lib/pacman", "/etc/pacman.conf", []string{}, false)
 	suite.NotNil(h, err)
 	suite.Nil(err, err)
 
@@ -133,3 +159,5777 @@ func (suite *pacseekTestSuite) TestInfoAur() {
 	suite.NotEqual("", p.Error, "error empty")
 	suite.Equal(0, len(p.Results), "Results not empty")
 }
+
+func (suite *pacseekTestSuite) TestGetUpgradableSizes() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("core",
+			&mockPackage{name: "foo", version: "1.1-1", size: 100, isize: 500},
+			&mockPackage{name: "bar", version: "2.0-1", size: 50, isize: 200},
+		)},
+		local: newMockDB("local",
+			&mockPackage{name: "foo", version: "1.0-1", isize: 400},
+			&mockPackage{name: "bar", version: "2.0-1", isize: 200},
+			&mockPackage{name: "baz", version: "1.0-1", isize: 10},
+		),
+	}
+
+	// ok
+	up, nf := getUpgradable(h, UpgradeOptions{ComputeSizes: true}, IgnoreRules{})
+	suite.Equal([]string{"baz"}, packageNames(nf), "not found list wrong")
+	suite.Equal("local", nf[0].Source)
+	suite.Len(up, 2, "Number of upgrades != 2")
+	suite.Equal("foo", up[0].Name)
+	suite.Equal(int64(100), up[0].DownloadSize, "download size wrong")
+	suite.Equal(int64(100), up[0].InstalledSizeDelta, "installed size delta wrong")
+	suite.Equal("baz", up[1].Name)
+	suite.Equal(int64(0), up[1].DownloadSize, "download size for local package not 0")
+
+	// sizes not requested
+	up, _ = getUpgradable(h, UpgradeOptions{}, IgnoreRules{})
+	suite.Len(up, 2, "Number of upgrades != 2")
+	suite.Equal(int64(0), up[0].DownloadSize, "download size not 0")
+	suite.Equal(int64(0), up[0].InstalledSizeDelta, "installed size delta not 0")
+
+	// nok
+	up, nf = getUpgradable(nil, UpgradeOptions{ComputeSizes: true}, IgnoreRules{})
+	suite.Equal([]Upgrade{}, up, "[]Upgrade not empty")
+	suite.Equal([]Package{}, nf, "not found list not empty")
+}
+
+func (suite *pacseekTestSuite) TestSearchReposInstalledFilter() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "foo", version: "1.0-1"},
+			&mockPackage{name: "foobar", version: "1.0-1"},
+		)},
+		local: newMockDB("local",
+			&mockPackage{name: "foo", version: "1.0-1"},
+		),
+	}
+
+	// any
+	p, l, err := searchRepos(h, "foo", "StartsWith", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Len(p, 2, "Number of packages != 2")
+	suite.Len(l, 1, "Number of local packages != 1")
+
+	// installed only
+	p, l, err = searchRepos(h, "foo", "StartsWith", "Name", 10, SearchOptions{Installed: InstalledOnly})
+	suite.Nil(err, err)
+	suite.Len(p, 1, "Number of packages != 1")
+	suite.Equal("foo", p[0].Name)
+	suite.True(p[0].IsInstalled)
+	suite.Len(l, 1, "Number of local packages != 1")
+
+	// not installed only
+	p, l, err = searchRepos(h, "foo", "StartsWith", "Name", 10, SearchOptions{Installed: NotInstalledOnly})
+	suite.Nil(err, err)
+	suite.Len(p, 1, "Number of packages != 1")
+	suite.Equal("foobar", p[0].Name)
+	suite.False(p[0].IsInstalled)
+	suite.Len(l, 0, "Number of local packages != 0")
+}
+
+func (suite *pacseekTestSuite) TestDuplicateOwnedFiles() {
+	h := &mockHandle{
+		local: newMockDB("local",
+			&mockPackage{name: "foo", version: "1.0-1", files: []alpm.File{{Name: "usr/"}, {Name: "usr/bin/"}, {Name: "usr/bin/foo"}, {Name: "usr/share/foo/data"}}},
+			&mockPackage{name: "foo-git", version: "1.0-1", files: []alpm.File{{Name: "usr/"}, {Name: "usr/bin/"}, {Name: "usr/bin/foo"}, {Name: "usr/share/foo/data"}}},
+			&mockPackage{name: "bar", version: "1.0-1", files: []alpm.File{{Name: "usr/"}, {Name: "usr/bin/"}, {Name: "usr/bin/bar"}}},
+		),
+	}
+
+	// ok
+	d, err := duplicateOwnedFiles(h, "")
+	suite.Nil(err, err)
+	suite.Len(d, 2, "Number of duplicates != 2")
+	suite.Equal([]string{"foo", "foo-git"}, d["/usr/bin/foo"])
+	suite.Equal([]string{"foo", "foo-git"}, d["/usr/share/foo/data"])
+
+	// prefix
+	d, err = duplicateOwnedFiles(h, "/usr/bin/")
+	suite.Nil(err, err)
+	suite.Len(d, 1, "Number of duplicates != 1")
+	suite.Contains(d, "/usr/bin/foo")
+
+	// nok
+	d, err = duplicateOwnedFiles(nil, "")
+	suite.NotNil(err)
+	suite.Len(d, 0, "duplicates not empty")
+}
+
+func (suite *pacseekTestSuite) TestSearchAurExact() {
+	var requestTypes []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		requestTypes = append(requestTypes, r.Form.Get("type"))
+		if r.Form.Get("type") == "info" {
+			if r.Form.Get("arg[]") == "yay" {
+				fmt.Fprint(w, `{"resultcount":1,"results":[{"Name":"yay","Version":"12.0.0-1","Popularity":20.5}],"type":"multiinfo","version":5}`)
+				return
+			}
+			fmt.Fprint(w, `{"resultcount":0,"results":[],"type":"multiinfo","version":5}`)
+			return
+		}
+		fmt.Fprint(w, `{"resultcount":3,"results":[{"Name":"yay"},{"Name":"yay-bin"},{"Name":"yay-git"}],"type":"search","version":5}`)
+	}))
+	defer srv.Close()
+
+	// ok
+	p, err := searchAur(srv.URL, "yay", 5000, "Exact", "Name", 20)
+	suite.Nil(err, err)
+	suite.Equal([]string{"info"}, requestTypes, "info endpoint not used")
+	suite.Len(p, 1, "Number of packages != 1")
+	suite.Equal("yay", p[0].Name)
+	suite.Equal("AUR", p[0].Source)
+	suite.Equal(20.5, p[0].Popularity)
+
+	// nok
+	p, err = searchAur(srv.URL, "nonsense_nonsense", 5000, "Exact", "Name", 20)
+	suite.Nil(err, err)
+	suite.Equal([]Package{}, p, "[]Packages not empty")
+
+	p, err = searchAur("nonsense", "yay", 5000, "Exact", "Name", 20)
+	suite.NotNil(err, err)
+	suite.Equal([]Package{}, p, "[]Packages not empty")
+}
+
+func (suite *pacseekTestSuite) TestRegisterSyncDBs() {
+	registered := []string{}
+	register := func(name string, siglevel alpm.SigLevel) (alpm.IDB, error) {
+		if name == "multilib" {
+			return nil, errors.New("invalid database")
+		}
+		registered = append(registered, name)
+		return newMockDB(name), nil
+	}
+
+	// ok
+	w, err := registerSyncDBs(register, []string{"core", "extra"}, false)
+	suite.Nil(err, err)
+	suite.Len(w, 0, "warnings not empty")
+	suite.Equal([]string{"core", "extra"}, registered)
+
+	// abort
+	registered = []string{}
+	_, err = registerSyncDBs(register, []string{"core", "multilib", "extra"}, false)
+	suite.NotNil(err)
+	suite.Equal("failed to register repo 'multilib': invalid database", err.Error())
+	suite.Equal([]string{"core"}, registered)
+
+	// skip
+	registered = []string{}
+	w, err = registerSyncDBs(register, []string{"core", "multilib", "extra"}, true)
+	suite.Nil(err, err)
+	suite.Equal([]string{"failed to register repo 'multilib': invalid database"}, w)
+	suite.Equal([]string{"core", "extra"}, registered)
+}
+
+func (suite *pacseekTestSuite) TestPackageDownloadURLs() {
+	conf, err := pconf.Parse(`
+[options]
+Architecture = aarch64
+
+[core]
+Server = https://mirror.example.org/$repo/os/$arch
+Server = https://other.example.org/archlinux/$arch/$repo/
+`)
+	suite.Nil(err, err)
+
+	// ok
+	u := packageDownloadURLs(conf, "core", "glibc-2.38-7-aarch64.pkg.tar.zst")
+	suite.Equal([]string{
+		"https://mirror.example.org/core/os/aarch64/glibc-2.38-7-aarch64.pkg.tar.zst",
+		"https://other.example.org/archlinux/aarch64/core/glibc-2.38-7-aarch64.pkg.tar.zst",
+	}, u)
+
+	// auto
+	conf.Architecture = []string{"auto"}
+	u = packageDownloadURLs(conf, "core", "glibc.pkg.tar.zst")
+	suite.Equal("https://mirror.example.org/core/os/"+util.ResolveArchitecture("auto")+"/glibc.pkg.tar.zst", u[0])
+
+	// nok
+	u = packageDownloadURLs(conf, "nonsense", "glibc.pkg.tar.zst")
+	suite.Equal([]string{}, u, "URL's not empty")
+}
+
+func (suite *pacseekTestSuite) TestSearchAurKeywords() {
+	var by string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		by = r.Form.Get("by")
+		if r.Form.Get("type") == "info" {
+			fmt.Fprint(w, `{"resultcount":1,"results":[{"Name":"pacseek","Keywords":["pacman","tui","aur"]}],"type":"multiinfo","version":5}`)
+			return
+		}
+		fmt.Fprint(w, `{"resultcount":2,"results":[{"Name":"pacseek","Keywords":["pacman","tui"]},{"Name":"yay","Keywords":["aur","helper"]}],"type":"search","version":5}`)
+	}))
+	defer srv.Close()
+
+	// ok
+	p, err := searchAur(srv.URL, "tui", 5000, "StartsWith", "Keywords", 20)
+	suite.Nil(err, err)
+	suite.Equal("keywords", by, "keyword search not requested")
+	suite.Len(p, 1, "Number of packages != 1")
+	suite.Equal("pacseek", p[0].Name)
+
+	p, err = searchAur(srv.URL, "tui", 5000, "Contains", "Keywords", 20)
+	suite.Nil(err, err)
+	suite.Len(p, 2, "Number of packages != 2")
+
+	i := infoAur(srv.URL, 5000, "pacseek")
+	suite.Equal("", i.Error, "error not empty")
+	suite.Equal([]string{"pacman", "tui", "aur"}, i.Results[0].Keywords)
+
+	// repo packages don't have keywords
+	h := &mockHandle{
+		sync:  []*mockDB{newMockDB("extra", &mockPackage{name: "tuifoo", version: "1.0-1"})},
+		local: newMockDB("local"),
+	}
+	r, _, err := searchRepos(h, "tui", "StartsWith", "Keywords", 20, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal([]Package{}, r, "[]Packages not empty")
+}
+
+func (suite *pacseekTestSuite) TestAreInstalled() {
+	h := &mockHandle{
+		local: newMockDB("local",
+			&mockPackage{name: "glibc", version: "2.38-7"},
+			&mockPackage{name: "yay", version: "12.0.0-1"},
+		),
+	}
+
+	// ok
+	r := areInstalled(h, []string{"glibc", "yay", "nonsense_nonsense"})
+	suite.Equal(map[string]bool{"glibc": true, "yay": true, "nonsense_nonsense": false}, r)
+	suite.Equal(1, h.localCalls, "local db fetched more than once")
+
+	// nok
+	r = areInstalled(&mockHandle{}, []string{"glibc"})
+	suite.Len(r, 0, "map not empty")
+}
+
+func (suite *pacseekTestSuite) TestBrokenDependencies() {
+	h := &mockHandle{
+		local: newMockDB("local",
+			&mockPackage{name: "glibc", version: "2.38-7"},
+			&mockPackage{name: "libfoo", version: "1.0-1", provides: mockDeps("libfoo.so=1-64")},
+			&mockPackage{name: "foo", version: "1.0-1", depends: mockDeps("glibc>=2.38", "libfoo.so=1-64")},
+			&mockPackage{name: "bar", version: "1.0-1", depends: mockDeps("glibc>=2.39", "libbar.so=2-64", "foo")},
+		),
+	}
+
+	// ok
+	b, err := brokenDependencies(h)
+	suite.Nil(err, err)
+	suite.Equal([]string{"bar requires glibc>=2.39", "bar requires libbar.so=2-64"}, b)
+
+	// nok
+	b, err = brokenDependencies(nil)
+	suite.NotNil(err)
+	suite.Len(b, 0, "broken dependencies not empty")
+}
+
+func (suite *pacseekTestSuite) TestGetUpgradableReplaced() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "foo", version: "1.1-1"},
+			&mockPackage{name: "newbar", version: "2.0-1", replaces: mockDeps("bar", "newbar")},
+		)},
+		local: newMockDB("local",
+			&mockPackage{name: "foo", version: "1.0-1"},
+			&mockPackage{name: "bar", version: "1.0-1"},
+		),
+	}
+
+	// ok
+	up, nf := getUpgradable(h, UpgradeOptions{IncludeReplaced: true}, IgnoreRules{})
+	suite.Len(nf, 0, "not found list not empty")
+	suite.Len(up, 2, "Number of upgrades != 2")
+	suite.Equal("foo", up[0].Name)
+	suite.Equal("upgrade", up[0].Status)
+	suite.Equal("bar", up[1].Name)
+	suite.Equal("replaced", up[1].Status)
+	suite.Equal("newbar", up[1].ReplacedBy)
+	suite.Equal("1.0-1", up[1].LocalVersion)
+
+	// replacements not requested
+	up, nf = getUpgradable(h, UpgradeOptions{}, IgnoreRules{})
+	suite.Equal([]string{"bar"}, packageNames(nf))
+	suite.Len(up, 2, "Number of upgrades != 2")
+	suite.Equal("upgrade", up[1].Status)
+}
+
+func (suite *pacseekTestSuite) TestLimitDependencies() {
+	deps := []string{}
+	for i := 0; i < 300; i++ {
+		deps = append(deps, fmt.Sprintf("texlive-dep%d", i))
+	}
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "texlive-full", version: "1.0-1", depends: mockDeps(deps...), optDepends: mockDeps("perl-tk", "ruby")},
+			&mockPackage{name: "small", version: "1.0-1", depends: mockDeps("glibc")},
+		)},
+		local: newMockDB("local"),
+	}
+	r := infoPacman(h, false, "texlive-full", "small").Results
+	suite.Len(r, 2, "Results not 2")
+
+	// cap applied
+	limitDependencies(100, r...)
+	suite.Len(r[0].Depends, 100, "Number of dependencies != 100")
+	suite.Len(r[0].OptDepends, 0, "Number of optional dependencies != 0")
+	suite.Equal(202, r[0].OmittedDepends, "Number of omitted dependencies != 202")
+	suite.Equal([]string{"glibc"}, r[1].Depends)
+	suite.Equal(0, r[1].OmittedDepends, "Number of omitted dependencies != 0")
+
+	// unlimited
+	r = infoPacman(h, false, "texlive-full").Results
+	limitDependencies(0, r...)
+	suite.Len(r[0].Depends, 300, "Number of dependencies != 300")
+	suite.Equal(0, r[0].OmittedDepends, "Number of omitted dependencies != 0")
+}
+
+func (suite *pacseekTestSuite) TestSearchReposBroad() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "vim", version: "9.1-1", desc: "Vi Improved, a highly configurable text editor"},
+			&mockPackage{name: "neovim", version: "0.10-1", desc: "Fork of Vim aiming to improve user experience", provides: mockDeps("vi")},
+			&mockPackage{name: "nano", version: "8.0-1", desc: "Pico editor clone with enhancements"},
+			&mockPackage{name: "gvim", version: "9.1-1", desc: "Vi Improved, graphical version", provides: mockDeps("vim=9.1")},
+		)},
+		local: newMockDB("local"),
+	}
+
+	// name, provides and description
+	p, _, err := searchRepos(h, "vi", "StartsWith", "Broad", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Len(p, 3, "Number of packages != 3")
+	fields := map[string]string{}
+	for _, pkg := range p {
+		fields[pkg.Name] = pkg.MatchedField
+	}
+	suite.Equal(map[string]string{"vim": "Name", "neovim": "Provides", "gvim": "Provides"}, fields)
+
+	p, _, err = searchRepos(h, "editor", "Contains", "Broad", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Len(p, 2, "Number of packages != 2")
+	for _, pkg := range p {
+		suite.Equal("Description", pkg.MatchedField)
+	}
+
+	// max results
+	p, _, err = searchRepos(h, "vi", "Contains", "Broad", 2, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Len(p, 2, "Number of packages != 2")
+
+	// name only
+	p, _, err = searchRepos(h, "vi", "StartsWith", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Len(p, 1, "Number of packages != 1")
+	suite.Equal("Name", p[0].MatchedField)
+}
+
+func (suite *pacseekTestSuite) TestGetUpgradableNotFoundSorted() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("core",
+			&mockPackage{name: "glibc", version: "2.39-1"},
+		)},
+		local: newMockDB("local",
+			&mockPackage{name: "yay", version: "12.0-1"},
+			&mockPackage{name: "glibc", version: "2.39-1"},
+			&mockPackage{name: "brave-bin", version: "1.0-1"},
+			&mockPackage{name: "paru", version: "2.0-1"},
+		),
+	}
+
+	_, nf := getUpgradable(h, UpgradeOptions{}, IgnoreRules{})
+	suite.Equal([]string{"brave-bin", "paru", "yay"}, packageNames(nf), "not found list not sorted")
+	for _, pkg := range nf {
+		suite.Equal("local", pkg.Source)
+		suite.True(pkg.IsInstalled)
+	}
+}
+
+func (suite *pacseekTestSuite) TestComputeMakeRequiredBy() {
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("extra",
+				&mockPackage{name: "cmake", version: "3.30-1", provides: mockDeps("cmake-bin=3.30")},
+				&mockPackage{name: "kdevelop", version: "24.0-1", depends: mockDeps("cmake"), makeDepends: mockDeps("extra-cmake-modules")},
+				&mockPackage{name: "qt6-base", version: "6.7-1", makeDepends: mockDeps("cmake>=3.20", "ninja")},
+				&mockPackage{name: "llvm", version: "18.0-1", makeDepends: mockDeps("cmake-bin")},
+			),
+			newMockDB("multilib",
+				&mockPackage{name: "lib32-llvm", version: "18.0-1", makeDepends: mockDeps("cmake", "lib32-gcc-libs")},
+			),
+		},
+		local: newMockDB("local"),
+	}
+
+	// ok
+	rb, err := computeMakeRequiredBy(h, "cmake")
+	suite.Nil(err, err)
+	suite.Equal([]string{"lib32-llvm", "llvm", "qt6-base"}, rb)
+
+	// no make dependants
+	rb, err = computeMakeRequiredBy(h, "kdevelop")
+	suite.Nil(err, err)
+	suite.Equal([]string{}, rb)
+
+	// nok
+	_, err = computeMakeRequiredBy(nil, "cmake")
+	suite.NotNil(err, "nil handle did not return an error")
+}
+
+func (suite *pacseekTestSuite) TestExportAndRestoreInstalled() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("core",
+			&mockPackage{name: "linux", version: "6.10-1"},
+			&mockPackage{name: "glibc", version: "2.39-1"},
+			&mockPackage{name: "vim", version: "9.1-1"},
+		)},
+		local: newMockDB("local",
+			&mockPackage{name: "vim", version: "9.1-1", reason: alpm.PkgReasonExplicit},
+			&mockPackage{name: "glibc", version: "2.39-1", reason: alpm.PkgReasonDepend},
+			&mockPackage{name: "yay", version: "12.0-1", reason: alpm.PkgReasonExplicit},
+			&mockPackage{name: "linux", version: "6.10-1", reason: alpm.PkgReasonExplicit},
+		),
+	}
+
+	// export
+	list, err := exportInstalledList(h)
+	suite.Nil(err, err)
+	suite.Equal([]string{"linux", "vim", "yay"}, list)
+
+	// restore
+	cmds, err := restoreCommands(h, list, "sudo pacman -S --needed", "yay -S --needed {pkg} --noconfirm")
+	suite.Nil(err, err)
+	suite.Equal([]string{"sudo pacman -S --needed linux vim", "yay -S --needed yay --noconfirm"}, cmds)
+
+	// no foreign packages
+	cmds, err = restoreCommands(h, []string{"vim"}, "sudo pacman -S --needed", "yay -S --needed")
+	suite.Nil(err, err)
+	suite.Equal([]string{"sudo pacman -S --needed vim"}, cmds)
+
+	// nok
+	_, err = exportInstalledList(nil)
+	suite.NotNil(err, "nil handle did not return an error")
+	_, err = restoreCommands(nil, list, "", "")
+	suite.NotNil(err, "nil handle did not return an error")
+}
+
+func (suite *pacseekTestSuite) TestSearchReposPreferNameMatches() {
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("core",
+				&mockPackage{name: "bash", version: "5.2-1", desc: "The GNU Bourne Again shell"},
+				&mockPackage{name: "coreutils", version: "9.5-1", desc: "The basic file, shell and text manipulation utilities"},
+			),
+			newMockDB("extra",
+				&mockPackage{name: "shellcheck", version: "0.10-1", desc: "Shell script analysis tool"},
+				&mockPackage{name: "zsh", version: "5.9-1", desc: "A very advanced and programmable command interpreter (shell)"},
+				&mockPackage{name: "shellharden", version: "4.3-1", desc: "The corrective bash syntax highlighter"},
+			),
+		},
+		local: newMockDB("local"),
+	}
+
+	// name matches survive truncation
+	p, _, err := searchRepos(h, "shell", "Contains", "Name & Description", 3, SearchOptions{PreferNameMatches: true})
+	suite.Nil(err, err)
+	suite.Len(p, 3, "Number of packages != 3")
+	suite.Equal([]string{"shellcheck", "shellharden", "bash"}, packageNames(p))
+	suite.Equal("Name", p[0].MatchedField)
+	suite.Equal("Name", p[1].MatchedField)
+	suite.Equal("Description", p[2].MatchedField)
+
+	// no duplicates
+	p, _, err = searchRepos(h, "shell", "Contains", "Name & Description", 10, SearchOptions{PreferNameMatches: true})
+	suite.Nil(err, err)
+	suite.Len(p, 5, "Number of packages != 5")
+
+	// db order without preference
+	p, _, err = searchRepos(h, "shell", "Contains", "Name & Description", 3, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal([]string{"bash", "coreutils", "shellcheck"}, packageNames(p))
+}
+
+func (suite *pacseekTestSuite) TestSearchReposArchitecture() {
+	conf, err := pconf.Parse(`
+[options]
+Architecture = aarch64
+`)
+	suite.Nil(err, err)
+	arch := configuredArchitecture(conf.Architecture)
+	suite.Equal("aarch64", arch)
+
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("core",
+			&mockPackage{name: "linux", version: "6.10-1", arch: "x86_64"},
+			&mockPackage{name: "linux-aarch64", version: "6.10-1", arch: "aarch64"},
+			&mockPackage{name: "linux-firmware", version: "20240809-1", arch: "any"},
+		)},
+		local: newMockDB("local"),
+	}
+
+	// target architecture
+	p, _, err := searchRepos(h, "linux", "StartsWith", "Name", 10, SearchOptions{Arches: resultFilter{}.searchArches(arch)})
+	suite.Nil(err, err)
+	suite.Equal([]string{"linux-aarch64", "linux-firmware"}, packageNames(p))
+
+	// no filter
+	p, _, err = searchRepos(h, "linux", "StartsWith", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Len(p, 3, "Number of packages != 3")
+
+	// auto
+	conf.Architecture = []string{"auto"}
+	suite.Equal(util.ResolveArchitecture("auto"), configuredArchitecture(conf.Architecture))
+}
+
+func (suite *pacseekTestSuite) TestSortPackages() {
+	pkgs := []Package{
+		{Name: "yay-bin", Source: "AUR", NumVotes: 500},
+		{Name: "yay", Source: "AUR", NumVotes: 2000},
+		{Name: "yay-git", Source: "AUR", NumVotes: 100},
+		{Name: "paru-yay", Source: "AUR", NumVotes: 3000},
+		{Name: "yaycli", Source: "extra"},
+		{Name: "yay-bin", Source: "chaotic-aur", NumVotes: 500},
+	}
+
+	// relevance -> source -> votes -> name
+	sortPackages(pkgs, SortSpec{
+		Keys:           []SortKey{SortByRelevance, SortBySourcePriority, SortByVotes, SortByName},
+		Term:           "yay",
+		SourcePriority: []string{"extra", "AUR"},
+	})
+	names := []string{}
+	for _, pkg := range pkgs {
+		names = append(names, pkg.Source+"/"+pkg.Name)
+	}
+	suite.Equal([]string{"AUR/yay", "extra/yaycli", "AUR/yay-bin", "AUR/yay-git", "chaotic-aur/yay-bin", "AUR/paru-yay"}, names)
+
+	// ties keep their order
+	pkgs = []Package{
+		{Name: "b", Popularity: 1},
+		{Name: "a", Popularity: 2},
+		{Name: "c", Popularity: 1},
+	}
+	sortPackages(pkgs, SortSpec{Keys: []SortKey{SortByPopularity}})
+	suite.Equal([]string{"a", "b", "c"}, packageNames(pkgs))
+
+	// name only
+	pkgs = []Package{{Name: "b"}, {Name: "c"}, {Name: "a"}}
+	sortPackages(pkgs, SortSpec{Keys: []SortKey{SortByName}})
+	suite.Equal([]string{"a", "b", "c"}, packageNames(pkgs))
+}
+
+func (suite *pacseekTestSuite) TestInfoFromFile() {
+	pkginfo := `# Generated by makepkg 6.1.0
+# using fakeroot version 1.36
+pkgname = pacseek
+pkgbase = pacseek
+xdata = pkgtype=pkg
+pkgver = 1.8.2-1
+pkgdesc = A terminal user interface for searching and installing Arch Linux packages
+url = https://github.com/moson-mo/pacseek
+builddate = 1700000000
+packager = Unknown Packager
+size = 9437184
+arch = x86_64
+license = MIT
+depend = pacman
+depend = glibc>=2.38
+optdepend = fakeroot: upgrades list
+makedepend = go
+makedepend = git
+provides = pacseek-bin=1.8.2
+conflict = pacseek-git
+`
+	// ok
+	i, err := parsePkginfo([]byte(pkginfo))
+	suite.Nil(err, err)
+	suite.Equal("pacseek", i.Name)
+	suite.Equal("1.8.2-1", i.Version)
+	suite.Equal("file", i.Source)
+	suite.Equal("x86_64", i.Architecture)
+	suite.Equal(1700000000, i.LastModified)
+	suite.Equal([]string{"MIT"}, i.License)
+	suite.Equal([]string{"pacman", "glibc>=2.38"}, i.Depends)
+	suite.Equal([]string{"fakeroot"}, i.OptDepends)
+	suite.Equal([]string{"go", "git"}, i.MakeDepends)
+	suite.Equal([]string{"pacseek-bin=1.8.2"}, i.Provides)
+	suite.Equal([]string{"pacseek-git"}, i.Conflicts)
+
+	// nok
+	_, err = parsePkginfo([]byte("garbage"))
+	suite.NotNil(err, "invalid package info did not return an error")
+	_, err = infoFromFile("/nonsense/pacseek-1.8.2-1-x86_64.pkg.tar.zst")
+	suite.NotNil(err, "missing file did not return an error")
+}
+
+func (suite *pacseekTestSuite) TestGetUpgradableEpochBump() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "foo", version: "1:1.0-1"},
+			&mockPackage{name: "bar", version: "1.1-1"},
+			&mockPackage{name: "baz", version: "3:0.9-1"},
+		)},
+		local: newMockDB("local",
+			&mockPackage{name: "foo", version: "1.0-1"},
+			&mockPackage{name: "bar", version: "1.0-1"},
+			&mockPackage{name: "baz", version: "2:1.0-1"},
+		),
+	}
+
+	up, _ := getUpgradable(h, UpgradeOptions{}, IgnoreRules{})
+	suite.Len(up, 3, "Number of upgrades != 3")
+	bumps := map[string]bool{}
+	for _, u := range up {
+		bumps[u.Name] = u.EpochBump
+	}
+	suite.Equal(map[string]bool{"foo": true, "bar": false, "baz": true}, bumps)
+
+	suite.Equal(0, versionEpoch("1.0-1"))
+	suite.Equal(2, versionEpoch("2:1.0-1"))
+}
+
+func (suite *pacseekTestSuite) TestFilterIgnoredAur() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"resultcount":4,"results":[{"Name":"yay"},{"Name":"yay-bin"},{"Name":"yay-git"},{"Name":"yaycache"}],"type":"search","version":5}`)
+	}))
+	defer srv.Close()
+
+	p, err := searchAur(srv.URL, "yay", 5000, "StartsWith", "Name", 20)
+	suite.Nil(err, err)
+	suite.Len(p, 4, "Number of packages != 4")
+
+	// ok
+	suite.Equal([]string{"yay", "yaycache"}, packageNames(filterIgnoredAur(p, []string{"*-bin", "*-git"})))
+	suite.Equal([]string{"yay-bin", "yay-git"}, packageNames(filterIgnoredAur(p, []string{"yay", "yayc?che"})))
+
+	// no patterns
+	suite.Len(filterIgnoredAur(p, []string{}), 4, "Number of packages != 4")
+}
+
+func (suite *pacseekTestSuite) TestMissingOptDepends() {
+	h := &mockHandle{
+		local: newMockDB("local",
+			&mockPackage{name: "mpv", version: "0.38-1", optDepends: mockDeps("yt-dlp: for video-sharing websites playback", "pipewire-audio: audio output", "vapoursynth: vapoursynth filter")},
+			&mockPackage{name: "yt-dlp", version: "2024.08-1"},
+			&mockPackage{name: "pipewire-pulse", version: "1.2-1", provides: mockDeps("pipewire-audio")},
+		),
+	}
+
+	// ok
+	m, err := missingOptDepends(h, "mpv")
+	suite.Nil(err, err)
+	suite.Equal([]string{"vapoursynth"}, m)
+
+	// nothing missing
+	m, err = missingOptDepends(h, "yt-dlp")
+	suite.Nil(err, err)
+	suite.Len(m, 0, "missing optional dependencies not empty")
+
+	// nok
+	_, err = missingOptDepends(h, "vlc")
+	suite.NotNil(err, "package not installed did not return an error")
+	_, err = missingOptDepends(nil, "mpv")
+	suite.NotNil(err, "nil handle did not return an error")
+}
+
+func (suite *pacseekTestSuite) TestDiffInstalledSets() {
+	// overlapping
+	a, b, c := diffInstalledSets([]string{"vim", "linux", "firefox", "yay"}, []string{"yay", "linux-lts", "vim", "chromium", "vim"})
+	suite.Equal([]string{"firefox", "linux"}, a)
+	suite.Equal([]string{"chromium", "linux-lts"}, b)
+	suite.Equal([]string{"vim", "yay"}, c)
+
+	// disjoint
+	a, b, c = diffInstalledSets([]string{"b", "a"}, []string{"d", "c"})
+	suite.Equal([]string{"a", "b"}, a)
+	suite.Equal([]string{"c", "d"}, b)
+	suite.Equal([]string{}, c)
+
+	// empty
+	a, b, c = diffInstalledSets([]string{}, []string{})
+	suite.Equal([]string{}, a)
+	suite.Equal([]string{}, b)
+	suite.Equal([]string{}, c)
+}
+
+func (suite *pacseekTestSuite) TestBuildDateMissing() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "foo", version: "1.0-1", buildDate: time.Unix(1700000000, 0)},
+			&mockPackage{name: "foo-nodate", version: "1.0-1"},
+			&mockPackage{name: "foo-epoch", version: "1.0-1", buildDate: time.Unix(0, 0)},
+		)},
+		local: newMockDB("local"),
+	}
+
+	// search
+	p, _, err := searchRepos(h, "foo", "StartsWith", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Len(p, 3, "Number of packages != 3")
+	suite.Equal(1700000000, p[0].LastModified)
+	suite.True(p[0].HasBuildDate)
+	for _, pkg := range p[1:] {
+		suite.Equal(0, pkg.LastModified, "LastModified not 0 for "+pkg.Name)
+		suite.False(pkg.HasBuildDate)
+	}
+
+	// info
+	r := infoPacman(h, false, "foo", "foo-nodate").Results
+	suite.True(r[0].HasBuildDate)
+	suite.Equal(1700000000, r[0].LastModified)
+	suite.False(r[1].HasBuildDate)
+	suite.Equal(0, r[1].LastModified)
+}
+
+func (suite *pacseekTestSuite) TestSearchReposGroup() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "gnome-terminal", version: "3.52-1", groups: []string{"gnome"}},
+			&mockPackage{name: "gnome-console", version: "46.0-1", desc: "A simple user-friendly terminal emulator", groups: []string{"gnome"}},
+			&mockPackage{name: "gnome-shell", version: "46.0-1", groups: []string{"gnome"}},
+			&mockPackage{name: "xfce4-terminal", version: "1.1-1", groups: []string{"xfce4"}},
+			&mockPackage{name: "terminator", version: "2.1-1"},
+		)},
+		local: newMockDB("local",
+			&mockPackage{name: "gnome-terminal", version: "3.52-1"},
+			&mockPackage{name: "xfce4-terminal", version: "1.1-1"},
+		),
+	}
+
+	// ok
+	p, l, err := searchRepos(h, "terminal", "Contains", "Name & Description", 10, SearchOptions{Group: "gnome"})
+	suite.Nil(err, err)
+	suite.Equal([]string{"gnome-terminal", "gnome-console"}, packageNames(p))
+	suite.Equal([]string{"gnome-terminal"}, packageNames(l))
+
+	// unknown group
+	p, _, err = searchRepos(h, "terminal", "Contains", "Name & Description", 10, SearchOptions{Group: "kde-applications"})
+	suite.Nil(err, err)
+	suite.Len(p, 0, "Number of packages != 0")
+
+	// no group
+	p, _, err = searchRepos(h, "terminal", "Contains", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Len(p, 2, "Number of packages != 2")
+}
+
+func (suite *pacseekTestSuite) TestRemovalOrphans() {
+	h := &mockHandle{
+		local: newMockDB("local",
+			&mockPackage{name: "a", version: "1.0-1", reason: alpm.PkgReasonExplicit, depends: mockDeps("c")},
+			&mockPackage{name: "b", version: "1.0-1", reason: alpm.PkgReasonExplicit, depends: mockDeps("c>=1.0", "e")},
+			&mockPackage{name: "c", version: "1.0-1", reason: alpm.PkgReasonDepend, depends: mockDeps("libd")},
+			&mockPackage{name: "d", version: "1.0-1", reason: alpm.PkgReasonDepend, provides: mockDeps("libd=1.0")},
+			&mockPackage{name: "e", version: "1.0-1", reason: alpm.PkgReasonDepend},
+			&mockPackage{name: "f", version: "1.0-1", reason: alpm.PkgReasonExplicit, depends: mockDeps("e")},
+			&mockPackage{name: "g", version: "1.0-1", reason: alpm.PkgReasonDepend},
+		),
+	}
+
+	// removing one of them
+	o, err := removalOrphans(h, []string{"a"})
+	suite.Nil(err, err)
+	suite.Len(o, 0, "orphans not empty")
+	o, err = removalOrphans(h, []string{"b"})
+	suite.Nil(err, err)
+	suite.Len(o, 0, "orphans not empty")
+
+	// removing both
+	o, err = removalOrphans(h, []string{"a", "b"})
+	suite.Nil(err, err)
+	suite.Equal([]string{"c", "d"}, o)
+
+	// nok
+	_, err = removalOrphans(h, []string{"a", "nonsense"})
+	suite.NotNil(err, "package not installed did not return an error")
+	_, err = removalOrphans(nil, []string{"a"})
+	suite.NotNil(err, "nil handle did not return an error")
+}
+
+func (suite *pacseekTestSuite) TestChooseProvider() {
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("extra",
+				&mockPackage{name: "jre-openjdk", version: "22.0-1", provides: mockDeps("java-runtime=22")},
+				&mockPackage{name: "jre17-openjdk", version: "17.0-1", provides: mockDeps("java-runtime=17")},
+				&mockPackage{name: "pipewire-jack", version: "1.2-1", provides: mockDeps("jack")},
+				&mockPackage{name: "jack2", version: "1.9-1", provides: mockDeps("jack")},
+				&mockPackage{name: "gnu-netcat", version: "0.7-1", provides: mockDeps("netcat")},
+				&mockPackage{name: "sh-provider", version: "1.0-1", provides: mockDeps("sh")},
+			),
+		},
+		local: newMockDB("local",
+			&mockPackage{name: "bash", version: "5.2-1", provides: mockDeps("sh")},
+		),
+	}
+
+	// installed provider
+	p, c, err := chooseProvider(h, "sh")
+	suite.Nil(err, err)
+	suite.Equal("bash", p)
+	suite.Len(c, 0, "candidates not empty")
+
+	// single provider
+	p, _, err = chooseProvider(h, "netcat")
+	suite.Nil(err, err)
+	suite.Equal("gnu-netcat", p)
+	p, _, err = chooseProvider(h, "java-runtime>=20")
+	suite.Nil(err, err)
+	suite.Equal("jre-openjdk", p)
+
+	// exact name
+	p, _, err = chooseProvider(h, "jack2")
+	suite.Nil(err, err)
+	suite.Equal("jack2", p)
+
+	// multiple providers
+	p, c, err = chooseProvider(h, "java-runtime")
+	suite.Nil(err, err)
+	suite.Equal("", p)
+	suite.Equal([]string{"jre-openjdk", "jre17-openjdk"}, c)
+
+	// no provider
+	_, _, err = chooseProvider(h, "nonsense")
+	suite.NotNil(err, "missing provider did not return an error")
+	_, _, err = chooseProvider(nil, "sh")
+	suite.NotNil(err, "nil handle did not return an error")
+}
+
+func (suite *pacseekTestSuite) TestResolveArchitecture() {
+	suite.Equal(util.ArchitectureName(runtime.GOARCH), util.ResolveArchitecture("auto"))
+	suite.Equal(util.ArchitectureName(runtime.GOARCH), util.ResolveArchitecture(""))
+	suite.Equal("x86_64", util.ResolveArchitecture("x86_64"))
+	suite.Equal("aarch64", util.ResolveArchitecture("aarch64"))
+
+	suite.Equal("x86_64", util.ArchitectureName("amd64"))
+	suite.Equal("aarch64", util.ArchitectureName("arm64"))
+	suite.Equal("riscv64", util.ArchitectureName("riscv64"))
+}
+
+func (suite *pacseekTestSuite) TestTotalInstalledSize() {
+	h := &mockHandle{
+		local: newMockDB("local",
+			&mockPackage{name: "linux-firmware", version: "20240809-1", isize: 1 << 30},
+			&mockPackage{name: "glibc", version: "2.39-1", isize: 512 << 20},
+			&mockPackage{name: "filesystem", version: "2024.04-1", isize: 0},
+		),
+	}
+
+	// ok
+	s, err := totalInstalledSize(h)
+	suite.Nil(err, err)
+	suite.Equal(int64(1610612736), s)
+	suite.Equal("1.50 GiB", util.FormatSize(s))
+	suite.Equal("512.00 B", util.FormatSize(512))
+	suite.Equal("-2.00 KiB", util.FormatSize(-2048))
+
+	// nok
+	_, err = totalInstalledSize(nil)
+	suite.NotNil(err, "nil handle did not return an error")
+}
+
+func (suite *pacseekTestSuite) TestAdoptedPackages() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "yay", version: "12.3-1"},
+			&mockPackage{name: "pacseek", version: "1.8.2-1"},
+			&mockPackage{name: "vim", version: "9.1-2"},
+		)},
+		local: newMockDB("local",
+			&mockPackage{name: "yay", version: "12.0-1", validation: alpm.ValidationNone},
+			&mockPackage{name: "pacseek", version: "1.8.3-1", validation: alpm.ValidationNone},
+			&mockPackage{name: "vim", version: "9.1-1", validation: alpm.ValidationSHA256Sum | alpm.ValidationSignature},
+			&mockPackage{name: "paru", version: "2.0-1", validation: alpm.ValidationNone},
+		),
+	}
+
+	// ok
+	a, err := adoptedPackages(h)
+	suite.Nil(err, err)
+	suite.Equal(map[string]string{"yay": "extra", "pacseek": "extra"}, a)
+
+	// upgrades
+	up, _ := getUpgradable(h, UpgradeOptions{}, IgnoreRules{})
+	foreign := map[string]bool{}
+	for _, u := range up {
+		foreign[u.Name] = u.WasForeign
+	}
+	suite.Equal(map[string]bool{"yay": true, "vim": false, "paru": false}, foreign)
+
+	// nok
+	_, err = adoptedPackages(nil)
+	suite.NotNil(err, "nil handle did not return an error")
+}
+
+func (suite *pacseekTestSuite) TestRepoMatchCount() {
+	pkgs := []*mockPackage{}
+	for i := 0; i < 50; i++ {
+		pkgs = append(pkgs, &mockPackage{name: fmt.Sprintf("python-%d", i), version: "1.0-1"})
+	}
+	pkgs = append(pkgs, &mockPackage{name: "perl", version: "5.38-1"})
+	h := &mockHandle{
+		sync:  []*mockDB{newMockDB("extra", pkgs...)},
+		local: newMockDB("local", &mockPackage{name: "python-1", version: "1.0-1"}),
+	}
+
+	// broad term
+	p, l, err := searchRepos(h, "py", "StartsWith", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal(10, len(p)+len(l), "Number of packages != 10")
+	c := repoMatchCount(h, "py", "StartsWith", "Name", SearchOptions{})
+	suite.Equal(51, c, "match count != 51")
+	suite.Greater(c, 10, "match count not greater than max results")
+
+	// filtered
+	suite.Equal(49, repoMatchCount(h, "py", "StartsWith", "Name", SearchOptions{Installed: NotInstalledOnly}), "match count != 49")
+	suite.Equal(1, repoMatchCount(h, "perl", "StartsWith", "Name", SearchOptions{}))
+	suite.Equal(0, repoMatchCount(nil, "py", "StartsWith", "Name", SearchOptions{}))
+}
+
+type mockReleasable struct {
+	name     string
+	released bool
+}
+
+func (r *mockReleasable) Release() error {
+	r.released = true
+	return nil
+}
+
+func (suite *pacseekTestSuite) TestSwapHandle() {
+	old := &mockReleasable{name: "old"}
+
+	// failed reload
+	h, _, err := swapHandle(old, func() (*mockReleasable, []string, error) {
+		return nil, nil, errors.New("failed to initialize alpm library")
+	})
+	suite.NotNil(err, "failed reload did not return an error")
+	suite.Equal(old, h, "old handle not returned")
+	suite.False(old.released, "old handle released")
+
+	// ok
+	h, w, err := swapHandle(old, func() (*mockReleasable, []string, error) {
+		return &mockReleasable{name: "new"}, []string{"failed to register repo 'multilib': invalid database"}, nil
+	})
+	suite.Nil(err, err)
+	suite.Equal("new", h.name)
+	suite.Len(w, 1, "warnings not passed")
+	suite.True(old.released, "old handle not released")
+
+	// no previous handle
+	h, _, err = swapHandle(nil, func() (*mockReleasable, []string, error) {
+		return &mockReleasable{name: "new"}, nil, nil
+	})
+	suite.Nil(err, err)
+	suite.Equal("new", h.name)
+}
+
+func (suite *pacseekTestSuite) TestSearchByVersion() {
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("core", &mockPackage{name: "glibc", version: "2.39-1"}),
+			newMockDB("core-testing", &mockPackage{name: "glibc", version: "2.40-1"}),
+		},
+		local: newMockDB("local", &mockPackage{name: "glibc", version: "2.39-1"}),
+	}
+
+	sources := func(p []Package) []string {
+		s := []string{}
+		for _, pkg := range p {
+			s = append(s, pkg.Source)
+		}
+		return s
+	}
+
+	for _, tc := range []struct {
+		op       string
+		version  string
+		expected []string
+	}{
+		{"<", "2.40", []string{"core"}},
+		{"<=", "2.40-1", []string{"core", "core-testing"}},
+		{"=", "2.39-1", []string{"core"}},
+		{">=", "2.39", []string{"core", "core-testing"}},
+		{">", "2.39-1", []string{"core-testing"}},
+		{">", "2.40-1", []string{}},
+	} {
+		p, err := searchByVersion(h, "glibc", tc.op, tc.version)
+		suite.Nil(err, err)
+		suite.Equal(tc.expected, sources(p), "glibc"+tc.op+tc.version)
+	}
+
+	// nok
+	_, err := searchByVersion(h, "glibc", "=>", "2.39")
+	suite.NotNil(err, "invalid operator did not return an error")
+	_, err = searchByVersion(nil, "glibc", ">=", "2.39")
+	suite.NotNil(err, "nil handle did not return an error")
+}
+
+func (suite *pacseekTestSuite) TestSideEffectUpgrades() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "a", version: "1.0-1", depends: mockDeps("b>=2.0", "c", "d")},
+			&mockPackage{name: "b", version: "2.1-1", depends: mockDeps("e>=1.5")},
+			&mockPackage{name: "c", version: "1.0-1", depends: mockDeps("f")},
+			&mockPackage{name: "d", version: "3.0-1"},
+			&mockPackage{name: "e", version: "1.6-1"},
+			&mockPackage{name: "f", version: "1.0-1"},
+		)},
+		local: newMockDB("local",
+			&mockPackage{name: "b", version: "1.0-1"},
+			&mockPackage{name: "d", version: "2.0-1"},
+			&mockPackage{name: "e", version: "1.0-1"},
+		),
+	}
+
+	// installing "a" forces an upgrade of "b" which requires a newer "e"
+	u, err := sideEffectUpgrades(h, "a")
+	suite.Nil(err, err)
+	suite.Equal([]string{"b", "e"}, u)
+
+	// no upgrades
+	u, err = sideEffectUpgrades(h, "c")
+	suite.Nil(err, err)
+	suite.Len(u, 0, "upgrades not empty")
+
+	// nok
+	_, err = sideEffectUpgrades(h, "nonsense")
+	suite.NotNil(err, "unknown package did not return an error")
+	_, err = sideEffectUpgrades(nil, "a")
+	suite.NotNil(err, "nil handle did not return an error")
+}
+
+func (suite *pacseekTestSuite) TestExportCSV() {
+	// packages
+	b, err := exportCSV([]Package{
+		{Name: "yay", Source: "AUR", IsInstalled: true, LastModified: 1700000000, Popularity: 12.5, NumVotes: 2000},
+		{Name: "vim", Source: "extra", LastModified: 1710000000, Popularity: math.MaxFloat64},
+	})
+	suite.Nil(err, err)
+	suite.Equal("Name,Source,Installed,LastModified,Popularity,NumVotes\n"+
+		"yay,AUR,true,1700000000,12.5,2000\n"+
+		"vim,extra,false,1710000000,,0\n", string(b))
+
+	// upgrades
+	up := Upgrade{Status: "upgrade", DownloadSize: 1024, InstalledSizeDelta: -512}
+	up.Name = "vim"
+	up.Source = "extra"
+	up.LocalVersion = "9.0-1"
+	up.Version = "9.1-1"
+	up.Description = `Vi Improved, a highly configurable, "improved" version of vi`
+	b, err = exportUpgradesCSV([]Upgrade{up})
+	suite.Nil(err, err)
+	suite.Equal("Name,Source,LocalVersion,Version,Status,DownloadSize,InstalledSizeDelta,Description\n"+
+		`vim,extra,9.0-1,9.1-1,upgrade,1024,-512,"Vi Improved, a highly configurable, ""improved"" version of vi"`+"\n", string(b))
+
+	// empty
+	b, err = exportCSV([]Package{})
+	suite.Nil(err, err)
+	suite.Equal("Name,Source,Installed,LastModified,Popularity,NumVotes\n", string(b))
+}
+
+func (suite *pacseekTestSuite) TestAurMalformedResults() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"resultcount":3,"results":[{"Name":"yay","Version":"12.0.0-1"},{"Name":"","Version":"1.0-1"},{"Version":"2.0-1","Description":"no name"}],"type":"search","version":5}`)
+	}))
+	defer srv.Close()
+
+	// search
+	p, err := searchAur(srv.URL, "yay", 5000, "Contains", "Name", 20)
+	suite.Nil(err, err)
+	suite.Equal([]string{"yay"}, packageNames(p))
+
+	// info
+	i := infoAur(srv.URL, 5000, "yay")
+	suite.Equal("", i.Error, "error not empty")
+	suite.Len(i.Results, 1, "Number of results != 1")
+	suite.Equal(2, i.Skipped, "Number of skipped records != 2")
+}
+
+func (suite *pacseekTestSuite) TestSignedRepos() {
+	conf, err := pconf.Parse(`
+[options]
+SigLevel = Required DatabaseOptional
+
+[core]
+
+[custom]
+SigLevel = Optional TrustAll
+
+[unsigned]
+SigLevel = PackageNever
+`)
+	suite.Nil(err, err)
+
+	signed := signedRepos(conf)
+	suite.Equal(map[string]bool{"core": true, "custom": false, "unsigned": false}, signed)
+
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("core", &mockPackage{name: "foo", version: "1.0-1"}),
+			newMockDB("custom", &mockPackage{name: "foo-custom", version: "1.0-1"}),
+		},
+		local: newMockDB("local"),
+	}
+
+	// annotated
+	p, _, err := searchRepos(h, "foo", "StartsWith", "Name", 10, SearchOptions{SignedRepos: signed})
+	suite.Nil(err, err)
+	suite.Len(p, 2, "Number of packages != 2")
+	suite.True(p[0].SignedRepo, "core not signed")
+	suite.False(p[1].SignedRepo, "custom signed")
+
+	// global default
+	conf, err = pconf.Parse(`
+[options]
+[core]
+`)
+	suite.Nil(err, err)
+	suite.Equal(map[string]bool{"core": true}, signedRepos(conf))
+}
+
+func (suite *pacseekTestSuite) TestRecentlyInstalled() {
+	h := &mockHandle{
+		local: newMockDB("local",
+			&mockPackage{name: "old", version: "1.0-1", installDate: time.Unix(1600000000, 0)},
+			&mockPackage{name: "unknown", version: "1.0-1"},
+			&mockPackage{name: "newest", version: "1.0-1", installDate: time.Unix(1700000000, 0)},
+			&mockPackage{name: "new", version: "1.0-1", installDate: time.Unix(1690000000, 0)},
+		),
+	}
+
+	// ok
+	suite.Equal([]string{"newest", "new"}, packageNames(recentlyInstalled(h, 2)))
+	suite.Equal([]string{"newest", "new", "old", "unknown"}, packageNames(recentlyInstalled(h, 10)))
+
+	// nok
+	suite.Len(recentlyInstalled(nil, 10), 0, "packages not empty")
+}
+
+func (suite *pacseekTestSuite) TestSysUpgradePreview() {
+	newHandle := func() *mockTransHandle {
+		return &mockTransHandle{mockHandle: &mockHandle{
+			sync:  []*mockDB{newMockDB("core", &mockPackage{name: "glibc", version: "2.39-1"})},
+			local: newMockDB("local", &mockPackage{name: "glibc", version: "2.39-1"}),
+		}}
+	}
+
+	// clean upgrade
+	h := newHandle()
+	up, err := sysUpgradePreview(h)
+	suite.Nil(err, err)
+	suite.Len(up, 0, "upgrades not empty")
+	suite.Equal(alpm.TransFlagNoLock, h.initFlags, "transaction not initialized without lock")
+	suite.True(h.released, "transaction not released")
+
+	// conflict
+	h = newHandle()
+	h.upgradeErr = errors.New("conflicting dependencies")
+	_, err = sysUpgradePreview(h)
+	suite.EqualError(err, "failed to compute system upgrade: conflicting dependencies")
+	suite.True(h.released, "transaction not released")
+
+	// transaction can't be initialized
+	h = newHandle()
+	h.initErr = errors.New("unable to lock database")
+	_, err = sysUpgradePreview(h)
+	suite.EqualError(err, "failed to initialize transaction: unable to lock database")
+	suite.False(h.released, "transaction released without being initialized")
+
+	// nok
+	_, err = sysUpgradePreview(nil)
+	suite.NotNil(err, "nil handle did not return an error")
+}
+
+func (suite *pacseekTestSuite) TestSearchReposExcludeSources() {
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("core", &mockPackage{name: "wine-mono", version: "9.2-1"}),
+			newMockDB("multilib", &mockPackage{name: "wine", version: "9.16-1"}),
+		},
+		local: newMockDB("local", &mockPackage{name: "wine-custom", version: "1.0-1"}),
+	}
+
+	// excluded repository
+	p, l, err := searchRepos(h, "wine", "StartsWith", "Name", 10, SearchOptions{ExcludeSources: []string{"multilib"}})
+	suite.Nil(err, err)
+	suite.Equal([]string{"wine-mono"}, packageNames(p))
+	suite.Equal([]string{"wine-custom"}, packageNames(l))
+	suite.Equal(2, repoMatchCount(h, "wine", "StartsWith", "Name", SearchOptions{ExcludeSources: []string{"multilib"}}))
+
+	// excluded local db
+	p, l, err = searchRepos(h, "wine", "StartsWith", "Name", 10, SearchOptions{ExcludeSources: []string{"local", "core"}})
+	suite.Nil(err, err)
+	suite.Equal([]string{"wine"}, packageNames(p))
+	suite.Len(l, 0, "Number of local packages != 0")
+
+	// everything excluded
+	p, l, err = searchRepos(h, "wine", "StartsWith", "Name", 10, SearchOptions{ExcludeSources: []string{"core", "multilib", "local", "aur"}})
+	suite.Nil(err, err)
+	suite.Len(p, 0, "Number of packages != 0")
+	suite.Len(l, 0, "Number of local packages != 0")
+}
+
+func (suite *pacseekTestSuite) TestInfoPacmanLocalVersion() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "firefox", version: "131.0-1"},
+			&mockPackage{name: "thunderbird", version: "128.3-1"},
+		)},
+		local: newMockDB("local",
+			&mockPackage{name: "firefox", version: "130.0.1-1"},
+			&mockPackage{name: "my-tool", version: "0.1-1"},
+		),
+	}
+
+	r := infoPacman(h, false, "firefox", "thunderbird", "my-tool")
+	suite.Equal("", r.Error)
+	suite.Len(r.Results, 3, "Number of results != 3")
+
+	// installed, newer version available
+	suite.Equal("extra", r.Results[0].Source)
+	suite.Equal("131.0-1", r.Results[0].Version)
+	suite.Equal("130.0.1-1", r.Results[0].LocalVersion)
+
+	// not installed
+	suite.Equal("128.3-1", r.Results[1].Version)
+	suite.Equal("", r.Results[1].LocalVersion)
+
+	// local only
+	suite.Equal("local", r.Results[2].Source)
+	suite.Equal(r.Results[2].Version, r.Results[2].LocalVersion)
+}
+
+func (suite *pacseekTestSuite) TestLeafExplicitPackages() {
+	h := &mockHandle{
+		local: newMockDB("local",
+			&mockPackage{name: "firefox", version: "131.0-1", reason: alpm.PkgReasonExplicit},
+			&mockPackage{name: "gtk3", version: "3.24-1", reason: alpm.PkgReasonExplicit, requiredBy: []string{"firefox"}},
+			&mockPackage{name: "nss", version: "3.105-1", reason: alpm.PkgReasonDepend, requiredBy: []string{"firefox"}},
+			&mockPackage{name: "orphan-lib", version: "1.0-1", reason: alpm.PkgReasonDepend},
+			&mockPackage{name: "htop", version: "3.3-1", reason: alpm.PkgReasonExplicit},
+		),
+	}
+
+	// ok
+	suite.Equal([]string{"firefox", "htop"}, packageNames(leafExplicitPackages(h)))
+
+	// nok
+	suite.Len(leafExplicitPackages(nil), 0, "Number of packages != 0")
+}
+
+func (suite *pacseekTestSuite) TestPostProcessors() {
+	pkgs := []Package{
+		{Name: "yay", Source: "AUR"},
+		{Name: "pacman", Source: "core"},
+		{Name: "paru", Source: "AUR"},
+	}
+
+	pp := &postProcessors{}
+
+	// no processors
+	suite.Equal(pkgs, pp.apply(pkgs))
+
+	// filter and annotate (in order of registration)
+	pp.register(func(pkgs []Package) []Package {
+		ret := []Package{}
+		for _, pkg := range pkgs {
+			if pkg.Source == "AUR" {
+				ret = append(ret, pkg)
+			}
+		}
+		return ret
+	})
+	pp.register(func(pkgs []Package) []Package {
+		for i := range pkgs {
+			pkgs[i].IsInstalled = pkgs[i].Name == "yay"
+		}
+		return pkgs
+	})
+	pp.register(nil)
+
+	r := pp.apply(pkgs)
+	suite.Equal([]string{"yay", "paru"}, packageNames(r))
+	suite.True(r[0].IsInstalled)
+	suite.False(r[1].IsInstalled)
+	suite.Len(pp.processors, 2, "Number of processors != 2")
+}
+
+func (suite *pacseekTestSuite) TestPackageSuccessor() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "pipewire-pulse", version: "1.2-1", replaces: mockDeps("pulseaudio"), provides: mockDeps("pulseaudio")},
+			&mockPackage{name: "pulseaudio-alt", version: "1.0-1", replaces: mockDeps("pulseaudio")},
+			&mockPackage{name: "kde-cli-tools", version: "6.1-1", replaces: mockDeps("kde-cli-tools5")},
+			&mockPackage{name: "foo", version: "1.0-1", replaces: mockDeps("foo-git")},
+		)},
+		local: newMockDB("local",
+			&mockPackage{name: "pulseaudio", version: "17.0-1"},
+			&mockPackage{name: "kde-cli-tools5", version: "5.27-1"},
+			&mockPackage{name: "dropped", version: "1.0-1"},
+		),
+	}
+	dbs, _ := h.SyncDBs()
+
+	// renamed (replaces & provides)
+	suite.Equal("pipewire-pulse", packageSuccessor(dbs, "pulseaudio"))
+
+	// merged (replaces only)
+	suite.Equal("kde-cli-tools", packageSuccessor(dbs, "kde-cli-tools5"))
+
+	// dropped
+	suite.Equal("", packageSuccessor(dbs, "dropped"))
+
+	// still exists in a repo
+	suite.Equal("", packageSuccessor(dbs, "foo"))
+
+	// info shows the successor
+	r := infoPacman(h, false, "pulseaudio", "dropped")
+	suite.Contains(r.Results[0].Description, "replaced by 'pipewire-pulse'")
+	suite.Contains(r.Results[1].Description, "not found in repositories")
+}
+
+func (suite *pacseekTestSuite) TestSearchReposNormalizedTerm() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "vlc", version: "3.0-1", desc: "Multi-platform MPEG, VCD/DVD, and DivX player"},
+			&mockPackage{name: "mpv", version: "0.38-1", desc: "a free, open source, and cross-platform media player"},
+		)},
+		local: newMockDB("local"),
+	}
+
+	// padded term
+	p, _, err := searchRepos(h, "  vlc \t", "StartsWith", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal([]string{"vlc"}, packageNames(p))
+
+	// internal whitespace
+	p, _, err = searchRepos(h, "media   player", "Contains", "Name & Description", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal([]string{"mpv"}, packageNames(p))
+
+	// whitespace only
+	p, l, err := searchRepos(h, " \t ", "Contains", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Len(p, 0, "Number of packages != 0")
+	suite.Len(l, 0, "Number of local packages != 0")
+	suite.Equal(0, repoMatchCount(h, "   ", "Contains", "Name", SearchOptions{}))
+
+	suite.Equal("media player", normalizeSearchTerm(" media \n player "))
+}
+
+func (suite *pacseekTestSuite) TestAurOnlyUpgrades() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"resultcount":3,"results":[{"Name":"yay","Version":"12.4.2-1"},{"Name":"paru","Version":"2.0.3-1"},{"Name":"pacseek","Version":"1.8.3-1"}],"type":"multiinfo","version":5}`)
+	}))
+	defer srv.Close()
+
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "firefox", version: "131.0-1"},
+		)},
+		local: newMockDB("local",
+			&mockPackage{name: "firefox", version: "130.0-1"},
+			&mockPackage{name: "yay", version: "12.3.5-1"},
+			&mockPackage{name: "paru", version: "2.0.3-1"},
+			&mockPackage{name: "pacseek", version: "1.8.4-1"},
+			&mockPackage{name: "my-tool", version: "0.1-1"},
+		),
+	}
+
+	// only AUR packages with a newer version
+	up, err := aurOnlyUpgrades(h, srv.URL, 5000)
+	suite.Nil(err, err)
+	suite.Len(up, 1, "Number of upgrades != 1")
+	suite.Equal("yay", up[0].Name)
+	suite.Equal("AUR", up[0].Source)
+	suite.Equal("12.4.2-1", up[0].Version)
+	suite.Equal("12.3.5-1", up[0].LocalVersion)
+
+	// AUR not reachable
+	_, err = aurOnlyUpgrades(h, "http://127.0.0.1:1", 500)
+	suite.NotNil(err, "unreachable AUR did not return an error")
+
+	// nok
+	_, err = aurOnlyUpgrades(nil, srv.URL, 5000)
+	suite.NotNil(err, "nil handle did not return an error")
+}
+
+func (suite *pacseekTestSuite) TestOrderResults() {
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("core", &mockPackage{name: "python-ytmusic", version: "1.0-1"}),
+			newMockDB("extra",
+				&mockPackage{name: "python", version: "3.12-1"},
+				&mockPackage{name: "python-aiohttp", version: "3.10-1"},
+			),
+		},
+		local: newMockDB("local"),
+	}
+	p, _, err := searchRepos(h, "python", "StartsWith", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+
+	// original order
+	preserved := append([]Package{}, p...)
+	orderResults(preserved, true)
+	suite.Equal([]string{"python-ytmusic", "python", "python-aiohttp"}, packageNames(preserved))
+
+	// sorted by name
+	orderResults(p, false)
+	suite.Equal([]string{"python", "python-aiohttp", "python-ytmusic"}, packageNames(p))
+}
+
+func (suite *pacseekTestSuite) TestQueueConflict() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "pipewire-pulse", version: "1.2-1", conflicts: mockDeps("pulseaudio"), provides: mockDeps("pulseaudio")},
+			&mockPackage{name: "pulseaudio", version: "17.0-1"},
+			&mockPackage{name: "jack2", version: "1.9-1"},
+			&mockPackage{name: "pipewire-jack", version: "1.2-1", replaces: mockDeps("jack<2")},
+			&mockPackage{name: "jack", version: "0.126-1"},
+			&mockPackage{name: "vlc", version: "3.0-1"},
+		)},
+		local: newMockDB("local"),
+	}
+
+	// candidate conflicts with queued package
+	c, err := queueConflict(h, "pipewire-pulse", []string{"vlc", "pulseaudio"})
+	suite.Nil(err, err)
+	suite.Equal("pulseaudio", c)
+
+	// queued package conflicts with candidate
+	c, err = queueConflict(h, "pulseaudio", []string{"pipewire-pulse"})
+	suite.Nil(err, err)
+	suite.Equal("pipewire-pulse", c)
+
+	// replaced (versioned)
+	c, err = queueConflict(h, "jack", []string{"vlc", "pipewire-jack"})
+	suite.Nil(err, err)
+	suite.Equal("pipewire-jack", c)
+	c, err = queueConflict(h, "jack2", []string{"pipewire-jack", "yay"})
+	suite.Nil(err, err)
+	suite.Equal("", c)
+
+	// no conflict
+	c, err = queueConflict(h, "vlc", []string{"pulseaudio", "jack2"})
+	suite.Nil(err, err)
+	suite.Equal("", c)
+
+	// nok
+	_, err = queueConflict(h, "yay", []string{"vlc"})
+	suite.NotNil(err, "unknown package did not return an error")
+}
+
+func (suite *pacseekTestSuite) TestSearchReposSegmentPrefix() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "git-lfs", version: "3.5-1"},
+			&mockPackage{name: "lfs-tools", version: "1.0-1"},
+			&mockPackage{name: "python-dateutil.tz", version: "1.0-1"},
+			&mockPackage{name: "selfsigned", version: "1.0-1"},
+		)},
+		local: newMockDB("local"),
+	}
+
+	// segment prefix
+	p, _, err := searchRepos(h, "lfs", "StartsWith", "Name", 10, SearchOptions{SegmentPrefix: true})
+	suite.Nil(err, err)
+	suite.Equal([]string{"git-lfs", "lfs-tools"}, packageNames(p))
+	p, _, err = searchRepos(h, "tz", "StartsWith", "Name", 10, SearchOptions{SegmentPrefix: true})
+	suite.Nil(err, err)
+	suite.Equal([]string{"python-dateutil.tz"}, packageNames(p))
+
+	// plain prefix
+	p, _, err = searchRepos(h, "lfs", "StartsWith", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal([]string{"lfs-tools"}, packageNames(p))
+	p, _, err = searchRepos(h, "git", "StartsWith", "Name", 10, SearchOptions{SegmentPrefix: true})
+	suite.Nil(err, err)
+	suite.Equal([]string{"git-lfs"}, packageNames(p))
+}
+
+func (suite *pacseekTestSuite) TestFormatInfoText() {
+	r := InfoRecord{
+		Source:       "extra",
+		Name:         "mpv",
+		Version:      "1:0.38.0-2",
+		Description:  "a free, open source, and cross-platform media player",
+		Architecture: "x86_64",
+		URL:          "https://mpv.io/",
+		License:      []string{"GPL-2.0-or-later", "LGPL-2.1-or-later"},
+		Provides:     []string{"libmpv.so=2-64"},
+		Depends:      []string{"alsa-lib", "desktop-file-utils", "ffmpeg", "glibc", "hicolor-icon-theme", "jack", "lcms2", "libarchive", "libass"},
+		OptDepends:   []string{"yt-dlp", "youtube-dl"},
+		Maintainer:   "Christian Hesse <eworm@archlinux.org>",
+		LastModified: 1719835200,
+	}
+
+	expected := `Repository      : extra
+Name            : mpv
+Version         : 1:0.38.0-2
+Description     : a free, open source, and cross-platform media player
+Architecture    : x86_64
+URL             : https://mpv.io/
+Licenses        : GPL-2.0-or-later  LGPL-2.1-or-later
+Groups          : None
+Provides        : libmpv.so=2-64
+Depends On      : alsa-lib  desktop-file-utils  ffmpeg  glibc
+                  hicolor-icon-theme  jack  lcms2  libarchive  libass
+Optional Deps   : yt-dlp
+                  youtube-dl
+Make Deps       : None
+Check Deps      : None
+Required By     : None
+Conflicts With  : None
+Replaces        : None
+Packager        : Christian Hesse <eworm@archlinux.org>
+Last Modified   : 2024-07-01 - 12:00:00 (UTC)
+`
+	suite.Equal(expected, FormatInfoText(r))
+
+	// AUR specific fields
+	text := FormatInfoText(InfoRecord{Source: "AUR", Name: "yay", NumVotes: 2300, Keywords: []string{"aur", "helper"}})
+	suite.Contains(text, "Maintainer      : None\n")
+	suite.Contains(text, "Votes           : 2300\n")
+	suite.Contains(text, "Keywords        : aur  helper\n")
+}
+
+func (suite *pacseekTestSuite) TestInfoPacmanProvidesConflicts() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "pipewire-jack", version: "1:1.2.5-1", provides: mockDeps("jack", "libjack.so=0-64"), conflicts: mockDeps("jack", "jack2")},
+		)},
+		local: newMockDB("local"),
+	}
+
+	r := infoPacman(h, false, "pipewire-jack")
+	suite.Len(r.Results, 1, "Number of results != 1")
+	suite.Equal([]string{"jack", "libjack.so=0-64"}, r.Results[0].Provides)
+	suite.Equal([]string{"jack", "jack2"}, r.Results[0].Conflicts)
+}
+
+func (suite *pacseekTestSuite) TestSearchReposHasOptDepends() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "mpv", version: "0.38-1", optDepends: mockDeps("yt-dlp")},
+			&mockPackage{name: "mpv-mpris", version: "1.1-1"},
+		)},
+		local: newMockDB("local",
+			&mockPackage{name: "mpv", version: "0.38-1", optDepends: mockDeps("yt-dlp")},
+		),
+	}
+
+	// ok
+	p, l, err := searchRepos(h, "mpv", "StartsWith", "Name", 10, SearchOptions{HasOptDepends: true})
+	suite.Nil(err, err)
+	suite.Equal([]string{"mpv"}, packageNames(p))
+	suite.Equal([]string{"mpv"}, packageNames(l))
+
+	// no filter
+	p, _, err = searchRepos(h, "mpv", "StartsWith", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Len(p, 2, "Number of packages != 2")
+}
+
+func (suite *pacseekTestSuite) TestSearchReposFuzzy() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "pavucontrol-qt", version: "2.0-1"},
+			&mockPackage{name: "pavucontrol", version: "1:6.0-1"},
+			&mockPackage{name: "pacman-contrib", version: "1.10-1"},
+			&mockPackage{name: "firefox", version: "131.0-1"},
+		)},
+		local: newMockDB("local"),
+	}
+
+	// exact
+	p, _, err := searchRepos(h, "pavucontrol", "Fuzzy", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal([]string{"pavucontrol", "pavucontrol-qt"}, packageNames(p))
+	suite.Equal(0, p[0].Score)
+
+	// subsequence (best match first)
+	p, _, err = searchRepos(h, "pavcontrol", "Fuzzy", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal([]string{"pavucontrol", "pavucontrol-qt"}, packageNames(p))
+	suite.Less(p[0].Score, p[1].Score)
+
+	// typo
+	p, _, err = searchRepos(h, "firefxo", "Fuzzy", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal([]string{"firefox"}, packageNames(p))
+
+	// limit applies to ranked results
+	p, _, err = searchRepos(h, "pavcontrol", "Fuzzy", "Name", 1, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal([]string{"pavucontrol"}, packageNames(p))
+
+	// no match
+	p, _, err = searchRepos(h, "thunderbird", "Fuzzy", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Len(p, 0, "Number of packages != 0")
+
+	suite.Equal(-1, fuzzyScore("vlc", "pacman-contrib"))
+}
+
+func (suite *pacseekTestSuite) TestSortPackagesPopularity() {
+	pkgs := []Package{
+		{Name: "paru", Source: "AUR", Popularity: 15.5},
+		{Name: "pacman", Source: "core", Popularity: repoPopularity},
+		{Name: "broken", Source: "AUR", Popularity: math.NaN()},
+		{Name: "yay", Source: "AUR", Popularity: 25.1},
+		{Name: "pacseek", Source: "AUR", Popularity: 0.5},
+		{Name: "pacman-contrib", Source: "extra", Popularity: math.MaxFloat64},
+	}
+
+	// repo packages first
+	sorted := append([]Package{}, pkgs...)
+	sortPackages(sorted, SortSpec{Keys: []SortKey{SortByPopularity, SortByName}})
+	suite.Equal([]string{"pacman", "pacman-contrib", "yay", "paru", "pacseek", "broken"}, packageNames(sorted))
+
+	// repo packages last
+	sorted = append([]Package{}, pkgs...)
+	sortPackages(sorted, SortSpec{Keys: []SortKey{SortByPopularity, SortByName}, ReposLast: true})
+	suite.Equal([]string{"yay", "paru", "pacseek", "broken", "pacman", "pacman-contrib"}, packageNames(sorted))
+
+	suite.Equal(0, comparePopularity(math.NaN(), 0))
+	suite.Equal(-1, comparePopularity(repoPopularity, math.Inf(1)))
+}
+
+func (suite *pacseekTestSuite) TestGetUpgradableIgnored() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("core",
+			&mockPackage{name: "linux", version: "6.11-1"},
+			&mockPackage{name: "linux-headers", version: "6.11-1"},
+			&mockPackage{name: "gnome-shell", version: "47.0-1", groups: []string{"gnome"}},
+			&mockPackage{name: "mutter", version: "47.0-1", groups: []string{"gnome"}},
+			&mockPackage{name: "glibc", version: "2.40-1"},
+		)},
+		local: newMockDB("local",
+			&mockPackage{name: "linux", version: "6.10-1"},
+			&mockPackage{name: "linux-headers", version: "6.10-1"},
+			&mockPackage{name: "gnome-shell", version: "46.0-1"},
+			&mockPackage{name: "mutter", version: "46.0-1"},
+			&mockPackage{name: "glibc", version: "2.39-1"},
+		),
+	}
+
+	// glob pattern, group and package ignored by group and name
+	up, _ := getUpgradable(h, UpgradeOptions{}, IgnoreRules{Packages: []string{"linux*", "mutter"}, Groups: []string{"gnome"}})
+	suite.Len(up, 5, "Number of upgrades != 5")
+	ignored := map[string]bool{}
+	for _, u := range up {
+		ignored[u.Name] = u.IsIgnored
+	}
+	suite.Equal(map[string]bool{"linux": true, "linux-headers": true, "gnome-shell": true, "mutter": true, "glibc": false}, ignored)
+
+	// no rules
+	up, _ = getUpgradable(h, UpgradeOptions{}, IgnoreRules{})
+	for _, u := range up {
+		suite.False(u.IsIgnored, u.Name)
+	}
+}
+
+func (suite *pacseekTestSuite) TestPackageChangelog() {
+	dbPath := suite.T().TempDir()
+	dir := filepath.Join(dbPath, "local", "vim-9.1.0-1")
+	suite.Nil(os.MkdirAll(dir, 0755))
+	suite.Nil(os.WriteFile(filepath.Join(dir, "changelog"), []byte("2024-01-02 Vim Maintainer\n\t* 9.1.0-1 :\n\tnew upstream release\n"), 0644))
+	suite.Nil(os.MkdirAll(filepath.Join(dbPath, "local", "nano-8.2-1"), 0755))
+
+	h := &mockHandle{
+		local: newMockDB("local",
+			&mockPackage{name: "vim", version: "9.1.0-1"},
+			&mockPackage{name: "nano", version: "8.2-1"},
+		),
+	}
+
+	// ok
+	c, err := packageChangelog(h, dbPath, "vim")
+	suite.Nil(err, err)
+	suite.Contains(c, "new upstream release")
+
+	// no changelog
+	_, err = packageChangelog(h, dbPath, "nano")
+	suite.EqualError(err, "package 'nano' does not have a changelog")
+
+	// not installed
+	_, err = packageChangelog(h, dbPath, "emacs")
+	suite.EqualError(err, "package 'emacs' is not installed")
+
+	// nok
+	_, err = packageChangelog(nil, dbPath, "vim")
+	suite.NotNil(err, "nil handle did not return an error")
+}
+
+func (suite *pacseekTestSuite) TestSearchReposMergeOrder() {
+	h := syntheticHandle(6, 50)
+
+	// results are merged in database order and limited deterministically
+	for i := 0; i < 10; i++ {
+		p, _, err := searchRepos(h, "pkg", "StartsWith", "Name", 120, SearchOptions{})
+		suite.Nil(err, err)
+		suite.Len(p, 120, "Number of packages != 120")
+		suite.Equal("repo0", p[0].Source)
+		suite.Equal("repo2", p[119].Source)
+		suite.Equal("pkg-19", p[119].Name)
+	}
+}
+
+// creates a handle with "numDBs" sync databases containing "numPkgs" packages each
+func syntheticHandle(numDBs, numPkgs int) *mockHandle {
+	h := &mockHandle{local: newMockDB("local")}
+	for i := 0; i < numDBs; i++ {
+		pkgs := []*mockPackage{}
+		for j := 0; j < numPkgs; j++ {
+			pkgs = append(pkgs, &mockPackage{name: fmt.Sprintf("pkg-%d", j), version: "1.0-1", desc: "synthetic package"})
+		}
+		h.sync = append(h.sync, newMockDB(fmt.Sprintf("repo%d", i), pkgs...))
+	}
+	return h
+}
+
+func BenchmarkMatchDatabases(b *testing.B) {
+	h := syntheticHandle(8, 5000)
+	dbs, _ := h.SyncDBs()
+	candidates := [][]searchCandidate{}
+	for _, db := range dbs.Slice() {
+		c := []searchCandidate{}
+		for _, pkg := range db.PkgCache().Slice() {
+			c = append(c, searchCandidate{pkg: pkg, text: newPkgText(pkg, "Name & Description", false)})
+		}
+		candidates = append(candidates, c)
+	}
+	match := func(c *searchCandidate) bool {
+		c.field = matchedField(c.text, "synthetc", "Name & Description", false, func(s, term string) bool {
+			return fuzzyScore(term, s) != -1
+		})
+		return c.field != ""
+	}
+
+	for _, parallel := range []bool{false, true} {
+		b.Run(fmt.Sprintf("parallel=%t", parallel), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				matchDatabases(context.Background(), candidates, parallel, math.MaxInt, match)
+			}
+		})
+	}
+}
+
+func (suite *pacseekTestSuite) TestMatchDatabases() {
+	h := syntheticHandle(4, 50)
+	dbs, _ := h.SyncDBs()
+	candidates := [][]searchCandidate{}
+	for _, db := range dbs.Slice() {
+		c := []searchCandidate{}
+		for _, pkg := range db.PkgCache().Slice() {
+			c = append(c, searchCandidate{pkg: pkg, text: newPkgText(pkg, "Name", false)})
+		}
+		candidates = append(candidates, c)
+	}
+	match := func(c *searchCandidate) bool {
+		c.field = matchedField(c.text, "pkg-1", "Name", false, strings.HasPrefix)
+		return c.field != ""
+	}
+
+	// concurrent matching returns the same results (in database order)
+	sequential := matchDatabases(context.Background(), candidates, false, 5, match)
+	parallel := matchDatabases(context.Background(), candidates, true, 5, match)
+	suite.Equal(sequential, parallel)
+	suite.Len(parallel, 4)
+	for _, found := range parallel {
+		suite.Len(found, 5)
+		suite.Equal("pkg-1", found[0].pkg.Name())
+		suite.Equal("Name", found[0].field)
+	}
+}
+
+func (suite *pacseekTestSuite) TestListRepoPackages() {
+	h := syntheticHandle(2, 25)
+	h.local = newMockDB("local", &mockPackage{name: "pkg-3", version: "1.0-1"})
+
+	// counts
+	suite.Equal(map[string]int{"repo0": 25, "repo1": 25}, packagesByRepo(h))
+	suite.Len(packagesByRepo(nil), 0, "Number of repos != 0")
+
+	// pages
+	p, err := listRepoPackages(h, "repo1", 0, 10)
+	suite.Nil(err, err)
+	suite.Len(p, 10, "Number of packages != 10")
+	suite.Equal("pkg-0", p[0].Name)
+	suite.Equal("repo1", p[0].Source)
+	suite.True(p[3].IsInstalled)
+
+	p, err = listRepoPackages(h, "repo1", 20, 10)
+	suite.Nil(err, err)
+	suite.Equal([]string{"pkg-20", "pkg-21", "pkg-22", "pkg-23", "pkg-24"}, packageNames(p))
+
+	// beyond last page
+	p, err = listRepoPackages(h, "repo1", 30, 10)
+	suite.Nil(err, err)
+	suite.Len(p, 0, "Number of packages != 0")
+
+	// local
+	p, err = listRepoPackages(h, "local", 0, 10)
+	suite.Nil(err, err)
+	suite.Equal([]string{"pkg-3"}, packageNames(p))
+
+	// unknown repo
+	_, err = listRepoPackages(h, "multilib", 0, 10)
+	suite.EqualError(err, "repository 'multilib' not found")
+}
+
+func (suite *pacseekTestSuite) TestSearchReposCancel() {
+	h := syntheticHandle(4, 10000)
+
+	// cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	p, l, err := searchReposCtx(ctx, h, "pkg", "Contains", "Name", 50000, SearchOptions{})
+	suite.ErrorIs(err, context.Canceled)
+	suite.Less(time.Since(start), time.Second, "cancelled search did not return promptly")
+	suite.Less(len(p), 40000, "cancelled search returned all packages")
+	suite.Len(l, 0, "Number of local packages != 0")
+
+	// cancelled while searching
+	ctx, cancel = context.WithCancel(context.Background())
+	go cancel()
+	p, _, err = searchReposCtx(ctx, h, "pkg", "Contains", "Name", 50000, SearchOptions{})
+	if err != nil {
+		suite.ErrorIs(err, context.Canceled)
+	} else {
+		suite.Len(p, 40000, "Number of packages != 40000")
+	}
+
+	// not cancelled
+	p, _, err = searchReposCtx(context.Background(), h, "pkg", "Contains", "Name", 50000, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Len(p, 40000, "Number of packages != 40000")
+}
+
+func (suite *pacseekTestSuite) TestSeparateRootAndDbPath() {
+	rootPath := suite.T().TempDir()
+	dbPath := suite.T().TempDir()
+
+	suite.Nil(os.MkdirAll(filepath.Join(rootPath, "usr", "bin"), 0755))
+	suite.Nil(os.WriteFile(filepath.Join(rootPath, "usr", "bin", "vim"), []byte{}, 0755))
+	suite.Nil(os.MkdirAll(filepath.Join(dbPath, "local", "vim-9.1.0-1"), 0755))
+	suite.Nil(os.WriteFile(filepath.Join(dbPath, "local", "vim-9.1.0-1", "changelog"), []byte("changes"), 0644))
+
+	h := &mockHandle{
+		local: newMockDB("local",
+			&mockPackage{name: "vim", version: "9.1.0-1", files: []alpm.File{
+				{Name: "usr/"}, {Name: "usr/bin/"}, {Name: "usr/bin/vim"}, {Name: "usr/bin/vimdiff"},
+			}},
+		),
+	}
+
+	// file checks use the installation root
+	owner, err := fileOwner(h, rootPath, filepath.Join(rootPath, "usr", "bin", "vim"))
+	suite.Nil(err, err)
+	suite.Equal("vim", owner)
+	_, err = fileOwner(h, rootPath, "/usr/bin/vim")
+	suite.NotNil(err, "file outside of root did not return an error")
+	_, err = fileOwner(h, rootPath, filepath.Join(rootPath, "usr", "bin", "emacs"))
+	suite.NotNil(err, "unowned file did not return an error")
+
+	missing, err := missingPackageFiles(h, rootPath, "vim")
+	suite.Nil(err, err)
+	suite.Equal([]string{"/usr/bin/vimdiff"}, missing)
+
+	// database files use the db path
+	c, err := packageChangelog(h, dbPath, "vim")
+	suite.Nil(err, err)
+	suite.Equal("changes", c)
+	_, err = packageChangelog(h, rootPath, "vim")
+	suite.NotNil(err, "changelog found in root path")
+}
+
+func (suite *pacseekTestSuite) TestInfoPacmanSizes() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "firefox", version: "131.0-1", size: 70 * 1024 * 1024, isize: 250 * 1024 * 1024},
+		)},
+		local: newMockDB("local",
+			&mockPackage{name: "my-tool", version: "0.1-1", isize: 2048},
+		),
+	}
+
+	r := infoPacman(h, false, "firefox", "my-tool")
+	suite.Len(r.Results, 2, "Number of results != 2")
+	suite.Equal(int64(70*1024*1024), r.Results[0].Size)
+	suite.Equal(int64(250*1024*1024), r.Results[0].InstalledSize)
+	suite.Equal(int64(0), r.Results[1].Size)
+	suite.Equal(int64(2048), r.Results[1].InstalledSize)
+}
+
+func (suite *pacseekTestSuite) TestGetUpgradableBySize() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "firefox", version: "131.0-1", size: 70000},
+			&mockPackage{name: "htop", version: "3.3-2", size: 200},
+			&mockPackage{name: "linux-firmware", version: "20241010-1", size: 300000},
+			&mockPackage{name: "tzdata", version: "2024b-1", size: 200},
+		)},
+		local: newMockDB("local",
+			&mockPackage{name: "firefox", version: "130.0-1"},
+			&mockPackage{name: "htop", version: "3.3-1"},
+			&mockPackage{name: "linux-firmware", version: "20240909-1"},
+			&mockPackage{name: "tzdata", version: "2024a-1"},
+		),
+	}
+
+	// ascending (equal sizes keep their order)
+	up, _ := getUpgradableBySize(h, false, IgnoreRules{})
+	names := []string{}
+	for _, u := range up {
+		names = append(names, u.Name)
+	}
+	suite.Equal([]string{"htop", "tzdata", "firefox", "linux-firmware"}, names)
+
+	// descending
+	up, _ = getUpgradableBySize(h, true, IgnoreRules{})
+	names = []string{}
+	for _, u := range up {
+		names = append(names, u.Name)
+	}
+	suite.Equal([]string{"linux-firmware", "firefox", "htop", "tzdata"}, names)
+}
+
+func (suite *pacseekTestSuite) TestSummarizeUpgrades() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "firefox", version: "131.0-1", size: 300 * 1024 * 1024, isize: 260 * 1024 * 1024},
+			&mockPackage{name: "python", version: "3.13-1", size: 112 * 1024 * 1024, isize: 80 * 1024 * 1024},
+			&mockPackage{name: "newbar", version: "2.0-1", size: 1024, replaces: mockDeps("bar")},
+		)},
+		local: newMockDB("local",
+			&mockPackage{name: "firefox", version: "130.0-1", isize: 200 * 1024 * 1024},
+			&mockPackage{name: "python", version: "3.12-1", isize: 82 * 1024 * 1024},
+			&mockPackage{name: "bar", version: "1.0-1", isize: 4096},
+			&mockPackage{name: "my-tool", version: "0.1-1", size: 1024, isize: 2048},
+		),
+	}
+
+	up, nf := getUpgradable(h, UpgradeOptions{ComputeSizes: true, IncludeReplaced: true}, IgnoreRules{})
+	suite.Equal([]string{"my-tool"}, packageNames(nf))
+	s := summarizeUpgrades(up)
+	suite.Equal(2, s.Count)
+	suite.Equal(int64(412*1024*1024), s.DownloadSize)
+	// firefox grows by 60 MiB, python shrinks by 2 MiB
+	suite.Equal(int64(58*1024*1024), s.InstalledSizeDelta)
+	suite.Equal("Download: 412.00 MiB, Net: +58.00 MiB", s.String())
+	suite.Equal(int64(340*1024*1024), s.InstalledSize)
+
+	// ignored upgrades are not performed
+	up[0].IsIgnored = true
+	suite.Equal(1, summarizeUpgrades(up).Count)
+
+	// shrinking
+	s = summarizeUpgrades([]Upgrade{{InfoRecord: InfoRecord{Source: "core"}, Status: "upgrade", DownloadSize: 1024, InstalledSizeDelta: -2048}})
+	suite.Equal("Download: 1.00 KiB, Net: -2.00 KiB", s.String())
+	suite.Equal("-2.00 KiB", formatSizeDelta(-2048))
+	suite.Equal("+0.00 B", formatSizeDelta(0))
+
+	// nothing to upgrade
+	suite.Equal(UpgradeSummary{}, summarizeUpgrades([]Upgrade{}))
+}
+
+func (suite *pacseekTestSuite) TestSearchReposConstraint() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "python", version: "3.12.6-1"},
+			&mockPackage{name: "python-legacy", version: "3.10.0-1", provides: mockDeps("python=3.10.0")},
+			&mockPackage{name: "pipewire-jack", version: "1:1.2.5-1", provides: mockDeps("jack", "libjack.so=0-64")},
+			&mockPackage{name: "jack2", version: "1.9.22-1", provides: mockDeps("libjack.so=0-64")},
+			&mockPackage{name: "foo", version: "1.0-1", desc: "python>=3.11 is required"},
+		)},
+		local: newMockDB("local",
+			&mockPackage{name: "python", version: "3.12.6-1"},
+		),
+	}
+
+	// version constraint on package name / provides
+	p, l, err := searchRepos(h, "python>=3.11", "Contains", "Name & Description", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal([]string{"python"}, packageNames(p))
+	suite.Equal("Name", p[0].MatchedField)
+	suite.True(p[0].IsInstalled)
+	suite.Equal([]string{"python"}, packageNames(l))
+
+	p, _, err = searchRepos(h, "python<3.11", "StartsWith", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal([]string{"python-legacy"}, packageNames(p))
+	suite.Equal("Provides", p[0].MatchedField)
+
+	// library provides
+	p, _, err = searchRepos(h, "libjack.so=0-64", "StartsWith", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal([]string{"pipewire-jack", "jack2"}, packageNames(p))
+
+	// search options apply to constraints as well
+	p, _, err = searchRepos(h, "python>=3.10", "Fuzzy", "Name", 10, SearchOptions{MatchRanges: true, FairQuota: true})
+	suite.Nil(err, err)
+	suite.Equal([]string{"python", "python-legacy"}, packageNames(p))
+	suite.Equal([][2]int{{0, 6}}, p[0].MatchRanges)
+	suite.Nil(p[1].MatchRanges)
+	p, _, err = searchRepos(h, "python>=3.10", "StartsWith", "Name", 1, SearchOptions{PreferNameMatches: true})
+	suite.Nil(err, err)
+	suite.Equal([]string{"python"}, packageNames(p))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = searchReposCtx(ctx, h, "python>=3.11", "StartsWith", "Name", 10, SearchOptions{})
+	suite.ErrorIs(err, context.Canceled)
+
+	// no constraint
+	_, ok := constraintTerm("python")
+	suite.False(ok)
+	_, ok = constraintTerm("python>=")
+	suite.False(ok)
+}
+
+func (suite *pacseekTestSuite) TestSearchReposMaintainer() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "firefox", version: "131.0-1", packager: "Jan Alexander Steffens (heftig) <heftig@archlinux.org>"},
+			&mockPackage{name: "gnome-shell", version: "47.0-1", packager: "Jan Alexander Steffens (heftig) <heftig@archlinux.org>"},
+			&mockPackage{name: "vim", version: "9.1-1", packager: "Levente Polyak <anthraxx@archlinux.org>"},
+			&mockPackage{name: "jan-tool", version: "1.0-1", packager: "Someone Else <someone@example.org>"},
+		)},
+		local: newMockDB("local"),
+	}
+
+	// name
+	p, _, err := searchRepos(h, "jan alexander", "StartsWith", "Maintainer", 10, SearchOptions{PreferNameMatches: true})
+	suite.Nil(err, err)
+	suite.Equal([]string{"firefox", "gnome-shell"}, packageNames(p))
+	suite.Equal("Maintainer", p[0].MatchedField)
+
+	// e-mail
+	p, _, err = searchRepos(h, "anthraxx@", "StartsWith", "Maintainer", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal([]string{"vim"}, packageNames(p))
+
+	// substring
+	p, _, err = searchRepos(h, "heftig", "Contains", "Maintainer", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal([]string{"firefox", "gnome-shell"}, packageNames(p))
+}
+
+func (suite *pacseekTestSuite) TestStreamSearch() {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		fmt.Fprint(w, `{"resultcount":2,"results":[{"Name":"vim-git","Version":"9.1-1"},{"Name":"vim-airline","Version":"0.11-1"}],"type":"search","version":5}`)
+	}))
+	defer srv.Close()
+
+	h := &mockHandle{
+		sync:  []*mockDB{newMockDB("extra", &mockPackage{name: "vim", version: "9.1-1"})},
+		local: newMockDB("local"),
+	}
+	sources := []searchSource{
+		{name: "repos", search: func(ctx context.Context) ([]Package, error) {
+			p, _, err := searchReposCtx(ctx, h, "vim", "StartsWith", "Name", 10, SearchOptions{})
+			return p, err
+		}},
+		{name: "AUR", search: func(ctx context.Context) ([]Package, error) {
+			return searchAurCtx(ctx, srv.URL, "vim", 5000, "StartsWith", "Name", 10)
+		}},
+	}
+
+	// repo results are emitted while the AUR request is pending
+	emitted := []string{}
+	p, w, err := streamSearch(context.Background(), sources, func(source string, pkgs []Package, err error) {
+		suite.Nil(err, err)
+		emitted = append(emitted, source)
+		if source == "repos" {
+			suite.Equal([]string{"vim"}, packageNames(pkgs))
+			close(release)
+		}
+	})
+	suite.Nil(err, err)
+	suite.Equal([]string{"repos", "AUR"}, emitted)
+	suite.Equal([]string{}, w)
+	suite.Equal([]string{"vim", "vim-airline", "vim-git"}, packageNames(p))
+
+	// cancelled while waiting for the AUR
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := false
+	sources[1].search = func(ctx context.Context) ([]Package, error) {
+		defer func() { stopped = true }()
+		return searchAurCtx(ctx, srv.URL+"/blocking", "vim", 5000, "StartsWith", "Name", 10)
+	}
+	release = make(chan struct{})
+	p, _, err = streamSearch(ctx, sources, func(source string, pkgs []Package, err error) {
+		if source == "repos" {
+			cancel()
+		}
+	})
+	suite.ErrorIs(err, context.Canceled)
+	suite.Equal([]string{"vim"}, packageNames(p))
+	suite.True(stopped)
+}
+
+func (suite *pacseekTestSuite) TestFindDependents() {
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("core",
+				&mockPackage{name: "openssl", version: "3.3-1", provides: mockDeps("libssl.so=3-64", "libcrypto.so=3-64")},
+				&mockPackage{name: "curl", version: "8.9-1", depends: mockDeps("openssl")},
+			),
+			newMockDB("extra",
+				&mockPackage{name: "python-cryptography", version: "43.0-1", depends: mockDeps("libcrypto.so=3-64")},
+				&mockPackage{name: "nodejs", version: "22.0-1", makeDepends: mockDeps("openssl>=3")},
+				&mockPackage{name: "git", version: "2.46-1", optDepends: mockDeps("openssl: for https")},
+				&mockPackage{name: "vim", version: "9.1-1"},
+			),
+		},
+		local: newMockDB("local", &mockPackage{name: "curl", version: "8.9-1"}),
+	}
+
+	// without optional deps
+	p, err := findDependents(h, "openssl", false)
+	suite.Nil(err, err)
+	suite.Equal([]string{"curl", "nodejs", "python-cryptography"}, packageNames(p))
+	suite.Equal("core", p[0].Source)
+	suite.True(p[0].IsInstalled)
+	suite.False(p[1].IsInstalled)
+
+	// with optional deps
+	p, err = findDependents(h, "openssl", true)
+	suite.Nil(err, err)
+	suite.Equal([]string{"curl", "git", "nodejs", "python-cryptography"}, packageNames(p))
+
+	// virtual target
+	p, err = findDependents(h, "libcrypto.so", false)
+	suite.Nil(err, err)
+	suite.Equal([]string{"python-cryptography"}, packageNames(p))
+
+	// no dependents
+	p, err = findDependents(h, "vim", true)
+	suite.Nil(err, err)
+	suite.Equal([]string{}, packageNames(p))
+
+	// nok
+	_, err = findDependents(nil, "openssl", false)
+	suite.NotNil(err, "nil handle did not return an error")
+}
+
+func (suite *pacseekTestSuite) TestProvidersOf() {
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("extra",
+				&mockPackage{name: "ttf-liberation", version: "2.1-1", provides: mockDeps("ttf-font")},
+				&mockPackage{name: "ttf-dejavu", version: "2.37-1", provides: mockDeps("ttf-font")},
+				&mockPackage{name: "jre-openjdk", version: "22-1", provides: mockDeps("java-runtime=22", "jre-openjdk-headless=22")},
+				&mockPackage{name: "ttf-font", version: "1.0-1"},
+			),
+			newMockDB("community",
+				&mockPackage{name: "noto-fonts", version: "24-1", provides: mockDeps("ttf-font")},
+				&mockPackage{name: "jre17-openjdk", version: "17-1", provides: mockDeps("java-runtime=17")},
+			),
+		},
+		local: newMockDB("local", &mockPackage{name: "ttf-dejavu", version: "2.37-1"}),
+	}
+
+	// multiple providers
+	p, err := providersOf(h, "ttf-font")
+	suite.Nil(err, err)
+	suite.Equal([]string{"noto-fonts", "ttf-dejavu", "ttf-liberation"}, packageNames(p))
+	suite.Equal("community", p[0].Source)
+	suite.True(p[1].IsInstalled)
+
+	// versioned provides
+	p, err = providersOf(h, "java-runtime")
+	suite.Nil(err, err)
+	suite.Equal([]string{"jre-openjdk", "jre17-openjdk"}, packageNames(p))
+
+	// no providers
+	p, err = providersOf(h, "nothing")
+	suite.Nil(err, err)
+	suite.Equal([]string{}, packageNames(p))
+
+	// nok
+	_, err = providersOf(nil, "ttf-font")
+	suite.NotNil(err, "nil handle did not return an error")
+}
+
+func (suite *pacseekTestSuite) TestSearchReposMultipleTerms() {
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("extra",
+				&mockPackage{name: "python-gobject", version: "3.48-1", desc: "Python bindings for GLib/GObject/GIO/GTK"},
+				&mockPackage{name: "python-gtk-theme", version: "1.0-1", desc: "A theme"},
+				&mockPackage{name: "gtk3", version: "3.24-1", desc: "GObject-based multi-platform GUI toolkit"},
+				&mockPackage{name: "python", version: "3.12-1", desc: "The Python programming language"},
+			),
+		},
+		local: newMockDB("local"),
+	}
+
+	// both tokens match the name
+	p, _, err := searchRepos(h, "python gtk", "Contains", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal([]string{"python-gtk-theme"}, packageNames(p))
+	suite.Equal("Name", p[0].MatchedField)
+
+	// one token only matches the description
+	p, _, err = searchRepos(h, "python  gtk", "Contains", "Name & Description", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal([]string{"python-gobject", "python-gtk-theme"}, packageNames(p))
+	suite.Equal("Description", p[0].MatchedField)
+	suite.Equal(2, repoMatchCount(h, "python gtk", "Contains", "Name & Description", SearchOptions{}))
+
+	// a token that matches nothing
+	p, _, err = searchRepos(h, "python gtk qt", "Contains", "Name & Description", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal([]string{}, packageNames(p))
+
+	// single token unchanged
+	p, _, err = searchRepos(h, "python", "StartsWith", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal([]string{"python-gobject", "python-gtk-theme", "python"}, packageNames(p))
+
+	// fuzzy
+	p, _, err = searchRepos(h, "gtk-thme python", "Fuzzy", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal([]string{"python-gtk-theme"}, packageNames(p))
+}
+
+func (suite *pacseekTestSuite) TestCachedPackageFile() {
+	cache1 := suite.T().TempDir()
+	cache2 := suite.T().TempDir()
+	for _, f := range []string{
+		filepath.Join(cache1, "vim-9.1.0-1-x86_64.pkg.tar.zst.sig"),
+		filepath.Join(cache1, "vim-runtime-9.1.0-1-x86_64.pkg.tar.zst"),
+		filepath.Join(cache2, "vim-9.1.0-1-x86_64.pkg.tar.zst"),
+		filepath.Join(cache2, "vim-9.1.0-1-x86_64.pkg.tar.zst.sig"),
+		filepath.Join(cache2, "glibc-2.40-1-x86_64.pkg.tar.xz"),
+	} {
+		suite.Nil(os.WriteFile(f, []byte{}, 0644))
+	}
+	dirs := []string{cache1, cache2}
+
+	// found in second cache dir
+	f, ok := cachedPackageFile(dirs, "vim", "9.1.0-1")
+	suite.True(ok)
+	suite.Equal(filepath.Join(cache2, "vim-9.1.0-1-x86_64.pkg.tar.zst"), f)
+
+	// other compression
+	f, ok = cachedPackageFile(dirs, "glibc", "2.40-1")
+	suite.True(ok)
+	suite.Equal(filepath.Join(cache2, "glibc-2.40-1-x86_64.pkg.tar.xz"), f)
+
+	// different version / not cached
+	_, ok = cachedPackageFile(dirs, "vim", "9.1.0-2")
+	suite.False(ok)
+	_, ok = cachedPackageFile(dirs, "vim-runtime", "9.1.0-2")
+	suite.False(ok)
+	_, ok = cachedPackageFile([]string{}, "vim", "9.1.0-1")
+	suite.False(ok)
+}
+
+func (suite *pacseekTestSuite) TestCachedVersions() {
+	cache1 := suite.T().TempDir()
+	cache2 := suite.T().TempDir()
+	for _, f := range []string{
+		filepath.Join(cache1, "vim-9.1.0-1-x86_64.pkg.tar.zst"),
+		filepath.Join(cache1, "vim-9.1.0-1-x86_64.pkg.tar.zst.sig"),
+		filepath.Join(cache1, "vim-runtime-9.1.0-1-x86_64.pkg.tar.zst"),
+		filepath.Join(cache1, "vim-9.0.2-3-x86_64.pkg.tar.xz"),
+		filepath.Join(cache2, "vim-9.1.0-1-x86_64.pkg.tar.zst"),
+		filepath.Join(cache2, "vim-9.1.0-10-x86_64.pkg.tar.zst"),
+		filepath.Join(cache2, "vim-1:8.0-1-any.pkg.tar.zst"),
+	} {
+		suite.Nil(os.WriteFile(f, []byte("pkg"), 0644))
+	}
+
+	// ok
+	v := cachedVersions([]string{cache1, cache2}, "vim")
+	versions := []string{}
+	for _, c := range v {
+		versions = append(versions, c.Version)
+	}
+	suite.Equal([]string{"1:8.0-1", "9.1.0-10", "9.1.0-1", "9.0.2-3"}, versions)
+	suite.Equal(cachedPackage{Version: "9.1.0-1", Arch: "x86_64", Path: filepath.Join(cache1, "vim-9.1.0-1-x86_64.pkg.tar.zst"), Size: 3}, v[2])
+	suite.Equal("any", v[0].Arch)
+
+	// not cached
+	suite.Len(cachedVersions([]string{cache1, cache2}, "emacs"), 0)
+	suite.Len(cachedVersions([]string{}, "vim"), 0)
+
+	// cache directories
+	suite.Equal([]string{"/var/cache/pacman/pkg/", "/tmp/one", "/tmp/two"}, packageCacheDirs("/nonsense/pacman.conf", " /tmp/one;;/tmp/two;/var/cache/pacman/pkg/"))
+}
+
+func (suite *pacseekTestSuite) TestSortResults() {
+	pkgs := func() []Package {
+		return []Package{
+			{Name: "python-vim", LastModified: 3, Popularity: 1},
+			{Name: "vim-airline", LastModified: 1, Popularity: repoPopularity},
+			{Name: "gvim", LastModified: 5, Popularity: 2},
+			{Name: "vim", LastModified: 2, Popularity: 0.5},
+			{Name: "vim-ale", LastModified: 4, Popularity: repoPopularity},
+		}
+	}
+
+	// relevance: exact match, prefix, contained (stable for equal ranks)
+	p := pkgs()
+	suite.Nil(sortResults(p, "relevance", "vim"))
+	suite.Equal([]string{"vim", "vim-airline", "vim-ale", "python-vim", "gvim"}, packageNames(p))
+
+	// fuzzy scores break ties
+	p = []Package{{Name: "vmi", Score: 2}, {Name: "vim-git", Score: 4}, {Name: "vimb", Score: 1}}
+	suite.Nil(sortResults(p, "relevance", "vim"))
+	suite.Equal([]string{"vimb", "vim-git", "vmi"}, packageNames(p))
+
+	// name
+	p = pkgs()
+	suite.Nil(sortResults(p, "name", "vim"))
+	suite.Equal([]string{"gvim", "python-vim", "vim", "vim-airline", "vim-ale"}, packageNames(p))
+
+	// date
+	p = pkgs()
+	suite.Nil(sortResults(p, "date", "vim"))
+	suite.Equal([]string{"gvim", "vim-ale", "python-vim", "vim", "vim-airline"}, packageNames(p))
+
+	// popularity
+	p = pkgs()
+	suite.Nil(sortResults(p, "popularity", "vim"))
+	suite.Equal([]string{"vim-airline", "vim-ale", "gvim", "python-vim", "vim"}, packageNames(p))
+
+	// votes / installed size (equal packages keep their order)
+	p = []Package{{Name: "vim", InstalledSize: 4096}, {Name: "gvim", NumVotes: 12}, {Name: "vim-git", NumVotes: 30}, {Name: "neovim", InstalledSize: 8192}}
+	suite.Nil(sortResults(p, "votes", "vim"))
+	suite.Equal([]string{"vim-git", "gvim", "vim", "neovim"}, packageNames(p))
+	suite.Nil(sortResults(p, "size", "vim"))
+	suite.Equal([]string{"neovim", "vim", "vim-git", "gvim"}, packageNames(p))
+
+	// configured criterion, results are ordered by name first
+	conf := config.Defaults()
+	conf.SortResults = "votes"
+	sortSearchResults(p, conf, "vim")
+	suite.Equal([]string{"vim-git", "gvim", "neovim", "vim"}, packageNames(p))
+	conf.SortResults = "name"
+	sortSearchResults(p, conf, "vim")
+	suite.Equal([]string{"gvim", "neovim", "vim", "vim-git"}, packageNames(p))
+	conf.SearchMode = "Fuzzy"
+	p = []Package{{Name: "vmi", Score: 2}, {Name: "vim-git", Score: 4}, {Name: "vimb", Score: 1}}
+	sortSearchResults(p, conf, "vim")
+	suite.Equal([]string{"vimb", "vmi", "vim-git"}, packageNames(p), "fuzzy matches not ranked by score")
+
+	// nok
+	suite.NotNil(sortResults(pkgs(), "nonsense", "vim"))
+}
+
+func (suite *pacseekTestSuite) TestUnreachablePackages() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra")},
+		local: newMockDB("local",
+			&mockPackage{name: "vim", version: "9.1-1", reason: alpm.PkgReasonExplicit, depends: mockDeps("gpm", "libsodium.so"), optDepends: mockDeps("python: python scripting")},
+			&mockPackage{name: "gpm", version: "1.20-1", reason: alpm.PkgReasonDepend, depends: mockDeps("ncurses")},
+			&mockPackage{name: "ncurses", version: "6.5-1", reason: alpm.PkgReasonDepend, depends: mockDeps("gpm")},
+			&mockPackage{name: "libsodium", version: "1.0-1", reason: alpm.PkgReasonDepend, provides: mockDeps("libsodium.so=23-64")},
+			&mockPackage{name: "python", version: "3.12-1", reason: alpm.PkgReasonDepend, depends: mockDeps("expat")},
+			&mockPackage{name: "expat", version: "2.6-1", reason: alpm.PkgReasonDepend},
+			// island: packages only depending on each other (cycle)
+			&mockPackage{name: "qt5-base", version: "5.15-1", reason: alpm.PkgReasonDepend, depends: mockDeps("qt5-svg")},
+			&mockPackage{name: "qt5-svg", version: "5.15-1", reason: alpm.PkgReasonDepend, depends: mockDeps("qt5-base")},
+		),
+	}
+
+	// without optional deps
+	suite.Equal([]string{"python", "expat", "qt5-base", "qt5-svg"}, packageNames(unreachablePackages(h, false)))
+
+	// optional deps keep python alive
+	p := unreachablePackages(h, true)
+	suite.Equal([]string{"qt5-base", "qt5-svg"}, packageNames(p))
+	suite.Equal("local", p[0].Source)
+
+	// nil handle
+	suite.Equal([]Package{}, unreachablePackages(nil, false))
+}
+
+func (suite *pacseekTestSuite) TestSearchReposArches() {
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("core",
+				&mockPackage{name: "linux", version: "6.10-1", arch: "x86_64"},
+				&mockPackage{name: "linux-firmware", version: "2024-1", arch: "any"},
+			),
+			newMockDB("custom",
+				&mockPackage{name: "linux-rpi", version: "6.6-1", arch: "aarch64"},
+				&mockPackage{name: "linux-armv7", version: "6.6-1", arch: "armv7h"},
+			),
+		},
+		local: newMockDB("local"),
+	}
+
+	// host architectures
+	arches := hostArchitectures([]string{"x86_64"})
+	suite.Equal([]string{"x86_64", "any"}, arches)
+	p, _, err := searchRepos(h, "linux", "StartsWith", "Name", 10, SearchOptions{Arches: arches})
+	suite.Nil(err, err)
+	suite.Equal([]string{"linux", "linux-firmware"}, packageNames(p))
+
+	// multiple architectures, without "any"
+	p, _, err = searchRepos(h, "linux", "StartsWith", "Name", 10, SearchOptions{Arches: []string{"aarch64", "armv7h"}})
+	suite.Nil(err, err)
+	suite.Equal([]string{"linux-rpi", "linux-armv7"}, packageNames(p))
+
+	// no filter
+	p, _, err = searchRepos(h, "linux", "StartsWith", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Len(p, 4)
+
+	// auto / duplicates
+	suite.Equal([]string{util.ResolveArchitecture("auto"), "any"}, hostArchitectures([]string{}))
+	suite.Equal([]string{"x86_64", "any"}, hostArchitectures([]string{"x86_64", "any", "x86_64"}))
+}
+
+func (suite *pacseekTestSuite) TestStreamSearchDeadline() {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		fmt.Fprint(w, `{"resultcount":1,"results":[{"Name":"vim-git","Version":"9.1-1"}],"type":"search","version":5}`)
+	}))
+	defer srv.Close()
+
+	h := &mockHandle{
+		sync:  []*mockDB{newMockDB("extra", &mockPackage{name: "vim", version: "9.1-1"})},
+		local: newMockDB("local"),
+	}
+	type lateResult struct {
+		pkgs []Package
+		err  error
+	}
+	late := make(chan lateResult, 1)
+	sources := []searchSource{
+		{name: "repos", search: func(ctx context.Context) ([]Package, error) {
+			p, _, err := searchReposCtx(ctx, h, "vim", "StartsWith", "Name", 10, SearchOptions{})
+			return p, err
+		}},
+		{name: "AUR", search: func(ctx context.Context) ([]Package, error) {
+			return searchAurCtx(ctx, srv.URL, "vim", 5000, "StartsWith", "Name", 10)
+		}, deadline: 50 * time.Millisecond, late: func(pkgs []Package, err error) {
+			late <- lateResult{pkgs, err}
+		}},
+	}
+
+	// AUR exceeds its deadline, repo results are returned with a warning
+	start := time.Now()
+	p, w, err := streamSearch(context.Background(), sources, nil)
+	suite.Nil(err, err)
+	suite.Less(time.Since(start), 2*time.Second)
+	suite.Equal([]string{"vim"}, packageNames(p))
+	suite.Equal([]string{"AUR timed out, results incomplete"}, w)
+
+	// AUR results are delivered later
+	close(release)
+	select {
+	case r := <-late:
+		suite.Nil(r.err, r.err)
+		suite.Equal([]string{"vim-git"}, packageNames(r.pkgs))
+	case <-time.After(5 * time.Second):
+		suite.Fail("late results were not delivered")
+	}
+
+	// source finishing within its deadline
+	sources[1].deadline = 5 * time.Second
+	p, w, err = streamSearch(context.Background(), sources, nil)
+	suite.Nil(err, err)
+	suite.Equal([]string{"vim", "vim-git"}, packageNames(p))
+	suite.Equal([]string{}, w)
+}
+
+func (suite *pacseekTestSuite) TestSearchGroups() {
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("core",
+				&mockPackage{name: "gcc", version: "14.2-1", groups: []string{"base-devel"}},
+				&mockPackage{name: "make", version: "4.4-1", groups: []string{"base-devel"}},
+				&mockPackage{name: "glibc", version: "2.40-1"},
+			),
+			newMockDB("extra",
+				&mockPackage{name: "gnome-shell", version: "47.0-1", groups: []string{"gnome"}},
+				&mockPackage{name: "pkgconf", version: "2.1-1", groups: []string{"base-devel"}},
+			),
+		},
+		local: newMockDB("local", &mockPackage{name: "make", version: "4.4-1"}),
+	}
+
+	// group expansion
+	p, err := searchGroups(h, "base-devel")
+	suite.Nil(err, err)
+	suite.Equal([]string{"gcc", "make", "pkgconf"}, packageNames(p))
+	suite.Equal("core", p[0].Source)
+	suite.Equal("extra", p[2].Source)
+	suite.False(p[0].IsInstalled)
+	suite.True(p[1].IsInstalled)
+
+	// unknown / empty group
+	p, err = searchGroups(h, "nonsense")
+	suite.Nil(err, err)
+	suite.Equal([]Package{}, p)
+	p, err = searchGroups(h, "")
+	suite.Nil(err, err)
+	suite.Equal([]Package{}, p)
+
+	// group names (our mock packages can't return a group list)
+	g, err := listGroups(h)
+	suite.Nil(err, err)
+	suite.Equal([]string{}, g)
+
+	// nok
+	_, err = searchGroups(nil, "gnome")
+	suite.NotNil(err, "nil handle did not return an error")
+	_, err = listGroups(nil)
+	suite.NotNil(err, "nil handle did not return an error")
+}
+
+func (suite *pacseekTestSuite) TestPackageFiles() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "vim", version: "9.1-1"},
+			&mockPackage{name: "neovim", version: "0.10-1"},
+		)},
+		local: newMockDB("local",
+			&mockPackage{name: "vim", version: "9.1-1", files: []alpm.File{{Name: "usr/bin/"}, {Name: "usr/bin/vim"}, {Name: "usr/share/vim/vimrc"}}},
+		),
+	}
+	dbPath := suite.T().TempDir()
+
+	// installed package
+	f, err := packageFiles(h, "/", dbPath, "vim")
+	suite.Nil(err, err)
+	suite.Equal([]string{"usr/bin/", "usr/bin/vim", "usr/share/vim/vimrc"}, f)
+
+	// filter
+	suite.Equal([]string{"usr/bin/vim", "usr/share/vim/vimrc"}, filterFiles(f, ""))
+	suite.Equal([]string{"usr/share/vim/vimrc"}, filterFiles(f, "VIMRC"))
+	suite.Equal([]string{}, filterFiles(f, "nonsense"))
+
+	// repo package without files db
+	_, err = packageFiles(h, "/", dbPath, "neovim")
+	suite.ErrorIs(err, errFilesDBNotSynced)
+
+	// nok
+	_, err = packageFiles(h, "/", dbPath, "nonsense")
+	suite.NotNil(err, "unknown package did not return an error")
+	_, err = packageFiles(nil, "/", dbPath, "vim")
+	suite.NotNil(err, "nil handle did not return an error")
+}
+
+func (suite *pacseekTestSuite) TestInfoPacmanInstallReason() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "firefox", version: "131.0-1"},
+			&mockPackage{name: "gtk3", version: "3.24-1"},
+			&mockPackage{name: "chromium", version: "129.0-1"},
+		)},
+		local: newMockDB("local",
+			&mockPackage{name: "firefox", version: "131.0-1", reason: alpm.PkgReasonExplicit},
+			&mockPackage{name: "gtk3", version: "3.24-1", reason: alpm.PkgReasonDepend},
+			&mockPackage{name: "my-tool", version: "1.0-1", reason: alpm.PkgReasonExplicit},
+		),
+	}
+
+	r := infoPacman(h, false, "firefox", "gtk3", "chromium", "my-tool").Results
+	suite.Len(r, 4)
+	suite.Equal("Explicit", r[0].InstallReason)
+	suite.Equal("Dependency", r[1].InstallReason)
+	suite.Equal("", r[2].InstallReason)
+	suite.Equal("Explicit", r[3].InstallReason)
+}
+
+func (suite *pacseekTestSuite) TestListForeignAndOrphans() {
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("core", &mockPackage{name: "glibc", version: "2.40-1"}),
+			newMockDB("extra",
+				&mockPackage{name: "vim", version: "9.1-1"},
+				&mockPackage{name: "python", version: "3.12-1"},
+				&mockPackage{name: "gpm", version: "1.20-1"},
+			),
+		},
+		local: newMockDB("local",
+			&mockPackage{name: "glibc", version: "2.40-1", reason: alpm.PkgReasonDepend, requiredBy: []string{"vim"}},
+			&mockPackage{name: "vim", version: "9.1-1", reason: alpm.PkgReasonExplicit},
+			&mockPackage{name: "python", version: "3.12-1", reason: alpm.PkgReasonDepend, optionalFor: []string{"vim"}},
+			&mockPackage{name: "gpm", version: "1.20-1", reason: alpm.PkgReasonDepend},
+			&mockPackage{name: "yay", version: "12.0-1", reason: alpm.PkgReasonExplicit},
+			&mockPackage{name: "yay-helper-lib", version: "1.0-1", reason: alpm.PkgReasonDepend},
+		),
+	}
+
+	// foreign
+	suite.Equal([]string{"yay", "yay-helper-lib"}, listForeign(h))
+
+	// orphans
+	suite.Equal([]string{"gpm", "yay-helper-lib"}, listOrphans(h, false))
+	suite.Equal([]string{"gpm", "python", "yay-helper-lib"}, listOrphans(h, true))
+
+	// filters
+	packages := []Package{
+		{Name: "glibc", Source: "core", IsInstalled: true},
+		{Name: "vim", Source: "extra", IsInstalled: true},
+		{Name: "gpm", Source: "extra", IsInstalled: true},
+		{Name: "emacs", Source: "extra"},
+		{Name: "yay", Source: "AUR", IsInstalled: true},
+	}
+	suite.Equal(packages, filterLocal(h, packages, "All"))
+	suite.Equal([]string{"gpm"}, packageNames(filterLocal(h, packages, "Orphans")))
+	suite.Equal([]string{"vim", "yay"}, packageNames(filterLocal(h, packages, "Explicit")))
+	suite.Equal([]string{"yay"}, packageNames(filterLocal(h, packages, "Foreign")))
+	suite.Len(filterLocal(h, packages, "nonsense"), 0)
+
+	// nil handle
+	suite.Equal([]string{}, listForeign(nil))
+	suite.Equal([]string{}, listOrphans(nil, false))
+	suite.Len(filterLocal(nil, packages, "Orphans"), 0)
+}
+
+func (suite *pacseekTestSuite) TestHandleCache() {
+	dbPath := suite.T().TempDir()
+	suite.Nil(os.Mkdir(filepath.Join(dbPath, "sync"), 0755))
+	coreDB := filepath.Join(dbPath, "sync", "core.db")
+	suite.Nil(os.WriteFile(coreDB, []byte{}, 0644))
+	past := time.Now().Add(-time.Hour)
+	suite.Nil(os.Chtimes(coreDB, past, past))
+	suite.Nil(os.Chtimes(filepath.Join(dbPath, "sync"), past, past))
+
+	created := 0
+	var fail error
+	c := newHandleCache(func(rootPath, dbPath, confPath string, repos []string, skipFailing bool) (*mockReleasable, []string, error) {
+		if fail != nil {
+			return nil, nil, fail
+		}
+		created++
+		return &mockReleasable{name: fmt.Sprintf("%d", created)}, nil, nil
+	})
+
+	// created once, then reused
+	h1, _, err := c.get("/", dbPath, "/etc/pacman.conf", []string{"core"}, false)
+	suite.Nil(err, err)
+	h2, _, err := c.get("/", dbPath, "/etc/pacman.conf", []string{"core"}, false)
+	suite.Nil(err, err)
+	suite.Same(h1, h2)
+	suite.Equal(1, created)
+
+	// different configuration
+	h3, _, err := c.get("/", dbPath, "/etc/pacman.conf", []string{"core", "extra"}, false)
+	suite.Nil(err, err)
+	suite.NotSame(h1, h3)
+	suite.Equal(2, created)
+
+	// sync db modified
+	suite.Nil(os.Chtimes(coreDB, time.Now(), time.Now()))
+	h4, _, err := c.get("/", dbPath, "/etc/pacman.conf", []string{"core"}, false)
+	suite.Nil(err, err)
+	suite.NotSame(h1, h4)
+	suite.True(h1.released, "outdated handle not released")
+	suite.False(h3.released)
+	suite.Equal(3, created)
+
+	// failing re-initialization keeps the old handle
+	fail = errors.New("failed to initialize alpm library")
+	suite.Nil(os.WriteFile(filepath.Join(dbPath, "sync", "extra.db"), []byte{}, 0644))
+	future := time.Now().Add(time.Hour)
+	suite.Nil(os.Chtimes(filepath.Join(dbPath, "sync", "extra.db"), future, future))
+	h5, _, err := c.get("/", dbPath, "/etc/pacman.conf", []string{"core"}, false)
+	suite.NotNil(err, "failed initialization did not return an error")
+	suite.Same(h4, h5)
+	suite.False(h4.released)
+	fail = nil
+
+	// local db modified (packages installed / removed)
+	suite.Nil(os.Mkdir(filepath.Join(dbPath, "local"), 0755))
+	future = future.Add(time.Hour)
+	suite.Nil(os.Chtimes(filepath.Join(dbPath, "local"), future, future))
+	h6, _, err := c.get("/", dbPath, "/etc/pacman.conf", []string{"core"}, false)
+	suite.Nil(err, err)
+	suite.NotSame(h4, h6)
+	suite.True(h4.released, "outdated handle not released")
+
+	// concurrent use
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h, _, err := c.get("/", dbPath, "/etc/pacman.conf", []string{"core"}, false)
+			suite.Nil(err, err)
+			suite.Same(h6, h)
+		}()
+	}
+	wg.Wait()
+
+	c.release()
+	suite.True(h3.released)
+	suite.True(h6.released)
+}
+
+func (suite *pacseekTestSuite) TestTempDBSyncCommand() {
+	// fakeroot preferred
+	suite.Equal([]string{"fakeroot", "--", "pacman", "-Sy", "--root=/", "--config=/etc/pacman.conf", "--dbpath=/tmp/checkup-db-1000"}, tempDBSyncCommand("/", "/etc/pacman.conf", "/tmp/checkup-db-1000", true))
+
+	// fallback without fakeroot
+	suite.Equal([]string{"pacman", "-Sy", "--root=/mnt", "--config=/mnt/etc/pacman.conf", "--dbpath=/tmp/checkup-db-1000"}, tempDBSyncCommand("/mnt", "/mnt/etc/pacman.conf", "/tmp/checkup-db-1000", false))
+}
+
+func (suite *pacseekTestSuite) TestRepoInitErrors() {
+	registered := []string{}
+	register := func(name string, siglevel alpm.SigLevel) (alpm.IDB, error) {
+		if name == "multilib" || name == "custom" {
+			return nil, fs.ErrNotExist
+		}
+		registered = append(registered, name)
+		return newMockDB(name), nil
+	}
+
+	// single repo failing
+	_, err := registerSyncDBs(register, []string{"core", "custom"}, false)
+	var repoErr *RepoInitError
+	suite.True(errors.As(err, &repoErr))
+	suite.Equal("custom", repoErr.Repo)
+	suite.ErrorIs(err, fs.ErrNotExist)
+
+	// multiple repos failing
+	registered = []string{}
+	err = registerAllSyncDBs(register, []string{"core", "multilib", "extra", "custom"})
+	var repoErrs RepoInitErrors
+	suite.True(errors.As(err, &repoErrs))
+	suite.Equal([]string{"multilib", "custom"}, repoErrs.Repos())
+	suite.Equal("failed to register repo 'multilib': file does not exist; failed to register repo 'custom': file does not exist", err.Error())
+	suite.Equal([]string{"core", "extra"}, registered)
+
+	// no failures
+	suite.Nil(registerAllSyncDBs(register, []string{"core", "extra"}))
+}
+
+func (suite *pacseekTestSuite) TestSearchReposCaseInsensitive() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "firefox", version: "131.0-1", desc: "Fast, Private & Safe Web Browser"},
+			&mockPackage{name: "Firefox-Nightly", version: "133.0-1", desc: "Nightly builds"},
+			&mockPackage{name: "perl-Text-CSV", version: "2.04-1", desc: "Comma-separated values manipulator", provides: mockDeps("perl-Text-CSV_PP")},
+		)},
+		local: newMockDB("local"),
+	}
+	opts := SearchOptions{CaseInsensitive: true}
+
+	// case sensitive (default)
+	p, _, err := searchRepos(h, "Firefox", "StartsWith", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal([]string{"Firefox-Nightly"}, packageNames(p))
+
+	// mixed case term and names
+	p, _, err = searchRepos(h, "Firefox", "StartsWith", "Name", 10, opts)
+	suite.Nil(err, err)
+	suite.Equal([]string{"firefox", "Firefox-Nightly"}, packageNames(p))
+	p, _, err = searchRepos(h, "text-csv", "Contains", "Name", 10, opts)
+	suite.Nil(err, err)
+	suite.Equal([]string{"perl-Text-CSV"}, packageNames(p))
+
+	// description, provides and fuzzy
+	p, _, err = searchRepos(h, "WEB browser", "Contains", "Name & Description", 10, opts)
+	suite.Nil(err, err)
+	suite.Equal([]string{"firefox"}, packageNames(p))
+	p, _, err = searchRepos(h, "CSV_pp", "Contains", "Broad", 10, opts)
+	suite.Nil(err, err)
+	suite.Equal([]string{"perl-Text-CSV"}, packageNames(p))
+	suite.Equal("Provides", p[0].MatchedField)
+	p, _, err = searchRepos(h, "FIREFX", "Fuzzy", "Name", 10, opts)
+	suite.Nil(err, err)
+	suite.Contains(packageNames(p), "firefox")
+
+	// match count
+	suite.Equal(2, repoMatchCount(h, "FIREFOX", "StartsWith", "Name", opts))
+}
+
+func (suite *pacseekTestSuite) TestUpgradeKind() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "vim", version: "9.1.0800-1"},
+			&mockPackage{name: "python", version: "3.12.7-2"},
+			&mockPackage{name: "ffmpeg", version: "2:7.1-1"},
+		)},
+		local: newMockDB("local",
+			&mockPackage{name: "vim", version: "9.1.0700-1"},
+			&mockPackage{name: "python", version: "3.12.7-1"},
+			&mockPackage{name: "ffmpeg", version: "1:7.1-1"},
+		),
+	}
+
+	up, _ := getUpgradable(h, UpgradeOptions{}, IgnoreRules{})
+	kinds := map[string]string{}
+	for _, u := range up {
+		kinds[u.Name] = u.Kind
+	}
+	suite.Equal(map[string]string{"vim": "version", "python": "pkgrel", "ffmpeg": "epoch"}, kinds)
+
+	// components
+	e, v, r := splitVersion("1:2.0.1-3")
+	suite.Equal(1, e)
+	suite.Equal("2.0.1", v)
+	suite.Equal("3", r)
+	e, v, r = splitVersion("r123.abc")
+	suite.Equal(0, e)
+	suite.Equal("r123.abc", v)
+	suite.Equal("", r)
+
+	// odd version strings
+	suite.Equal("version", upgradeKind("", "1.0-1"))
+	suite.Equal("pkgrel", upgradeKind("x:1.0-1", "1.0-2"))
+	suite.Equal("epoch", upgradeKind("1.0-1", "1:"))
+	suite.Equal("pkgrel", upgradeKind("-", "-"))
+}
+
+func (suite *pacseekTestSuite) TestInfoPacmanValidation() {
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("core", &mockPackage{name: "glibc", version: "2.40-1", validation: alpm.ValidationSHA256Sum | alpm.ValidationSignature}),
+			newMockDB("custom",
+				&mockPackage{name: "my-tool", version: "1.0-1", validation: alpm.ValidationSHA256Sum},
+				&mockPackage{name: "unsigned", version: "1.0-1", validation: alpm.ValidationNone},
+			),
+		},
+		local: newMockDB("local",
+			&mockPackage{name: "glibc", version: "2.40-1"},
+			&mockPackage{name: "foreign", version: "1.0-1", validation: alpm.ValidationNone},
+		),
+	}
+
+	r := infoPacman(h, false, "glibc", "my-tool", "unsigned", "foreign").Results
+	suite.Len(r, 4)
+	suite.Equal("pgp", r[0].Validation)
+	suite.Equal("sha256", r[1].Validation)
+	suite.Equal("none", r[2].Validation)
+	suite.Equal("", r[3].Validation, "local package has validation method")
+	suite.Equal("", validationMethod(alpm.ValidationUnkown))
+}
+
+func (suite *pacseekTestSuite) TestInstalledSet() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("core", &mockPackage{name: "glibc", version: "2.40-1"})},
+		local: newMockDB("local",
+			&mockPackage{name: "glibc", version: "2.39-2"},
+			&mockPackage{name: "yay", version: "12.0-1"},
+		),
+	}
+
+	i, err := installedSet(h)
+	suite.Nil(err, err)
+	suite.Equal(map[string]string{"glibc": "2.39-2", "yay": "12.0-1"}, i)
+
+	// nok
+	_, err = installedSet(&mockHandle{})
+	suite.NotNil(err, "local db error not returned")
+}
+
+func (suite *pacseekTestSuite) TestSearchReposIncludeProvides() {
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("core",
+				&mockPackage{name: "bash", version: "5.2-1", provides: mockDeps("sh")},
+				&mockPackage{name: "dash", version: "0.5-1", provides: mockDeps("sh")},
+				&mockPackage{name: "sh-utils", version: "1.0-1", provides: mockDeps("sh=1")},
+				&mockPackage{name: "fcron", version: "3.3-1", provides: mockDeps("cron")},
+			),
+		},
+		local: newMockDB("local", &mockPackage{name: "bash", version: "5.2-1", provides: mockDeps("sh")}),
+	}
+	opts := SearchOptions{IncludeProvides: true}
+
+	// only matches via provides
+	p, _, err := searchRepos(h, "cron", "StartsWith", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal([]string{}, packageNames(p))
+	p, _, err = searchRepos(h, "cron", "StartsWith", "Name", 10, opts)
+	suite.Nil(err, err)
+	suite.Equal([]string{"fcron"}, packageNames(p))
+	suite.Equal("Provides", p[0].MatchedField)
+
+	// name and provides matching: no duplicates
+	p, _, err = searchRepos(h, "sh", "StartsWith", "Name", 10, opts)
+	suite.Nil(err, err)
+	suite.Equal([]string{"bash", "dash", "sh-utils"}, packageNames(p))
+	suite.Equal("Name", p[2].MatchedField)
+	suite.Equal(4, repoMatchCount(h, "sh", "StartsWith", "Name", opts)) // including the local db
+
+	// together with descriptions
+	p, _, err = searchRepos(h, "cron", "StartsWith", "Name & Description", 10, opts)
+	suite.Nil(err, err)
+	suite.Equal([]string{"fcron"}, packageNames(p))
+}
+
+func (suite *pacseekTestSuite) TestResolveDeps() {
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("core",
+				&mockPackage{name: "glibc", version: "2.40-1"},
+				&mockPackage{name: "zlib", version: "1.3-1", depends: mockDeps("glibc")},
+				&mockPackage{name: "openssl", version: "3.3-1", depends: mockDeps("glibc"), provides: mockDeps("libssl.so=3-64")},
+			),
+			newMockDB("extra",
+				&mockPackage{name: "curl", version: "8.9-1", depends: mockDeps("zlib", "libssl.so", "libpsl")},
+				&mockPackage{name: "libpsl", version: "0.21-1", depends: mockDeps("zlib", "libidn2")},
+				&mockPackage{name: "libidn2", version: "2.3-1", depends: mockDeps("libpsl"), optDepends: mockDeps("python: scripts")},
+				&mockPackage{name: "python", version: "3.12-1"},
+				&mockPackage{name: "vim", version: "9.1-1", depends: mockDeps("glibc")},
+				&mockPackage{name: "broken", version: "1.0-1", depends: mockDeps("nonsense")},
+			),
+		},
+		local: newMockDB("local", &mockPackage{name: "glibc", version: "2.40-1"}),
+	}
+
+	// shared dependencies (zlib) are listed once, provides are resolved, cycles (libpsl <-> libidn2) terminate
+	i, a, err := resolveDeps(h, "curl")
+	suite.Nil(err, err)
+	suite.Equal([]string{"curl", "zlib", "openssl", "libpsl", "libidn2"}, i)
+	suite.Equal([]string{"glibc"}, a)
+
+	// all dependencies installed
+	i, a, err = resolveDeps(h, "vim")
+	suite.Nil(err, err)
+	suite.Equal([]string{"vim"}, i)
+	suite.Equal([]string{"glibc"}, a)
+
+	// nok
+	_, _, err = resolveDeps(h, "broken")
+	suite.NotNil(err, "unsatisfiable dependency did not return an error")
+	_, _, err = resolveDeps(h, "nonsense")
+	suite.NotNil(err, "unknown package did not return an error")
+	_, _, err = resolveDeps(nil, "curl")
+	suite.NotNil(err, "nil handle did not return an error")
+}
+
+func (suite *pacseekTestSuite) TestSearchReposFairQuota() {
+	extra := newMockDB("extra")
+	for i := 0; i < 20; i++ {
+		extra.pkgs = append(extra.pkgs, &mockPackage{name: fmt.Sprintf("python-pkg%02d", i), version: "1.0-1", db: extra})
+	}
+	h := &mockHandle{
+		sync: []*mockDB{extra, newMockDB("custom",
+			&mockPackage{name: "python-custom1", version: "1.0-1"},
+			&mockPackage{name: "python-custom2", version: "1.0-1"},
+		)},
+		local: newMockDB("local"),
+	}
+	sources := func(p []Package) map[string]int {
+		s := map[string]int{}
+		for _, pkg := range p {
+			s[pkg.Source]++
+		}
+		return s
+	}
+
+	// global cutoff: the first database takes all slots
+	p, _, err := searchRepos(h, "python", "StartsWith", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal(map[string]int{"extra": 10}, sources(p))
+
+	// fair quota: the second database contributes, unused slots are filled up
+	p, _, err = searchRepos(h, "python", "StartsWith", "Name", 10, SearchOptions{FairQuota: true})
+	suite.Nil(err, err)
+	suite.Equal(map[string]int{"extra": 8, "custom": 2}, sources(p))
+
+	// shares
+	suite.Equal([]int{4, 3, 3}, fairShares([]int{20, 10, 3}, 10))
+	suite.Equal([]int{5, 1, 0}, fairShares([]int{5, 1, 0}, 10))
+	suite.Equal([]int{0, 0}, fairShares([]int{5, 1}, 0))
+	suite.Equal([]int{}, fairShares([]int{}, 10))
+}
+
+func (suite *pacseekTestSuite) TestMatchRanges() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "firefox", version: "131.0-1", desc: "Fast, Private & Safe Web Browser"},
+			&mockPackage{name: "git-lfs", version: "3.5-1", desc: "Git extension for versioning large files"},
+			&mockPackage{name: "kdeconnect", version: "24.08-1", desc: "Adds communication between KDE and your smartphone"},
+			&mockPackage{name: "übersetzer", version: "1.0-1", desc: "Übersetzt Texte für dich"},
+		)},
+		local: newMockDB("local"),
+	}
+	opts := SearchOptions{MatchRanges: true}
+
+	// prefix
+	p, _, err := searchRepos(h, "fire", "StartsWith", "Name", 10, opts)
+	suite.Nil(err, err)
+	suite.Equal([][2]int{{0, 4}}, p[0].MatchRanges)
+	p, _, err = searchRepos(h, "lfs", "StartsWith", "Name", 10, SearchOptions{MatchRanges: true, SegmentPrefix: true})
+	suite.Nil(err, err)
+	suite.Equal([][2]int{{4, 7}}, p[0].MatchRanges)
+
+	// contains, description
+	p, _, err = searchRepos(h, "web", "Contains", "Name & Description", 10, opts)
+	suite.Nil(err, err)
+	suite.Equal("Description", p[0].MatchedField)
+	suite.Equal([][2]int{{21, 24}}, p[0].MatchRanges)
+	p, _, err = searchRepos(h, "git large", "Contains", "Name & Description", 10, opts)
+	suite.Nil(err, err)
+	suite.Equal([][2]int{{0, 3}, {29, 34}}, p[0].MatchRanges)
+
+	// fuzzy (not contiguous)
+	p, _, err = searchRepos(h, "kdcnect", "Fuzzy", "Name", 10, opts)
+	suite.Nil(err, err)
+	suite.Equal([][2]int{{0, 2}, {3, 4}, {5, 6}, {7, 10}}, p[0].MatchRanges)
+
+	// multi-byte characters
+	d := "Übersetzt Texte für dich"
+	r := matchRanges(d, "FÜR", "Contains", false)
+	suite.Equal([][2]int{{17, 21}}, r)
+	suite.Equal("für", d[r[0][0]:r[0][1]])
+	r = matchRanges("Kelvin", "ke", "StartsWith", false) // kelvin sign, 3 bytes, lowercases to a 1-byte "k"
+	suite.Equal([][2]int{{0, 4}}, r)
+
+	// without option
+	p, _, err = searchRepos(h, "fire", "StartsWith", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Nil(p[0].MatchRanges)
+
+	// no match
+	suite.Equal([][2]int{}, matchRanges("firefox", "xyz", "Fuzzy", false))
+}
+
+func (suite *pacseekTestSuite) TestDepTree() {
+	requests := 0
+	aur := map[string]string{
+		"yay":        `{"Name":"yay","Version":"12.0.0-1","Depends":["pacman>6","git"],"MakeDepends":["go"]}`,
+		"foo-git":    `{"Name":"foo-git","Version":"1.0-1","Depends":["bar-git"]}`,
+		"bar-git":    `{"Name":"bar-git","Version":"1.0-1","Depends":["foo-git","nonsense"]}`,
+		"go-helpers": `{"Name":"go-helpers","Version":"1.0-1"}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		requests++
+		results := []string{}
+		for _, name := range r.Form["arg[]"] {
+			if res, ok := aur[name]; ok {
+				results = append(results, res)
+			}
+		}
+		fmt.Fprintf(w, `{"resultcount":%d,"results":[%s],"type":"multiinfo","version":5}`, len(results), strings.Join(results, ","))
+	}))
+	defer srv.Close()
+
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("core",
+				&mockPackage{name: "glibc", version: "2.40-1"},
+				&mockPackage{name: "pacman", version: "7.0-1", depends: mockDeps("glibc")},
+			),
+			newMockDB("extra",
+				&mockPackage{name: "git", version: "2.46-1", depends: mockDeps("glibc", "perl")},
+				&mockPackage{name: "perl", version: "5.40-1", depends: mockDeps("glibc")},
+				&mockPackage{name: "go", version: "1.23-1"},
+			),
+		},
+		local: newMockDB("local", &mockPackage{name: "glibc", version: "2.40-1"}),
+	}
+
+	// AUR package with repo dependencies
+	tree, err := depTree(h, srv.URL, 5000, "yay")
+	suite.Nil(err, err)
+	suite.Equal([]string{
+		"yay (AUR, build)",
+		"├─ pacman (core)",
+		"│  └─ glibc (installed)",
+		"├─ git (extra)",
+		"│  ├─ glibc (installed)",
+		"│  └─ perl (extra)",
+		"│     └─ glibc (installed)",
+		"└─ go (extra)",
+	}, tree.lines(false))
+	suite.Equal([]string{depAur, depRepo, depInstalled, depRepo, depInstalled, depRepo, depInstalled, depRepo}, tree.kinds())
+	suite.Equal(1, requests, "Number of AUR requests != 1")
+
+	// cycles terminate, unknown dependencies are marked
+	requests = 0
+	tree, err = depTree(h, srv.URL, 5000, "foo-git")
+	suite.Nil(err, err)
+	suite.Equal([]string{
+		"foo-git (AUR, build)",
+		"`- bar-git (AUR, build)",
+		"   |- foo-git (AUR, build) (cycle)",
+		"   `- nonsense (not found)",
+	}, tree.lines(true))
+	suite.Equal(3, requests, "Number of AUR requests != 3")
+
+	// repo package, no AUR
+	tree, err = depTree(h, "", 5000, "git")
+	suite.Nil(err, err)
+	suite.Equal("git (extra)", tree.label())
+	suite.Len(tree.children, 2, "Number of dependencies != 2")
+
+	// nok
+	_, err = depTree(h, srv.URL, 5000, "nonsense")
+	suite.NotNil(err, "unknown package did not return an error")
+	_, err = depTree(h, "http://127.0.0.1:0", 5000, "yay")
+	suite.NotNil(err, "AUR error not returned")
+	_, err = depTree(nil, srv.URL, 5000, "yay")
+	suite.NotNil(err, "nil handle did not return an error")
+}
+
+func (suite *pacseekTestSuite) TestSearchRegex() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "python-numpy", version: "2.1-1", desc: "Scientific tools for Python"},
+			&mockPackage{name: "python2-numpy", version: "1.16-1"},
+			&mockPackage{name: "lib32-glibc", version: "2.40-1", desc: "GNU C Library (32-bit)"},
+			&mockPackage{name: "python>=3", version: "1.0-1"},
+		)},
+		local: newMockDB("local"),
+	}
+
+	// ok
+	p, _, err := searchRepos(h, `^python\d-`, "Regex", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal([]string{"python2-numpy"}, packageNames(p))
+	p, _, err = searchRepos(h, `SCIENTIFIC\s+tools`, "Regex", "Name & Description", 10, SearchOptions{CaseInsensitive: true, MatchRanges: true})
+	suite.Nil(err, err)
+	suite.Equal([]string{"python-numpy"}, packageNames(p))
+	suite.Equal("Description", p[0].MatchedField)
+	suite.Equal([][2]int{{0, 16}}, p[0].MatchRanges)
+	p, _, err = searchRepos(h, `python>=3`, "Regex", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal([]string{"python>=3"}, packageNames(p), "term treated as dependency constraint")
+	suite.Equal(1, repoMatchCount(h, `^lib\d+-`, "Regex", "Name", SearchOptions{}))
+
+	// literals for AUR searches
+	suite.Equal("python", requiredLiteral(`^python\d-`))
+	suite.Equal("numpy", requiredLiteral(`(py)+numpy(-git)?`))
+	suite.Equal("", requiredLiteral(`foo|bar`))
+
+	// AUR results are filtered
+	var args []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		args = append(args, r.URL.Query().Get("arg"))
+		fmt.Fprint(w, `{"resultcount":3,"results":[{"Name":"yay"},{"Name":"yay-bin"},{"Name":"yay-git"}],"type":"search","version":5}`)
+	}))
+	defer srv.Close()
+	p, err = searchAur(srv.URL, `^YAY-(bin|git)$`, 5000, "Regex", "Name", 20)
+	suite.Nil(err, err)
+	suite.Equal([]string{"yay-"}, args)
+	suite.Equal([]string{"yay-bin", "yay-git"}, packageNames(p))
+
+	// nok
+	_, _, err = searchRepos(h, `python(`, "Regex", "Name", 10, SearchOptions{})
+	suite.NotNil(err, "invalid regex did not return an error")
+	suite.Equal(0, repoMatchCount(h, `python(`, "Regex", "Name", SearchOptions{}))
+	_, err = searchAur(srv.URL, `python(`, 5000, "Regex", "Name", 20)
+	suite.NotNil(err, "invalid regex did not return an error")
+	_, err = searchAur(srv.URL, `a|b`, 5000, "Regex", "Name", 20)
+	suite.NotNil(err, "regex without literal did not return an error")
+	suite.Equal([][2]int{}, matchRanges("firefox", `python(`, "Regex", false))
+}
+
+func (suite *pacseekTestSuite) TestSearchAurFuzzy() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"resultcount":3,"results":[{"Name":"python-yay-helper"},{"Name":"yay-bin"},{"Name":"yay"}],"type":"search","version":5}`)
+	}))
+	defer srv.Close()
+
+	// closest matches first
+	p, err := searchAur(srv.URL, "yay", 5000, "Fuzzy", "Name", 20)
+	suite.Nil(err, err)
+	suite.Equal([]string{"yay", "yay-bin", "python-yay-helper"}, packageNames(p))
+	suite.Equal(0, p[0].Score)
+}
+
+func (suite *pacseekTestSuite) TestSearchFiles() {
+	extra := newMockDB("extra",
+		&mockPackage{name: "ffmpeg", version: "2:7.0-1", files: []alpm.File{{Name: "usr/"}, {Name: "usr/bin/"}, {Name: "usr/bin/ffmpeg"}, {Name: "usr/bin/ffprobe"}}},
+		&mockPackage{name: "ffmpeg4.4", version: "4.4-1", files: []alpm.File{{Name: "usr/lib/ffmpeg4.4/bin/ffmpeg"}}},
+		&mockPackage{name: "myffmpeg", version: "1.0-1", files: []alpm.File{{Name: "usr/bin/myffmpeg"}}},
+	)
+	local := newMockDB("local",
+		&mockPackage{name: "ffmpeg", version: "2:7.0-1", files: []alpm.File{{Name: "usr/bin/ffmpeg"}}},
+		&mockPackage{name: "ffmpeg-aur", version: "1.0-1", files: []alpm.File{{Name: "opt/ffmpeg-aur/bin/ffmpeg"}}},
+	)
+	installed := map[string]string{"ffmpeg": "2:7.0-1", "ffmpeg-aur": "1.0-1"}
+
+	// ok
+	p := searchFileDBs([]alpm.IDB{extra}, installed, "bin/ffmpeg", 10, InstalledAny)
+	suite.Equal([]string{"ffmpeg", "ffmpeg4.4"}, packageNames(p))
+	suite.True(p[0].IsInstalled, "ffmpeg not installed")
+	suite.Equal("File", p[0].MatchedField)
+	suite.Equal([]string{"ffmpeg"}, packageNames(searchFileDBs([]alpm.IDB{extra}, installed, "/usr/bin/ffmpeg", 10, InstalledAny)))
+	suite.Equal([]string{"ffmpeg", "ffmpeg4.4"}, packageNames(searchFileDBs([]alpm.IDB{extra}, installed, "ffmpeg", 10, InstalledAny)))
+	suite.Len(searchFileDBs([]alpm.IDB{extra, local}, installed, "bin/ffmpeg", 3, InstalledAny), 3, "Number of packages != 3")
+
+	// without files db's, only installed packages are found
+	h := &mockHandle{sync: []*mockDB{extra}, local: local}
+	p, i, err := searchFiles(h, "/", suite.T().TempDir(), "bin/ffmpeg", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Len(p, 0, "packages from files db")
+	suite.Equal([]string{"ffmpeg", "ffmpeg-aur"}, packageNames(i))
+
+	// excluded sources / install state
+	suite.Equal([]string{"ffmpeg4.4"}, packageNames(searchFileDBs([]alpm.IDB{extra}, installed, "bin/ffmpeg", 10, NotInstalledOnly)))
+	suite.Equal([]string{"ffmpeg"}, packageNames(searchFileDBs([]alpm.IDB{extra}, installed, "bin/ffmpeg", 10, InstalledOnly)))
+	_, i, err = searchFiles(h, "/", suite.T().TempDir(), "bin/ffmpeg", 10, SearchOptions{ExcludeSources: []string{"local"}})
+	suite.ErrorIs(err, errFilesDBNotSynced)
+	suite.Len(i, 0, "excluded local db searched")
+	_, i, err = searchFiles(h, "/", suite.T().TempDir(), "bin/ffmpeg", 10, SearchOptions{Installed: NotInstalledOnly})
+	suite.ErrorIs(err, errFilesDBNotSynced)
+	suite.Len(i, 0, "installed packages returned")
+
+	// nok
+	_, _, err = searchFiles(h, "/", suite.T().TempDir(), "bin/nonsense", 10, SearchOptions{})
+	suite.ErrorIs(err, errFilesDBNotSynced)
+	suite.Len(searchFileDBs([]alpm.IDB{extra}, installed, "fmpeg", 10, InstalledAny), 0, "partial file names matched")
+	suite.Len(searchFileDBs([]alpm.IDB{extra}, installed, "/", 10, InstalledAny), 0, "empty term matched")
+	_, _, err = searchFiles(nil, "/", "", "bin/ffmpeg", 10, SearchOptions{})
+	suite.NotNil(err, "nil handle did not return an error")
+}
+
+func (suite *pacseekTestSuite) TestSearchAll() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("type") == "info" {
+			fmt.Fprint(w, `{"resultcount":2,"results":[{"Name":"yay","Version":"12.0.0-1"},{"Name":"yay-bin","Version":"12.0.1-1"}],"type":"multiinfo","version":5}`)
+			return
+		}
+		fmt.Fprint(w, `{"resultcount":2,"results":[{"Name":"yay-bin","Popularity":2.5},{"Name":"yay","Popularity":20.5}],"type":"search","version":5}`)
+	}))
+	defer srv.Close()
+
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra", &mockPackage{name: "yaycl", version: "1.0-1"})},
+		local: newMockDB("local",
+			&mockPackage{name: "yay", version: "11.0.0-1"},
+			&mockPackage{name: "yayfoo", version: "0.1-1"},
+		),
+	}
+	conf := config.Defaults()
+	conf.AurRpcUrl = srv.URL
+	conf.SearchMode = "StartsWith"
+
+	// ok
+	r, err := searchAll(h, conf, "", "yay")
+	suite.Nil(err, err)
+	suite.Equal([]cliResult{
+		{Name: "yay", Version: "12.0.0-1", Source: "AUR", Installed: true, Popularity: 20.5},
+		{Name: "yay-bin", Version: "12.0.1-1", Source: "AUR", Popularity: 2.5},
+		{Name: "yaycl", Version: "1.0-1", Source: "extra"},
+		{Name: "yayfoo", Version: "0.1-1", Source: "local", Installed: true},
+	}, r)
+
+	b, err := formatResults(r[:2], "json")
+	suite.Nil(err, err)
+	suite.Contains(string(b), `"name": "yay",`)
+	suite.Contains(string(b), `"installed": true,`)
+	b, err = formatResults(r[:2], "csv")
+	suite.Nil(err, err)
+	suite.Equal("Name,Version,Source,Installed,Popularity\nyay,12.0.0-1,AUR,true,20.5\nyay-bin,12.0.1-1,AUR,false,2.5\n", string(b))
+	b, err = formatResults(r[2:], "plain")
+	suite.Nil(err, err)
+	suite.Equal("extra/yaycl 1.0-1\nlocal/yayfoo 0.1-1 [installed]\n", string(b))
+	b, err = formatResults([]cliResult{}, "json")
+	suite.Nil(err, err)
+	suite.Equal("[]\n", string(b))
+
+	// repos only
+	conf.DisableAur = true
+	r, err = searchAll(h, conf, "", "yay")
+	suite.Nil(err, err)
+	suite.Equal([]string{"yay", "yaycl", "yayfoo"}, []string{r[0].Name, r[1].Name, r[2].Name})
+
+	// nok
+	_, err = formatResults(r, "xml")
+	suite.NotNil(err, "unknown format did not return an error")
+	err = Search(conf, args.Flags{SearchTerm: "yay", OutputFormat: "xml"}, io.Discard, io.Discard)
+	suite.NotNil(err, "unknown format did not return an error")
+	err = Search(conf, args.Flags{SearchTerm: "y", OutputFormat: "json"}, io.Discard, io.Discard)
+	suite.NotNil(err, "short search term did not return an error")
+	conf.DisableAur = false
+	conf.AurRpcUrl = "http://127.0.0.1:0"
+	_, err = searchAll(h, conf, "", "yay")
+	suite.NotNil(err, "AUR error not returned")
+}
+
+func (suite *pacseekTestSuite) TestBatchCommands() {
+	conf := config.Defaults()
+	queue := []queuedPackage{
+		{InfoRecord: InfoRecord{Name: "vim", Source: "extra"}},
+		{InfoRecord: InfoRecord{Name: "nano", Source: "extra"}, Installed: true},
+		{InfoRecord: InfoRecord{Name: "yay", Source: "AUR", PackageBase: "yay"}},
+		{InfoRecord: InfoRecord{Name: "paru-bin", Source: "AUR", PackageBase: "paru-bin"}},
+		{InfoRecord: InfoRecord{Name: "emacs", Source: "extra"}, Installed: true},
+	}
+
+	// ok
+	suite.Equal([]string{"yay -Rs nano emacs", "yay -S vim yay paru-bin"}, batchCommands(conf, nil, queue))
+	conf.InstallCommand = "sudo pacman -S {pkg} --needed {optdepends}"
+	conf.AurUseDifferentCommands = true
+	conf.AurInstallCommand = "cd /tmp && git clone {giturl} && cd {pkgbase} && makepkg -si"
+	suite.Equal([]string{
+		"yay -Rs nano emacs",
+		"sudo pacman -S vim --needed ",
+		"cd /tmp && git clone https://aur.archlinux.org/yay.git && cd yay && makepkg -si yay",
+		"cd /tmp && git clone https://aur.archlinux.org/paru-bin.git && cd paru-bin && makepkg -si paru-bin",
+	}, batchCommands(conf, nil, queue))
+
+	// single packages
+	suite.Equal("sudo pacman -S vim --needed gvim", packageCommand(conf, InfoRecord{Name: "vim", Source: "extra", OptDepends: []string{"gvim"}}, false))
+	suite.Equal("yay -Rs vim", packageCommand(conf, InfoRecord{Name: "vim", Source: "extra"}, true))
+
+	// nok
+	suite.Len(batchCommands(conf, nil, []queuedPackage{}), 0, "commands for an empty queue")
+}
+
+func (suite *pacseekTestSuite) TestFlatpakSource() {
+	out := "org.gimp.GIMP\tGNU Image Manipulation Program\tCreate images and edit photographs\t2.10.38\tflathub\n" +
+		"org.example.Gimpish\tGimpish\tA GIMP lookalike\t1.0\tbeta,flathub\n" +
+		"broken line\n"
+	fail := false
+	listed := 0
+	f := newFlatpakSource()
+	f.run = func(args ...string) (string, error) {
+		if fail {
+			return "", errors.New("flatpak not found")
+		}
+		if args[0] == "list" {
+			listed++
+			return "org.gimp.GIMP\n", nil
+		}
+		return out, nil
+	}
+
+	// ok
+	pkgs, errs := searchSources([]packageSource{f}, "gimp", 10)
+	suite.Len(errs, 0)
+	suite.Equal([]Package{
+		{Name: "org.gimp.GIMP", Source: "Flatpak", IsInstalled: true},
+		{Name: "org.example.Gimpish", Source: "Flatpak"},
+	}, pkgs)
+	pkgs, _ = searchSources([]packageSource{f}, "gimp", 1)
+	suite.Len(pkgs, 1, "max results not applied")
+
+	r, err := f.Info("org.gimp.GIMP")
+	suite.Nil(err)
+	suite.Equal("2.10.38", r.Version)
+	suite.Equal("2.10.38", r.LocalVersion)
+	suite.Equal("https://flathub.org/apps/org.gimp.GIMP", r.URL)
+	r, err = f.Info("org.example.Gimpish")
+	suite.Nil(err)
+	suite.Equal("beta", r.PackageBase)
+	suite.Equal("", r.URL)
+	suite.Equal("", r.LocalVersion)
+
+	// installed applications are cached until our sources are refreshed
+	suite.Equal(1, listed)
+	refreshSources([]packageSource{f})
+	installed, err := f.Installed()
+	suite.Nil(err)
+	suite.Equal(map[string]bool{"org.gimp.GIMP": true}, installed)
+	suite.Equal(2, listed)
+
+	suite.Equal("flatpak install beta org.example.Gimpish", f.InstallCommand(r))
+	suite.Equal("flatpak uninstall org.example.Gimpish", f.UninstallCommand(r))
+	suite.Equal(f, findSource([]packageSource{f}, "Flatpak"))
+	suite.Nil(findSource([]packageSource{f}, "AUR"))
+
+	// queued flatpak packages use the commands of their source
+	queue := []queuedPackage{
+		{InfoRecord: InfoRecord{Name: "vim", Source: "extra"}},
+		{InfoRecord: r},
+		{InfoRecord: InfoRecord{Name: "org.gimp.GIMP", Source: "Flatpak"}, Installed: true},
+	}
+	suite.Equal([]string{"yay -S vim", "flatpak install beta org.example.Gimpish", "flatpak uninstall org.gimp.GIMP"},
+		batchCommands(config.Defaults(), []packageSource{f}, queue))
+
+	// nok
+	_, err = f.Info("org.unknown.App")
+	suite.NotNil(err, "unknown app did not return an error")
+	suite.Len(parseFlatpakSearch("No matches found\n"), 0)
+	fail = true
+	refreshSources([]packageSource{f})
+	_, err = f.Installed()
+	suite.NotNil(err, "flatpak error not returned")
+	pkgs, errs = searchSources([]packageSource{f}, "gimp", 10)
+	suite.Len(pkgs, 0)
+	suite.Len(errs, 1, "flatpak error not returned")
+}
+
+func (suite *pacseekTestSuite) TestAdvisories() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/all.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `[
+{"name":"AVG-1","packages":["curl","lib32-curl"],"status":"Vulnerable","severity":"Medium","type":"denial of service","affected":"8.0.0-1","fixed":null,"issues":["CVE-2024-0001"]},
+{"name":"AVG-2","packages":["openssl"],"status":"Fixed","severity":"Critical","type":"arbitrary code execution","affected":"3.0.0-1","fixed":"3.0.7-1","issues":["CVE-2024-0002","CVE-2024-0003"]},
+{"name":"AVG-3","packages":["vim"],"status":"Fixed","severity":"High","type":"arbitrary code execution","affected":"9.0.0-1","fixed":"9.0.1-1","issues":["CVE-2024-0004"]},
+{"name":"AVG-4","packages":["curl"],"status":"Not affected","severity":"Low","type":"unknown","affected":"8.0.0-1","fixed":null,"issues":[]},
+{"name":"AVG-5","packages":["curl"],"status":"Fixed","severity":"High","type":"information disclosure","affected":"7.0.0-1","fixed":"7.5.0-1","issues":["CVE-2024-0005"]}
+]`)
+	}))
+	defer srv.Close()
+
+	// ok
+	advisories, err := getAdvisories(srv.URL + "/all.json")
+	suite.Nil(err)
+	suite.Len(advisories, 5)
+	suite.Equal("", advisories[0].Fixed)
+	suite.Equal([]string{"CVE-2024-0002", "CVE-2024-0003"}, advisories[1].Issues)
+
+	installed := map[string]string{"curl": "8.1.0-1", "openssl": "3.0.5-1", "vim": "9.0.1-1", "nano": "7.0-1"}
+	vulnerable := vulnerablePackages(advisories, installed)
+	suite.Len(vulnerable, 2)
+	suite.Len(vulnerable["curl"], 1, "not affected or fixed advisory included")
+	suite.Equal("AVG-1", vulnerable["curl"][0].Name)
+	suite.Equal("8.1.0-1", vulnerable["curl"][0].InstalledVersion)
+	suite.Equal("AVG-2", vulnerable["openssl"][0].Name)
+	older := vulnerablePackages(advisories, map[string]string{"openssl": "1.1.1-1"})
+	suite.Len(older["openssl"], 1, "version older than the affected one not included")
+
+	sorted := sortedVulnerabilities(vulnerable)
+	suite.Equal("openssl", sorted[0].Package, "critical vulnerability is not listed first")
+	suite.Equal("curl", sorted[1].Package)
+	suite.Equal(len(advisorySeverities), severityRank("whatever"))
+
+	// nok
+	_, err = getAdvisories(srv.URL + "/nonsense.json")
+	suite.NotNil(err, "wrong status did not return an error")
+	_, err = getAdvisories("http://127.0.0.1:0")
+	suite.NotNil(err, "unreachable tracker did not return an error")
+	suite.Len(vulnerablePackages(advisories, map[string]string{}), 0)
+}
+
+func (suite *pacseekTestSuite) TestReverseDeps() {
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("extra",
+				&mockPackage{name: "python", version: "3.12-1", requiredBy: []string{"python-foo"}},
+				&mockPackage{name: "python-foo", version: "1.0-1"},
+			),
+		},
+		local: newMockDB("local",
+			&mockPackage{name: "glibc", version: "2.40-1", requiredBy: []string{"pacman", "bash"}, optionalFor: []string{"nano"}},
+			&mockPackage{name: "bash", version: "5.2-1", requiredBy: []string{"pacman"}},
+			&mockPackage{name: "pacman", version: "7.0-1", requiredBy: []string{"glibc"}},
+			&mockPackage{name: "nano", version: "8.0-1", requiredBy: []string{"vim"}},
+		),
+	}
+
+	// ok
+	r, err := newRevResolver(h, "glibc")
+	suite.Nil(err)
+	labels := func(nodes []*depNode) []string {
+		l := []string{}
+		for _, n := range nodes {
+			l = append(l, n.label())
+		}
+		return l
+	}
+	suite.Equal([]string{"bash", "pacman", "nano (optional)"}, labels(r.children("glibc", []string{"glibc"})))
+	suite.Equal([]string{"glibc (cycle)"}, labels(r.children("pacman", []string{"glibc", "pacman"})), "cycle not detected")
+	suite.Len(r.children("vim", []string{"glibc", "nano", "vim"}), 0, "unknown package has reverse dependencies")
+	suite.Equal([]string{"bash", "pacman"}, r.breaking("glibc"), "optional dependencies or cycles not handled")
+
+	// sync db's for packages that are not installed
+	r, err = newRevResolver(h, "python")
+	suite.Nil(err)
+	suite.Equal([]string{"python-foo"}, r.breaking("python"))
+
+	// nok
+	_, err = newRevResolver(h, "nonsense")
+	suite.NotNil(err, "unknown package did not return an error")
+	_, err = newRevResolver(nil, "glibc")
+	suite.NotNil(err, "nil handle did not return an error")
+}
+
+func (suite *pacseekTestSuite) TestAurComments() {
+	page := `<html><body><div id="pkgdetails"></div>
+<div class="comments package-comments">
+  <div class="comments-header"><h3><span class="text">Pinned Comments</span></h3></div>
+  <h4 id="comment-1" class="comment-header"><a href="/account/jane">jane</a> commented on <a href="#comment-1" class="date">2024-01-01 10:00 (UTC)</a></h4>
+  <div id="comment-1-content" class="article-content"><div><p>Import the key first:<br>gpg --recv-keys ABC</p><p>See <a href="https://wiki.archlinux.org/title/Makepkg">the wiki</a></p></div></div>
+</div>
+<div class="comments package-comments">
+  <div class="comments-header"><h3><span class="text">Latest Comments</span></h3>
+    <p class="comments-header-nav"><a class="page" href="/packages/yay?O=10">Next &rsaquo;</a></p>
+  </div>
+  <h4 id="comment-2" class="comment-header"><a href="/account/joe">joe</a> commented on <a href="#comment-2" class="date">2024-02-01 12:00 (UTC)</a></h4>
+  <div id="comment-2-content" class="article-content"><div><p>Build <b>fails</b> with:</p><pre><code>error: go
+  missing</code></pre><ul><li>one</li><li>two</li></ul><p><a href="https://example.org">https://example.org</a></p></div></div>
+</div>
+</body></html>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/packages/yay" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, page)
+	}))
+	defer srv.Close()
+
+	// ok
+	p, err := getAurComments(srv.URL+"/packages/yay", 0)
+	suite.Nil(err)
+	suite.True(p.HasMore)
+	suite.Len(p.Comments, 2)
+	suite.Equal(aurComment{
+		Author: "jane",
+		Date:   "2024-01-01 10:00 (UTC)",
+		Pinned: true,
+		Text:   "Import the key first:\ngpg --recv-keys ABC\n\nSee the wiki (https://wiki.archlinux.org/title/Makepkg)",
+	}, p.Comments[0])
+	suite.False(p.Comments[1].Pinned)
+	suite.Equal("joe", p.Comments[1].Author)
+	suite.Equal("Build fails with:\n\nerror: go\n  missing\n\n- one\n- two\n\nhttps://example.org", p.Comments[1].Text)
+
+	// last page
+	_, more, err := parseAurComments(strings.NewReader(page), 10)
+	suite.Nil(err)
+	suite.False(more)
+
+	// no comments
+	comments, more, err := parseAurComments(strings.NewReader("<html><body></body></html>"), 0)
+	suite.Nil(err)
+	suite.Len(comments, 0)
+	suite.False(more)
+
+	// nok
+	_, err = getAurComments(srv.URL+"/packages/nonsense", 0)
+	suite.NotNil(err, "wrong status did not return an error")
+	_, err = getAurComments("http://127.0.0.1:0", 0)
+	suite.NotNil(err, "unreachable AUR did not return an error")
+}
+
+// changelog
+func (suite *pacseekTestSuite) TestChangelog() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gitlab":
+			fmt.Fprint(w, `[{"short_id":"1a2b3c4d","message":"upgpkg: 1.0-2\n\nrebuild","author_name":"jane","created_at":"2024-03-01T10:00:00.000+01:00"}]`)
+		case "/github":
+			fmt.Fprint(w, `[{"sha":"0123456789abcdef","commit":{"message":"Update to 2.0","author":{"name":"joe","date":"2024-02-01T12:00:00Z"}}}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	// repo
+	entries, err := getChangelog(srv.URL+"/gitlab", false)
+	suite.Nil(err)
+	suite.Len(entries, 1)
+	suite.Equal("1a2b3c4d", entries[0].Id)
+	suite.Equal("jane", entries[0].Author)
+	suite.Equal("2024-03-01 1a2b3c4d jane\n    upgpkg: 1.0-2\n\n    rebuild", formatChangelog("", entries))
+
+	// AUR
+	entries, err = getChangelog(srv.URL+"/github", true)
+	suite.Nil(err)
+	suite.Equal([]changelogEntry{{Id: "01234567", Author: "joe", Date: time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC), Message: "Update to 2.0"}}, entries)
+
+	// nok
+	_, err = getChangelog(srv.URL+"/nothing", true)
+	suite.NotNil(err)
+
+	// urls
+	suite.Equal("https://gitlab.archlinux.org/api/v4/projects/archlinux%2Fpackaging%2Fpackages%2Fgtk2-extra/repository/commits?per_page=25", getChangelogUrl("extra", "gtk2+extra"))
+	suite.Equal("https://api.github.com/repos/archlinux/aur/commits?sha=yay&per_page=25", getChangelogUrl("AUR", "yay"))
+
+	// local changelog file
+	dir := suite.T().TempDir()
+	suite.Nil(os.MkdirAll(filepath.Join(dir, "local", "foo-1.0-1"), 0755))
+	suite.Nil(os.WriteFile(filepath.Join(dir, "local", "foo-1.0-1", "changelog"), []byte("1.0: initial release\n"), 0644))
+	local, found := localChangelog(dir, "foo", "1.0-1")
+	suite.True(found)
+	_, found = localChangelog(dir, "foo", "2.0-1")
+	suite.False(found)
+	suite.Equal("1.0: initial release\n\n2024-02-01 01234567 joe\n    Update to 2.0", formatChangelog(local, entries))
+	suite.Equal("", formatChangelog("", nil))
+}
+
+// transaction preview
+func (suite *pacseekTestSuite) TestTransactionPreview() {
+	out := `warning: nano-8.0-1 is up to date -- reinstalling
+core nano 8.0-1
+extra vim 9.1-1
+extra gvim 9.1-1
+core ncurses 6.5-1
+:: some garbage line here
+`
+	targets := parsePrintTargets(out)
+	suite.Equal([]previewEntry{
+		{Repo: "core", Name: "nano", Version: "8.0-1"},
+		{Repo: "extra", Name: "vim", Version: "9.1-1"},
+		{Repo: "extra", Name: "gvim", Version: "9.1-1"},
+		{Repo: "core", Name: "ncurses", Version: "6.5-1"},
+	}, targets)
+
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("core",
+				&mockPackage{name: "nano", version: "8.0-1", size: 100, isize: 400},
+				&mockPackage{name: "ncurses", version: "6.5-1", size: 200, isize: 1000},
+			),
+			newMockDB("extra",
+				&mockPackage{name: "vim", version: "9.1-1", size: 1000, isize: 4000, conflicts: mockDeps("vi")},
+				&mockPackage{name: "gvim", version: "9.1-1", size: 2000, isize: 8000},
+			),
+		},
+		local: newMockDB("local",
+			&mockPackage{name: "nano", version: "8.0-1", isize: 400},
+			&mockPackage{name: "ncurses", version: "6.6-1", isize: 900},
+			&mockPackage{name: "vi", version: "1:070224-6", isize: 300},
+			&mockPackage{name: "gvim-old", version: "1.0-1", isize: 50, conflicts: mockDeps("gvim")},
+		),
+	}
+
+	// ok
+	p, err := buildTransactionPreview(h, targets)
+	suite.Nil(err)
+	actions := map[string]string{}
+	for _, e := range p.Entries {
+		actions[e.Name] = e.Action
+	}
+	suite.Equal(map[string]string{
+		"nano":     "reinstall",
+		"vim":      "install",
+		"gvim":     "install",
+		"ncurses":  "downgrade",
+		"gvim-old": "remove",
+		"vi":       "remove",
+	}, actions)
+	suite.Equal("gvim-old", p.Entries[4].Name)
+	suite.Equal("gvim", p.Entries[4].ConflictsWith)
+	suite.Equal("vim", p.Entries[5].ConflictsWith)
+	suite.Equal("6.6-1", p.Entries[3].LocalVersion)
+	suite.Equal(int64(3300), p.DownloadSize)
+	suite.Equal(int64(0+4000+8000+100-50-300), p.InstalledSizeDelta)
+
+	// nok
+	_, err = buildTransactionPreview(nil, targets)
+	suite.NotNil(err)
+	_, err = buildTransactionPreview(&mockHandle{}, targets)
+	suite.NotNil(err)
+
+	// only repository installs are previewed
+	f := &flatpakSource{}
+	queue := []queuedPackage{
+		{InfoRecord: InfoRecord{Name: "vim", Source: "extra"}},
+		{InfoRecord: InfoRecord{Name: "nano", Source: "core"}, Installed: true},
+		{InfoRecord: InfoRecord{Name: "yay", Source: "AUR"}},
+		{InfoRecord: InfoRecord{Name: "org.gimp.GIMP", Source: "Flatpak"}},
+	}
+	suite.Equal([]string{"vim"}, repoInstallTargets([]packageSource{f}, queue))
+	suite.Len(repoInstallTargets(nil, queue[1:3]), 0)
+}
+
+func (suite *pacseekTestSuite) TestPackageGitFiles() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/PKGBUILD" {
+			fmt.Fprint(w, "pkgname=yay")
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	// ok
+	c, err := getPkgbuildContent(srv.URL + "/PKGBUILD")
+	suite.Nil(err, err)
+	suite.Equal("pkgname=yay", c)
+	suite.Equal("https://raw.githubusercontent.com/archlinux/aur/yay/.SRCINFO", getPackageFileUrl("AUR", "yay", fileSrcinfo))
+	suite.Equal(getPkgbuildUrl("AUR", "yay"), getPackageFileUrl("AUR", "yay", filePkgbuild))
+	suite.Equal(fileSrcinfo, otherPackageFile(filePkgbuild))
+	suite.Equal(filePkgbuild, otherPackageFile(fileSrcinfo))
+	suite.Equal("ini", packageFileLexer(fileSrcinfo))
+
+	// nok
+	_, err = getPkgbuildContent(srv.URL + "/.SRCINFO")
+	suite.NotNil(err, "missing file did not return an error")
+}
+
+// AUR packages that are available prebuilt in a third-party repository
+func (suite *pacseekTestSuite) TestMergePrebuilt() {
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("extra", &mockPackage{name: "vim", version: "9.1-1"}),
+			newMockDB("chaotic-aur",
+				&mockPackage{name: "yay", version: "12.3-1", isize: 100, buildDate: time.Unix(1700000000, 0)},
+				&mockPackage{name: "paru", version: "2.0-1"},
+			),
+			newMockDB("custom", &mockPackage{name: "yay", version: "12.2-1"}),
+		},
+		local: newMockDB("local"),
+	}
+	official := getArchRepos()
+	suite.Equal([]string{"chaotic-aur", "custom"}, thirdPartyRepos(h, official))
+	suite.Equal([]string{}, thirdPartyRepos(nil, official))
+
+	packages := []Package{
+		{Name: "vim", Source: "extra"},
+		{Name: "paru", Source: "chaotic-aur"},
+		{Name: "yay", Source: "AUR", NumVotes: 2000, IsInstalled: true},
+		{Name: "paru", Source: "AUR", NumVotes: 1000},
+		{Name: "pikaur", Source: "AUR"},
+	}
+	suite.Equal([]Package{
+		{Name: "vim", Source: "extra"},
+		{Name: "paru", Source: "chaotic-aur", AurAvailable: true},
+		{Name: "yay", Source: "chaotic-aur", NumVotes: 2000, IsInstalled: true, AurAvailable: true, HasBuildDate: true, LastModified: 1700000000, InstalledSize: 100},
+		{Name: "pikaur", Source: "AUR"},
+	}, mergePrebuilt(h, packages, official))
+
+	// no third-party repositories
+	suite.Equal(packages, mergePrebuilt(h, packages, []string{"extra", "chaotic-aur", "custom"}))
+	suite.Equal(packages, mergePrebuilt(nil, packages, official))
+}
+
+// background update checks / notifications
+func (suite *pacseekTestSuite) TestWatchUpgrades() {
+	up := []Upgrade{
+		{InfoRecord: InfoRecord{Name: "vim", Version: "9.1-2"}},
+		{InfoRecord: InfoRecord{Name: "linux", Version: "6.10-1", IsIgnored: true}},
+		{InfoRecord: InfoRecord{Name: "glibc", Version: "2.40-1"}},
+	}
+	suite.Equal(2, countUpgrades(up))
+
+	// only new upgrades are notified
+	notified := map[string]string{}
+	found := newUpgrades(up, notified)
+	suite.Equal([]string{"glibc", "vim"}, []string{found[0].Name, found[1].Name})
+	suite.Len(newUpgrades(up, notified), 0)
+	up[0].Version = "9.1-3"
+	found = newUpgrades(up, notified)
+	suite.Len(found, 1)
+
+	summary, body := upgradeNotification(found, countUpgrades(up))
+	suite.Equal("2 updates available", summary)
+	suite.Equal("New: vim 9.1-3", body)
+	many := []Upgrade{}
+	for i := 0; i < 12; i++ {
+		many = append(many, Upgrade{InfoRecord: InfoRecord{Name: fmt.Sprintf("pkg%02d", i), Version: "1-1"}})
+	}
+	_, body = upgradeNotification(many, 12)
+	suite.True(strings.HasSuffix(body, "pkg09 1-1, and 2 more"))
+
+	// quiet hours
+	at := func(h, m int) time.Time { return time.Date(2024, 1, 1, h, m, 0, 0, time.Local) }
+	q, err := parseQuietHours("22:00-07:30")
+	suite.Nil(err)
+	suite.True(q.contains(at(23, 0)))
+	suite.True(q.contains(at(7, 29)))
+	suite.False(q.contains(at(7, 30)))
+	suite.False(q.contains(at(12, 0)))
+	q, err = parseQuietHours("12:00 - 13:00")
+	suite.Nil(err)
+	suite.True(q.contains(at(12, 30)))
+	suite.False(q.contains(at(13, 0)))
+	q, err = parseQuietHours("")
+	suite.Nil(err)
+	suite.False(q.contains(at(12, 0)))
+
+	// nok
+	_, err = parseQuietHours("22:00")
+	suite.NotNil(err)
+	_, err = parseQuietHours("25:00-07:00")
+	suite.NotNil(err)
+	err = Watch(&config.Settings{UpdateCheckInterval: 0}, args.Flags{}, io.Discard)
+	suite.NotNil(err)
+	err = Watch(&config.Settings{UpdateCheckInterval: 60}, args.Flags{Offline: true}, io.Discard)
+	suite.NotNil(err)
+}
+
+func (suite *pacseekTestSuite) TestAurDiskCache() {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		requests++
+		if r.Form.Get("type") == "info" {
+			fmt.Fprint(w, `{"resultcount":1,"results":[{"Name":"yay","Version":"12.0.0-1"}],"type":"multiinfo","version":5}`)
+			return
+		}
+		fmt.Fprint(w, `{"resultcount":2,"results":[{"Name":"yay"},{"Name":"yay-bin"}],"type":"search","version":5}`)
+	}))
+	url := srv.URL
+	c := &diskCache{dir: suite.T().TempDir(), ttl: time.Minute}
+
+	// miss & hit
+	p, err := cachedSearchAur(c, url, "yay", 5000, "Contains", "Name", 20)
+	suite.Nil(err, err)
+	suite.Len(p, 2, "Number of packages != 2")
+	p, err = cachedSearchAur(c, url, "yay", 5000, "Contains", "Name", 20)
+	suite.Nil(err, err)
+	suite.Len(p, 2, "Number of packages != 2")
+	suite.Equal(1, requests, "cached search not used")
+
+	sr := cachedInfoAur(c, url, 5000, "yay")
+	suite.Len(sr.Results, 1, "Number of results != 1")
+	sr = cachedInfoAur(c, url, 5000, "yay")
+	suite.Len(sr.Results, 1, "Number of results != 1")
+	suite.Equal("12.0.0-1", sr.Results[0].Version)
+	suite.Equal(2, requests, "cached info not used")
+
+	// other search parameters
+	_, err = cachedSearchAur(c, url, "yay", 5000, "StartsWith", "Name", 20)
+	suite.Nil(err, err)
+	suite.Equal(3, requests, "search mode not part of the key")
+
+	// expired
+	entries, _ := os.ReadDir(c.dir)
+	old := time.Now().Add(-2 * time.Minute)
+	for _, e := range entries {
+		os.Chtimes(filepath.Join(c.dir, e.Name()), old, old)
+	}
+	_, err = cachedSearchAur(c, url, "yay", 5000, "Contains", "Name", 20)
+	suite.Nil(err, err)
+	suite.Equal(4, requests, "expired search used")
+
+	// stale entries when the AUR can't be reached
+	srv.Close()
+	for _, e := range entries {
+		os.Chtimes(filepath.Join(c.dir, e.Name()), old, old)
+	}
+	p, err = cachedSearchAur(c, url, "yay", 5000, "Contains", "Name", 20)
+	suite.Nil(err, err)
+	suite.Len(p, 2, "stale search not used")
+	sr = cachedInfoAur(c, url, 5000, "yay")
+	suite.Equal("", sr.Error)
+	suite.Len(sr.Results, 1, "stale info not used")
+	sr = cachedInfoAur(c, url, 5000, "yay", "paru")
+	suite.NotEqual("", sr.Error)
+	_, err = cachedSearchAur(c, url, "paru", 5000, "Contains", "Name", 20)
+	suite.NotNil(err)
+
+	// clear
+	suite.Nil(c.clear())
+	_, err = cachedSearchAur(c, url, "yay", 5000, "Contains", "Name", 20)
+	suite.NotNil(err)
+
+	// disabled
+	conf := config.Defaults()
+	conf.DisableDiskCache = true
+	var nc *diskCache
+	suite.Equal(nc, newAurDiskCache(conf))
+	suite.Nil(nc.set("key", "value"))
+	suite.False(nc.get("key", new(string), true))
+	suite.Nil(nc.clear())
+	_, err = cachedSearchAur(nil, url, "yay", 5000, "Contains", "Name", 20)
+	suite.NotNil(err)
+	sr = cachedInfoAur(nil, url, 5000, "yay")
+	suite.NotEqual("", sr.Error)
+}
+
+func (suite *pacseekTestSuite) TestSummarizeGroups() {
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("core",
+				&mockPackage{name: "gcc", version: "14.2-1", groups: []string{"base-devel"}},
+				&mockPackage{name: "make", version: "4.4-1", groups: []string{"base-devel"}},
+			),
+			newMockDB("extra",
+				&mockPackage{name: "gnome-shell", version: "47.0-1", groups: []string{"gnome"}},
+			),
+		},
+		local: newMockDB("local",
+			&mockPackage{name: "make", version: "4.4-1"},
+			&mockPackage{name: "gnome-shell", version: "47.0-1"},
+		),
+	}
+
+	// ok
+	g, err := summarizeGroups(h, []string{"base-devel", "gnome", "nonsense"})
+	suite.Nil(err, err)
+	suite.Equal([]packageGroup{
+		{Name: "base-devel", Members: 2, Installed: 1},
+		{Name: "gnome", Members: 1, Installed: 1},
+		{Name: "nonsense", Members: 0, Installed: 0},
+	}, g)
+
+	g, err = summarizeGroups(h, []string{})
+	suite.Nil(err, err)
+	suite.Equal([]packageGroup{}, g)
+
+	// nok
+	_, err = summarizeGroups(nil, []string{"gnome"})
+	suite.NotNil(err, "nil handle did not return an error")
+}
+
+func (suite *pacseekTestSuite) TestSearchByProvides() {
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("extra",
+				&mockPackage{name: "jre-openjdk", version: "23-1", desc: "java-runtime environment", provides: mockDeps("java-runtime=23", "jre")},
+				&mockPackage{name: "jre17-openjdk", version: "17-1", provides: mockDeps("java-runtime=17")},
+				&mockPackage{name: "java-runtime-common", version: "3-5", replaces: mockDeps("java-common")},
+			),
+		},
+		local: newMockDB("local"),
+	}
+
+	// repositories
+	p, _, err := searchRepos(h, "java-runtime", "StartsWith", "Provides", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal([]string{"jre-openjdk", "jre17-openjdk", "java-runtime-common"}, packageNames(p))
+	suite.Equal("Provides", p[0].MatchedField)
+	suite.Equal("Name", p[2].MatchedField)
+	p, _, err = searchRepos(h, "environment", "Contains", "Provides", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Equal([]string{}, packageNames(p), "description matched")
+
+	// provides / conflicts / replaces in our package info
+	i := infoPacman(h, false, "java-runtime-common")
+	suite.Len(i.Results, 1)
+	suite.Equal([]string{"java-common"}, i.Results[0].Replaces)
+	i = infoPacman(h, false, "jre-openjdk")
+	suite.Equal([]string{"java-runtime=23", "jre"}, i.Results[0].Provides)
+
+	// AUR
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		fmt.Fprint(w, `{"resultcount":2,"results":[{"Name":"jdk-temurin","Description":"Eclipse Temurin"},{"Name":"zulu-jre"}],"type":"search","version":5}`)
+	}))
+	defer srv.Close()
+
+	for _, mode := range []string{"Exact", "StartsWith", "Contains"} {
+		p, err = searchAur(srv.URL, "java-runtime", 5000, mode, "Provides", 20)
+		suite.Nil(err, err)
+		suite.Contains(query, "type=search&by=provides", mode)
+		suite.Equal([]string{"jdk-temurin", "zulu-jre"}, packageNames(p), mode)
+	}
+}
+
+func (suite *pacseekTestSuite) TestUnreadNews() {
+	dir := suite.T().TempDir()
+
+	// last system upgrade
+	logFile := filepath.Join(dir, "pacman.log")
+	os.WriteFile(logFile, []byte(`[2019-01-01 12:34] [PACMAN] starting full system upgrade
+[2024-03-10T12:34:56+0100] [PACMAN] Running 'pacman -Syu'
+[2024-03-10T12:34:56+0100] [PACMAN] starting full system upgrade
+[2024-03-10T12:35:01+0100] [ALPM] upgraded glibc (2.39-1 -> 2.39-2)
+[2024-03-12T08:00:00+0100] [PACMAN] Running 'pacman -S vim'
+`), 0644)
+	last, err := lastSysUpgrade(logFile)
+	suite.Nil(err, err)
+	suite.Equal(time.Date(2024, 3, 10, 11, 34, 56, 0, time.UTC), last.UTC())
+
+	os.WriteFile(logFile, []byte("[2019-01-01 12:34] [PACMAN] starting full system upgrade\n"), 0644)
+	last, err = lastSysUpgrade(logFile)
+	suite.Nil(err, err)
+	suite.Equal(time.Date(2019, 1, 1, 12, 34, 0, 0, time.Local), last)
+
+	os.WriteFile(logFile, []byte("[2024-03-12T08:00:00+0100] [PACMAN] Running 'pacman -S vim'\n"), 0644)
+	last, err = lastSysUpgrade(logFile)
+	suite.Nil(err, err)
+	suite.True(last.IsZero())
+
+	_, err = lastSysUpgrade(filepath.Join(dir, "nonsense.log"))
+	suite.NotNil(err)
+
+	// unread items
+	date := func(d int) *time.Time {
+		t := time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	items := []*gofeed.Item{
+		{Title: "new", GUID: "https://archlinux.org/news/new/", PublishedParsed: date(20)},
+		{Title: "read", Link: "https://archlinux.org/news/read/", PublishedParsed: date(15)},
+		{Title: "old", GUID: "https://archlinux.org/news/old/", PublishedParsed: date(1)},
+		{Title: "no date", GUID: "https://archlinux.org/news/nodate/"},
+	}
+	since := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	unread := unreadNews(items, since, map[string]bool{"https://archlinux.org/news/read/": true})
+	suite.Len(unread, 1)
+	suite.Equal("new", unread[0].Title)
+	suite.Len(unreadNews(items, time.Time{}, map[string]bool{}), 3)
+
+	// read state
+	stateFile := filepath.Join(dir, "pacseek", "news.json")
+	suite.Equal(map[string]bool{}, loadReadNews(stateFile))
+	suite.Nil(saveReadNews(stateFile, map[string]bool{newsId(items[0]): true, newsId(items[1]): true}))
+	read := loadReadNews(stateFile)
+	suite.Equal(map[string]bool{"https://archlinux.org/news/new/": true, "https://archlinux.org/news/read/": true}, read)
+	suite.Len(unreadNews(items, since, read), 0)
+
+	os.WriteFile(stateFile, []byte("nonsense"), 0644)
+	suite.Equal(map[string]bool{}, loadReadNews(stateFile))
+
+	// pacman.conf
+	suite.Equal("/var/log/pacman.log", pacmanLogFile(filepath.Join(dir, "nonsense.conf")))
+}
+
+func (suite *pacseekTestSuite) TestUpgradeIgnoreRules() {
+	pacRules := IgnoreRules{Packages: []string{"linux"}, Groups: []string{"gnome"}}
+
+	// pacman.conf rules and our ignore list
+	ignore := upgradeIgnoreRules(pacRules, []string{"mutter", "nvidia*"})
+	suite.Equal([]string{"linux", "mutter", "nvidia*"}, ignore.Packages)
+	suite.Equal([]string{"gnome"}, ignore.Groups)
+	suite.Equal([]string{"linux"}, pacRules.Packages)
+	ignore = upgradeIgnoreRules(IgnoreRules{Packages: []string{"linux"}}, []string{"mutter", "nvidia*"})
+	suite.True(ignore.matches(nil, "linux"))
+	suite.True(ignore.matches(nil, "nvidia-utils"))
+	suite.True(ignore.matches(nil, "mutter"))
+	suite.False(ignore.matches(nil, "glibc"))
+
+	suite.Len(upgradeIgnoreRules(IgnoreRules{}, []string{}).Packages, 0)
+
+	// nok
+	conf := config.Defaults()
+	conf.PacmanConfigPath = filepath.Join(suite.T().TempDir(), "nonsense.conf")
+	_, err := findUpgrades(conf, nil)
+	suite.NotNil(err, "unreadable pacman.conf not reported")
+}
+
+func (suite *pacseekTestSuite) TestPartialResults() {
+	sources := []searchSource{{name: "repositories"}, {name: "AUR"}, {name: "sources"}}
+	results := map[string][]Package{
+		"AUR":          {{Name: "yay", Source: "AUR"}, {Name: "paru", Source: "AUR"}},
+		"repositories": {{Name: "pacman", Source: "core"}},
+	}
+	conf := config.Defaults()
+	conf.MaxResults = 2
+
+	// sorted and limited
+	p := partialResults(results, sources, conf, "")
+	suite.Equal([]string{"pacman", "paru"}, packageNames(p))
+
+	// nothing yet
+	suite.Equal([]Package{}, partialResults(map[string][]Package{}, sources, conf, ""))
+}
+
+func (suite *pacseekTestSuite) TestCachedSearchAurCtx() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"resultcount":1,"results":[{"Name":"yay"}],"type":"search","version":5}`)
+	}))
+	c := &diskCache{dir: suite.T().TempDir(), ttl: time.Minute}
+	_, err := cachedSearchAurCtx(context.Background(), c, srv.URL, "yay", 5000, "Contains", "Name", 20)
+	suite.Nil(err, err)
+
+	// expired entries are not used for cancelled searches
+	srv.Close()
+	c.ttl = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = cachedSearchAurCtx(ctx, c, srv.URL, "yay", 5000, "Contains", "Name", 20)
+	suite.ErrorIs(err, context.Canceled)
+	p, err := cachedSearchAurCtx(context.Background(), c, srv.URL, "yay", 5000, "Contains", "Name", 20)
+	suite.Nil(err, err)
+	suite.Equal([]string{"yay"}, packageNames(p))
+}
+
+func (suite *pacseekTestSuite) TestComputeStats() {
+	day := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("core", &mockPackage{name: "glibc"}, &mockPackage{name: "bash"}),
+			newMockDB("extra", &mockPackage{name: "firefox"}),
+		},
+		local: newMockDB("local",
+			&mockPackage{name: "glibc", isize: 500, reason: alpm.PkgReasonDepend, requiredBy: []string{"bash"}, installDate: day},
+			&mockPackage{name: "bash", isize: 100, reason: alpm.PkgReasonExplicit, installDate: day.Add(2 * time.Hour)},
+			&mockPackage{name: "firefox", isize: 900, reason: alpm.PkgReasonExplicit, installDate: day.Add(time.Hour)},
+			&mockPackage{name: "yay", isize: 50, reason: alpm.PkgReasonDepend, installDate: day.Add(3 * time.Hour)},
+		),
+	}
+
+	// ok
+	stats, err := computeStats(h)
+	suite.Nil(err, err)
+	suite.Equal(4, stats.Installed, "installed count wrong")
+	suite.Equal(2, stats.Explicit, "explicit count wrong")
+	suite.Equal(2, stats.Dependencies, "dependency count wrong")
+	suite.Equal(1, stats.Orphans, "orphan count wrong")
+	suite.Equal(1, stats.Foreign, "foreign count wrong")
+	suite.Equal(int64(1550), stats.InstalledSize, "installed size wrong")
+	suite.Equal([]repoStats{{"core", 2, 600}, {"extra", 1, 900}, {"foreign", 1, 50}}, stats.Repos)
+
+	names := func(pkgs []statsPackage) []string {
+		n := []string{}
+		for _, p := range pkgs {
+			n = append(n, p.Name)
+		}
+		return n
+	}
+	suite.Equal([]string{"firefox", "glibc", "bash", "yay"}, names(stats.Largest))
+	suite.Equal([]string{"yay", "bash", "firefox", "glibc"}, names(stats.Recent))
+
+	// nok
+	_, err = computeStats(nil)
+	suite.NotNil(err, "no error for nil handle")
+}
+
+func (suite *pacseekTestSuite) TestSearchAurMaintainerOrphaned() {
+	var by string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		by = r.Form.Get("by")
+		fmt.Fprint(w, `{"resultcount":2,"results":[{"Name":"pacseek","Maintainer":"moson"},{"Name":"pacseek-bin","Maintainer":null}],"type":"search","version":5}`)
+	}))
+	defer srv.Close()
+
+	// ok
+	p, err := searchAur(srv.URL, "moson", 5000, "StartsWith", "Maintainer", 20)
+	suite.Nil(err, err)
+	suite.Equal("maintainer", by, "maintainer search not requested")
+	suite.Len(p, 2, "Number of packages != 2")
+	suite.False(p[0].Orphaned, "maintained package flagged as orphaned")
+	suite.True(p[1].Orphaned, "package without maintainer not flagged as orphaned")
+}
+
+func (suite *pacseekTestSuite) TestSearchAurOutOfDate() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"resultcount":2,"results":[{"Name":"yay","Maintainer":"jguer"},{"Name":"yay-git","Maintainer":"jguer","OutOfDate":1672531200}],"type":"search","version":5}`)
+	}))
+	defer srv.Close()
+
+	// ok
+	p, err := searchAur(srv.URL, "yay", 5000, "StartsWith", "Name", 20)
+	suite.Nil(err, err)
+	suite.Len(p, 2, "Number of packages != 2")
+	suite.Equal(0, p[0].OutOfDate)
+	suite.Equal(1672531200, p[1].OutOfDate)
+
+	// filter
+	p = filterOutOfDate(p)
+	suite.Len(p, 1, "Number of packages != 1")
+	suite.Equal("yay", p[0].Name)
+	suite.Equal([]Package{}, filterOutOfDate([]Package{}), "[]Packages not empty")
+}
+
+func (suite *pacseekTestSuite) TestPacmanLogHistory() {
+	dir := suite.T().TempDir()
+	logFile := filepath.Join(dir, "pacman.log")
+	suite.Nil(os.WriteFile(logFile, []byte(`[2019-01-01 12:34] [ALPM] installed vim (8.1-1)
+[2024-03-10T12:00:00+0100] [PACMAN] Running 'pacman -Syu'
+[2024-03-10T12:00:01+0100] [ALPM] upgraded vim (8.1-1 -> 9.1-1)
+[2024-03-10T12:00:02+0100] [ALPM] upgraded vim-runtime (9.0-1 -> 9.1-1)
+[2024-03-10T12:00:03+0100] [ALPM-SCRIPTLET] installed something (1.0)
+[2024-03-10T12:00:04+0100] [ALPM] running 'texinfo-install.hook'...
+`), 0644))
+
+	// ok
+	l := newPacmanLog(logFile)
+	suite.Nil(l.update())
+	suite.Len(l.entries, 3, "Number of entries != 3")
+	suite.True(l.entries[1].Date.Equal(time.Date(2024, 3, 10, 11, 0, 1, 0, time.UTC)), "date wrong")
+	suite.Equal("upgraded", l.entries[1].Action)
+	suite.Equal("vim", l.entries[1].Name)
+	suite.Equal("8.1-1", l.entries[1].OldVersion)
+	suite.Equal("9.1-1", l.entries[1].Version)
+	suite.Equal("8.1-1", l.entries[0].Version)
+	suite.Equal(2019, l.entries[0].Date.Year())
+
+	// incremental updates, incomplete lines are read later
+	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_WRONLY, 0644)
+	suite.Nil(err, err)
+	_, err = f.WriteString("[2024-03-11T08:00:00+0100] [ALPM] removed vim-runtime (9.1-1)\n[2024-03-11T08:00:01+0100] [ALPM] downgraded vim (9.1-1 -> 9.0-1")
+	suite.Nil(err, err)
+	suite.Nil(l.update())
+	suite.Len(l.entries, 4, "Number of entries != 4")
+	_, err = f.WriteString(")\n")
+	suite.Nil(err, err)
+	suite.Nil(f.Close())
+	suite.Nil(l.update())
+	suite.Len(l.entries, 5, "Number of entries != 5")
+	suite.Equal("downgraded", l.entries[4].Action)
+	suite.Equal("9.0-1", l.entries[4].Version)
+
+	// filter
+	h := filterHistory(l.entries, "VIM")
+	suite.Len(h, 5, "Number of entries != 5")
+	suite.Equal("downgraded", h[0].Action, "newest entry not first")
+	h = filterHistory(l.entries, "=vim")
+	suite.Len(h, 3, "Number of entries != 3")
+	suite.Equal([]historyEntry{}, filterHistory(l.entries, "nonsense"), "entries not empty")
+
+	// rotated log file
+	suite.Nil(os.WriteFile(logFile, []byte("[2024-03-12T08:00:00+0100] [ALPM] installed yay (12.0-1)\n"), 0644))
+	suite.Nil(l.update())
+	suite.Len(l.entries, 1, "Number of entries != 1")
+	suite.Equal("yay", l.entries[0].Name)
+
+	// nok
+	suite.NotNil(newPacmanLog(filepath.Join(dir, "nonsense.log")).update(), "no error for missing log file")
+}
+
+func (suite *pacseekTestSuite) TestCommandTemplates() {
+	conf := config.Defaults()
+
+	// placeholders
+	suite.Equal("paru -S vim --needed", expandCommand("paru -S {pkg} --needed", InfoRecord{Name: "vim"}))
+	suite.Equal("echo extra && sudo pacman -S vim", expandCommand("echo {source} && sudo pacman -S {pkg}", InfoRecord{Name: "vim", Source: "extra"}))
+	conf.InstallCommand = "sudo pacman -S --needed {source}/{pkg}"
+	suite.Equal([]string{"sudo pacman -S --needed extra/vim", "sudo pacman -S --needed core/bash"}, batchCommands(conf, nil, []queuedPackage{
+		{InfoRecord: InfoRecord{Name: "vim", Source: "extra"}},
+		{InfoRecord: InfoRecord{Name: "bash", Source: "core"}},
+	}))
+
+	// flatpak
+	f := &flatpakSource{installCommand: "flatpak install --user {pkgbase} {pkg}"}
+	suite.Equal("flatpak install --user flathub org.gimp.GIMP", f.InstallCommand(InfoRecord{Name: "org.gimp.GIMP", PackageBase: "flathub"}))
+	suite.Equal("flatpak uninstall org.gimp.GIMP", f.UninstallCommand(InfoRecord{Name: "org.gimp.GIMP"}))
+
+	// validation
+	suite.Nil(validateCommands(config.Defaults()))
+	suite.Nil(validateCommand("Install command", "paru -S {pkg} {optdepends}"))
+	suite.NotNil(validateCommand("Install command", "paru -S {pkgs}"), "unknown placeholder not reported")
+	suite.NotNil(validateCommand("Install command", "paru -S {package}"), "unknown placeholder not reported")
+	suite.NotNil(validateCommand("Install command", "  "), "empty command not reported")
+	conf = config.Defaults()
+	conf.EnableFlatpak = true
+	conf.FlatpakInstallCommand = "flatpak install {remote} {pkg}"
+	suite.NotNil(validateCommands(conf), "unknown placeholder not reported")
+	conf.EnableFlatpak = false
+	suite.Nil(validateCommands(conf), "unused command validated")
+	conf.AurUseDifferentCommands = true
+	suite.Nil(validateCommands(conf), "empty AUR commands reported")
+}
+
+func (suite *pacseekTestSuite) TestMirrorHealth() {
+	now := time.Now().UTC()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/status/json/":
+			fmt.Fprintf(w, `{"urls":[{"url":"%s/fast/","last_sync":"%s"},{"url":"%s/old/","last_sync":"%s"},{"url":"%s/never/","last_sync":null}]}`,
+				"http://"+r.Host, now.Add(-time.Hour).Format(time.RFC3339), "http://"+r.Host, now.Add(-72*time.Hour).Format(time.RFC3339), "http://"+r.Host)
+		case "/fast/core/os/x86_64/core.db", "/old/core/os/x86_64/core.db":
+			fmt.Fprint(w, strings.Repeat("x", 1024))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	// mirrorlist
+	servers := parseMirrorlist(strings.NewReader(`## Worldwide
+#Server = ` + srv.URL + `/commented/$repo/os/$arch
+Server = ` + srv.URL + `/fast/$repo/os/$arch
+ Server=` + srv.URL + `/old/$repo/os/$arch
+
+Server = ` + srv.URL + `/broken/$repo/os/$arch
+`))
+	suite.Equal([]string{srv.URL + "/fast/$repo/os/$arch", srv.URL + "/old/$repo/os/$arch", srv.URL + "/broken/$repo/os/$arch"}, servers)
+	suite.Equal(srv.URL+"/fast/", mirrorBaseURL(servers[0]))
+
+	// status
+	synced, err := getMirrorStatus(srv.URL + "/status/json/")
+	suite.Nil(err, err)
+	suite.Len(synced, 2, "never synced mirror not skipped")
+
+	// checks
+	mirrors := checkMirrors(servers, "x86_64", synced)
+	suite.Len(mirrors, 3, "Number of mirrors != 3")
+	suite.Equal("", mirrors[0].Error)
+	suite.Greater(mirrors[0].Throughput, 0.0, "throughput not measured")
+	suite.Equal("", mirrors[1].Error)
+	suite.NotEqual("", mirrors[2].Error, "error empty")
+	suite.True(mirrors[2].LastSync.IsZero(), "unknown mirror has a last sync")
+
+	outdated := outdatedMirrors(mirrors, now)
+	suite.Len(outdated, 1, "Number of outdated mirrors != 1")
+	suite.Equal(servers[1], outdated[0].Server)
+	suite.Equal("3d0h", formatLag(72*time.Hour))
+	suite.Equal("1h05m", formatLag(65*time.Minute))
+
+	// nok
+	_, err = getMirrorStatus(srv.URL + "/nonsense")
+	suite.NotNil(err, err)
+	_, err = readMirrorlist(filepath.Join(suite.T().TempDir(), "nonsense"))
+	suite.NotNil(err, err)
+}
+
+func (suite *pacseekTestSuite) TestPackageSnapshot() {
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("core", &mockPackage{name: "bash"}, &mockPackage{name: "glibc"}), newMockDB("extra", &mockPackage{name: "vim"}, &mockPackage{name: "git"})},
+		local: newMockDB("local",
+			&mockPackage{name: "bash", reason: alpm.PkgReasonExplicit},
+			&mockPackage{name: "glibc", reason: alpm.PkgReasonDepend},
+			&mockPackage{name: "vim", reason: alpm.PkgReasonExplicit},
+			&mockPackage{name: "yay", reason: alpm.PkgReasonExplicit},
+		),
+	}
+
+	// export
+	snap, err := takeSnapshot(h)
+	suite.Nil(err, err)
+	suite.Equal(packageSnapshot{Native: []string{"bash", "vim"}, Foreign: []string{"yay"}}, snap)
+	var b bytes.Buffer
+	suite.Nil(snap.write(&b))
+	parsed, err := parseSnapshot(&b)
+	suite.Nil(err, err)
+	suite.Equal(snap, parsed, "snapshot changed after writing / parsing")
+
+	// import
+	parsed, err = parseSnapshot(strings.NewReader("# comment\nbash\ngit\n\n[foreign]\nparu\nnonsense\n"))
+	suite.Nil(err, err)
+	suite.Equal([]string{"bash", "git"}, parsed.Native)
+	suite.Equal([]string{"paru", "nonsense"}, parsed.Foreign)
+	diff, err := diffSnapshot(h, parsed, func(names ...string) map[string]bool {
+		return map[string]bool{"paru": true}
+	})
+	suite.Nil(err, err)
+	suite.Equal([]Package{{Name: "git", Source: "extra"}, {Name: "paru", Source: "AUR"}}, diff.Missing)
+	suite.Equal([]string{"nonsense"}, diff.Unavailable)
+	suite.Equal([]string{"vim", "yay"}, diff.Extra)
+
+	// nok
+	_, err = parseSnapshot(strings.NewReader("[nonsense]\nbash\n"))
+	suite.NotNil(err, "no error for unknown section")
+	_, err = parseSnapshot(strings.NewReader("bash git\n"))
+	suite.NotNil(err, "no error for invalid line")
+	_, err = takeSnapshot(nil)
+	suite.NotNil(err, "no error for nil handle")
+}
+
+func (suite *pacseekTestSuite) TestSettingsValidation() {
+	dir := suite.T().TempDir()
+	file := filepath.Join(dir, "pacman.conf")
+	suite.Nil(os.WriteFile(file, []byte("[options]\n"), 0644))
+
+	// URL's
+	suite.Nil(validateSetting("AUR RPC URL: ", "https://aur.archlinux.org/rpc"))
+	suite.NotNil(validateSetting("AUR RPC URL: ", "aur.archlinux.org/rpc"), "URL without scheme not reported")
+	suite.NotNil(validateSetting("AUR RPC URL: ", "ftp://aur.archlinux.org"), "non-http URL not reported")
+
+	// paths
+	suite.Nil(validateSetting("Pacman config path: ", file))
+	suite.NotNil(validateSetting("Pacman config path: ", dir), "directory not reported")
+	suite.NotNil(validateSetting("Pacman config path: ", filepath.Join(dir, "missing.conf")), "missing file not reported")
+	suite.Nil(validateSetting("Pacman DB path: ", dir))
+	suite.NotNil(validateSetting("Pacman DB path: ", file), "file not reported")
+	suite.NotNil(validateSetting("Pacman root path: ", filepath.Join(dir, "missing")), "missing directory not reported")
+
+	// commands
+	suite.Nil(validateSetting("Install command: ", "sh -c {pkg}"))
+	suite.Nil(validateSetting("Install command: ", "cd /tmp && nonsense-binary -S"), "shell syntax checked for programs")
+	suite.ErrorIs(validateSetting("Install command: ", "nonsense-binary -S"), errNotInPath, "missing program not reported")
+	suite.ErrorIs(validateSetting("Install command: ", "sudo nonsense-binary -S"), errNotInPath, "missing program after sudo not reported")
+	suite.False(errors.Is(validateSetting("Install command: ", "sh -c {package}"), errNotInPath), "unknown placeholder is a warning")
+	suite.NotNil(validateSetting("Install command: ", "sh -c {package}"), "unknown placeholder not reported")
+	suite.NotNil(validateSetting("Install command: ", ""), "empty command not reported")
+	suite.Nil(validateSetting("AUR Install command: ", ""), "empty AUR command reported")
+	suite.Nil(validateSetting("Merge command: ", "sh -c {original} {file}"))
+	suite.ErrorIs(validateSetting("Merge command: ", "nonsense-binary {original} {file}"), errNotInPath, "missing merge program not reported")
+	suite.NotNil(validateSetting("Merge command: ", "sh -c {pkg}"), "unknown placeholder not reported")
+
+	// fields without validation
+	suite.Nil(validateSetting("Search mode: ", "anything"))
+}
+
+func (suite *pacseekTestSuite) TestChrootBuild() {
+	conf := config.Defaults()
+	conf.InstallCommand = "yay -S"
+	vim := InfoRecord{Name: "vim-git", PackageBase: "vim-git", Source: "AUR"}
+	bash := InfoRecord{Name: "bash", Source: "core"}
+
+	// which packages are built in a chroot
+	suite.False(useChroot(conf, vim, false))
+	conf.AurChrootPackages = []string{"vim-git"}
+	suite.True(useChroot(conf, vim, false))
+	suite.False(useChroot(conf, vim, true), "removal built in chroot")
+	conf.AurChrootPackages = []string{}
+	conf.AurChrootBuild = true
+	suite.True(useChroot(conf, vim, false))
+	suite.False(useChroot(conf, bash, false), "repo package built in chroot")
+
+	// tool detection
+	found := func(programs ...string) func(string) (string, error) {
+		return func(program string) (string, error) {
+			if util.SliceContains(programs, program) {
+				return "/usr/bin/" + program, nil
+			}
+			return "", errors.New("not found")
+		}
+	}
+	build, err := chrootBuildCommand(conf, found("extra-x86_64-build", "pkgctl"))
+	suite.Nil(err)
+	suite.Equal("pkgctl build", build)
+	build, _ = chrootBuildCommand(conf, found("extra-x86_64-build"))
+	suite.Equal("extra-x86_64-build", build)
+	_, err = chrootBuildCommand(conf, found())
+	suite.NotNil(err, "missing tools not reported")
+	conf.AurChrootCommand = "podman run --rm -v .:/pkg builder"
+	build, err = chrootBuildCommand(conf, found())
+	suite.Nil(err)
+	suite.Equal(conf.AurChrootCommand, build)
+
+	// commands
+	suite.Equal("(dir=$(mktemp -d) && git clone https://aur.archlinux.org/vim-git.git \"$dir\" && cd \"$dir\" && podman run --rm -v .:/pkg builder && sudo pacman -U $(find . -maxdepth 1 -name '*.pkg.tar.*' ! -name '*.sig'))",
+		packageCommand(conf, vim, false))
+	suite.Equal("yay -Rs vim-git", packageCommand(conf, vim, true))
+	commands := batchCommands(conf, nil, []queuedPackage{{InfoRecord: vim}, {InfoRecord: bash}})
+	suite.Len(commands, 2)
+	suite.Equal("yay -S bash", commands[0])
+	suite.Contains(commands[1], "aur.archlinux.org/vim-git.git")
+}
+
+func (suite *pacseekTestSuite) TestDebouncer() {
+	var d debouncer
+	calls := make(chan string, 3)
+	for _, term := range []string{"v", "vi", "vim"} {
+		term := term
+		d.call(20*time.Millisecond, func() {
+			calls <- term
+		})
+	}
+	suite.Equal("vim", <-calls)
+	time.Sleep(40 * time.Millisecond)
+	suite.Len(calls, 0, "replaced calls have been run")
+
+	// cancelled
+	d.call(10*time.Millisecond, func() {
+		calls <- "vim"
+	})
+	d.stop()
+	time.Sleep(30 * time.Millisecond)
+	suite.Len(calls, 0, "stopped call has been run")
+}
+
+func (suite *pacseekTestSuite) TestColorThemes() {
+	dir := suite.T().TempDir()
+	suite.T().Setenv("XDG_CONFIG_HOME", dir)
+	suite.Nil(os.MkdirAll(filepath.Join(dir, "pacseek", "themes"), 0755))
+	suite.Nil(os.WriteFile(filepath.Join(dir, "pacseek", "themes", "mine.json"), []byte(`{"Accent": "#ff0000", "Warning": "208", "Error": "darkred", "Background": "1c1c1c"}`), 0644))
+	suite.Nil(os.WriteFile(filepath.Join(dir, "pacseek", "themes", "broken.json"), []byte(`{"Accent": "nocolor"}`), 0644))
+
+	suite.Contains(config.ColorSchemes(), "mine")
+	suite.Contains(config.ColorSchemes(), "Nord")
+
+	// user theme, undefined colors are taken from our default scheme
+	conf := config.Defaults()
+	suite.Nil(conf.SetColorScheme("mine"))
+	conf.SetTransparency(false)
+	suite.Equal(tcell.NewHexColor(0xff0000), conf.Colors().Accent)
+	suite.Equal(tcell.PaletteColor(208), conf.Colors().Warning)
+	suite.Equal(tcell.ColorDarkRed, conf.Colors().Error)
+	suite.Equal(tcell.NewHexColor(0x1c1c1c), conf.Colors().DefaultBackground)
+	suite.Equal(config.Defaults().Colors().Title, conf.Colors().Title)
+	suite.Equal(tcell.ColorWhite, conf.Colors().Text)
+	conf.SetTransparency(true)
+	suite.Equal(tcell.ColorDefault, conf.Colors().DefaultBackground)
+
+	suite.NotNil(conf.SetColorScheme("broken"), "invalid color not reported")
+	suite.NotNil(conf.SetColorScheme("missing"), "missing theme not reported")
+	suite.Equal(config.Defaults().Colors().Accent, conf.Colors().Accent)
+
+	// terminal background detection
+	suite.T().Setenv("COLORFGBG", "0;15")
+	suite.Nil(conf.SetColorScheme("Auto"))
+	suite.Equal(tcell.ColorWhite, conf.Colors().Background)
+	suite.T().Setenv("COLORFGBG", "15;default;0")
+	suite.Nil(conf.SetColorScheme("Auto"))
+	suite.Equal(config.Defaults().Colors().Accent, conf.Colors().Accent)
+}
+
+func (suite *pacseekTestSuite) TestKeymap() {
+	// defaults
+	km, err := newKeymap(nil)
+	suite.Nil(err)
+	suite.True(km.matches("Quit", tcell.NewEventKey(tcell.KeyCtrlQ, 0, tcell.ModCtrl)))
+	suite.True(km.matches("SortByModified", tcell.NewEventKey(tcell.KeyRune, 'M', tcell.ModNone)))
+	suite.True(km.matches("Mirrors", tcell.NewEventKey(tcell.KeyRune, 'T', tcell.ModNone)))
+	suite.True(km.matches("Queue", tcell.NewEventKey(tcell.KeyRune, ' ', tcell.ModNone)))
+	suite.True(km.matches("NextBox", tcell.NewEventKey(tcell.KeyTab, 0, tcell.ModNone)))
+	suite.False(km.matches("Quit", tcell.NewEventKey(tcell.KeyRune, 'q', tcell.ModNone)))
+	suite.Equal("CTRL+Q", km.label("Quit"))
+	suite.Equal("Shift+F", km.label("LocalFilter"))
+	suite.Equal("SPACE", km.label("Queue"))
+	suite.Equal("ENTER", km.label("Install"))
+
+	// parsing
+	for s, b := range map[string]keyBinding{
+		"ctrl+c":     {key: tcell.KeyCtrlC, mods: tcell.ModCtrl},
+		"Shift+m":    {key: tcell.KeyRune, ch: 'M'},
+		"Alt+x":      {key: tcell.KeyRune, ch: 'x', mods: tcell.ModAlt},
+		"f5":         {key: tcell.KeyF5},
+		"Shift+Left": {key: tcell.KeyLeft, mods: tcell.ModShift},
+		"Shift++":    {key: tcell.KeyRune, ch: '+'},
+		"Escape":     {key: tcell.KeyEsc},
+	} {
+		parsed, err := parseKeyBinding(s)
+		suite.Nil(err, s)
+		suite.Equal(b, parsed, s)
+	}
+	for _, s := range []string{"Hyper+x", "Ctrl+1", "Ctrl+i", "Foo"} {
+		_, err := parseKeyBinding(s)
+		suite.NotNil(err, s)
+	}
+	suite.Equal("ALT+x", keyBinding{key: tcell.KeyRune, ch: 'x', mods: tcell.ModAlt}.String())
+	suite.Equal("Shift+Left", keyBinding{key: tcell.KeyLeft, mods: tcell.ModShift}.String())
+
+	// user bindings
+	km, err = newKeymap(map[string]string{"Quit": "Ctrl+C", "Mirrors": "Alt+m"})
+	suite.Nil(err)
+	suite.True(km.matches("Quit", tcell.NewEventKey(tcell.KeyCtrlC, 0, tcell.ModCtrl)))
+	suite.False(km.matches("Quit", tcell.NewEventKey(tcell.KeyCtrlQ, 0, tcell.ModCtrl)))
+	suite.True(km.matches("Mirrors", tcell.NewEventKey(tcell.KeyRune, 'm', tcell.ModAlt)))
+	suite.False(km.matches("Mirrors", tcell.NewEventKey(tcell.KeyRune, 'm', tcell.ModNone)))
+
+	// problems
+	_, err = newKeymap(map[string]string{"Mirrors": "Shift+M"})
+	suite.ErrorContains(err, "Mirrors and SortByModified are both bound to Shift+M")
+	_, err = newKeymap(map[string]string{"Queue": "Ctrl+S"})
+	suite.ErrorContains(err, "Settings and Queue are both bound to CTRL+S")
+	_, err = newKeymap(map[string]string{"ShowQueue": "Enter"})
+	suite.ErrorContains(err, "Install and ShowQueue", "list conflict not reported")
+	_, err = newKeymap(map[string]string{"Search": "Tab"})
+	suite.Nil(err, "keys of different contexts conflict")
+	_, err = newKeymap(map[string]string{"Quit": "q"})
+	suite.ErrorContains(err, "global keys need a modifier")
+	km, err = newKeymap(map[string]string{"Nope": "Ctrl+C", "Quit": "Hyper+q"})
+	suite.ErrorContains(err, "unknown action 'Nope'")
+	suite.ErrorContains(err, "unknown modifier")
+	suite.Equal("CTRL+Q", km.label("Quit"), "invalid binding did not fall back to default")
+}
+
+func (suite *pacseekTestSuite) TestSelectiveUpgrades() {
+	up := []Upgrade{
+		{InfoRecord: InfoRecord{Name: "yay", Source: "AUR", PackageBase: "yay"}, Status: "upgrade"},
+		{InfoRecord: InfoRecord{Name: "vim", Source: "extra", IsIgnored: true}, Status: "upgrade"},
+		{InfoRecord: InfoRecord{Name: "mesa", Source: "extra"}, Status: "upgrade"},
+		{InfoRecord: InfoRecord{Name: "glibc", Source: "core"}, Status: "upgrade"},
+		{InfoRecord: InfoRecord{Name: "linux", Source: "core"}, Status: "upgrade"},
+	}
+
+	// grouping
+	groups := groupUpgrades(up, []string{"core", "extra"})
+	suite.Len(groups, 3)
+	sources, names := []string{}, []string{}
+	for _, g := range groups {
+		sources = append(sources, g.Source)
+		for _, u := range g.Upgrades {
+			names = append(names, u.Name)
+		}
+	}
+	suite.Equal([]string{"core", "extra", "AUR"}, sources)
+	suite.Equal([]string{"glibc", "linux", "mesa", "vim", "yay"}, names, "ignored packages are not last in their group")
+	suite.Equal("yay", up[0].Name, "grouping modified our upgrades")
+	suite.Len(groupUpgrades([]Upgrade{}, nil), 0)
+
+	// selection
+	conf := config.Defaults()
+	suite.Equal([]string{"yay -Sy yay mesa glibc linux"}, selectiveUpgradeCommands(conf, nil, up, nil))
+	suite.Equal([]string{"yay -Sy mesa linux"}, selectiveUpgradeCommands(conf, nil, up, map[string]bool{"yay": true, "glibc": true}))
+	suite.Equal([]string{"yay -S yay"}, selectiveUpgradeCommands(conf, nil, up, map[string]bool{"mesa": true, "glibc": true, "linux": true}))
+	suite.Len(selectiveUpgradeCommands(conf, nil, up, map[string]bool{"yay": true, "mesa": true, "glibc": true, "linux": true}), 0)
+	conf.InstallCommand = "sudo pacman -S"
+	conf.AurUseDifferentCommands = true
+	conf.AurInstallCommand = "paru -S"
+	suite.Equal([]string{"sudo pacman -Sy mesa", "paru -S yay"}, selectiveUpgradeCommands(conf, nil, up, map[string]bool{"glibc": true, "linux": true}))
+	suite.Equal("sudo pacman -Sy --needed", withRefresh("sudo pacman -S --needed"))
+	suite.Equal("sudo pacman -Syu", withRefresh("sudo pacman -Syu"))
+	suite.Equal("/usr/local/bin/install.sh", withRefresh("/usr/local/bin/install.sh"))
+	suite.False(selectableUpgrade(Upgrade{InfoRecord: InfoRecord{Name: "nano"}, Status: "replaced"}))
+
+	// held back by pacman.conf
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("core",
+			&mockPackage{name: "gnome-shell", version: "47.0-1", groups: []string{"gnome"}},
+		)},
+	}
+	dbs, _ := h.SyncDBs()
+	held := []Upgrade{
+		{InfoRecord: InfoRecord{Name: "gnome-shell", Source: "core", IsIgnored: true}},
+		{InfoRecord: InfoRecord{Name: "linux", Source: "core", IsIgnored: true}},
+		{InfoRecord: InfoRecord{Name: "yay", Source: "AUR", IsIgnored: true}},
+		{InfoRecord: InfoRecord{Name: "vim", Source: "extra", IsIgnored: true}},
+		{InfoRecord: InfoRecord{Name: "mesa", Source: "extra"}},
+	}
+	markHeldBack(dbs, held, IgnoreRules{Packages: []string{"linux", "y*"}, Groups: []string{"gnome"}})
+	heldBack := map[string]bool{}
+	for _, u := range held {
+		heldBack[u.Name] = u.HeldBack
+	}
+	suite.Equal(map[string]bool{"gnome-shell": true, "linux": true, "yay": true, "vim": false, "mesa": false}, heldBack)
+}
+
+func (suite *pacseekTestSuite) TestPackageDiskSize() {
+	root := suite.T().TempDir()
+	suite.Nil(os.MkdirAll(filepath.Join(root, "usr/bin"), 0755))
+	suite.Nil(os.WriteFile(filepath.Join(root, "usr/bin/vim"), make([]byte, 10000), 0644))
+	suite.Nil(os.Link(filepath.Join(root, "usr/bin/vim"), filepath.Join(root, "usr/bin/vi")))
+	suite.Nil(os.WriteFile(filepath.Join(root, "usr/bin/xxd"), []byte("xxd"), 0644))
+	h := &mockHandle{
+		local: newMockDB("local",
+			&mockPackage{name: "vim", version: "9.1-1", files: []alpm.File{
+				{Name: "usr/"}, {Name: "usr/bin/"}, {Name: "usr/bin/vim"}, {Name: "usr/bin/vi"}, {Name: "usr/bin/xxd"}, {Name: "usr/bin/missing"},
+			}},
+		),
+	}
+	expected := int64(0)
+	for _, f := range []string{"usr/bin/vim", "usr/bin/xxd"} {
+		fi, err := os.Lstat(filepath.Join(root, f))
+		suite.Nil(err)
+		expected += fi.Sys().(*syscall.Stat_t).Blocks * 512
+	}
+
+	// ok
+	size, err := packageDiskSize(h, root, "vim")
+	suite.Nil(err)
+	suite.Equal(expected, size, "hard links counted twice / directories counted")
+	suite.GreaterOrEqual(size, int64(10000))
+	size, err = packageDiskSize(h, root, "nano")
+	suite.Nil(err)
+	suite.Zero(size, "size of a package that isn't installed")
+
+	// nok
+	_, err = packageDiskSize(nil, root, "vim")
+	suite.NotNil(err)
+
+	// sort by download size
+	pkgs := []Package{{Name: "a", DownloadSize: 10}, {Name: "b", DownloadSize: 300}, {Name: "c"}, {Name: "d", DownloadSize: 20}}
+	suite.Nil(sortResults(pkgs, "download", ""))
+	suite.Equal([]string{"b", "d", "a", "c"}, packageNames(pkgs))
+}
+
+func (suite *pacseekTestSuite) TestAurAccount() {
+	page := `<html><body><table class="results">
+<thead><tr><th>Name</th><th>Version</th><th>Votes</th><th>Popularity</th><th>Voted</th><th>Notify</th><th>Description</th></tr></thead>
+<tbody>
+<tr><td><a href="/packages/yay">yay</a></td><td>12.4.2-1</td><td>2500</td><td>40.1</td><td>Yes</td><td>Yes</td><td>AUR helper</td></tr>
+<tr><td><a href="/packages/paru">paru</a></td><td>2.0.4-1</td><td>900</td><td>20.3</td><td>Yes</td><td></td><td>AUR helper</td></tr>
+<tr><td><a href="/packages/pikaur">pikaur</a></td><td>1.29-1</td><td>500</td><td>3.1</td><td></td><td>Yes</td><td>AUR helper</td></tr>
+</tbody></table></body></html>`
+	loggedOut := strings.Replace(page, "<th>Voted</th><th>Notify</th>", "", 1)
+
+	// parsing
+	voted, err := parseVotedPackages(strings.NewReader(page))
+	suite.Nil(err)
+	suite.Equal([]string{"yay", "paru"}, voted)
+	_, err = parseVotedPackages(strings.NewReader(loggedOut))
+	suite.ErrorContains(err, "not logged in")
+
+	// session file
+	file := filepath.Join(suite.T().TempDir(), aurSessionFileName)
+	suite.Nil(os.WriteFile(file, []byte("AURSID=abc123\n"), 0600))
+	session, err := readAurSession(file)
+	suite.Nil(err)
+	suite.Equal("abc123", session)
+	suite.Nil(os.WriteFile(file, []byte("def456"), 0600))
+	session, _ = readAurSession(file)
+	suite.Equal("def456", session)
+	suite.Nil(os.Chmod(file, 0644))
+	_, err = readAurSession(file)
+	suite.ErrorContains(err, "chmod 600", "session file readable by others")
+	_, err = readAurSession(file + "-missing")
+	suite.ErrorIs(err, os.ErrNotExist)
+
+	// voting
+	var ran []string
+	fail := false
+	a := &aurAccount{
+		sshCommand: "ssh -p 22 aur@aur.archlinux.org",
+		run: func(name string, args ...string) ([]byte, error) {
+			ran = append([]string{name}, args...)
+			if fail {
+				return []byte("error: package base not found: nope\n"), errors.New("exit status 1")
+			}
+			return nil, nil
+		},
+	}
+	suite.Nil(a.vote("yay", false))
+	suite.Equal([]string{"ssh", "-p", "22", "aur@aur.archlinux.org", "vote", "yay"}, ran)
+	suite.Nil(a.vote("yay", true))
+	suite.Equal([]string{"ssh", "-p", "22", "aur@aur.archlinux.org", "unvote", "yay"}, ran)
+	fail = true
+	suite.EqualError(a.vote("nope", false), "vote failed: error: package base not found: nope")
+	a.sshCommand = " "
+	suite.NotNil(a.vote("yay", false))
+
+	// voted packages
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("AURSID"); err == nil && c.Value == "abc123" {
+			io.WriteString(w, page)
+			return
+		}
+		io.WriteString(w, loggedOut)
+	}))
+	defer ts.Close()
+	a = &aurAccount{votedUrl: ts.URL, session: "abc123"}
+	voted, err = a.votedPackages()
+	suite.Nil(err)
+	suite.Equal([]string{"yay", "paru"}, voted)
+	a.session = "expired"
+	_, err = a.votedPackages()
+	suite.ErrorContains(err, "session might have expired")
+	a.session = ""
+	_, err = a.votedPackages()
+	suite.ErrorContains(err, "no AUR session")
+}
+
+func (suite *pacseekTestSuite) TestRemovalPreview() {
+	// flags
+	suite.Equal("-Rs", removalFlags("yay -Rs"))
+	suite.Equal("-Rns", removalFlags("sudo pacman -Rns {pkg} --noconfirm"))
+	suite.Equal("", removalFlags("remove.sh"))
+	suite.Equal("", removalFlags("yay --remove-R"))
+	suite.Equal("sudo pacman -Rc {pkg} --noconfirm", withRemovalFlags("sudo pacman -Rns {pkg} --noconfirm", "-Rc"))
+	suite.Equal("sh -c 'pacman -Rns  \"$0\"'", withRemovalFlags("sh -c 'pacman -Rs  \"$0\"'", "-Rns"), "command modified")
+	suite.Equal("remove.sh", withRemovalFlags("remove.sh", "-Rns"))
+	suite.Equal("yay -Rs", withRemovalFlags("yay -Rs", ""))
+	suite.Equal([]string{"-Rs", "-Rns", "-Rc"}, removalOptions("yay -Rs"))
+	suite.Equal([]string{"-Rns", "-Rs", "-Rc"}, removalOptions("yay -Rns"))
+	suite.Equal([]string{"-Rsu", "-Rs", "-Rns", "-Rc"}, removalOptions("sudo pacman -Rsu"))
+	suite.Len(removalOptions("remove.sh"), 0)
+
+	// parsing and preview
+	out := "warning: something\nlibx 1.0-1\nvim 9.1-1\n:: dependency cycle\nglibc 2.40-1\n"
+	targets := parseRemovalTargets(out)
+	suite.Len(targets, 3)
+	suite.Equal(previewEntry{Name: "libx", Repo: "local", LocalVersion: "1.0-1", Action: "remove"}, targets[0])
+
+	h := &mockHandle{
+		local: newMockDB("local",
+			&mockPackage{name: "vim", version: "9.1-1", isize: 4000},
+			&mockPackage{name: "libx", version: "1.0-1", isize: 500},
+			&mockPackage{name: "glibc", version: "2.40-1", isize: 50000},
+		),
+	}
+	preview, err := buildRemovalPreview(h, "-Rc", targets, []string{"pacman", "lib*"})
+	suite.Nil(err)
+	suite.Equal("-Rc", preview.Flags)
+	suite.Equal([]string{"glibc", "libx", "vim"}, []string{preview.Entries[0].Name, preview.Entries[1].Name, preview.Entries[2].Name})
+	suite.Equal(int64(-54500), preview.InstalledSizeDelta)
+	suite.Equal([]string{"glibc", "libx"}, preview.Essential, "essential / HoldPkg packages not detected")
+	preview, _ = buildRemovalPreview(h, "-Rs", targets[1:2], nil)
+	suite.Len(preview.Essential, 0)
+
+	// nok
+	_, err = buildRemovalPreview(nil, "-Rs", targets, nil)
+	suite.NotNil(err)
+	suite.Equal("As configured in the uninstall command", removalDescription("-Rsu"))
+}
+
+func (suite *pacseekTestSuite) TestResultPaging() {
+	suite.Equal(50, resultLimit(50, 1))
+	suite.Equal(150, resultLimit(50, 3))
+	suite.Equal(50, resultLimit(50, 0))
+	suite.Equal(1, resultPages(0, 50))
+	suite.Equal(1, resultPages(50, 50))
+	suite.Equal(2, resultPages(51, 50))
+	suite.Equal(3, resultPages(150, 50))
+
+	packages := []Package{{Name: "liba"}, {Name: "libb"}, {Name: "libc"}}
+	page, more := pageResults(packages, 2, false)
+	suite.Equal([]Package{{Name: "liba"}, {Name: "libb"}}, page)
+	suite.True(more)
+	page, more = pageResults(packages, 3, false)
+	suite.Len(page, 3)
+	suite.False(more)
+	page, more = pageResults(packages, 3, true)
+	suite.Len(page, 3)
+	suite.True(more)
+
+	// the next page contains the first one
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "liba", version: "1.0-1"},
+			&mockPackage{name: "libb", version: "1.0-1"},
+			&mockPackage{name: "libc", version: "1.0-1"},
+		)},
+		local: newMockDB("local"),
+	}
+	first, _, err := searchRepos(h, "lib", "StartsWith", "Name", resultLimit(2, 1), SearchOptions{})
+	suite.Nil(err, err)
+	suite.Len(first, 2)
+	second, _, err := searchRepos(h, "lib", "StartsWith", "Name", resultLimit(2, 2), SearchOptions{})
+	suite.Nil(err, err)
+	suite.Len(second, 3)
+	suite.Equal(first, second[:2])
+}
+
+func (suite *pacseekTestSuite) TestOfflineMode() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("type") == "info" {
+			fmt.Fprint(w, `{"resultcount":1,"results":[{"Name":"yay","Version":"12.0.0-1"}],"type":"multiinfo","version":5}`)
+			return
+		}
+		fmt.Fprint(w, `{"resultcount":2,"results":[{"Name":"yay"},{"Name":"yay-bin"}],"type":"search","version":5}`)
+	}))
+	url := srv.URL
+	c := &diskCache{dir: suite.T().TempDir(), ttl: time.Minute}
+
+	// nothing cached yet
+	p, found := offlineSearchAur(c, url, "yay", "Contains", "Name", 20)
+	suite.False(found)
+	suite.Len(p, 0)
+	suite.Len(offlineInfoAur(c, url, "yay").Results, 0)
+
+	// fetched from the AUR
+	p, cached, err := cachedSearchAurStale(context.Background(), c, url, "yay", 5000, "Contains", "Name", 20)
+	suite.Nil(err, err)
+	suite.False(cached)
+	suite.Len(p, 2)
+	cachedInfoAur(c, url, 5000, "yay")
+
+	// the AUR can't be reached, expired entries are used
+	srv.Close()
+	entries, _ := os.ReadDir(c.dir)
+	old := time.Now().Add(-2 * time.Minute)
+	for _, e := range entries {
+		os.Chtimes(filepath.Join(c.dir, e.Name()), old, old)
+	}
+	p, cached, err = cachedSearchAurStale(context.Background(), c, url, "yay", 5000, "Contains", "Name", 20)
+	suite.NotNil(err)
+	suite.True(isNetworkError(err), err)
+	suite.True(cached)
+	suite.Len(p, 2)
+	p, found = offlineSearchAur(c, url, "yay", "Contains", "Name", 20)
+	suite.True(found)
+	suite.Len(p, 2)
+	sr := offlineInfoAur(c, url, "yay", "nonsense")
+	suite.Len(sr.Results, 1)
+	suite.Equal("12.0.0-1", sr.Results[0].Version)
+
+	// network errors only
+	suite.False(isNetworkError(nil))
+	suite.False(isNetworkError(errors.New("Too many package results.")))
+	suite.False(isNetworkError(context.Canceled))
+
+	// going offline and online again
+	now := time.Now()
+	conn := newConnectivity(false)
+	conn.now = func() time.Time { return now }
+	suite.False(conn.offline())
+	suite.False(conn.failed(errors.New("Too many package results.")))
+	suite.False(conn.offline())
+	suite.True(conn.failed(err))
+	suite.True(conn.offline())
+	suite.False(conn.failed(err), "already offline")
+	now = now.Add(offlineRetryInterval)
+	suite.False(conn.offline(), "no retry")
+	suite.True(conn.succeeded())
+	suite.False(conn.succeeded())
+
+	// offline flag
+	conn = newConnectivity(true)
+	suite.True(conn.offline())
+	suite.False(conn.failed(err))
+	suite.False(conn.succeeded())
+	suite.True(conn.offline())
+	var none *connectivity
+	suite.False(none.offline())
+}
+
+func (suite *pacseekTestSuite) TestPkgstats() {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Query().Get("offset") == "0" {
+			entries := []string{`{"name":"pacman","popularity":100}`}
+			for i := 1; i < pkgstatsPageSize; i++ {
+				entries = append(entries, fmt.Sprintf(`{"name":"pkg%d","popularity":50}`, i))
+			}
+			fmt.Fprintf(w, `{"total":%d,"packagePopularities":[%s]}`, pkgstatsPageSize+1, strings.Join(entries, ","))
+			return
+		}
+		fmt.Fprint(w, `{"total":251,"packagePopularities":[{"name":"vim","popularity":12.5}]}`)
+	}))
+	defer srv.Close()
+
+	// ok
+	popularity, err := fetchPkgstats(srv.URL+"/api/packages?limit=%d&offset=%d", pkgstatsMaxPackages)
+	suite.Nil(err, err)
+	suite.Equal(2, requests)
+	suite.Len(popularity, pkgstatsPageSize+1)
+	suite.Equal(12.5, popularity["vim"])
+
+	// limited
+	requests = 0
+	_, err = fetchPkgstats(srv.URL+"/api/packages?limit=%d&offset=%d", pkgstatsPageSize)
+	suite.Nil(err, err)
+	suite.Equal(1, requests)
+
+	// nok
+	_, err = fetchPkgstats("nonsense?limit=%d&offset=%d", pkgstatsMaxPackages)
+	suite.NotNil(err)
+
+	// apply to repo packages only
+	s := &pkgstats{}
+	pkgs := []Package{{Name: "pacman", Popularity: repoPopularity}}
+	s.apply(pkgs)
+	suite.Equal(repoPopularity, pkgs[0].Popularity, "no data")
+	suite.True(s.expired())
+	s.set(popularity, time.Now())
+	suite.False(s.expired())
+	pkgs = []Package{
+		{Name: "pacman", Source: "core", Popularity: repoPopularity},
+		{Name: "unknown", Source: "extra", Popularity: repoPopularity},
+		{Name: "vim", Source: "AUR", Popularity: 3},
+	}
+	s.apply(pkgs)
+	suite.Equal(100.0, pkgs[0].Popularity)
+	suite.Equal(0.0, pkgs[1].Popularity)
+	suite.Equal(3.0, pkgs[2].Popularity)
+	p, ok := s.get("vim")
+	suite.True(ok)
+	suite.Equal(12.5, p)
+	var none *pkgstats
+	_, ok = none.get("vim")
+	suite.False(ok)
+	none.apply(pkgs)
+
+	// cache file
+	file := filepath.Join(suite.T().TempDir(), "pacseek", pkgstatsFileName)
+	suite.Nil(s.write(file))
+	r := &pkgstats{}
+	suite.Nil(r.read(file))
+	suite.False(r.expired())
+	p, _ = r.get("vim")
+	suite.Equal(12.5, p)
+	r.set(popularity, time.Now().Add(-pkgstatsExpiry-time.Hour))
+	suite.True(r.expired())
+	suite.NotNil(r.read(filepath.Join(suite.T().TempDir(), "nonsense")))
+}
+
+func (suite *pacseekTestSuite) TestResultFilter() {
+	// parsing
+	f, err := parseResultFilter("license:GPL,MIT arch:any")
+	suite.Nil(err, err)
+	suite.Equal([]string{"GPL", "MIT"}, f.Licenses)
+	suite.Equal([]string{"any"}, f.Arches)
+	suite.True(f.active())
+	suite.Equal("license:GPL,MIT arch:any", f.String())
+	suite.Equal([]string{"any"}, f.searchArches("x86_64"))
+	suite.Equal([]string{"x86_64", "any"}, resultFilter{}.searchArches("x86_64"))
+	f, err = parseResultFilter("  ")
+	suite.Nil(err, err)
+	suite.False(f.active())
+	suite.Equal("", f.String())
+	_, err = parseResultFilter("license:")
+	suite.NotNil(err)
+	_, err = parseResultFilter("size:10")
+	suite.NotNil(err)
+	_, err = parseResultFilter("GPL")
+	suite.NotNil(err)
+
+	// licenses
+	suite.True(licenseMatches([]string{"GPL-3.0-or-later"}, []string{"gpl"}))
+	suite.True(licenseMatches([]string{"GPL2"}, []string{"GPL"}))
+	suite.True(licenseMatches([]string{"(GPL-2.0-only OR MIT)"}, []string{"MIT"}))
+	suite.True(licenseMatches([]string{"custom:MIT"}, []string{"MIT"}))
+	suite.True(licenseMatches(nil, nil))
+	suite.False(licenseMatches([]string{"LGPL-2.1-or-later"}, []string{"GPL"}))
+	suite.False(licenseMatches([]string{"Apache-2.0 WITH LLVM-exception"}, []string{"GPL", "MIT"}))
+	suite.False(licenseMatches(nil, []string{"MIT"}))
+
+	// AUR packages
+	pkgs := []Package{{Name: "yay", Source: "AUR"}, {Name: "paru", Source: "AUR"}, {Name: "unknown", Source: "AUR"}}
+	infos := []InfoRecord{{Name: "yay", License: []string{"GPL-3.0-or-later"}}, {Name: "paru", License: []string{"GPL3"}}}
+	suite.Equal(pkgs, filterAurLicenses(pkgs, infos, nil))
+	suite.Equal([]Package{{Name: "yay", Source: "AUR"}, {Name: "paru", Source: "AUR"}}, filterAurLicenses(pkgs, infos, []string{"GPL"}))
+	suite.Len(filterAurLicenses(pkgs, infos, []string{"MIT"}), 0)
+
+	// architectures (repositories)
+	h := &mockHandle{
+		sync: []*mockDB{newMockDB("extra",
+			&mockPackage{name: "foo", version: "1.0-1", arch: "x86_64"},
+			&mockPackage{name: "foo-docs", version: "1.0-1", arch: "any"},
+		)},
+		local: newMockDB("local"),
+	}
+	p, _, err := searchRepos(h, "foo", "StartsWith", "Name", 10, SearchOptions{Arches: []string{"any"}})
+	suite.Nil(err, err)
+	suite.Len(p, 1)
+	suite.Equal("foo-docs", p[0].Name)
+}
+
+func (suite *pacseekTestSuite) TestSearchQuery() {
+	// parsing
+	q, err := parseSearchQuery("python http -test repo:extra,AUR installed:yes", "StartsWith")
+	suite.Nil(err, err)
+	suite.Equal([]string{"python", "http"}, q.Terms)
+	suite.Equal([]string{"test"}, q.Excluded)
+	suite.Equal([]string{"extra", "aur"}, q.Repos)
+	suite.Equal(InstalledOnly, q.Installed)
+	suite.Equal("python http", q.Term())
+	suite.Equal("python", q.aurTerm())
+	suite.True(q.includesAur())
+	q, err = parseSearchQuery("foo - key:value repo:core", "Contains")
+	suite.Nil(err, err)
+	suite.Equal([]string{"foo", "-", "key:value"}, q.Terms)
+	suite.False(q.includesAur())
+	q, err = parseSearchQuery("^foo -bar$", "Regex")
+	suite.Nil(err, err)
+	suite.Equal("^foo -bar$", q.Term())
+	suite.Nil(q.predicate())
+	_, err = parseSearchQuery("foo installed:maybe", "StartsWith")
+	suite.NotNil(err)
+
+	// predicates
+	q, _ = parseSearchQuery("foo -test repo:extra installed:no", "StartsWith")
+	pred := q.predicate()
+	suite.True(pred(packageCandidate{Name: "foo", Source: "extra"}))
+	suite.False(pred(packageCandidate{Name: "foo-test", Source: "extra"}))
+	suite.False(pred(packageCandidate{Name: "foo", Description: "A TEST package", Source: "extra"}))
+	suite.False(pred(packageCandidate{Name: "foo", Source: "core"}))
+	suite.False(pred(packageCandidate{Name: "foo", Source: "extra", Installed: true}))
+	suite.Nil(allOf(nil, nil))
+
+	// AUR results
+	q, _ = parseSearchQuery("python http -test", "Contains")
+	pkgs := []Package{
+		{Name: "python-httpx", Source: "AUR"},
+		{Name: "python-requests", Description: "HTTP library", Source: "AUR"},
+		{Name: "python-http-test", Source: "AUR"},
+		{Name: "python-foo", Source: "AUR"},
+	}
+	filtered := filterByPredicate(pkgs, q.aurPredicate("Name & Description"))
+	suite.Equal([]string{"python-httpx", "python-requests"}, packageNames(filtered))
+	suite.Len(filterByPredicate(pkgs, nil), 4)
+
+	// repositories
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("core", &mockPackage{name: "foo", version: "1.0-1"}),
+			newMockDB("extra",
+				&mockPackage{name: "foo-bar", version: "1.0-1"},
+				&mockPackage{name: "foo-test", version: "1.0-1"},
+			),
+		},
+		local: newMockDB("local"),
+	}
+	q, _ = parseSearchQuery("foo -test repo:extra", "StartsWith")
+	p, _, err := searchRepos(h, q.Term(), "StartsWith", "Name", 10, SearchOptions{Predicate: q.predicate()})
+	suite.Nil(err, err)
+	suite.Equal([]string{"foo-bar"}, packageNames(p))
+}
+
+func (suite *pacseekTestSuite) TestLookupPackage() {
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("core", &mockPackage{name: "foo", version: "1.0-1"}),
+			newMockDB("extra", &mockPackage{name: "foo", version: "1.1-1"}, &mockPackage{name: "bar", version: "1.0-1"}),
+		},
+		local: newMockDB("local", &mockPackage{name: "bar", version: "1.0-1"}, &mockPackage{name: "yay", version: "12.0-1"}),
+	}
+
+	p, err := lookupPackage(h, "foo")
+	suite.Nil(err, err)
+	suite.Equal("core", p.Source)
+	suite.False(p.IsInstalled)
+	p, err = lookupPackage(h, "extra/foo")
+	suite.Nil(err, err)
+	suite.Equal("extra", p.Source)
+	p, err = lookupPackage(h, "bar")
+	suite.Nil(err, err)
+	suite.Equal("extra", p.Source)
+	suite.True(p.IsInstalled)
+
+	// not part of a repository
+	p, err = lookupPackage(h, "yay")
+	suite.Nil(err, err)
+	suite.Equal("AUR", p.Source)
+	suite.True(p.IsInstalled)
+	p, err = lookupPackage(h, "aur/yay")
+	suite.Nil(err, err)
+	suite.Equal("yay", p.Name)
+	_, err = lookupPackage(h, "core/bar")
+	suite.NotNil(err)
+	_, err = lookupPackage(nil, "foo")
+	suite.NotNil(err)
+}
+
+func (suite *pacseekTestSuite) TestAppstream() {
+	catalog := `<?xml version="1.0" encoding="UTF-8"?>
+<components version="0.14" origin="extra">
+  <component type="desktop-application">
+    <id>org.mozilla.firefox</id>
+    <pkgname>firefox</pkgname>
+    <name>Firefox</name>
+    <name xml:lang="de">Firefox-Browser</name>
+    <summary>Web Browser</summary>
+    <summary xml:lang="de">Webbrowser</summary>
+    <categories>
+      <category>Network</category>
+      <category>WebBrowser</category>
+    </categories>
+    <screenshots>
+      <screenshot>
+        <image type="thumbnail">https://example.org/other-small.png</image>
+        <image type="source">https://example.org/other.png</image>
+      </screenshot>
+      <screenshot type="default">
+        <image type="source">https://example.org/default.png</image>
+      </screenshot>
+    </screenshots>
+  </component>
+  <component type="addon">
+    <id>org.mozilla.firefox.addon</id>
+    <pkgname>firefox-addon</pkgname>
+  </component>
+  <component type="desktop">
+    <id>gimp.desktop</id>
+    <pkgname>gimp</pkgname>
+    <name>GIMP</name>
+  </component>
+</components>`
+
+	c, err := parseAppstream(strings.NewReader(catalog))
+	suite.Nil(err, err)
+	suite.Len(c, 2)
+	ff := c["firefox"]
+	suite.Equal("Firefox", ff.Name)
+	suite.Equal("Web Browser", ff.Summary)
+	suite.Equal([]string{"Network", "WebBrowser"}, ff.Categories)
+	suite.Equal([]string{"https://example.org/default.png", "https://example.org/other.png"}, ff.Screenshots)
+	suite.Equal("GIMP", c["gimp"].Name)
+	_, err = parseAppstream(strings.NewReader("<components><component>"))
+	suite.NotNil(err)
+
+	// catalogs (gzip compressed)
+	dir := suite.T().TempDir()
+	f, _ := os.Create(filepath.Join(dir, "extra.xml.gz"))
+	gz := gzip.NewWriter(f)
+	gz.Write([]byte(catalog))
+	gz.Close()
+	f.Close()
+	os.WriteFile(filepath.Join(dir, "broken.xml"), []byte("<components><component>"), 0644)
+	a := &appstream{}
+	suite.Equal(2, a.load([]string{filepath.Join(dir, "missing"), dir}))
+	got, ok := a.get("firefox")
+	suite.True(ok)
+	suite.Equal("org.mozilla.firefox", got.ID)
+	_, ok = a.get("nonsense")
+	suite.False(ok)
+	var none *appstream
+	_, ok = none.get("firefox")
+	suite.False(ok)
+}
+
+func (suite *pacseekTestSuite) TestGraphics() {
+	env := func(vars map[string]string) func(string) string {
+		return func(k string) string { return vars[k] }
+	}
+	suite.Equal("kitty", detectGraphicsProtocol(env(map[string]string{"TERM": "xterm-kitty"})))
+	suite.Equal("kitty", detectGraphicsProtocol(env(map[string]string{"TERM": "xterm-256color", "TERM_PROGRAM": "ghostty"})))
+	suite.Equal("sixel", detectGraphicsProtocol(env(map[string]string{"TERM": "foot"})))
+	suite.Equal("sixel", detectGraphicsProtocol(env(map[string]string{"TERM_PROGRAM": "WezTerm"})))
+	suite.Equal("", detectGraphicsProtocol(env(map[string]string{"TERM": "xterm-256color"})))
+	suite.Equal("sixel", graphicsProtocol("sixel"))
+	suite.Equal("", graphicsProtocol("none"))
+
+	img := image.NewRGBA(image.Rect(0, 0, 100, 7))
+	for x := 0; x < 100; x++ {
+		img.Set(x, 0, color.RGBA{255, 0, 0, 255})
+	}
+
+	// kitty (PNG in chunks)
+	var buf bytes.Buffer
+	suite.Nil(writeImage(&buf, "kitty", img, 80))
+	suite.True(strings.HasPrefix(buf.String(), "\x1b_Ga=T,f=100,m=0;"))
+	suite.True(strings.HasSuffix(buf.String(), "\x1b\\"))
+	noise := image.NewRGBA(image.Rect(0, 0, 200, 200))
+	for i := range noise.Pix {
+		noise.Pix[i] = byte(i * 7919 % 251)
+	}
+	buf.Reset()
+	suite.Nil(writeImage(&buf, "kitty", noise, 10))
+	suite.True(strings.HasPrefix(buf.String(), "\x1b_Ga=T,f=100,m=1,c=10;"))
+	suite.Contains(buf.String(), "\x1b_Gm=0;")
+
+	// sixel: 2 bands, red pixels in the first row
+	buf.Reset()
+	suite.Nil(writeImage(&buf, "sixel", img, 80))
+	out := buf.String()
+	suite.True(strings.HasPrefix(out, "\x1bP0;1;0q\"1;1;100;7"))
+	suite.Equal(2, strings.Count(out, "-"))
+	suite.Contains(out, "#180"+strings.Repeat("@", 100)+"$")
+	suite.True(strings.HasSuffix(out, "\x1b\\"))
+	suite.NotNil(writeImage(&buf, "", img, 80))
+
+	// scaling
+	scaled := scaleImage(noise, 50)
+	suite.Equal(50, scaled.Bounds().Dx())
+	suite.Equal(50, scaled.Bounds().Dy())
+	suite.Equal(noise, scaleImage(noise, 500))
+}
+
+func (suite *pacseekTestSuite) TestClipboard() {
+	env := func(vars map[string]string) func(string) string {
+		return func(k string) string { return vars[k] }
+	}
+	lookPath := func(available ...string) func(string) (string, error) {
+		return func(program string) (string, error) {
+			if util.SliceContains(available, program) {
+				return "/usr/bin/" + program, nil
+			}
+			return "", errors.New("not found")
+		}
+	}
+
+	wayland := env(map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"})
+	suite.Equal([]string{"wl-copy"}, clipboardCommand(wayland, lookPath("wl-copy", "xclip")))
+	suite.Equal([]string{"xclip", "-selection", "clipboard"}, clipboardCommand(wayland, lookPath("xclip")))
+	x11 := env(map[string]string{"DISPLAY": ":0"})
+	suite.Equal([]string{"xsel", "--clipboard", "--input"}, clipboardCommand(x11, lookPath("wl-copy", "xsel")))
+	suite.Nil(clipboardCommand(x11, lookPath()))
+	suite.Nil(clipboardCommand(env(nil), lookPath("wl-copy", "xclip")))
+
+	// OSC 52
+	suite.Equal("\x1b]52;c;cGFjc2Vlaw==\a", osc52("pacseek", env(nil)))
+	suite.Equal("\x1bPtmux;\x1b\x1b]52;c;cGFjc2Vlaw==\a\x1b\\", osc52("pacseek", env(map[string]string{"TMUX": "/tmp/tmux"})))
+	suite.Equal("\x1bP\x1b]52;c;cGFjc2Vlaw==\a\x1b\\", osc52("pacseek", env(map[string]string{"TERM": "screen-256color"})))
+
+	// tools keeping a child process in the background (like xclip) don't block us
+	dir := suite.T().TempDir()
+	copied := filepath.Join(dir, "copied")
+	script := "#!/bin/sh\ncat > " + copied + "\nsleep 5 &\n"
+	suite.Nil(os.WriteFile(filepath.Join(dir, "wl-copy"), []byte(script), 0755))
+	suite.T().Setenv("PATH", dir+":"+os.Getenv("PATH"))
+	suite.T().Setenv("WAYLAND_DISPLAY", "wayland-0")
+	start := time.Now()
+	suite.Nil(copyToClipboard("pacseek"))
+	suite.Less(time.Since(start), clipboardTimeout)
+	b, err := os.ReadFile(copied)
+	suite.Nil(err, err)
+	suite.Equal("pacseek", string(b))
+
+	// nok
+	suite.Nil(os.WriteFile(filepath.Join(dir, "wl-copy"), []byte("#!/bin/sh\necho no compositor >&2\nexit 1\n"), 0755))
+	err = copyToClipboard("pacseek")
+	suite.NotNil(err)
+	suite.Contains(err.Error(), "no compositor")
+}
+
+func (suite *pacseekTestSuite) TestRepoMerge() {
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("core-testing", &mockPackage{name: "foo", version: "1.2-1"}),
+			newMockDB("core", &mockPackage{name: "foo", version: "1.0-1"}, &mockPackage{name: "foo-utils", version: "1.0-1"}),
+			newMockDB("extra", &mockPackage{name: "foo", version: "1.1-1"}),
+		},
+		local: newMockDB("local"),
+	}
+
+	// duplicate rows
+	p, _, err := searchRepos(h, "foo", "StartsWith", "Name", 10, SearchOptions{})
+	suite.Nil(err, err)
+	suite.Len(p, 4)
+
+	// merged, pacman.conf order
+	p, _, err = searchRepos(h, "foo", "StartsWith", "Name", 10, SearchOptions{MergeRepos: true})
+	suite.Nil(err, err)
+	suite.Len(p, 2)
+	suite.Equal("foo", p[0].Name)
+	suite.Equal("core-testing", p[0].Source)
+	suite.Equal([]string{"core", "extra"}, p[0].OtherRepos)
+	suite.Equal("foo-utils", p[1].Name)
+	suite.Nil(p[1].OtherRepos)
+
+	// merged, our own priority
+	p, _, err = searchRepos(h, "foo", "StartsWith", "Name", 10, SearchOptions{MergeRepos: true, RepoPriority: []string{"extra", "unknown"}})
+	suite.Nil(err, err)
+	suite.Equal("extra", p[0].Source)
+	suite.Equal([]string{"core-testing", "core"}, p[0].OtherRepos)
+
+	// merged packages don't take up a slot
+	p, _, err = searchRepos(h, "foo", "StartsWith", "Name", 2, SearchOptions{MergeRepos: true})
+	suite.Nil(err, err)
+	suite.Len(p, 2)
+
+	// versions
+	suite.Equal([]repoVersion{{"core-testing", "1.2-1"}, {"core", "1.0-1"}, {"extra", "1.1-1"}}, repoVersions(h, "foo", nil))
+	suite.Equal([]repoVersion{{"core", "1.0-1"}, {"core-testing", "1.2-1"}, {"extra", "1.1-1"}}, repoVersions(h, "foo", []string{"core"}))
+	suite.Len(repoVersions(h, "nonsense", nil), 0)
+
+	// install targets
+	conf := config.Defaults()
+	suite.Equal("foo", installTarget(conf, InfoRecord{Name: "foo", Source: "extra"}))
+	conf.RepoPriority = []string{"extra"}
+	suite.Equal("extra/foo", installTarget(conf, InfoRecord{Name: "foo", Source: "extra"}))
+	suite.Equal("yay", installTarget(conf, InfoRecord{Name: "yay", Source: "AUR"}))
+	suite.Equal("yay -S extra/foo", packageCommand(conf, InfoRecord{Name: "foo", Source: "extra"}, false))
+	suite.Equal("yay -Rs foo", packageCommand(conf, InfoRecord{Name: "foo", Source: "extra"}, true))
+	suite.Equal([]string{"yay -S extra/foo yay"}, batchCommands(conf, nil, []queuedPackage{
+		{InfoRecord: InfoRecord{Name: "foo", Source: "extra"}},
+		{InfoRecord: InfoRecord{Name: "yay", Source: "AUR"}},
+	}))
+}
+
+func (suite *pacseekTestSuite) TestDownload() {
+	h := &mockHandle{
+		sync: []*mockDB{
+			newMockDB("core", &mockPackage{name: "gcc", version: "13.1-1"}, &mockPackage{name: "git", version: "2.41-1"}),
+			newMockDB("extra", &mockPackage{name: "go", version: "1.21-1"}, &mockPackage{name: "curl", version: "8.1-1"}),
+		},
+		local: newMockDB("local", &mockPackage{name: "git", version: "2.41-1"}),
+	}
+
+	// repo dependencies of AUR packages, installed and AUR ones are skipped
+	aur := []InfoRecord{
+		{Name: "yay", Source: "AUR", Depends: []string{"git", "curl"}, MakeDepends: []string{"go>=1.20", "other-aur-pkg"}},
+		{Name: "yay-bin", Source: "AUR", Depends: []string{"curl"}},
+	}
+	suite.Equal([]string{"curl", "go"}, aurRepoDependencies(h, aur))
+	suite.Len(aurRepoDependencies(nil, aur), 0)
+
+	// placeholders, nothing is appended
+	suite.Equal("git clone https://aur.archlinux.org/foo.git && cd foo && makepkg -od",
+		aurCommand("git clone {giturl} && cd {pkgbase} && makepkg -od", InfoRecord{Name: "foo-git", PackageBase: "foo"}))
+	suite.Equal("yay -G bar", aurCommand("yay -G {pkg}", InfoRecord{Name: "bar"}))
+
+	conf := config.Defaults()
+	conf.DownloadCommand = "sudo pacman -Sw"
+	conf.AurDownloadCommand = "git clone {giturl}"
+	conf.AurDownloadDir = "/tmp/dl"
+	sources := []packageSource{newFlatpakSource()}
+	pkgs := []InfoRecord{
+		{Name: "gcc", Source: "core"},
+		{Name: "gcc", Source: "core"},
+		{Name: "yay", Source: "AUR"},
+		{Name: "org.gimp.GIMP", Source: "Flatpak"},
+	}
+	suite.Equal([]string{
+		"sudo pacman -Sw gcc curl go",
+		"(mkdir -p \"/tmp/dl\" && cd \"/tmp/dl\" && git clone https://aur.archlinux.org/yay.git)",
+	}, downloadCommands(conf, sources, pkgs, []string{"gcc", "curl", "go"}))
+
+	// only packages we can't download
+	suite.Len(downloadCommands(conf, sources, pkgs[3:], nil), 0)
+
+	// repo priority
+	conf.RepoPriority = []string{"core"}
+	suite.Equal([]string{"sudo pacman -Sw core/gcc"}, downloadCommands(conf, nil, pkgs[:1], nil))
+	suite.NotEmpty(aurDownloadDir(config.Defaults()))
+}
+
+func (suite *pacseekTestSuite) TestLogging() {
+	// levels
+	suite.Equal(levelDebug, parseLogLevel("Debug"))
+	suite.Equal(levelOff, parseLogLevel("off"))
+	suite.Equal(levelInfo, parseLogLevel("nonsense"))
+
+	// directory
+	env := map[string]string{"XDG_STATE_HOME": "/state", "HOME": "/home/user"}
+	suite.Equal("/state/pacseek", stateDir(func(k string) string { return env[k] }))
+	env["XDG_STATE_HOME"] = "relative"
+	suite.Equal("/home/user/.local/state/pacseek", stateDir(func(k string) string { return env[k] }))
+
+	// formatting
+	t := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	suite.Equal(`time=2024-05-01T12:30:00.000Z level=info msg="command finished" command=yay exit_code=1 duration=1.235s`,
+		formatLogLine(t, levelInfo, "command finished", "command", "yay", "exit_code", 1, "duration", 1234567*time.Microsecond))
+	suite.Equal(`time=2024-05-01T12:30:00.000Z level=warn msg=search term="foo bar" error="a \"b\""`,
+		formatLogLine(t, levelWarn, "search", "term", "foo bar", "results", nil, "error", errors.New(`a "b"`)))
+	suite.Equal(`time=2024-05-01T12:30:00.000Z level=error msg=x empty=""`, formatLogLine(t, levelError, "x", "empty", "", "odd"))
+
+	// a nil log doesn't do anything
+	var nl *eventLog
+	nl.info("nothing")
+	suite.Len(nl.last(0), 0)
+	suite.Nil(nl.close())
+
+	// writing, levels and rotation
+	path := filepath.Join(suite.T().TempDir(), "state", "pacseek.log")
+	l, err := newEventLog(path, levelInfo, 200, 2)
+	suite.Nil(err, err)
+	l.now = func() time.Time { return t }
+	l.debug("not logged")
+	l.info("alpm initialized", "repos", "core,extra")
+	l.warn("http request failed", "error", "timeout")
+	suite.Len(l.last(0), 2)
+	suite.Equal([]string{`time=2024-05-01T12:30:00.000Z level=warn msg="http request failed" error=timeout`}, l.last(1))
+	b, _ := os.ReadFile(path)
+	suite.Equal(2, strings.Count(string(b), "\n"))
+
+	for i := 0; i < 10; i++ {
+		l.info("filler", "i", i)
+	}
+	_, err = os.Stat(path + ".1")
+	suite.Nil(err, err)
+	_, err = os.Stat(path + ".2")
+	suite.Nil(err, err)
+	_, err = os.Stat(path + ".3")
+	suite.True(os.IsNotExist(err))
+	b, _ = os.ReadFile(path)
+	suite.Less(len(b), 200)
+	suite.Len(l.last(0), 12)
+
+	// disabled, our lines are kept
+	suite.Nil(l.setLevel(levelOff))
+	l.log(levelError, "not logged")
+	suite.Len(l.last(0), 12)
+	suite.Nil(l.setLevel(levelDebug))
+	l.debug("logged")
+	suite.Contains(l.last(1)[0], "msg=logged")
+	suite.Nil(l.close())
+
+	// http requests
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	prev := logger
+	logger, _ = newEventLog(filepath.Join(suite.T().TempDir(), "pacseek.log"), levelInfo, 0, 0)
+	defer func() {
+		logger.close()
+		logger = prev
+	}()
+	client := http.Client{Transport: loggedTransport{}}
+	r, err := client.Get(srv.URL + "/rpc?v=5&type=search&arg=yay")
+	suite.Nil(err, err)
+	r.Body.Close()
+	r, err = client.Get(srv.URL + "/missing")
+	suite.Nil(err, err)
+	r.Body.Close()
+	lines := logger.last(0)
+	suite.Len(lines, 2)
+	suite.Contains(lines[0], "level=info msg=\"http request\" method=GET url=\""+srv.URL+"/rpc?v=5&type=search&arg=yay\" status=200")
+	suite.Contains(lines[1], "level=warn")
+	suite.Contains(lines[1], "status=404")
+}
+
+func (suite *pacseekTestSuite) TestUpgradeDiff() {
+	h := &mockHandle{
+		local: newMockDB("local", &mockPackage{name: "linux", version: "6.5-1"}, &mockPackage{name: "bash", version: "5.1-1"}),
+	}
+	prev := []upgradeRecord{
+		{Name: "linux", Source: "core", Version: "6.5-1", LocalVersion: "6.4-1"},
+		{Name: "firefox", Source: "extra", Version: "118.0-1", LocalVersion: "117.0-1"},
+		{Name: "bash", Source: "core", Version: "5.2-1", LocalVersion: "5.1-1"},
+		{Name: "yay", Source: "AUR", Version: "12.1-1", LocalVersion: "12.0-1"},
+	}
+	current := upgradeRecords([]Upgrade{
+		{InfoRecord: InfoRecord{Name: "yay", Source: "AUR", Version: "12.1-1", LocalVersion: "12.0-1"}},
+		{InfoRecord: InfoRecord{Name: "firefox", Source: "extra", Version: "118.0.1-1", LocalVersion: "117.0-1"}},
+		{InfoRecord: InfoRecord{Name: "gcc", Source: "core", Version: "13.2-1", LocalVersion: "13.1-1"}},
+		{InfoRecord: InfoRecord{Name: "ignored", Source: "core", Version: "2-1", LocalVersion: "1-1", IsIgnored: true}},
+	})
+	suite.Len(current, 3)
+
+	changes := diffUpgrades(prev, current, h)
+	suite.Equal([]upgradeChange{
+		{upgradeRecord: upgradeRecord{Name: "gcc", Source: "core", Version: "13.2-1", LocalVersion: "13.1-1"}, Kind: "new"},
+		{upgradeRecord: upgradeRecord{Name: "firefox", Source: "extra", Version: "118.0.1-1", LocalVersion: "117.0-1"}, Kind: "bumped", PreviousVersion: "118.0-1"},
+		{upgradeRecord: upgradeRecord{Name: "linux", Source: "core", Version: "6.5-1", LocalVersion: "6.5-1"}, Kind: "installed", PreviousVersion: "6.5-1"},
+		{upgradeRecord: upgradeRecord{Name: "bash", Source: "core", Version: "5.2-1", LocalVersion: "5.1-1"}, Kind: "gone", PreviousVersion: "5.2-1"},
+	}, changes)
+	suite.Equal("new upgrade", describeUpgradeChange(changes[0]))
+	suite.Equal("newer version (was 118.0-1)", describeUpgradeChange(changes[1]))
+	suite.Equal("installed", describeUpgradeChange(changes[2]))
+	suite.Equal("no longer available (was 5.2-1)", describeUpgradeChange(changes[3]))
+
+	// without a handle, installed versions are unknown
+	suite.Equal("gone", diffUpgrades(prev, current, nil)[2].Kind)
+	suite.Len(diffUpgrades(current, current, h), 0)
+
+	// state
+	file := filepath.Join(suite.T().TempDir(), "state", "upgrades.json")
+	t1 := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	up := []Upgrade{{InfoRecord: InfoRecord{Name: "linux", Source: "core", Version: "6.5-1", LocalVersion: "6.4-1"}}}
+
+	// first check, nothing to compare with
+	state, err := recordUpgradeCheck(file, up, h, t1)
+	suite.Nil(err, err)
+	suite.Len(state.Changes, 0)
+
+	// a new upgrade
+	up = append(up, Upgrade{InfoRecord: InfoRecord{Name: "gcc", Source: "core", Version: "13.2-1"}})
+	state, err = recordUpgradeCheck(file, up, h, t1.Add(time.Hour))
+	suite.Nil(err, err)
+	suite.Equal(t1, state.Since)
+	suite.Len(state.Changes, 1)
+
+	// nothing changed, our changes are kept
+	state, err = recordUpgradeCheck(file, up, h, t1.Add(2*time.Hour))
+	suite.Nil(err, err)
+	suite.Equal(t1, state.Since)
+	suite.Equal("gcc", state.Changes[0].Name)
+
+	read, err := readUpgradeState(file)
+	suite.Nil(err, err)
+	suite.Equal(t1.Add(2*time.Hour), read.Checked.UTC())
+	suite.Len(read.Upgrades, 2)
+
+	// linux has been installed
+	state, err = recordUpgradeCheck(file, up[1:], h, t1.Add(3*time.Hour))
+	suite.Nil(err, err)
+	suite.Equal(t1.Add(2*time.Hour), state.Since.UTC())
+	suite.Equal([]upgradeChange{
+		{upgradeRecord: upgradeRecord{Name: "linux", Source: "core", Version: "6.5-1", LocalVersion: "6.5-1"}, Kind: "installed", PreviousVersion: "6.5-1"},
+	}, state.Changes)
+
+	// a broken state is replaced
+	suite.Nil(os.WriteFile(file, []byte("{"), 0644))
+	_, err = readUpgradeState(file)
+	suite.NotNil(err)
+	state, err = recordUpgradeCheck(file, up, h, t1)
+	suite.Nil(err, err)
+	suite.Len(state.Changes, 0)
+
+	// no temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(file))
+	suite.Nil(err, err)
+	suite.Len(entries, 1)
+}
+
+func (suite *pacseekTestSuite) TestPacnew() {
+	root := suite.T().TempDir()
+	write := func(name string) {
+		path := filepath.Join(root, name)
+		suite.Nil(os.MkdirAll(filepath.Dir(path), 0755))
+		suite.Nil(os.WriteFile(path, []byte(name), 0644))
+	}
+	write("etc/pacman.conf")
+	write("etc/pacman.conf.pacnew")
+	write("etc/ssh/sshd_config.pacsave")
+	write("etc/.pacnew")
+	write("usr/share/foo/foo.conf.pacnew")
+	write("usr/share/bar/bar.conf.pacnew")
+
+	// backup files outside of our directories are only found when they are listed
+	files := findPacnewFiles(root, []string{"usr/share/foo/foo.conf", "etc/pacman.conf"}, []string{"etc", "nonexistent"})
+	suite.Len(files, 3)
+	suite.Equal(filepath.Join(root, "etc/pacman.conf.pacnew"), files[0].Path)
+	suite.Equal(filepath.Join(root, "etc/pacman.conf"), files[0].Original)
+	suite.Equal("pacnew", files[0].Kind)
+	suite.Equal(filepath.Join(root, "etc/ssh/sshd_config"), files[1].Original)
+	suite.Equal("pacsave", files[1].Kind)
+	suite.Equal(filepath.Join(root, "usr/share/foo/foo.conf.pacnew"), files[2].Path)
+	suite.False(files[0].merged())
+
+	// new and replaced files
+	before := findPacnewFiles(root, nil, []string{"etc"})
+	suite.Len(changedPacnewFiles(before, before), 0)
+	write("etc/locale.gen.pacnew")
+	old := time.Now().Add(-time.Hour)
+	suite.Nil(os.Chtimes(filepath.Join(root, "etc/pacman.conf.pacnew"), old, old))
+	changed := changedPacnewFiles(before, findPacnewFiles(root, nil, []string{"etc"}))
+	suite.Len(changed, 2)
+	suite.Equal(filepath.Join(root, "etc/locale.gen.pacnew"), changed[0].Path)
+	suite.Equal(filepath.Join(root, "etc/pacman.conf.pacnew"), changed[1].Path)
+
+	// merged
+	suite.Nil(os.Remove(changed[0].Path))
+	suite.True(changed[0].merged())
+
+	// no handle
+	suite.Len(backupFiles(nil), 0)
+
+	// merge commands
+	f := pacnewFile{Path: "/etc/it's.conf.pacnew", Original: "/etc/it's.conf"}
+	suite.Equal(`sudo vimdiff '/etc/it'\''s.conf' '/etc/it'\''s.conf.pacnew'`, mergeCommand("sudo vimdiff {original} {file}", f))
+	suite.Equal(`meld '/etc/it'\''s.conf' '/etc/it'\''s.conf.pacnew'`, mergeCommand("meld", f))
+	suite.Equal(`cp '/etc/it'\''s.conf.pacnew' /tmp`, mergeCommand("cp {file} /tmp", f))
+}
+
+func (suite *pacseekTestSuite) TestPluginSource() {
+	requests := []pluginRequest{}
+	p := newPluginSource("/usr/lib/pacseek/pacseek-nix")
+	p.run = func(req []byte) ([]byte, error) {
+		r := pluginRequest{}
+		suite.Nil(json.Unmarshal(req, &r))
+		requests = append(requests, r)
+		switch r.Method {
+		case "search", "info":
+			return []byte(`{"packages": [
+				{"name": "hello", "version": "2.12.1", "localVersion": "2.12", "description": "GNU Hello", "url": "https://www.gnu.org/software/hello/", "license": ["GPL-3.0-or-later"]},
+				{"name": "ripgrep", "version": "14.1.0", "description": "A faster grep"},
+				{"version": "1.0"}
+			]}`), nil
+		case "installed":
+			return []byte(`{"packages": [{"name": "hello"}]}`), nil
+		case "upgradable":
+			return []byte(`{"packages": [{"name": "hello", "version": "2.12.1", "localVersion": "2.12"}]}`), nil
+		}
+		return []byte(`{"error": "unknown method"}`), nil
+	}
+	suite.Equal("nix", p.Name())
+
+	// ok
+	pkgs, errs := searchSources([]packageSource{p}, "hel", 10)
+	suite.Len(errs, 0)
+	suite.Equal([]Package{
+		{Name: "hello", Source: "nix", IsInstalled: true, Description: "GNU Hello"},
+		{Name: "ripgrep", Source: "nix", Description: "A faster grep"},
+	}, pkgs)
+	suite.Equal(pluginRequest{Method: "search", Term: "hel", MaxResults: 10}, requests[0])
+	pkgs, _ = searchSources([]packageSource{p}, "hel", 1)
+	suite.Len(pkgs, 1, "max results not applied")
+
+	r, err := p.Info("hello")
+	suite.Nil(err)
+	suite.Equal(InfoRecord{Name: "hello", Version: "2.12.1", LocalVersion: "2.12", Description: "GNU Hello",
+		URL: "https://www.gnu.org/software/hello/", License: []string{"GPL-3.0-or-later"}, Source: "nix"}, r)
+	installed, err := p.Installed()
+	suite.Nil(err)
+	suite.Equal(map[string]bool{"hello": true}, installed)
+
+	up, errs := sourceUpgrades([]packageSource{p})
+	suite.Len(errs, 0)
+	suite.Len(up, 1)
+	suite.Equal("nix", up[0].Source)
+	suite.Equal("2.12", up[0].LocalVersion)
+	suite.True(selectableUpgrade(up[0]))
+
+	suite.Equal(`'/usr/lib/pacseek/pacseek-nix' install 'hello'`, p.InstallCommand(r))
+	suite.Equal(`'/usr/lib/pacseek/pacseek-nix' uninstall 'hello'`, p.UninstallCommand(r))
+	suite.Equal(`'/usr/lib/pacseek/pacseek-nix' upgrade 'hello'`, p.UpgradeCommand(r))
+
+	// selective upgrades use the upgrade command of their source
+	suite.Equal([]string{`'/usr/lib/pacseek/pacseek-nix' upgrade 'hello'`},
+		selectiveUpgradeCommands(config.Defaults(), []packageSource{p}, up, nil))
+
+	// nok
+	_, err = p.Info("unknown")
+	suite.NotNil(err, "unknown package did not return an error")
+	p.run = func(req []byte) ([]byte, error) {
+		return []byte(`{"error": "nix not found"}`), nil
+	}
+	_, err = p.Installed()
+	suite.EqualError(err, "plugin nix: nix not found")
+	p.run = func(req []byte) ([]byte, error) {
+		return []byte("no json"), nil
+	}
+	_, errs = sourceUpgrades([]packageSource{p})
+	suite.Len(errs, 1, "invalid response not returned")
+	p.run = func(req []byte) ([]byte, error) {
+		return nil, errors.New("exit status 1")
+	}
+	pkgs, errs = searchSources([]packageSource{p}, "hel", 10)
+	suite.Len(pkgs, 0)
+	suite.Len(errs, 1, "plugin error not returned")
+
+	// executable plugins
+	dir := suite.T().TempDir()
+	script := filepath.Join(dir, "pacseek-cargo")
+	suite.Nil(os.WriteFile(script, []byte("#!/bin/sh\ncat >/dev/null\necho '{\"packages\": [{\"name\": \"bat\", \"version\": \"0.24.0\"}]}'\n"), 0755))
+	failing := filepath.Join(dir, "failing")
+	suite.Nil(os.WriteFile(failing, []byte("#!/bin/sh\necho broken >&2\nexit 1\n"), 0755))
+	suite.Nil(validatePlugins(script + "; " + failing))
+	suite.NotNil(validatePlugins(dir), "directory is not a plugin")
+	suite.NotNil(validatePlugins(filepath.Join(dir, "missing")))
+
+	up, err = newPluginSource(script).Upgradable()
+	suite.Nil(err)
+	suite.Equal([]Upgrade{{InfoRecord: InfoRecord{Name: "bat", Version: "0.24.0", Source: "cargo"}, Status: "upgrade"}}, up)
+	_, err = newPluginSource(failing).Installed()
+	suite.ErrorContains(err, "broken", "stderr of the plugin not returned")
+
+	// plugins replace the previous ones, names that are used already are skipped
+	f := newFlatpakSource()
+	sources := withPlugins([]packageSource{f}, script+";"+failing)
+	suite.Len(sources, 3)
+	sources = withPlugins(sources, script+"; /opt/Flatpak; /opt/aur; "+script)
+	suite.Len(sources, 2)
+	suite.Equal(f, sources[0])
+	suite.Equal("cargo", sources[1].Name())
+}
+
+func (suite *pacseekTestSuite) TestFlatpakUpgrades() {
+	updates := "org.gimp.GIMP\t2.10.38\tflathub\n" +
+		"org.example.App\t\tbeta\n" +
+		"broken line\n"
+	installed := "org.gimp.GIMP\t2.10.36\norg.example.App\t1.0\n"
+	f := newFlatpakSource()
+	f.run = func(args ...string) (string, error) {
+		if args[0] == "list" {
+			return installed, nil
+		}
+		return updates, nil
+	}
+
+	// ok
+	up, err := f.Upgradable()
+	suite.Nil(err)
+	suite.Equal([]Upgrade{
+		{InfoRecord: InfoRecord{Name: "org.gimp.GIMP", Version: "2.10.38", LocalVersion: "2.10.36", PackageBase: "flathub", Source: "Flatpak"}, Status: "upgrade"},
+		{InfoRecord: InfoRecord{Name: "org.example.App", Version: "1.0", LocalVersion: "1.0", PackageBase: "beta", Source: "Flatpak"}, Status: "upgrade"},
+	}, up)
+	suite.Equal("flatpak update org.gimp.GIMP", f.UpgradeCommand(up[0].InfoRecord))
+	f.upgradeCommand = "flatpak update --user {pkg}"
+	suite.Equal("flatpak update --user org.gimp.GIMP", f.UpgradeCommand(up[0].InfoRecord))
+	suite.Len(parseFlatpakUpdates("", installed), 0)
+
+	// nok
+	f.run = func(args ...string) (string, error) {
+		return "", errors.New("flatpak not found")
+	}
+	_, errs := sourceUpgrades([]packageSource{f})
+	suite.Len(errs, 1, "flatpak error not returned")
+}
+
+func (suite *pacseekTestSuite) TestPrivileges() {
+	available := func(programs ...string) func(string) (string, error) {
+		return func(name string) (string, error) {
+			for _, p := range programs {
+				if p == name {
+					return "/usr/bin/" + name, nil
+				}
+			}
+			return "", errors.New("not found")
+		}
+	}
+	user, root := 1000, 0
+
+	// ok
+	suite.Nil(privilegeError("sudo pacman -S vim", user, available("sudo")))
+	suite.Nil(privilegeError("doas pacman -Rs vim", user, available("doas")))
+	suite.Nil(privilegeError("pacman -Syu", root, available()))
+	suite.Nil(privilegeError("yay -S vim", user, available("doas")))
+	suite.Nil(privilegeError("cd /tmp && git clone -q https://aur.archlinux.org/yay.git && cd yay && makepkg -si", user, available("sudo")))
+	suite.Nil(privilegeError("flatpak install flathub org.gimp.GIMP", user, available()))
+	suite.Nil(privilegeError(`'/usr/lib/pacseek/pacseek-nix' install 'hello'`, user, available()))
+	suite.Nil(privilegeError("DIFFPROG=meld sudo -E pacdiff", user, available("sudo")))
+	suite.Nil(privilegeError("(sudo /usr/bin/pacman -Sw vim)", user, available("sudo")))
+
+	// nok
+	suite.ErrorContains(privilegeError("sudo pacman -S vim", user, available("doas")), "sudo is not installed")
+	suite.ErrorContains(privilegeError("sudo pacman -S vim", root, available()), "sudo is not installed", "wrapper is run as root as well")
+	suite.ErrorContains(privilegeError("pacman -S vim", user, available("sudo")), "pacman needs root privileges")
+	suite.ErrorContains(privilegeError("sudo pacman -Rs vim && /usr/bin/pacman -S vim", user, available("sudo")), "pacman needs root privileges")
+	suite.ErrorContains(privilegeError("paru -S vim", user, available()), "paru needs sudo / doas / pkexec / run0")
+	suite.ErrorContains(privilegeError("cd yay; makepkg -si", user, available()), "makepkg needs")
+
+	// all of our transaction actions can be bound
+	km, err := newKeymap(nil)
+	suite.Nil(err)
+	for _, name := range transactionActions {
+		_, ok := km[name]
+		suite.True(ok, name+" is not an action")
+	}
+}
+
+func (suite *pacseekTestSuite) TestNilHandle() {
+	ps := &UI{}
+	suite.Nil(ps.handle())
+	_, err := lookupPackage(ps.handle(), "pacman")
+	suite.ErrorContains(err, "alpm handle is nil")
+	suite.Empty(repoVersions(ps.handle(), "pacman", nil))
+	suite.Empty(backupFiles(ps.handle()))
+	suite.Empty(areInstalled(ps.handle(), []string{"pacman"}))
+	suite.False(isPackageInstalled(ps.handle(), "pacman"))
+	_, err = installedSet(ps.handle())
+	suite.ErrorContains(err, "alpm handle is nil")
+	suite.Equal("alpm handle is nil", infoPacman(ps.handle(), false, "pacman").Error)
+	suite.Empty(suggestRepos(ps.handle(), "pac"))
+	installed, notFound := getInstalled(ps.handle(), false)
+	suite.Empty(installed)
+	suite.Empty(notFound)
+	pkgs := []InfoRecord{{Name: "pacman", Depends: []string{"glibc"}}}
+	addLocalSatisfiers(ps.handle(), pkgs...)
+	suite.Equal([]DependencySatisfier{{DepName: "glibc", DepType: "dep"}}, pkgs[0].DepsAndSatisfiers)
+}
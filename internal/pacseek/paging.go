@@ -0,0 +1,51 @@
+package pacseek
+
+// the AUR RPC refuses searches with more results than this
+const aurMaxResults = 5000
+
+// searchPaging is the state of the search results shown in our package list
+// further pages are loaded when the last one is reached, "more" tells if there might be any
+type searchPaging struct {
+	term    string
+	pages   int
+	more    bool
+	loading bool
+}
+
+// returns the number of results we show for a number of pages (MaxResults is the size of a page)
+func resultLimit(pageSize, pages int) int {
+	if pages < 1 {
+		pages = 1
+	}
+	return pageSize * pages
+}
+
+// returns the number of pages needed for a number of results
+func resultPages(count, pageSize int) int {
+	if pageSize < 1 || count <= pageSize {
+		return 1
+	}
+	return (count + pageSize - 1) / pageSize
+}
+
+// strips down our results to a limit
+// there are more results if we had to strip some or if one of our sources was capped ("capped")
+func pageResults(packages []Package, limit int, capped bool) ([]Package, bool) {
+	if len(packages) > limit {
+		return packages[:limit], true
+	}
+	return packages, capped
+}
+
+// loads the next page of our search results (when the last package in our list is selected)
+func (ps *UI) loadMoreResults() {
+	p := ps.searchPaging
+	// our search term has been changed in the meantime
+	if ps.conf.DisableLazyLoading || !p.more || p.loading || p.term != ps.lastSearchTerm {
+		return
+	}
+	ps.searchPaging.loading = true
+	row, _ := ps.tablePackages.GetSelection()
+	ps.tablePackages.SetTitle(ps.packageListTitle(row))
+	ps.searchPackages(p.term, p.pages+1)
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/moson-mo/pacseek/internal/util"
 )
@@ -25,13 +26,28 @@ var gitlabRepl = []RegexReplace{
 	{repl: `unix-tree`, match: regexp.MustCompile(`^tree$`)},
 }
 
+// files of a package's git repository that can be shown
+const (
+	filePkgbuild = "PKGBUILD"
+	fileSrcinfo  = ".SRCINFO"
+)
+
+// timeout for downloading PKGBUILD / .SRCINFO files
+const pkgbuildTimeout = 10 * time.Second
+
 // download the PKGBUILD file
 func getPkgbuildContent(url string) (string, error) {
-	resp, err := http.Get(url)
+	client := http.Client{
+		Timeout: pkgbuildTimeout,
+	}
+	resp, err := client.Get(url)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
 
 	b, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -49,6 +65,27 @@ func getPkgbuildUrl(source, base string) string {
 	return fmt.Sprintf(UrlAurPkgbuild, base)
 }
 
+// composes the URL to a file (e.g. .SRCINFO) in the same git repository as the PKGBUILD
+func getPackageFileUrl(source, base, file string) string {
+	return strings.TrimSuffix(getPkgbuildUrl(source, base), filePkgbuild) + file
+}
+
+// returns the file we switch to from a PKGBUILD / .SRCINFO
+func otherPackageFile(file string) string {
+	if file == filePkgbuild {
+		return fileSrcinfo
+	}
+	return filePkgbuild
+}
+
+// returns the name of the chroma lexer for highlighting a package file
+func packageFileLexer(file string) string {
+	if file == fileSrcinfo {
+		return "ini"
+	}
+	return "bash"
+}
+
 func encodePackageGitlabUrl(pkgname string) string {
 	for _, regex := range gitlabRepl {
 		pkgname = regex.match.ReplaceAllString(pkgname, regex.repl)
@@ -0,0 +1,171 @@
+package pacseek
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Jguer/go-alpm/v2"
+)
+
+// retrieves package information from a package file (e.g. *.pkg.tar.zst)
+// our alpm bindings can't load package files, so we extract the .PKGINFO file with bsdtar (libarchive, required by pacman)
+func infoFromFile(path string) (InfoRecord, error) {
+	if _, err := os.Stat(path); err != nil {
+		return InfoRecord{}, err
+	}
+
+	out, err := exec.Command("bsdtar", "-xOf", path, ".PKGINFO").Output()
+	if err != nil {
+		return InfoRecord{}, fmt.Errorf("failed to read package file '%s': %w", path, err)
+	}
+
+	i, err := parsePkginfo(out)
+	if err != nil {
+		return InfoRecord{}, fmt.Errorf("failed to read package file '%s': %w", path, err)
+	}
+	return i, nil
+}
+
+// parses the contents of a .PKGINFO file and returns it in the same format as infoPacman
+func parsePkginfo(data []byte) (InfoRecord, error) {
+	i := InfoRecord{
+		Source: "file",
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(line, " = ")
+		if !found {
+			continue
+		}
+
+		switch key {
+		case "pkgname":
+			i.Name = value
+		case "pkgbase":
+			i.PackageBase = value
+		case "pkgver":
+			i.Version = value
+		case "pkgdesc":
+			i.Description = value
+		case "url":
+			i.URL = value
+		case "builddate":
+			i.LastModified, _ = strconv.Atoi(value)
+		case "packager":
+			i.Maintainer = value
+		case "arch":
+			i.Architecture = value
+		case "license":
+			i.License = append(i.License, value)
+		case "group":
+			i.Groups = append(i.Groups, value)
+		case "depend":
+			i.Depends = append(i.Depends, value)
+		case "optdepend":
+			// strip description, we only show the dependency itself
+			name, _, _ := strings.Cut(value, ": ")
+			i.OptDepends = append(i.OptDepends, name)
+		case "makedepend":
+			i.MakeDepends = append(i.MakeDepends, value)
+		case "checkdepend":
+			i.CheckDepends = append(i.CheckDepends, value)
+		case "provides":
+			i.Provides = append(i.Provides, value)
+		case "conflict":
+			i.Conflicts = append(i.Conflicts, value)
+		case "replaces":
+			i.Replaces = append(i.Replaces, value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return i, err
+	}
+
+	if i.Name == "" || i.Version == "" {
+		return i, errors.New("invalid package info: pkgname or pkgver missing")
+	}
+	return i, nil
+}
+
+// looks for a downloaded package file of a package (e.g. name-1.0-1-x86_64.pkg.tar.zst) in all of our cache directories
+// returns the path of the first file found (cache directories are checked in the given order)
+func cachedPackageFile(cacheDirs []string, name, version string) (string, bool) {
+	prefix := name + "-" + version + "-"
+	for _, dir := range cacheDirs {
+		files, err := filepath.Glob(filepath.Join(dir, prefix+"*.pkg.tar*"))
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			// the remainder is the architecture and file extension (e.g. "x86_64.pkg.tar.zst"), signature files are skipped
+			rest := strings.TrimPrefix(filepath.Base(file), prefix)
+			if strings.Contains(rest, "-") || strings.HasSuffix(rest, ".sig") {
+				continue
+			}
+			if fi, err := os.Stat(file); err == nil && fi.Mode().IsRegular() {
+				return file, true
+			}
+		}
+	}
+	return "", false
+}
+
+// cachedPackage is a downloaded package file of a specific version
+type cachedPackage struct {
+	Version string
+	Arch    string
+	Path    string
+	Size    int64
+}
+
+// returns all versions of a package found in our cache directories (newest first)
+// the package file of a version is looked up with cachedPackageFile, so it is taken from the first directory having it
+func cachedVersions(cacheDirs []string, name string) []cachedPackage {
+	versions := []cachedPackage{}
+	seen := map[string]bool{}
+	for _, dir := range cacheDirs {
+		files, err := filepath.Glob(filepath.Join(dir, name+"-*.pkg.tar*"))
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			// the remainder is "pkgver-pkgrel-arch.pkg.tar.ext", which skips packages with the same prefix (e.g. vim-runtime for vim)
+			parts := strings.Split(strings.TrimPrefix(filepath.Base(file), name+"-"), "-")
+			if len(parts) != 3 {
+				continue
+			}
+			version := parts[0] + "-" + parts[1]
+			if seen[version] {
+				continue
+			}
+			path, found := cachedPackageFile(cacheDirs, name, version)
+			if !found {
+				continue
+			}
+			fi, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			seen[version] = true
+			arch, _, _ := strings.Cut(strings.TrimPrefix(filepath.Base(path), name+"-"+version+"-"), ".pkg.tar")
+			versions = append(versions, cachedPackage{Version: version, Arch: arch, Path: path, Size: fi.Size()})
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return alpm.VerCmp(versions[i].Version, versions[j].Version) > 0
+	})
+	return versions
+}
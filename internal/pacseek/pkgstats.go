@@ -0,0 +1,192 @@
+package pacseek
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	pkgstatsTimeout     = 10 * time.Second
+	pkgstatsPageSize    = 250  // maximum number of packages per request
+	pkgstatsMaxPackages = 5000 // we fetch the most popular ones only, all others are close to 0%
+	pkgstatsExpiry      = 7 * 24 * time.Hour
+	pkgstatsFileName    = "pkgstats.json"
+)
+
+// pkgstats holds the popularity of repository packages from pkgstats.archlinux.de,
+// the percentage of the systems submitting their package list that have a package installed
+// a nil pkgstats is valid and doesn't change anything (e.g. when disabled)
+type pkgstats struct {
+	mu         sync.RWMutex
+	fetched    time.Time
+	popularity map[string]float64
+}
+
+// pkgstatsFile is the format of our cache file
+type pkgstatsFile struct {
+	Fetched    time.Time
+	Popularity map[string]float64
+}
+
+// a page of the package list of the pkgstats API (ordered by popularity)
+type pkgstatsResponse struct {
+	Total               int `json:"total"`
+	PackagePopularities []struct {
+		Name       string  `json:"name"`
+		Popularity float64 `json:"popularity"`
+	} `json:"packagePopularities"`
+}
+
+// retrieves the popularity of the most popular packages ("max") from the pkgstats API
+func fetchPkgstats(url string, max int) (map[string]float64, error) {
+	client := http.Client{
+		Timeout: pkgstatsTimeout,
+	}
+	popularity := map[string]float64{}
+	for offset := 0; offset < max; offset += pkgstatsPageSize {
+		req, err := http.NewRequest("GET", fmt.Sprintf(url, pkgstatsPageSize, offset), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", "pacseek/"+version)
+		req.Header.Set("Accept", "application/json")
+
+		r, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		var page pkgstatsResponse
+		err = json.NewDecoder(r.Body).Decode(&page)
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if r.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("pkgstats returned status %d", r.StatusCode)
+		}
+		for _, p := range page.PackagePopularities {
+			popularity[p.Name] = p.Popularity
+		}
+		if len(page.PackagePopularities) < pkgstatsPageSize || offset+pkgstatsPageSize >= page.Total {
+			break
+		}
+	}
+	return popularity, nil
+}
+
+// returns the path of our cache file
+func pkgstatsCacheFile() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "pacseek", pkgstatsFileName), nil
+}
+
+// reads our popularity data from a cache file
+func (s *pkgstats) read(file string) error {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var f pkgstatsFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return err
+	}
+	s.set(f.Popularity, f.Fetched)
+	return nil
+}
+
+// writes our popularity data to a cache file
+func (s *pkgstats) write(file string) error {
+	s.mu.RLock()
+	b, err := json.Marshal(pkgstatsFile{Fetched: s.fetched, Popularity: s.popularity})
+	s.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, b, 0644)
+}
+
+// replaces our popularity data
+func (s *pkgstats) set(popularity map[string]float64, fetched time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.popularity, s.fetched = popularity, fetched
+}
+
+// checks if our data needs to be refreshed (pkgstats are aggregated monthly, so once a week is plenty)
+func (s *pkgstats) expired() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.popularity) == 0 || time.Since(s.fetched) > pkgstatsExpiry
+}
+
+// sets the popularity of repository packages (the ones with repoPopularity), packages without data get 0
+// nothing is changed while we don't have any data
+func (s *pkgstats) apply(pkgs []Package) {
+	if s == nil {
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.popularity) == 0 {
+		return
+	}
+	for i := range pkgs {
+		if pkgs[i].Popularity == repoPopularity {
+			pkgs[i].Popularity = s.popularity[pkgs[i].Name]
+		}
+	}
+}
+
+// returns the popularity of a repository package
+func (s *pkgstats) get(name string) (float64, bool) {
+	if s == nil {
+		return 0, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.popularity) == 0 {
+		return 0, false
+	}
+	return s.popularity[name], true
+}
+
+// loads our popularity data from our cache file, it is refreshed in the background when it's expired
+func (ps *UI) loadPkgstats() {
+	if ps.conf.DisablePkgstats {
+		return
+	}
+	go func() {
+		file, err := pkgstatsCacheFile()
+		if err != nil {
+			return
+		}
+		ps.popularity.read(file)
+		if !ps.popularity.expired() || ps.connectivity.offline() {
+			return
+		}
+		popularity, err := fetchPkgstats(UrlPkgstats, pkgstatsMaxPackages)
+		if err != nil {
+			return
+		}
+		ps.popularity.set(popularity, time.Now())
+		ps.popularity.write(file)
+	}()
+}
+
+// sets the popularity of repository packages (unless pkgstats are disabled)
+func (ps *UI) applyPopularity(pkgs []Package) {
+	if !ps.conf.DisablePkgstats {
+		ps.popularity.apply(pkgs)
+	}
+}
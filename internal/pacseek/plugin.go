@@ -0,0 +1,255 @@
+package pacseek
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maximum time a plugin may take to answer a request
+const pluginTimeout = 15 * time.Second
+
+// names that can't be used by plugins, they would be mistaken for our own sources
+var reservedSourceNames = []string{"AUR", "local", "all"}
+
+// pluginRequest is sent to a plugin (JSON on stdin)
+// methods are "search" (Term / MaxResults), "info" (Name), "installed" and "upgradable"
+type pluginRequest struct {
+	Method     string `json:"method"`
+	Term       string `json:"term,omitempty"`
+	MaxResults int    `json:"maxResults,omitempty"`
+	Name       string `json:"name,omitempty"`
+}
+
+// pluginPackage is a package returned by a plugin
+type pluginPackage struct {
+	Name          string   `json:"name"`
+	Version       string   `json:"version"`
+	LocalVersion  string   `json:"localVersion"` // installed version (if installed)
+	Description   string   `json:"description"`
+	URL           string   `json:"url"`
+	License       []string `json:"license"`
+	Depends       []string `json:"depends"`
+	Provides      []string `json:"provides"`
+	Maintainer    string   `json:"maintainer"`
+	LastModified  int      `json:"lastModified"` // unix time
+	InstalledSize int64    `json:"installedSize"`
+	Installed     bool     `json:"installed"`
+}
+
+// pluginResponse is the answer of a plugin (JSON on stdout)
+type pluginResponse struct {
+	Error    string          `json:"error"`
+	Packages []pluginPackage `json:"packages"`
+}
+
+// pluginSource is a source of packages provided by an executable (e.g. for nix profiles or cargo / pip user installs)
+// queries are sent as JSON on stdin and answered on stdout, packages are installed / removed / upgraded with
+// "<plugin> install|uninstall|upgrade <pkg>", which is run in our terminal (so that it can ask for confirmation)
+type pluginSource struct {
+	name string
+	path string
+	run  func(req []byte) ([]byte, error) // runs the plugin with a request and returns its output
+}
+
+// creates a plugin source for an executable, its name is the file name without the "pacseek-" prefix
+func newPluginSource(path string) *pluginSource {
+	p := &pluginSource{
+		name: strings.TrimPrefix(filepath.Base(path), "pacseek-"),
+		path: path,
+	}
+	p.run = func(req []byte) ([]byte, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), pluginTimeout)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, p.path)
+		cmd.Stdin = bytes.NewReader(req)
+		out, err := cmd.Output()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(bytes.TrimSpace(exitErr.Stderr)) > 0 {
+			return out, fmt.Errorf("%w: %s", err, bytes.TrimSpace(exitErr.Stderr))
+		}
+		return out, err
+	}
+	return p
+}
+
+// returns the paths of our plugins (separated by semicolons)
+func pluginPaths(plugins string) []string {
+	paths := []string{}
+	for _, path := range strings.Split(plugins, ";") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// returns our sources with (only) the given plugins, the other sources are kept
+// plugins with a reserved name or a name that is used already are skipped
+func withPlugins(sources []packageSource, plugins string) []packageSource {
+	merged := []packageSource{}
+	for _, s := range sources {
+		if _, ok := s.(*pluginSource); !ok {
+			merged = append(merged, s)
+		}
+	}
+	for _, path := range pluginPaths(plugins) {
+		p := newPluginSource(path)
+		if findSource(merged, p.name) != nil || indexOfFold(reservedSourceNames, p.name) >= 0 {
+			logger.warn("plugin skipped, its name is in use already", "plugin", path, "name", p.name)
+			continue
+		}
+		merged = append(merged, p)
+	}
+	return merged
+}
+
+// returns the index of a string in a list, ignoring the case (-1 if it is not found)
+func indexOfFold(list []string, s string) int {
+	for i, item := range list {
+		if strings.EqualFold(item, s) {
+			return i
+		}
+	}
+	return -1
+}
+
+// sends a request to our plugin and returns the packages of its response
+func (p *pluginSource) request(req pluginRequest) ([]pluginPackage, error) {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	out, err := p.run(b)
+	logger.debug("plugin request", "plugin", p.name, "method", req.Method, "duration", time.Since(start), "error", err)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s failed: %w", p.name, err)
+	}
+	resp := pluginResponse{}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return nil, fmt.Errorf("plugin %s returned an invalid response: %w", p.name, err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("plugin %s: %s", p.name, resp.Error)
+	}
+	valid := []pluginPackage{}
+	for _, pkg := range resp.Packages {
+		if pkg.Name != "" {
+			valid = append(valid, pkg)
+		}
+	}
+	return valid, nil
+}
+
+// converts a package of our plugin
+func (p *pluginSource) record(pkg pluginPackage) InfoRecord {
+	return InfoRecord{
+		Name:          pkg.Name,
+		Version:       pkg.Version,
+		LocalVersion:  pkg.LocalVersion,
+		Description:   pkg.Description,
+		URL:           pkg.URL,
+		License:       pkg.License,
+		Depends:       pkg.Depends,
+		Provides:      pkg.Provides,
+		Maintainer:    pkg.Maintainer,
+		LastModified:  pkg.LastModified,
+		InstalledSize: pkg.InstalledSize,
+		Source:        p.name,
+	}
+}
+
+// Name returns the name of our plugin
+func (p *pluginSource) Name() string {
+	return p.name
+}
+
+// Search searches the packages of our plugin
+func (p *pluginSource) Search(term string, maxResults int) ([]Package, error) {
+	packages := []Package{}
+	pkgs, err := p.request(pluginRequest{Method: "search", Term: term, MaxResults: maxResults})
+	if err != nil {
+		return packages, err
+	}
+	for _, pkg := range pkgs {
+		if len(packages) >= maxResults {
+			break
+		}
+		packages = append(packages, Package{
+			Name:          pkg.Name,
+			Source:        p.name,
+			IsInstalled:   pkg.Installed || pkg.LocalVersion != "",
+			LastModified:  pkg.LastModified,
+			InstalledSize: pkg.InstalledSize,
+			Description:   pkg.Description,
+		})
+	}
+	return packages, nil
+}
+
+// Info returns the information of a package
+func (p *pluginSource) Info(name string) (InfoRecord, error) {
+	pkgs, err := p.request(pluginRequest{Method: "info", Name: name})
+	if err != nil {
+		return InfoRecord{}, err
+	}
+	for _, pkg := range pkgs {
+		if pkg.Name == name {
+			return p.record(pkg), nil
+		}
+	}
+	return InfoRecord{}, fmt.Errorf("package '%s' not found", name)
+}
+
+// Installed returns the names of the installed packages
+func (p *pluginSource) Installed() (map[string]bool, error) {
+	installed := map[string]bool{}
+	pkgs, err := p.request(pluginRequest{Method: "installed"})
+	if err != nil {
+		return installed, err
+	}
+	for _, pkg := range pkgs {
+		installed[pkg.Name] = true
+	}
+	return installed, nil
+}
+
+// Upgradable returns the installed packages that can be upgraded
+func (p *pluginSource) Upgradable() ([]Upgrade, error) {
+	up := []Upgrade{}
+	pkgs, err := p.request(pluginRequest{Method: "upgradable"})
+	if err != nil {
+		return up, err
+	}
+	for _, pkg := range pkgs {
+		up = append(up, Upgrade{InfoRecord: p.record(pkg), Status: "upgrade"})
+	}
+	return up, nil
+}
+
+// returns the command that runs our plugin for a package
+func (p *pluginSource) command(action string, pkg InfoRecord) string {
+	return strings.Join([]string{shellQuote(p.path), action, shellQuote(pkg.Name)}, " ")
+}
+
+// InstallCommand returns the command for installing a package
+func (p *pluginSource) InstallCommand(pkg InfoRecord) string {
+	return p.command("install", pkg)
+}
+
+// UninstallCommand returns the command for removing a package
+func (p *pluginSource) UninstallCommand(pkg InfoRecord) string {
+	return p.command("uninstall", pkg)
+}
+
+// UpgradeCommand returns the command for upgrading a package
+func (p *pluginSource) UpgradeCommand(pkg InfoRecord) string {
+	return p.command("upgrade", pkg)
+}
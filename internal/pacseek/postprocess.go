@@ -0,0 +1,37 @@
+package pacseek
+
+import "sync"
+
+// PostProcessor modifies the list of search results (e.g. filter, annotate or sort packages)
+type PostProcessor func(pkgs []Package) []Package
+
+// postProcessors is a registry of post processors which are applied in the order they were registered
+type postProcessors struct {
+	locker     sync.RWMutex
+	processors []PostProcessor
+}
+
+// adds a post processor to our registry
+func (pp *postProcessors) register(p PostProcessor) {
+	if p == nil {
+		return
+	}
+	pp.locker.Lock()
+	defer pp.locker.Unlock()
+	pp.processors = append(pp.processors, p)
+}
+
+// runs all registered post processors on our list of packages
+func (pp *postProcessors) apply(pkgs []Package) []Package {
+	pp.locker.RLock()
+	defer pp.locker.RUnlock()
+	for _, p := range pp.processors {
+		pkgs = p(pkgs)
+	}
+	return pkgs
+}
+
+// AddPostProcessor registers a function that is applied to search results (after searching repositories and AUR)
+func (ps *UI) AddPostProcessor(p PostProcessor) {
+	ps.postProcessors.register(p)
+}
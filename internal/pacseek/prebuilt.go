@@ -0,0 +1,81 @@
+package pacseek
+
+import (
+	"github.com/Jguer/go-alpm/v2"
+	"github.com/moson-mo/pacseek/internal/util"
+)
+
+// returns the repositories of our sync db's that are not official ones (e.g. chaotic-aur or custom repositories from pacman.conf)
+func thirdPartyRepos(h dbHandle, official []string) []string {
+	repos := []string{}
+	if h == nil {
+		return repos
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return repos
+	}
+	for _, db := range dbs.Slice() {
+		if !util.SliceContains(official, db.Name()) {
+			repos = append(repos, db.Name())
+		}
+	}
+	return repos
+}
+
+// merges AUR packages with their prebuilt variants from third-party repositories, keyed by package name
+// the repository package is kept and marked as available in the AUR (AurAvailable), the AUR package is dropped
+// if the repository package is not part of the results (e.g. it didn't match the search), it takes the place of the AUR package
+func mergePrebuilt(h dbHandle, packages []Package, official []string) []Package {
+	repos := thirdPartyRepos(h, official)
+	if len(repos) == 0 {
+		return packages
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return packages
+	}
+
+	// prebuilt variants of our AUR packages (first repository that has it) and the repository packages in our results
+	prebuilt := map[string]alpm.IPackage{}
+	found := map[string]bool{}
+	for _, pkg := range packages {
+		if pkg.Source != "AUR" {
+			found[pkg.Name+"-"+pkg.Source] = true
+			continue
+		}
+		for _, db := range dbs.Slice() {
+			if rpkg := db.Pkg(pkg.Name); rpkg != nil && util.SliceContains(repos, db.Name()) {
+				prebuilt[pkg.Name] = rpkg
+				break
+			}
+		}
+	}
+
+	merged := []Package{}
+	for _, pkg := range packages {
+		rpkg, ok := prebuilt[pkg.Name]
+		switch {
+		case !ok:
+			merged = append(merged, pkg)
+		case pkg.Source == rpkg.DB().Name():
+			pkg.AurAvailable = true
+			merged = append(merged, pkg)
+		case pkg.Source == "AUR" && !found[pkg.Name+"-"+rpkg.DB().Name()]:
+			merged = append(merged, Package{
+				Name:          pkg.Name,
+				Source:        rpkg.DB().Name(),
+				IsInstalled:   pkg.IsInstalled,
+				LastModified:  int(rpkg.BuildDate().Unix()),
+				HasBuildDate:  true,
+				Popularity:    pkg.Popularity,
+				NumVotes:      pkg.NumVotes,
+				InstalledSize: rpkg.ISize(),
+				AurAvailable:  true,
+			})
+		case pkg.Source != "AUR":
+			merged = append(merged, pkg)
+		}
+	}
+	return merged
+}
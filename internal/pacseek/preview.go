@@ -0,0 +1,145 @@
+package pacseek
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/Jguer/go-alpm/v2"
+	"github.com/moson-mo/pacseek/internal/config"
+)
+
+// previewEntry is a package that is part of an install transaction
+// "Action" is one of "install", "upgrade", "reinstall", "downgrade" or "remove" (conflicting packages)
+type previewEntry struct {
+	Name               string
+	Repo               string
+	Version            string
+	LocalVersion       string
+	Action             string
+	ConflictsWith      string
+	DownloadSize       int64
+	InstalledSizeDelta int64
+}
+
+// transactionPreview lists what an install command is going to do
+type transactionPreview struct {
+	Entries            []previewEntry
+	DownloadSize       int64
+	InstalledSizeDelta int64
+}
+
+// runs "pacman -Sp" (which doesn't require root privileges) to get all targets for installing packages, including their dependencies
+func printTargets(conf *config.Settings, names []string) (string, error) {
+	args := append([]string{"-Sp", "--print-format", "%r %n %v", "--config", conf.PacmanConfigPath, "--root", conf.PacmanRootPath, "--dbpath", conf.PacmanDbPath}, names...)
+	out, err := exec.Command("pacman", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("pacman failed: %s", strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
+
+// parses the output of "pacman -Sp --print-format '%r %n %v'"
+// lines that don't match our format (warnings and such) are skipped
+func parsePrintTargets(out string) []previewEntry {
+	targets := []previewEntry{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || strings.HasSuffix(fields[0], ":") {
+			continue
+		}
+		targets = append(targets, previewEntry{Repo: fields[0], Name: fields[1], Version: fields[2]})
+	}
+	return targets
+}
+
+// classifies the targets of a transaction and computes their sizes
+// installed packages that conflict with (or are replaced by) one of the targets are added as removals
+func buildTransactionPreview(h dbHandle, targets []previewEntry) (transactionPreview, error) {
+	preview := transactionPreview{Entries: []previewEntry{}}
+	if h == nil {
+		return preview, errors.New("alpm handle is nil")
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return preview, err
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return preview, err
+	}
+
+	targetNames := map[string]bool{}
+	syncPkgs := []alpm.IPackage{}
+	for _, t := range targets {
+		targetNames[t.Name] = true
+		t.Action = "install"
+		var spkg alpm.IPackage
+		for _, db := range dbs.Slice() {
+			if db.Name() == t.Repo {
+				spkg = db.Pkg(t.Name)
+			}
+		}
+		if spkg != nil {
+			syncPkgs = append(syncPkgs, spkg)
+			t.DownloadSize = spkg.Size()
+			t.InstalledSizeDelta = spkg.ISize()
+		}
+		if lpkg := local.Pkg(t.Name); lpkg != nil {
+			t.LocalVersion = lpkg.Version()
+			t.InstalledSizeDelta -= lpkg.ISize()
+			switch cmp := alpm.VerCmp(t.Version, lpkg.Version()); {
+			case cmp > 0:
+				t.Action = "upgrade"
+			case cmp < 0:
+				t.Action = "downgrade"
+			default:
+				t.Action = "reinstall"
+			}
+		}
+		preview.DownloadSize += t.DownloadSize
+		preview.InstalledSizeDelta += t.InstalledSizeDelta
+		preview.Entries = append(preview.Entries, t)
+	}
+
+	removals := []previewEntry{}
+	for _, lpkg := range local.PkgCache().Slice() {
+		if targetNames[lpkg.Name()] {
+			continue
+		}
+		for _, spkg := range syncPkgs {
+			if packagesConflict(spkg, lpkg) || packagesConflict(lpkg, spkg) {
+				removals = append(removals, previewEntry{
+					Name:               lpkg.Name(),
+					Repo:               "local",
+					LocalVersion:       lpkg.Version(),
+					Action:             "remove",
+					ConflictsWith:      spkg.Name(),
+					InstalledSizeDelta: -lpkg.ISize(),
+				})
+				preview.InstalledSizeDelta -= lpkg.ISize()
+				break
+			}
+		}
+	}
+	sort.Slice(removals, func(i, j int) bool {
+		return removals[i].Name < removals[j].Name
+	})
+	preview.Entries = append(preview.Entries, removals...)
+
+	return preview, nil
+}
+
+// returns the names of our queued packages that are installed from the repositories (the ones we can preview)
+func repoInstallTargets(sources []packageSource, queue []queuedPackage) []string {
+	names := []string{}
+	for _, q := range queue {
+		if q.Installed || q.Source == "AUR" || findSource(sources, q.Source) != nil {
+			continue
+		}
+		names = append(names, q.Name)
+	}
+	return names
+}
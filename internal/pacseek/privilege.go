@@ -0,0 +1,93 @@
+package pacseek
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/moson-mo/pacseek/internal/util"
+)
+
+// actions that install / remove / upgrade packages, they are unbound in read-only mode
+var transactionActions = []string{"Install", "Upgrade", "AurUpgrade", "Queue", "Download"}
+
+// programs that need root privileges, they have to be run with one of our commandWrappers (e.g. sudo)
+var privilegedPrograms = []string{"pacman", "pacman-key"}
+
+// programs that elevate their privileges themselves when needed, using one of our commandWrappers (e.g. yay runs sudo pacman)
+var selfElevatingPrograms = []string{"yay", "paru", "pikaur", "trizen", "aura", "makepkg"}
+
+// the operators separating the commands of a shell command line (e.g. "cd /tmp && makepkg -si")
+var shellOperators = regexp.MustCompile(`&&|\|\||[;&|()]`)
+
+var errReadOnly = errors.New("read-only mode: packages can't be installed, removed or upgraded")
+
+// checks if a command (line) is able to run its programs with root privileges
+// it fails if a wrapper like sudo is not installed, if pacman is run without one (and we're not root)
+// and if an AUR helper / makepkg is run without any wrapper being available
+func privilegeError(command string, euid int, lookPath func(string) (string, error)) error {
+	wrapperFound := func() bool {
+		for _, w := range commandWrappers {
+			if _, err := lookPath(w); err == nil {
+				return true
+			}
+		}
+		return false
+	}
+	for _, segment := range shellOperators.Split(command, -1) {
+		fields := strings.Fields(segment)
+		// variable assignments (e.g. "DIFFPROG=meld pacdiff")
+		for len(fields) > 0 && strings.Contains(fields[0], "=") && !strings.Contains(fields[0], "/") {
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		program := filepath.Base(strings.Trim(fields[0], `'"`))
+		switch {
+		case util.SliceContains(commandWrappers, program):
+			if _, err := lookPath(program); err != nil {
+				return fmt.Errorf("%s is not installed, it is needed to run: %s", program, strings.TrimSpace(segment))
+			}
+		case euid == 0:
+			continue
+		case util.SliceContains(privilegedPrograms, program):
+			return fmt.Errorf("%s needs root privileges, prefix it with sudo / doas in your command: %s", program, strings.TrimSpace(segment))
+		case util.SliceContains(selfElevatingPrograms, program) && !wrapperFound():
+			return fmt.Errorf("%s needs %s to elevate privileges, none of them is installed", program, strings.Join(commandWrappers, " / "))
+		}
+	}
+	return nil
+}
+
+// checks if our read-only mode is enabled (--read-only or our settings)
+func (ps *UI) readOnly() bool {
+	return ps.flags.ReadOnly || ps.conf.ReadOnly
+}
+
+// checks if a command that changes our system may be run (read-only mode) and if it can elevate its privileges
+// it is checked before our previews are shown, so that we don't end up with a failed command in our terminal
+func (ps *UI) preflight(command string) error {
+	if ps.readOnly() {
+		return errReadOnly
+	}
+	if err := privilegeError(command, os.Geteuid(), exec.LookPath); err != nil {
+		logger.warn("command can't be run", "command", command, "error", err)
+		return err
+	}
+	return nil
+}
+
+// creates our keymap, actions that install / remove / upgrade packages are unbound in read-only mode
+func (ps *UI) setupKeymap() {
+	ps.keys, _ = newKeymap(ps.conf.KeyBindings)
+	if ps.readOnly() {
+		for _, name := range transactionActions {
+			delete(ps.keys, name)
+		}
+	}
+}
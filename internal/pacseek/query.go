@@ -0,0 +1,195 @@
+package pacseek
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/moson-mo/pacseek/internal/util"
+)
+
+// searchQuery is a parsed search term like "python http -test repo:extra installed:yes"
+// all terms have to match (name / description, depending on our search settings), excluded words must not
+// be part of the name or description, repos restricts the sources ("aur" for the AUR, "local" for local-only packages)
+type searchQuery struct {
+	Terms     []string
+	Excluded  []string
+	Repos     []string
+	Installed InstalledFilter
+}
+
+// our qualifiers, e.g. "repo:extra"
+var queryQualifiers = []string{"repo", "installed"}
+
+// parses a search term into a query, regular expressions are not parsed (they can contain anything)
+func parseSearchQuery(text, mode string) (searchQuery, error) {
+	q := searchQuery{}
+	if mode == "Regex" {
+		q.Terms = []string{normalizeSearchTerm(text)}
+		return q, nil
+	}
+	for _, word := range strings.Fields(text) {
+		if strings.HasPrefix(word, "-") && len(word) > 1 {
+			q.Excluded = append(q.Excluded, strings.ToLower(word[1:]))
+			continue
+		}
+		key, value, found := strings.Cut(word, ":")
+		if !found || !util.SliceContains(queryQualifiers, strings.ToLower(key)) {
+			q.Terms = append(q.Terms, word)
+			continue
+		}
+		switch strings.ToLower(key) {
+		case "repo":
+			for _, repo := range strings.Split(strings.ToLower(value), ",") {
+				if repo != "" {
+					q.Repos = append(q.Repos, repo)
+				}
+			}
+		case "installed":
+			switch strings.ToLower(value) {
+			case "yes", "true", "1":
+				q.Installed = InstalledOnly
+			case "no", "false", "0":
+				q.Installed = NotInstalledOnly
+			default:
+				return q, fmt.Errorf("invalid qualifier '%s', use installed:yes or installed:no", word)
+			}
+		}
+	}
+	return q, nil
+}
+
+// returns the terms that have to match, this is what we search for
+func (q searchQuery) Term() string {
+	return strings.Join(q.Terms, " ")
+}
+
+// returns the term we search the AUR for, it can only search for a single literal (the longest of our terms)
+func (q searchQuery) aurTerm() string {
+	longest := ""
+	for _, t := range q.Terms {
+		if len(t) > len(longest) {
+			longest = t
+		}
+	}
+	return longest
+}
+
+// checks if the AUR is part of our sources
+func (q searchQuery) includesAur() bool {
+	return len(q.Repos) == 0 || util.SliceContains(q.Repos, "aur")
+}
+
+// packageCandidate holds the properties of a package our query predicates look at
+type packageCandidate struct {
+	Name        string
+	Description string
+	Source      string
+	Installed   bool
+}
+
+// queryPredicate decides if a package is part of our search results
+type queryPredicate func(c packageCandidate) bool
+
+// combines predicates, all of them have to be true (nil predicates are skipped)
+// returns nil if there are none, which means everything matches
+func allOf(preds ...queryPredicate) queryPredicate {
+	chain := []queryPredicate{}
+	for _, p := range preds {
+		if p != nil {
+			chain = append(chain, p)
+		}
+	}
+	if len(chain) == 0 {
+		return nil
+	}
+	return func(c packageCandidate) bool {
+		for _, p := range chain {
+			if !p(c) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// packages with any of the words in their name or description (case insensitive) don't match
+func excludesWords(words []string) queryPredicate {
+	if len(words) == 0 {
+		return nil
+	}
+	return func(c packageCandidate) bool {
+		name, desc := strings.ToLower(c.Name), strings.ToLower(c.Description)
+		for _, w := range words {
+			if strings.Contains(name, w) || strings.Contains(desc, w) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// packages need to contain all of the words in their name or description (case insensitive)
+func containsWords(words []string) queryPredicate {
+	if len(words) == 0 {
+		return nil
+	}
+	return func(c packageCandidate) bool {
+		name, desc := strings.ToLower(c.Name), strings.ToLower(c.Description)
+		for _, w := range words {
+			if !strings.Contains(name, w) && !strings.Contains(desc, w) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// only packages of one of the sources match
+func inSources(sources []string) queryPredicate {
+	if len(sources) == 0 {
+		return nil
+	}
+	return func(c packageCandidate) bool {
+		return util.SliceContains(sources, strings.ToLower(c.Source))
+	}
+}
+
+// only packages with the given install state match
+func installState(f InstalledFilter) queryPredicate {
+	if f == InstalledAny {
+		return nil
+	}
+	return func(c packageCandidate) bool {
+		return f.matches(c.Installed)
+	}
+}
+
+// returns the predicate chain of our query (exclusions and qualifiers), nil if there's nothing to check
+func (q searchQuery) predicate() queryPredicate {
+	return allOf(excludesWords(q.Excluded), inSources(q.Repos), installState(q.Installed))
+}
+
+// returns the predicate chain for AUR results, which also checks the terms we didn't search the AUR for
+func (q searchQuery) aurPredicate(by string) queryPredicate {
+	words := []string{}
+	if len(q.Terms) > 1 && (by == "Name" || by == "Name & Description" || by == "Broad") {
+		for _, t := range q.Terms {
+			words = append(words, strings.ToLower(t))
+		}
+	}
+	return allOf(q.predicate(), containsWords(words))
+}
+
+// removes the packages not matching a predicate (e.g. AUR results, which are filtered after searching)
+func filterByPredicate(pkgs []Package, pred queryPredicate) []Package {
+	if pred == nil {
+		return pkgs
+	}
+	filtered := []Package{}
+	for _, pkg := range pkgs {
+		if pred(packageCandidate{Name: pkg.Name, Description: pkg.Description, Source: pkg.Source, Installed: pkg.IsInstalled}) {
+			filtered = append(filtered, pkg)
+		}
+	}
+	return filtered
+}
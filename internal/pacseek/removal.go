@@ -0,0 +1,236 @@
+package pacseek
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+
+	pconf "github.com/Morganamilo/go-pacmanconf"
+	"github.com/moson-mo/pacseek/internal/config"
+	"github.com/moson-mo/pacseek/internal/util"
+	"github.com/rivo/tview"
+)
+
+// removalStrategy is a set of pacman flags for removing packages
+type removalStrategy struct {
+	Flags       string
+	Description string
+}
+
+// the removal strategies we offer in our removal preview
+var removalStrategies = []removalStrategy{
+	{"-Rs", "Remove packages and their dependencies that are not required by other packages"},
+	{"-Rns", "Same as -Rs, configuration files are removed as well (no .pacsave backups)"},
+	{"-Rc", "Remove packages and all packages that depend on them (cascade)"},
+}
+
+// packages that are essential for a working system (in addition to HoldPkg of pacman.conf)
+var essentialPackages = []string{"base", "filesystem", "glibc", "bash", "coreutils", "pacman", "systemd", "util-linux", "shadow", "sudo", "linux", "linux-lts", "linux-zen", "linux-hardened"}
+
+// matches the removal operation (and its options) of a command, e.g. "-Rs" in "yay -Rs"
+var removalFlagsRegex = regexp.MustCompile(`(?:^|\s)(-R[a-zA-Z]*)(?:\s|$)`)
+
+// removalPreview lists what a removal is going to do
+// Essential are the packages that are essential for our system (see essentialPackages) and would be removed
+type removalPreview struct {
+	Flags              string
+	Entries            []previewEntry
+	InstalledSizeDelta int64
+	Essential          []string
+}
+
+// returns the removal flags of an uninstall command ("" if there are none, e.g. for a script)
+func removalFlags(command string) string {
+	if m := removalFlagsRegex.FindStringSubmatch(command); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// replaces the removal flags of an uninstall command, e.g. "yay -Rs" -> "yay -Rns"
+// commands without removal flags are returned unchanged
+func withRemovalFlags(command, flags string) string {
+	m := removalFlagsRegex.FindStringSubmatchIndex(command)
+	if m == nil || flags == "" {
+		return command
+	}
+	return command[:m[2]] + flags + command[m[3]:]
+}
+
+// returns the flags we can choose from: the ones of our uninstall command first, then our strategies
+func removalOptions(command string) []string {
+	current := removalFlags(command)
+	if current == "" {
+		return []string{}
+	}
+	options := []string{current}
+	for _, s := range removalStrategies {
+		if s.Flags != current {
+			options = append(options, s.Flags)
+		}
+	}
+	return options
+}
+
+// runs "pacman -R<flags>p" (which doesn't require root privileges) to get all packages that are removed with the given flags
+func printRemovalTargets(conf *config.Settings, flags string, names []string) (string, error) {
+	args := append([]string{flags + "p", "--print-format", "%n %v", "--config", conf.PacmanConfigPath, "--root", conf.PacmanRootPath, "--dbpath", conf.PacmanDbPath}, names...)
+	out, err := exec.Command("pacman", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("pacman failed: %s", strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
+
+// parses the output of "pacman -Rp --print-format '%n %v'"
+// lines that don't match our format (warnings and such) are skipped
+func parseRemovalTargets(out string) []previewEntry {
+	targets := []previewEntry{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || strings.HasSuffix(fields[0], ":") {
+			continue
+		}
+		targets = append(targets, previewEntry{Name: fields[0], Repo: "local", LocalVersion: fields[1], Action: "remove"})
+	}
+	return targets
+}
+
+// computes the sizes of the packages that are removed and checks if essential packages are part of them
+// "hold" are additional essential packages (HoldPkg of pacman.conf, glob patterns are supported)
+func buildRemovalPreview(h dbHandle, flags string, targets []previewEntry, hold []string) (removalPreview, error) {
+	preview := removalPreview{Flags: flags, Entries: []previewEntry{}, Essential: []string{}}
+	if h == nil {
+		return preview, errors.New("alpm handle is nil")
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return preview, err
+	}
+
+	essential := IgnoreRules{Packages: append(append([]string{}, essentialPackages...), hold...)}
+	for _, t := range targets {
+		if lpkg := local.Pkg(t.Name); lpkg != nil {
+			t.InstalledSizeDelta = -lpkg.ISize()
+		}
+		preview.InstalledSizeDelta += t.InstalledSizeDelta
+		preview.Entries = append(preview.Entries, t)
+		if essential.matches(nil, t.Name) {
+			preview.Essential = append(preview.Essential, t.Name)
+		}
+	}
+	sort.Slice(preview.Entries, func(i, j int) bool {
+		return preview.Entries[i].Name < preview.Entries[j].Name
+	})
+	sort.Strings(preview.Essential)
+	return preview, nil
+}
+
+// returns the HoldPkg entries of a pacman config file
+func pacmanHoldPackages(confPath string) []string {
+	conf, _, err := pconf.ParseFile(confPath)
+	if err != nil {
+		return []string{}
+	}
+	return conf.HoldPkg
+}
+
+// returns the description of a removal strategy
+func removalDescription(flags string) string {
+	for _, s := range removalStrategies {
+		if s.Flags == flags {
+			return s.Description
+		}
+	}
+	return "As configured in the uninstall command"
+}
+
+// shows a preview of the packages that are removed, the removal strategy can be chosen with 1, 2, 3...
+// "run" is called with the chosen flags (when ENTER is pressed), without a preview (disabled or a command without removal flags) it is called right away
+func (ps *UI) previewRemoval(names []string, run func(flags string)) {
+	options := removalOptions(ps.conf.UninstallCommand)
+	if ps.conf.DisableInstallPreview || len(names) == 0 || len(options) == 0 {
+		run(removalFlags(ps.conf.UninstallCommand))
+		return
+	}
+	ps.removalNames, ps.removalOptions, ps.removalRun = names, options, run
+	ps.flexRight.Clear().
+		AddItem(ps.textPreview, 0, 1, true)
+	ps.app.SetFocus(ps.textPreview)
+	ps.computeRemovalPreview(0)
+}
+
+// computes the removal preview with one of our removal options
+func (ps *UI) computeRemovalPreview(option int) {
+	if option < 0 || option >= len(ps.removalOptions) {
+		return
+	}
+	flags, names, run := ps.removalOptions[option], ps.removalNames, ps.removalRun
+	ps.removalChosen = flags
+	ps.previewRun = func() {
+		ps.removalNames, ps.removalOptions, ps.removalRun = nil, nil, nil
+		run(flags)
+	}
+	ps.textPreview.Clear().
+		SetTitle(" [::b]Computing removal... ")
+
+	go func() {
+		ps.locker.Lock()
+		ps.startSpinner()
+		defer func() {
+			ps.locker.Unlock()
+			ps.stopSpinner()
+		}()
+
+		var preview removalPreview
+		out, err := printRemovalTargets(ps.conf, flags, names)
+		if err == nil {
+			preview, err = buildRemovalPreview(ps.handle(), flags, parseRemovalTargets(out), pacmanHoldPackages(ps.conf.PacmanConfigPath))
+		}
+		ps.app.QueueUpdateDraw(func() {
+			// the preview is gone or another strategy has been chosen in the meantime
+			if ps.flexRight.GetItem(0) != ps.textPreview || ps.removalRun == nil || ps.removalChosen != flags {
+				return
+			}
+			ps.drawRemovalPreview(preview, flags, err)
+		})
+	}()
+}
+
+// draws a removal preview, our removal options are listed in the title
+func (ps *UI) drawRemovalPreview(preview removalPreview, flags string, err error) {
+	keys := []string{}
+	for i, option := range ps.removalOptions {
+		if option == flags {
+			option = "[::r]" + option + "[::-]"
+		}
+		keys = append(keys, fmt.Sprintf("%d: %s", i+1, option))
+	}
+	ps.textPreview.SetTitle(" [::b]Removal preview [::-](" + strings.Join(keys, ", ") + ", ENTER: proceed, ESC: cancel) ")
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("[::b]%s:[::-] %s\n\n", flags, removalDescription(flags)))
+	if err != nil {
+		sb.WriteString(colorTag(ps.conf.Colors().Error) + tview.Escape(err.Error()) + "[-]\n\nPress ENTER to run the uninstall command anyway")
+		ps.textPreview.SetText(sb.String())
+		return
+	}
+	if len(preview.Essential) > 0 {
+		sb.WriteString(fmt.Sprintf("%s[::b]WARNING: essential packages would be removed: %s[-::-]\n", colorTag(ps.conf.Colors().Error), tview.Escape(strings.Join(preview.Essential, ", "))))
+		sb.WriteString(colorTag(ps.conf.Colors().Error) + "[::b]This is likely to break your system![-::-]\n\n")
+	}
+	sb.WriteString(fmt.Sprintf("%s[::b]Remove (%d)[-::-]\n", colorTag(ps.conf.Colors().Accent), len(preview.Entries)))
+	for _, e := range preview.Entries {
+		line := fmt.Sprintf("  %-40s %s", tview.Escape(e.Name), e.LocalVersion)
+		if util.SliceContains(preview.Essential, e.Name) {
+			line = colorTag(ps.conf.Colors().Error) + line + " (essential)[-]"
+		}
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString(fmt.Sprintf("\n[::b]Net size:[::-] %s", formatSizeDelta(preview.InstalledSizeDelta)))
+	ps.textPreview.SetText(sb.String())
+	ps.textPreview.ScrollToBeginning()
+}
@@ -0,0 +1,95 @@
+package pacseek
+
+import (
+	"sort"
+
+	"github.com/Jguer/go-alpm/v2"
+)
+
+// repoMerger merges packages that exist in multiple repositories (e.g. extra-testing and extra)
+// into a single entry of the repository with the highest priority, the others are kept in OtherRepos
+// repositories of our priority list come first, all others follow in the order of pacman.conf
+// a nil merger doesn't merge anything
+type repoMerger struct {
+	rank  map[string]int
+	index map[string]int // package name -> index in our results
+}
+
+// creates a merger for our sync db's and a priority list (repository names)
+func newRepoMerger(dbs []alpm.IDB, priority []string) *repoMerger {
+	m := &repoMerger{
+		rank:  map[string]int{},
+		index: map[string]int{},
+	}
+	for i, repo := range priority {
+		if _, found := m.rank[repo]; !found {
+			m.rank[repo] = i
+		}
+	}
+	for i, db := range dbs {
+		if _, found := m.rank[db.Name()]; !found {
+			m.rank[db.Name()] = len(priority) + i
+		}
+	}
+	return m
+}
+
+// returns the rank of a repository (lower is a higher priority), unknown ones come last
+func (m *repoMerger) rankOf(repo string) int {
+	if r, found := m.rank[repo]; found {
+		return r
+	}
+	return len(m.rank)
+}
+
+// adds a package to our results, returns false if it has been merged into an existing entry
+func (m *repoMerger) add(packages []Package, pkg Package) ([]Package, bool) {
+	if m == nil {
+		return append(packages, pkg), true
+	}
+	i, found := m.index[pkg.Name]
+	if !found {
+		m.index[pkg.Name] = len(packages)
+		return append(packages, pkg), true
+	}
+	existing := packages[i]
+	if m.rankOf(pkg.Source) < m.rankOf(existing.Source) {
+		pkg.OtherRepos = append(existing.OtherRepos, existing.Source)
+		existing = pkg
+	} else {
+		existing.OtherRepos = append(existing.OtherRepos, pkg.Source)
+	}
+	sort.SliceStable(existing.OtherRepos, func(a, b int) bool {
+		return m.rankOf(existing.OtherRepos[a]) < m.rankOf(existing.OtherRepos[b])
+	})
+	packages[i] = existing
+	return packages, false
+}
+
+// repoVersion is the version of a package in one of our repositories
+type repoVersion struct {
+	Repo    string
+	Version string
+}
+
+// returns the versions of a package in all of our sync db's, ordered by priority (see repoMerger)
+func repoVersions(h dbHandle, name string, priority []string) []repoVersion {
+	versions := []repoVersion{}
+	if h == nil {
+		return versions
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return versions
+	}
+	m := newRepoMerger(dbs.Slice(), priority)
+	for _, db := range dbs.Slice() {
+		if pkg := db.Pkg(name); pkg != nil {
+			versions = append(versions, repoVersion{Repo: db.Name(), Version: pkg.Version()})
+		}
+	}
+	sort.SliceStable(versions, func(a, b int) bool {
+		return m.rankOf(versions[a].Repo) < m.rankOf(versions[b].Repo)
+	})
+	return versions
+}
@@ -0,0 +1,161 @@
+package pacseek
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// resultFilter restricts our search results by license and architecture, e.g. "license:GPL,MIT arch:any"
+// licenses are matched by prefix (case insensitive), "GPL" matches "GPL2" and "GPL-3.0-or-later" but not "LGPL"
+// architectures have to match exactly, AUR packages are not filtered by them (the AUR doesn't provide them)
+type resultFilter struct {
+	Licenses []string
+	Arches   []string
+}
+
+// parses the text of our filter bar, an empty text removes all filters
+func parseResultFilter(text string) (resultFilter, error) {
+	f := resultFilter{}
+	for _, field := range strings.Fields(text) {
+		key, value, found := strings.Cut(field, ":")
+		if !found || value == "" {
+			return f, fmt.Errorf("invalid filter '%s', use license:<licenses> or arch:<architectures>", field)
+		}
+		values := []string{}
+		for _, v := range strings.Split(value, ",") {
+			if v != "" {
+				values = append(values, v)
+			}
+		}
+		switch strings.ToLower(key) {
+		case "license":
+			f.Licenses = append(f.Licenses, values...)
+		case "arch":
+			f.Arches = append(f.Arches, values...)
+		default:
+			return f, fmt.Errorf("unknown filter '%s', use license:<licenses> or arch:<architectures>", key)
+		}
+	}
+	return f, nil
+}
+
+// checks if we have any filters
+func (f resultFilter) active() bool {
+	return len(f.Licenses) > 0 || len(f.Arches) > 0
+}
+
+// returns the architectures we search for: the ones of our filter or the target architecture (and "any")
+func (f resultFilter) searchArches(target string) []string {
+	if len(f.Arches) > 0 {
+		return f.Arches
+	}
+	return []string{target, "any"}
+}
+
+// returns our filter in the format of our filter bar
+func (f resultFilter) String() string {
+	parts := []string{}
+	if len(f.Licenses) > 0 {
+		parts = append(parts, "license:"+strings.Join(f.Licenses, ","))
+	}
+	if len(f.Arches) > 0 {
+		parts = append(parts, "arch:"+strings.Join(f.Arches, ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+// checks if one of the licenses of a package matches one of our license filters
+// SPDX expressions like "GPL-2.0-only OR MIT" are split into their identifiers, "custom:" prefixes are ignored
+func licenseMatches(licenses []string, filters []string) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, license := range licenses {
+		ids := strings.FieldsFunc(license, func(r rune) bool {
+			return r == ' ' || r == '(' || r == ')'
+		})
+		for _, id := range ids {
+			id = strings.TrimPrefix(strings.ToLower(id), "custom:")
+			if id == "or" || id == "and" || id == "with" {
+				continue
+			}
+			for _, f := range filters {
+				if strings.HasPrefix(id, strings.ToLower(f)) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// removes the AUR packages with a license that doesn't match our filter (license information is part of the info records)
+func filterAurLicenses(pkgs []Package, infos []InfoRecord, filters []string) []Package {
+	if len(filters) == 0 {
+		return pkgs
+	}
+	licenses := map[string][]string{}
+	for _, info := range infos {
+		licenses[info.Name] = info.License
+	}
+	filtered := []Package{}
+	for _, pkg := range pkgs {
+		if licenseMatches(licenses[pkg.Name], filters) {
+			filtered = append(filtered, pkg)
+		}
+	}
+	return filtered
+}
+
+// shows / hides our filter bar
+func (ps *UI) toggleFilterBar() {
+	if ps.filterBarVisible {
+		ps.hideFilterBar()
+		return
+	}
+	ps.filterBarVisible = true
+	ps.inputFilter.SetText(ps.resultFilter.String())
+	ps.flexLeft.ResizeItem(ps.inputFilter, 3, 0)
+	ps.app.SetFocus(ps.inputFilter)
+}
+
+// hides our filter bar, the filter stays active
+func (ps *UI) hideFilterBar() {
+	ps.filterBarVisible = false
+	ps.flexLeft.ResizeItem(ps.inputFilter, 0, 0)
+	ps.app.SetFocus(ps.tablePackages)
+}
+
+// applies the filter of our filter bar and repeats our last search
+func (ps *UI) applyResultFilter(text string) {
+	f, err := parseResultFilter(text)
+	if err != nil {
+		ps.displayMessage(err.Error(), true)
+		return
+	}
+	ps.resultFilter = f
+	ps.cacheSearch.Flush()
+	ps.hideFilterBar()
+	if len(ps.lastSearchTerm) >= 2 {
+		ps.displayPackages(ps.lastSearchTerm)
+	}
+}
+
+// sets up our filter bar
+func (ps *UI) setupFilterBar() {
+	ps.inputFilter.SetLabel("Filter: ").
+		SetLabelStyle(tcell.StyleDefault.Bold(true)).
+		SetPlaceholder("license:GPL,MIT arch:any,x86_64").
+		SetBorder(true).
+		SetTitle(" Filter results (ENTER: apply, ESC: close) ").
+		SetTitleAlign(tview.AlignLeft)
+	// ESC is handled by our global key handler
+	ps.inputFilter.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEnter {
+			ps.applyResultFilter(ps.inputFilter.GetText())
+		}
+	})
+}
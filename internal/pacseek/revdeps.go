@@ -0,0 +1,91 @@
+package pacseek
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/Jguer/go-alpm/v2"
+	"github.com/moson-mo/pacseek/internal/util"
+)
+
+// reverse dependency kinds of a node (its relation to the parent node)
+const (
+	revRequired = "required"
+	revOptional = "optional"
+)
+
+// revResolver looks up the packages requiring (or optionally using) a package
+// installed packages are resolved with the local db (what would break on removal), all others with the sync db's
+type revResolver struct {
+	lookup func(name string) alpm.IPackage
+}
+
+// creates a resolver for the reverse dependencies of a package
+func newRevResolver(h dbHandle, name string) (*revResolver, error) {
+	if h == nil {
+		return nil, errors.New("alpm handle is nil")
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return nil, err
+	}
+	if local.Pkg(name) != nil {
+		return &revResolver{lookup: local.Pkg}, nil
+	}
+
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return nil, err
+	}
+	if findSyncPackage(dbs, name) == nil {
+		return nil, fmt.Errorf("package '%s' not found", name)
+	}
+	return &revResolver{lookup: func(name string) alpm.IPackage {
+		return findSyncPackage(dbs, name)
+	}}, nil
+}
+
+// returns the direct reverse dependencies of a package (required ones first)
+// "path" are the packages from the root to the package, reverse dependencies on that path are marked as cycle
+func (r *revResolver) children(name string, path []string) []*depNode {
+	children := []*depNode{}
+	pkg := r.lookup(name)
+	if pkg == nil {
+		return children
+	}
+
+	add := func(names []string, kind string) {
+		sort.Strings(names)
+		for _, n := range names {
+			children = append(children, &depNode{name: n, kind: kind, cycle: util.SliceContains(path, n)})
+		}
+	}
+	add(pkg.ComputeRequiredBy(), revRequired)
+	add(pkg.ComputeOptionalFor(), revOptional)
+
+	return children
+}
+
+// returns all packages (sorted) that directly or transitively require a package, hence break when it is removed
+func (r *revResolver) breaking(name string) []string {
+	seen := map[string]bool{name: true}
+	queue := []string{name}
+	broken := []string{}
+	for len(queue) > 0 {
+		pkg := r.lookup(queue[0])
+		queue = queue[1:]
+		if pkg == nil {
+			continue
+		}
+		for _, req := range pkg.ComputeRequiredBy() {
+			if !seen[req] {
+				seen[req] = true
+				broken = append(broken, req)
+				queue = append(queue, req)
+			}
+		}
+	}
+	sort.Strings(broken)
+	return broken
+}
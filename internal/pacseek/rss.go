@@ -1,12 +1,22 @@
 package pacseek
 
 import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
+	pconf "github.com/Morganamilo/go-pacmanconf"
 	"github.com/mmcdole/gofeed"
+	"github.com/moson-mo/pacseek/internal/config"
 )
 
+// pacman's log entry for "pacman -Syu"
+const sysUpgradeLogEntry = "[PACMAN] starting full system upgrade"
+
 // get news from rss feed(s)
 func getNews(urls string, limit int) ([]*gofeed.Item, error) {
 	p := gofeed.NewParser()
@@ -32,3 +42,130 @@ func getNews(urls string, limit int) ([]*gofeed.Item, error) {
 
 	return items[:limit], retErr
 }
+
+// returns the log file configured in pacman.conf (or pacman's default one)
+func pacmanLogFile(confPath string) string {
+	conf, _, err := pconf.ParseFile(confPath)
+	if err != nil || conf.LogFile == "" {
+		return "/var/log/pacman.log"
+	}
+	return conf.LogFile
+}
+
+// returns the time of the last full system upgrade from pacman's log file (zero if there is none)
+func lastSysUpgrade(logFile string) (time.Time, error) {
+	f, err := os.Open(logFile)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+
+	last := time.Time{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.Contains(line, sysUpgradeLogEntry) {
+			continue
+		}
+		if t, _, ok := parseLogTime(line); ok {
+			last = t
+		}
+	}
+	return last, scanner.Err()
+}
+
+// returns the identifier of a news item that we keep track of (GUID or link)
+func newsId(item *gofeed.Item) string {
+	if item.GUID != "" {
+		return item.GUID
+	}
+	return item.Link
+}
+
+// returns the news items published after "since" (e.g. our last system upgrade) that haven't been marked as read
+func unreadNews(items []*gofeed.Item, since time.Time, read map[string]bool) []*gofeed.Item {
+	unread := []*gofeed.Item{}
+	for _, item := range items {
+		if item.PublishedParsed != nil && item.PublishedParsed.After(since) && !read[newsId(item)] {
+			unread = append(unread, item)
+		}
+	}
+	return unread
+}
+
+// returns the path of the file that holds the news items we've marked as read (~/.config/pacseek/news.json)
+func newsStateFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "pacseek", "news.json"), nil
+}
+
+// reads the ids of the news items that have been marked as read, a missing file means none are
+func loadReadNews(file string) map[string]bool {
+	read := map[string]bool{}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return read
+	}
+	ids := []string{}
+	if json.Unmarshal(b, &ids) != nil {
+		return read
+	}
+	for _, id := range ids {
+		read[id] = true
+	}
+	return read
+}
+
+// stores the ids of the news items that have been marked as read
+func saveReadNews(file string, read map[string]bool) error {
+	ids := []string{}
+	for id := range read {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	b, err := json.MarshalIndent(ids, "", "	")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, b, 0644)
+}
+
+// returns the time of our last system upgrade and the news items that have been marked as read
+// if pacman's log can't be read, all news items are considered to be newer
+func newsReadState(conf *config.Settings) (time.Time, map[string]bool) {
+	since, _ := lastSysUpgrade(pacmanLogFile(conf.PacmanConfigPath))
+	read := map[string]bool{}
+	if file, err := newsStateFile(); err == nil {
+		read = loadReadNews(file)
+	}
+	return since, read
+}
+
+// returns the unread news items that have been published since our last system upgrade
+func pendingNews(conf *config.Settings) ([]*gofeed.Item, error) {
+	items, err := getNews(conf.FeedURLs, conf.FeedMaxItems)
+	if err != nil {
+		return nil, err
+	}
+	since, read := newsReadState(conf)
+	return unreadNews(items, since, read), nil
+}
+
+// marks news items as read
+func markNewsRead(items []*gofeed.Item) error {
+	file, err := newsStateFile()
+	if err != nil {
+		return err
+	}
+	read := loadReadNews(file)
+	for _, item := range items {
+		read[newsId(item)] = true
+	}
+	return saveReadNews(file, read)
+}
@@ -0,0 +1,299 @@
+package pacseek
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// searchSource is a source of packages (e.g. repositories or AUR) that can be searched concurrently
+// with a (soft) "deadline", we stop waiting for the source once it expired. results that arrive afterwards are passed to "late"
+type searchSource struct {
+	name     string
+	search   func(ctx context.Context) ([]Package, error)
+	deadline time.Duration
+	late     func(pkgs []Package, err error)
+}
+
+// debouncer runs a function once there were no further calls for a certain delay (e.g. while typing a search term)
+// the zero value is ready to use
+type debouncer struct {
+	mu    sync.Mutex
+	timer *time.Timer
+}
+
+// runs "f" after "delay", a pending call is replaced
+func (d *debouncer) call(delay time.Duration, f func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.timer = time.AfterFunc(delay, f)
+}
+
+// cancels a pending call
+func (d *debouncer) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+}
+
+// searchResult holds the packages (or error) of a single search source
+type searchResult struct {
+	index    int
+	packages []Package
+	err      error
+}
+
+// searches all sources concurrently and calls "emit" with the results of each source as soon as they are available
+// (e.g. repo results are emitted before AUR results). "emit" is called from the calling goroutine only.
+// once all sources are done (or their deadline expired), the merged results are returned, sorted by name,
+// together with a warning for each source that timed out.
+// when our context is cancelled, sources that didn't finish yet are cancelled as well. we return once they stopped,
+// so that they don't outlive the lock of our caller (e.g. for our alpm handle). sources delivering late results are not waited for
+func streamSearch(ctx context.Context, sources []searchSource, emit func(source string, pkgs []Package, err error)) ([]Package, []string, error) {
+	cctx, cancel := context.WithCancel(ctx)
+	var workers sync.WaitGroup
+	defer func() {
+		cancel()
+		workers.Wait()
+	}()
+
+	results := make(chan searchResult, len(sources))
+	expired := make(chan int, len(sources))
+	for i, s := range sources {
+		// sources delivering late results must not be cancelled when we return
+		sctx := cctx
+		if s.late != nil {
+			sctx = ctx
+		} else {
+			workers.Add(1)
+		}
+		go func(i int, s searchSource, ctx context.Context) {
+			if s.late == nil {
+				defer workers.Done()
+			}
+			pkgs, err := s.search(ctx)
+			results <- searchResult{index: i, packages: pkgs, err: err}
+		}(i, s, sctx)
+
+		if s.deadline > 0 {
+			timer := time.AfterFunc(s.deadline, func(i int) func() {
+				return func() { expired <- i }
+			}(i))
+			defer timer.Stop()
+		}
+	}
+
+	merged := []Package{}
+	warnings := []string{}
+	timedOut := map[int]bool{}
+	done := map[int]bool{}
+	received := 0
+	var err error
+	for pending := len(sources); pending > 0 && err == nil; {
+		select {
+		case r := <-results:
+			received++
+			if timedOut[r.index] {
+				if late := sources[r.index].late; late != nil {
+					late(r.packages, r.err)
+				}
+				continue
+			}
+			done[r.index] = true
+			if emit != nil {
+				emit(sources[r.index].name, r.packages, r.err)
+			}
+			merged = append(merged, r.packages...)
+			pending--
+		case i := <-expired:
+			// the source might have finished just before its deadline
+			if done[i] {
+				continue
+			}
+			timedOut[i] = true
+			warnings = append(warnings, fmt.Sprintf("%s timed out, results incomplete", sources[i].name))
+			pending--
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+
+	// deliver results of sources that exceeded their deadline
+	if len(timedOut) > 0 && received < len(sources) {
+		go func(remaining int) {
+			for ; remaining > 0; remaining-- {
+				r := <-results
+				if late := sources[r.index].late; timedOut[r.index] && late != nil {
+					late(r.packages, r.err)
+				}
+			}
+		}(len(sources) - received)
+	}
+
+	sortPackages(merged, SortSpec{Keys: []SortKey{SortByName}})
+	return merged, warnings, err
+}
+
+// returns the byte ranges (start, end) of "s" that match our search term (each of its words) according to the search mode
+// matching ignores case, the offsets are valid for the original string (lowercasing can change the size of a character)
+// fuzzy matches that are not a subsequence of "s" (typos) don't have any ranges
+// for "Regex", the ranges are the (non-empty) matches of the regular expression
+func matchRanges(s, term, mode string, segments bool) [][2]int {
+	if mode == "Regex" {
+		return regexRanges(s, term)
+	}
+	lower, offsets := lowerWithOffsets(s)
+	ranges := [][2]int{}
+	for _, token := range strings.Fields(strings.ToLower(term)) {
+		for _, r := range tokenRanges(lower, token, mode, segments) {
+			ranges = append(ranges, [2]int{offsets[r[0]], offsets[r[1]]})
+		}
+	}
+	return mergeRanges(ranges)
+}
+
+// returns the ranges of all non-empty matches of a regular expression (ignoring case)
+func regexRanges(s, term string) [][2]int {
+	ranges := [][2]int{}
+	re, err := compileSearchRegex(term, true)
+	if err != nil {
+		return ranges
+	}
+	for _, m := range re.FindAllStringIndex(s, -1) {
+		if m[1] > m[0] {
+			ranges = append(ranges, [2]int{m[0], m[1]})
+		}
+	}
+	return ranges
+}
+
+// compiles the regular expression of a "Regex" search
+func compileSearchRegex(term string, caseInsensitive bool) (*regexp.Regexp, error) {
+	if caseInsensitive {
+		term = "(?i)" + term
+	}
+	re, err := regexp.Compile(term)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %w", err)
+	}
+	return re, nil
+}
+
+// returns the longest (lowercase) literal that any match of a regular expression contains
+// the AUR can't search with regular expressions, so we search for the literal and filter the results
+func requiredLiteral(term string) string {
+	re, err := syntax.Parse(term, syntax.Perl)
+	if err != nil {
+		return ""
+	}
+	return longestLiteral(re.Simplify())
+}
+
+// returns the longest literal of a (parsed) regular expression that can't be skipped
+func longestLiteral(re *syntax.Regexp) string {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return strings.ToLower(string(re.Rune))
+	case syntax.OpCapture, syntax.OpPlus:
+		return longestLiteral(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min > 0 {
+			return longestLiteral(re.Sub[0])
+		}
+	case syntax.OpConcat:
+		longest := ""
+		for _, sub := range re.Sub {
+			if l := longestLiteral(sub); len(l) > len(longest) {
+				longest = l
+			}
+		}
+		return longest
+	}
+	return ""
+}
+
+// returns the ranges of a single (lowercase) word within a lowercase string
+func tokenRanges(s, token, mode string, segments bool) [][2]int {
+	switch mode {
+	case "Contains", "Fuzzy":
+		if i := strings.Index(s, token); i != -1 {
+			return [][2]int{{i, i + len(token)}}
+		}
+		if mode == "Fuzzy" {
+			return subsequenceRanges(s, token)
+		}
+	default:
+		if strings.HasPrefix(s, token) {
+			return [][2]int{{0, len(token)}}
+		}
+		if segments {
+			for i, r := range s {
+				if (r == '-' || r == '.') && strings.HasPrefix(s[i+1:], token) {
+					return [][2]int{{i + 1, i + 1 + len(token)}}
+				}
+			}
+		}
+	}
+	return [][2]int{}
+}
+
+// returns the ranges of the characters of "token" in "s" (in order), e.g. "ffx" in "firefox"
+func subsequenceRanges(s, token string) [][2]int {
+	ranges := [][2]int{}
+	pos := 0
+	for _, r := range token {
+		i := strings.IndexRune(s[pos:], r)
+		if i == -1 {
+			return [][2]int{}
+		}
+		start := pos + i
+		pos = start + utf8.RuneLen(r)
+		ranges = append(ranges, [2]int{start, pos})
+	}
+	return mergeRanges(ranges)
+}
+
+// lowercases a string and returns a mapping of byte offsets in the lowercase string to the ones in the original string
+func lowerWithOffsets(s string) (string, []int) {
+	var b strings.Builder
+	offsets := make([]int, 0, len(s)+1)
+	for i, r := range s {
+		n, _ := b.WriteRune(unicode.ToLower(r))
+		for j := 0; j < n; j++ {
+			offsets = append(offsets, i)
+		}
+	}
+	offsets = append(offsets, len(s))
+	return b.String(), offsets
+}
+
+// sorts ranges and merges the ones that overlap or are adjacent
+func mergeRanges(ranges [][2]int) [][2]int {
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i][0] < ranges[j][0]
+	})
+	merged := [][2]int{}
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && r[0] <= merged[n-1][1] {
+			if r[1] > merged[n-1][1] {
+				merged[n-1][1] = r[1]
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
@@ -0,0 +1,233 @@
+package pacseek
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/moson-mo/pacseek/internal/util"
+	"github.com/rivo/tview"
+)
+
+// validators for the input fields of our settings form (by label)
+var settingValidators = map[string]func(string) error{
+	"AUR RPC URL: ":               validateURL,
+	"Security tracker URL: ":      validateURL,
+	"Pacman root path: ":          validateDir,
+	"Pacman DB path: ":            validateDir,
+	"Pacman config path: ":        validateFile,
+	"Install command: ":           validateCommandLine,
+	"Uninstall command: ":         validateCommandLine,
+	"Upgrade command: ":           validateCommandLine,
+	"Downgrade command: ":         validateCommandLine,
+	"Download command: ":          validateCommandLine,
+	"AUR download command: ":      validateCommandLine,
+	"Merge command: ":             validateMergeCommand,
+	"AUR Install command: ":       validateOptionalCommandLine,
+	"AUR Upgrade command: ":       validateOptionalCommandLine,
+	"Chroot build command: ":      validateOptionalCommandLine,
+	"Flatpak install command: ":   validateCommandLine,
+	"Flatpak uninstall command: ": validateCommandLine,
+	"Flatpak upgrade command: ":   validateCommandLine,
+	"Plugins: ":                   validatePlugins,
+}
+
+// settings that are only validated when their feature is enabled (label of the checkbox)
+var settingFeatures = map[string]string{
+	"Flatpak install command: ":   "Enable Flatpak: ",
+	"Flatpak uninstall command: ": "Enable Flatpak: ",
+	"Flatpak upgrade command: ":   "Enable Flatpak: ",
+}
+
+// a program of a command that is not installed (yet), it is a warning only and doesn't prevent saving our settings
+var errNotInPath = errors.New("not found in PATH")
+
+// programs that run the command given as their argument (we check the availability of the program they run as well)
+var commandWrappers = []string{"sudo", "doas", "pkexec", "run0"}
+
+// checks if a URL is an absolute http(s) URL
+func validateURL(s string) error {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("not a valid http(s) URL")
+	}
+	return nil
+}
+
+// checks if a directory exists
+func validateDir(s string) error {
+	fi, err := os.Stat(s)
+	if err != nil {
+		return errors.New("directory does not exist")
+	}
+	if !fi.IsDir() {
+		return errors.New("not a directory")
+	}
+	return nil
+}
+
+// checks if a (regular) file exists
+func validateFile(s string) error {
+	fi, err := os.Stat(s)
+	if err != nil {
+		return errors.New("file does not exist")
+	}
+	if fi.IsDir() {
+		return errors.New("is a directory")
+	}
+	return nil
+}
+
+// checks if our plugins (separated by semicolons) are executable files
+func validatePlugins(s string) error {
+	for _, path := range pluginPaths(s) {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("plugin '%s' does not exist", path)
+		}
+		if fi.IsDir() || fi.Mode().Perm()&0111 == 0 {
+			return fmt.Errorf("plugin '%s' is not executable", path)
+		}
+	}
+	return nil
+}
+
+// checks the placeholders of a command template and if its program can be found in our PATH
+func validateCommandLine(command string) error {
+	if err := validateCommand("command", command); err != nil {
+		return err
+	}
+	return validatePrograms(command)
+}
+
+// checks our merge command, it has placeholders of its own ({original} / {file})
+func validateMergeCommand(command string) error {
+	if err := validatePlaceholders("command", command, mergePlaceholders); err != nil {
+		return err
+	}
+	return validatePrograms(command)
+}
+
+// checks if the program of a command (and the one run by sudo & co.) can be found in our PATH
+// commands using shell syntax (e.g. "cd /tmp && makepkg -si") are not checked
+func validatePrograms(command string) error {
+	if strings.ContainsAny(command, ";|&$`()<>") {
+		return nil
+	}
+	fields := strings.Fields(command)
+	for i, field := range fields {
+		if strings.HasPrefix(field, "{") || (i > 0 && strings.HasPrefix(field, "-")) {
+			break
+		}
+		if _, err := exec.LookPath(field); err != nil {
+			return fmt.Errorf("%s %w", field, errNotInPath)
+		}
+		if !util.SliceContains(commandWrappers, field) {
+			break
+		}
+	}
+	return nil
+}
+
+// same as validateCommandLine, but an empty command is valid (e.g. AUR commands fall back to the regular ones)
+func validateOptionalCommandLine(command string) error {
+	if strings.TrimSpace(command) == "" {
+		return nil
+	}
+	return validateCommandLine(command)
+}
+
+// validates an input field of our settings form, returns nil for fields without a validator
+func validateSetting(label, value string) error {
+	validate, ok := settingValidators[label]
+	if !ok {
+		return nil
+	}
+	if err := validate(value); err != nil {
+		return fmt.Errorf("%s%w", label, err)
+	}
+	return nil
+}
+
+// validates our settings while they are edited
+func (ps *UI) addSettingsValidation() {
+	for i := 0; i < ps.formSettings.GetFormItemCount(); i++ {
+		input, ok := ps.formSettings.GetFormItem(i).(*tview.InputField)
+		if !ok {
+			continue
+		}
+		if _, found := settingValidators[input.GetLabel()]; !found {
+			continue
+		}
+		input.SetChangedFunc(func(text string) {
+			ps.settingsChanged = true
+			ps.drawSettingsValidation()
+		})
+	}
+	ps.drawSettingsValidation()
+}
+
+// shows invalid values of our settings form in red, the first error is shown in the title of our form
+func (ps *UI) drawSettingsValidation() {
+	title := " [::b]" + ps.conf.Glyphs().Settings + "Settings "
+	var first, warning error
+	for i := 0; i < ps.formSettings.GetFormItemCount(); i++ {
+		input, ok := ps.formSettings.GetFormItem(i).(*tview.InputField)
+		if !ok {
+			continue
+		}
+		if _, found := settingValidators[input.GetLabel()]; !found {
+			continue
+		}
+		err := ps.validateSettingsInput(input)
+		switch {
+		case err == nil:
+			input.SetFieldTextColor(ps.conf.Colors().SettingsFieldText)
+		case errors.Is(err, errNotInPath):
+			input.SetFieldTextColor(ps.conf.Colors().Warning)
+			if warning == nil {
+				warning = err
+			}
+		default:
+			input.SetFieldTextColor(ps.conf.Colors().Error)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	if first != nil {
+		title += colorTag(ps.conf.Colors().Error) + "- " + tview.Escape(first.Error()) + " "
+	} else if warning != nil {
+		title += colorTag(ps.conf.Colors().Warning) + "- " + tview.Escape(warning.Error()) + " "
+	}
+	ps.formSettings.SetTitle(title)
+}
+
+// validates an input field of our settings form, fields of disabled features are not validated
+func (ps *UI) validateSettingsInput(input *tview.InputField) error {
+	if feature, ok := settingFeatures[input.GetLabel()]; ok {
+		if cb, ok := ps.formSettings.GetFormItemByLabel(feature).(*tview.Checkbox); ok && !cb.IsChecked() {
+			return nil
+		}
+	}
+	return validateSetting(input.GetLabel(), input.GetText())
+}
+
+// validates all input fields of our settings form, the first error is returned
+// programs that are not found in our PATH are shown as warnings only
+func (ps *UI) validateSettingsForm() error {
+	for i := 0; i < ps.formSettings.GetFormItemCount(); i++ {
+		if input, ok := ps.formSettings.GetFormItem(i).(*tview.InputField); ok {
+			if err := ps.validateSettingsInput(input); err != nil && !errors.Is(err, errNotInPath) {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -1,7 +1,6 @@
 package pacseek
 
 import (
-	"fmt"
 	"os/exec"
 	"strconv"
 	"strings"
@@ -23,12 +22,27 @@ func (ps *UI) createComponents() {
 
 	// components
 	ps.inputSearch = tview.NewInputField()
+	ps.inputFilter = tview.NewInputField()
 	ps.tablePackages = tview.NewTable()
 	ps.tableDetails = tview.NewTable()
 	ps.spinner = tview.NewTextView()
 	ps.formSettings = tview.NewForm()
 	ps.textMessage = tview.NewTextView()
 	ps.textPkgbuild = tview.NewTextView()
+	ps.treeRevDeps = tview.NewTreeView()
+	ps.textComments = tview.NewTextView()
+	ps.textChangelog = tview.NewTextView()
+	ps.textPreview = tview.NewTextView()
+	ps.textDebug = tview.NewTextView()
+	ps.flexFiles = tview.NewFlex().SetDirection(tview.FlexRow)
+	ps.inputFiles = tview.NewInputField()
+	ps.tableFiles = tview.NewTable()
+	ps.tableCache = tview.NewTable()
+	ps.tableGroups = tview.NewTable()
+	ps.tablePacnew = tview.NewTable()
+	ps.flexHistory = tview.NewFlex().SetDirection(tview.FlexRow)
+	ps.inputHistory = tview.NewInputField()
+	ps.tableHistory = tview.NewTable()
 	ps.tableNews = tview.NewTable()
 
 	// component config
@@ -39,6 +53,7 @@ func (ps *UI) createComponents() {
 	if ps.conf.EnableAutoSuggest {
 		ps.inputSearch.SetAutocompleteFunc(ps.autoComplete)
 	}
+	ps.setupFilterBar()
 	ps.tableDetails.SetEvaluateAllRows(true).
 		SetFocusFunc(func() {
 			if ps.flexRight.GetItem(0) == ps.textPkgbuild {
@@ -69,6 +84,56 @@ func (ps *UI) createComponents() {
 		SetTitleAlign(tview.AlignLeft).
 		SetBorderPadding(1, 1, 1, 1)
 	ps.pkgbuildWriter = tview.ANSIWriter(ps.textPkgbuild)
+	ps.treeRevDeps.SetBorder(true).
+		SetTitleAlign(tview.AlignLeft).
+		SetBorderPadding(1, 1, 1, 1)
+	ps.textComments.SetWordWrap(true).
+		SetDynamicColors(true).
+		SetBorder(true).
+		SetTitleAlign(tview.AlignLeft).
+		SetBorderPadding(1, 1, 1, 1)
+	ps.textChangelog.SetWordWrap(true).
+		SetDynamicColors(true).
+		SetBorder(true).
+		SetTitleAlign(tview.AlignLeft).
+		SetBorderPadding(1, 1, 1, 1)
+	ps.textDebug.SetWrap(false).
+		SetDynamicColors(true).
+		SetBorder(true).
+		SetTitleAlign(tview.AlignLeft).
+		SetBorderPadding(0, 0, 1, 1)
+	ps.textPreview.SetDynamicColors(true).
+		SetBorder(true).
+		SetTitleAlign(tview.AlignLeft).
+		SetBorderPadding(1, 1, 1, 1)
+	ps.flexFiles.SetBorder(true).
+		SetTitleAlign(tview.AlignLeft).
+		SetBorderPadding(1, 0, 1, 1)
+	ps.inputFiles.SetLabel("Filter: ").
+		SetLabelStyle(tcell.StyleDefault.Bold(true))
+	ps.tableFiles.SetSelectable(true, false)
+	ps.tableCache.SetSelectable(true, false).
+		SetFixed(1, 0).
+		SetBorder(true).
+		SetTitleAlign(tview.AlignLeft).
+		SetBorderPadding(1, 1, 1, 1)
+	ps.tablePacnew.SetSelectable(true, false).
+		SetFixed(1, 0).
+		SetBorder(true).
+		SetTitleAlign(tview.AlignLeft).
+		SetBorderPadding(1, 1, 1, 1)
+	ps.tableGroups.SetSelectable(true, false).
+		SetFixed(1, 0).
+		SetBorder(true).
+		SetTitleAlign(tview.AlignLeft).
+		SetBorderPadding(1, 1, 1, 1)
+	ps.flexHistory.SetBorder(true).
+		SetTitleAlign(tview.AlignLeft).
+		SetBorderPadding(1, 0, 1, 1)
+	ps.inputHistory.SetLabel("Filter: ").
+		SetLabelStyle(tcell.StyleDefault.Bold(true))
+	ps.tableHistory.SetSelectable(true, false).
+		SetFixed(1, 0)
 	ps.tableNews.SetSelectable(false, false).
 		SetFocusFunc(func() {
 			ps.app.SetFocus(ps.inputSearch)
@@ -85,7 +150,7 @@ func (ps *UI) createComponents() {
 		if rowCount-offset > innerHeight {
 			for i, char := range "..." {
 				style := tcell.StyleDefault.Background(ps.conf.Colors().DefaultBackground).
-					Foreground(tcell.ColorWhite)
+					Foreground(ps.conf.Colors().Text)
 				screen.SetContent(x+2+i, y+height-2, char, nil, style)
 			}
 		}
@@ -98,10 +163,15 @@ func (ps *UI) createComponents() {
 	ps.flexContainer.AddItem(ps.flexLeft, 0, ps.leftProportion, true).
 		AddItem(ps.flexRight, 0, 10-ps.leftProportion, false)
 	ps.flexLeft.AddItem(ps.flexTopLeft, 3, 1, true).
+		AddItem(ps.inputFilter, 0, 0, false).
 		AddItem(ps.tablePackages, 0, 1, false)
 	ps.flexTopLeft.AddItem(ps.inputSearch, 0, 1, true).
 		AddItem(ps.spinner, 3, 1, false)
 	ps.flexRight.AddItem(ps.tableDetails, 0, 1, false)
+	ps.flexFiles.AddItem(ps.inputFiles, 2, 0, true).
+		AddItem(ps.tableFiles, 0, 1, false)
+	ps.flexHistory.AddItem(ps.inputHistory, 2, 0, true).
+		AddItem(ps.tableHistory, 0, 1, false)
 }
 
 // apply colors from color scheme
@@ -113,11 +183,46 @@ func (ps *UI) applyColors() {
 	ps.inputSearch.SetFieldBackgroundColor(ps.conf.Colors().SearchBar).SetBackgroundColor(ps.conf.Colors().DefaultBackground)
 	ps.inputSearch.SetAutocompleteStyles(ps.conf.Colors().SettingsDropdownNotSelected, tcell.StyleDefault, tcell.StyleDefault.Reverse(true))
 	ps.textPkgbuild.SetTitleColor(ps.conf.Colors().Title).SetBackgroundColor(ps.conf.Colors().DefaultBackground)
+	ps.treeRevDeps.SetTitleColor(ps.conf.Colors().Title).SetBackgroundColor(ps.conf.Colors().DefaultBackground)
+	ps.textComments.SetTitleColor(ps.conf.Colors().Title).SetBackgroundColor(ps.conf.Colors().DefaultBackground)
+	ps.textChangelog.SetTitleColor(ps.conf.Colors().Title).SetBackgroundColor(ps.conf.Colors().DefaultBackground)
+	ps.textPreview.SetTitleColor(ps.conf.Colors().Title).SetBackgroundColor(ps.conf.Colors().DefaultBackground)
+	ps.textDebug.SetTitleColor(ps.conf.Colors().Title).SetBackgroundColor(ps.conf.Colors().DefaultBackground)
+	ps.flexFiles.SetTitleColor(ps.conf.Colors().Title).SetBackgroundColor(ps.conf.Colors().DefaultBackground)
+	ps.inputFiles.SetFieldBackgroundColor(ps.conf.Colors().SearchBar).SetBackgroundColor(ps.conf.Colors().DefaultBackground)
+	ps.inputFilter.SetFieldBackgroundColor(ps.conf.Colors().SearchBar).SetBackgroundColor(ps.conf.Colors().DefaultBackground)
+	ps.inputFilter.SetTitleColor(ps.conf.Colors().Title)
+	ps.tableFiles.SetBackgroundColor(ps.conf.Colors().DefaultBackground)
+	ps.tableFiles.SetSelectedStyle(tcell.StyleDefault.Reverse(true))
+	ps.tableCache.SetTitleColor(ps.conf.Colors().Title).SetBackgroundColor(ps.conf.Colors().DefaultBackground)
+	ps.tableCache.SetSelectedStyle(tcell.StyleDefault.Reverse(true))
+	ps.tablePacnew.SetTitleColor(ps.conf.Colors().Title).SetBackgroundColor(ps.conf.Colors().DefaultBackground)
+	ps.tablePacnew.SetSelectedStyle(tcell.StyleDefault.Reverse(true))
+	ps.tableGroups.SetTitleColor(ps.conf.Colors().Title).SetBackgroundColor(ps.conf.Colors().DefaultBackground)
+	ps.tableGroups.SetSelectedStyle(tcell.StyleDefault.Reverse(true))
+	ps.flexHistory.SetTitleColor(ps.conf.Colors().Title).SetBackgroundColor(ps.conf.Colors().DefaultBackground)
+	ps.inputHistory.SetFieldBackgroundColor(ps.conf.Colors().SearchBar).SetBackgroundColor(ps.conf.Colors().DefaultBackground)
+	ps.tableHistory.SetBackgroundColor(ps.conf.Colors().DefaultBackground)
+	ps.tableHistory.SetSelectedStyle(tcell.StyleDefault.Reverse(true))
 	ps.tableNews.SetTitleColor(ps.conf.Colors().Title).SetBackgroundColor(ps.conf.Colors().DefaultBackground)
 	ps.tablePackages.SetBackgroundColor(ps.conf.Colors().DefaultBackground)
 	ps.tablePackages.SetSelectedStyle(tcell.StyleDefault.Reverse(true))
 	ps.spinner.SetBackgroundColor(ps.conf.Colors().DefaultBackground)
 
+	// borders and text
+	for _, box := range []interface {
+		SetBorderColor(tcell.Color) *tview.Box
+	}{ps.flexRoot, ps.inputSearch, ps.inputFilter, ps.tablePackages, ps.tableDetails, ps.spinner, ps.formSettings, ps.textMessage, ps.textPkgbuild,
+		ps.treeRevDeps, ps.textComments, ps.textChangelog, ps.textPreview, ps.textDebug, ps.flexFiles, ps.tableCache, ps.tablePacnew, ps.tableGroups, ps.flexHistory, ps.tableNews} {
+		box.SetBorderColor(ps.conf.Colors().Border)
+	}
+	for _, text := range []*tview.TextView{ps.spinner, ps.textMessage, ps.textComments, ps.textChangelog, ps.textPreview, ps.textDebug} {
+		text.SetTextColor(ps.conf.Colors().Text)
+	}
+	for _, input := range []*tview.InputField{ps.inputSearch, ps.inputFilter, ps.inputFiles, ps.inputHistory} {
+		input.SetFieldTextColor(ps.conf.Colors().SettingsFieldText)
+	}
+
 	// settings form
 	ps.formSettings.SetFieldBackgroundColor(ps.conf.Colors().SettingsFieldBackground).
 		SetFieldTextColor(ps.conf.Colors().SettingsFieldText).
@@ -156,7 +261,7 @@ func (ps *UI) applyColors() {
 
 // apply drop-down colors
 func (ps *UI) applyDropDownColors() {
-	for _, title := range []string{"Search mode: ", "Search by: ", "Color scheme: ", "Border style: ", "Glyph style: "} {
+	for _, title := range []string{"Search mode: ", "Search by: ", "Local filter: ", "Sort results by: ", "Screenshot graphics: ", "Color scheme: ", "Border style: ", "Glyph style: "} {
 		if dd, ok := ps.formSettings.GetFormItemByLabel(title).(*tview.DropDown); ok {
 			dd.SetListStyles(tcell.StyleDefault.Background(ps.conf.Colors().SettingsDropdownNotSelected).Foreground(ps.conf.Colors().SettingsFieldText),
 				tcell.StyleDefault.Background(ps.conf.Colors().SettingsFieldText).Foreground(ps.conf.Colors().SettingsDropdownNotSelected))
@@ -192,7 +297,7 @@ func (ps *UI) applyGlyphStyle() {
 
 	// titles
 	ps.formSettings.SetTitle(" [::b]" + ps.conf.Glyphs().Settings + "Settings ")
-	ps.flexRoot.SetTitle(" [::b]" + ps.conf.Glyphs().Package + "pacseek - v" + version + " ")
+	ps.drawTitle()
 	ps.tableNews.SetTitle(" [::b]" + ps.conf.Glyphs().Pkgbuild + "Latest news ")
 
 	// package list
@@ -217,12 +322,32 @@ func (ps *UI) setupKeyBindings() {
 
 	// app / global
 	ps.app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		// keys are handled by our prompt (e.g. source selection)
+		if ps.promptVisible {
+			return event
+		}
+		// ESC - close our filter bar
+		if event.Key() == tcell.KeyEscape && ps.app.GetFocus() == ps.inputFilter {
+			ps.hideFilterBar()
+			return nil
+		}
 		settingsVisible := ps.flexRight.GetItem(0) == ps.formSettings
 		pkgbuildVisible := ps.flexRight.GetItem(0) == ps.textPkgbuild
+		revDepsVisible := ps.flexRight.GetItem(0) == ps.treeRevDeps
+		commentsVisible := ps.flexRight.GetItem(0) == ps.textComments
+		changelogVisible := ps.flexRight.GetItem(0) == ps.textChangelog
+		previewVisible := ps.flexRight.GetItem(0) == ps.textPreview
+		filesVisible := ps.flexRight.GetItem(0) == ps.flexFiles
+		cacheVisible := ps.flexRight.GetItem(0) == ps.tableCache
+		pacnewVisible := ps.flexRight.GetItem(0) == ps.tablePacnew
+		groupsVisible := ps.flexRight.GetItem(0) == ps.tableGroups
+		historyVisible := ps.flexRight.GetItem(0) == ps.flexHistory
+		debugVisible := ps.flexRight.GetItem(0) == ps.textDebug
+		detailsHidden := ps.flexRight.GetItem(0) != ps.tableDetails
 
 		// CTRL+Q / ESC - Quit
-		if event.Key() == tcell.KeyCtrlQ ||
-			(event.Key() == tcell.KeyEscape && !settingsVisible && !pkgbuildVisible && !ps.conf.EnableAutoSuggest) {
+		if ps.keys.matches("Quit", event) ||
+			(event.Key() == tcell.KeyEscape && !detailsHidden && !ps.conf.EnableAutoSuggest) {
 			if !ps.settingsChanged {
 				if ps.conf.SaveWindowLayout {
 					ps.conf.LeftProportion = ps.leftProportion
@@ -243,7 +368,7 @@ func (ps *UI) setupKeyBindings() {
 			ps.app.SetRoot(ask, true)
 		}
 		// CTRL+S - Show settings
-		if event.Key() == tcell.KeyCtrlS ||
+		if ps.keys.matches("Settings", event) ||
 			(event.Key() == tcell.KeyEscape && settingsVisible) {
 			if !settingsVisible {
 				ps.flexRight.Clear()
@@ -261,7 +386,7 @@ func (ps *UI) setupKeyBindings() {
 			return nil
 		}
 		// CTRL+N - Show help/instructions
-		if event.Key() == tcell.KeyCtrlN {
+		if ps.keys.matches("Help", event) {
 			ps.displayHelp()
 			if settingsVisible {
 				ps.flexRight.Clear()
@@ -270,30 +395,39 @@ func (ps *UI) setupKeyBindings() {
 			return nil
 		}
 		// CTRL+U - Upgrade
-		if event.Key() == tcell.KeyCtrlU {
+		if ps.keys.matches("Upgrade", event) {
 			ps.performUpgrade(false)
 			return nil
 		}
 		// CTRL+A - AUR upgrade
-		if event.Key() == tcell.KeyCtrlA {
+		if ps.keys.matches("AurUpgrade", event) {
 			ps.performUpgrade(true)
 			return nil
 		}
 		// CTRL+B - Show about
-		if event.Key() == tcell.KeyCtrlB {
+		if ps.keys.matches("About", event) {
 			ps.displayAbout()
 			return nil
 		}
 
-		// CTRL+W - Wipe cache
-		if event.Key() == tcell.KeyCtrlW {
+		// CTRL+W - Wipe cache and refresh our results
+		if ps.keys.matches("WipeCache", event) {
 			ps.cacheSearch.Flush()
 			ps.cacheInfo.Flush()
+			ps.cacheDeps.Flush()
+			if err := ps.diskCache.clear(); err != nil {
+				ps.displayMessage(err.Error(), true)
+				return nil
+			}
+			ps.displayMessage("Cache has been wiped", false)
+			if len(ps.lastSearchTerm) >= 2 {
+				ps.displayPackages(ps.lastSearchTerm)
+			}
 			return nil
 		}
 
 		// CTRL+P
-		if event.Key() == tcell.KeyCtrlP ||
+		if ps.keys.matches("Pkgbuild", event) ||
 			event.Key() == tcell.KeyEscape && pkgbuildVisible {
 			if ps.selectedPackage != nil {
 				if pkgbuildVisible {
@@ -310,15 +444,172 @@ func (ps *UI) setupKeyBindings() {
 			}
 		}
 
+		// CTRL+D - Toggle dependency tree for selected package
+		// only handled in our package list / details, input fields use it for deleting characters
+		focus := ps.app.GetFocus()
+		if ps.keys.matches("Dependencies", event) && ps.selectedPackage != nil && (focus == ps.tablePackages || focus == ps.tableDetails) {
+			if detailsHidden {
+				ps.flexRight.Clear()
+				ps.flexRight.AddItem(ps.tableDetails, 0, 1, false)
+			}
+			if ps.depTreeVisible {
+				ps.drawPackageInfo(*ps.selectedPackage, ps.width)
+			} else {
+				ps.displayDepTree()
+			}
+			return nil
+		}
+
+		// CTRL+R - Toggle reverse dependencies of selected package
+		if (ps.keys.matches("ReverseDependencies", event) && ps.selectedPackage != nil) ||
+			(event.Key() == tcell.KeyEscape && revDepsVisible) {
+			if revDepsVisible {
+				ps.flexRight.Clear()
+				ps.flexRight.AddItem(ps.tableDetails, 0, 1, false)
+				ps.app.SetFocus(ps.tablePackages)
+			} else {
+				ps.displayReverseDeps()
+			}
+			return nil
+		}
+
+		// CTRL+T - Toggle AUR comments of selected package
+		if (ps.keys.matches("Comments", event) && ps.selectedPackage != nil) ||
+			(event.Key() == tcell.KeyEscape && commentsVisible) {
+			if commentsVisible {
+				ps.flexRight.Clear()
+				ps.flexRight.AddItem(ps.tableDetails, 0, 1, false)
+				ps.app.SetFocus(ps.tablePackages)
+			} else if ps.selectedPackage.Source == "AUR" {
+				ps.displayComments(ps.selectedPackage.Name, 0)
+			} else {
+				ps.displayMessage("Comments are only available for AUR packages", true)
+			}
+			return nil
+		}
+
+		// CTRL+E - Toggle changelog of selected package
+		if (ps.keys.matches("Changelog", event) && ps.selectedPackage != nil) ||
+			(event.Key() == tcell.KeyEscape && changelogVisible) {
+			if changelogVisible {
+				ps.flexRight.Clear()
+				ps.flexRight.AddItem(ps.tableDetails, 0, 1, false)
+				ps.app.SetFocus(ps.tablePackages)
+			} else if findSource(ps.sources, ps.selectedPackage.Source) == nil {
+				ps.displayChangelog(*ps.selectedPackage)
+			} else {
+				ps.displayMessage("Changelogs are only available for repository and AUR packages", true)
+			}
+			return nil
+		}
+
+		// CTRL+F - Toggle file list of selected package
+		if (ps.keys.matches("Files", event) && ps.selectedPackage != nil) ||
+			(event.Key() == tcell.KeyEscape && filesVisible) {
+			if filesVisible {
+				ps.flexRight.Clear()
+				ps.flexRight.AddItem(ps.tableDetails, 0, 1, false)
+				ps.app.SetFocus(ps.tablePackages)
+			} else if findSource(ps.sources, ps.selectedPackage.Source) != nil ||
+				(ps.selectedPackage.Source == "AUR" && ps.selectedPackage.LocalVersion == "") {
+				ps.displayMessage("File lists are only available for repository and installed packages", true)
+			} else {
+				ps.displayFiles(*ps.selectedPackage)
+			}
+			return nil
+		}
+
+		// CTRL+K - Toggle cached versions of selected package
+		if (ps.keys.matches("CachedVersions", event) && ps.selectedPackage != nil) ||
+			(event.Key() == tcell.KeyEscape && cacheVisible) {
+			if cacheVisible {
+				ps.flexRight.Clear()
+				ps.flexRight.AddItem(ps.tableDetails, 0, 1, false)
+				ps.app.SetFocus(ps.tablePackages)
+			} else if findSource(ps.sources, ps.selectedPackage.Source) != nil {
+				ps.displayMessage("Cached versions are only available for pacman packages", true)
+			} else {
+				ps.displayCachedVersions(*ps.selectedPackage)
+			}
+			return nil
+		}
+
+		// F12 - Toggle debug console
+		if ps.keys.matches("DebugConsole", event) ||
+			(event.Key() == tcell.KeyEscape && debugVisible) {
+			if debugVisible {
+				ps.flexRight.Clear()
+				ps.flexRight.AddItem(ps.tableDetails, 0, 1, false)
+				ps.app.SetFocus(ps.tablePackages)
+			} else {
+				ps.displayDebugConsole()
+			}
+			return nil
+		}
+
+		// ESC - Close the list of .pacnew / .pacsave files
+		if event.Key() == tcell.KeyEscape && pacnewVisible {
+			ps.flexRight.Clear()
+			ps.flexRight.AddItem(ps.tableDetails, 0, 1, false)
+			ps.app.SetFocus(ps.tablePackages)
+			return nil
+		}
+
+		// CTRL+Y - Show package statistics
+		if ps.keys.matches("Statistics", event) {
+			if detailsHidden {
+				ps.flexRight.Clear()
+				ps.flexRight.AddItem(ps.tableDetails, 0, 1, false)
+			}
+			ps.displayStats()
+			return nil
+		}
+
+		// CTRL+X - Toggle package groups
+		if ps.keys.matches("Groups", event) ||
+			(event.Key() == tcell.KeyEscape && groupsVisible) {
+			if groupsVisible {
+				ps.flexRight.Clear()
+				ps.flexRight.AddItem(ps.tableDetails, 0, 1, false)
+				ps.app.SetFocus(ps.tablePackages)
+			} else {
+				ps.displayGroups()
+			}
+			return nil
+		}
+
+		// CTRL+J - Toggle install history
+		if ps.keys.matches("History", event) ||
+			(event.Key() == tcell.KeyEscape && historyVisible) {
+			if historyVisible {
+				ps.flexRight.Clear()
+				ps.flexRight.AddItem(ps.tableDetails, 0, 1, false)
+				ps.app.SetFocus(ps.tablePackages)
+			} else {
+				ps.displayHistory()
+			}
+			return nil
+		}
+
+		// ESC - Cancel install (transaction preview)
+		if event.Key() == tcell.KeyEscape && previewVisible {
+			ps.previewRun = nil
+			ps.removalNames, ps.removalOptions, ps.removalRun = nil, nil, nil
+			ps.flexRight.Clear()
+			ps.flexRight.AddItem(ps.tableDetails, 0, 1, false)
+			ps.app.SetFocus(ps.tablePackages)
+			return nil
+		}
+
 		// CTRL+O - Open URL for selected package
-		if event.Key() == tcell.KeyCtrlO && ps.selectedPackage != nil {
+		if ps.keys.matches("OpenURL", event) && ps.selectedPackage != nil {
 			exec.Command("xdg-open", ps.selectedPackage.URL).Start()
 			return nil
 		}
 
 		// CTRL+G - Upgradable packages
-		if event.Key() == tcell.KeyCtrlG {
-			if pkgbuildVisible || settingsVisible {
+		if ps.keys.matches("Upgrades", event) {
+			if detailsHidden {
 				ps.flexRight.Clear()
 				ps.flexRight.AddItem(ps.tableDetails, 0, 1, false)
 			}
@@ -326,9 +617,19 @@ func (ps *UI) setupKeyBindings() {
 			return nil
 		}
 
+		// CTRL+V - Security advisories for installed packages
+		if ps.keys.matches("Advisories", event) {
+			if detailsHidden {
+				ps.flexRight.Clear()
+				ps.flexRight.AddItem(ps.tableDetails, 0, 1, false)
+			}
+			ps.displayAdvisories()
+			return nil
+		}
+
 		// CTRL+L - Locally installed packages
-		if event.Key() == tcell.KeyCtrlL {
-			if pkgbuildVisible {
+		if ps.keys.matches("Installed", event) {
+			if detailsHidden && !settingsVisible {
 				ps.flexRight.Clear()
 				ps.flexRight.AddItem(ps.tableDetails, 0, 1, false)
 			}
@@ -368,22 +669,36 @@ func (ps *UI) setupKeyBindings() {
 
 	// input field
 	// ENTER / TAB
+	search := func() {
+		ps.liveSearch.stop()
+		ps.lastSearchTerm = ps.searchTerm(ps.inputSearch.GetText())
+		if len(ps.lastSearchTerm) == 0 {
+			ps.displayInstalled(false)
+			return
+		} else if len(ps.lastSearchTerm) < 2 {
+			ps.displayMessage("Minimum number of characters is 2", true)
+			return
+		}
+		ps.displayPackages(ps.lastSearchTerm)
+	}
+	// ENTER is handled by our done func, so that auto-suggest can use it to pick a suggestion
 	ps.inputSearch.SetDoneFunc(func(key tcell.Key) {
-		if key == tcell.KeyEnter {
-			ps.lastSearchTerm = strings.ToLower(ps.inputSearch.GetText())
-			if len(ps.lastSearchTerm) == 0 {
-				ps.displayInstalled(false)
-				return
-			} else if len(ps.lastSearchTerm) < 2 {
-				ps.displayMessage("Minimum number of characters is 2", true)
-				return
-			}
-			ps.displayPackages(ps.lastSearchTerm)
+		if key == tcell.KeyEnter && ps.keys["Search"].key == tcell.KeyEnter {
+			search()
 		} else if key == tcell.KeyTAB {
 			ps.app.SetFocus(ps.tablePackages)
 		}
+	}).SetChangedFunc(func(text string) {
+		if ps.conf.LiveSearch {
+			ps.searchLive(text)
+		}
 	}).SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		itemRight := ps.flexRight.GetItem(0)
+		// search with a key other than ENTER
+		if ps.keys["Search"].key != tcell.KeyEnter && ps.keys.matches("Search", event) {
+			search()
+			return nil
+		}
 		// Down
 		if event.Key() == tcell.KeyDown && !ps.conf.EnableAutoSuggest {
 			ps.app.SetFocus(ps.tablePackages)
@@ -410,11 +725,11 @@ func (ps *UI) setupKeyBindings() {
 		itemRight := ps.flexRight.GetItem(0)
 
 		// TAB
-		if event.Key() == tcell.KeyTAB {
+		if ps.keys.matches("NextBox", event) {
 			if itemRight == ps.formSettings {
 				ps.app.SetFocus(ps.formSettings.GetFormItem(0))
 			} else if (itemRight == ps.tableDetails && ps.tableDetailsMore) ||
-				(itemRight == ps.formSettings || itemRight == ps.textPkgbuild) {
+				(itemRight == ps.formSettings || itemRight == ps.textPkgbuild || itemRight == ps.treeRevDeps || itemRight == ps.textComments || itemRight == ps.textChangelog || itemRight == ps.textPreview || itemRight == ps.textDebug || itemRight == ps.flexFiles || itemRight == ps.tableCache || itemRight == ps.tablePacnew || itemRight == ps.tableGroups || itemRight == ps.flexHistory) {
 				ps.app.SetFocus(itemRight)
 			} else {
 				ps.app.SetFocus(ps.inputSearch)
@@ -436,9 +751,79 @@ func (ps *UI) setupKeyBindings() {
 			ps.prevComponent = ps.tablePackages
 			return nil
 		}
-		// ENTER
-		if event.Key() == tcell.KeyEnter {
-			ps.installSelectedPackage()
+		// ENTER - install / remove queued packages or the selected one
+		if ps.keys.matches("Install", event) {
+			if len(ps.queue) > 0 {
+				ps.installQueue()
+			} else {
+				ps.installSelectedPackage()
+			}
+			return nil
+		}
+		// SPACE - mark / unmark package for a batch install / removal
+		if ps.keys.matches("Queue", event) {
+			ps.toggleQueued(row)
+			return nil
+		}
+		// Q - show queue
+		if ps.keys.matches("ShowQueue", event) {
+			ps.displayQueue()
+			return nil
+		}
+		// L - show / hide our filter bar (license / architecture)
+		if ps.keys.matches("FilterBar", event) {
+			ps.toggleFilterBar()
+			return nil
+		}
+		// G - download the queued / selected packages without installing them
+		if ps.keys.matches("Download", event) {
+			ps.downloadSelected()
+			return nil
+		}
+		// C / H / A / E / Y - copy the name, URL, package URL, install command or info of the selected package
+		for action := range clipboardActions {
+			if ps.keys.matches(action, event) {
+				ps.copySelected(action)
+				return nil
+			}
+		}
+		// O - show the screenshot of the selected application (AppStream)
+		if ps.keys.matches("Screenshot", event) {
+			ps.showScreenshot()
+			return nil
+		}
+		// R - show the .pacnew / .pacsave files
+		if ps.keys.matches("Pacnew", event) {
+			ps.displayAllPacnew()
+			return nil
+		}
+		// F - switch between orphan / explicit / foreign / all packages
+		if ps.keys.matches("LocalFilter", event) {
+			ps.cycleLocalFilter()
+			return nil
+		}
+		// T - test the mirrors of our mirrorlist
+		if ps.keys.matches("Mirrors", event) {
+			if itemRight != ps.tableDetails {
+				ps.flexRight.Clear()
+				ps.flexRight.AddItem(ps.tableDetails, 0, 1, false)
+			}
+			ps.displayMirrors()
+			return nil
+		}
+		// U - vote / unvote for the selected AUR package
+		if ps.keys.matches("Vote", event) {
+			ps.voteSelectedPackage()
+			return nil
+		}
+		// W - show the AUR packages we voted for
+		if ps.keys.matches("VotedPackages", event) {
+			ps.displayVotedPackages()
+			return nil
+		}
+		// X - add / remove selected package to / from our ignore list
+		if ps.keys.matches("Ignore", event) {
+			ps.toggleIgnored()
 			return nil
 		}
 		// Down / j / k -> noop: WTF? Prevent lock-up with empty list ;) :(
@@ -449,9 +834,11 @@ func (ps *UI) setupKeyBindings() {
 		}
 
 		// sorting keys
-		if util.SliceContains([]rune{'N', 'S', 'I', 'M', 'P'}, event.Rune()) {
-			ps.sortAndRedrawPackageList(event.Rune())
-			return nil
+		for action, criterion := range sortActions {
+			if ps.keys.matches(action, event) {
+				ps.sortAndRedrawPackageList(criterion)
+				return nil
+			}
 		}
 
 		return event
@@ -462,11 +849,298 @@ func (ps *UI) setupKeyBindings() {
 			ps.flexRight.AddItem(ps.tableDetails, 0, 1, false)
 		}
 		ps.displayPackageInfo(row, column)
-		ps.tablePackages.SetTitle(fmt.Sprintf(" (%d/%d) ", row, ps.tablePackages.GetRowCount()-1))
+		ps.tablePackages.SetTitle(ps.packageListTitle(row))
+		// the last package of our search results has been reached, get the next page
+		if row == ps.tablePackages.GetRowCount()-1 {
+			ps.loadMoreResults()
+		}
+	})
+
+	// reverse dependencies
+	ps.treeRevDeps.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		// CTRL+Left
+		if event.Key() == tcell.KeyLeft && event.Modifiers() == tcell.ModCtrl {
+			ps.app.SetFocus(ps.tablePackages)
+			return nil
+		}
+		// TAB
+		if event.Key() == tcell.KeyTAB {
+			ps.app.SetFocus(ps.inputSearch)
+			return nil
+		}
+
+		return event
+	})
+
+	// AUR comments
+	ps.textComments.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		// n / p - next / previous page
+		if event.Rune() == 'n' && ps.commentsMore {
+			ps.displayComments(ps.commentsPkg, ps.commentsOffset+aurCommentsPerPage)
+			return nil
+		}
+		if event.Rune() == 'p' && ps.commentsOffset > 0 {
+			ps.displayComments(ps.commentsPkg, ps.commentsOffset-aurCommentsPerPage)
+			return nil
+		}
+		// CTRL+Left
+		if event.Key() == tcell.KeyLeft && event.Modifiers() == tcell.ModCtrl {
+			ps.app.SetFocus(ps.tablePackages)
+			return nil
+		}
+		// TAB
+		if event.Key() == tcell.KeyTAB {
+			ps.app.SetFocus(ps.inputSearch)
+			return nil
+		}
+
+		return event
+	})
+
+	// changelog
+	ps.textChangelog.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		// CTRL+Left
+		if event.Key() == tcell.KeyLeft && event.Modifiers() == tcell.ModCtrl {
+			ps.app.SetFocus(ps.tablePackages)
+			return nil
+		}
+		// TAB
+		if event.Key() == tcell.KeyTAB {
+			ps.app.SetFocus(ps.inputSearch)
+			return nil
+		}
+
+		return event
+	})
+
+	// debug console
+	ps.textDebug.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		// CTRL+Left
+		if event.Key() == tcell.KeyLeft && event.Modifiers() == tcell.ModCtrl {
+			ps.app.SetFocus(ps.tablePackages)
+			return nil
+		}
+		// TAB
+		if event.Key() == tcell.KeyTAB {
+			ps.app.SetFocus(ps.inputSearch)
+			return nil
+		}
+		// r - reload
+		if event.Rune() == 'r' {
+			ps.drawDebugConsole()
+			return nil
+		}
+
+		return event
+	})
+
+	// file list
+	ps.inputFiles.SetChangedFunc(func(text string) {
+		ps.drawFiles(text)
+	})
+	ps.inputFiles.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		// Down / ENTER / TAB
+		if event.Key() == tcell.KeyDown || event.Key() == tcell.KeyEnter || event.Key() == tcell.KeyTAB {
+			ps.app.SetFocus(ps.tableFiles)
+			return nil
+		}
+		// CTRL+Left
+		if event.Key() == tcell.KeyLeft && event.Modifiers() == tcell.ModCtrl {
+			ps.app.SetFocus(ps.tablePackages)
+			return nil
+		}
+
+		return event
+	})
+	ps.tableFiles.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		row, _ := ps.tableFiles.GetSelection()
+
+		// Up / "/" - Filter
+		if (event.Key() == tcell.KeyUp && row == 0) || event.Rune() == '/' {
+			ps.app.SetFocus(ps.inputFiles)
+			return nil
+		}
+		// CTRL+Left
+		if event.Key() == tcell.KeyLeft && event.Modifiers() == tcell.ModCtrl {
+			ps.app.SetFocus(ps.tablePackages)
+			return nil
+		}
+		// TAB
+		if event.Key() == tcell.KeyTAB {
+			ps.app.SetFocus(ps.inputSearch)
+			return nil
+		}
+
+		return event
+	})
+
+	// install history
+	ps.inputHistory.SetChangedFunc(func(text string) {
+		ps.drawHistory(text)
+	})
+	ps.inputHistory.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		// Down / ENTER / TAB
+		if event.Key() == tcell.KeyDown || event.Key() == tcell.KeyEnter || event.Key() == tcell.KeyTAB {
+			ps.app.SetFocus(ps.tableHistory)
+			return nil
+		}
+		// CTRL+Left
+		if event.Key() == tcell.KeyLeft && event.Modifiers() == tcell.ModCtrl {
+			ps.app.SetFocus(ps.tablePackages)
+			return nil
+		}
+
+		return event
+	})
+	ps.tableHistory.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		row, _ := ps.tableHistory.GetSelection()
+
+		// Up / "/" - Filter
+		if (event.Key() == tcell.KeyUp && row <= 1) || event.Rune() == '/' {
+			ps.app.SetFocus(ps.inputHistory)
+			return nil
+		}
+		// ENTER - Show cached versions (reinstall / downgrade)
+		if event.Key() == tcell.KeyEnter && row > 0 && row <= len(ps.shownHistory) {
+			ps.displayCachedVersions(ps.historyPackage(ps.shownHistory[row-1].Name))
+			return nil
+		}
+		// t - Show timeline of the selected package
+		if event.Rune() == 't' && row > 0 && row <= len(ps.shownHistory) {
+			ps.inputHistory.SetText("=" + ps.shownHistory[row-1].Name)
+			return nil
+		}
+		// CTRL+Left
+		if event.Key() == tcell.KeyLeft && event.Modifiers() == tcell.ModCtrl {
+			ps.app.SetFocus(ps.tablePackages)
+			return nil
+		}
+		// TAB
+		if event.Key() == tcell.KeyTAB {
+			ps.app.SetFocus(ps.inputSearch)
+			return nil
+		}
+
+		return event
+	})
+
+	// cached versions
+	ps.tableCache.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		row, _ := ps.tableCache.GetSelection()
+
+		// ENTER - Install (downgrade to) selected version
+		if event.Key() == tcell.KeyEnter && row > 0 && row <= len(ps.cachedVersions) {
+			ps.installCachedVersion(ps.cachedPkg, ps.cachedVersions[row-1])
+			return nil
+		}
+		// CTRL+Left
+		if event.Key() == tcell.KeyLeft && event.Modifiers() == tcell.ModCtrl {
+			ps.app.SetFocus(ps.tablePackages)
+			return nil
+		}
+		// TAB
+		if event.Key() == tcell.KeyTAB {
+			ps.app.SetFocus(ps.inputSearch)
+			return nil
+		}
+
+		return event
+	})
+
+	// .pacnew / .pacsave files
+	ps.tablePacnew.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		row, _ := ps.tablePacnew.GetSelection()
+
+		// ENTER - Merge the selected file with our merge command
+		if event.Key() == tcell.KeyEnter && row > 0 && row <= len(ps.pacnewFiles) {
+			ps.mergePacnew(ps.pacnewFiles[row-1])
+			return nil
+		}
+		// CTRL+Left
+		if event.Key() == tcell.KeyLeft && event.Modifiers() == tcell.ModCtrl {
+			ps.app.SetFocus(ps.tablePackages)
+			return nil
+		}
+		// TAB
+		if event.Key() == tcell.KeyTAB {
+			ps.app.SetFocus(ps.inputSearch)
+			return nil
+		}
+
+		return event
+	})
+
+	// package groups
+	ps.tableGroups.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		row, _ := ps.tableGroups.GetSelection()
+		if row < 1 || row > len(ps.groups) {
+			return event
+		}
+
+		// ENTER - Show members of selected group
+		if event.Key() == tcell.KeyEnter {
+			ps.displayGroupMembers(ps.groups[row-1].Name)
+			return nil
+		}
+		// i - Install whole group
+		if event.Rune() == 'i' {
+			ps.installGroup(ps.groups[row-1])
+			return nil
+		}
+		// CTRL+Left
+		if event.Key() == tcell.KeyLeft && event.Modifiers() == tcell.ModCtrl {
+			ps.app.SetFocus(ps.tablePackages)
+			return nil
+		}
+		// TAB
+		if event.Key() == tcell.KeyTAB {
+			ps.app.SetFocus(ps.inputSearch)
+			return nil
+		}
+
+		return event
+	})
+
+	// transaction preview
+	ps.textPreview.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		// 1, 2, 3... - choose the removal strategy (removal preview)
+		if ps.removalRun != nil && event.Key() == tcell.KeyRune && event.Rune() >= '1' && event.Rune() < '1'+rune(len(ps.removalOptions)) {
+			ps.computeRemovalPreview(int(event.Rune() - '1'))
+			return nil
+		}
+		// ENTER - Run install command
+		if event.Key() == tcell.KeyEnter {
+			run := ps.previewRun
+			ps.previewRun = nil
+			ps.flexRight.Clear()
+			ps.flexRight.AddItem(ps.tableDetails, 0, 1, false)
+			ps.app.SetFocus(ps.tablePackages)
+			if run != nil {
+				run()
+			}
+			return nil
+		}
+		// CTRL+Left
+		if event.Key() == tcell.KeyLeft && event.Modifiers() == tcell.ModCtrl {
+			ps.app.SetFocus(ps.tablePackages)
+			return nil
+		}
+		// TAB
+		if event.Key() == tcell.KeyTAB {
+			ps.app.SetFocus(ps.inputSearch)
+			return nil
+		}
+
+		return event
 	})
 
 	// PKGBUILD
 	ps.textPkgbuild.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		// s - switch between PKGBUILD and .SRCINFO
+		if event.Rune() == 's' {
+			ps.displayPackageFile(otherPackageFile(ps.pkgbuildFile))
+			return nil
+		}
 		// CTRL+Left
 		if event.Key() == tcell.KeyLeft && event.Modifiers() == tcell.ModCtrl {
 			ps.app.SetFocus(ps.tablePackages)
@@ -520,6 +1194,12 @@ func (ps *UI) setupSettingsForm() {
 // read settings from from and saves to config file
 func (ps *UI) saveSettings(defaults bool) {
 	var err error
+	if err := ps.validateSettingsForm(); err != nil {
+		ps.displayMessage(err.Error(), true)
+		return
+	}
+	root, dbPath, confPath, aurUrl := ps.conf.PacmanRootPath, ps.conf.PacmanDbPath, ps.conf.PacmanConfigPath, ps.conf.AurRpcUrl
+	showUpdates, updateInterval := ps.conf.ShowUpdateStatus, ps.conf.UpdateCheckInterval
 	for i := 0; i < ps.formSettings.GetFormItemCount(); i++ {
 		item := ps.formSettings.GetFormItem(i)
 		if input, ok := item.(*tview.InputField); ok {
@@ -539,6 +1219,39 @@ func (ps *UI) saveSettings(defaults bool) {
 					ps.displayMessage("Can't convert delay value to int", true)
 					return
 				}
+			case "Live search delay (ms): ":
+				ps.conf.LiveSearchDelay, err = strconv.Atoi(txt)
+				if err != nil {
+					ps.displayMessage("Can't convert delay value to int", true)
+					return
+				}
+			case "Live search min. length: ":
+				ps.conf.LiveSearchMinLength, err = strconv.Atoi(txt)
+				if err != nil || ps.conf.LiveSearchMinLength < 1 {
+					ps.displayMessage("Minimum length must be a number greater than 0", true)
+					return
+				}
+			case "AUR ignore patterns: ":
+				ps.conf.AurIgnore = strings.Fields(txt)
+			case "Chroot build packages: ":
+				ps.conf.AurChrootPackages = strings.Fields(txt)
+			case "Chroot build command: ":
+				ps.conf.AurChrootCommand = txt
+			case "AUR SSH command: ":
+				if strings.TrimSpace(txt) == "" {
+					ps.displayMessage("AUR SSH command can't be empty", true)
+					return
+				}
+				ps.conf.AurSshCommand = txt
+			case "Exclude sources: ":
+				ps.conf.ExcludeSources = strings.Fields(txt)
+			case "Repo priority: ":
+				ps.conf.RepoPriority = strings.Fields(txt)
+			case "Ignored packages: ":
+				ps.conf.IgnoredPackages = strings.Fields(txt)
+				ps.cacheInfo.Delete("#upgrades#")
+			case "Pacman root path: ":
+				ps.conf.PacmanRootPath = txt
 			case "Pacman DB path: ":
 				ps.conf.PacmanDbPath = txt
 			case "Pacman config path: ":
@@ -547,26 +1260,78 @@ func (ps *UI) saveSettings(defaults bool) {
 				ps.conf.InstallCommand = txt
 			case "Uninstall command: ":
 				ps.conf.UninstallCommand = txt
+			case "Downgrade command: ":
+				ps.conf.DowngradeCommand = txt
+			case "Download command: ":
+				ps.conf.DownloadCommand = txt
+			case "AUR download command: ":
+				ps.conf.AurDownloadCommand = txt
+			case "AUR download dir: ":
+				ps.conf.AurDownloadDir = txt
+			case "Merge command: ":
+				ps.conf.MergeCommand = txt
+			case "Package cache dirs: ":
+				ps.conf.PackageCacheDirs = txt
 			case "AUR Install command: ":
 				ps.conf.AurInstallCommand = txt
 			case "Upgrade command: ":
 				ps.conf.SysUpgradeCommand = txt
 			case "AUR Upgrade command: ":
 				ps.conf.AurUpgradeCommand = txt
+			case "Flatpak install command: ":
+				ps.conf.FlatpakInstallCommand = txt
+			case "Flatpak uninstall command: ":
+				ps.conf.FlatpakUninstallCommand = txt
+			case "Flatpak upgrade command: ":
+				ps.conf.FlatpakUpgradeCommand = txt
+			case "Plugins: ":
+				ps.conf.Plugins = txt
 			case "Max search results: ":
 				ps.conf.MaxResults, err = strconv.Atoi(txt)
 				if err != nil {
 					ps.displayMessage("Can't convert max results value to int", true)
 					return
 				}
+			case "Max dependencies: ":
+				ps.conf.MaxDependencies, err = strconv.Atoi(txt)
+				if err != nil {
+					ps.displayMessage("Can't convert max dependencies value to int", true)
+					return
+				}
+			case "Broad search warning: ":
+				ps.conf.BroadSearchWarning, err = strconv.Atoi(txt)
+				if err != nil {
+					ps.displayMessage("Can't convert broad search warning value to int", true)
+					return
+				}
 			case "Cache expiry (m): ":
 				ps.conf.CacheExpiry, err = strconv.Atoi(txt)
 				if err != nil {
 					ps.displayMessage("Can't convert cache expiry value to int", true)
 					return
 				}
+			case "Disk cache expiry (m): ":
+				ps.conf.DiskCacheExpiry, err = strconv.Atoi(txt)
+				if err != nil || ps.conf.DiskCacheExpiry < 1 {
+					ps.displayMessage("Disk cache expiry must be a positive number", true)
+					return
+				}
 			case "Show PKGBUILD command: ":
 				ps.conf.ShowPkgbuildCommand = txt
+			case "Security tracker URL: ":
+				ps.conf.SecurityTrackerUrl = txt
+			case "Update check interval (m): ":
+				ps.conf.UpdateCheckInterval, err = strconv.Atoi(txt)
+				if err != nil || ps.conf.UpdateCheckInterval < 1 {
+					ps.displayMessage("Update check interval must be a positive number", true)
+					return
+				}
+			case "Quiet hours: ":
+				if _, err := parseQuietHours(txt); err != nil {
+					ps.displayMessage(err.Error(), true)
+					return
+				}
+				ps.conf.QuietHours = txt
 			case "News-feed URL(s): ":
 				ps.conf.FeedURLs = txt
 			case "News-feed max items: ":
@@ -589,6 +1354,14 @@ func (ps *UI) saveSettings(defaults bool) {
 				ps.conf.SearchMode = opt
 			case "Search by: ":
 				ps.conf.SearchBy = opt
+			case "Local filter: ":
+				ps.conf.LocalFilter = opt
+			case "Sort results by: ":
+				ps.conf.SortResults = opt
+			case "Screenshot graphics: ":
+				ps.conf.ScreenshotGraphics = opt
+			case "Log level: ":
+				ps.conf.LogLevel = opt
 			case "Color scheme: ":
 				ps.conf.ColorScheme = opt
 			case "Border style: ":
@@ -600,16 +1373,46 @@ func (ps *UI) saveSettings(defaults bool) {
 			switch cb.GetLabel() {
 			case "Disable AUR: ":
 				ps.conf.DisableAur = cb.IsChecked()
+			case "Hide out-of-date: ":
+				ps.conf.HideOutOfDate = cb.IsChecked()
+			case "Disable lazy loading: ":
+				ps.conf.DisableLazyLoading = cb.IsChecked()
+			case "Disable pkgstats: ":
+				ps.conf.DisablePkgstats = cb.IsChecked()
+			case "Disable repo merging: ":
+				ps.conf.DisableRepoMerge = cb.IsChecked()
+			case "Disable AppStream: ":
+				ps.conf.DisableAppStream = cb.IsChecked()
+			case "Disable .pacnew check: ":
+				ps.conf.DisablePacnewCheck = cb.IsChecked()
+			case "Read-only mode: ":
+				ps.conf.ReadOnly = cb.IsChecked()
+			case "Build AUR in chroot: ":
+				ps.conf.AurChrootBuild = cb.IsChecked()
+			case "AUR voting (SSH): ":
+				ps.conf.AurVoting = cb.IsChecked()
+			case "Enable Flatpak: ":
+				ps.conf.EnableFlatpak = cb.IsChecked()
 			case "Disable Cache: ":
 				ps.conf.DisableCache = cb.IsChecked()
+			case "Disable disk cache: ":
+				ps.conf.DisableDiskCache = cb.IsChecked()
 			case "Separate AUR commands: ":
 				ps.conf.AurUseDifferentCommands = cb.IsChecked()
 			case "Show PKGBUILD internally: ":
 				ps.conf.ShowPkgbuildInternally = cb.IsChecked()
 			case "Compute \"Required by\": ":
 				ps.conf.ComputeRequiredBy = cb.IsChecked()
+			case "Show update status: ":
+				ps.conf.ShowUpdateStatus = cb.IsChecked()
+			case "Disable install preview: ":
+				ps.conf.DisableInstallPreview = cb.IsChecked()
+			case "Disable advisories: ":
+				ps.conf.DisableAdvisories = cb.IsChecked()
 			case "Disable news-feed: ":
 				ps.conf.DisableNewsFeed = cb.IsChecked()
+			case "Disable news warning: ":
+				ps.conf.DisableNewsWarning = cb.IsChecked()
 			case "Save window layout: ":
 				ps.conf.SaveWindowLayout = cb.IsChecked()
 			case "Transparent: ":
@@ -621,11 +1424,27 @@ func (ps *UI) saveSettings(defaults bool) {
 				} else {
 					ps.inputSearch.SetAutocompleteFunc(nil)
 				}
+			case "Search as you type: ":
+				ps.conf.LiveSearch = cb.IsChecked()
 			case "Separate Deps with Newline: ":
 				ps.conf.SepDepsWithNewLine = cb.IsChecked()
+			case "Skip failing repos: ":
+				ps.conf.SkipFailingRepos = cb.IsChecked()
+			case "Prefer name matches: ":
+				ps.conf.PreferNameMatches = cb.IsChecked()
+			case "Preserve repo order: ":
+				ps.conf.PreserveRepoOrder = cb.IsChecked()
+			case "Match name segments: ":
+				ps.conf.SegmentPrefixMatch = cb.IsChecked()
+			case "Case sensitive search: ":
+				ps.conf.CaseSensitive = cb.IsChecked()
 			}
 		}
 	}
+	if err := validateCommands(ps.conf); err != nil {
+		ps.displayMessage(err.Error(), true)
+		return
+	}
 	err = ps.conf.Save()
 	if err != nil {
 		ps.displayMessage(err.Error(), true)
@@ -640,5 +1459,41 @@ func (ps *UI) saveSettings(defaults bool) {
 	ps.cacheSearch.Flush()
 	if ps.conf.DisableCache {
 		ps.cacheInfo.Flush()
+		ps.cacheDeps.Flush()
+	}
+	if ps.conf.AurRpcUrl != aurUrl {
+		ps.cacheInfo.Flush()
+	}
+	// our searches might be using the disk cache and our alpm handle, they are swapped once they are done
+	// pacman paths are applied without a restart
+	diskCache := newAurDiskCache(ps.conf)
+	reload := defaults || ps.conf.PacmanRootPath != root || ps.conf.PacmanDbPath != dbPath || ps.conf.PacmanConfigPath != confPath
+	ps.updateLocked(func() {
+		ps.diskCache = diskCache
+		if !reload {
+			return
+		}
+		if err := ps.reinitPacmanDbs(); err != nil {
+			ps.displayMessage(err.Error(), true)
+		}
+	})
+	if err := ps.setupLog(); err != nil {
+		ps.displayMessage(err.Error(), true)
+	}
+	ps.setupKeymap()
+	ps.drawTitle()
+	// our background update checks are restarted with their new interval
+	if defaults || ps.conf.ShowUpdateStatus != showUpdates || ps.conf.UpdateCheckInterval != updateInterval {
+		ps.watchUpgrades()
 	}
+	// our sources are used by our background work (e.g. searches) as well
+	ps.updateLocked(func() {
+		for _, s := range ps.sources {
+			if fp, ok := s.(*flatpakSource); ok {
+				fp.installCommand, fp.uninstallCommand, fp.upgradeCommand = ps.conf.FlatpakInstallCommand, ps.conf.FlatpakUninstallCommand, ps.conf.FlatpakUpgradeCommand
+			}
+		}
+		ps.sources = withPlugins(ps.sources, ps.conf.Plugins)
+		ps.sourceUpgrades = nil
+	})
 }
@@ -0,0 +1,185 @@
+package pacseek
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Jguer/go-alpm/v2"
+	"github.com/moson-mo/pacseek/internal/args"
+	"github.com/moson-mo/pacseek/internal/config"
+)
+
+// packageSnapshot is the set of explicitly installed packages of a system
+// native packages are the ones found in our sync db's, foreign ones (e.g. from the AUR) are not
+type packageSnapshot struct {
+	Native  []string
+	Foreign []string
+}
+
+// snapshotDiff compares a snapshot with our installed packages
+// Missing: packages of the snapshot that are not installed and can be installed (repositories / AUR)
+// Unavailable: missing packages that can't be found anywhere, Extra: explicitly installed packages that are not part of the snapshot
+type snapshotDiff struct {
+	Missing     []Package
+	Unavailable []string
+	Extra       []string
+}
+
+// creates a snapshot of our explicitly installed packages
+func takeSnapshot(h dbHandle) (packageSnapshot, error) {
+	snap := packageSnapshot{Native: []string{}, Foreign: []string{}}
+	if h == nil {
+		return snap, errors.New("alpm handle is nil")
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return snap, err
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return snap, err
+	}
+
+	for _, pkg := range local.PkgCache().Slice() {
+		if pkg.Reason() != alpm.PkgReasonExplicit {
+			continue
+		}
+		if findSyncPackage(dbs, pkg.Name()) != nil {
+			snap.Native = append(snap.Native, pkg.Name())
+		} else {
+			snap.Foreign = append(snap.Foreign, pkg.Name())
+		}
+	}
+	sort.Strings(snap.Native)
+	sort.Strings(snap.Foreign)
+	return snap, nil
+}
+
+// writes a snapshot in our file format: one package per line in a [native] and [foreign] section
+func (s packageSnapshot) write(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("# pacseek snapshot - " + time.Now().Format("2006-01-02 15:04:05") + "\n")
+	for _, section := range []struct {
+		name string
+		pkgs []string
+	}{{"native", s.Native}, {"foreign", s.Foreign}} {
+		sb.WriteString("\n[" + section.name + "]\n")
+		for _, name := range section.pkgs {
+			sb.WriteString(name + "\n")
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// parses a snapshot file, empty lines and comments (#) are skipped
+// package names in front of any section are treated as native ones (e.g. the output of "pacman -Qqe")
+func parseSnapshot(r io.Reader) (packageSnapshot, error) {
+	snap := packageSnapshot{Native: []string{}, Foreign: []string{}}
+	section := &snap.Native
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case line == "[native]":
+			section = &snap.Native
+		case line == "[foreign]":
+			section = &snap.Foreign
+		case strings.HasPrefix(line, "[") || strings.ContainsAny(line, " \t"):
+			return snap, fmt.Errorf("invalid snapshot: line %d: %s", n, line)
+		default:
+			*section = append(*section, line)
+		}
+	}
+	return snap, scanner.Err()
+}
+
+// compares a snapshot with our installed packages
+// missing native packages are installed from the first repository that has them, missing foreign ones are expected to be in the AUR
+// "aurAvailable" returns which of the foreign packages can be found in the AUR
+func diffSnapshot(h dbHandle, snap packageSnapshot, aurAvailable func(names ...string) map[string]bool) (snapshotDiff, error) {
+	diff := snapshotDiff{Missing: []Package{}, Unavailable: []string{}, Extra: []string{}}
+	current, err := takeSnapshot(h)
+	if err != nil {
+		return diff, err
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return diff, err
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return diff, err
+	}
+
+	wanted := map[string]bool{}
+	foreign := []string{}
+	for _, name := range append(append([]string{}, snap.Native...), snap.Foreign...) {
+		if wanted[name] {
+			continue
+		}
+		wanted[name] = true
+		if local.Pkg(name) != nil {
+			continue
+		}
+		if spkg := findSyncPackage(dbs, name); spkg != nil && spkg.DB() != nil {
+			diff.Missing = append(diff.Missing, Package{Name: name, Source: spkg.DB().Name()})
+		} else {
+			foreign = append(foreign, name)
+		}
+	}
+	if len(foreign) > 0 {
+		available := aurAvailable(foreign...)
+		for _, name := range foreign {
+			if available[name] {
+				diff.Missing = append(diff.Missing, Package{Name: name, Source: "AUR"})
+			} else {
+				diff.Unavailable = append(diff.Unavailable, name)
+			}
+		}
+	}
+
+	for _, name := range append(current.Native, current.Foreign...) {
+		if !wanted[name] {
+			diff.Extra = append(diff.Extra, name)
+		}
+	}
+	sort.Strings(diff.Extra)
+	return diff, nil
+}
+
+// ExportSnapshot writes the explicitly installed packages (native and foreign ones) to "file"
+// a summary is written to "w"
+func ExportSnapshot(conf *config.Settings, flags args.Flags, file string, w io.Writer) error {
+	h, _, err := initPacmanDbs(conf.PacmanRootPath, conf.PacmanDbPath, conf.PacmanConfigPath, flags.Repositories, conf.SkipFailingRepos)
+	if err != nil {
+		return err
+	}
+	defer h.Release()
+
+	snap, err := takeSnapshot(h)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := snap.write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Exported %d native and %d foreign packages to %s\n", len(snap.Native), len(snap.Foreign), file)
+	return nil
+}
@@ -0,0 +1,218 @@
+package pacseek
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/moson-mo/pacseek/internal/config"
+	"github.com/moson-mo/pacseek/internal/util"
+)
+
+// SortKey is a single criteria for ordering packages
+type SortKey int
+
+const (
+	SortByName SortKey = iota
+	SortByRelevance
+	SortBySourcePriority
+	SortByVotes
+	SortByPopularity
+	SortByLastModified
+	SortByInstalled
+	SortByScore
+	SortByInstalledSize
+	SortByDownloadSize
+)
+
+// criteria for sorting search results (see sortResults)
+var sortCriteria = []string{"name", "relevance", "popularity", "votes", "date", "size", "download"}
+
+// popularity of repository packages (they don't have one), the highest possible value so that they are sorted first
+const repoPopularity = math.MaxFloat64
+
+// SortSpec defines how packages are ordered
+// Keys are applied in order, the next key is only used to break ties of the previous one
+// Term is needed for SortByRelevance, SourcePriority for SortBySourcePriority (unlisted sources go last)
+// ReposLast puts repository packages behind AUR packages when sorting by popularity
+type SortSpec struct {
+	Keys           []SortKey
+	Term           string
+	SourcePriority []string
+	ReposLast      bool
+}
+
+// sorts a list of packages according to our sort specification (stable)
+func sortPackages(pkgs []Package, spec SortSpec) {
+	sort.SliceStable(pkgs, func(i, j int) bool {
+		for _, key := range spec.Keys {
+			if c := comparePackages(pkgs[i], pkgs[j], key, spec); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+}
+
+// adds installed packages that are not part of our (repo / AUR) search results
+func addLocalOnly(packages, localPackages []Package) []Package {
+	for _, lpkg := range localPackages {
+		found := false
+		for _, pkg := range packages {
+			if pkg.Name == lpkg.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			packages = append(packages, lpkg)
+		}
+	}
+	return packages
+}
+
+// sorts search results by name
+// with "preserveOrder", the order in which packages were found (alpm db / cache order, then AUR) is kept
+func orderResults(pkgs []Package, preserveOrder bool) {
+	if preserveOrder {
+		return
+	}
+	sortPackages(pkgs, SortSpec{Keys: []SortKey{SortByName}})
+}
+
+// orders merged (repo / AUR) search results with the criterion of our settings
+// results are sorted by name first (unless the order is preserved), so that equal packages are in alphabetical order
+// with the "name" criterion, fuzzy matches are ranked by their score
+func sortSearchResults(pkgs []Package, conf *config.Settings, term string) {
+	orderResults(pkgs, conf.PreserveRepoOrder || conf.SearchMode == "Fuzzy")
+	if conf.SortResults != "" && conf.SortResults != "name" {
+		sortResults(pkgs, conf.SortResults, term)
+		return
+	}
+	if conf.SearchMode == "Fuzzy" {
+		sortPackages(pkgs, SortSpec{Keys: []SortKey{SortByScore}})
+	}
+}
+
+// sorts search results by a named criterion: "relevance" (exact name match, prefix, contained / fuzzy score),
+// "popularity", "votes" (AUR), "name", "date" (last modified, newest first), "size" (installed size, biggest first)
+// or "download" (download size, biggest first).
+// packages that are equal keep their order
+func sortResults(pkgs []Package, criterion, term string) error {
+	var spec SortSpec
+	switch criterion {
+	case "relevance":
+		spec = SortSpec{Keys: []SortKey{SortByRelevance, SortByScore}, Term: term}
+	case "popularity":
+		spec = SortSpec{Keys: []SortKey{SortByPopularity}}
+	case "name":
+		spec = SortSpec{Keys: []SortKey{SortByName}}
+	case "date":
+		spec = SortSpec{Keys: []SortKey{SortByLastModified}}
+	case "votes":
+		spec = SortSpec{Keys: []SortKey{SortByVotes}}
+	case "size":
+		spec = SortSpec{Keys: []SortKey{SortByInstalledSize}}
+	case "download":
+		spec = SortSpec{Keys: []SortKey{SortByDownloadSize}}
+	default:
+		return fmt.Errorf("unknown sort criterion '%s'", criterion)
+	}
+	sortPackages(pkgs, spec)
+	return nil
+}
+
+// compares two packages by a sort key
+// returns a negative value if "a" goes first, a positive one if "b" goes first or 0 if they are equal
+func comparePackages(a, b Package, key SortKey, spec SortSpec) int {
+	switch key {
+	case SortByName:
+		return strings.Compare(a.Name, b.Name)
+	case SortByRelevance:
+		return relevance(a.Name, spec.Term) - relevance(b.Name, spec.Term)
+	case SortBySourcePriority:
+		return sourcePriority(a.Source, spec.SourcePriority) - sourcePriority(b.Source, spec.SourcePriority)
+	case SortByVotes:
+		return b.NumVotes - a.NumVotes
+	case SortByPopularity:
+		c := comparePopularity(a.Popularity, b.Popularity)
+		if spec.ReposLast && (a.Popularity == repoPopularity) != (b.Popularity == repoPopularity) {
+			return -c
+		}
+		return c
+	case SortByLastModified:
+		return b.LastModified - a.LastModified
+	case SortByScore:
+		return a.Score - b.Score
+	case SortByInstalledSize:
+		switch {
+		case a.InstalledSize > b.InstalledSize:
+			return -1
+		case a.InstalledSize < b.InstalledSize:
+			return 1
+		}
+		return 0
+	case SortByDownloadSize:
+		switch {
+		case a.DownloadSize > b.DownloadSize:
+			return -1
+		case a.DownloadSize < b.DownloadSize:
+			return 1
+		}
+		return 0
+	case SortByInstalled:
+		if a.IsInstalled == b.IsInstalled {
+			return 0
+		}
+		if a.IsInstalled {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+// compares two popularity values (higher goes first)
+// repository packages (repoPopularity) are compared without any arithmetic, invalid values (NaN) are treated as 0
+func comparePopularity(a, b float64) int {
+	if math.IsNaN(a) {
+		a = 0
+	}
+	if math.IsNaN(b) {
+		b = 0
+	}
+	switch {
+	case a == b:
+		return 0
+	case a == repoPopularity:
+		return -1
+	case b == repoPopularity:
+		return 1
+	case a > b:
+		return -1
+	}
+	return 1
+}
+
+// ranks a package name by how well it matches the search term (lower is better)
+// exact match, prefix, contained, no match
+func relevance(name, term string) int {
+	switch {
+	case name == term:
+		return 0
+	case strings.HasPrefix(name, term):
+		return 1
+	case strings.Contains(name, term):
+		return 2
+	}
+	return 3
+}
+
+// returns the priority of a source (lower is better), sources that are not in our list go last
+func sourcePriority(source string, priority []string) int {
+	if i := util.IndexOf(priority, source); i != -1 {
+		return i
+	}
+	return len(priority)
+}
@@ -0,0 +1,79 @@
+package pacseek
+
+// packageSource is an additional source of packages (besides the repositories and the AUR), e.g. Flatpak or a plugin
+// the packages of a source are tagged with its name (Package.Source / InfoRecord.Source)
+type packageSource interface {
+	Name() string
+	Search(term string, maxResults int) ([]Package, error)
+	Info(name string) (InfoRecord, error)
+	Installed() (map[string]bool, error)
+	Upgradable() ([]Upgrade, error)
+	InstallCommand(pkg InfoRecord) string
+	UninstallCommand(pkg InfoRecord) string
+	UpgradeCommand(pkg InfoRecord) string
+}
+
+// refreshable sources cache some of their state (e.g. the installed packages), it is dropped after our transactions
+type refreshable interface {
+	refresh()
+}
+
+// drops the cached state of our additional sources
+func refreshSources(sources []packageSource) {
+	for _, s := range sources {
+		if r, ok := s.(refreshable); ok {
+			r.refresh()
+		}
+	}
+}
+
+// returns the additional source with the given name (nil if there is none)
+func findSource(sources []packageSource, name string) packageSource {
+	for _, s := range sources {
+		if s.Name() == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// searches all additional sources, the results are tagged with the name of their source
+// errors of a source don't prevent the others from being searched
+func searchSources(sources []packageSource, term string, maxResults int) ([]Package, []error) {
+	packages := []Package{}
+	errs := []error{}
+	for _, s := range sources {
+		pkgs, err := s.Search(term, maxResults)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for i := range pkgs {
+			pkgs[i].Source = s.Name()
+		}
+		packages = append(packages, pkgs...)
+	}
+	return packages, errs
+}
+
+// returns the upgrades of all additional sources, tagged with the name of their source
+// errors of a source don't prevent the others from being checked
+func sourceUpgrades(sources []packageSource) ([]Upgrade, []error) {
+	upgrades := []Upgrade{}
+	errs := []error{}
+	for _, s := range sources {
+		up, err := s.Upgradable()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for i := range up {
+			up[i].Source = s.Name()
+			if up[i].Status == "" {
+				up[i].Status = "upgrade"
+			}
+		}
+		upgrades = append(upgrades, up...)
+	}
+	return upgrades, errs
+}
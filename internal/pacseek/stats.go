@@ -0,0 +1,117 @@
+package pacseek
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/Jguer/go-alpm/v2"
+)
+
+// number of packages we list for the largest / most recently installed ones
+const statsTopCount = 10
+
+// packageStats summarizes the packages installed on our system
+type packageStats struct {
+	Installed     int
+	Explicit      int
+	Dependencies  int
+	Orphans       int
+	Foreign       int
+	InstalledSize int64
+	Largest       []statsPackage
+	Recent        []statsPackage
+	Repos         []repoStats
+}
+
+// statsPackage is an installed package listed in our statistics
+type statsPackage struct {
+	Name string
+	Size int64
+	Date time.Time
+}
+
+// repoStats is the number / size of installed packages coming from a repository ("foreign" for packages not found in any of them)
+type repoStats struct {
+	Name  string
+	Count int
+	Size  int64
+}
+
+// aggregates statistics over our local db
+func computeStats(h dbHandle) (packageStats, error) {
+	stats := packageStats{Largest: []statsPackage{}, Recent: []statsPackage{}, Repos: []repoStats{}}
+	if h == nil {
+		return stats, errors.New("alpm handle is nil")
+	}
+	dbs, err := h.SyncDBs()
+	if err != nil {
+		return stats, err
+	}
+	local, err := h.LocalDB()
+	if err != nil {
+		return stats, err
+	}
+
+	all := []statsPackage{}
+	repos := map[string]*repoStats{}
+	order := []string{}
+	for _, pkg := range local.PkgCache().Slice() {
+		stats.Installed++
+		stats.InstalledSize += pkg.ISize()
+		if pkg.Reason() == alpm.PkgReasonExplicit {
+			stats.Explicit++
+		} else {
+			stats.Dependencies++
+		}
+
+		repo := "foreign"
+		if spkg := findSyncPackage(dbs, pkg.Name()); spkg != nil && spkg.DB() != nil {
+			repo = spkg.DB().Name()
+		} else {
+			stats.Foreign++
+		}
+		if repos[repo] == nil {
+			repos[repo] = &repoStats{Name: repo}
+			order = append(order, repo)
+		}
+		repos[repo].Count++
+		repos[repo].Size += pkg.ISize()
+
+		all = append(all, statsPackage{Name: pkg.Name(), Size: pkg.ISize(), Date: pkg.InstallDate()})
+	}
+	stats.Orphans = len(listOrphans(h, false))
+
+	for _, repo := range order {
+		stats.Repos = append(stats.Repos, *repos[repo])
+	}
+	sort.SliceStable(stats.Repos, func(i, j int) bool {
+		return stats.Repos[i].Count > stats.Repos[j].Count
+	})
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Size != all[j].Size {
+			return all[i].Size > all[j].Size
+		}
+		return all[i].Name < all[j].Name
+	})
+	stats.Largest = append(stats.Largest, all[:statsLimit(len(all))]...)
+
+	sort.Slice(all, func(i, j int) bool {
+		if !all[i].Date.Equal(all[j].Date) {
+			return all[i].Date.After(all[j].Date)
+		}
+		return all[i].Name < all[j].Name
+	})
+	stats.Recent = append(stats.Recent, all[:statsLimit(len(all))]...)
+
+	return stats, nil
+}
+
+// returns the number of packages we list (statsTopCount at most)
+func statsLimit(n int) int {
+	if n > statsTopCount {
+		return statsTopCount
+	}
+	return n
+}
@@ -1,8 +1,10 @@
 package pacseek
 
 import (
+	"context"
 	"io"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 
@@ -22,6 +24,8 @@ const (
 	UrlRepoPkgbuild = "https://gitlab.archlinux.org/archlinux/packaging/packages/%s/-/raw/main/PKGBUILD"
 
 	UrlAurMaintainer = "https://aur.archlinux.org/packages?SeB=m&K=%s"
+	UrlAurVoted      = "https://aur.archlinux.org/packages?SB=w&SO=d&PP=250" // packages we voted for first (max. 250 per page)
+	UrlPkgstats      = "https://pkgstats.archlinux.de/api/packages?limit=%d&offset=%d"
 
 	version = "1.8.2"
 )
@@ -40,12 +44,27 @@ type UI struct {
 	flexContainer *tview.Flex
 
 	inputSearch   *tview.InputField
+	inputFilter   *tview.InputField
 	tablePackages *tview.Table
 	tableDetails  *tview.Table
 	spinner       *tview.TextView
 	formSettings  *tview.Form
 	textMessage   *tview.TextView
 	textPkgbuild  *tview.TextView
+	treeRevDeps   *tview.TreeView
+	textComments  *tview.TextView
+	textChangelog *tview.TextView
+	textPreview   *tview.TextView
+	textDebug     *tview.TextView
+	flexFiles     *tview.Flex
+	inputFiles    *tview.InputField
+	tableFiles    *tview.Table
+	tableCache    *tview.Table
+	tableGroups   *tview.Table
+	tablePacnew   *tview.Table
+	flexHistory   *tview.Flex
+	inputHistory  *tview.InputField
+	tableHistory  *tview.Table
 	prevComponent tview.Primitive
 	tableNews     *tview.Table
 
@@ -53,6 +72,7 @@ type UI struct {
 	messageLocker *sync.RWMutex
 
 	quitSpin        chan bool
+	stopWatch       chan struct{}
 	width           int
 	leftProportion  int
 	selectedPackage *InfoRecord
@@ -60,18 +80,72 @@ type UI struct {
 	cacheInfo       *cache.Cache
 	cacheSearch     *cache.Cache
 	cachePkgbuild   *cache.Cache
+	cacheDeps       *cache.Cache
+	diskCache       *diskCache
 	filterRepos     []string
 	asciiMode       bool
 	shell           string
 	lastSearchTerm  string
+	searchCancel    context.CancelFunc
+	searchPaging    searchPaging
+	connectivity    *connectivity
+	appstream       *appstream
+	popularity      *pkgstats
+	resultFilter    resultFilter
+	liveSearch      debouncer
+	keys            keymap
 	shownPackages   []Package
+	queue           []queuedPackage
+	vulnerable      map[string][]vulnerability
 	sortAscending   bool
 	isArm           bool
+	arch            string
 	flags           args.Flags
+	postProcessors  *postProcessors
+	sources         []packageSource
 
 	tableDetailsMore bool
+	depTreeVisible   bool
+	filterBarVisible bool
 
 	pkgbuildWriter io.Writer
+	pkgbuildFile   string
+
+	commentsPkg    string
+	commentsOffset int
+	commentsMore   bool
+
+	changelogPkg string
+	previewRun   func()
+
+	removalNames   []string
+	removalOptions []string
+	removalRun     func(flags string)
+	removalChosen  string
+
+	promptVisible bool
+	upgradeCount  int
+
+	upgradeDeselected map[string]bool
+	upgradeState      upgradeState // our last check for upgrades and the changes since the one before
+	sourceUpgrades    []Upgrade    // the upgrades of our additional sources when our list of upgrades was shown last
+
+	aurVotes map[string]bool // AUR packages we voted for, nil until we retrieved them
+
+	filesPkg string
+	files    []string
+
+	cachedPkg      InfoRecord
+	cachedVersions []cachedPackage
+
+	groups []packageGroup
+
+	pacnewFiles       []pacnewFile
+	pacnewTransaction bool
+
+	history        *pacmanLog
+	historyEntries []historyEntry
+	shownHistory   []historyEntry
 }
 
 // New creates a UI object and makes sure everything is initialized
@@ -86,21 +160,41 @@ func New(conf *config.Settings, flags args.Flags) (*UI, error) {
 		cacheInfo:       cache.New(time.Duration(conf.CacheExpiry)*time.Minute, 1*time.Minute),
 		cacheSearch:     cache.New(time.Duration(conf.CacheExpiry)*time.Minute, 1*time.Minute),
 		cachePkgbuild:   cache.New(time.Duration(conf.CacheExpiry)*time.Minute, 1*time.Minute),
+		cacheDeps:       cache.New(time.Duration(conf.CacheExpiry)*time.Minute, 1*time.Minute),
+		diskCache:       newAurDiskCache(conf),
+		connectivity:    newConnectivity(flags.Offline),
+		popularity:      &pkgstats{},
+		appstream:       &appstream{},
 
-		flags:         flags,
-		sortAscending: true,
-		isArm:         runtime.GOARCH != "amd64",
+		flags:          flags,
+		sortAscending:  true,
+		isArm:          runtime.GOARCH != "amd64",
+		postProcessors: &postProcessors{},
 	}
 
 	// get users default shell
 	ui.shell = util.Shell()
 
+	// our log is opened first, so that the alpm initialization is recorded
+	logErr := ui.setupLog()
+	logger.info("pacseek started", "version", version)
+
 	// get a handle to the pacman DB's
 	var err error
-	ui.alpmHandle, err = initPacmanDbs(conf.PacmanDbPath, conf.PacmanConfigPath, flags.Repositories)
+	var warnings []string
+	ui.alpmHandle, warnings, err = initPacmanDbs(conf.PacmanRootPath, conf.PacmanDbPath, conf.PacmanConfigPath, flags.Repositories, conf.SkipFailingRepos)
 	if err != nil {
 		return nil, err
 	}
+	ui.arch, _ = pacmanArchitecture(conf.PacmanConfigPath)
+
+	// additional package sources
+	if conf.EnableFlatpak {
+		fp := newFlatpakSource()
+		fp.installCommand, fp.uninstallCommand, fp.upgradeCommand = conf.FlatpakInstallCommand, conf.FlatpakUninstallCommand, conf.FlatpakUpgradeCommand
+		ui.sources = append(ui.sources, fp)
+	}
+	ui.sources = withPlugins(ui.sources, conf.Plugins)
 
 	// set window layout
 	if conf.SaveWindowLayout {
@@ -113,6 +207,7 @@ func New(conf *config.Settings, flags args.Flags) (*UI, error) {
 	}
 
 	// setup UI
+	ui.setupKeymap()
 	ui.createComponents()
 	if flags.MonochromeMode {
 		ui.conf.SetColorScheme("Monochrome")
@@ -127,24 +222,57 @@ func New(conf *config.Settings, flags args.Flags) (*UI, error) {
 	ui.setupKeyBindings()
 	ui.setupSettingsForm()
 
+	// show warnings for repositories that have been skipped
+	if len(warnings) > 0 {
+		ui.displayMessage(strings.Join(warnings, "\n"), true)
+	}
+	if logErr != nil {
+		ui.displayMessage(logErr.Error(), true)
+	}
+
 	return &ui, nil
 }
 
 // Start runs application / event-loop
 func (ps *UI) Start() error {
-	if ps.flags.SearchTerm != "" {
+	if err := validateCommands(ps.conf); err != nil {
+		ps.displayMessage(err.Error(), true)
+	}
+	if _, err := newKeymap(ps.conf.KeyBindings); err != nil {
+		ps.displayMessage(err.Error(), true)
+	}
+	ps.updateVulnerabilities()
+	ps.loadPkgstats()
+	ps.loadAppstream()
+	ps.watchUpgrades()
+	ps.showStartupView()
+
+	defer logger.close()
+	defer ps.stopWatchingUpgrades()
+	return ps.app.SetRoot(ps.flexRoot, true).EnableMouse(true).Run()
+}
+
+// shows what has been requested with our flags: a package (--info / open), search results,
+// installed packages / upgrades or the difference to an imported package list
+func (ps *UI) showStartupView() {
+	switch {
+	case ps.flags.Package != "":
+		ps.openPackage(ps.flags.Package)
+	case ps.flags.SearchTerm != "":
 		ps.inputSearch.SetText(ps.flags.SearchTerm)
+		ps.lastSearchTerm = ps.flags.SearchTerm
 		ps.displayPackages(ps.flags.SearchTerm)
-	} else {
+	default:
 		if ps.flags.ShowInstalled {
 			ps.displayInstalled(ps.flags.ShowUpdates)
 		}
 		if ps.flags.ShowUpdates && !ps.flags.ShowInstalled {
 			ps.displayUpgradable()
 		}
+		if ps.flags.ImportFile != "" {
+			ps.displaySnapshotDiff(ps.flags.ImportFile)
+		}
 	}
-
-	return ps.app.SetRoot(ps.flexRoot, true).EnableMouse(true).Run()
 }
 
 // getArchRepos returns a list of Arch Linux repositories
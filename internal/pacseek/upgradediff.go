@@ -0,0 +1,197 @@
+package pacseek
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Jguer/go-alpm/v2"
+	"github.com/moson-mo/pacseek/internal/util"
+)
+
+// our checks for upgrades (background checks, the list of upgrades and the watch command) must not write our state concurrently
+var upgradeStateLock sync.Mutex
+
+// upgradeRecord is an upgrade we've found with one of our checks
+type upgradeRecord struct {
+	Name         string
+	Source       string
+	Version      string
+	LocalVersion string
+}
+
+// upgradeChange is a difference between two checks for upgrades
+type upgradeChange struct {
+	upgradeRecord
+	Kind            string // "new", "bumped" (a newer version is available), "installed" (upgraded in the meantime) or "gone"
+	PreviousVersion string // the version of our previous check ("bumped", "installed" and "gone")
+}
+
+// upgradeState is what we remember of our last check for upgrades
+// our changes are kept until a check finds different upgrades, "Since" is the check they are compared to
+type upgradeState struct {
+	Checked  time.Time
+	Upgrades []upgradeRecord
+	Since    time.Time
+	Changes  []upgradeChange
+}
+
+// the order of our changes
+var upgradeChangeKinds = []string{"new", "bumped", "installed", "gone"}
+
+// maximum number of changes shown in our list of upgrades
+const upgradeChangesMax = 25
+
+// returns the file of our upgrade state
+func upgradeStateFile() string {
+	return filepath.Join(stateDir(os.Getenv), "upgrades.json")
+}
+
+// returns the records of upgrades, ignored ones are left out
+func upgradeRecords(up []Upgrade) []upgradeRecord {
+	records := []upgradeRecord{}
+	for _, u := range up {
+		if u.IsIgnored {
+			continue
+		}
+		records = append(records, upgradeRecord{Name: u.Name, Source: u.Source, Version: u.Version, LocalVersion: u.LocalVersion})
+	}
+	return records
+}
+
+// compares the upgrades of our previous check with the current ones
+// upgrades that disappeared have been installed or are no longer available (e.g. the package has been removed from its repository)
+// "h" is used to look up the installed versions, without a handle these are "gone"
+func diffUpgrades(prev []upgradeRecord, current []upgradeRecord, h dbHandle) []upgradeChange {
+	changes := []upgradeChange{}
+	previous := map[string]upgradeRecord{}
+	for _, r := range prev {
+		previous[r.Name] = r
+	}
+	found := map[string]bool{}
+	for _, r := range current {
+		found[r.Name] = true
+		p, ok := previous[r.Name]
+		switch {
+		case !ok:
+			changes = append(changes, upgradeChange{upgradeRecord: r, Kind: "new"})
+		case p.Version != r.Version:
+			changes = append(changes, upgradeChange{upgradeRecord: r, Kind: "bumped", PreviousVersion: p.Version})
+		}
+	}
+
+	var local alpm.IDB
+	if h != nil {
+		local, _ = h.LocalDB()
+	}
+	for _, p := range prev {
+		if found[p.Name] {
+			continue
+		}
+		c := upgradeChange{upgradeRecord: p, Kind: "gone", PreviousVersion: p.Version}
+		if local != nil {
+			if pkg := local.Pkg(p.Name); pkg != nil && alpm.VerCmp(pkg.Version(), p.Version) >= 0 {
+				c.Kind, c.LocalVersion, c.Version = "installed", pkg.Version(), pkg.Version()
+			}
+		}
+		changes = append(changes, c)
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		ki, kj := util.IndexOf(upgradeChangeKinds, changes[i].Kind), util.IndexOf(upgradeChangeKinds, changes[j].Kind)
+		if ki != kj {
+			return ki < kj
+		}
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+// returns a description of a change for our list of upgrades
+func describeUpgradeChange(c upgradeChange) string {
+	switch c.Kind {
+	case "new":
+		return "new upgrade"
+	case "bumped":
+		return "newer version (was " + c.PreviousVersion + ")"
+	case "installed":
+		return "installed"
+	}
+	return "no longer available (was " + c.PreviousVersion + ")"
+}
+
+// reads our upgrade state, it's empty if we haven't checked for upgrades yet
+func readUpgradeState(file string) (upgradeState, error) {
+	state := upgradeState{}
+	b, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	return state, json.Unmarshal(b, &state)
+}
+
+// writes our upgrade state (to a temporary file first, so that it's never written partially)
+// the temporary file is unique, our UI and "pacseek --watch" might write the state at the same time
+func writeUpgradeState(file string, state upgradeState) error {
+	b, err := json.MarshalIndent(state, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(b)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// records the result of a check for upgrades and returns our new state with the changes since the previous check
+// a state that can't be read is replaced
+func recordUpgradeCheck(file string, up []Upgrade, h dbHandle, now time.Time) (upgradeState, error) {
+	upgradeStateLock.Lock()
+	defer upgradeStateLock.Unlock()
+
+	prev, _ := readUpgradeState(file)
+	state := upgradeState{
+		Checked:  now,
+		Upgrades: upgradeRecords(up),
+		Since:    prev.Since,
+		Changes:  prev.Changes,
+	}
+	// there's nothing to compare with on our first check
+	if !prev.Checked.IsZero() {
+		if changes := diffUpgrades(prev.Upgrades, state.Upgrades, h); len(changes) > 0 {
+			state.Since, state.Changes = prev.Checked, changes
+		}
+	}
+	return state, writeUpgradeState(file, state)
+}
+
+// records the upgrades we've found and returns our new state, its changes are shown in our list of upgrades
+func (ps *UI) recordUpgrades(up []Upgrade) upgradeState {
+	state, err := recordUpgradeCheck(upgradeStateFile(), up, ps.handle(), time.Now())
+	if err != nil {
+		logger.warn("upgrade state could not be saved", "error", err)
+	}
+	return state
+}
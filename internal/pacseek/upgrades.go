@@ -0,0 +1,194 @@
+package pacseek
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Jguer/go-alpm/v2"
+	"github.com/moson-mo/pacseek/internal/config"
+)
+
+// upgradeGroup is a list of upgrades from the same source (repository / AUR)
+type upgradeGroup struct {
+	Source   string
+	Upgrades []Upgrade
+}
+
+// groups upgrades by their source, repositories in the order of "repos" (pacman.conf) followed by all others (e.g. AUR)
+// within a group, ignored packages come last
+func groupUpgrades(up []Upgrade, repos []string) []upgradeGroup {
+	groups := []upgradeGroup{}
+	index := map[string]int{}
+	for _, u := range up {
+		i, ok := index[u.Source]
+		if !ok {
+			i = len(groups)
+			index[u.Source] = i
+			groups = append(groups, upgradeGroup{Source: u.Source, Upgrades: []Upgrade{}})
+		}
+		groups[i].Upgrades = append(groups[i].Upgrades, u)
+	}
+
+	order := map[string]int{}
+	for i, repo := range repos {
+		order[repo] = i
+	}
+	rank := func(source string) int {
+		if i, ok := order[source]; ok {
+			return i
+		}
+		return len(repos)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return rank(groups[i].Source) < rank(groups[j].Source)
+	})
+	for _, g := range groups {
+		sort.SliceStable(g.Upgrades, func(i, j int) bool {
+			return !g.Upgrades[i].IsIgnored && g.Upgrades[j].IsIgnored
+		})
+	}
+	return groups
+}
+
+// marks upgrades that are held back by IgnorePkg / IgnoreGroup of pacman.conf (rather than our own ignore list)
+func markHeldBack(dbs alpm.IDBList, up []Upgrade, rules IgnoreRules) {
+	for i := range up {
+		if !up[i].IsIgnored {
+			continue
+		}
+		// groups can only be resolved for repository packages
+		r := rules
+		if up[i].Source == "AUR" {
+			r = IgnoreRules{Packages: rules.Packages}
+		}
+		up[i].HeldBack = r.matches(dbs, up[i].Name)
+	}
+}
+
+// checks if an upgrade can be selected for a selective upgrade (ignored ones can't)
+func selectableUpgrade(u Upgrade) bool {
+	return !u.IsIgnored && u.Status == "upgrade"
+}
+
+// returns the upgrades that are selected, which are all selectable ones that have not been deselected
+func selectedUpgrades(up []Upgrade, deselected map[string]bool) []Upgrade {
+	selected := []Upgrade{}
+	for _, u := range up {
+		if selectableUpgrade(u) && !deselected[u.Name] {
+			selected = append(selected, u)
+		}
+	}
+	return selected
+}
+
+// returns the selected upgrades as packages to be installed
+func selectedUpgradeQueue(up []Upgrade, deselected map[string]bool) []queuedPackage {
+	queue := []queuedPackage{}
+	for _, u := range selectedUpgrades(up, deselected) {
+		queue = append(queue, queuedPackage{InfoRecord: u.InfoRecord})
+	}
+	return queue
+}
+
+// returns the commands for upgrading only the selected packages
+// they are installed with our install command (e.g. "pacman -S pkg1 pkg2") instead of running a sysupgrade
+// our upgrades are found with a temporary copy of the sync db's, so repo packages are installed with a refresh of them (e.g. "pacman -Sy pkg1")
+// this is a partial upgrade, which is not supported by Arch Linux
+func selectiveUpgradeCommands(conf *config.Settings, sources []packageSource, up []Upgrade, deselected map[string]bool) []string {
+	queue := selectedUpgradeQueue(up, deselected)
+	if len(queue) == 0 {
+		return []string{}
+	}
+	if len(repoInstallTargets(sources, queue)) > 0 {
+		c := *conf
+		c.InstallCommand = withRefresh(c.InstallCommand)
+		conf = &c
+	}
+	return batchCommands(conf, sources, queue)
+}
+
+// matches the sync operation (and its options) of a command, e.g. "-S" in "sudo pacman -S"
+var syncFlagsRegex = regexp.MustCompile(`(?:^|\s)(-S[a-zA-Z]*)(?:\s|$)`)
+
+// adds the refresh option to the sync operation of an install command, e.g. "sudo pacman -S" -> "sudo pacman -Sy"
+// commands without a sync operation (e.g. a script) or with a refresh already are returned unchanged
+func withRefresh(command string) string {
+	m := syncFlagsRegex.FindStringSubmatchIndex(command)
+	if m == nil || strings.Contains(command[m[2]:m[3]], "y") {
+		return command
+	}
+	return command[:m[3]] + "y" + command[m[3]:]
+}
+
+// returns the names of our sync db's in the order of pacman.conf
+func (ps *UI) syncRepoNames() []string {
+	repos := []string{}
+	if ps.alpmHandle == nil {
+		return repos
+	}
+	dbs, err := ps.alpmHandle.SyncDBs()
+	if err != nil {
+		return repos
+	}
+	for _, db := range dbs.Slice() {
+		repos = append(repos, db.Name())
+	}
+	return repos
+}
+
+// returns our upgradable packages together with the upgrades of our additional sources (e.g. Flatpak or plugins)
+// sources that fail are left out, so that they don't hide the upgrades of our repositories
+// the sources are only checked here (when our list of upgrades is shown), our background checks reuse their upgrades
+func (ps *UI) findUpgrades() ([]Upgrade, error) {
+	up, err := findUpgrades(ps.conf, ps.filterRepos)
+	if err != nil {
+		return up, err
+	}
+	sourceUp, errs := sourceUpgrades(ps.sources)
+	for _, err := range errs {
+		logger.warn("upgrades of source could not be checked", "error", err)
+	}
+	ignore := IgnoreRules{Packages: ps.conf.IgnoredPackages}
+	for i := range sourceUp {
+		sourceUp[i].IsIgnored = ignore.matches(nil, sourceUp[i].Name)
+	}
+	ps.sourceUpgrades = sourceUp
+	return append(up, sourceUp...), nil
+}
+
+// selects / deselects a package of our list of upgrades
+func (ps *UI) toggleUpgrade(up []Upgrade, names ...string) {
+	if ps.upgradeDeselected == nil {
+		ps.upgradeDeselected = map[string]bool{}
+	}
+	// toggling a group deselects all of its packages, unless all of them are deselected already
+	deselect := false
+	for _, name := range names {
+		if !ps.upgradeDeselected[name] {
+			deselect = true
+		}
+	}
+	for _, name := range names {
+		if deselect {
+			ps.upgradeDeselected[name] = true
+		} else {
+			delete(ps.upgradeDeselected, name)
+		}
+	}
+	ps.drawUpgradable(up, true)
+}
+
+// upgrades the selected packages only
+func (ps *UI) performSelectiveUpgrade(up []Upgrade) {
+	commands := selectiveUpgradeCommands(ps.conf, ps.sources, up, ps.upgradeDeselected)
+	if len(commands) == 0 {
+		ps.displayMessage("No packages selected", true)
+		return
+	}
+	if err := checkChrootBuild(ps.conf, selectedUpgradeQueue(up, ps.upgradeDeselected)); err != nil {
+		ps.displayMessage(err.Error(), true)
+		return
+	}
+	ps.runUpgradeCommand(strings.Join(commands, " && "))
+}
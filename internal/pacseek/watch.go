@@ -0,0 +1,285 @@
+package pacseek
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jguer/go-alpm/v2"
+	"github.com/moson-mo/pacseek/internal/args"
+	"github.com/moson-mo/pacseek/internal/config"
+)
+
+// maximum number of package names listed in a notification
+const notificationMaxNames = 10
+
+// syncs to our temporary db's (background checks and the list of upgrades) must not run concurrently
+var tempDBLock sync.Mutex
+
+// quietHours is a daily time range (e.g. 22:00-07:00) in which we don't send notifications
+type quietHours struct {
+	from, to int // minutes since midnight
+}
+
+// returns the upgradable packages (repositories and AUR), using a temporary copy of the sync db's (like checkupdates)
+func findUpgrades(conf *config.Settings, repos []string) ([]Upgrade, error) {
+	tempDBLock.Lock()
+	defer tempDBLock.Unlock()
+
+	pacRules, err := pacmanIgnoreRules(conf.PacmanConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the ignore rules of pacman.conf: %w", err)
+	}
+	h, err := syncToTempDB(conf.PacmanRootPath, conf.PacmanDbPath, conf.PacmanConfigPath, repos, conf.SkipFailingRepos)
+	if err != nil {
+		return nil, err
+	}
+	defer h.Release()
+
+	ignore := upgradeIgnoreRules(pacRules, conf.IgnoredPackages)
+	up, nf := getUpgradable(h, UpgradeOptions{ComputeRequiredBy: conf.ComputeRequiredBy, ComputeSizes: true}, ignore)
+	aurPkgs := infoAur(conf.AurRpcUrl, conf.AurTimeout, packageNames(nf)...)
+	for _, aurPkg := range aurPkgs.Results {
+		for i := 0; i < len(up); i++ {
+			if up[i].Source == "local" && up[i].Name == aurPkg.Name {
+				if alpm.VerCmp(aurPkg.Version, up[i].LocalVersion) > 0 {
+					up[i].Description = aurPkg.Description
+					up[i].Version = aurPkg.Version
+					up[i].Source = "AUR"
+					up[i].IsIgnored = up[i].IsIgnored || ignore.matches(nil, aurPkg.Name)
+				}
+			}
+		}
+	}
+	foundUp := []Upgrade{}
+	for _, pkg := range up {
+		if pkg.Version != pkg.LocalVersion {
+			foundUp = append(foundUp, pkg)
+		}
+	}
+	if dbs, err := h.SyncDBs(); err == nil {
+		markHeldBack(dbs, foundUp, pacRules)
+	}
+	return foundUp, nil
+}
+
+// returns the ignore rules of pacman.conf together with our own ignore list
+func upgradeIgnoreRules(pacRules IgnoreRules, ignored []string) IgnoreRules {
+	return IgnoreRules{
+		Packages: append(append([]string{}, pacRules.Packages...), ignored...),
+		Groups:   pacRules.Groups,
+	}
+}
+
+// returns the number of upgrades that are not ignored
+func countUpgrades(up []Upgrade) int {
+	count := 0
+	for _, u := range up {
+		if !u.IsIgnored {
+			count++
+		}
+	}
+	return count
+}
+
+// returns the upgrades (not ignored) that we haven't notified about yet
+// "notified" maps package names to the version we've sent a notification for, it is updated with the new ones
+func newUpgrades(up []Upgrade, notified map[string]string) []Upgrade {
+	found := []Upgrade{}
+	for _, u := range up {
+		if u.IsIgnored || notified[u.Name] == u.Version {
+			continue
+		}
+		notified[u.Name] = u.Version
+		found = append(found, u)
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].Name < found[j].Name
+	})
+	return found
+}
+
+// returns the summary and body of a notification for new upgrades
+func upgradeNotification(up []Upgrade, total int) (string, string) {
+	names := []string{}
+	for i, u := range up {
+		if i == notificationMaxNames {
+			names = append(names, fmt.Sprintf("and %d more", len(up)-notificationMaxNames))
+			break
+		}
+		names = append(names, u.Name+" "+u.Version)
+	}
+	return fmt.Sprintf("%d updates available", total), "New: " + strings.Join(names, ", ")
+}
+
+// parses a time range like "22:00-07:00", an empty string means no quiet hours
+func parseQuietHours(s string) (*quietHours, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	from, to, found := strings.Cut(s, "-")
+	if !found {
+		return nil, fmt.Errorf("invalid quiet hours '%s', expected a range like 22:00-07:00", s)
+	}
+	q := &quietHours{}
+	for _, t := range []struct {
+		text string
+		ref  *int
+	}{{from, &q.from}, {to, &q.to}} {
+		parsed, err := time.Parse("15:04", strings.TrimSpace(t.text))
+		if err != nil {
+			return nil, fmt.Errorf("invalid quiet hours '%s', expected a range like 22:00-07:00", s)
+		}
+		*t.ref = parsed.Hour()*60 + parsed.Minute()
+	}
+	return q, nil
+}
+
+// checks if a point in time falls into our quiet hours (ranges can span midnight)
+func (q *quietHours) contains(t time.Time) bool {
+	if q == nil {
+		return false
+	}
+	m := t.Hour()*60 + t.Minute()
+	if q.from <= q.to {
+		return m >= q.from && m < q.to
+	}
+	return m >= q.from || m < q.to
+}
+
+// sends a desktop notification with notify-send
+func sendNotification(summary, body string) error {
+	out, err := exec.Command("notify-send", "--app-name=pacseek", "--icon=system-software-update", summary, body).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("notify-send failed: %s %w", strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
+
+// Watch periodically checks for upgrades and sends a desktop notification when new ones are available
+// it runs until it receives an interrupt signal, errors of single checks are written to "w"
+func Watch(conf *config.Settings, flags args.Flags, w io.Writer) error {
+	quiet, err := parseQuietHours(conf.QuietHours)
+	if err != nil {
+		return err
+	}
+	if flags.Offline {
+		return errors.New("update checks need network access, they can't be run in offline mode")
+	}
+	if conf.UpdateCheckInterval < 1 {
+		return fmt.Errorf("invalid update check interval %d, it must be a positive number of minutes", conf.UpdateCheckInterval)
+	}
+	interval := time.Duration(conf.UpdateCheckInterval) * time.Minute
+	fmt.Fprintf(w, "Checking for updates every %s\n", interval)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	handles := newHandleCache(initPacmanDbs)
+	defer handles.release()
+
+	notified := map[string]string{}
+	for {
+		if !quiet.contains(time.Now()) {
+			up, err := findUpgrades(conf, flags.Repositories)
+			if err == nil {
+				if err := recordWatchedUpgrades(handles, conf, flags.Repositories, up); err != nil {
+					fmt.Fprintln(w, "Failed to save the upgrade state:", err)
+				}
+			}
+			if err != nil {
+				fmt.Fprintln(w, "Failed to check for updates:", err)
+			} else if found := newUpgrades(up, notified); len(found) > 0 {
+				summary, body := upgradeNotification(found, countUpgrades(up))
+				fmt.Fprintln(w, summary+" - "+body)
+				if err := sendNotification(summary, body); err != nil {
+					fmt.Fprintln(w, err)
+				}
+			}
+		}
+
+		select {
+		case <-sig:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// records the upgrades found in watch mode
+// our handle is re-created when our db's changed, so that packages installed in the meantime are not reported as gone
+func recordWatchedUpgrades(handles *handleCache[*alpm.Handle], conf *config.Settings, repos []string, up []Upgrade) error {
+	h, _, err := handles.get(conf.PacmanRootPath, conf.PacmanDbPath, conf.PacmanConfigPath, repos, conf.SkipFailingRepos)
+	if err != nil {
+		return err
+	}
+	_, err = recordUpgradeCheck(upgradeStateFile(), up, h, time.Now())
+	return err
+}
+
+// periodically checks for upgrades in the background and shows the number of available ones in our title
+// the upgrades are cached, so that our list of upgrades can be shown right away
+// a running check loop is stopped first, so that it can be restarted when our settings are changed
+// no checks are done while we are offline
+func (ps *UI) watchUpgrades() {
+	ps.stopWatchingUpgrades()
+	if !ps.conf.ShowUpdateStatus || ps.conf.UpdateCheckInterval < 1 {
+		return
+	}
+	stop := make(chan struct{})
+	ps.stopWatch = stop
+	interval := time.Duration(ps.conf.UpdateCheckInterval) * time.Minute
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if !ps.connectivity.offline() {
+				ps.checkUpgrades()
+			}
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// stops our background checks for upgrades (if they are running)
+func (ps *UI) stopWatchingUpgrades() {
+	if ps.stopWatch != nil {
+		close(ps.stopWatch)
+		ps.stopWatch = nil
+	}
+}
+
+// checks for upgrades and updates the number of available ones in our title
+// our additional sources (Flatpak, plugins) are not checked, we take their upgrades of our last list of upgrades
+func (ps *UI) checkUpgrades() {
+	up, err := findUpgrades(ps.conf, ps.filterRepos)
+	if err != nil {
+		return
+	}
+	// our alpm handle tells which of the upgrades that are gone have been installed
+	ps.locker.Lock()
+	up = append(up, ps.sourceUpgrades...)
+	state := ps.recordUpgrades(up)
+	ps.locker.Unlock()
+	ps.app.QueueUpdateDraw(func() {
+		if !ps.conf.DisableCache {
+			ps.cacheInfo.Set("#upgrades#", up, time.Duration(ps.conf.CacheExpiry)*time.Minute)
+		}
+		ps.upgradeState = state
+		ps.upgradeCount = countUpgrades(up)
+		ps.drawTitle()
+	})
+}
@@ -1,7 +1,9 @@
 package util
 
 import (
+	"fmt"
 	"os"
+	"runtime"
 )
 
 // SliceContains checks if a slice contains a certain element
@@ -62,3 +64,39 @@ func UniqueStrings(strSlices ...[]string) []string {
 
 	return result
 }
+
+// ResolveArchitecture returns the architecture for a pacman.conf "Architecture" value
+// "auto" (or an empty value) resolves to the architecture of the running system
+func ResolveArchitecture(confArch string) string {
+	if confArch == "" || confArch == "auto" {
+		return ArchitectureName(runtime.GOARCH)
+	}
+	return confArch
+}
+
+// ArchitectureName maps a GOARCH value to the architecture name used by pacman
+func ArchitectureName(goarch string) string {
+	switch goarch {
+	case "amd64":
+		return "x86_64"
+	case "386":
+		return "i686"
+	case "arm64":
+		return "aarch64"
+	case "arm":
+		return "armv7h"
+	}
+	return goarch
+}
+
+// FormatSize returns a human readable representation of a size in bytes (pacman style, e.g. "1.50 MiB")
+func FormatSize(bytes int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+	size := float64(bytes)
+	unit := 0
+	for (size >= 1024 || size <= -1024) && unit < len(units)-1 {
+		size /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.2f %s", size, units[unit])
+}
@@ -11,12 +11,22 @@ import (
 
 const helpText = `
 Usage: pacseek [OPTION] [SEARCH-TERM]
+       pacseek open PACKAGE
 	-r 	Limit searching to a comma separated list of repositories
 	-s	Search-term
 	-a	ASCII mode
 	-m	Monochrome mode
-	-u	show upgrades after startup
-	-i	show installed packages after startup
+	-u	show upgrades after startup (--upgrades)
+	-i	show installed packages after startup (--installed)
+	--info PACKAGE	show the details of PACKAGE after startup (same as "pacseek open PACKAGE")
+	-o	print search results (json, csv, plain) instead of starting the UI
+	-j	print search results as JSON (same as -o json)
+	-w	check for updates periodically and send desktop notifications (--watch)
+	--export FILE	export the explicitly installed packages (native / foreign) to FILE
+	--import FILE	compare FILE with the installed packages and queue the missing ones
+	--offline	search the local / sync databases only and use cached AUR data
+	--read-only	don't allow installing, removing or upgrading packages
+	--completion SHELL	print a completion script for SHELL (bash, zsh, fish)
 
 Examples:
 
@@ -29,6 +39,21 @@ pacseek -ui
 pacseek pacseek
 -> Searches for "pacseek" in all repositories
 
+pacseek --search yay --json
+-> Prints the search results for "yay" as JSON
+
+pacseek open extra/firefox
+-> Shows the details of the "firefox" package from the "extra" repository
+
+pacseek --completion bash > ~/.local/share/bash-completion/completions/pacseek
+-> Installs the bash completion
+
+pacseek --watch
+-> Checks for updates in the background and notifies about new ones
+
+pacseek --export packages.txt
+-> Saves the list of explicitly installed packages, "pacseek --import packages.txt" on another machine queues the missing ones
+
 ----------------------------------------------------------------
 
 See also:
@@ -49,6 +74,14 @@ func main() {
 		printHelp()
 		os.Exit(0)
 	}
+	if f.Completion != "" {
+		script, err := args.Completion(f.Completion)
+		if err != nil {
+			printErrorExit("Error generating completion script", err)
+		}
+		fmt.Print(script)
+		os.Exit(0)
+	}
 
 	conf, err := config.Load()
 	if err != nil {
@@ -61,6 +94,24 @@ func main() {
 			printErrorExit("Error loading configuration file", err)
 		}
 	}
+	if f.OutputFormat != "" {
+		if err = pacseek.Search(conf, f, os.Stdout, os.Stderr); err != nil {
+			printErrorExit("Error searching packages", err)
+		}
+		os.Exit(0)
+	}
+	if f.ExportFile != "" {
+		if err = pacseek.ExportSnapshot(conf, f, f.ExportFile, os.Stdout); err != nil {
+			printErrorExit("Error exporting packages", err)
+		}
+		os.Exit(0)
+	}
+	if f.Watch {
+		if err = pacseek.Watch(conf, f, os.Stdout); err != nil {
+			printErrorExit("Error watching for updates", err)
+		}
+		os.Exit(0)
+	}
 	ps, err := pacseek.New(conf, f)
 	if err != nil {
 		printErrorExit("Error during pacseek initialization", err)